@@ -3,6 +3,8 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,11 +16,19 @@ import (
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"network-log-formatter/internal/agent"
+	"network-log-formatter/internal/archive"
 	"network-log-formatter/internal/config"
 	"network-log-formatter/internal/executor"
+	"network-log-formatter/internal/job"
 	"network-log-formatter/internal/model"
 	"network-log-formatter/internal/project"
 	"network-log-formatter/internal/pyenv"
+	"network-log-formatter/internal/quality"
+	"network-log-formatter/internal/schedule"
+	"network-log-formatter/internal/schema"
+	"network-log-formatter/internal/sink"
+	"network-log-formatter/internal/timezone"
+	"network-log-formatter/internal/watch"
 )
 
 // App is the main controller bridging the Wails frontend and Go backend.
@@ -29,12 +39,28 @@ type App struct {
 	codeValidator   *agent.CodeValidator
 	batchExecutor   *executor.BatchExecutor
 	envManager      *pyenv.PythonEnvManager
+	jobManager      *job.JobManager
 	projectManager  *project.ProjectManager
+	manifestStore   *project.ManifestStore
+	runStore        *project.RunStore
+	scheduler       *schedule.Scheduler
 	settingsManager *config.SettingsManager
 	llmClient       *agent.LLMClient
 	mu              sync.Mutex // protects pyenvReady and pyenvError
 	pyenvReady      bool
 	pyenvError      string
+	previewMu       sync.Mutex // protects previews
+	previews        []cachedPreview
+}
+
+// maxPreviews is the number of previews kept for paging, most recent first.
+const maxPreviews = 5
+
+// cachedPreview is a preview with all its rows, kept so the frontend can
+// page through its tables.
+type cachedPreview struct {
+	preview  *model.Preview
+	pageSize int
 }
 
 // NewApp creates a new App with SettingsManager and ProjectManager initialized.
@@ -48,11 +74,49 @@ func NewApp(configDir string) *App {
 		fmt.Printf("warning: failed to initialize project manager: %v\n", err)
 	}
 
-	return &App{
+	maxConcurrent := 2
+	if settings, err := settingsMgr.Load(); err == nil && settings.MaxConcurrentJobs > 0 {
+		maxConcurrent = settings.MaxConcurrentJobs
+	}
+
+	jobStore, err := job.NewJobStore(filepath.Join(configDir, "jobs"))
+	if err != nil {
+		// Jobs still run, they just won't survive a restart
+		fmt.Printf("warning: failed to initialize job store: %v\n", err)
+	}
+
+	manifestStore, err := project.NewManifestStore(filepath.Join(configDir, "manifests"))
+	if err != nil {
+		// Incremental runs fall back to processing every file
+		fmt.Printf("warning: failed to initialize manifest store: %v\n", err)
+	}
+
+	runStore, err := project.NewRunStore(filepath.Join(configDir, "runs"))
+	if err != nil {
+		// Runs still work, they just aren't recorded
+		fmt.Printf("warning: failed to initialize run store: %v\n", err)
+	}
+
+	scheduleStore, err := project.NewScheduleStore(filepath.Join(configDir, "schedules"))
+	if err != nil {
+		// Schedules don't fire without a place to track them
+		fmt.Printf("warning: failed to initialize schedule store: %v\n", err)
+	}
+
+	a := &App{
 		configDir:       configDir,
 		settingsManager: settingsMgr,
 		projectManager:  projectMgr,
+		manifestStore:   manifestStore,
+		runStore:        runStore,
 	}
+	a.jobManager = job.NewJobManager(a.runJob, jobStore, maxConcurrent)
+	if projectMgr != nil && scheduleStore != nil {
+		a.scheduler = schedule.New(projectMgr.List, scheduleStore, a.fireSchedule)
+	}
+	// Hold the queue until the Python environment is ready
+	a.jobManager.SetPaused(true)
+	return a
 }
 
 // startup is called by Wails when the application starts.
@@ -76,6 +140,24 @@ func (a *App) startup(ctx context.Context) {
 	}
 	envPath := filepath.Join(a.configDir, "pyenv")
 	a.envManager = pyenv.NewPythonEnvManager(uvPath, envPath)
+	a.envManager.SetLimits(scriptLimits(*settings))
+
+	// Initialize LLM components if configured
+	if settings.LLM.BaseURL != "" && settings.LLM.APIKey != "" && settings.LLM.ModelName != "" {
+		_ = a.initLLMComponents(settings.LLM)
+	}
+
+	// Reload the persisted job queue; it starts once the environment is ready
+	resumeJobs := settings.ResumeInterruptedJobs == nil || *settings.ResumeInterruptedJobs
+	if err := a.jobManager.Restore(resumeJobs); err != nil {
+		fmt.Printf("warning: failed to restore job queue: %v\n", err)
+	}
+
+	// Queue scheduled runs while the application is open; their jobs wait
+	// for the environment like the restored ones
+	if a.scheduler != nil {
+		go a.scheduler.Run(ctx)
+	}
 
 	// Auto-initialize Python environment in background
 	go func() {
@@ -85,16 +167,14 @@ func (a *App) startup(ctx context.Context) {
 			a.mu.Unlock()
 			fmt.Printf("warning: auto python env init failed: %v\n", err)
 		} else {
+			// Probe the sandbox now so the first script doesn't wait for it
+			pyenv.DetectSandbox()
 			a.mu.Lock()
 			a.pyenvReady = true
 			a.mu.Unlock()
+			a.jobManager.SetPaused(false)
 		}
 	}()
-
-	// Initialize LLM components if configured
-	if settings.LLM.BaseURL != "" && settings.LLM.APIKey != "" && settings.LLM.ModelName != "" {
-		_ = a.initLLMComponents(settings.LLM)
-	}
 }
 
 // initLLMComponents initializes or reinitializes the LLM client and all
@@ -184,52 +264,307 @@ func (a *App) AnalyzeSample(projectName string, sampleText string) (*model.Gener
 	}, nil
 }
 
-// RunBatch starts batch processing in a background goroutine so it doesn't block the UI.
-func (a *App) RunBatch(projectID string, inputDir string, outputDir string, outputFileName string) error {
+// RunBatch queues a batch job for the project and returns its job ID.
+// The job runs in the background so it doesn't block the UI; use
+// GetBatchProgress or GetJob with the returned ID to follow it. Jobs are
+// persisted, so queued jobs survive an application restart.
+func (a *App) RunBatch(projectID string, inputDir string, outputDir string, outputFileName string) (string, error) {
+	return a.RunBatchWithOptions(projectID, model.BatchParams{
+		InputDir:       inputDir,
+		OutputDir:      outputDir,
+		OutputFileName: outputFileName,
+	})
+}
+
+// RunBatchWithOptions queues a batch job with the full set of batch
+// parameters, such as the number of parallel workers, and returns its job ID.
+func (a *App) RunBatchWithOptions(projectID string, params model.BatchParams) (string, error) {
 	if a.batchExecutor == nil {
-		return fmt.Errorf("LLM is not configured. Please configure LLM settings first")
+		return "", fmt.Errorf("LLM is not configured. Please configure LLM settings first")
 	}
 	if a.projectManager == nil {
-		return fmt.Errorf("project manager is not initialized")
+		return "", fmt.Errorf("project manager is not initialized")
 	}
 
 	a.mu.Lock()
 	envReady := a.pyenvReady
 	a.mu.Unlock()
 	if !envReady {
-		return fmt.Errorf("Python 环境尚未就绪，请等待初始化完成")
+		return "", fmt.Errorf("Python 环境尚未就绪，请等待初始化完成")
 	}
 
 	p, err := a.projectManager.Get(projectID)
 	if err != nil {
-		return fmt.Errorf("failed to get project: %w", err)
+		return "", fmt.Errorf("failed to get project: %w", err)
 	}
 
 	if strings.TrimSpace(p.Code) == "" {
-		return fmt.Errorf("项目代码为空，无法执行")
+		return "", fmt.Errorf("项目代码为空，无法执行")
+	}
+	// A sinks-only run may ship to the project's sinks alone
+	if err := executor.ValidateFormats(a.runParams(p, params)); err != nil {
+		return "", err
 	}
 
-	go func() {
-		_, execErr := a.batchExecutor.Execute(a.ctx, p.Code, inputDir, outputDir, outputFileName)
+	j, err := a.jobManager.Submit(projectID, p.Name, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to queue batch job: %w", err)
+	}
+	return j.ID, nil
+}
+
+// runJob executes a queued batch job with the project's current code and
+// updates the project status from the outcome. Watch jobs keep running until
+// they are cancelled. It is the JobManager runner.
+func (a *App) runJob(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+	if a.batchExecutor == nil {
+		return nil, fmt.Errorf("LLM is not configured. Please configure LLM settings first")
+	}
+	if a.projectManager == nil {
+		return nil, fmt.Errorf("project manager is not initialized")
+	}
+	if j.Params.Watch {
+		return a.runWatch(ctx, j, report)
+	}
+	return a.runOnce(ctx, j, j.Params, report)
+}
+
+// runOnce runs the project's current code once for the given job with the
+// given parameters, keeping the project's file manifest, status and run
+// history up to date. Runs of the same project wait for each other.
+func (a *App) runOnce(ctx context.Context, j model.BatchJob, params model.BatchParams, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+	projectID := j.ProjectID
+	if a.manifestStore != nil {
+		// Runs of a project take turns, from reading its code and manifest
+		// to recording the run
+		unlock, err := a.manifestStore.Lock(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	p, err := a.projectManager.Get(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	started := time.Now()
 
-		// Update project status based on result
-		status := "executed"
-		if execErr != nil {
-			status = "failed"
+	var prev *model.FileManifest
+	if a.manifestStore != nil {
+		if prev, err = a.manifestStore.Load(projectID); err != nil {
+			fmt.Printf("warning: ignoring manifest of project %s: %v\n", projectID, err)
 		}
-		statusStr := status
-		_ = a.projectManager.Update(projectID, model.ProjectUpdate{Status: &statusStr})
+	}
+
+	execParams := a.runParams(p, params)
+	result, manifest, execErr := a.batchExecutor.ExecuteIncremental(ctx, p.Code, execParams, prev, report)
+	if execErr == nil && manifest != nil && a.manifestStore != nil {
+		manifest.ProjectID = projectID
+		if err := a.manifestStore.Save(*manifest); err != nil {
+			fmt.Printf("warning: failed to save manifest of project %s: %v\n", projectID, err)
+		}
+	}
+	defer func() {
+		a.recordRun(ctx, j, p, params, started, result, execErr)
 	}()
+	if ctx.Err() != nil {
+		// Cancelled by the user — leave the project status untouched
+		return result, execErr
+	}
 
-	return nil
+	// Update project status based on result
+	status := "executed"
+	if execErr != nil {
+		status = "failed"
+	}
+	update := model.ProjectUpdate{Status: &status}
+	if result != nil && result.RepairedCode != "" && a.autoApplyRepairs() {
+		// Keep the fix so later runs don't hit the same error
+		update.Code = &result.RepairedCode
+		update.CodeReason = "repair"
+		result.CodeApplied = true
+	}
+	_ = a.projectManager.Update(projectID, update)
+
+	return result, execErr
 }
 
-// GetBatchProgress returns the current batch processing progress.
-func (a *App) GetBatchProgress() (*model.BatchProgress, error) {
-	if a.batchExecutor == nil {
-		return &model.BatchProgress{Status: "idle"}, nil
+// recordRun adds a finished run of project p by job j to its run history.
+func (a *App) recordRun(ctx context.Context, j model.BatchJob, p *model.Project, params model.BatchParams, started time.Time, result *model.BatchResult, execErr error) {
+	if a.runStore == nil {
+		return
+	}
+	// Each run of a watch job is recorded as a single run
+	params.Watch = false
+
+	hash := sha256.Sum256([]byte(p.Code))
+	r := model.RunRecord{
+		ID:           uuid.New().String(),
+		ProjectID:    p.ID,
+		JobID:        j.ID,
+		Params:       params,
+		OutputFile:   executor.OutputWorkbookPath(params),
+		StartedAt:    started,
+		FinishedAt:   time.Now(),
+		CodeHash:     hex.EncodeToString(hash[:]),
+		ScheduleID:   j.ScheduleID,
+		ScheduledFor: j.ScheduledFor,
+	}
+	switch {
+	case ctx.Err() != nil:
+		r.Status = "cancelled"
+	case execErr != nil:
+		r.Status = "failed"
+		r.Error = execErr.Error()
+	case result != nil && result.Degraded:
+		r.Status = "degraded"
+	default:
+		r.Status = "completed"
+	}
+	if result != nil {
+		r.TotalFiles = result.TotalFiles
+		r.Succeeded = result.Succeeded
+		r.Failed = result.Failed
+		r.Files = result.Files
+		r.Stderr = result.Stderr
+		r.RepairAttempts = result.RepairAttempts
+		r.RepairedCode = result.RepairedCode != ""
+		r.Outputs = result.Outputs
+		r.Sheets = result.Sheets
+		r.Sinks = result.Sinks
+		r.Summary = result.Summary
+		r.Quality = result.Quality
+		r.TimeZones = result.TimeZones
+	}
+	if err := a.runStore.Save(r); err != nil {
+		fmt.Printf("warning: failed to record run of project %s: %v\n", p.ID, err)
 	}
-	return a.batchExecutor.GetProgress(), nil
+}
+
+// runParams returns the parameters a run of p executes with. The
+// project's sinks, schema, quality thresholds and time zones and the
+// enrichment databases of the settings are resolved per run, so the run
+// record keeps the run's own settings and a re-run uses the current ones.
+func (a *App) runParams(p *model.Project, params model.BatchParams) model.BatchParams {
+	exec := params
+	exec.Sinks = sink.ForRun(p, params)
+	if len(exec.Schema) == 0 {
+		exec.Schema = p.Schema
+	}
+	if exec.Quality == nil {
+		q := p.Quality
+		exec.Quality = &q
+	}
+	if exec.TimeZones == nil {
+		z := p.TimeZones
+		exec.TimeZones = &z
+	}
+	if params.Enrich != nil {
+		e := *params.Enrich
+		if settings, err := a.settingsManager.Load(); err == nil {
+			if e.CityDB == "" && e.ASNDB == "" {
+				e.CityDB, e.ASNDB = settings.GeoIPCityDB, settings.GeoIPASNDB
+			}
+			if e.Language == "" {
+				e.Language = settings.Language
+			}
+		}
+		exec.Enrich = &e
+	}
+	return exec
+}
+
+// autoApplyRepairs reports whether code repaired during a run is saved to
+// the project without asking.
+func (a *App) autoApplyRepairs() bool {
+	settings, err := a.settingsManager.Load()
+	return err == nil && settings.AutoApplyRepairs
+}
+
+// runWatch processes the job's input directory incrementally every time
+// files arrive or rotate, until the job is cancelled. A failed run is
+// reported but doesn't end the watch; the next change retries it.
+func (a *App) runWatch(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+	params := j.Params
+	params.Incremental = true
+
+	// Single runs end in "completed", "degraded" or "failed"; a watch
+	// carries on
+	runReport := func(p *model.BatchProgress) {
+		if p.Status == "completed" || p.Status == "degraded" || p.Status == "failed" {
+			q := *p
+			q.Status = "watching"
+			p = &q
+		}
+		report(p)
+	}
+
+	var last *model.BatchResult
+	runs := 0
+	recursive := params.Filter != nil && params.Filter.Recursive
+	w := watch.NewWatcher(params.InputDir, recursive, time.Duration(params.WatchDebounce)*time.Second)
+	err := w.Run(ctx, func(ctx context.Context, changed []string) {
+		result, err := a.runOnce(ctx, j, params, runReport)
+		if ctx.Err() != nil {
+			return
+		}
+		runs++
+		at := time.Now().Format("15:04:05")
+		if err != nil {
+			runReport(&model.BatchProgress{
+				Status:  "watching",
+				Message: fmt.Sprintf("Run %d at %s failed: %v; still watching %s", runs, at, err, params.InputDir),
+			})
+			return
+		}
+		last = result
+		runReport(&model.BatchProgress{
+			Status:     "watching",
+			TotalFiles: result.TotalFiles,
+			Processed:  result.Succeeded,
+			Progress:   1.0,
+			Message: fmt.Sprintf("Run %d at %s processed %d file(s), skipped %d; watching %s",
+				runs, at, result.Succeeded, len(result.Skipped), params.InputDir),
+		})
+	})
+	return last, err
+}
+
+// GetBatchProgress returns the progress of the given batch job. An empty job
+// ID returns the progress of the most recently started job.
+func (a *App) GetBatchProgress(jobID string) (*model.BatchProgress, error) {
+	if jobID == "" {
+		latest := a.jobManager.Latest()
+		if latest == nil {
+			return &model.BatchProgress{Status: "idle"}, nil
+		}
+		return &latest.Progress, nil
+	}
+	j, err := a.jobManager.Get(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &j.Progress, nil
+}
+
+// ListJobs returns all batch jobs sorted by creation time descending.
+func (a *App) ListJobs() []model.BatchJob {
+	return a.jobManager.List()
+}
+
+// GetJob returns a single batch job, including its progress and result.
+func (a *App) GetJob(id string) (*model.BatchJob, error) {
+	return a.jobManager.Get(id)
+}
+
+// CancelJob cancels a queued or running batch job.
+func (a *App) CancelJob(id string) error {
+	return a.jobManager.Cancel(id)
+}
+
+// DeleteJob removes a finished batch job from the job list and the queue on disk.
+func (a *App) DeleteJob(id string) error {
+	return a.jobManager.Delete(id)
 }
 
 // ListProjects returns all projects sorted by creation time descending.
@@ -256,27 +591,321 @@ func (a *App) UpdateProjectCode(id string, code string) error {
 	return a.projectManager.Update(id, model.ProjectUpdate{Code: &code})
 }
 
+// UpdateProjectSinks replaces the services every run of a project ships its
+// rows to.
+func (a *App) UpdateProjectSinks(id string, sinks []model.SinkConfig) error {
+	if a.projectManager == nil {
+		return fmt.Errorf("project manager is not initialized")
+	}
+	if err := sink.Validate(sinks); err != nil {
+		return err
+	}
+	return a.projectManager.Update(id, model.ProjectUpdate{Sinks: &sinks})
+}
+
+// UpdateProjectSchema replaces the declared column types of a project.
+func (a *App) UpdateProjectSchema(id string, columns []model.ColumnSchema) error {
+	if a.projectManager == nil {
+		return fmt.Errorf("project manager is not initialized")
+	}
+	if err := schema.Validate(columns); err != nil {
+		return err
+	}
+	return a.projectManager.Update(id, model.ProjectUpdate{Schema: &columns})
+}
+
+// UpdateProjectQuality replaces the data quality thresholds of a project.
+func (a *App) UpdateProjectQuality(id string, thresholds model.QualityThresholds) error {
+	if a.projectManager == nil {
+		return fmt.Errorf("project manager is not initialized")
+	}
+	if err := quality.Validate(thresholds); err != nil {
+		return err
+	}
+	return a.projectManager.Update(id, model.ProjectUpdate{Quality: &thresholds})
+}
+
+// UpdateProjectTimeZones replaces the zones the times of a project's runs
+// are normalized with.
+func (a *App) UpdateProjectTimeZones(id string, zones model.TimeZones) error {
+	if a.projectManager == nil {
+		return fmt.Errorf("project manager is not initialized")
+	}
+	if err := timezone.Validate(zones); err != nil {
+		return err
+	}
+	return a.projectManager.Update(id, model.ProjectUpdate{TimeZones: &zones})
+}
+
+// UpdateProjectSchedules replaces the schedules of a project. Schedules
+// without an ID are given one.
+func (a *App) UpdateProjectSchedules(id string, schedules []model.Schedule) error {
+	if a.projectManager == nil {
+		return fmt.Errorf("project manager is not initialized")
+	}
+	for i := range schedules {
+		if schedules[i].ID == "" {
+			schedules[i].ID = uuid.New().String()
+		}
+	}
+	if err := schedule.Validate(schedules); err != nil {
+		return err
+	}
+	return a.projectManager.Update(id, model.ProjectUpdate{Schedules: &schedules})
+}
+
+// GetScheduleStatus returns the schedules of a project with what each has
+// done so far and when it next runs.
+func (a *App) GetScheduleStatus(projectID string) ([]model.ScheduleStatus, error) {
+	if a.projectManager == nil || a.scheduler == nil {
+		return nil, fmt.Errorf("scheduler is not initialized")
+	}
+	p, err := a.projectManager.Get(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return a.scheduler.Status(*p, time.Now())
+}
+
+// ListScheduleRuns returns the recorded runs a schedule of a project
+// started, most recent first.
+func (a *App) ListScheduleRuns(projectID string, scheduleID string) ([]model.RunRecord, error) {
+	runs, err := a.ListRuns(projectID)
+	if err != nil {
+		return nil, err
+	}
+	var scheduled []model.RunRecord
+	for _, r := range runs {
+		if r.ScheduleID == scheduleID {
+			scheduled = append(scheduled, r)
+		}
+	}
+	return scheduled, nil
+}
+
+// fireSchedule queues the run of schedule s of project p that came due at
+// the given time. It is the scheduler's Fire: a run isn't queued while the
+// previous one of the schedule is still queued or running.
+func (a *App) fireSchedule(p model.Project, s model.Schedule, at time.Time) (string, error) {
+	if a.batchExecutor == nil {
+		return "", fmt.Errorf("LLM is not configured. Please configure LLM settings first")
+	}
+	if strings.TrimSpace(p.Code) == "" {
+		return "", fmt.Errorf("项目代码为空，无法执行")
+	}
+	for _, j := range a.jobManager.List() {
+		if j.ScheduleID == s.ID && (j.Status == "queued" || j.Status == "running") {
+			return "", fmt.Errorf("previous run of the schedule is still %s", j.Status)
+		}
+	}
+
+	params := model.BatchParams{
+		InputDir:       s.InputDir,
+		OutputDir:      s.OutputDir,
+		OutputFileName: schedule.OutputName(s.OutputName, p.Name, at),
+		Incremental:    s.Incremental,
+		Summary:        s.Summary,
+	}
+	if err := executor.ValidateFormats(a.runParams(&p, params)); err != nil {
+		return "", err
+	}
+	j, err := a.jobManager.SubmitScheduled(p.ID, p.Name, params, s.ID, at)
+	if err != nil {
+		return "", err
+	}
+	return j.ID, nil
+}
+
+// GetRepairDiff returns the line diff from the project's current code to the
+// code repaired during the given job.
+func (a *App) GetRepairDiff(jobID string) ([]model.DiffLine, error) {
+	j, p, err := a.repairedJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return project.DiffLines(p.Code, j.Result.RepairedCode), nil
+}
+
+// ApplyRepairedCode saves the code repaired during the given job to its
+// project, keeping the replaced code in the project's revision history.
+func (a *App) ApplyRepairedCode(jobID string) error {
+	j, _, err := a.repairedJob(jobID)
+	if err != nil {
+		return err
+	}
+	code := j.Result.RepairedCode
+	if err := a.projectManager.Update(j.ProjectID, model.ProjectUpdate{Code: &code, CodeReason: "repair"}); err != nil {
+		return err
+	}
+	return a.jobManager.MarkCodeApplied(jobID)
+}
+
+// repairedJob returns a job whose run repaired the code, and its project.
+func (a *App) repairedJob(jobID string) (*model.BatchJob, *model.Project, error) {
+	if a.projectManager == nil {
+		return nil, nil, fmt.Errorf("project manager is not initialized")
+	}
+	j, err := a.jobManager.Get(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if j.Result == nil || j.Result.RepairedCode == "" {
+		return nil, nil, fmt.Errorf("job %s has no repaired code", jobID)
+	}
+	p, err := a.projectManager.Get(j.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return j, p, nil
+}
+
 // DeleteProject removes a project by ID.
 func (a *App) DeleteProject(id string) error {
 	if a.projectManager == nil {
 		return fmt.Errorf("project manager is not initialized")
 	}
-	return a.projectManager.Delete(id)
+	if err := a.projectManager.Delete(id); err != nil {
+		return err
+	}
+	if a.manifestStore != nil {
+		_ = a.manifestStore.Delete(id)
+	}
+	if a.runStore != nil {
+		_ = a.runStore.DeleteProject(id)
+	}
+	if a.scheduler != nil {
+		_ = a.scheduler.Forget(id)
+	}
+	return nil
+}
+
+// ListRuns returns the run history of a project, most recent first.
+func (a *App) ListRuns(projectID string) ([]model.RunRecord, error) {
+	if a.runStore == nil {
+		return nil, fmt.Errorf("run store is not initialized")
+	}
+	return a.runStore.List(projectID)
+}
+
+// GetRun returns a single run of a project.
+func (a *App) GetRun(projectID string, runID string) (*model.RunRecord, error) {
+	if a.runStore == nil {
+		return nil, fmt.Errorf("run store is not initialized")
+	}
+	return a.runStore.Get(projectID, runID)
+}
+
+// RerunRun queues a batch job with the parameters of an earlier run, using
+// the project's current code, and returns the job ID.
+func (a *App) RerunRun(projectID string, runID string) (string, error) {
+	r, err := a.GetRun(projectID, runID)
+	if err != nil {
+		return "", err
+	}
+	return a.RunBatchWithOptions(projectID, r.Params)
+}
+
+// OpenRunOutput opens the workbook an earlier run wrote, or its output
+// directory when the workbook is gone.
+func (a *App) OpenRunOutput(projectID string, runID string) error {
+	r, err := a.GetRun(projectID, runID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(r.OutputFile); err == nil {
+		return openFileExplorer(r.OutputFile)
+	}
+	if _, err := os.Stat(r.Params.OutputDir); err != nil {
+		return fmt.Errorf("output of run %s no longer exists", runID)
+	}
+	return a.OpenDirectory(r.Params.OutputDir)
+}
+
+// PreviewBatch runs the project's code on the first lines of the selected
+// input files with the project's schema, time zones and the chosen
+// enrichment, without writing to any output directory, and returns the
+// first page of the rows it produced for each file. Further pages are read
+// with GetPreviewPage.
+func (a *App) PreviewBatch(projectID string, params model.PreviewParams) (*model.Preview, error) {
+	if a.batchExecutor == nil {
+		return nil, fmt.Errorf("LLM is not configured. Please configure LLM settings first")
+	}
+	if a.projectManager == nil {
+		return nil, fmt.Errorf("project manager is not initialized")
+	}
+
+	a.mu.Lock()
+	envReady := a.pyenvReady
+	a.mu.Unlock()
+	if !envReady {
+		return nil, fmt.Errorf("Python 环境尚未就绪，请等待初始化完成")
+	}
+
+	p, err := a.projectManager.Get(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if strings.TrimSpace(p.Code) == "" {
+		return nil, fmt.Errorf("项目代码为空，无法执行")
+	}
+
+	// The rows look as a run would write them, without shipping them
+	run := a.runParams(p, model.BatchParams{Enrich: params.Enrich})
+	preview, err := a.batchExecutor.Preview(a.ctx, p.Code, params, run, func(*model.BatchProgress) {})
+	if err != nil {
+		return nil, err
+	}
+	preview.ID = uuid.New().String()
+
+	a.previewMu.Lock()
+	a.previews = append([]cachedPreview{{preview: preview, pageSize: params.PageSize}}, a.previews...)
+	if len(a.previews) > maxPreviews {
+		a.previews = a.previews[:maxPreviews]
+	}
+	a.previewMu.Unlock()
+
+	first := *preview
+	first.Tables = make([]model.PreviewTable, len(preview.Tables))
+	for i, t := range preview.Tables {
+		first.Tables[i] = executor.PageTable(t, 0, params.PageSize)
+	}
+	return &first, nil
+}
+
+// GetPreviewPage returns a zero-based page of a table of a recent preview.
+func (a *App) GetPreviewPage(previewID string, table int, page int) (*model.PreviewTable, error) {
+	a.previewMu.Lock()
+	defer a.previewMu.Unlock()
+	for _, c := range a.previews {
+		if c.preview.ID != previewID {
+			continue
+		}
+		if table < 0 || table >= len(c.preview.Tables) {
+			return nil, fmt.Errorf("preview has no table %d", table)
+		}
+		t := executor.PageTable(c.preview.Tables[table], page, c.pageSize)
+		return &t, nil
+	}
+	return nil, fmt.Errorf("preview not found: %s", previewID)
 }
 
-// RerunProject starts batch processing using an existing project's code.
-func (a *App) RerunProject(id string, inputDir string, outputDir string, outputFileName string) error {
+// RerunProject queues batch processing using an existing project's code and
+// returns the job ID.
+func (a *App) RerunProject(id string, inputDir string, outputDir string, outputFileName string) (string, error) {
 	return a.RunBatch(id, inputDir, outputDir, outputFileName)
 }
 
 // BrowseLogFile opens a file picker for log files, reads the first N lines
 // (configured by SampleLines setting, default 5), and returns the sample text
 // along with a project name derived from the file name (without extension).
+// Compressed files and archives are sampled from their decompressed content,
+// and the project name of an archive comes from its first file.
 func (a *App) BrowseLogFile() (*model.LogFileSample, error) {
 	filePath, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
 		Title: "选择日志文件",
 		Filters: []wailsRuntime.FileFilter{
 			{DisplayName: "日志文件", Pattern: "*.log;*.txt;*.csv;*.json;*.xml"},
+			{DisplayName: "压缩日志", Pattern: "*.gz;*.bz2;*.xz;*.zip;*.tar;*.tgz"},
 			{DisplayName: "所有文件", Pattern: "*.*"},
 		},
 	})
@@ -293,8 +922,8 @@ func (a *App) BrowseLogFile() (*model.LogFileSample, error) {
 		sampleLines = settings.SampleLines
 	}
 
-	// Read first N lines
-	f, err := os.Open(filePath)
+	// Read first N lines, decompressing archived logs on the fly
+	f, innerName, err := archive.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("无法打开文件: %w", err)
 	}
@@ -314,8 +943,8 @@ func (a *App) BrowseLogFile() (*model.LogFileSample, error) {
 	}
 
 	baseName := filepath.Base(filePath)
-	ext := filepath.Ext(baseName)
-	projectName := strings.TrimSuffix(baseName, ext)
+	ext := filepath.Ext(innerName)
+	projectName := strings.TrimSuffix(innerName, ext)
 
 	return &model.LogFileSample{
 		FileName:    baseName,
@@ -338,6 +967,21 @@ func (a *App) SelectDirectory(title string) (string, error) {
 	return dir, nil
 }
 
+// SelectGeoIPDatabase opens a native file picker for a MaxMind database and
+// returns the selected path; empty when cancelled.
+func (a *App) SelectGeoIPDatabase(title string) (string, error) {
+	if title == "" {
+		title = "选择 GeoIP 数据库"
+	}
+	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: title,
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "MaxMind 数据库", Pattern: "*.mmdb"},
+			{DisplayName: "所有文件", Pattern: "*.*"},
+		},
+	})
+}
+
 // OpenDirectory opens the given directory in the system file explorer.
 func (a *App) OpenDirectory(dir string) error {
 	if dir == "" {
@@ -368,6 +1012,7 @@ func (a *App) SaveSettings(settings model.Settings) error {
 	}
 	envPath := filepath.Join(a.configDir, "pyenv")
 	newEnvMgr := pyenv.NewPythonEnvManager(uvPath, envPath)
+	newEnvMgr.SetLimits(scriptLimits(settings))
 
 	a.mu.Lock()
 	a.envManager = newEnvMgr
@@ -375,6 +1020,7 @@ func (a *App) SaveSettings(settings model.Settings) error {
 	a.pyenvReady = false
 	a.pyenvError = ""
 	a.mu.Unlock()
+	a.jobManager.SetPaused(true)
 
 	// Re-initialize Python environment in background
 	go func() {
@@ -384,12 +1030,19 @@ func (a *App) SaveSettings(settings model.Settings) error {
 			a.mu.Unlock()
 			fmt.Printf("warning: python env re-init failed: %v\n", err)
 		} else {
+			// Probe the sandbox now so the first script doesn't wait for it
+			pyenv.DetectSandbox()
 			a.mu.Lock()
 			a.pyenvReady = true
 			a.mu.Unlock()
+			a.jobManager.SetPaused(false)
 		}
 	}()
 
+	if settings.MaxConcurrentJobs > 0 {
+		a.jobManager.SetMaxConcurrent(settings.MaxConcurrentJobs)
+	}
+
 	// Reinitialize LLM components if configured
 	if settings.LLM.BaseURL != "" && settings.LLM.APIKey != "" && settings.LLM.ModelName != "" {
 		if err := a.initLLMComponents(settings.LLM); err != nil {
@@ -400,6 +1053,26 @@ func (a *App) SaveSettings(settings model.Settings) error {
 	return nil
 }
 
+// scriptLimits converts the resource limit settings for the Python
+// environment manager.
+func scriptLimits(settings model.Settings) pyenv.Limits {
+	nice := settings.ScriptNice
+	if nice < 0 {
+		nice = 0
+	} else if nice > 19 {
+		nice = 19
+	}
+	limits := pyenv.Limits{
+		Timeout:     time.Duration(settings.ScriptTimeoutMinutes) * time.Minute,
+		IdleTimeout: time.Duration(settings.ScriptIdleMinutes) * time.Minute,
+		Nice:        nice,
+	}
+	if settings.ScriptMemoryMB > 0 {
+		limits.MemoryBytes = uint64(settings.ScriptMemoryMB) << 20
+	}
+	return limits
+}
+
 // EnsurePythonEnv ensures the Python virtual environment is set up.
 func (a *App) EnsurePythonEnv() error {
 	if a.envManager == nil {
@@ -416,14 +1089,19 @@ func (a *App) GetEnvStatus() (*pyenv.EnvStatus, error) {
 	return a.envManager.GetStatus(), nil
 }
 
-// GetPythonEnvReady returns whether the auto-init has completed and any error message.
+// GetPythonEnvReady returns whether the auto-init has completed and any error
+// message, plus a warning once ready when scripts can't be sandboxed.
 func (a *App) GetPythonEnvReady() map[string]interface{} {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"ready": a.pyenvReady,
 		"error": a.pyenvError,
 	}
+	if a.pyenvReady {
+		status["sandbox_warning"] = pyenv.DetectSandbox().Warning
+	}
+	return status
 }
 
 // IsLLMConfigured returns true if LLM settings are filled and the client is initialized.
@@ -517,6 +1195,33 @@ func (a *llmRepairerAdapter) RepairCode(ctx context.Context, code string, errorM
 	return extractRepairCode(resp), nil
 }
 
+// RepairQuality asks for code whose rows pass the data quality checks it
+// failed; the code itself ran without errors.
+func (a *llmRepairerAdapter) RepairQuality(ctx context.Context, code string, problem string) (string, error) {
+	messages := []model.Message{
+		{
+			Role: "system",
+			Content: "You are an expert Python developer. The given Python log parser runs without errors, " +
+				"but the rows it streams fail data quality checks. Fix the parsing so the rows pass them. " +
+				"Return the complete fixed Python code inside a single ```python code block. " +
+				"Do not explain the changes, just return the corrected code.",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("The following Python code ran without errors, but its rows failed data quality checks:\n\n```python\n%s\n```\n\n"+
+				"Quality problems:\n```\n%s\n```\n\nPlease fix the parsing and return the complete corrected code.",
+				code, problem),
+		},
+	}
+
+	resp, err := a.llmClient.Chat(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+
+	return extractRepairCode(resp), nil
+}
+
 // extractRepairCode extracts Python code from an LLM repair response.
 func extractRepairCode(response string) string {
 	// Try ```python block
@@ -77,6 +77,319 @@ function showConfirm(message, options) {
     return _showDialog('confirm', message, options);
 }
 
+// ---- Sink Editor ----
+
+const SINK_TYPES = {
+    elasticsearch: 'Elasticsearch', opensearch: 'OpenSearch', loki: 'Grafana Loki',
+    webhook: 'Webhook', splunk: 'Splunk HEC', clickhouse: 'ClickHouse',
+};
+
+// renderSinkEditor lists the sinks rows are shipped to, with a form to add
+// one, in el. onChange gets the new list after an addition or removal.
+function renderSinkEditor(el, sinks, onChange) {
+    const split = v => v.split(',').map(x => x.trim()).filter(x => x);
+    // pairs parses "name=value" pairs separated by sep into an object, or
+    // returns null after telling the user of a malformed pair.
+    const pairs = (v, sep, what) => {
+        const out = {};
+        for (const pair of v.split(sep).map(x => x.trim()).filter(x => x)) {
+            const eq = pair.indexOf('=');
+            if (eq <= 0) { showAlert(what + '格式应为 名称=值：' + pair); return null; }
+            out[pair.substring(0, eq).trim()] = pair.substring(eq + 1).trim();
+        }
+        return out;
+    };
+    const target = s => {
+        const labels = Object.entries(s.labels || {}).map(([k, v]) => k + '=' + v);
+        switch (s.type) {
+        case 'loki': return labels.concat(s.label_columns || []).join(', ');
+        case 'webhook': return labels.join(', ');
+        case 'splunk': return [s.source_type || '（项目名称）', s.index].filter(x => x).join(' / ');
+        case 'clickhouse': return s.table;
+        default: return s.index || '（项目名称）';
+        }
+    };
+    let html = '';
+    if (sinks.length > 0) {
+        html += '<table class="table"><thead><tr><th>类型</th><th>地址</th><th>索引 / 标签 / 表</th><th>批量 / 重试 / 待发</th><th>操作</th></tr></thead><tbody>';
+        sinks.forEach((s, i) => {
+            html += '<tr><td class="text-sm">' + escapeHtml(SINK_TYPES[s.type] || s.type) + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(s.url) + (s.tls_insecure ? ' <span class="text-xs text-muted">（不校验证书）</span>' : '') + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(target(s) || '') + '</td>';
+            html += '<td class="text-sm">' + (s.batch_size || 500) + ' / ' + (s.max_retries < 0 ? 0 : (s.max_retries || 3)) + ' / ' + (s.max_pending || 4) + '</td>';
+            html += '<td><button class="btn btn-danger btn-sm sink-remove-btn" data-index="' + i + '">删除</button></td></tr>';
+        });
+        html += '</tbody></table>';
+    }
+    // data-types lists the sink types an input applies to; it is hidden for the others.
+    html += `
+        <div class="input-with-btn mt-8">
+            <select class="form-select sink-type">
+                ${Object.entries(SINK_TYPES).map(([v, label]) => '<option value="' + v + '">' + label + '</option>').join('')}
+            </select>
+            <input type="text" class="sink-url" placeholder="地址，如 https://es.example.com:9200">
+        </div>
+        <div class="input-with-btn mt-8">
+            <input type="text" class="sink-index" data-types="elasticsearch opensearch splunk" placeholder="索引名（默认使用项目名称）">
+            <input type="text" class="sink-table" data-types="clickhouse" placeholder="表名，如 logs.firewall">
+            <input type="text" class="sink-source-type" data-types="splunk" placeholder="sourcetype（默认使用项目名称）">
+            <input type="text" class="sink-labels" data-types="loki webhook splunk" placeholder="标签，如 env=prod, site=bj">
+            <input type="text" class="sink-label-columns" data-types="loki" placeholder="作为标签的列，逗号分隔">
+            <input type="text" class="sink-time-column" data-types="elasticsearch opensearch loki webhook splunk" placeholder="时间列（默认自动识别）">
+        </div>
+        <div class="input-with-btn mt-8">
+            <input type="text" class="sink-username" data-types="elasticsearch opensearch loki webhook clickhouse" placeholder="用户名">
+            <input type="password" class="sink-password" data-types="elasticsearch opensearch loki webhook clickhouse" placeholder="密码">
+            <input type="password" class="sink-api-key" data-types="elasticsearch opensearch" placeholder="API Key">
+            <input type="password" class="sink-token" data-types="webhook splunk" placeholder="令牌（Splunk HEC Token / Bearer）">
+            <input type="text" class="sink-tenant" data-types="loki" placeholder="租户 ID（X-Scope-OrgID）">
+        </div>
+        <div class="input-with-btn mt-8">
+            <input type="text" class="sink-headers" placeholder="附加请求头，如 X-Env=prod; X-Team=net">
+            <input type="text" class="sink-dead-letter" placeholder="死信文件（默认 输出名.deadletter.jsonl）">
+        </div>
+        <div class="input-with-btn mt-8">
+            <input type="text" class="sink-ca-file" placeholder="CA 证书文件（PEM）">
+            <input type="text" class="sink-cert-file" placeholder="客户端证书文件">
+            <input type="text" class="sink-key-file" placeholder="客户端私钥文件">
+            <label class="wizard-checkbox"><input type="checkbox" class="sink-tls-insecure"><span>不校验证书</span></label>
+        </div>
+        <div class="input-with-btn mt-8">
+            <input type="number" class="sink-batch-size" min="0" placeholder="每批行数（默认 500）">
+            <input type="number" class="sink-max-retries" min="-1" placeholder="重试次数（默认 3，-1 不重试）">
+            <input type="number" class="sink-max-pending" min="0" placeholder="最多待发批次（默认 4）">
+            <button class="btn btn-default btn-sm sink-add-btn">添加</button>
+        </div>`;
+    el.innerHTML = html;
+
+    const q = cls => el.querySelector('.' + cls);
+    const shown = cls => q(cls).style.display !== 'none';
+    const toggle = () => {
+        const type = q('sink-type').value;
+        el.querySelectorAll('[data-types]').forEach(input => {
+            input.style.display = input.dataset.types.split(' ').includes(type) ? '' : 'none';
+        });
+    };
+    q('sink-type').addEventListener('change', toggle);
+    toggle();
+
+    el.querySelectorAll('.sink-remove-btn').forEach(btn => {
+        btn.addEventListener('click', () => {
+            onChange(sinks.filter((_, i) => i !== parseInt(btn.dataset.index, 10)));
+        });
+    });
+    q('sink-add-btn').addEventListener('click', () => {
+        const type = q('sink-type').value;
+        const url = q('sink-url').value.trim();
+        if (!url) { showAlert('请填写输出目标地址'); return; }
+        if (type === 'splunk' && !q('sink-token').value) { showAlert('请填写 Splunk HEC 令牌'); return; }
+        if (type === 'clickhouse' && !q('sink-table').value.trim()) { showAlert('请填写 ClickHouse 表名'); return; }
+        const headers = pairs(q('sink-headers').value, ';', '请求头');
+        if (!headers) return;
+        const sink = {
+            type: type,
+            url: url,
+            headers: headers,
+            tls_insecure: q('sink-tls-insecure').checked,
+            ca_file: q('sink-ca-file').value.trim(),
+            cert_file: q('sink-cert-file').value.trim(),
+            key_file: q('sink-key-file').value.trim(),
+            dead_letter: q('sink-dead-letter').value.trim(),
+            batch_size: parseInt(q('sink-batch-size').value, 10) || 0,
+            max_retries: parseInt(q('sink-max-retries').value, 10) || 0,
+            max_pending: parseInt(q('sink-max-pending').value, 10) || 0,
+        };
+        const text = {
+            'sink-index': 'index', 'sink-table': 'table', 'sink-source-type': 'source_type',
+            'sink-time-column': 'time_column', 'sink-username': 'username', 'sink-tenant': 'tenant_id',
+        };
+        for (const [cls, key] of Object.entries(text)) {
+            if (shown(cls)) sink[key] = q(cls).value.trim();
+        }
+        for (const [cls, key] of [['sink-password', 'password'], ['sink-api-key', 'api_key'], ['sink-token', 'token']]) {
+            if (shown(cls)) sink[key] = q(cls).value;
+        }
+        if (shown('sink-labels')) {
+            sink.labels = pairs(q('sink-labels').value, ',', '标签');
+            if (!sink.labels) return;
+        }
+        if (shown('sink-label-columns')) sink.label_columns = split(q('sink-label-columns').value);
+        onChange(sinks.concat([sink]));
+    });
+}
+
+// sinkResultsHtml shows how many rows reached each sink of a run.
+function sinkResultsHtml(results) {
+    if (!results || results.length === 0) return '';
+    let html = '<div class="text-xs text-muted mt-8 mb-8">输出目标</div>';
+    html += '<table class="table"><thead><tr><th>类型</th><th>目标</th><th>已送达</th><th>失败</th><th>错误</th></tr></thead><tbody>';
+    for (const s of results) {
+        html += '<tr><td class="text-sm">' + escapeHtml(SINK_TYPES[s.type] || s.type) + '</td>';
+        html += '<td class="text-sm">' + escapeHtml(s.target) + '</td>';
+        html += '<td class="text-sm">' + (s.sent || 0) + '</td>';
+        html += '<td class="text-sm">' + (s.failed || 0) + '</td>';
+        html += '<td class="text-sm">' + escapeHtml(s.error || '');
+        if (s.dead_letter) html += '<div class="text-xs text-muted">死信文件：' + escapeHtml(s.dead_letter) + '</div>';
+        html += '</td></tr>';
+    }
+    html += '</tbody></table>';
+    return html;
+}
+
+// summaryHtml shows the summary of a run's rows, as on the Summary sheet.
+function summaryHtml(summary) {
+    if (!summary) return '';
+    const topTable = (title, head, top) => {
+        let t = '<div class="text-xs text-muted mt-8 mb-8">' + escapeHtml(title) + '</div>';
+        t += '<table class="table"><thead><tr><th>' + head + '</th><th>行数</th><th>占比</th></tr></thead><tbody>';
+        for (const v of top.values || []) {
+            const share = top.total ? (100 * v.count / top.total).toFixed(1) + '%' : '';
+            t += '<tr><td class="text-sm">' + escapeHtml(v.value) + '</td><td class="text-sm">' + v.count + '</td><td class="text-sm">' + share + '</td></tr>';
+        }
+        return t + '</tbody></table>';
+    };
+
+    let html = '<div class="text-xs text-muted mt-8 mb-8">数据汇总</div>';
+    html += '<div class="text-sm">共 ' + summary.rows + ' 行';
+    if (summary.from) html += '，时间范围 ' + escapeHtml(summary.from) + ' 至 ' + escapeHtml(summary.to);
+    html += '</div>';
+    if (summary.approximate) html += '<div class="text-xs text-muted">部分列的不同取值过多，其计数为下限</div>';
+    if (summary.files && summary.files.length > 0) {
+        html += '<table class="table mt-8"><thead><tr><th>文件</th><th>行数</th><th>时间列</th><th>开始</th><th>结束</th></tr></thead><tbody>';
+        for (const f of summary.files) {
+            html += '<tr><td class="text-sm">' + escapeHtml(f.file) + '</td>';
+            html += '<td class="text-sm">' + f.rows + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(f.time_column || '-') + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(f.from || '-') + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(f.to || '-') + '</td></tr>';
+        }
+        html += '</tbody></table>';
+    }
+    for (const top of summary.top_ips || []) html += topTable('访问最多的地址：' + top.column, '地址', top);
+    for (const top of summary.statuses || []) html += topTable('取值分布：' + top.column, '取值', top);
+    const hourly = summary.hourly || [];
+    if (hourly.length > 0) {
+        const max = Math.max(...hourly.map(h => h.count)) || 1;
+        html += '<div class="text-xs text-muted mt-8 mb-8">每小时行数</div>';
+        html += '<table class="table"><thead><tr><th>小时</th><th>行数</th><th></th></tr></thead><tbody>';
+        for (const h of hourly) {
+            html += '<tr><td class="text-sm">' + escapeHtml(h.hour) + '</td><td class="text-sm">' + h.count + '</td>';
+            html += '<td style="width:50%"><div class="summary-bar" style="width:' + (100 * h.count / max).toFixed(1) + '%"></div></td></tr>';
+        }
+        html += '</tbody></table>';
+    }
+    if (summary.errors && summary.errors.length > 0) {
+        html += '<div class="text-xs text-muted mt-8 mb-8">最常见的错误信息</div>';
+        html += '<table class="table"><thead><tr><th>错误信息</th><th>行数</th></tr></thead><tbody>';
+        for (const e of summary.errors) {
+            html += '<tr><td class="text-sm">' + escapeHtml(e.value) + '</td><td class="text-sm">' + e.count + '</td></tr>';
+        }
+        html += '</tbody></table>';
+    }
+    return html;
+}
+
+// qualityHtml shows the data quality report of a run's rows.
+function qualityHtml(quality) {
+    if (!quality) return '';
+    const pct = (n, total) => total ? (100 * n / total).toFixed(1) + '%' : '-';
+    let html = '<div class="text-xs text-muted mt-8 mb-8">数据质量</div>';
+    for (const v of quality.violations || []) {
+        html += '<div class="text-sm" style="color: var(--danger-text)">未达标：' + escapeHtml(v) + '</div>';
+    }
+    html += '<div class="text-sm">共 ' + quality.rows + ' 行，重复行 ' + quality.duplicates + '（' + pct(quality.duplicates, quality.rows) + '）';
+    html += '，时间解析失败 ' + quality.time_failures + '，时间倒序 ' + quality.out_of_order + '，未来时间 ' + quality.future + '</div>';
+    if (quality.approximate) html += '<div class="text-xs text-muted">行数过多，重复行计数为下限</div>';
+    const columns = quality.columns || [];
+    if (columns.length > 0) {
+        html += '<table class="table mt-8"><thead><tr><th>列</th><th>类型</th><th>空值</th><th>类型不符</th><th>示例</th></tr></thead><tbody>';
+        for (const c of columns) {
+            const values = c.rows - c.empty;
+            html += '<tr><td class="text-sm">' + escapeHtml(c.name) + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(c.type || '-') + (c.declared ? '' : ' <span class="text-xs text-muted">推断</span>') + '</td>';
+            html += '<td class="text-sm">' + c.empty + '（' + pct(c.empty, c.rows) + '）</td>';
+            html += '<td class="text-sm">' + c.mismatched + '（' + pct(c.mismatched, values) + '）</td>';
+            html += '<td class="text-xs">' + (c.examples || []).map(escapeHtml).join('<br>') + '</td></tr>';
+        }
+        html += '</tbody></table>';
+    }
+    const files = quality.files || [];
+    if (files.length > 1 || files.some(f => f.out_of_order || f.future || f.duplicates)) {
+        html += '<table class="table mt-8"><thead><tr><th>文件</th><th>行数</th><th>时间列</th><th>时间倒序</th><th>未来时间</th><th>重复行</th></tr></thead><tbody>';
+        for (const f of files) {
+            html += '<tr><td class="text-sm">' + escapeHtml(f.file) + '</td>';
+            html += '<td class="text-sm">' + f.rows + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(f.time_column || '-') + '</td>';
+            html += '<td class="text-sm">' + f.out_of_order + '</td>';
+            html += '<td class="text-sm">' + f.future + '</td>';
+            html += '<td class="text-sm">' + f.duplicates + '</td></tr>';
+        }
+        html += '</tbody></table>';
+    }
+    return html;
+}
+
+// timeZoneFieldsHtml returns the inputs time zones are edited in, their
+// ids starting with prefix.
+function timeZoneFieldsHtml(prefix) {
+    return `
+        <div class="input-with-btn">
+            <input type="text" id="${prefix}-source" placeholder="日志时区，如 Asia/Shanghai、+08:00、UTC；留空同目标时区">
+            <input type="text" id="${prefix}-target" placeholder="目标时区，如 Local、UTC、Europe/Berlin；留空不转换">
+            <select class="form-select" id="${prefix}-format">
+                <option value="datetime">Excel 日期时间</option>
+                <option value="iso">ISO 8601（带时差）</option>
+            </select>
+        </div>
+        <div class="input-with-btn mt-8">
+            <input type="text" id="${prefix}-files" placeholder="按文件指定时区，如 fw-*.log=+02:00, asia/*=Asia/Tokyo">
+        </div>`;
+}
+
+// fillTimeZoneFields shows zones in the inputs of timeZoneFieldsHtml.
+function fillTimeZoneFields(prefix, zones) {
+    zones = zones || {};
+    document.getElementById(prefix + '-source').value = zones.source || '';
+    document.getElementById(prefix + '-target').value = zones.target || '';
+    document.getElementById(prefix + '-format').value = zones.format || 'datetime';
+    document.getElementById(prefix + '-files').value = (zones.files || []).map(f => f.pattern + '=' + f.zone).join(', ');
+}
+
+// readTimeZoneFields returns the zones in the inputs of timeZoneFieldsHtml;
+// it throws when a file zone isn't written as pattern=zone.
+function readTimeZoneFields(prefix) {
+    const files = [];
+    for (const pair of document.getElementById(prefix + '-files').value.split(',').map(x => x.trim()).filter(x => x)) {
+        const eq = pair.lastIndexOf('=');
+        if (eq <= 0) throw new Error('按文件指定的时区格式应为 文件模式=时区：' + pair);
+        files.push({ pattern: pair.substring(0, eq).trim(), zone: pair.substring(eq + 1).trim() });
+    }
+    return {
+        source: document.getElementById(prefix + '-source').value.trim(),
+        files: files,
+        target: document.getElementById(prefix + '-target').value.trim(),
+        format: document.getElementById(prefix + '-format').value
+    };
+}
+
+// timeZonesHtml shows the time zones a run's times were normalized with.
+function timeZonesHtml(zones) {
+    if (!zones) return '';
+    const formats = { datetime: 'Excel 日期时间', iso: 'ISO 8601' };
+    let html = '<div class="text-xs text-muted mt-8 mb-8">时区转换</div>';
+    html += '<div class="text-sm">日志时区 ' + escapeHtml(zones.source) + ' → 目标时区 ' + escapeHtml(zones.target);
+    html += '，格式 ' + escapeHtml(formats[zones.format] || zones.format) + '，共转换 ' + (zones.converted || 0) + ' 个时间</div>';
+    const files = Object.entries(zones.files || {});
+    if (files.length > 0) {
+        html += '<table class="table mt-8"><thead><tr><th>文件</th><th>日志时区</th></tr></thead><tbody>';
+        for (const [file, zone] of files.sort()) {
+            html += '<tr><td class="text-sm">' + escapeHtml(file) + '</td><td class="text-sm">' + escapeHtml(zone) + '</td></tr>';
+        }
+        html += '</tbody></table>';
+    }
+    return html;
+}
+
 const App = {
     pages: {},
     currentPage: null,
@@ -267,9 +580,15 @@ const App = {
             try {
                 const status = await window.go.main.App.GetPythonEnvReady();
                 if (status.ready) {
+                    this.updateEnvIndicator('ready', I18n.t('env.ready'));
+                    if (status.sandbox_warning) {
+                        banner.className = 'alert alert-warning';
+                        banner.innerHTML = I18n.t('env.sandbox_warning') + ': ' + escapeHtml(status.sandbox_warning);
+                        setTimeout(() => banner.remove(), 10000);
+                        return;
+                    }
                     banner.className = 'alert alert-success';
                     banner.innerHTML = I18n.t('env.init_success');
-                    this.updateEnvIndicator('ready', I18n.t('env.ready'));
                     setTimeout(() => banner.remove(), 3000);
                     return;
                 }
@@ -47,6 +47,21 @@ const translations = {
         'settings.other': '其他',
         'settings.sample_lines': '采样条数（浏览日志文件时取前几行作为样本）',
         'settings.sample_lines_placeholder': '默认 5',
+        'settings.max_concurrent_jobs': '最大并发任务数（同时运行的批处理任务）',
+        'settings.limits': '脚本资源限制',
+        'settings.script_timeout': '单次运行时间上限（分钟）',
+        'settings.script_idle': '无进度超时（分钟，脚本在此时间内没有任何输出即终止）',
+        'settings.script_memory': '内存上限（MB，仅 Linux）',
+        'settings.script_nice': 'CPU 优先级（0 正常 ~ 19 最低，仅 Linux）',
+        'settings.no_limit_placeholder': '留空或 0 表示不限制',
+        'settings.max_concurrent_jobs_placeholder': '默认 2',
+        'settings.resume_interrupted_jobs': '重启后自动恢复被中断的任务',
+        'settings.auto_apply_repairs': '自动将运行中修复的代码保存到项目',
+        'settings.geoip': 'GeoIP 数据库',
+        'settings.geoip_desc': '批量处理时可为 IP 列追加国家、城市、ASN 与组织信息，使用本地 MaxMind 数据库（.mmdb，如 GeoLite2），不联网查询',
+        'settings.geoip_city_db': 'City 或 Country 数据库',
+        'settings.geoip_asn_db': 'ASN 数据库',
+        'settings.geoip_select': '选择 GeoIP 数据库',
         'settings.show_wizard': '启动时显示使用向导',
         'settings.language': '界面语言',
         'settings.saved': '设置已保存',
@@ -108,6 +123,7 @@ const translations = {
         'batch.progress_failed': '获取进度失败',
         'batch.status.running': '处理中',
         'batch.status.completed': '已完成',
+        'batch.status.degraded': '已完成（降级）',
         'batch.status.failed': '失败',
         'batch.status.fixing': '修复中',
         'batch.status.idle': '空闲',
@@ -149,6 +165,7 @@ const translations = {
         'env.timeout': '初始化超时',
         'env.init_banner': '正在自动初始化 Python 环境...',
         'env.init_success': '✅ Python 环境已就绪',
+        'env.sandbox_warning': '⚠️ Python 环境已就绪，但脚本将在无沙箱的情况下运行',
         'env.init_failed': '❌ 环境初始化失败',
         'env.init_timeout': '⏱ 环境初始化超时，请在设置中手动初始化',
         
@@ -219,6 +236,21 @@ const translations = {
         'settings.other': 'Other',
         'settings.sample_lines': 'Sample Lines (number of lines to read when browsing log files)',
         'settings.sample_lines_placeholder': 'Default: 5',
+        'settings.max_concurrent_jobs': 'Max Concurrent Jobs (batch jobs running at the same time)',
+        'settings.limits': 'Script Resource Limits',
+        'settings.script_timeout': 'Time limit per run (minutes)',
+        'settings.script_idle': 'No-progress timeout (minutes without any script output)',
+        'settings.script_memory': 'Memory limit (MB, Linux only)',
+        'settings.script_nice': 'CPU priority (0 normal to 19 lowest, Linux only)',
+        'settings.no_limit_placeholder': 'Empty or 0 for no limit',
+        'settings.max_concurrent_jobs_placeholder': 'Default: 2',
+        'settings.resume_interrupted_jobs': 'Resume interrupted jobs after restart',
+        'settings.auto_apply_repairs': 'Save code repaired during a run to the project automatically',
+        'settings.geoip': 'GeoIP Databases',
+        'settings.geoip_desc': 'Batch runs can add country, city, ASN and organization columns for IP columns from local MaxMind databases (.mmdb, such as GeoLite2), without online lookups',
+        'settings.geoip_city_db': 'City or Country database',
+        'settings.geoip_asn_db': 'ASN database',
+        'settings.geoip_select': 'Select GeoIP database',
         'settings.show_wizard': 'Show wizard on startup',
         'settings.language': 'Language',
         'settings.saved': 'Settings saved',
@@ -280,6 +312,7 @@ const translations = {
         'batch.progress_failed': 'Failed to get progress',
         'batch.status.running': 'Running',
         'batch.status.completed': 'Completed',
+        'batch.status.degraded': 'Completed (degraded)',
         'batch.status.failed': 'Failed',
         'batch.status.fixing': 'Fixing',
         'batch.status.idle': 'Idle',
@@ -321,6 +354,7 @@ const translations = {
         'env.timeout': 'Initialization timeout',
         'env.init_banner': 'Automatically initializing Python environment...',
         'env.init_success': '✅ Python environment ready',
+        'env.sandbox_warning': '⚠️ Python environment ready, but scripts will run without a sandbox',
         'env.init_failed': '❌ Environment initialization failed',
         'env.init_timeout': '⏱ Environment initialization timeout, please initialize manually in settings',
         
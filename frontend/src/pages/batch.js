@@ -30,13 +30,121 @@ App.registerPage('batch', function(container) {
                 </div>
             </div>
             <div class="form-group">
-                <label for="batch-output-name">输出文件名（不含 .xlsx 后缀）</label>
+                <label for="batch-output-name">输出文件名（不含扩展名）</label>
                 <input type="text" id="batch-output-name" placeholder="默认使用项目名称">
             </div>
+            <div class="form-group">
+                <label for="batch-workers">并行进程数</label>
+                <input type="number" id="batch-workers" min="1" max="32" value="1" placeholder="1 表示单进程处理">
+            </div>
+            <div class="form-group">
+                <label for="batch-max-workbook-rows">每个工作簿最大行数（超过后续写到“名称 (2).xlsx”等新工作簿；单个工作表超过 Excel 上限 1048576 行时自动分表）</label>
+                <input type="number" id="batch-max-workbook-rows" min="0" placeholder="留空表示不限">
+            </div>
+            <div class="form-group">
+                <label>输出格式（可多选；CSV、JSON Lines、Parquet 每个输入文件一个文件，SQLite 每个输入文件一张表；增量与监控模式仅支持 Excel）</label>
+                <div id="batch-formats">
+                    <label class="wizard-checkbox"><input type="checkbox" value="xlsx" checked><span>Excel (.xlsx)</span></label>
+                    <label class="wizard-checkbox"><input type="checkbox" value="csv"><span>CSV</span></label>
+                    <label class="wizard-checkbox"><input type="checkbox" value="jsonl"><span>JSON Lines</span></label>
+                    <label class="wizard-checkbox"><input type="checkbox" value="parquet"><span>Parquet</span></label>
+                    <label class="wizard-checkbox"><input type="checkbox" value="sqlite"><span>SQLite</span></label>
+                </div>
+            </div>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-project-sinks" checked>
+                <span>发送到项目配置的输出目标（<span id="batch-project-sinks-count">0</span> 个，在项目管理中配置）</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-sinks-toggle">
+                <span>本次运行的其他输出目标（Elasticsearch/OpenSearch、Loki、Webhook、Splunk HEC、ClickHouse，可单独设置批量与重试）</span>
+            </label>
+            <div id="batch-sinks" style="display:none;"></div>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-sinks-only">
+                <span>仅发送到输出目标，不写出文件（发送失败的行仍记入死信文件）</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-zones-toggle">
+                <span>本次运行另设时区（默认使用项目的时区设置；目标时区留空表示本次不转换时间）</span>
+            </label>
+            <div id="batch-zones" style="display:none;">
+                ${timeZoneFieldsHtml('batch-zones')}
+            </div>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-enrich-range">
+                <span>为 IP 列追加地址范围（公网、私有、环回、保留等，无需数据库）</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-enrich-geoip">
+                <span>为 IP 列追加国家、城市、ASN 与组织（使用设置中配置的 GeoIP 数据库；IP 列按项目的列类型或列中的值识别）</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-summary" checked>
+                <span>生成数据汇总（各文件行数与时间范围、访问最多的地址、状态/动作分布、每小时行数、常见错误信息；写在工作簿的 Summary 工作表中，不输出 Excel 时另存为“名称.summary.xlsx”；增量与监控模式不生成）</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-filter-toggle">
+                <span>文件筛选（递归子目录、按名称/大小/修改时间选择输入文件）</span>
+            </label>
+            <div id="batch-filter-options" style="display:none;">
+                <label class="wizard-checkbox">
+                    <input type="checkbox" id="batch-recursive">
+                    <span>包含子目录（子目录中的文件以“目录_文件名”命名工作表）</span>
+                </label>
+                <div class="form-group">
+                    <label for="batch-include">包含模式（逗号分隔，如 *.log, web*/**/*.log）</label>
+                    <input type="text" id="batch-include" placeholder="留空表示全部文件">
+                </div>
+                <div class="form-group">
+                    <label for="batch-exclude">排除模式（逗号分隔，可匹配文件或目录）</label>
+                    <input type="text" id="batch-exclude" placeholder="如 *.gz, archive">
+                </div>
+                <div class="form-group">
+                    <label for="batch-min-size">文件大小范围（KB）</label>
+                    <div class="input-with-btn">
+                        <input type="number" id="batch-min-size" min="0" placeholder="最小">
+                        <input type="number" id="batch-max-size" min="0" placeholder="最大">
+                    </div>
+                </div>
+                <div class="form-group">
+                    <label for="batch-modified-after">修改时间范围</label>
+                    <div class="input-with-btn">
+                        <input type="datetime-local" id="batch-modified-after">
+                        <input type="datetime-local" id="batch-modified-before">
+                    </div>
+                </div>
+            </div>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-incremental">
+                <span>增量处理（仅处理新增或变更的文件，并更新已有输出文件中对应的工作表）</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="batch-watch">
+                <span>监控模式（持续监控输入目录，自动处理新到达或轮转的日志，直到手动停止）</span>
+            </label>
+            <div class="form-group" id="batch-watch-options" style="display:none;">
+                <label for="batch-watch-debounce">静默等待时间（秒）</label>
+                <input type="number" id="batch-watch-debounce" min="1" max="3600" value="2" placeholder="文件停止写入多久后开始处理">
+            </div>
+            <div class="form-group">
+                <label for="batch-preview-lines">预览（每个文件读取前 N 行，仅预览指定文件时填写文件名，逗号分隔）</label>
+                <div class="input-with-btn">
+                    <input type="number" id="batch-preview-lines" min="1" max="10000" value="100" placeholder="行数">
+                    <input type="text" id="batch-preview-files" placeholder="留空表示全部文件">
+                </div>
+            </div>
             <button id="batch-start-btn" class="btn btn-primary">
                 <svg width="15" height="15" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><polygon points="5 3 19 12 5 21 5 3"/></svg>
                 开始处理
             </button>
+            <button id="batch-preview-btn" class="btn btn-default">预览解析结果</button>
+        </div>
+        <div id="batch-preview-section" style="display:none;">
+            <div class="card">
+                <div class="card-title">预览结果（不写入输出目录）</div>
+                <div id="batch-preview-content"></div>
+            </div>
         </div>
         <div id="batch-progress-section" style="display:none;">
             <div class="card">
@@ -57,6 +165,7 @@ App.registerPage('batch', function(container) {
                 <div class="section-divider"></div>
                 <div class="text-xs text-muted mb-8" style="font-weight:600;text-transform:uppercase;letter-spacing:0.5px;">运行日志</div>
                 <div class="log-area" id="batch-log"></div>
+                <button class="btn btn-danger btn-sm mt-12" id="batch-cancel-btn">取消任务</button>
             </div>
         </div>
         <div id="batch-result-section" style="display:none;">
@@ -68,6 +177,13 @@ App.registerPage('batch', function(container) {
                 <div id="batch-result-content"></div>
             </div>
         </div>
+        <div class="card">
+            <div class="flex-between mb-8">
+                <div class="card-title" style="margin-bottom:0;">任务列表</div>
+                <button class="btn btn-default btn-sm" id="refresh-jobs-btn">刷新</button>
+            </div>
+            <div id="batch-jobs-list"></div>
+        </div>
     `;
 
     const startBtn = document.getElementById('batch-start-btn');
@@ -75,17 +191,84 @@ App.registerPage('batch', function(container) {
     const inputDirInput = document.getElementById('batch-input-dir');
     const outputDirInput = document.getElementById('batch-output-dir');
     const outputNameInput = document.getElementById('batch-output-name');
+    const workersInput = document.getElementById('batch-workers');
+    const maxWorkbookRowsInput = document.getElementById('batch-max-workbook-rows');
+    const incrementalToggle = document.getElementById('batch-incremental');
+    const watchToggle = document.getElementById('batch-watch');
+    const filterToggle = document.getElementById('batch-filter-toggle');
+
+    const sinksToggle = document.getElementById('batch-sinks-toggle');
+    const sinksEl = document.getElementById('batch-sinks');
+    let runSinks = [];
+    function renderRunSinks() {
+        renderSinkEditor(sinksEl, runSinks, updated => {
+            runSinks = updated;
+            renderRunSinks();
+        });
+    }
+    renderRunSinks();
+    sinksToggle.addEventListener('change', () => {
+        sinksEl.style.display = sinksToggle.checked ? 'block' : 'none';
+    });
+
+    // The run's zones start from the project's
+    const zonesToggle = document.getElementById('batch-zones-toggle');
+    zonesToggle.addEventListener('change', () => {
+        if (zonesToggle.checked) fillTimeZoneFields('batch-zones', projectZones[projectSelect.value]);
+        document.getElementById('batch-zones').style.display = zonesToggle.checked ? 'block' : 'none';
+    });
+
+    filterToggle.addEventListener('change', () => {
+        document.getElementById('batch-filter-options').style.display = filterToggle.checked ? 'block' : 'none';
+    });
+
+    // buildEnrich collects the enrichment options, or null when none is selected.
+    function buildEnrich() {
+        const classify = document.getElementById('batch-enrich-range').checked;
+        const geoip = document.getElementById('batch-enrich-geoip').checked;
+        if (!classify && !geoip) return null;
+        return { classify: classify, geoip: geoip };
+    }
+
+    // buildFilter collects the file filter options, or null when filtering is off.
+    function buildFilter() {
+        if (!filterToggle.checked) return null;
+        const patterns = id => document.getElementById(id).value.split(',').map(x => x.trim()).filter(x => x);
+        const kb = id => Math.round((parseFloat(document.getElementById(id).value) || 0) * 1024);
+        const time = id => {
+            const v = document.getElementById(id).value;
+            return v ? new Date(v).toISOString() : undefined;
+        };
+        return {
+            recursive: document.getElementById('batch-recursive').checked,
+            include: patterns('batch-include'),
+            exclude: patterns('batch-exclude'),
+            min_size: kb('batch-min-size'),
+            max_size: kb('batch-max-size'),
+            modified_after: time('batch-modified-after'),
+            modified_before: time('batch-modified-before'),
+        };
+    }
+    const watchDebounceInput = document.getElementById('batch-watch-debounce');
+
+    watchToggle.addEventListener('change', () => {
+        document.getElementById('batch-watch-options').style.display = watchToggle.checked ? 'block' : 'none';
+    });
     const progressSection = document.getElementById('batch-progress-section');
     const resultSection = document.getElementById('batch-result-section');
     const progressBar = document.getElementById('batch-progress-bar');
     const progressText = document.getElementById('batch-progress-text');
     const fileInfo = document.getElementById('batch-file-info');
-    const statusBadge = document.getElementById('batch-status-badge');
+    const statusBadgeEl = document.getElementById('batch-status-badge');
     const logArea = document.getElementById('batch-log');
     const resultContent = document.getElementById('batch-result-content');
+    const cancelBtn = document.getElementById('batch-cancel-btn');
+    const jobsList = document.getElementById('batch-jobs-list');
 
     // Load projects into dropdown
     let projectsMap = {};
+    let projectSinks = {};
+    let projectZones = {};
     (async () => {
         try {
             const projects = await window.go.main.App.ListProjects();
@@ -94,6 +277,8 @@ App.registerPage('batch', function(container) {
                 projects.forEach(p => {
                     const label = p.name || p.id.substring(0, 8);
                     projectsMap[p.id] = label;
+                    projectSinks[p.id] = (p.sinks || []).length;
+                    projectZones[p.id] = p.time_zones;
                     projectSelect.innerHTML += '<option value="' + p.id + '">' + escapeHtml(label) + '</option>';
                 });
             }
@@ -104,14 +289,20 @@ App.registerPage('batch', function(container) {
                 const name = App.pageParams.projectName || projectsMap[App.pageParams.projectId] || '';
                 if (name) outputNameInput.value = name;
                 App.pageParams = null;
+                showProjectSinks();
             }
         } catch (_) {
             projectSelect.innerHTML = '<option value="">加载项目失败</option>';
         }
     })();
 
+    function showProjectSinks() {
+        document.getElementById('batch-project-sinks-count').textContent = projectSinks[projectSelect.value] || 0;
+    }
+
     // Auto-fill output name when project selection changes
     projectSelect.addEventListener('change', () => {
+        showProjectSinks();
         const id = projectSelect.value;
         if (id && projectsMap[id] && !outputNameInput.value.trim()) {
             outputNameInput.value = projectsMap[id];
@@ -144,6 +335,8 @@ App.registerPage('batch', function(container) {
 
     let pollTimer = null;
     let lastLogMessage = '';
+    let currentJobId = '';
+    let currentOutputDir = '';
 
     // Clean up polling timer when navigating away from this page.
     // Use a hashchange listener that auto-removes itself.
@@ -164,39 +357,192 @@ App.registerPage('batch', function(container) {
         const inputDir = inputDirInput.value.trim();
         const outputDir = outputDirInput.value.trim();
         const outputName = outputNameInput.value.trim() || (projectsMap[projectId] || '');
+        const workers = parseInt(workersInput.value, 10) || 1;
 
         if (!projectId) { showAlert('请选择项目'); return; }
         if (!inputDir) { showAlert('请选择输入目录'); return; }
         if (!outputDir) { showAlert('请选择输出目录'); return; }
-
-        startBtn.disabled = true;
-        progressSection.style.display = 'block';
-        resultSection.style.display = 'none';
-        logArea.textContent = '';
-        progressBar.style.width = '0%';
-        progressText.textContent = '0%';
+        const sinksOnly = document.getElementById('batch-sinks-only').checked;
+        const formats = sinksOnly ? [] : Array.from(document.querySelectorAll('#batch-formats input:checked')).map(el => el.value);
+        if (!sinksOnly && formats.length === 0) { showAlert('请至少选择一种输出格式'); return; }
+        let timeZones = null;
+        if (zonesToggle.checked) {
+            try {
+                timeZones = readTimeZoneFields('batch-zones');
+            } catch (err) {
+                showAlert(err.message);
+                return;
+            }
+        }
 
         try {
-            await window.go.main.App.RunBatch(projectId, inputDir, outputDir, outputName);
+            const jobId = await window.go.main.App.RunBatchWithOptions(projectId, {
+                input_dir: inputDir,
+                output_dir: outputDir,
+                output_file_name: outputName,
+                workers: workers,
+                incremental: incrementalToggle.checked,
+                watch: watchToggle.checked,
+                watch_debounce: parseInt(watchDebounceInput.value, 10) || 0,
+                filter: buildFilter(),
+                formats: formats,
+                max_workbook_rows: parseInt(maxWorkbookRowsInput.value, 10) || 0,
+                sinks: sinksToggle.checked ? runSinks : [],
+                no_project_sinks: !document.getElementById('batch-project-sinks').checked,
+                sinks_only: sinksOnly,
+                enrich: buildEnrich(),
+                time_zones: timeZones,
+                summary: document.getElementById('batch-summary').checked && !incrementalToggle.checked && !watchToggle.checked,
+            });
+            currentOutputDir = outputDir;
+            watchJob(jobId);
             appendLog('批处理已启动...');
-            startPolling();
         } catch (err) {
+            progressSection.style.display = 'block';
             appendLog('启动失败: ' + err);
-            startBtn.disabled = false;
         }
+        loadJobs();
     });
 
+    const previewBtn = document.getElementById('batch-preview-btn');
+    const previewSection = document.getElementById('batch-preview-section');
+    const previewContent = document.getElementById('batch-preview-content');
+    let currentPreviewId = '';
+
+    previewBtn.addEventListener('click', async () => {
+        const projectId = projectSelect.value;
+        const inputDir = inputDirInput.value.trim();
+        if (!projectId) { showAlert('请选择项目'); return; }
+        if (!inputDir) { showAlert('请选择输入目录'); return; }
+
+        const files = document.getElementById('batch-preview-files').value.split(',').map(x => x.trim()).filter(x => x);
+        previewBtn.disabled = true;
+        previewSection.style.display = 'block';
+        previewContent.innerHTML = '<div class="text-sm text-muted">正在预览...</div>';
+        try {
+            const preview = await window.go.main.App.PreviewBatch(projectId, {
+                input_dir: inputDir,
+                files: files,
+                filter: buildFilter(),
+                enrich: buildEnrich(),
+                lines: parseInt(document.getElementById('batch-preview-lines').value, 10) || 0,
+            });
+            currentPreviewId = preview.id;
+            renderPreview(preview);
+        } catch (err) {
+            previewContent.innerHTML = '<div class="text-sm text-muted">预览失败: ' + escapeHtml(String(err)) + '</div>';
+        } finally {
+            previewBtn.disabled = false;
+        }
+    });
+
+    // renderPreview shows a table per previewed file, each with its own pager.
+    function renderPreview(preview) {
+        const tables = preview.tables || [];
+        if (tables.length === 0) {
+            previewContent.innerHTML = '<div class="text-sm text-muted">脚本未输出任何数据</div>';
+            return;
+        }
+        previewContent.innerHTML = tables.map((_, i) => '<div id="batch-preview-table-' + i + '"></div>').join('');
+        tables.forEach((t, i) => renderPreviewTable(i, t));
+    }
+
+    function renderPreviewTable(index, t) {
+        const el = document.getElementById('batch-preview-table-' + index);
+        if (!el) return;
+        const empty = new Set(t.empty_columns || []);
+        const title = t.file ? t.file : '工作表 ' + t.sheet;
+        let html = '<div class="text-xs text-muted mt-8 mb-8">' + escapeHtml(title) + ' — ' + t.total_rows + ' 行';
+        if (t.skipped_lines) html += '，跳过 ' + t.skipped_lines + ' 行';
+        if (empty.size > 0) html += '，' + empty.size + ' 列全为空';
+        html += '</div>';
+
+        if (t.columns.length > 0) {
+            html += '<div style="overflow-x:auto;"><table class="table"><thead><tr>';
+            t.columns.forEach((c, i) => {
+                const name = escapeHtml(c || '(列 ' + (i + 1) + ')');
+                html += '<th' + (empty.has(i) ? ' class="text-muted" title="该列全为空"' : '') + '>' + name + (empty.has(i) ? ' ∅' : '') + '</th>';
+            });
+            html += '</tr></thead><tbody>';
+            for (const row of t.rows) {
+                html += '<tr>' + row.map(v => '<td class="text-sm">' + escapeHtml(v) + '</td>').join('') + '</tr>';
+            }
+            html += '</tbody></table></div>';
+        } else if (t.file) {
+            html += '<div class="text-sm text-muted">未找到名为该文件的工作表</div>';
+        }
+
+        const pages = Math.max(1, Math.ceil(t.total_rows / t.page_size));
+        if (pages > 1) {
+            html += '<div class="flex-between text-xs text-muted mt-8">';
+            html += '<button class="btn btn-default btn-sm" data-page="' + (t.page - 1) + '"' + (t.page === 0 ? ' disabled' : '') + '>上一页</button>';
+            html += '<span>第 ' + (t.page + 1) + ' / ' + pages + ' 页</span>';
+            html += '<button class="btn btn-default btn-sm" data-page="' + (t.page + 1) + '"' + (t.page + 1 >= pages ? ' disabled' : '') + '>下一页</button>';
+            html += '</div>';
+        }
+
+        const skipped = t.skipped || [];
+        if (skipped.length > 0) {
+            html += '<div class="text-xs text-muted mt-8 mb-8">被跳过的行</div>';
+            html += '<table class="table"><thead><tr><th>行号</th><th>原因</th><th>内容</th></tr></thead><tbody>';
+            for (const s of skipped) {
+                html += '<tr><td class="text-sm">' + s.line + '</td><td class="text-sm">' + escapeHtml(s.reason || '') + '</td><td class="text-sm">' + escapeHtml(s.text) + '</td></tr>';
+            }
+            html += '</tbody></table>';
+        }
+        el.innerHTML = html;
+
+        el.querySelectorAll('button[data-page]').forEach(btn => {
+            btn.addEventListener('click', async () => {
+                try {
+                    const page = await window.go.main.App.GetPreviewPage(currentPreviewId, index, parseInt(btn.dataset.page, 10));
+                    renderPreviewTable(index, page);
+                } catch (err) {
+                    showAlert('加载预览失败: ' + err);
+                }
+            });
+        });
+    }
+
+    cancelBtn.addEventListener('click', async () => {
+        if (!currentJobId) return;
+        try {
+            await window.go.main.App.CancelJob(currentJobId);
+            appendLog('已请求取消任务');
+        } catch (err) {
+            appendLog('取消失败: ' + err);
+        }
+    });
+
+    document.getElementById('refresh-jobs-btn').addEventListener('click', loadJobs);
+    loadJobs();
+
+    // watchJob switches the progress panel to the given job and starts polling it.
+    function watchJob(jobId) {
+        currentJobId = jobId;
+        progressSection.style.display = 'block';
+        resultSection.style.display = 'none';
+        cancelBtn.style.display = '';
+        logArea.textContent = '';
+        lastLogMessage = '';
+        progressBar.style.width = '0%';
+        progressText.textContent = '0%';
+        fileInfo.textContent = '';
+        startPolling();
+    }
+
     function startPolling() {
         if (pollTimer) clearInterval(pollTimer);
         pollTimer = setInterval(async () => {
             try {
-                const p = await window.go.main.App.GetBatchProgress();
+                const p = await window.go.main.App.GetBatchProgress(currentJobId);
                 updateProgress(p);
-                if (p.status === 'completed' || p.status === 'failed') {
+                if (p.status === 'completed' || p.status === 'degraded' || p.status === 'failed' || p.status === 'cancelled' || p.status === 'interrupted') {
                     clearInterval(pollTimer);
                     pollTimer = null;
-                    startBtn.disabled = false;
+                    cancelBtn.style.display = 'none';
                     showResult(p);
+                    loadJobs();
                 }
             } catch (err) {
                 appendLog('获取进度失败: ' + err);
@@ -204,24 +550,100 @@ App.registerPage('batch', function(container) {
         }, 1000);
     }
 
-    function updateProgress(p) {
-        const pct = Math.round((p.progress || 0) * 100);
-        progressBar.style.width = pct + '%';
-        progressText.textContent = pct + '%';
+    async function loadJobs() {
+        try {
+            const jobs = await window.go.main.App.ListJobs();
+            renderJobs(jobs || []);
+        } catch (err) {
+            jobsList.innerHTML = '<div class="alert alert-error">' + escapeHtml(String(err)) + '</div>';
+        }
+    }
 
-        if (p.current_file) {
-            fileInfo.textContent = '当前: ' + p.current_file;
+    function renderJobs(jobs) {
+        if (jobs.length === 0) {
+            jobsList.innerHTML = '<div class="text-sm text-muted">暂无任务</div>';
+            return;
         }
+        let html = '<table class="table"><thead><tr>';
+        html += '<th>创建时间</th><th>项目</th><th>输入目录</th><th>状态</th><th>进度</th><th>操作</th>';
+        html += '</tr></thead><tbody>';
+        jobs.forEach(j => {
+            const pct = Math.round(((j.progress && j.progress.progress) || 0) * 100);
+            const active = j.status === 'queued' || j.status === 'running';
+            html += '<tr>';
+            html += '<td class="text-sm">' + new Date(j.created_at).toLocaleString() + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(j.project_name || j.project_id.substring(0, 8)) + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(j.params.input_dir) + '</td>';
+            const watching = j.status === 'running' && j.progress && j.progress.status === 'watching';
+            html += '<td>' + statusBadge(watching ? 'watching' : j.status) + '</td>';
+            html += '<td class="text-sm">' + pct + '%</td>';
+            html += '<td><div class="btn-group">';
+            html += '<button class="btn btn-default btn-sm job-view-btn" data-id="' + escapeHtml(j.id) + '" data-output="' + escapeHtml(j.params.output_dir) + '">查看</button>';
+            if (active) {
+                html += '<button class="btn btn-danger btn-sm job-cancel-btn" data-id="' + escapeHtml(j.id) + '">' + (j.params.watch ? '停止' : '取消') + '</button>';
+            } else {
+                html += '<button class="btn btn-default btn-sm job-delete-btn" data-id="' + escapeHtml(j.id) + '">删除</button>';
+            }
+            html += '</div></td></tr>';
+        });
+        html += '</tbody></table>';
+        jobsList.innerHTML = html;
 
+        jobsList.querySelectorAll('.job-view-btn').forEach(btn => {
+            btn.addEventListener('click', () => {
+                currentOutputDir = btn.dataset.output;
+                watchJob(btn.dataset.id);
+            });
+        });
+        jobsList.querySelectorAll('.job-delete-btn').forEach(btn => {
+            btn.addEventListener('click', async () => {
+                try {
+                    await window.go.main.App.DeleteJob(btn.dataset.id);
+                } catch (err) {
+                    showError('删除失败: ' + err);
+                }
+                loadJobs();
+            });
+        });
+        jobsList.querySelectorAll('.job-cancel-btn').forEach(btn => {
+            btn.addEventListener('click', async () => {
+                try {
+                    await window.go.main.App.CancelJob(btn.dataset.id);
+                } catch (err) {
+                    showError('取消失败: ' + err);
+                }
+                loadJobs();
+            });
+        });
+    }
+
+    function statusBadge(status) {
         const statusMap = {
+            'queued': ['排队中', 'badge badge-info'],
             'running': ['处理中', 'badge badge-info'],
+            'watching': ['监控中', 'badge badge-success'],
             'completed': ['已完成', 'badge badge-success'],
+            'degraded': ['已完成（降级）', 'badge badge-warning'],
             'failed': ['失败', 'badge badge-error'],
             'fixing': ['修复中', 'badge badge-warning'],
+            'cancelled': ['已取消', 'badge badge-warning'],
+            'interrupted': ['已中断', 'badge badge-warning'],
             'idle': ['空闲', 'badge badge-info']
         };
-        const [label, cls] = statusMap[p.status] || [p.status, 'badge badge-info'];
-        statusBadge.innerHTML = '<span class="' + cls + '">' + label + '</span>';
+        const [label, cls] = statusMap[status] || [status, 'badge badge-info'];
+        return '<span class="' + cls + '">' + label + '</span>';
+    }
+
+    function updateProgress(p) {
+        const pct = Math.round((p.progress || 0) * 100);
+        progressBar.style.width = pct + '%';
+        progressText.textContent = pct + '%';
+
+        if (p.current_file) {
+            fileInfo.textContent = '当前: ' + p.current_file + (p.rows ? '（已写入 ' + p.rows + ' 行）' : '');
+        }
+
+        statusBadgeEl.innerHTML = statusBadge(p.status);
 
         if (p.message && p.message !== lastLogMessage) {
             appendLog(p.message);
@@ -229,12 +651,180 @@ App.registerPage('batch', function(container) {
         }
     }
 
+    // showJobResult adds the parts of the job's result that progress
+    // updates don't carry.
+    async function showJobResult() {
+        let job;
+        try {
+            job = await window.go.main.App.GetJob(currentJobId);
+        } catch (err) {
+            return;
+        }
+        const result = (job && job.result) || {};
+        showOutputs(result.outputs || []);
+        showSheets(result.sheets || []);
+        resultContent.insertAdjacentHTML('beforeend', sinkResultsHtml(result.sinks));
+        resultContent.insertAdjacentHTML('beforeend', summaryHtml(result.summary));
+        resultContent.insertAdjacentHTML('beforeend', qualityHtml(result.quality));
+        resultContent.insertAdjacentHTML('beforeend', timeZonesHtml(result.time_zones));
+        showRejected(result);
+        showSkipped(result.skipped || []);
+        showRepair(currentJobId, result);
+    }
+
+    // showRepair shows the code a runtime repair produced as a diff against
+    // the project's code and lets the user save it to the project.
+    async function showRepair(jobId, result) {
+        if (!result.repaired_code) return;
+        if (result.code_applied) {
+            resultContent.insertAdjacentHTML('beforeend', '<div class="alert alert-info mt-8">运行中修复的代码已保存到项目，原代码保留在历史版本中</div>');
+            return;
+        }
+        let diff;
+        try {
+            diff = await window.go.main.App.GetRepairDiff(jobId);
+        } catch (err) {
+            return;
+        }
+
+        let html = '<div id="repair-section" class="mt-8">';
+        html += '<div class="text-xs text-muted mt-8 mb-8">运行中修复了代码，与项目代码的差异如下：</div>';
+        html += '<div class="code-diff">';
+        for (const d of diff || []) {
+            const sign = d.op === 'add' ? '+ ' : d.op === 'remove' ? '- ' : '  ';
+            html += '<div class="diff-line diff-' + d.op + '">' + escapeHtml(sign + d.text) + '</div>';
+        }
+        html += '</div>';
+        html += '<button class="btn btn-primary btn-sm mt-8" id="apply-repair-btn">采用修复后的代码</button>';
+        html += '</div>';
+        resultContent.insertAdjacentHTML('beforeend', html);
+
+        document.getElementById('apply-repair-btn').addEventListener('click', async () => {
+            try {
+                await window.go.main.App.ApplyRepairedCode(jobId);
+                document.getElementById('repair-section').innerHTML = '<div class="alert alert-success">已保存到项目，原代码保留在历史版本中</div>';
+            } catch (err) {
+                showError('保存修复代码失败: ' + err);
+            }
+        });
+    }
+
+    // showOutputs lists the files the run wrote in its output formats.
+    function showOutputs(outputs) {
+        if (outputs.length === 0) return;
+        let html = '<div class="text-xs text-muted mt-8 mb-8">已写入 ' + outputs.length + ' 个输出文件：</div>';
+        html += '<ul class="text-xs">';
+        for (const o of outputs) {
+            html += '<li>' + escapeHtml(o) + '</li>';
+        }
+        html += '</ul>';
+        resultContent.insertAdjacentHTML('beforeend', html);
+    }
+
+    // showSheets lists where each file's rows went when a file was split
+    // over several sheets or the rows rolled over to further workbooks.
+    function showSheets(sheets) {
+        const files = new Set(sheets.map(s => s.file));
+        const workbooks = new Set(sheets.map(s => s.workbook));
+        if (sheets.length === files.size && workbooks.size <= 1) return;
+        let html = '<div class="text-xs text-muted mt-8 mb-8">行数超出上限，以下文件分布在多个工作表或工作簿中：</div>';
+        html += '<table class="table"><thead><tr><th>文件</th><th>工作簿</th><th>工作表</th><th>行数</th></tr></thead><tbody>';
+        for (const s of sheets) {
+            html += '<tr><td class="text-sm">' + escapeHtml(s.file) + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(s.workbook) + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(s.sheet) + '</td>';
+            html += '<td class="text-sm">' + s.rows + '</td></tr>';
+        }
+        html += '</tbody></table>';
+        resultContent.insertAdjacentHTML('beforeend', html);
+    }
+
+    // showRejected points to the quarantine file of rejected lines.
+    function showRejected(result) {
+        if (!result.rejected_path) return;
+        let html = '<div class="text-xs text-muted mt-8 mb-8">';
+        if (result.coverage !== undefined && result.coverage !== null) {
+            html += '解析覆盖率 ' + result.coverage.toFixed(1) + '%，';
+        }
+        html += '无法解析的行已隔离到 ' + escapeHtml(result.rejected_path) + '</div>';
+        resultContent.insertAdjacentHTML('beforeend', html);
+    }
+
+    // showSkipped lists the files an incremental run left untouched.
+    function showSkipped(skipped) {
+        if (skipped.length === 0) return;
+
+        let html = '<div class="text-xs text-muted mt-8 mb-8">已跳过 ' + skipped.length + ' 个文件</div>';
+        html += '<table class="table"><thead><tr><th>文件</th><th>原因</th></tr></thead><tbody>';
+        for (const s of skipped) {
+            html += '<tr><td>' + escapeHtml(s.file) + '</td><td>' + escapeHtml(skipReason(s.reason)) + '</td></tr>';
+        }
+        html += '</tbody></table>';
+        resultContent.insertAdjacentHTML('beforeend', html);
+    }
+
+    // showFiles lists how each processed file went.
+    function showFiles(files) {
+        if (files.length === 0) return;
+
+        let html = '<div class="text-xs text-muted mt-8 mb-8">逐文件结果</div>';
+        html += '<table class="table"><thead><tr><th>文件</th><th>状态</th><th>行数</th><th>跳过行</th><th>覆盖率</th><th>警告</th><th>耗时</th><th>大小</th></tr></thead><tbody>';
+        for (const f of files) {
+            html += '<tr>';
+            html += '<td>' + escapeHtml(f.file) + (f.error ? '<div class="text-xs text-muted">' + escapeHtml(f.error) + '</div>' : '') + '</td>';
+            html += '<td>' + fileStatusBadge(f.status) + '</td>';
+            html += '<td class="text-sm">' + (f.rows === undefined || f.rows === null ? '-' : f.rows) + '</td>';
+            html += '<td class="text-sm">' + (f.skipped_lines || 0) + '</td>';
+            html += '<td class="text-sm">' + (f.coverage === undefined || f.coverage === null ? '-' : f.coverage.toFixed(1) + '%') + '</td>';
+            html += '<td class="text-sm">' + (f.warnings || 0) + '</td>';
+            html += '<td class="text-sm">' + ((f.duration_ms || 0) / 1000).toFixed(1) + ' s</td>';
+            html += '<td class="text-sm">' + formatBytes(f.bytes || 0) + '</td>';
+            html += '</tr>';
+        }
+        html += '</tbody></table>';
+        resultContent.insertAdjacentHTML('beforeend', html);
+    }
+
+    function fileStatusBadge(status) {
+        const statusMap = {
+            'succeeded': ['成功', 'badge badge-success'],
+            'empty': ['无数据', 'badge badge-warning'],
+            'failed': ['失败', 'badge badge-error']
+        };
+        const [label, cls] = statusMap[status] || [status, 'badge badge-info'];
+        return '<span class="' + cls + '">' + label + '</span>';
+    }
+
+    function formatBytes(n) {
+        if (n < 1024) return n + ' B';
+        if (n < 1024 * 1024) return (n / 1024).toFixed(1) + ' KB';
+        return (n / 1024 / 1024).toFixed(1) + ' MB';
+    }
+
+    function limitLabel(failure) {
+        switch (failure) {
+            case 'timeout': return '超出运行时间上限';
+            case 'idle': return '长时间无进度';
+            case 'memory': return '超出内存上限';
+            default: return '超出资源限制';
+        }
+    }
+
+    function skipReason(reason) {
+        switch (reason) {
+            case 'unchanged since last run': return '自上次处理后未变更';
+            case 'content unchanged (only modification time differs)': return '内容未变（仅修改时间不同）';
+            default: return reason;
+        }
+    }
+
     function showResult(p) {
         resultSection.style.display = 'block';
         const total = p.total_files || 0;
+        const files = p.files || [];
         const processed = p.processed || 0;
-        const failed = p.failed || 0;
-        const succeeded = processed - failed;
+        const failed = files.length > 0 ? files.filter(f => f.status !== 'succeeded').length : (p.failed || 0);
+        const succeeded = files.length > 0 ? files.length - failed : processed - failed;
 
         let html = '<div class="stat-row">';
         html += '<div class="stat-card"><div class="stat-value">' + total + '</div><div class="stat-label">总文件数</div></div>';
@@ -242,23 +832,34 @@ App.registerPage('batch', function(container) {
         html += '<div class="stat-card"><div class="stat-value danger">' + failed + '</div><div class="stat-label">失败</div></div>';
         html += '</div>';
 
-        if (p.status === 'completed') {
-            html += '<div class="alert alert-success">批量处理完成</div>';
+        if (p.status === 'completed' || p.status === 'degraded') {
+            if (p.status === 'degraded') {
+                html += '<div class="alert alert-warning">批量处理完成，但无法解析的行过多，结果可能不完整</div>';
+            } else {
+                html += '<div class="alert alert-success">批量处理完成</div>';
+            }
             html += '<button class="btn btn-primary btn-sm" id="open-output-dir-btn" style="margin-top:8px;">'
                 + '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" style="vertical-align:-2px;margin-right:4px;">'
                 + '<path d="M22 19a2 2 0 01-2 2H4a2 2 0 01-2-2V5a2 2 0 012-2h5l2 3h9a2 2 0 012 2z" stroke-linecap="round" stroke-linejoin="round"/></svg>'
                 + '打开输出目录</button>';
+        } else if (p.status === 'failed' && p.failure) {
+            html += '<div class="alert alert-error">' + limitLabel(p.failure) + '，脚本已被终止（未尝试自动修复）'
+                + (p.message ? ': ' + escapeHtml(p.message) : '') + '</div>';
         } else if (p.status === 'failed') {
             html += '<div class="alert alert-error">批量处理失败' + (p.message ? ': ' + escapeHtml(p.message) : '') + '</div>';
+        } else if (p.status === 'cancelled') {
+            html += '<div class="alert alert-warning">批量处理已取消</div>';
         }
 
         resultContent.innerHTML = html;
+        showFiles(files);
+        if (p.status === 'completed' || p.status === 'degraded') showJobResult();
 
         const openBtn = document.getElementById('open-output-dir-btn');
         if (openBtn) {
             openBtn.addEventListener('click', async () => {
                 try {
-                    await window.go.main.App.OpenDirectory(outputDirInput.value.trim());
+                    await window.go.main.App.OpenDirectory(currentOutputDir || outputDirInput.value.trim());
                 } catch (err) {
                     showError('打开目录失败: ' + err);
                 }
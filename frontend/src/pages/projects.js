@@ -40,6 +40,21 @@ App.registerPage('projects', function(container) {
                     <label>Python 代码</label>
                     <textarea id="detail-code" rows="14"></textarea>
                 </div>
+                <div class="form-group" id="revisions-group" style="display:none;">
+                    <label>历史版本</label>
+                    <div id="detail-revisions"></div>
+                </div>
+                <div class="form-group">
+                    <label>输出目标（每次运行将解析结果同时发送到 Elasticsearch/OpenSearch、Loki、Webhook、Splunk 或 ClickHouse，可在批量处理时关闭）</label>
+                    <div id="detail-sinks"></div>
+                </div>
+                <div class="form-group">
+                    <label>列类型（可选；类型为 string、integer、number、boolean、datetime、ip，未声明的列按值推断，声明为 ip 的列在 IP 信息补充时使用）</label>
+                    <div class="input-with-btn">
+                        <input type="text" id="detail-schema" placeholder="如 src_ip=ip, dst_ip=ip, time=datetime">
+                        <button class="btn btn-default btn-sm" id="save-schema-btn">保存列类型</button>
+                    </div>
+                </div>
                 <div class="btn-group">
                     <button class="btn btn-primary btn-sm" id="save-code-btn">保存代码</button>
                     <button class="btn btn-default btn-sm" id="rerun-btn">重新运行</button>
@@ -47,6 +62,50 @@ App.registerPage('projects', function(container) {
                 </div>
                 <div id="detail-message" class="mt-12"></div>
             </div>
+            <div class="card">
+                <div class="card-title">数据质量要求</div>
+                <p class="text-xs text-muted mb-8">每次运行后检查写出的行；填写百分比上限，留空表示不检查。空值与类型不符只检查所列的列（留空为全部列）</p>
+                <div class="input-with-btn">
+                    <input type="number" min="0" max="100" step="any" id="quality-max-empty" placeholder="空值 %">
+                    <input type="number" min="0" max="100" step="any" id="quality-max-mismatched" placeholder="类型不符 %">
+                    <input type="number" min="0" max="100" step="any" id="quality-max-time-failures" placeholder="时间解析失败 %">
+                </div>
+                <div class="input-with-btn mt-8">
+                    <input type="number" min="0" max="100" step="any" id="quality-max-out-of-order" placeholder="时间倒序 %">
+                    <input type="number" min="0" max="100" step="any" id="quality-max-future" placeholder="未来时间 %">
+                    <input type="number" min="0" max="100" step="any" id="quality-max-duplicates" placeholder="重复行 %">
+                </div>
+                <div class="input-with-btn mt-8">
+                    <input type="text" id="quality-columns" placeholder="检查的列，逗号分隔，如 status, src_ip">
+                    <select class="form-select" id="quality-action">
+                        <option value="degrade">未达标时：标记降级</option>
+                        <option value="fail">未达标时：运行失败</option>
+                        <option value="repair">未达标时：自动修复代码后重新运行</option>
+                    </select>
+                    <button class="btn btn-default btn-sm" id="save-quality-btn">保存质量要求</button>
+                </div>
+                <div id="quality-message" class="mt-12"></div>
+            </div>
+            <div class="card">
+                <div class="card-title">时区</div>
+                <p class="text-xs text-muted mb-8">设置目标时区后，每次运行将时间列转换到目标时区：不带时差的时间按日志时区（或匹配文件模式的时区）解释，带时差的时间保持时刻不变；仅有日期的值不转换。批量处理时可为单次运行另行设置</p>
+                ${timeZoneFieldsHtml('zones')}
+                <div class="btn-group mt-8">
+                    <button class="btn btn-default btn-sm" id="save-zones-btn">保存时区</button>
+                </div>
+                <div id="zones-message" class="mt-12"></div>
+            </div>
+            <div class="card">
+                <div class="card-title">定时运行</div>
+                <p class="text-xs text-muted mb-8">应用打开期间按 cron 表达式（本地时间，如 0 2 * * * 或 @daily）自动运行；输出文件名可使用 {project}、{date}、{time}、{year}、{month}、{day}、{hour}、{minute}，增量运行请使用不含时间的文件名</p>
+                <div id="detail-schedules"></div>
+            </div>
+            <div class="card">
+                <div class="card-title">运行历史</div>
+                <div id="runs-filter" class="text-sm text-muted mb-8" style="display:none;"></div>
+                <div id="detail-runs"></div>
+                <div id="run-detail" class="mt-12" style="display:none;"></div>
+            </div>
             <div id="rerun-section" style="display:none;">
                 <div class="card">
                     <div class="card-title">重新运行</div>
@@ -135,6 +194,14 @@ App.registerPage('projects', function(container) {
             document.getElementById('detail-created').textContent = new Date(p.created_at).toLocaleString();
             document.getElementById('detail-sample').value = p.sample_data || '';
             document.getElementById('detail-code').value = p.code || '';
+            renderRevisions(p.revisions || []);
+            renderSinks(id, p.sinks || []);
+            document.getElementById('detail-schema').value = (p.schema || []).map(c => c.name + '=' + c.type).join(', ');
+            renderQuality(p.quality || {});
+            fillTimeZoneFields('zones', p.time_zones);
+            document.getElementById('zones-message').innerHTML = '';
+            loadSchedules(id);
+            loadRuns(id);
             document.getElementById('detail-message').innerHTML = '';
             document.getElementById('rerun-section').style.display = 'none';
             document.getElementById('rerun-output-name').value = p.name || '';
@@ -146,6 +213,372 @@ App.registerPage('projects', function(container) {
         }
     }
 
+    // renderRevisions lists the project's earlier code, newest first. Restoring
+    // one loads it into the editor; saving keeps the current code as a revision.
+    function renderRevisions(revisions) {
+        const group = document.getElementById('revisions-group');
+        group.style.display = revisions.length === 0 ? 'none' : 'block';
+        if (revisions.length === 0) return;
+
+        const reasons = { edit: '手动编辑', repair: '运行中修复' };
+        let html = '<table class="table"><thead><tr><th>被替换时间</th><th>替换原因</th><th>操作</th></tr></thead><tbody>';
+        for (let i = revisions.length - 1; i >= 0; i--) {
+            const r = revisions[i];
+            html += '<tr>';
+            html += '<td class="text-sm">' + new Date(r.replaced_at).toLocaleString() + '</td>';
+            html += '<td class="text-sm">' + escapeHtml(reasons[r.reason] || r.reason) + '</td>';
+            html += '<td><button class="btn btn-default btn-sm restore-revision-btn" data-index="' + i + '">载入</button></td>';
+            html += '</tr>';
+        }
+        html += '</tbody></table>';
+        const el = document.getElementById('detail-revisions');
+        el.innerHTML = html;
+        el.querySelectorAll('.restore-revision-btn').forEach(btn => {
+            btn.addEventListener('click', () => {
+                document.getElementById('detail-code').value = revisions[btn.dataset.index].code;
+            });
+        });
+    }
+
+    // renderSinks shows the project's sinks; changes are saved right away.
+    function renderSinks(projectId, sinks) {
+        renderSinkEditor(document.getElementById('detail-sinks'), sinks, async updated => {
+            try {
+                await window.go.main.App.UpdateProjectSinks(projectId, updated);
+                renderSinks(projectId, updated);
+            } catch (err) {
+                showError('保存输出目标失败: ' + err);
+            }
+        });
+    }
+
+    // Save the declared column types, written as name=type pairs
+    document.getElementById('save-schema-btn').addEventListener('click', async () => {
+        if (!currentProjectId) return;
+        const columns = [];
+        for (const pair of document.getElementById('detail-schema').value.split(',').map(x => x.trim()).filter(x => x)) {
+            const eq = pair.lastIndexOf('=');
+            if (eq <= 0) { showAlert('列类型格式应为 列名=类型：' + pair); return; }
+            columns.push({ name: pair.substring(0, eq).trim(), type: pair.substring(eq + 1).trim() });
+        }
+        const msgEl = document.getElementById('detail-message');
+        try {
+            await window.go.main.App.UpdateProjectSchema(currentProjectId, columns);
+            msgEl.innerHTML = '<div class="alert alert-success">列类型已保存</div>';
+            setTimeout(() => { msgEl.innerHTML = ''; }, 3000);
+        } catch (err) {
+            msgEl.innerHTML = '<div class="alert alert-error">保存列类型失败: ' + escapeHtml(String(err)) + '</div>';
+        }
+    });
+
+    // Quality thresholds and the inputs they are edited in
+    const qualityInputs = {
+        max_empty: 'quality-max-empty',
+        max_mismatched: 'quality-max-mismatched',
+        max_time_failures: 'quality-max-time-failures',
+        max_out_of_order: 'quality-max-out-of-order',
+        max_future: 'quality-max-future',
+        max_duplicates: 'quality-max-duplicates'
+    };
+
+    function renderQuality(quality) {
+        for (const [key, id] of Object.entries(qualityInputs)) {
+            const v = quality[key];
+            document.getElementById(id).value = v === undefined || v === null ? '' : v;
+        }
+        document.getElementById('quality-columns').value = (quality.columns || []).join(', ');
+        document.getElementById('quality-action').value = quality.action || 'degrade';
+        document.getElementById('quality-message').innerHTML = '';
+    }
+
+    document.getElementById('save-quality-btn').addEventListener('click', async () => {
+        if (!currentProjectId) return;
+        const quality = {
+            columns: document.getElementById('quality-columns').value.split(',').map(x => x.trim()).filter(x => x),
+            action: document.getElementById('quality-action').value
+        };
+        for (const [key, id] of Object.entries(qualityInputs)) {
+            const v = document.getElementById(id).value.trim();
+            if (v === '') continue;
+            const n = parseFloat(v);
+            if (isNaN(n) || n < 0 || n > 100) { showAlert('质量要求应为 0 到 100 之间的百分比'); return; }
+            quality[key] = n;
+        }
+        const msgEl = document.getElementById('quality-message');
+        try {
+            await window.go.main.App.UpdateProjectQuality(currentProjectId, quality);
+            msgEl.innerHTML = '<div class="alert alert-success">质量要求已保存</div>';
+            setTimeout(() => { msgEl.innerHTML = ''; }, 3000);
+        } catch (err) {
+            msgEl.innerHTML = '<div class="alert alert-error">保存质量要求失败: ' + escapeHtml(String(err)) + '</div>';
+        }
+    });
+
+    document.getElementById('save-zones-btn').addEventListener('click', async () => {
+        if (!currentProjectId) return;
+        let zones;
+        try {
+            zones = readTimeZoneFields('zones');
+        } catch (err) {
+            showAlert(err.message);
+            return;
+        }
+        const msgEl = document.getElementById('zones-message');
+        try {
+            await window.go.main.App.UpdateProjectTimeZones(currentProjectId, zones);
+            msgEl.innerHTML = '<div class="alert alert-success">时区已保存</div>';
+            setTimeout(() => { msgEl.innerHTML = ''; }, 3000);
+        } catch (err) {
+            msgEl.innerHTML = '<div class="alert alert-error">保存时区失败: ' + escapeHtml(String(err)) + '</div>';
+        }
+    });
+
+    // loadSchedules shows the project's schedules with when they run next
+    // and what they did last, and a form to add one. Changes are saved
+    // right away.
+    async function loadSchedules(projectId) {
+        const el = document.getElementById('detail-schedules');
+        let statuses;
+        try {
+            statuses = await window.go.main.App.GetScheduleStatus(projectId) || [];
+        } catch (err) {
+            el.innerHTML = '<div class="alert alert-error">' + escapeHtml(String(err)) + '</div>';
+            return;
+        }
+        const schedules = statuses.map(st => st.schedule);
+        const time = t => t ? new Date(t).toLocaleString() : '-';
+
+        let html = '';
+        if (statuses.length > 0) {
+            html += '<table class="table"><thead><tr><th>Cron</th><th>输入 / 输出</th><th>错过的运行</th><th>下次运行</th><th>上次运行</th><th>操作</th></tr></thead><tbody>';
+            statuses.forEach((st, i) => {
+                const s = st.schedule;
+                html += '<tr><td class="text-sm"><code>' + escapeHtml(s.cron) + '</code>' + (s.incremental ? ' <span class="text-xs text-muted">增量</span>' : '') + (s.summary ? ' <span class="text-xs text-muted">汇总</span>' : '') + '</td>';
+                html += '<td class="text-sm">' + escapeHtml(s.input_dir) + '<br>→ ' + escapeHtml(s.output_dir) + ' / ' + escapeHtml(s.output_name || '{project}_{date}_{time}') + '</td>';
+                html += '<td class="text-sm">' + (s.missed_runs === 'catch_up' ? '启动时补运行' : '跳过') + (st.state.missed ? '<br><span class="text-xs text-muted">已错过 ' + st.state.missed + ' 次</span>' : '') + '</td>';
+                html += '<td class="text-sm">' + (s.enabled ? time(st.next) : '<span class="badge badge-info">已停用</span>') + '</td>';
+                html += '<td class="text-sm">' + time(st.state.last_run);
+                if (st.state.last_error) html += '<br><span class="text-xs" style="color:var(--danger-text);">' + escapeHtml(st.state.last_error) + '</span>';
+                html += '</td>';
+                html += '<td><div class="btn-group">';
+                html += '<button class="btn btn-default btn-sm schedule-toggle-btn" data-index="' + i + '">' + (s.enabled ? '停用' : '启用') + '</button>';
+                html += '<button class="btn btn-default btn-sm schedule-runs-btn" data-index="' + i + '">历史</button>';
+                html += '<button class="btn btn-danger btn-sm schedule-remove-btn" data-index="' + i + '">删除</button>';
+                html += '</div></td></tr>';
+            });
+            html += '</tbody></table>';
+        }
+        html += `
+            <div class="input-with-btn mt-8">
+                <input type="text" class="schedule-cron" placeholder="cron 表达式，如 0 2 * * *">
+                <select class="form-select schedule-missed">
+                    <option value="skip">错过的运行：跳过</option>
+                    <option value="catch_up">错过的运行：启动时补运行一次</option>
+                </select>
+                <label class="wizard-checkbox"><input type="checkbox" class="schedule-incremental"><span>增量运行</span></label>
+                <label class="wizard-checkbox"><input type="checkbox" class="schedule-summary" checked><span>生成数据汇总（增量运行不支持）</span></label>
+            </div>
+            <div class="input-with-btn mt-8">
+                <input type="text" class="schedule-input" placeholder="输入目录" readonly>
+                <button class="btn btn-default btn-sm schedule-input-btn">浏览...</button>
+                <input type="text" class="schedule-output" placeholder="输出目录" readonly>
+                <button class="btn btn-default btn-sm schedule-output-btn">浏览...</button>
+            </div>
+            <div class="input-with-btn mt-8">
+                <input type="text" class="schedule-name" placeholder="输出文件名（默认 {project}_{date}_{time}）">
+                <button class="btn btn-default btn-sm schedule-add-btn">添加定时运行</button>
+            </div>`;
+        el.innerHTML = html;
+
+        const q = cls => el.querySelector('.' + cls);
+        const save = async updated => {
+            try {
+                await window.go.main.App.UpdateProjectSchedules(projectId, updated);
+                loadSchedules(projectId);
+            } catch (err) {
+                showError('保存定时运行失败: ' + err);
+            }
+        };
+        el.querySelectorAll('.schedule-toggle-btn').forEach(btn => {
+            btn.addEventListener('click', () => {
+                const i = parseInt(btn.dataset.index, 10);
+                save(schedules.map((s, j) => j === i ? Object.assign({}, s, { enabled: !s.enabled }) : s));
+            });
+        });
+        el.querySelectorAll('.schedule-remove-btn').forEach(btn => {
+            btn.addEventListener('click', () => {
+                save(schedules.filter((_, i) => i !== parseInt(btn.dataset.index, 10)));
+            });
+        });
+        el.querySelectorAll('.schedule-runs-btn').forEach(btn => {
+            btn.addEventListener('click', () => {
+                const s = schedules[parseInt(btn.dataset.index, 10)];
+                loadRuns(projectId, s);
+            });
+        });
+        for (const [cls, title] of [['schedule-input', '选择输入目录'], ['schedule-output', '选择输出目录']]) {
+            q(cls + '-btn').addEventListener('click', async () => {
+                try {
+                    const dir = await window.go.main.App.SelectDirectory(title);
+                    if (dir) q(cls).value = dir;
+                } catch (_) {}
+            });
+        }
+        q('schedule-add-btn').addEventListener('click', () => {
+            const schedule = {
+                cron: q('schedule-cron').value.trim(),
+                input_dir: q('schedule-input').value.trim(),
+                output_dir: q('schedule-output').value.trim(),
+                output_name: q('schedule-name').value.trim(),
+                incremental: q('schedule-incremental').checked,
+                summary: q('schedule-summary').checked && !q('schedule-incremental').checked,
+                missed_runs: q('schedule-missed').value,
+                enabled: true
+            };
+            if (!schedule.cron) { showAlert('请填写 cron 表达式'); return; }
+            if (!schedule.input_dir || !schedule.output_dir) { showAlert('请选择输入和输出目录'); return; }
+            save(schedules.concat([schedule]));
+        });
+    }
+
+    // loadRuns lists the project's earlier batch runs, newest first; with a
+    // schedule, only the runs it started.
+    async function loadRuns(projectId, schedule) {
+        const el = document.getElementById('detail-runs');
+        const filterEl = document.getElementById('runs-filter');
+        document.getElementById('run-detail').style.display = 'none';
+        filterEl.style.display = schedule ? 'block' : 'none';
+        if (schedule) {
+            filterEl.innerHTML = '仅显示定时运行 <code>' + escapeHtml(schedule.cron) + '</code> 的记录 <button class="btn btn-default btn-sm" id="runs-show-all-btn">显示全部</button>';
+            document.getElementById('runs-show-all-btn').addEventListener('click', () => loadRuns(projectId));
+        }
+        let runs;
+        try {
+            runs = schedule
+                ? await window.go.main.App.ListScheduleRuns(projectId, schedule.id)
+                : await window.go.main.App.ListRuns(projectId);
+        } catch (err) {
+            el.innerHTML = '<div class="alert alert-error">' + escapeHtml(String(err)) + '</div>';
+            return;
+        }
+        if (!runs || runs.length === 0) {
+            el.innerHTML = '<div class="text-sm text-muted">暂无运行记录</div>';
+            return;
+        }
+
+        let html = '<table class="table"><thead><tr><th>开始时间</th><th>状态</th><th>文件</th><th>修复次数</th><th>耗时</th><th>操作</th></tr></thead><tbody>';
+        for (const r of runs) {
+            const seconds = (new Date(r.finished_at) - new Date(r.started_at)) / 1000;
+            html += '<tr>';
+            html += '<td class="text-sm">' + new Date(r.started_at).toLocaleString() + '</td>';
+            html += '<td>' + runStatusBadge(r.status) + (r.schedule_id ? ' <span class="badge badge-info">定时</span>' : '') + '</td>';
+            html += '<td class="text-sm">' + (r.succeeded || 0) + ' / ' + (r.total_files || 0) + '</td>';
+            html += '<td class="text-sm">' + (r.repair_attempts || 0) + '</td>';
+            html += '<td class="text-sm">' + seconds.toFixed(1) + ' s</td>';
+            html += '<td><div class="btn-group">';
+            html += '<button class="btn btn-default btn-sm run-view-btn" data-id="' + escapeHtml(r.id) + '">详情</button>';
+            html += '<button class="btn btn-default btn-sm run-rerun-btn" data-id="' + escapeHtml(r.id) + '">重新运行</button>';
+            html += '<button class="btn btn-default btn-sm run-open-btn" data-id="' + escapeHtml(r.id) + '">打开输出</button>';
+            html += '</div></td>';
+            html += '</tr>';
+        }
+        html += '</tbody></table>';
+        el.innerHTML = html;
+
+        el.querySelectorAll('.run-view-btn').forEach(btn => {
+            btn.addEventListener('click', () => showRun(projectId, btn.dataset.id));
+        });
+        el.querySelectorAll('.run-rerun-btn').forEach(btn => {
+            btn.addEventListener('click', async () => {
+                const msgEl = document.getElementById('detail-message');
+                try {
+                    await window.go.main.App.RerunRun(projectId, btn.dataset.id);
+                    msgEl.innerHTML = '<div class="alert alert-success">批处理已启动，请前往「批量处理」页面查看进度</div>';
+                } catch (err) {
+                    msgEl.innerHTML = '<div class="alert alert-error">' + escapeHtml(String(err)) + '</div>';
+                }
+            });
+        });
+        el.querySelectorAll('.run-open-btn').forEach(btn => {
+            btn.addEventListener('click', async () => {
+                try {
+                    await window.go.main.App.OpenRunOutput(projectId, btn.dataset.id);
+                } catch (err) {
+                    showError('打开输出失败: ' + err);
+                }
+            });
+        });
+    }
+
+    // showRun shows the parameters, per-file outcome and error output of a run.
+    async function showRun(projectId, runId) {
+        const el = document.getElementById('run-detail');
+        let r;
+        try {
+            r = await window.go.main.App.GetRun(projectId, runId);
+        } catch (err) {
+            showError('加载运行记录失败: ' + err);
+            return;
+        }
+
+        let html = '<div class="text-sm">';
+        if (r.scheduled_for) html += '<div>定时运行：计划于 ' + new Date(r.scheduled_for).toLocaleString() + '</div>';
+        html += '<div>输入目录：' + escapeHtml(r.params.input_dir) + '</div>';
+        if (r.outputs && r.outputs.length > 0) {
+            html += '<div>输出文件：' + r.outputs.map(escapeHtml).join('<br>') + '</div>';
+        } else {
+            html += '<div>输出文件：' + escapeHtml(r.output_file) + '</div>';
+        }
+        html += '<div>代码哈希：<code>' + escapeHtml((r.code_hash || '').substring(0, 12)) + '</code>';
+        if (r.repaired_code) html += '（运行中修复了代码）';
+        html += '</div>';
+        if (r.error) html += '<div class="alert alert-error mt-8">' + escapeHtml(r.error) + '</div>';
+        html += '</div>';
+        if (r.files && r.files.length > 0) {
+            html += '<table class="table mt-8"><thead><tr><th>文件</th><th>状态</th><th>行数</th><th>跳过行</th></tr></thead><tbody>';
+            for (const f of r.files) {
+                html += '<tr><td class="text-sm">' + escapeHtml(f.file) + '</td>';
+                html += '<td class="text-sm">' + escapeHtml(f.status) + '</td>';
+                html += '<td class="text-sm">' + (f.rows === undefined || f.rows === null ? '-' : f.rows) + '</td>';
+                html += '<td class="text-sm">' + (f.skipped_lines || 0) + '</td></tr>';
+            }
+            html += '</tbody></table>';
+        }
+        const sheets = r.sheets || [];
+        if (sheets.length > new Set(sheets.map(s => s.file)).size || new Set(sheets.map(s => s.workbook)).size > 1) {
+            html += '<div class="text-xs text-muted mt-8 mb-8">工作表分布</div>';
+            html += '<table class="table"><thead><tr><th>文件</th><th>工作簿</th><th>工作表</th><th>行数</th></tr></thead><tbody>';
+            for (const s of sheets) {
+                html += '<tr><td class="text-sm">' + escapeHtml(s.file) + '</td>';
+                html += '<td class="text-sm">' + escapeHtml(s.workbook) + '</td>';
+                html += '<td class="text-sm">' + escapeHtml(s.sheet) + '</td>';
+                html += '<td class="text-sm">' + s.rows + '</td></tr>';
+            }
+            html += '</tbody></table>';
+        }
+        html += sinkResultsHtml(r.sinks);
+        html += summaryHtml(r.summary);
+        html += qualityHtml(r.quality);
+        html += timeZonesHtml(r.time_zones);
+        if (r.stderr) {
+            html += '<div class="text-xs text-muted mt-8 mb-8">错误输出</div>';
+            html += '<div class="log-area">' + escapeHtml(r.stderr) + '</div>';
+        }
+        el.innerHTML = html;
+        el.style.display = 'block';
+    }
+
+    function runStatusBadge(status) {
+        const map = {
+            'completed': ['完成', 'badge badge-success'],
+            'degraded': ['降级', 'badge badge-warning'],
+            'failed': ['失败', 'badge badge-error'],
+            'cancelled': ['已取消', 'badge badge-info']
+        };
+        const [label, cls] = map[status] || [status, 'badge badge-info'];
+        return '<span class="' + cls + '">' + label + '</span>';
+    }
+
     document.getElementById('back-to-list-btn').addEventListener('click', () => {
         detailSection.style.display = 'none';
         listSection.style.display = 'block';
@@ -158,6 +591,10 @@ App.registerPage('projects', function(container) {
         const msgEl = document.getElementById('detail-message');
         try {
             await window.go.main.App.UpdateProjectCode(currentProjectId, code);
+            const p = await window.go.main.App.GetProject(currentProjectId);
+            renderRevisions(p.revisions || []);
+            renderSinks(currentProjectId, p.sinks || []);
+            loadRuns(currentProjectId);
             msgEl.innerHTML = '<div class="alert alert-success">代码已保存</div>';
             setTimeout(() => { msgEl.innerHTML = ''; }, 3000);
         } catch (err) {
@@ -54,7 +54,50 @@ App.registerPage('settings', function(container) {
                 </div>
             </div>
         </div>
+        <div class="card">
+            <div class="card-title">
+                <svg class="card-icon" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M3.055 11H5a2 2 0 012 2v1a2 2 0 002 2 2 2 0 012 2v2.945M8 3.935V5.5A2.5 2.5 0 0010.5 8h.5a2 2 0 012 2 2 2 0 104 0 2 2 0 012-2h1.064M15 20.488V18a2 2 0 012-2h3.064M21 12a9 9 0 11-18 0 9 9 0 0118 0z" stroke-linecap="round" stroke-linejoin="round"/></svg>
+                ${I18n.t('settings.geoip')}
+            </div>
+            <p class="text-sm text-muted">${I18n.t('settings.geoip_desc')}</p>
+            <div class="form-group">
+                <label for="geoip-city-db">${I18n.t('settings.geoip_city_db')}</label>
+                <div class="input-with-btn">
+                    <input type="text" id="geoip-city-db" placeholder="GeoLite2-City.mmdb / GeoLite2-Country.mmdb">
+                    <button class="btn btn-default btn-sm" id="browse-geoip-city-btn">${I18n.t('common.browse')}</button>
+                </div>
+            </div>
+            <div class="form-group" style="margin-bottom:0">
+                <label for="geoip-asn-db">${I18n.t('settings.geoip_asn_db')}</label>
+                <div class="input-with-btn">
+                    <input type="text" id="geoip-asn-db" placeholder="GeoLite2-ASN.mmdb">
+                    <button class="btn btn-default btn-sm" id="browse-geoip-asn-btn">${I18n.t('common.browse')}</button>
+                </div>
+            </div>
+        </div>
         <div id="settings-message" class="mt-12"></div>
+        <div class="card">
+            <div class="card-title">
+                <svg class="card-icon" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M12 8v4l3 3m6-3a9 9 0 11-18 0 9 9 0 0118 0z" stroke-linecap="round" stroke-linejoin="round"/></svg>
+                ${I18n.t('settings.limits')}
+            </div>
+            <div class="form-group">
+                <label for="script-timeout">${I18n.t('settings.script_timeout')}</label>
+                <input type="number" id="script-timeout" min="0" placeholder="${I18n.t('settings.no_limit_placeholder')}">
+            </div>
+            <div class="form-group">
+                <label for="script-idle">${I18n.t('settings.script_idle')}</label>
+                <input type="number" id="script-idle" min="0" placeholder="${I18n.t('settings.no_limit_placeholder')}">
+            </div>
+            <div class="form-group">
+                <label for="script-memory">${I18n.t('settings.script_memory')}</label>
+                <input type="number" id="script-memory" min="0" placeholder="${I18n.t('settings.no_limit_placeholder')}">
+            </div>
+            <div class="form-group" style="margin-bottom:0">
+                <label for="script-nice">${I18n.t('settings.script_nice')}</label>
+                <input type="number" id="script-nice" min="0" max="19" placeholder="0">
+            </div>
+        </div>
         <div class="card">
             <div class="card-title">
                 <svg class="card-icon" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M13 16h-1v-4h-1m1-4h.01M21 12a9 9 0 11-18 0 9 9 0 0118 0z" stroke-linecap="round" stroke-linejoin="round"/></svg>
@@ -64,6 +107,10 @@ App.registerPage('settings', function(container) {
                 <label for="sample-lines">${I18n.t('settings.sample_lines')}</label>
                 <input type="number" id="sample-lines" min="1" max="1000" placeholder="${I18n.t('settings.sample_lines_placeholder')}">
             </div>
+            <div class="form-group">
+                <label for="max-concurrent-jobs">${I18n.t('settings.max_concurrent_jobs')}</label>
+                <input type="number" id="max-concurrent-jobs" min="1" max="16" placeholder="${I18n.t('settings.max_concurrent_jobs_placeholder')}">
+            </div>
             <div class="form-group">
                 <label for="language-select">${I18n.t('settings.language')}</label>
                 <select id="language-select" class="form-select">
@@ -71,6 +118,14 @@ App.registerPage('settings', function(container) {
                     <option value="en">English</option>
                 </select>
             </div>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="resume-jobs-toggle">
+                <span>${I18n.t('settings.resume_interrupted_jobs')}</span>
+            </label>
+            <label class="wizard-checkbox">
+                <input type="checkbox" id="auto-apply-repairs-toggle">
+                <span>${I18n.t('settings.auto_apply_repairs')}</span>
+            </label>
             <label class="wizard-checkbox" style="margin-bottom:0">
                 <input type="checkbox" id="show-wizard-toggle">
                 <span>${I18n.t('settings.show_wizard')}</span>
@@ -85,11 +140,20 @@ App.registerPage('settings', function(container) {
         inputDir: document.getElementById('default-input-dir'),
         outputDir: document.getElementById('default-output-dir'),
         sampleLines: document.getElementById('sample-lines'),
+        maxConcurrentJobs: document.getElementById('max-concurrent-jobs'),
+        scriptTimeout: document.getElementById('script-timeout'),
+        scriptIdle: document.getElementById('script-idle'),
+        scriptMemory: document.getElementById('script-memory'),
+        scriptNice: document.getElementById('script-nice'),
         language: document.getElementById('language-select'),
+        geoipCityDb: document.getElementById('geoip-city-db'),
+        geoipAsnDb: document.getElementById('geoip-asn-db'),
     };
     const msgEl = document.getElementById('settings-message');
     const testResultEl = document.getElementById('llm-test-result');
     const wizardToggle = document.getElementById('show-wizard-toggle');
+    const resumeJobsToggle = document.getElementById('resume-jobs-toggle');
+    const autoApplyRepairsToggle = document.getElementById('auto-apply-repairs-toggle');
 
     // Cache loaded settings so we can preserve fields not shown in the UI (e.g. uv_path)
     let loadedSettings = null;
@@ -105,7 +169,16 @@ App.registerPage('settings', function(container) {
             fields.inputDir.value = s.default_input_dir || '';
             fields.outputDir.value = s.default_output_dir || '';
             fields.sampleLines.value = s.sample_lines || 5;
+            fields.maxConcurrentJobs.value = s.max_concurrent_jobs || 2;
+            fields.scriptTimeout.value = s.script_timeout_minutes || '';
+            fields.scriptIdle.value = s.script_idle_minutes || '';
+            fields.scriptMemory.value = s.script_memory_mb || '';
+            fields.scriptNice.value = s.script_nice || '';
+            resumeJobsToggle.checked = s.resume_interrupted_jobs !== false;
+            autoApplyRepairsToggle.checked = !!s.auto_apply_repairs;
             fields.language.value = s.language || I18n.currentLang;
+            fields.geoipCityDb.value = s.geoip_city_db || '';
+            fields.geoipAsnDb.value = s.geoip_asn_db || '';
         } catch (err) {
             msgEl.innerHTML = '<div class="alert alert-error">' + I18n.t('settings.load_failed') + ': ' + escapeHtml(String(err)) + '</div>';
         }
@@ -120,6 +193,7 @@ App.registerPage('settings', function(container) {
     wizardToggle.addEventListener('change', async () => {
         try {
             await window.go.main.App.SetShowWizard(wizardToggle.checked);
+            if (loadedSettings) loadedSettings.show_wizard = wizardToggle.checked;
         } catch (_) { /* ignore */ }
     });
 
@@ -139,7 +213,8 @@ App.registerPage('settings', function(container) {
     });
 
     function gatherSettings() {
-        return {
+        // Start from the loaded settings so fields without a form control are preserved
+        return Object.assign({}, loadedSettings || {}, {
             llm: {
                 base_url: fields.baseUrl.value.trim(),
                 api_key: fields.apiKey.value.trim(),
@@ -149,8 +224,17 @@ App.registerPage('settings', function(container) {
             default_input_dir: fields.inputDir.value.trim(),
             default_output_dir: fields.outputDir.value.trim(),
             sample_lines: parseInt(fields.sampleLines.value, 10) || 5,
+            max_concurrent_jobs: parseInt(fields.maxConcurrentJobs.value, 10) || 2,
+            script_timeout_minutes: Math.max(parseInt(fields.scriptTimeout.value, 10) || 0, 0),
+            script_idle_minutes: Math.max(parseInt(fields.scriptIdle.value, 10) || 0, 0),
+            script_memory_mb: Math.max(parseInt(fields.scriptMemory.value, 10) || 0, 0),
+            script_nice: Math.min(Math.max(parseInt(fields.scriptNice.value, 10) || 0, 0), 19),
+            resume_interrupted_jobs: resumeJobsToggle.checked,
+            auto_apply_repairs: autoApplyRepairsToggle.checked,
             language: fields.language.value,
-        };
+            geoip_city_db: fields.geoipCityDb.value.trim(),
+            geoip_asn_db: fields.geoipAsnDb.value.trim(),
+        });
     }
 
     // Directory browse buttons
@@ -168,6 +252,16 @@ App.registerPage('settings', function(container) {
         } catch (_) {}
     });
 
+    // GeoIP database browse buttons
+    for (const [btnId, field] of [['browse-geoip-city-btn', fields.geoipCityDb], ['browse-geoip-asn-btn', fields.geoipAsnDb]]) {
+        document.getElementById(btnId).addEventListener('click', async () => {
+            try {
+                const path = await window.go.main.App.SelectGeoIPDatabase(I18n.t('settings.geoip_select'));
+                if (path) field.value = path;
+            } catch (_) {}
+        });
+    }
+
     // Save settings
     document.getElementById('save-settings-btn').addEventListener('click', async () => {
         try {
@@ -5,40 +5,82 @@ import {pyenv} from '../models';
 
 export function AnalyzeSample(arg1:string,arg2:string):Promise<model.GenerateResult>;
 
+export function ApplyRepairedCode(arg1:string):Promise<void>;
+
 export function BrowseLogFile():Promise<model.LogFileSample>;
 
+export function CancelJob(arg1:string):Promise<void>;
+
+export function DeleteJob(arg1:string):Promise<void>;
+
 export function DeleteProject(arg1:string):Promise<void>;
 
 export function EnsurePythonEnv():Promise<void>;
 
-export function GetBatchProgress():Promise<model.BatchProgress>;
+export function GetBatchProgress(arg1:string):Promise<model.BatchProgress>;
 
 export function GetEnvStatus():Promise<pyenv.EnvStatus>;
 
+export function GetJob(arg1:string):Promise<model.BatchJob>;
+
+export function GetPreviewPage(arg1:string,arg2:number,arg3:number):Promise<model.PreviewTable>;
+
 export function GetProject(arg1:string):Promise<model.Project>;
 
 export function GetPythonEnvReady():Promise<Record<string, any>>;
 
+export function GetRepairDiff(arg1:string):Promise<Array<model.DiffLine>>;
+
+export function GetRun(arg1:string,arg2:string):Promise<model.RunRecord>;
+
+export function GetScheduleStatus(arg1:string):Promise<Array<model.ScheduleStatus>>;
+
 export function GetSettings():Promise<model.Settings>;
 
 export function GetShowWizard():Promise<boolean>;
 
 export function IsLLMConfigured():Promise<boolean>;
 
+export function ListJobs():Promise<Array<model.BatchJob>>;
+
 export function ListProjects():Promise<Array<model.Project>>;
 
+export function ListRuns(arg1:string):Promise<Array<model.RunRecord>>;
+
+export function ListScheduleRuns(arg1:string,arg2:string):Promise<Array<model.RunRecord>>;
+
 export function OpenDirectory(arg1:string):Promise<void>;
 
-export function RerunProject(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function OpenRunOutput(arg1:string,arg2:string):Promise<void>;
 
-export function RunBatch(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function PreviewBatch(arg1:string,arg2:model.PreviewParams):Promise<model.Preview>;
+
+export function RerunProject(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function RerunRun(arg1:string,arg2:string):Promise<string>;
+
+export function RunBatch(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function RunBatchWithOptions(arg1:string,arg2:model.BatchParams):Promise<string>;
 
 export function SaveSettings(arg1:model.Settings):Promise<void>;
 
 export function SelectDirectory(arg1:string):Promise<string>;
 
+export function SelectGeoIPDatabase(arg1:string):Promise<string>;
+
 export function SetShowWizard(arg1:boolean):Promise<void>;
 
 export function TestLLM():Promise<void>;
 
 export function UpdateProjectCode(arg1:string,arg2:string):Promise<void>;
+
+export function UpdateProjectQuality(arg1:string,arg2:model.QualityThresholds):Promise<void>;
+
+export function UpdateProjectSchedules(arg1:string,arg2:Array<model.Schedule>):Promise<void>;
+
+export function UpdateProjectSchema(arg1:string,arg2:Array<model.ColumnSchema>):Promise<void>;
+
+export function UpdateProjectSinks(arg1:string,arg2:Array<model.SinkConfig>):Promise<void>;
+
+export function UpdateProjectTimeZones(arg1:string,arg2:model.TimeZones):Promise<void>;
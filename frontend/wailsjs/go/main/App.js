@@ -6,10 +6,22 @@ export function AnalyzeSample(arg1, arg2) {
   return window['go']['main']['App']['AnalyzeSample'](arg1, arg2);
 }
 
+export function ApplyRepairedCode(arg1) {
+  return window['go']['main']['App']['ApplyRepairedCode'](arg1);
+}
+
 export function BrowseLogFile() {
   return window['go']['main']['App']['BrowseLogFile']();
 }
 
+export function CancelJob(arg1) {
+  return window['go']['main']['App']['CancelJob'](arg1);
+}
+
+export function DeleteJob(arg1) {
+  return window['go']['main']['App']['DeleteJob'](arg1);
+}
+
 export function DeleteProject(arg1) {
   return window['go']['main']['App']['DeleteProject'](arg1);
 }
@@ -18,14 +30,22 @@ export function EnsurePythonEnv() {
   return window['go']['main']['App']['EnsurePythonEnv']();
 }
 
-export function GetBatchProgress() {
-  return window['go']['main']['App']['GetBatchProgress']();
+export function GetBatchProgress(arg1) {
+  return window['go']['main']['App']['GetBatchProgress'](arg1);
 }
 
 export function GetEnvStatus() {
   return window['go']['main']['App']['GetEnvStatus']();
 }
 
+export function GetJob(arg1) {
+  return window['go']['main']['App']['GetJob'](arg1);
+}
+
+export function GetPreviewPage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetPreviewPage'](arg1, arg2, arg3);
+}
+
 export function GetProject(arg1) {
   return window['go']['main']['App']['GetProject'](arg1);
 }
@@ -34,6 +54,18 @@ export function GetPythonEnvReady() {
   return window['go']['main']['App']['GetPythonEnvReady']();
 }
 
+export function GetRepairDiff(arg1) {
+  return window['go']['main']['App']['GetRepairDiff'](arg1);
+}
+
+export function GetRun(arg1, arg2) {
+  return window['go']['main']['App']['GetRun'](arg1, arg2);
+}
+
+export function GetScheduleStatus(arg1) {
+  return window['go']['main']['App']['GetScheduleStatus'](arg1);
+}
+
 export function GetSettings() {
   return window['go']['main']['App']['GetSettings']();
 }
@@ -46,22 +78,50 @@ export function IsLLMConfigured() {
   return window['go']['main']['App']['IsLLMConfigured']();
 }
 
+export function ListJobs() {
+  return window['go']['main']['App']['ListJobs']();
+}
+
 export function ListProjects() {
   return window['go']['main']['App']['ListProjects']();
 }
 
+export function ListRuns(arg1) {
+  return window['go']['main']['App']['ListRuns'](arg1);
+}
+
+export function ListScheduleRuns(arg1, arg2) {
+  return window['go']['main']['App']['ListScheduleRuns'](arg1, arg2);
+}
+
 export function OpenDirectory(arg1) {
   return window['go']['main']['App']['OpenDirectory'](arg1);
 }
 
+export function OpenRunOutput(arg1, arg2) {
+  return window['go']['main']['App']['OpenRunOutput'](arg1, arg2);
+}
+
+export function PreviewBatch(arg1, arg2) {
+  return window['go']['main']['App']['PreviewBatch'](arg1, arg2);
+}
+
 export function RerunProject(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['RerunProject'](arg1, arg2, arg3, arg4);
 }
 
+export function RerunRun(arg1, arg2) {
+  return window['go']['main']['App']['RerunRun'](arg1, arg2);
+}
+
 export function RunBatch(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['RunBatch'](arg1, arg2, arg3, arg4);
 }
 
+export function RunBatchWithOptions(arg1, arg2) {
+  return window['go']['main']['App']['RunBatchWithOptions'](arg1, arg2);
+}
+
 export function SaveSettings(arg1) {
   return window['go']['main']['App']['SaveSettings'](arg1);
 }
@@ -70,6 +130,10 @@ export function SelectDirectory(arg1) {
   return window['go']['main']['App']['SelectDirectory'](arg1);
 }
 
+export function SelectGeoIPDatabase(arg1) {
+  return window['go']['main']['App']['SelectGeoIPDatabase'](arg1);
+}
+
 export function SetShowWizard(arg1) {
   return window['go']['main']['App']['SetShowWizard'](arg1);
 }
@@ -81,3 +145,23 @@ export function TestLLM() {
 export function UpdateProjectCode(arg1, arg2) {
   return window['go']['main']['App']['UpdateProjectCode'](arg1, arg2);
 }
+
+export function UpdateProjectQuality(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProjectQuality'](arg1, arg2);
+}
+
+export function UpdateProjectSchedules(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProjectSchedules'](arg1, arg2);
+}
+
+export function UpdateProjectSchema(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProjectSchema'](arg1, arg2);
+}
+
+export function UpdateProjectSinks(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProjectSinks'](arg1, arg2);
+}
+
+export function UpdateProjectTimeZones(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProjectTimeZones'](arg1, arg2);
+}
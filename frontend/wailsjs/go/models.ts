@@ -1,5 +1,441 @@
 export namespace model {
 	
+	export class AppliedZones {
+	    source: string;
+	    files?: Record<string, string>;
+	    target: string;
+	    format: string;
+	    converted: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AppliedZones(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.files = source["files"];
+	        this.target = source["target"];
+	        this.format = source["format"];
+	        this.converted = source["converted"];
+	    }
+	}
+	export class FileQuality {
+	    file: string;
+	    rows: number;
+	    time_column?: string;
+	    out_of_order: number;
+	    future: number;
+	    duplicates: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileQuality(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.rows = source["rows"];
+	        this.time_column = source["time_column"];
+	        this.out_of_order = source["out_of_order"];
+	        this.future = source["future"];
+	        this.duplicates = source["duplicates"];
+	    }
+	}
+	export class ColumnQuality {
+	    name: string;
+	    type: string;
+	    declared?: boolean;
+	    rows: number;
+	    empty: number;
+	    mismatched: number;
+	    time_failures: number;
+	    examples?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ColumnQuality(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.declared = source["declared"];
+	        this.rows = source["rows"];
+	        this.empty = source["empty"];
+	        this.mismatched = source["mismatched"];
+	        this.time_failures = source["time_failures"];
+	        this.examples = source["examples"];
+	    }
+	}
+	export class QualityReport {
+	    rows: number;
+	    columns?: ColumnQuality[];
+	    files?: FileQuality[];
+	    time_failures: number;
+	    out_of_order: number;
+	    future: number;
+	    duplicates: number;
+	    approximate?: boolean;
+	    violations?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new QualityReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rows = source["rows"];
+	        this.columns = this.convertValues(source["columns"], ColumnQuality);
+	        this.files = this.convertValues(source["files"], FileQuality);
+	        this.time_failures = source["time_failures"];
+	        this.out_of_order = source["out_of_order"];
+	        this.future = source["future"];
+	        this.duplicates = source["duplicates"];
+	        this.approximate = source["approximate"];
+	        this.violations = source["violations"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HourCount {
+	    hour: string;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HourCount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hour = source["hour"];
+	        this.count = source["count"];
+	    }
+	}
+	export class ValueCount {
+	    value: string;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ValueCount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.value = source["value"];
+	        this.count = source["count"];
+	    }
+	}
+	export class TopValues {
+	    column: string;
+	    total: number;
+	    values: ValueCount[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TopValues(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.column = source["column"];
+	        this.total = source["total"];
+	        this.values = this.convertValues(source["values"], ValueCount);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ColumnSummary {
+	    name: string;
+	    type: string;
+	    declared?: boolean;
+	    values: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ColumnSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.declared = source["declared"];
+	        this.values = source["values"];
+	    }
+	}
+	export class FileSummary {
+	    file: string;
+	    rows: number;
+	    time_column?: string;
+	    from?: string;
+	    to?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.rows = source["rows"];
+	        this.time_column = source["time_column"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	    }
+	}
+	export class RunSummary {
+	    rows: number;
+	    from?: string;
+	    to?: string;
+	    files?: FileSummary[];
+	    columns?: ColumnSummary[];
+	    top_ips?: TopValues[];
+	    statuses?: TopValues[];
+	    hourly?: HourCount[];
+	    errors?: ValueCount[];
+	    approximate?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RunSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rows = source["rows"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.files = this.convertValues(source["files"], FileSummary);
+	        this.columns = this.convertValues(source["columns"], ColumnSummary);
+	        this.top_ips = this.convertValues(source["top_ips"], TopValues);
+	        this.statuses = this.convertValues(source["statuses"], TopValues);
+	        this.hourly = this.convertValues(source["hourly"], HourCount);
+	        this.errors = this.convertValues(source["errors"], ValueCount);
+	        this.approximate = source["approximate"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SinkResult {
+	    type: string;
+	    target: string;
+	    sent: number;
+	    failed: number;
+	    error?: string;
+	    dead_letter?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SinkResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.target = source["target"];
+	        this.sent = source["sent"];
+	        this.failed = source["failed"];
+	        this.error = source["error"];
+	        this.dead_letter = source["dead_letter"];
+	    }
+	}
+	export class SheetPlacement {
+	    file: string;
+	    workbook: string;
+	    sheet: string;
+	    rows: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SheetPlacement(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.workbook = source["workbook"];
+	        this.sheet = source["sheet"];
+	        this.rows = source["rows"];
+	    }
+	}
+	export class LogEntry {
+	    level: string;
+	    file?: string;
+	    line?: number;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LogEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.level = source["level"];
+	        this.file = source["file"];
+	        this.line = source["line"];
+	        this.message = source["message"];
+	    }
+	}
+	export class SkippedFile {
+	    file: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkippedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class BatchResult {
+	    total_files: number;
+	    succeeded: number;
+	    failed: number;
+	    output_path: string;
+	    errors?: string[];
+	    skipped?: SkippedFile[];
+	    files?: FileResult[];
+	    log?: LogEntry[];
+	    coverage?: number;
+	    degraded?: boolean;
+	    rejected_path?: string;
+	    failure?: string;
+	    repaired_code?: string;
+	    code_applied?: boolean;
+	    repair_attempts?: number;
+	    stderr?: string;
+	    outputs?: string[];
+	    sheets?: SheetPlacement[];
+	    sinks?: SinkResult[];
+	    summary?: RunSummary;
+	    quality?: QualityReport;
+	    time_zones?: AppliedZones;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.total_files = source["total_files"];
+	        this.succeeded = source["succeeded"];
+	        this.failed = source["failed"];
+	        this.output_path = source["output_path"];
+	        this.errors = source["errors"];
+	        this.skipped = this.convertValues(source["skipped"], SkippedFile);
+	        this.files = this.convertValues(source["files"], FileResult);
+	        this.log = this.convertValues(source["log"], LogEntry);
+	        this.coverage = source["coverage"];
+	        this.degraded = source["degraded"];
+	        this.rejected_path = source["rejected_path"];
+	        this.failure = source["failure"];
+	        this.repaired_code = source["repaired_code"];
+	        this.code_applied = source["code_applied"];
+	        this.repair_attempts = source["repair_attempts"];
+	        this.stderr = source["stderr"];
+	        this.outputs = source["outputs"];
+	        this.sheets = this.convertValues(source["sheets"], SheetPlacement);
+	        this.sinks = this.convertValues(source["sinks"], SinkResult);
+	        this.summary = this.convertValues(source["summary"], RunSummary);
+	        this.quality = this.convertValues(source["quality"], QualityReport);
+	        this.time_zones = this.convertValues(source["time_zones"], AppliedZones);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileResult {
+	    file: string;
+	    status: string;
+	    rows?: number;
+	    skipped_lines?: number;
+	    rejected?: number;
+	    coverage?: number;
+	    warnings?: number;
+	    error?: string;
+	    duration_ms: number;
+	    bytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.status = source["status"];
+	        this.rows = source["rows"];
+	        this.skipped_lines = source["skipped_lines"];
+	        this.rejected = source["rejected"];
+	        this.coverage = source["coverage"];
+	        this.warnings = source["warnings"];
+	        this.error = source["error"];
+	        this.duration_ms = source["duration_ms"];
+	        this.bytes = source["bytes"];
+	    }
+	}
 	export class BatchProgress {
 	    status: string;
 	    current_file: string;
@@ -8,22 +444,441 @@ export namespace model {
 	    processed: number;
 	    failed: number;
 	    message: string;
+	    rows?: number;
+	    files?: FileResult[];
+	    failure?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.current_file = source["current_file"];
+	        this.progress = source["progress"];
+	        this.total_files = source["total_files"];
+	        this.processed = source["processed"];
+	        this.failed = source["failed"];
+	        this.message = source["message"];
+	        this.rows = source["rows"];
+	        this.files = this.convertValues(source["files"], FileResult);
+	        this.failure = source["failure"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileZone {
+	    pattern: string;
+	    zone: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileZone(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pattern = source["pattern"];
+	        this.zone = source["zone"];
+	    }
+	}
+	export class TimeZones {
+	    source?: string;
+	    files?: FileZone[];
+	    target?: string;
+	    format?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimeZones(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.files = this.convertValues(source["files"], FileZone);
+	        this.target = source["target"];
+	        this.format = source["format"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QualityThresholds {
+	    max_empty?: number;
+	    max_mismatched?: number;
+	    max_time_failures?: number;
+	    max_out_of_order?: number;
+	    max_future?: number;
+	    max_duplicates?: number;
+	    columns?: string[];
+	    action?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new QualityThresholds(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.max_empty = source["max_empty"];
+	        this.max_mismatched = source["max_mismatched"];
+	        this.max_time_failures = source["max_time_failures"];
+	        this.max_out_of_order = source["max_out_of_order"];
+	        this.max_future = source["max_future"];
+	        this.max_duplicates = source["max_duplicates"];
+	        this.columns = source["columns"];
+	        this.action = source["action"];
+	    }
+	}
+	export class ColumnSchema {
+	    name: string;
+	    type: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ColumnSchema(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	    }
+	}
+	export class EnrichParams {
+	    classify?: boolean;
+	    geoip?: boolean;
+	    city_db?: string;
+	    asn_db?: string;
+	    language?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EnrichParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.classify = source["classify"];
+	        this.geoip = source["geoip"];
+	        this.city_db = source["city_db"];
+	        this.asn_db = source["asn_db"];
+	        this.language = source["language"];
+	    }
+	}
+	export class SinkConfig {
+	    type: string;
+	    url: string;
+	    index?: string;
+	    table?: string;
+	    source_type?: string;
+	    labels?: Record<string, string>;
+	    label_columns?: string[];
+	    time_column?: string;
+	    username?: string;
+	    password?: string;
+	    api_key?: string;
+	    tenant_id?: string;
+	    token?: string;
+	    headers?: Record<string, string>;
+	    tls_insecure?: boolean;
+	    ca_file?: string;
+	    cert_file?: string;
+	    key_file?: string;
+	    dead_letter?: string;
+	    batch_size?: number;
+	    max_retries?: number;
+	    max_pending?: number;
+	    redacted?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SinkConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.url = source["url"];
+	        this.index = source["index"];
+	        this.table = source["table"];
+	        this.source_type = source["source_type"];
+	        this.labels = source["labels"];
+	        this.label_columns = source["label_columns"];
+	        this.time_column = source["time_column"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.api_key = source["api_key"];
+	        this.tenant_id = source["tenant_id"];
+	        this.token = source["token"];
+	        this.headers = source["headers"];
+	        this.tls_insecure = source["tls_insecure"];
+	        this.ca_file = source["ca_file"];
+	        this.cert_file = source["cert_file"];
+	        this.key_file = source["key_file"];
+	        this.dead_letter = source["dead_letter"];
+	        this.batch_size = source["batch_size"];
+	        this.max_retries = source["max_retries"];
+	        this.max_pending = source["max_pending"];
+	        this.redacted = source["redacted"];
+	    }
+	}
+	export class FileFilter {
+	    recursive?: boolean;
+	    include?: string[];
+	    exclude?: string[];
+	    min_size?: number;
+	    max_size?: number;
+	    // Go type: time
+	    modified_after?: any;
+	    // Go type: time
+	    modified_before?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.recursive = source["recursive"];
+	        this.include = source["include"];
+	        this.exclude = source["exclude"];
+	        this.min_size = source["min_size"];
+	        this.max_size = source["max_size"];
+	        this.modified_after = this.convertValues(source["modified_after"], null);
+	        this.modified_before = this.convertValues(source["modified_before"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BatchParams {
+	    input_dir: string;
+	    output_dir: string;
+	    output_file_name: string;
+	    workers?: number;
+	    incremental?: boolean;
+	    watch?: boolean;
+	    watch_debounce?: number;
+	    filter?: FileFilter;
+	    formats?: string[];
+	    max_workbook_rows?: number;
+	    sinks?: SinkConfig[];
+	    no_project_sinks?: boolean;
+	    sinks_only?: boolean;
+	    enrich?: EnrichParams;
+	    schema?: ColumnSchema[];
+	    summary?: boolean;
+	    quality?: QualityThresholds;
+	    time_zones?: TimeZones;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.input_dir = source["input_dir"];
+	        this.output_dir = source["output_dir"];
+	        this.output_file_name = source["output_file_name"];
+	        this.workers = source["workers"];
+	        this.incremental = source["incremental"];
+	        this.watch = source["watch"];
+	        this.watch_debounce = source["watch_debounce"];
+	        this.filter = this.convertValues(source["filter"], FileFilter);
+	        this.formats = source["formats"];
+	        this.max_workbook_rows = source["max_workbook_rows"];
+	        this.sinks = this.convertValues(source["sinks"], SinkConfig);
+	        this.no_project_sinks = source["no_project_sinks"];
+	        this.sinks_only = source["sinks_only"];
+	        this.enrich = this.convertValues(source["enrich"], EnrichParams);
+	        this.schema = this.convertValues(source["schema"], ColumnSchema);
+	        this.summary = source["summary"];
+	        this.quality = this.convertValues(source["quality"], QualityThresholds);
+	        this.time_zones = this.convertValues(source["time_zones"], TimeZones);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BatchJob {
+	    id: string;
+	    project_id: string;
+	    project_name: string;
+	    params: BatchParams;
+	    status: string;
+	    attempts?: number;
+	    progress: BatchProgress;
+	    result?: BatchResult;
+	    error?: string;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    started_at?: any;
+	    // Go type: time
+	    finished_at?: any;
+	    schedule_id?: string;
+	    // Go type: time
+	    scheduled_for?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchJob(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.project_id = source["project_id"];
+	        this.project_name = source["project_name"];
+	        this.params = this.convertValues(source["params"], BatchParams);
+	        this.status = source["status"];
+	        this.attempts = source["attempts"];
+	        this.progress = this.convertValues(source["progress"], BatchProgress);
+	        this.result = this.convertValues(source["result"], BatchResult);
+	        this.error = source["error"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	        this.schedule_id = source["schedule_id"];
+	        this.scheduled_for = this.convertValues(source["scheduled_for"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class CodeRevision {
+	    code: string;
+	    reason: string;
+	    // Go type: time
+	    replaced_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new CodeRevision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.reason = source["reason"];
+	        this.replaced_at = this.convertValues(source["replaced_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class DiffLine {
+	    op: string;
+	    text: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new BatchProgress(source);
+	        return new DiffLine(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.status = source["status"];
-	        this.current_file = source["current_file"];
-	        this.progress = source["progress"];
-	        this.total_files = source["total_files"];
-	        this.processed = source["processed"];
-	        this.failed = source["failed"];
-	        this.message = source["message"];
+	        this.op = source["op"];
+	        this.text = source["text"];
 	    }
 	}
+	
+	
+	
+	
+	
+	
 	export class GenerateResult {
 	    project_id: string;
 	    code: string;
@@ -42,6 +897,7 @@ export namespace model {
 	        this.errors = source["errors"];
 	    }
 	}
+	
 	export class LLMConfig {
 	    base_url: string;
 	    api_key: string;
@@ -58,6 +914,7 @@ export namespace model {
 	        this.model_name = source["model_name"];
 	    }
 	}
+	
 	export class LogFileSample {
 	    file_name: string;
 	    project_name: string;
@@ -74,6 +931,173 @@ export namespace model {
 	        this.sample_text = source["sample_text"];
 	    }
 	}
+	export class SkippedLine {
+	    line: number;
+	    reason?: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkippedLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.reason = source["reason"];
+	        this.text = source["text"];
+	    }
+	}
+	export class PreviewTable {
+	    file: string;
+	    sheet?: string;
+	    columns: string[];
+	    empty_columns?: number[];
+	    rows: string[][];
+	    total_rows: number;
+	    page: number;
+	    page_size: number;
+	    skipped?: SkippedLine[];
+	    skipped_lines?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewTable(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.sheet = source["sheet"];
+	        this.columns = source["columns"];
+	        this.empty_columns = source["empty_columns"];
+	        this.rows = source["rows"];
+	        this.total_rows = source["total_rows"];
+	        this.page = source["page"];
+	        this.page_size = source["page_size"];
+	        this.skipped = this.convertValues(source["skipped"], SkippedLine);
+	        this.skipped_lines = source["skipped_lines"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Preview {
+	    id: string;
+	    tables: PreviewTable[];
+	    log?: LogEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Preview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.tables = this.convertValues(source["tables"], PreviewTable);
+	        this.log = this.convertValues(source["log"], LogEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PreviewParams {
+	    input_dir: string;
+	    files?: string[];
+	    filter?: FileFilter;
+	    lines?: number;
+	    enrich?: EnrichParams;
+	    page_size?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.input_dir = source["input_dir"];
+	        this.files = source["files"];
+	        this.filter = this.convertValues(source["filter"], FileFilter);
+	        this.lines = source["lines"];
+	        this.enrich = this.convertValues(source["enrich"], EnrichParams);
+	        this.page_size = source["page_size"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Schedule {
+	    id: string;
+	    cron: string;
+	    input_dir: string;
+	    output_dir: string;
+	    output_name?: string;
+	    incremental?: boolean;
+	    summary?: boolean;
+	    enabled: boolean;
+	    missed_runs?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Schedule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.cron = source["cron"];
+	        this.input_dir = source["input_dir"];
+	        this.output_dir = source["output_dir"];
+	        this.output_name = source["output_name"];
+	        this.incremental = source["incremental"];
+	        this.summary = source["summary"];
+	        this.enabled = source["enabled"];
+	        this.missed_runs = source["missed_runs"];
+	    }
+	}
 	export class Project {
 	    id: string;
 	    name: string;
@@ -84,6 +1108,12 @@ export namespace model {
 	    // Go type: time
 	    updated_at: any;
 	    status: string;
+	    revisions?: CodeRevision[];
+	    sinks?: SinkConfig[];
+	    schema?: ColumnSchema[];
+	    schedules?: Schedule[];
+	    quality: QualityThresholds;
+	    time_zones: TimeZones;
 	
 	    static createFrom(source: any = {}) {
 	        return new Project(source);
@@ -98,6 +1128,172 @@ export namespace model {
 	        this.created_at = this.convertValues(source["created_at"], null);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
 	        this.status = source["status"];
+	        this.revisions = this.convertValues(source["revisions"], CodeRevision);
+	        this.sinks = this.convertValues(source["sinks"], SinkConfig);
+	        this.schema = this.convertValues(source["schema"], ColumnSchema);
+	        this.schedules = this.convertValues(source["schedules"], Schedule);
+	        this.quality = this.convertValues(source["quality"], QualityThresholds);
+	        this.time_zones = this.convertValues(source["time_zones"], TimeZones);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class RunRecord {
+	    id: string;
+	    project_id: string;
+	    job_id?: string;
+	    params: BatchParams;
+	    output_file: string;
+	    status: string;
+	    // Go type: time
+	    started_at: any;
+	    // Go type: time
+	    finished_at: any;
+	    total_files: number;
+	    succeeded: number;
+	    failed: number;
+	    files?: FileResult[];
+	    error?: string;
+	    stderr?: string;
+	    repair_attempts?: number;
+	    code_hash: string;
+	    repaired_code?: boolean;
+	    outputs?: string[];
+	    sheets?: SheetPlacement[];
+	    sinks?: SinkResult[];
+	    summary?: RunSummary;
+	    quality?: QualityReport;
+	    time_zones?: AppliedZones;
+	    schedule_id?: string;
+	    // Go type: time
+	    scheduled_for?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new RunRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.project_id = source["project_id"];
+	        this.job_id = source["job_id"];
+	        this.params = this.convertValues(source["params"], BatchParams);
+	        this.output_file = source["output_file"];
+	        this.status = source["status"];
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	        this.total_files = source["total_files"];
+	        this.succeeded = source["succeeded"];
+	        this.failed = source["failed"];
+	        this.files = this.convertValues(source["files"], FileResult);
+	        this.error = source["error"];
+	        this.stderr = source["stderr"];
+	        this.repair_attempts = source["repair_attempts"];
+	        this.code_hash = source["code_hash"];
+	        this.repaired_code = source["repaired_code"];
+	        this.outputs = source["outputs"];
+	        this.sheets = this.convertValues(source["sheets"], SheetPlacement);
+	        this.sinks = this.convertValues(source["sinks"], SinkResult);
+	        this.summary = this.convertValues(source["summary"], RunSummary);
+	        this.quality = this.convertValues(source["quality"], QualityReport);
+	        this.time_zones = this.convertValues(source["time_zones"], AppliedZones);
+	        this.schedule_id = source["schedule_id"];
+	        this.scheduled_for = this.convertValues(source["scheduled_for"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class ScheduleState {
+	    // Go type: time
+	    last_slot: any;
+	    // Go type: time
+	    last_run?: any;
+	    last_job_id?: string;
+	    last_error?: string;
+	    missed?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.last_slot = this.convertValues(source["last_slot"], null);
+	        this.last_run = this.convertValues(source["last_run"], null);
+	        this.last_job_id = source["last_job_id"];
+	        this.last_error = source["last_error"];
+	        this.missed = source["missed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScheduleStatus {
+	    schedule: Schedule;
+	    state: ScheduleState;
+	    // Go type: time
+	    next?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.schedule = this.convertValues(source["schedule"], Schedule);
+	        this.state = this.convertValues(source["state"], ScheduleState);
+	        this.next = this.convertValues(source["next"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -126,6 +1322,15 @@ export namespace model {
 	    sample_lines?: number;
 	    show_wizard?: boolean;
 	    language?: string;
+	    max_concurrent_jobs?: number;
+	    resume_interrupted_jobs?: boolean;
+	    script_timeout_minutes?: number;
+	    script_idle_minutes?: number;
+	    script_memory_mb?: number;
+	    script_nice?: number;
+	    auto_apply_repairs?: boolean;
+	    geoip_city_db?: string;
+	    geoip_asn_db?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Settings(source);
@@ -140,6 +1345,15 @@ export namespace model {
 	        this.sample_lines = source["sample_lines"];
 	        this.show_wizard = source["show_wizard"];
 	        this.language = source["language"];
+	        this.max_concurrent_jobs = source["max_concurrent_jobs"];
+	        this.resume_interrupted_jobs = source["resume_interrupted_jobs"];
+	        this.script_timeout_minutes = source["script_timeout_minutes"];
+	        this.script_idle_minutes = source["script_idle_minutes"];
+	        this.script_memory_mb = source["script_memory_mb"];
+	        this.script_nice = source["script_nice"];
+	        this.auto_apply_repairs = source["auto_apply_repairs"];
+	        this.geoip_city_db = source["geoip_city_db"];
+	        this.geoip_asn_db = source["geoip_asn_db"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -160,15 +1374,37 @@ export namespace model {
 		    return a;
 		}
 	}
+	
+	
+	
+	
+	
+	
+	
 
 }
 
 export namespace pyenv {
 	
+	export class SandboxInfo {
+	    mode: string;
+	    warning?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SandboxInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.warning = source["warning"];
+	    }
+	}
 	export class EnvStatus {
 	    uv_available: boolean;
 	    env_exists: boolean;
 	    env_path: string;
+	    sandbox: SandboxInfo;
 	
 	    static createFrom(source: any = {}) {
 	        return new EnvStatus(source);
@@ -179,7 +1415,26 @@ export namespace pyenv {
 	        this.uv_available = source["uv_available"];
 	        this.env_exists = source["env_exists"];
 	        this.env_path = source["env_path"];
+	        this.sandbox = this.convertValues(source["sandbox"], SandboxInfo);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
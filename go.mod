@@ -5,8 +5,11 @@ go 1.22.0
 require (
 	github.com/cloudwego/eino v0.7.32
 	github.com/cloudwego/eino-ext/components/model/openai v0.1.8
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/google/uuid v1.6.0
+	github.com/ulikunitz/xz v0.5.12
 	github.com/wailsapp/wails/v2 v2.11.0
+	golang.org/x/sys v0.30.0
 	pgregory.net/rapid v1.2.0
 )
 
@@ -61,7 +64,6 @@ require (
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/exp v0.0.0-20230713183714-613f0c0eb8a1 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
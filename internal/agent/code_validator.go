@@ -1,10 +1,11 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -100,14 +101,25 @@ func (cv *CodeValidator) checkSyntax(ctx context.Context, code string) (string,
 		return "", fmt.Errorf("failed to write temp file: %w", err)
 	}
 
-	pythonBin := cv.envManager.PythonPath()
 	compileCmd := fmt.Sprintf("compile(open(%q).read(), %q, 'exec')", tmpFile, tmpFile)
-	cmd := exec.CommandContext(ctx, pythonBin, "-c", compileCmd)
-
-	output, err := cmd.CombinedOutput()
+	limits := cv.envManager.Limits()
+	limits.IdleTimeout = 0 // compiling prints nothing
+	run, err := cv.envManager.RunCode(ctx, compileCmd, limits, pyenv.Access{ReadOnly: []string{tmpDir}})
 	if err != nil {
+		return "", fmt.Errorf("failed to start syntax check: %w", err)
+	}
+	var output bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&output, run.Stderr)
+		close(done)
+	}()
+	io.Copy(&output, run.Stdout)
+	<-done
+
+	if err := run.Wait(); err != nil {
 		// Syntax check failed — return the error output
-		errMsg := strings.TrimSpace(string(output))
+		errMsg := strings.TrimSpace(output.String())
 		if errMsg == "" {
 			errMsg = err.Error()
 		}
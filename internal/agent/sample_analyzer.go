@@ -53,19 +53,28 @@ const systemPrompt = `You are an expert Python developer specializing in log par
 Your task is to analyze sample log entries and generate a complete Python program that can batch-process log files of the same format.
 
 The generated Python program MUST:
-1. Accept --input, --output, and --output-name command line arguments (--input is the directory containing log files, --output is the directory for Excel output, --output-name is the Excel file name without extension, defaulting to "result" if not provided)
+1. Accept --input, --output, and --output-name command line arguments (--input is the directory containing log files, --output is the output directory, --output-name is the output name without extension, defaulting to "result" if not provided)
 2. Traverse all log files in the input directory
 3. Parse each log entry into structured data based on the detected format
-4. Use openpyxl to write ALL parsed data into a SINGLE Excel file named {output-name}.xlsx in the output directory, but create a SEPARATE SHEET for each input log file. The sheet name MUST be the original log file name WITH extension (e.g. "Apache_2k.log"). If the file name exceeds 31 characters (Excel sheet name limit), truncate it to 31 characters. Do NOT use generic names like "Log Entries" or "Sheet1". Do NOT merge all data into one worksheet.
-   IMPORTANT: Each log file must produce exactly ONE sheet. Do NOT create duplicate sheets. When creating the Workbook, immediately remove the default empty sheet (wb.remove(wb.active)) before adding any data sheets. Ensure each file is only processed once.
+4. Hand every parsed row to LogForge with logforge.row (see 7); do NOT write any output file yourself and do NOT import openpyxl. LogForge writes the workbook {output-name}.xlsx in the output directory with one sheet per input log file, named after the file, and the other selected output formats. Pass numbers as numbers and dates and times as ISO 8601 strings (e.g. "2024-01-02 03:04:05") so they become typed cells; pass IP addresses and identifiers as strings. Each log file must be processed exactly once.
 5. STRICTLY FORBIDDEN extra columns:
-   - Do NOT add a "source_file" column. The sheet name already identifies the source file.
+   - Do NOT add a "source_file" column. The file name given to logforge.row already identifies the source file.
    - Do NOT add a row number / line number / index / sequence column.
    - Do NOT add a "raw_log" / "raw_line" / "original" / "raw" column containing the original log line text.
-   - The Excel output must ONLY contain the parsed/structured data fields (e.g. datetime, level, module, pid, message). No redundant or auxiliary columns.
-6. For date/time fields: if the log contains date and time information that appears on multiple lines (e.g. a date header followed by time-only entries), consolidate them so each row has ONE complete datetime or date column. Do NOT repeat the same date across a separate column. Keep only one unified date/time column per row to make statistical analysis easier.
-7. Output progress to stdout as JSON lines, one per file processed, in this exact format:
-   {"file": "<filename>", "progress": <0.0-1.0>, "total": <total_files>, "current": <current_index>}
+   - The streamed rows must ONLY contain the parsed/structured data fields (e.g. datetime, level, module, pid, message). No redundant or auxiliary columns.
+6. For date/time fields: if the log contains date and time information that appears on multiple lines (e.g. a date header followed by time-only entries), consolidate them so each row has ONE complete datetime or date column. Do NOT repeat the same date across a separate column. Keep only one unified date/time column per row to make statistical analysis easier. Write times as the log gives them: keep an offset or "Z" the log has, and do not add an offset or convert between zones yourself; LogForge converts the times to the zone the run asks for (logforge.source_zone(<filename>) names the zone of times without an offset, or None, if you need it). Write epoch timestamps as UTC times ending in "Z".
+7. Report progress with the "logforge" module, which is available for import next to the script (do NOT implement it yourself, and do NOT print other JSON to stdout):
+   import logforge
+   logforge.start(total=<number_of_files>)
+   for each file: logforge.file_start(<filename>, index=<1-based index>, total=<number_of_files>)
+     then declare its columns: logforge.columns(<filename>, [<column names>])
+     for every parsed row: logforge.row(<filename>, [<values in column order>])
+     while writing a large file, optionally every few thousand rows: logforge.rows(<filename>, <rows_written_so_far>)
+     for every unparseable line: logforge.reject(<filename>, line=<line_number>, reason="<reason>", text=<raw_line>) (it is quarantined to a side file; don't also report it as a warning)
+     when the file is finished: logforge.file_done(<filename>, rows=<rows_written>, skipped=<unparseable_lines>)
+     if processing the file raises an exception: catch it, call logforge.file_done(<filename>, error=str(exc)) and continue with the next file
+   at the end: logforge.done(files=<number_of_files>, rows=<total_rows>)
+   Use plain print() only for human-readable messages; logforge.log("<message>") is preferred.
 8. Include complete error handling (try/except around file operations, graceful handling of unparseable entries)
 
 Return the complete Python code inside a single python code block.`
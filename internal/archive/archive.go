@@ -0,0 +1,402 @@
+// Package archive reads compressed and archived log files transparently.
+// Formats are recognised by their magic bytes rather than file extensions, so
+// rotated logs such as "app.log.2.gz" and collection bundles such as
+// "logs.tar.gz" or "export.zip" are read like plain log files.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Format identifies how a file is compressed or archived.
+type Format string
+
+const (
+	Plain    Format = ""
+	Gzip     Format = "gzip"
+	Bzip2    Format = "bzip2"
+	Xz       Format = "xz"
+	Zip      Format = "zip"
+	Tar      Format = "tar"
+	TarGzip  Format = "tar.gz"
+	TarBzip2 Format = "tar.bz2"
+	TarXz    Format = "tar.xz"
+)
+
+// headerSize is how much of a stream is inspected to detect its format. It
+// covers the magic field of a tar header.
+const headerSize = 512
+
+var (
+	gzipMagic     = []byte{0x1f, 0x8b}
+	bzip2Magic    = []byte("BZh")
+	xzMagic       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	tarMagic      = []byte("ustar")
+)
+
+// tarMagicOffset is the position of the magic field in a tar header.
+const tarMagicOffset = 257
+
+// Member is a file extracted by Expand.
+type Member struct {
+	Name string // File name inside the target directory
+	Path string // Full path of the extracted file
+	Size int64  // Decompressed size in bytes
+}
+
+// Detect returns the format of the file at path. Files that are neither
+// compressed nor archived are reported as Plain.
+func Detect(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Plain, err
+	}
+	defer f.Close()
+	return detect(bufio.NewReaderSize(f, headerSize))
+}
+
+// IsArchive reports whether files of the format can hold several members.
+func (f Format) IsArchive() bool {
+	return f == Zip || f.isTar()
+}
+
+// Open returns a reader over the decompressed content of the file at path
+// together with the name of that content: the file name without its
+// compression extension for compressed files, the base name of the first
+// file for archives, and the file name itself for plain files.
+func Open(file string) (io.ReadCloser, string, error) {
+	format, err := Detect(file)
+	if err != nil {
+		return nil, "", err
+	}
+	name := filepath.Base(file)
+	if format == Zip {
+		return openZip(file)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, "", err
+	}
+	if format == Plain {
+		return f, name, nil
+	}
+
+	r, err := decompress(format.compression(), bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, "", fmt.Errorf("invalid %s data in %s: %w", format, name, err)
+	}
+	if !format.isTar() {
+		return &readCloser{Reader: r, closers: []io.Closer{f}}, TrimExt(name), nil
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			f.Close()
+			return nil, "", fmt.Errorf("%s contains no files", name)
+		}
+		if err != nil {
+			f.Close()
+			return nil, "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if hdr.FileInfo().Mode().IsRegular() && !skipMember(hdr.Name) {
+			return &readCloser{Reader: tr, closers: []io.Closer{f}}, path.Base(memberPath(hdr.Name)), nil
+		}
+	}
+}
+
+// Expand decompresses the file at path into dir. A compressed file becomes a
+// single file named after it without the compression extension; an archive
+// becomes one file per member, named after the member's path inside the
+// archive with "/" replaced by "_". Hidden members and directories are
+// skipped. Names that already exist in dir get a " (n)" suffix.
+func Expand(path string, dir string) ([]Member, error) {
+	format, err := Detect(path)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(path)
+	switch format {
+	case Plain:
+		return nil, fmt.Errorf("%s is not compressed", name)
+	case Zip:
+		return expandZip(path, dir)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := decompress(format.compression(), bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s data in %s: %w", format, name, err)
+	}
+	if !format.isTar() {
+		m, err := writeMember(dir, TrimExt(name), r)
+		if err != nil {
+			return nil, err
+		}
+		return []Member{m}, nil
+	}
+
+	var members []Member
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return members, nil
+		}
+		if err != nil {
+			return members, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if !hdr.FileInfo().Mode().IsRegular() || skipMember(hdr.Name) {
+			continue
+		}
+		m, err := writeMember(dir, flatten(hdr.Name), tr)
+		if err != nil {
+			return members, err
+		}
+		members = append(members, m)
+	}
+}
+
+// TrimExt removes a compression extension (.gz, .bz2, .xz) from name.
+func TrimExt(name string) string {
+	ext := filepath.Ext(name)
+	switch strings.ToLower(ext) {
+	case ".gz", ".bz2", ".xz":
+		if trimmed := strings.TrimSuffix(name, ext); trimmed != "" {
+			return trimmed
+		}
+	}
+	return name
+}
+
+// detect inspects the head of r, looking inside compressed streams for a tar
+// header.
+func detect(r *bufio.Reader) (Format, error) {
+	head, err := r.Peek(headerSize)
+	if err != nil && err != io.EOF {
+		return Plain, err
+	}
+	switch {
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, zipEmptyMagic):
+		return Zip, nil
+	case isTarHeader(head):
+		return Tar, nil
+	}
+
+	var stream Format
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		stream = Gzip
+	case bytes.HasPrefix(head, bzip2Magic) && len(head) > 3 && head[3] >= '1' && head[3] <= '9':
+		stream = Bzip2
+	case bytes.HasPrefix(head, xzMagic):
+		stream = Xz
+	default:
+		return Plain, nil
+	}
+
+	dr, err := decompress(stream, r)
+	if err != nil {
+		return Plain, fmt.Errorf("invalid %s data: %w", stream, err)
+	}
+	inner := make([]byte, headerSize)
+	n, err := io.ReadFull(dr, inner)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Plain, fmt.Errorf("invalid %s data: %w", stream, err)
+	}
+	if isTarHeader(inner[:n]) {
+		switch stream {
+		case Gzip:
+			return TarGzip, nil
+		case Bzip2:
+			return TarBzip2, nil
+		}
+		return TarXz, nil
+	}
+	return stream, nil
+}
+
+// isTarHeader reports whether head starts with a POSIX tar header.
+func isTarHeader(head []byte) bool {
+	return len(head) >= tarMagicOffset+len(tarMagic) &&
+		bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic)
+}
+
+// isTar reports whether the format is a tar archive, compressed or not.
+func (f Format) isTar() bool {
+	return f == Tar || f == TarGzip || f == TarBzip2 || f == TarXz
+}
+
+// compression returns the compression applied to the whole file, or Plain.
+func (f Format) compression() Format {
+	switch f {
+	case Gzip, TarGzip:
+		return Gzip
+	case Bzip2, TarBzip2:
+		return Bzip2
+	case Xz, TarXz:
+		return Xz
+	}
+	return Plain
+}
+
+// decompress wraps r in a decompressor for the given compression.
+func decompress(compression Format, r io.Reader) (io.Reader, error) {
+	switch compression {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Bzip2:
+		return bzip2.NewReader(r), nil
+	case Xz:
+		return xz.NewReader(r)
+	}
+	return r, nil
+}
+
+// openZip opens the first file in the zip archive file.
+func openZip(file string) (io.ReadCloser, string, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", filepath.Base(file), err)
+	}
+	for _, zf := range zr.File {
+		if !zf.FileInfo().Mode().IsRegular() || skipMember(zf.Name) {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			zr.Close()
+			return nil, "", fmt.Errorf("failed to read %s: %w", zf.Name, err)
+		}
+		return &readCloser{Reader: rc, closers: []io.Closer{rc, zr}}, path.Base(memberPath(zf.Name)), nil
+	}
+	zr.Close()
+	return nil, "", fmt.Errorf("%s contains no files", filepath.Base(file))
+}
+
+// expandZip extracts every file of the zip archive file into dir.
+func expandZip(file string, dir string) ([]Member, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(file), err)
+	}
+	defer zr.Close()
+
+	var members []Member
+	for _, zf := range zr.File {
+		if !zf.FileInfo().Mode().IsRegular() || skipMember(zf.Name) {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return members, fmt.Errorf("failed to read %s: %w", zf.Name, err)
+		}
+		m, err := writeMember(dir, flatten(zf.Name), rc)
+		rc.Close()
+		if err != nil {
+			return members, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// writeMember copies r into a new file in dir named name, or a " (n)"
+// variant of it if name is taken.
+func writeMember(dir string, name string, r io.Reader) (Member, error) {
+	name = uniqueName(dir, name)
+	dst := filepath.Join(dir, name)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return Member{}, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	n, err := io.Copy(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return Member{}, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	return Member{Name: name, Path: dst, Size: n}, nil
+}
+
+// uniqueName returns name, or "stem (n).ext" with the smallest n >= 2 that
+// doesn't exist in dir yet.
+func uniqueName(dir string, name string) string {
+	if _, err := os.Lstat(filepath.Join(dir, name)); os.IsNotExist(err) {
+		return name
+	}
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, n, ext)
+		if _, err := os.Lstat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// memberPath normalises an archive member name to a clean slash-separated
+// relative path.
+func memberPath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// flatten turns a member path into a file name, e.g. "web1/access.log"
+// becomes "web1_access.log".
+func flatten(name string) string {
+	return strings.ReplaceAll(memberPath(name), "/", "_")
+}
+
+// skipMember reports whether an archive member is hidden, e.g. ".DS_Store",
+// macOS "__MACOSX" metadata or anything under a dot directory.
+func skipMember(name string) bool {
+	for _, seg := range strings.Split(strings.ReplaceAll(name, "\\", "/"), "/") {
+		if seg == "__MACOSX" || (strings.HasPrefix(seg, ".") && seg != ".") {
+			return true
+		}
+	}
+	return memberPath(name) == ""
+}
+
+// readCloser closes several underlying readers.
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes every underlying reader and returns the first error.
+func (rc *readCloser) Close() error {
+	var first error
+	for _, c := range rc.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
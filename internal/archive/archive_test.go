@@ -0,0 +1,258 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+	"pgregory.net/rapid"
+)
+
+// bzip2Line is "bzip2 line\n" compressed with bzip2; the standard library
+// can only decompress bzip2.
+var bzip2Line = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x80, 0xb0, 0x19, 0xcc, 0x00, 0x00,
+	0x01, 0xd9, 0x80, 0x00, 0x10, 0x40, 0x00, 0x10, 0x00, 0x12, 0x25, 0x40, 0x10, 0x20, 0x00, 0x22,
+	0x06, 0x9a, 0x32, 0x10, 0x03, 0x0c, 0x08, 0x24, 0xf9, 0xc3, 0xf1, 0x77, 0x24, 0x53, 0x85, 0x09,
+	0x08, 0x0b, 0x01, 0x9c, 0xc0,
+}
+
+// entry is a file to put into a test archive.
+type entry struct {
+	name string
+	data []byte
+}
+
+// compressWith compresses data with the given stream compression.
+func compressWith(t testing.TB, compression Format, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case Plain:
+		return data
+	case Gzip:
+		w = gzip.NewWriter(&buf)
+	case Xz:
+		xw, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("xz writer: %v", err)
+		}
+		w = xw
+	default:
+		t.Fatalf("cannot write %s", compression)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// tarOf builds a tar archive of entries.
+func tarOf(entries ...entry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg})
+		tw.Write(e.data)
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+// zipOf builds a zip archive of entries.
+func zipOf(entries ...entry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, _ := zw.Create(e.name)
+		w.Write(e.data)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// Feature: network-log-formatter, Property 18: 透明解压内容一致
+// For any log content packed into any supported format, the format is
+// detected from the bytes alone and Open returns exactly the original
+// content.
+func TestProperty18_OpenReturnsOriginalContent(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		dir, err := os.MkdirTemp("", "archive-test-*")
+		if err != nil {
+			rt.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		content := rapid.SliceOfN(rapid.Byte(), 0, 2000).Draw(rt, "content")
+		format := rapid.SampledFrom([]Format{Gzip, Xz, Zip, Tar, TarGzip, TarXz}).Draw(rt, "format")
+
+		var data []byte
+		switch format {
+		case Zip:
+			data = zipOf(entry{"access.log", content})
+		case Tar, TarGzip, TarXz:
+			data = compressWith(t, format.compression(), tarOf(entry{"access.log", content}))
+		default:
+			data = compressWith(t, format, content)
+		}
+		// The name carries no hint of the format
+		path := filepath.Join(dir, "access.log.dat")
+		os.WriteFile(path, data, 0644)
+
+		got, err := Detect(path)
+		if err != nil {
+			rt.Fatalf("detect failed: %v", err)
+		}
+		if got != format {
+			rt.Fatalf("expected %q, detected %q", format, got)
+		}
+
+		rc, _, err := Open(path)
+		if err != nil {
+			rt.Fatalf("open failed: %v", err)
+		}
+		defer rc.Close()
+		read, err := io.ReadAll(rc)
+		if err != nil {
+			rt.Fatalf("read failed: %v", err)
+		}
+		if !bytes.Equal(read, content) {
+			rt.Fatalf("content differs: got %d bytes, want %d", len(read), len(content))
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: plain text is not mistaken for a compressed file
+func TestDetect_Plain(t *testing.T) {
+	dir := t.TempDir()
+	for _, text := range []string{"", "BZh is not bzip2\n", "PK but not zip"} {
+		path := filepath.Join(dir, "plain.log")
+		os.WriteFile(path, []byte(text), 0644)
+		if got, err := Detect(path); err != nil || got != Plain {
+			t.Errorf("%q: expected plain, got %q (%v)", text, got, err)
+		}
+	}
+}
+
+// Unit test: bzip2 files are detected and decompressed
+func TestOpen_Bzip2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.3.bz2")
+	os.WriteFile(path, bzip2Line, 0644)
+
+	rc, name, err := Open(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if string(data) != "bzip2 line\n" || name != "app.log.3" {
+		t.Fatalf("unexpected content %q or name %q", data, name)
+	}
+}
+
+// Unit test: archives expand to one file per member named after its path,
+// skipping hidden members
+func TestExpand_TarGzip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs.tar.gz")
+	os.WriteFile(path, compressWith(t, Gzip, tarOf(
+		entry{"./web1/access.log", []byte("a\n")},
+		entry{"web2/access.log", []byte("bb\n")},
+		entry{".DS_Store", []byte("x")},
+		entry{"web1/.cache/tmp.log", []byte("x")},
+	)), 0644)
+
+	out := filepath.Join(dir, "out")
+	os.Mkdir(out, 0755)
+	members, err := Expand(path, out)
+	if err != nil {
+		t.Fatalf("expand failed: %v", err)
+	}
+	if len(members) != 2 || members[0].Name != "web1_access.log" || members[1].Name != "web2_access.log" {
+		t.Fatalf("unexpected members: %+v", members)
+	}
+	if members[1].Size != 3 {
+		t.Fatalf("expected size 3, got %d", members[1].Size)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 2 {
+		t.Fatalf("expected only the members in the output directory, got %d entries", len(entries))
+	}
+}
+
+// Unit test: members never escape the target directory and clashing names
+// are made unique
+func TestExpand_ZipNames(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.zip")
+	os.WriteFile(path, zipOf(
+		entry{"../../evil.log", []byte("x")},
+		entry{"/abs/app.log", []byte("1")},
+		entry{"abs/app.log", []byte("2")},
+		entry{"__MACOSX/._app.log", []byte("x")},
+	), 0644)
+
+	out := filepath.Join(dir, "out")
+	os.Mkdir(out, 0755)
+	members, err := Expand(path, out)
+	if err != nil {
+		t.Fatalf("expand failed: %v", err)
+	}
+	var got []string
+	for _, m := range members {
+		got = append(got, m.Name)
+		if filepath.Dir(m.Path) != out {
+			t.Fatalf("member %s written outside the target directory", m.Path)
+		}
+	}
+	sort.Strings(got)
+	if fmt.Sprint(got) != "[abs_app (2).log abs_app.log]" {
+		t.Fatalf("unexpected member names %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.log")); err == nil {
+		t.Fatal("member escaped the target directory")
+	}
+}
+
+// Unit test: a compressed file expands to its name without the extension
+func TestExpand_SingleStream(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log.2.xz")
+	os.WriteFile(path, compressWith(t, Xz, []byte("line\n")), 0644)
+
+	members, err := Expand(path, dir)
+	if err != nil {
+		t.Fatalf("expand failed: %v", err)
+	}
+	if len(members) != 1 || members[0].Name != "app.log.2" {
+		t.Fatalf("unexpected members: %+v", members)
+	}
+	if _, err := Expand(members[0].Path, dir); err == nil {
+		t.Fatal("expected error expanding a plain file")
+	}
+}
+
+// Unit test: compression extensions are trimmed
+func TestTrimExt(t *testing.T) {
+	cases := map[string]string{
+		"app.log.1.gz": "app.log.1",
+		"app.log.BZ2":  "app.log",
+		"app.log.xz":   "app.log",
+		"app.log":      "app.log",
+		".gz":          ".gz",
+	}
+	for in, want := range cases {
+		if got := TrimExt(in); got != want {
+			t.Errorf("TrimExt(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
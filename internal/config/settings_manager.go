@@ -23,17 +23,20 @@ func NewSettingsManager(configPath string) *SettingsManager {
 // defaultSettings returns the default application settings.
 func defaultSettings() model.Settings {
 	showWizard := true
+	resumeJobs := true
 	return model.Settings{
 		LLM: model.LLMConfig{
 			BaseURL:   "",
 			APIKey:    "",
 			ModelName: "",
 		},
-		UvPath:           "uv",
-		DefaultInputDir:  "",
-		DefaultOutputDir: "",
-		SampleLines:      5,
-		ShowWizard:       &showWizard,
+		UvPath:                "uv",
+		DefaultInputDir:       "",
+		DefaultOutputDir:      "",
+		SampleLines:           5,
+		ShowWizard:            &showWizard,
+		MaxConcurrentJobs:     2,
+		ResumeInterruptedJobs: &resumeJobs,
 	}
 }
 
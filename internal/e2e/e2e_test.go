@@ -600,8 +600,8 @@ func TestE2E_RealLLM_FullPipeline(t *testing.T) {
 	}
 
 	// Sanity checks on generated code
-	if !strings.Contains(generatedCode, "openpyxl") {
-		t.Error("generated code does not mention openpyxl")
+	if !strings.Contains(generatedCode, "logforge.row") {
+		t.Error("generated code does not stream rows with logforge.row")
 	}
 	if !strings.Contains(generatedCode, "--input") {
 		t.Error("generated code does not contain --input argument")
@@ -757,6 +757,11 @@ type testLLMRepairer struct {
 	llmClient *agent.LLMClient
 }
 
+// RepairQuality isn't reached here: the tests set no quality thresholds.
+func (r *testLLMRepairer) RepairQuality(ctx context.Context, code string, problem string) (string, error) {
+	return r.RepairCode(ctx, code, problem)
+}
+
 func (r *testLLMRepairer) RepairCode(ctx context.Context, code string, errorMsg string) (string, error) {
 	messages := []model.Message{
 		{
@@ -0,0 +1,65 @@
+package enrich
+
+import "net/netip"
+
+// Address ranges Classify tells apart.
+const (
+	Public        = "public"
+	Private       = "private" // RFC 1918 and IPv6 unique local addresses
+	Shared        = "shared"  // carrier-grade NAT, RFC 6598
+	Loopback      = "loopback"
+	LinkLocal     = "link-local"
+	Multicast     = "multicast"
+	Broadcast     = "broadcast"     // 255.255.255.255
+	Documentation = "documentation" // example ranges of RFC 5737 and RFC 3849
+	Unspecified   = "unspecified"   // 0.0.0.0 and ::
+	Reserved      = "reserved"      // any other special-purpose range
+)
+
+// ranges lists the special-purpose ranges of the IANA registries, more
+// specific ranges first.
+var ranges = []struct {
+	prefix netip.Prefix
+	class  string
+}{
+	{netip.MustParsePrefix("0.0.0.0/32"), Unspecified},
+	{netip.MustParsePrefix("0.0.0.0/8"), Reserved},
+	{netip.MustParsePrefix("10.0.0.0/8"), Private},
+	{netip.MustParsePrefix("100.64.0.0/10"), Shared},
+	{netip.MustParsePrefix("127.0.0.0/8"), Loopback},
+	{netip.MustParsePrefix("169.254.0.0/16"), LinkLocal},
+	{netip.MustParsePrefix("172.16.0.0/12"), Private},
+	{netip.MustParsePrefix("192.0.0.0/24"), Reserved},
+	{netip.MustParsePrefix("192.0.2.0/24"), Documentation},
+	{netip.MustParsePrefix("192.88.99.0/24"), Reserved},
+	{netip.MustParsePrefix("192.168.0.0/16"), Private},
+	{netip.MustParsePrefix("198.18.0.0/15"), Reserved},
+	{netip.MustParsePrefix("198.51.100.0/24"), Documentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), Documentation},
+	{netip.MustParsePrefix("224.0.0.0/4"), Multicast},
+	{netip.MustParsePrefix("255.255.255.255/32"), Broadcast},
+	{netip.MustParsePrefix("240.0.0.0/4"), Reserved},
+	{netip.MustParsePrefix("::/128"), Unspecified},
+	{netip.MustParsePrefix("::1/128"), Loopback},
+	{netip.MustParsePrefix("2001:db8::/32"), Documentation},
+	{netip.MustParsePrefix("fc00::/7"), Private},
+	{netip.MustParsePrefix("fe80::/10"), LinkLocal},
+	{netip.MustParsePrefix("ff00::/8"), Multicast},
+	{netip.MustParsePrefix("2000::/3"), Public},
+}
+
+// Classify returns the range of addr without needing a database. IPv4
+// addresses mapped into IPv6 are classified as IPv4; IPv6 addresses
+// outside the global unicast range 2000::/3 are reserved.
+func Classify(addr netip.Addr) string {
+	addr = addr.Unmap()
+	for _, r := range ranges {
+		if r.prefix.Contains(addr) {
+			return r.class
+		}
+	}
+	if addr.Is6() {
+		return Reserved
+	}
+	return Public
+}
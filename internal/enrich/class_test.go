@@ -0,0 +1,45 @@
+package enrich
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// --- Unit Tests ---
+
+// Unit test: special-purpose ranges are told apart without a database
+func TestClassify(t *testing.T) {
+	cases := map[string]string{
+		"8.8.8.8":         Public,
+		"10.1.2.3":        Private,
+		"172.31.255.1":    Private,
+		"172.32.0.1":      Public,
+		"192.168.1.1":     Private,
+		"100.64.0.1":      Shared,
+		"127.0.0.1":       Loopback,
+		"169.254.10.1":    LinkLocal,
+		"224.0.0.251":     Multicast,
+		"255.255.255.255": Broadcast,
+		"240.0.0.1":       Reserved,
+		"0.0.0.0":         Unspecified,
+		"0.1.2.3":         Reserved,
+		"192.0.2.10":      Documentation,
+		"198.51.100.7":    Documentation,
+		"203.0.113.9":     Documentation,
+		"198.18.0.1":      Reserved,
+		"::ffff:10.0.0.1": Private,
+		"::":              Unspecified,
+		"::1":             Loopback,
+		"fd12:3456::1":    Private,
+		"fe80::1":         LinkLocal,
+		"ff02::1":         Multicast,
+		"2001:db8::1":     Documentation,
+		"2606:4700::1111": Public,
+		"100::1":          Reserved,
+	}
+	for addr, want := range cases {
+		if got := Classify(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Classify(%s) = %q, want %q", addr, got, want)
+		}
+	}
+}
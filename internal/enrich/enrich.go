@@ -0,0 +1,286 @@
+// Package enrich adds columns describing the IP addresses in the rows
+// scripts stream: the range of each address, which needs no database, and
+// the country, city, autonomous system and organization from local MaxMind
+// DB files.
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/schema"
+)
+
+// SampleRows is how many rows of a file are held back to find its IP
+// columns when the schema doesn't declare every column.
+const SampleRows = 100
+
+// minIPShare is the share of a column's values in the sample that must be
+// IP addresses for it to be an IP column; the rest are usually
+// placeholders such as "-".
+const minIPShare = 0.8
+
+// cacheSize bounds the addresses whose columns are remembered.
+const cacheSize = 65536
+
+// Suffixes of the columns added for an IP column.
+const (
+	RangeSuffix   = "_range"
+	CountrySuffix = "_country"
+	CitySuffix    = "_city"
+	ASNSuffix     = "_asn"
+	OrgSuffix     = "_org"
+)
+
+// Enricher adds the columns of a run's enrichment to the rows of its
+// files. It isn't safe for concurrent use.
+type Enricher struct {
+	params   model.EnrichParams
+	schema   []model.ColumnSchema
+	city     *Reader
+	asn      *Reader
+	suffixes []string
+	cache    map[netip.Addr][]any
+}
+
+// Validate checks that enrichment adds something and that GeoIP lookups
+// have a database.
+func Validate(params *model.EnrichParams) error {
+	if params == nil {
+		return nil
+	}
+	if !params.Classify && !params.GeoIP {
+		return fmt.Errorf("enrichment needs the address range or GeoIP")
+	}
+	if params.GeoIP && params.CityDB == "" && params.ASNDB == "" {
+		return fmt.Errorf("GeoIP enrichment needs a City, Country or ASN database in the settings")
+	}
+	return nil
+}
+
+// New opens the databases of params. Columns declared in columns keep
+// their type; the IP columns among the others are found by their values.
+func New(params model.EnrichParams, columns []model.ColumnSchema) (*Enricher, error) {
+	if err := Validate(&params); err != nil {
+		return nil, err
+	}
+	e := &Enricher{params: params, schema: columns, cache: make(map[netip.Addr][]any)}
+	if params.Classify {
+		e.suffixes = append(e.suffixes, RangeSuffix)
+	}
+	if params.GeoIP && params.CityDB != "" {
+		r, err := OpenReader(params.CityDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+		}
+		e.city = r
+		e.suffixes = append(e.suffixes, CountrySuffix)
+		if !strings.Contains(r.DatabaseType(), "Country") {
+			e.suffixes = append(e.suffixes, CitySuffix)
+		}
+	}
+	if params.GeoIP && params.ASNDB != "" {
+		r, err := OpenReader(params.ASNDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ASN database: %w", err)
+		}
+		e.asn = r
+		e.suffixes = append(e.suffixes, ASNSuffix, OrgSuffix)
+	}
+	return e, nil
+}
+
+// File returns the enrichment of a file with the given columns.
+func (e *Enricher) File(columns []string) *File {
+	f := &File{e: e, names: output.Columns(columns), types: make([]string, len(columns))}
+	for i, c := range f.names {
+		f.types[i] = schema.Declared(e.schema, c)
+		if f.types[i] == "" {
+			f.undeclared++
+		}
+	}
+	if f.undeclared == 0 {
+		f.decide()
+	}
+	return f
+}
+
+// File enriches the rows of one input file. Until its IP columns are
+// known it holds back the rows it is given.
+type File struct {
+	e          *Enricher
+	names      []string
+	types      []string // declared type of each column; "" when undeclared
+	undeclared int
+	ips        []int // the IP columns, once decided
+	columns    []string
+	pending    [][]any
+}
+
+// Columns returns the columns of the enriched rows, or nil while the IP
+// columns aren't known yet.
+func (f *File) Columns() []string {
+	return f.columns
+}
+
+// IPColumns returns the IP columns found, once Columns is known.
+func (f *File) IPColumns() []string {
+	var names []string
+	for _, i := range f.ips {
+		names = append(names, f.names[i])
+	}
+	return names
+}
+
+// Add takes a row and returns the enriched rows ready to be written: none
+// while rows are held back, the held back rows once the sample is full.
+func (f *File) Add(values []any) [][]any {
+	if f.columns != nil {
+		return [][]any{f.enrich(values)}
+	}
+	f.pending = append(f.pending, values)
+	if len(f.pending) < SampleRows {
+		return nil
+	}
+	return f.Flush()
+}
+
+// Flush decides the IP columns from the rows held back and returns them
+// enriched.
+func (f *File) Flush() [][]any {
+	if f.columns == nil {
+		f.decide()
+	}
+	rows := make([][]any, len(f.pending))
+	for i, values := range f.pending {
+		rows[i] = f.enrich(values)
+	}
+	f.pending = nil
+	return rows
+}
+
+// decide picks the IP columns: the declared ones and the undeclared ones
+// whose values in the rows held back are mostly IP addresses.
+func (f *File) decide() {
+	for i, typ := range f.types {
+		switch typ {
+		case schema.IP:
+			f.ips = append(f.ips, i)
+		case "":
+			ips, values := 0, 0
+			for _, row := range f.pending {
+				if i >= len(row) {
+					continue
+				}
+				if s, ok := row[i].(string); ok && s != "" {
+					values++
+					if schema.IsIP(s) {
+						ips++
+					}
+				}
+			}
+			if ips > 0 && float64(ips) >= minIPShare*float64(values) {
+				f.ips = append(f.ips, i)
+			}
+		}
+	}
+	f.columns = append([]string(nil), f.names...)
+	for _, i := range f.ips {
+		for _, suffix := range f.e.suffixes {
+			f.columns = append(f.columns, f.names[i]+suffix)
+		}
+	}
+}
+
+// enrich returns values fitted to the file's columns with the added
+// columns after them.
+func (f *File) enrich(values []any) []any {
+	row := make([]any, len(f.names), len(f.columns))
+	copy(row, values)
+	for _, i := range f.ips {
+		row = append(row, f.e.lookup(row[i])...)
+	}
+	return row
+}
+
+// lookup returns the added columns of an IP column's value, all nil for
+// values that aren't addresses.
+func (e *Enricher) lookup(v any) []any {
+	s, _ := v.(string)
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return make([]any, len(e.suffixes))
+	}
+	addr = addr.Unmap()
+	if cols, ok := e.cache[addr]; ok {
+		return cols
+	}
+	class := Classify(addr)
+	var city, asn map[string]any
+	if class == Public {
+		// Other ranges aren't in the databases
+		city = e.find(e.city, addr)
+		asn = e.find(e.asn, addr)
+	}
+	cols := make([]any, 0, len(e.suffixes))
+	for _, suffix := range e.suffixes {
+		var v any
+		switch suffix {
+		case RangeSuffix:
+			v = class
+		case CountrySuffix:
+			v = e.name(city, "country")
+			if v == nil {
+				v = e.name(city, "registered_country")
+			}
+		case CitySuffix:
+			v = e.name(city, "city")
+		case ASNSuffix:
+			if n, ok := asn["autonomous_system_number"].(uint64); ok {
+				v = json.Number(strconv.FormatUint(n, 10))
+			}
+		case OrgSuffix:
+			if s, ok := asn["autonomous_system_organization"].(string); ok {
+				v = s
+			}
+		}
+		cols = append(cols, v)
+	}
+	if len(e.cache) >= cacheSize {
+		clear(e.cache)
+	}
+	e.cache[addr] = cols
+	return cols
+}
+
+// find returns the record of addr in a database; nil when there is none.
+// A record that can't be read counts as none.
+func (e *Enricher) find(r *Reader, addr netip.Addr) map[string]any {
+	if r == nil {
+		return nil
+	}
+	v, err := r.Lookup(addr)
+	if err != nil {
+		return nil
+	}
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// name returns the name of a place in a City or Country record in the
+// run's language, falling back to English.
+func (e *Enricher) name(record map[string]any, place string) any {
+	p, _ := record[place].(map[string]any)
+	names, _ := p["names"].(map[string]any)
+	for _, lang := range []string{e.params.Language, "en"} {
+		if s, ok := names[lang].(string); ok && s != "" {
+			return s
+		}
+	}
+	return nil
+}
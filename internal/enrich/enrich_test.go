@@ -0,0 +1,145 @@
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// testDatabases writes a City and an ASN database and returns params that
+// use them.
+func testDatabases(t *testing.T) model.EnrichParams {
+	dir := t.TempDir()
+	city := writeMMDB(t, dir, "city.mmdb", "GeoLite2-City", []mmdbNetwork{
+		{"81.2.69.0/24", map[string]any{
+			"city":    map[string]any{"names": map[string]any{"en": "London", "zh-CN": "伦敦"}},
+			"country": map[string]any{"iso_code": "GB", "names": map[string]any{"en": "United Kingdom", "zh-CN": "英国"}},
+		}},
+		{"2001:218::/32", map[string]any{
+			"registered_country": map[string]any{"iso_code": "JP", "names": map[string]any{"en": "Japan"}},
+		}},
+	})
+	asn := writeMMDB(t, dir, "asn.mmdb", "GeoLite2-ASN", []mmdbNetwork{
+		{"81.2.69.0/24", map[string]any{"autonomous_system_number": uint32(20712), "autonomous_system_organization": "Andrews & Arnold Ltd"}},
+	})
+	return model.EnrichParams{GeoIP: true, CityDB: city, ASNDB: asn}
+}
+
+// --- Unit Tests ---
+
+// Unit test: IP columns are found by their values once the sample is full
+// and every column of an IP column follows the row's own columns
+func TestFile_DetectsIPColumns(t *testing.T) {
+	params := testDatabases(t)
+	params.Classify = true
+	params.Language = "zh-CN"
+	e, err := New(params, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	f := e.File([]string{"src", "action", "peer", ""})
+	var rows [][]any
+	for i := 0; i < SampleRows-1; i++ {
+		if got := f.Add([]any{"81.2.69.160", "allow", "-", "10.0.0.1"}); got != nil {
+			t.Fatalf("row %d written before the sample was full", i)
+		}
+	}
+	if f.Columns() != nil {
+		t.Fatal("columns known before the sample was full")
+	}
+	rows = append(rows, f.Add([]any{"2001:218:1::1", "deny", "9.9.9.9"})...)
+	rows = append(rows, f.Add([]any{"bad", "deny", "1.1.1.1", "fe80::1"})...)
+	if len(rows) != SampleRows+1 {
+		t.Fatalf("%d rows, want %d", len(rows), SampleRows+1)
+	}
+	want := []string{
+		"src", "action", "peer", "column_4",
+		"src_range", "src_country", "src_city", "src_asn", "src_org",
+		"column_4_range", "column_4_country", "column_4_city", "column_4_asn", "column_4_org",
+	}
+	if !reflect.DeepEqual(f.Columns(), want) {
+		t.Fatalf("columns = %v, want %v", f.Columns(), want)
+	}
+	if got := f.IPColumns(); !reflect.DeepEqual(got, []string{"src", "column_4"}) {
+		t.Errorf("IP columns = %v", got)
+	}
+	wantRows := [][]any{
+		{"81.2.69.160", "allow", "-", "10.0.0.1", Public, "英国", "伦敦", json.Number("20712"), "Andrews & Arnold Ltd", Private, nil, nil, nil, nil},
+		{"2001:218:1::1", "deny", "9.9.9.9", nil, Public, "Japan", nil, nil, nil, nil, nil, nil, nil, nil},
+		{"bad", "deny", "1.1.1.1", "fe80::1", nil, nil, nil, nil, nil, LinkLocal, nil, nil, nil, nil},
+	}
+	for i, got := range [][]any{rows[0], rows[len(rows)-2], rows[len(rows)-1]} {
+		if w := wantRows[i]; !reflect.DeepEqual(got, w) {
+			t.Errorf("row %d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+// Unit test: a file whose columns are all declared starts at once, and
+// declared types override the values
+func TestFile_DeclaredColumns(t *testing.T) {
+	e, err := New(model.EnrichParams{Classify: true}, []model.ColumnSchema{
+		{Name: "src", Type: "ip"},
+		{Name: "host", Type: "string"},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	f := e.File([]string{"src", "host"})
+	if want := []string{"src", "host", "src_range"}; !reflect.DeepEqual(f.Columns(), want) {
+		t.Fatalf("columns = %v, want %v", f.Columns(), want)
+	}
+	got := f.Add([]any{"192.168.1.1", "10.0.0.1"})
+	if want := [][]any{{"192.168.1.1", "10.0.0.1", Private}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+
+	// A short file is decided when it is flushed
+	f = e.File([]string{"host", "dst"})
+	f.Add([]any{"a", "8.8.8.8"})
+	rows := f.Flush()
+	if want := [][]any{{"a", "8.8.8.8", Public}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("flushed rows = %v, want %v", rows, want)
+	}
+	if got := f.IPColumns(); !reflect.DeepEqual(got, []string{"dst"}) {
+		t.Errorf("IP columns = %v", got)
+	}
+}
+
+// Unit test: a Country database adds no city column, and an enrichment
+// without anything to add or without databases is refused
+func TestNew(t *testing.T) {
+	country := writeMMDB(t, t.TempDir(), "country.mmdb", "GeoLite2-Country", []mmdbNetwork{
+		{"81.2.69.0/24", map[string]any{"country": map[string]any{"names": map[string]any{"en": "United Kingdom"}}}},
+	})
+	e, err := New(model.EnrichParams{GeoIP: true, CityDB: country}, []model.ColumnSchema{{Name: "ip", Type: "ip"}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := e.File([]string{"ip"}).Columns(); !reflect.DeepEqual(got, []string{"ip", "ip_country"}) {
+		t.Errorf("columns = %v", got)
+	}
+
+	for _, p := range []model.EnrichParams{{}, {GeoIP: true}, {GeoIP: true, CityDB: "missing.mmdb"}} {
+		if _, err := New(p, nil); err == nil {
+			t.Errorf("%+v: expected an error", p)
+		}
+	}
+	if err := Validate(nil); err != nil {
+		t.Errorf("no enrichment: %v", err)
+	}
+}
+
+// Unit test: lookups are cached per address and the cache stays bounded
+func TestEnricher_Cache(t *testing.T) {
+	e, _ := New(model.EnrichParams{Classify: true}, nil)
+	for i := 0; i < cacheSize+10; i++ {
+		e.lookup(fmt.Sprintf("10.%d.%d.%d", i>>16&255, i>>8&255, i&255))
+	}
+	if len(e.cache) > cacheSize {
+		t.Errorf("cache holds %d addresses", len(e.cache))
+	}
+}
@@ -0,0 +1,367 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker starts the metadata at the end of a MaxMind DB file.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSeparator is the gap of zero bytes between the search tree and the
+// data section.
+const dataSeparator = 16
+
+// Reader looks up addresses in a MaxMind DB (.mmdb) file, such as the
+// GeoLite2 City, Country and ASN databases, read into memory. It follows
+// the MaxMind DB format 2.0 and is safe for concurrent use.
+type Reader struct {
+	path         string
+	buf          []byte
+	data         []byte // the data section
+	nodeCount    uint32
+	recordSize   int // bits per record: 24, 28 or 32
+	ipVersion    int
+	databaseType string
+	ipv4Start    uint32 // node of the IPv4 space in an IPv6 tree
+}
+
+// OpenReader reads the database at path.
+func OpenReader(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.path = path
+	return r, nil
+}
+
+// newReader parses the metadata and locates the sections of a database.
+func newReader(buf []byte) (*Reader, error) {
+	at := bytes.LastIndex(buf, metadataMarker)
+	if at < 0 {
+		return nil, fmt.Errorf("not a MaxMind DB file")
+	}
+	meta := buf[at+len(metadataMarker):]
+	v, _, err := (&decoder{buf: meta}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("bad metadata: %w", err)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("bad metadata")
+	}
+	uintField := func(key string) uint64 {
+		n, _ := m[key].(uint64)
+		return n
+	}
+	r := &Reader{
+		buf:        buf,
+		nodeCount:  uint32(uintField("node_count")),
+		recordSize: int(uintField("record_size")),
+		ipVersion:  int(uintField("ip_version")),
+	}
+	r.databaseType, _ = m["database_type"].(string)
+	if major := uintField("binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("unsupported format version %d", major)
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported IP version %d", r.ipVersion)
+	}
+	treeSize := int(r.nodeCount) * r.recordSize / 4
+	if treeSize+dataSeparator > at {
+		return nil, fmt.Errorf("search tree exceeds the file")
+	}
+	r.data = buf[treeSize+dataSeparator : at]
+	if r.ipVersion == 6 {
+		node := uint32(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// DatabaseType returns the type the database declares, such as
+// "GeoLite2-City".
+func (r *Reader) DatabaseType() string {
+	return r.databaseType
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a node.
+func (r *Reader) record(node uint32, bit int) uint32 {
+	size := r.recordSize / 4 // bytes per node
+	b := r.buf[int(node)*size : int(node)*size+size]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	case 28:
+		if bit == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	}
+	return binary.BigEndian.Uint32(b[bit*4:])
+}
+
+// Lookup returns the record of the network containing addr, decoded into
+// maps, slices, strings, uint64, int64, float64, bool and []byte; nil
+// when the database has none. IPv6 addresses aren't found in an IPv4
+// database.
+func (r *Reader) Lookup(addr netip.Addr) (any, error) {
+	addr = addr.Unmap()
+	var bits []byte
+	node := uint32(0)
+	if addr.Is4() {
+		a := addr.As4()
+		bits = a[:]
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else {
+		if r.ipVersion == 4 {
+			return nil, nil
+		}
+		a := addr.As16()
+		bits = a[:]
+	}
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		node = r.record(node, int(bits[i/8]>>(7-i%8)&1))
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, fmt.Errorf("%s: search tree ends inside the tree", r.path)
+	}
+	offset := int(node-r.nodeCount) - dataSeparator
+	if offset < 0 || offset >= len(r.data) {
+		return nil, fmt.Errorf("%s: record points outside the data section", r.path)
+	}
+	v, _, err := (&decoder{buf: r.data}).decode(offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.path, err)
+	}
+	return v, nil
+}
+
+// Data types of the data section.
+const (
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEnd       = 13
+	typeBool      = 14
+	typeFloat     = 15
+)
+
+// maxDepth bounds the nesting of decoded values, so a corrupt file can't
+// recurse without end.
+const maxDepth = 32
+
+// decoder decodes values of a data section. Pointers are offsets into buf.
+type decoder struct {
+	buf   []byte
+	depth int
+}
+
+var errTruncated = fmt.Errorf("data section truncated")
+
+// decode decodes the value at offset and returns the offset after it.
+func (d *decoder) decode(offset int) (any, int, error) {
+	if d.depth > maxDepth {
+		return nil, 0, fmt.Errorf("data nested too deeply")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		d.depth++
+		v, _, err := d.decode(target)
+		d.depth--
+		return v, next, err
+	}
+	return d.value(typ, size, offset)
+}
+
+// control reads the control byte at offset and returns the type, the
+// size and the offset of the payload. For pointers the size holds the
+// three size bits and the value bits.
+func (d *decoder) control(offset int) (typ int, size int, next int, err error) {
+	if offset >= len(d.buf) {
+		return 0, 0, 0, errTruncated
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ = int(ctrl >> 5)
+	if typ == typePointer {
+		return typ, int(ctrl & 0x1f), offset, nil
+	}
+	if typ == 0 {
+		if offset >= len(d.buf) {
+			return 0, 0, 0, errTruncated
+		}
+		typ = 7 + int(d.buf[offset])
+		offset++
+	}
+	size = int(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28 // bytes of the size
+		if offset+n > len(d.buf) {
+			return 0, 0, 0, errTruncated
+		}
+		extra := 0
+		for _, b := range d.buf[offset : offset+n] {
+			extra = extra<<8 | int(b)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// pointer returns the target of a pointer and the offset after it.
+func (d *decoder) pointer(bits int, offset int) (int, int, error) {
+	n := bits>>3&3 + 1 // bytes after the control byte
+	if offset+n > len(d.buf) {
+		return 0, 0, errTruncated
+	}
+	p := 0
+	if n < 4 {
+		p = bits & 7
+	}
+	for _, b := range d.buf[offset : offset+n] {
+		p = p<<8 | int(b)
+	}
+	switch n {
+	case 2:
+		p += 2048
+	case 3:
+		p += 526336
+	}
+	if p >= len(d.buf) {
+		return 0, 0, fmt.Errorf("pointer outside the data section")
+	}
+	return p, offset + n, nil
+}
+
+// value decodes a value of the given type and size at offset.
+func (d *decoder) value(typ int, size int, offset int) (any, int, error) {
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		d.depth++
+		defer func() { d.depth-- }()
+		for i := 0; i < size; i++ {
+			k, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			}
+			v, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 1024))
+		d.depth++
+		defer func() { d.depth-- }()
+		for i := 0; i < size; i++ {
+			v, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEnd:
+		return nil, offset, nil
+	}
+	if offset+size > len(d.buf) {
+		return nil, 0, errTruncated
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of %d bytes", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of %d bytes", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("unsigned integer of %d bytes", size)
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("integer of %d bytes", size)
+		}
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		if size == 4 {
+			return int64(int32(n)), next, nil
+		}
+		return int64(n), next, nil
+	}
+	return nil, 0, fmt.Errorf("unknown data type %d", typ)
+}
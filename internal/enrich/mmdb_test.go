@@ -0,0 +1,296 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// mmdbPointer makes buildMMDB refer to the data of an earlier network
+// instead of writing it again.
+type mmdbPointer string
+
+// mmdbNetwork is a network of a test database and its record.
+type mmdbNetwork struct {
+	prefix string
+	data   any
+}
+
+// encodeSize appends a control byte of typ with size, and the extended
+// type and size bytes it needs.
+func encodeSize(buf []byte, typ int, size int) []byte {
+	ctrl := byte(typ << 5)
+	if typ > 7 {
+		ctrl = 0
+	}
+	var extra []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		extra = []byte{byte(size - 29)}
+	case size < 65821:
+		ctrl |= 30
+		extra = binary.BigEndian.AppendUint16(nil, uint16(size-285))
+	default:
+		ctrl |= 31
+		n := size - 65821
+		extra = []byte{byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+	buf = append(buf, ctrl)
+	if typ > 7 {
+		buf = append(buf, byte(typ-7))
+	}
+	return append(buf, extra...)
+}
+
+// encodeData appends v in the data section format.
+func encodeData(buf []byte, v any) []byte {
+	unsigned := func(typ int, n uint64) []byte {
+		var b []byte
+		for ; n > 0; n >>= 8 {
+			b = append([]byte{byte(n)}, b...)
+		}
+		return append(encodeSize(buf, typ, len(b)), b...)
+	}
+	switch v := v.(type) {
+	case string:
+		return append(encodeSize(buf, typeString, len(v)), v...)
+	case uint16:
+		return unsigned(typeUint16, uint64(v))
+	case uint32:
+		return unsigned(typeUint32, uint64(v))
+	case uint64:
+		return unsigned(typeUint64, v)
+	case int32:
+		buf = encodeSize(buf, typeInt32, 4)
+		return binary.BigEndian.AppendUint32(buf, uint32(v))
+	case float64:
+		buf = encodeSize(buf, typeDouble, 8)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v))
+	case bool:
+		n := 0
+		if v {
+			n = 1
+		}
+		return encodeSize(buf, typeBool, n)
+	case []any:
+		buf = encodeSize(buf, typeArray, len(v))
+		for _, e := range v {
+			buf = encodeData(buf, e)
+		}
+		return buf
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = encodeSize(buf, typeMap, len(v))
+		for _, k := range keys {
+			buf = encodeData(buf, k)
+			buf = encodeData(buf, v[k])
+		}
+		return buf
+	}
+	panic("unsupported value")
+}
+
+// encodePointer appends a pointer to offset.
+func encodePointer(buf []byte, offset int) []byte {
+	switch {
+	case offset < 2048:
+		return append(buf, byte(typePointer<<5|offset>>8), byte(offset))
+	case offset < 526336:
+		p := offset - 2048
+		return append(buf, byte(typePointer<<5|1<<3|p>>16), byte(p>>8), byte(p))
+	case offset < 134744064:
+		p := offset - 526336
+		return append(buf, byte(typePointer<<5|2<<3|p>>24), byte(p>>16), byte(p>>8), byte(p))
+	}
+	return binary.BigEndian.AppendUint32(append(buf, typePointer<<5|3<<3), uint32(offset))
+}
+
+// buildMMDB returns a MaxMind DB with the given networks. IPv4 networks of
+// an IPv6 tree go below ::/96 as the format prescribes. A network whose
+// data is an mmdbPointer shares the data of the network it names.
+func buildMMDB(dbType string, recordSize int, ipVersion int, networks []mmdbNetwork) []byte {
+	const empty = -1
+	nodes := [][2]int{{empty, empty}}
+	leaves := make(map[[2]int]string) // record slot to network
+	var data []byte
+	offsets := make(map[string]int)
+	for _, n := range networks {
+		prefix := netip.MustParsePrefix(n.prefix)
+		var bits []byte
+		depth := prefix.Bits()
+		if prefix.Addr().Is4() && ipVersion == 4 {
+			a := prefix.Addr().As4()
+			bits = a[:]
+		} else {
+			a := prefix.Addr().As16()
+			if prefix.Addr().Is4() {
+				a = [16]byte{}
+				v4 := prefix.Addr().As4()
+				copy(a[12:], v4[:])
+				depth += 96
+			}
+			bits = a[:]
+		}
+		node := 0
+		for i := 0; i < depth-1; i++ {
+			bit := int(bits[i/8] >> (7 - i%8) & 1)
+			if nodes[node][bit] == empty {
+				nodes = append(nodes, [2]int{empty, empty})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+		last := int(bits[(depth-1)/8] >> (7 - (depth-1)%8) & 1)
+		nodes[node][last] = -2 // a leaf
+		leaves[[2]int{node, last}] = n.prefix
+		if p, ok := n.data.(mmdbPointer); ok {
+			offsets[n.prefix] = len(data)
+			data = encodePointer(data, offsets[string(p)])
+			continue
+		}
+		offsets[n.prefix] = len(data)
+		data = encodeData(data, n.data)
+	}
+
+	count := len(nodes)
+	var tree []byte
+	for i, node := range nodes {
+		var rec [2]uint32
+		for bit, r := range node {
+			switch r {
+			case empty:
+				rec[bit] = uint32(count)
+			case -2:
+				rec[bit] = uint32(count + dataSeparator + offsets[leaves[[2]int{i, bit}]])
+			default:
+				rec[bit] = uint32(r)
+			}
+		}
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(rec[0]>>16), byte(rec[0]>>8), byte(rec[0]), byte(rec[1]>>16), byte(rec[1]>>8), byte(rec[1]))
+		case 28:
+			tree = append(tree, byte(rec[0]>>16), byte(rec[0]>>8), byte(rec[0]), byte(rec[0]>>24<<4|rec[1]>>24&0x0f), byte(rec[1]>>16), byte(rec[1]>>8), byte(rec[1]))
+		default:
+			tree = binary.BigEndian.AppendUint32(tree, rec[0])
+			tree = binary.BigEndian.AppendUint32(tree, rec[1])
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(tree)
+	buf.Write(make([]byte, dataSeparator))
+	buf.Write(data)
+	buf.Write(metadataMarker)
+	buf.Write(encodeData(nil, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               dbType,
+		"description":                 map[string]any{"en": "test database"},
+		"ip_version":                  uint16(ipVersion),
+		"languages":                   []any{"en", "zh-CN"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(recordSize),
+	}))
+	return buf.Bytes()
+}
+
+// writeMMDB writes a test database into dir and returns its path.
+func writeMMDB(t *testing.T, dir string, name string, dbType string, networks []mmdbNetwork) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buildMMDB(dbType, 28, 6, networks), 0644); err != nil {
+		t.Fatalf("failed to write database: %v", err)
+	}
+	return path
+}
+
+// --- Unit Tests ---
+
+// Unit test: addresses are found in trees of every record size and IP
+// version, through pointers and with every data type
+func TestReader_Lookup(t *testing.T) {
+	big := map[string]any{
+		"note":   string(bytes.Repeat([]byte("x"), 3000)), // moves later data past the short pointers
+		"values": []any{uint16(7), uint32(70000), uint64(1 << 40), int32(-5), 2.5, true, false},
+	}
+	networks := []mmdbNetwork{
+		{"81.2.69.0/24", big},
+		{"89.160.20.112/28", map[string]any{"city": "Linköping"}},
+		{"89.160.20.128/25", mmdbPointer("89.160.20.112/28")},
+	}
+	v6 := append(networks, mmdbNetwork{"2001:218::/32", map[string]any{"asn": uint32(2914)}})
+
+	for _, version := range []int{4, 6} {
+		for _, size := range []int{24, 28, 32} {
+			nets := networks
+			if version == 6 {
+				nets = v6
+			}
+			r, err := newReader(buildMMDB("Test-DB", size, version, nets))
+			if err != nil {
+				t.Fatalf("IPv%d/%d: %v", version, size, err)
+			}
+			lookup := func(s string) any {
+				v, err := r.Lookup(netip.MustParseAddr(s))
+				if err != nil {
+					t.Fatalf("IPv%d/%d: Lookup(%s): %v", version, size, s, err)
+				}
+				return v
+			}
+			want := []any{uint64(7), uint64(70000), uint64(1 << 40), int64(-5), 2.5, true, false}
+			if got, _ := lookup("81.2.69.160").(map[string]any); got == nil || !reflect.DeepEqual(got["values"], want) || len(got["note"].(string)) != 3000 {
+				t.Errorf("IPv%d/%d: 81.2.69.160 = %v", version, size, got["values"])
+			}
+			if got := lookup("89.160.20.120"); !reflect.DeepEqual(got, map[string]any{"city": "Linköping"}) {
+				t.Errorf("IPv%d/%d: 89.160.20.120 = %v", version, size, got)
+			}
+			if got := lookup("::ffff:89.160.20.200"); !reflect.DeepEqual(got, map[string]any{"city": "Linköping"}) {
+				t.Errorf("IPv%d/%d: the pointer and the mapped address = %v", version, size, got)
+			}
+			if got := lookup("89.160.20.100"); got != nil {
+				t.Errorf("IPv%d/%d: 89.160.20.100 = %v, want none", version, size, got)
+			}
+			want6 := any(nil)
+			if version == 6 {
+				want6 = map[string]any{"asn": uint64(2914)}
+			}
+			if got := lookup("2001:218:1::1"); !reflect.DeepEqual(got, want6) {
+				t.Errorf("IPv%d/%d: 2001:218:1::1 = %v, want %v", version, size, got, want6)
+			}
+			if r.DatabaseType() != "Test-DB" {
+				t.Errorf("database type = %q", r.DatabaseType())
+			}
+		}
+	}
+}
+
+// Unit test: files that aren't databases and cut off databases are errors
+func TestReader_Errors(t *testing.T) {
+	if _, err := newReader([]byte("not a database")); err == nil {
+		t.Error("expected an error for a file without metadata")
+	}
+	db := buildMMDB("Test-DB", 24, 4, []mmdbNetwork{{"1.0.0.0/8", map[string]any{"a": "b"}}})
+	at := bytes.Index(db, metadataMarker)
+	cut := append(append([]byte(nil), db[:10]...), db[at:]...)
+	if _, err := newReader(cut); err == nil {
+		t.Error("expected an error for a tree past the data section")
+	}
+	if _, err := OpenReader(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
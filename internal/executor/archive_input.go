@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"network-log-formatter/internal/archive"
+)
+
+// hasCompressed reports whether any of files is compressed or an archive.
+func hasCompressed(files []inputFile) (bool, error) {
+	for _, f := range files {
+		format, err := archive.Detect(f.Path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		if format != archive.Plain {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// expandInputs places files into dir for a script run and returns the files
+// the script will see there. Plain files are staged under their name;
+// compressed files and archives are expanded, one file per member, named
+// after the member so their sheets carry the inner file names. Plain files
+// are staged first so they keep their names if a member would clash.
+func expandInputs(files []inputFile, dir string) ([]inputFile, error) {
+	var plain, packed []inputFile
+	for _, f := range files {
+		format, err := archive.Detect(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		if format == archive.Plain {
+			plain = append(plain, f)
+		} else {
+			packed = append(packed, f)
+		}
+	}
+
+	out := make([]inputFile, 0, len(files))
+	for _, f := range plain {
+		dst := filepath.Join(dir, f.Name)
+		if err := stageFile(f.Path, dst); err != nil {
+			return nil, fmt.Errorf("failed to stage %s: %w", f.Name, err)
+		}
+		out = append(out, inputFile{Path: dst, Name: f.Name, Size: f.Size})
+	}
+	for _, f := range packed {
+		members, err := archive.Expand(f.Path, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", f.Name, err)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("archive %s contains no files", f.Name)
+		}
+		for _, m := range members {
+			out = append(out, inputFile{Path: m.Path, Name: m.Name, Size: m.Size})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// --- Unit Tests ---
+
+// Unit test: plain files are staged, compressed files and archives are
+// expanded under their inner names
+func TestExpandInputs(t *testing.T) {
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "access.log"), []byte("plain\n"), 0644)
+
+	f, _ := os.Create(filepath.Join(src, "app.log.1.gz"))
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte("rotated\n"))
+	gz.Close()
+	f.Close()
+
+	f, _ = os.Create(filepath.Join(src, "bundle.zip"))
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("access.log")
+	w.Write([]byte("zipped\n"))
+	w, _ = zw.Create("web/error.log")
+	w.Write([]byte("error\n"))
+	zw.Close()
+	f.Close()
+
+	files, _ := listInputFiles(src, nil)
+	if compressed, err := hasCompressed(files); err != nil || !compressed {
+		t.Fatalf("expected compressed inputs to be detected (%v)", err)
+	}
+
+	dir := t.TempDir()
+	staged, err := expandInputs(files, dir)
+	if err != nil {
+		t.Fatalf("expand failed: %v", err)
+	}
+	got := fmt.Sprint(names(staged))
+	want := "[access.log app.log.1 access (2).log web_error.log]"
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "app.log.1"))
+	if string(data) != "rotated\n" {
+		t.Fatalf("unexpected decompressed content %q", data)
+	}
+	for _, f := range staged {
+		if filepath.Dir(f.Path) != dir {
+			t.Fatalf("%s staged outside the staging directory", f.Path)
+		}
+	}
+}
+
+// Unit test: plain inputs are not reported as compressed
+func TestHasCompressed_Plain(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.log"), []byte("line\n"), 0644)
+	files, _ := listInputFiles(dir, nil)
+	if compressed, err := hasCompressed(files); err != nil || compressed {
+		t.Fatalf("expected no compressed inputs, got %v (%v)", compressed, err)
+	}
+}
@@ -3,7 +3,7 @@ package executor
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,12 +14,16 @@ import (
 
 	"network-log-formatter/internal/model"
 	"network-log-formatter/internal/pyenv"
+	"network-log-formatter/internal/quality"
 )
 
 // LLMRepairer defines the interface for LLM-based code repair.
 // This avoids circular imports with the agent package.
 type LLMRepairer interface {
 	RepairCode(ctx context.Context, code string, errorMsg string) (string, error)
+	// RepairQuality fixes code that runs without errors but streams rows
+	// falling short of the quality thresholds problem describes.
+	RepairQuality(ctx context.Context, code string, problem string) (string, error)
 }
 
 // BatchExecutor runs generated Python scripts in a uv-managed environment,
@@ -44,60 +48,297 @@ func NewBatchExecutor(envManager *pyenv.PythonEnvManager, llmClient LLMRepairer,
 	}
 }
 
+// ProgressFunc receives progress updates for a single batch run.
+type ProgressFunc func(p *model.BatchProgress)
+
 // Execute runs the given Python code against the input directory and writes
-// results to the output directory. It monitors stdout for JSON progress lines
-// and stderr for errors. If a runtime error occurs, it sends the code and error
-// to the LLM for repair and retries up to maxRetries times.
+// results to the output directory. Progress is recorded on the executor and
+// can be read back with GetProgress.
 func (be *BatchExecutor) Execute(ctx context.Context, code string, inputDir string, outputDir string, outputFileName string) (*model.BatchResult, error) {
+	params := model.BatchParams{
+		InputDir:       inputDir,
+		OutputDir:      outputDir,
+		OutputFileName: outputFileName,
+	}
+	return be.ExecuteJob(ctx, code, params, be.setProgress)
+}
+
+// ExecuteJob runs the given Python code with the given batch parameters and
+// reports progress through report, so concurrent runs don't share state.
+// It monitors stdout for JSON progress lines and stderr for errors. If a
+// runtime error occurs, it sends the code and error to the LLM for repair and
+// retries up to maxRetries times.
+func (be *BatchExecutor) ExecuteJob(ctx context.Context, code string, params model.BatchParams, report ProgressFunc) (*model.BatchResult, error) {
+	if report == nil {
+		report = be.setProgress
+	}
+	params, err := prepareParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return be.execute(ctx, code, params, report)
+}
+
+// execute runs already validated parameters on one or several workers and
+// finishes the output formats written in Go. Without a file filter or
+// compressed inputs a single worker reads the input directory as is;
+// otherwise the files selected in Go are staged into a directory of their
+// own, with compressed files and archives expanded, so the script sees
+// exactly that selection as plain files.
+func (be *BatchExecutor) execute(ctx context.Context, code string, params model.BatchParams, report ProgressFunc) (*model.BatchResult, error) {
+	out, err := newRowOutput(params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := be.dispatch(ctx, code, params, out, report)
+	if err == nil {
+		result, err = be.repairQuality(ctx, code, params, out, result, report)
+	}
+	if err != nil {
+		out.abort()
+		return result, err
+	}
+	if err := finishOutputs(out, params, result); err != nil {
+		report(&model.BatchProgress{
+			Status:  "failed",
+			Message: fmt.Sprintf("Batch processing failed: %v", err),
+			Files:   result.Files,
+		})
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	return result, holdQuality(params, result, report)
+}
+
+// repairQuality has the code of a run whose rows fall short of the
+// project's quality thresholds repaired and runs it again, as long as the
+// run has repairs left and the thresholds ask for it. The rows of the
+// repaired code replace the earlier ones file by file.
+func (be *BatchExecutor) repairQuality(ctx context.Context, code string, params model.BatchParams, out *rowOutput, result *model.BatchResult, report ProgressFunc) (*model.BatchResult, error) {
+	if params.Quality == nil || params.Quality.Action != quality.Repair {
+		return result, nil
+	}
+	current := code
+	if result.RepairedCode != "" {
+		current = result.RepairedCode
+	}
+	repairs := result.RepairAttempts
+	for repairs < be.maxRetries {
+		q := out.qualityReport()
+		if q == nil {
+			break
+		}
+		violations := quality.Check(q, *params.Quality)
+		if len(violations) == 0 {
+			break
+		}
+		problem := quality.Problem(violations)
+		fix := func(ctx context.Context, r LLMRepairer) (string, error) {
+			return r.RepairQuality(ctx, current, problem)
+		}
+		fixed, ok := be.requestRepair(ctx, fmt.Sprintf("Rows fall short of the quality thresholds, attempting repair (attempt %d/%d)", repairs+1, be.maxRetries), report, fix)
+		repairs++
+		if !ok {
+			break
+		}
+		next, err := be.dispatch(ctx, fixed, params, out, report)
+		if next != nil {
+			repairs += next.RepairAttempts
+			next.RepairAttempts = repairs
+			if next.RepairedCode != "" {
+				fixed = next.RepairedCode
+			}
+			next.RepairedCode = fixed
+		}
+		if err != nil {
+			return next, err
+		}
+		result, current = next, fixed
+	}
+	result.RepairAttempts = repairs
+	return result, nil
+}
+
+// holdQuality holds the rows of a finished run to the quality thresholds
+// of params. Falling short fails the run when the thresholds say so and
+// marks it degraded otherwise; the files are written either way.
+func holdQuality(params model.BatchParams, result *model.BatchResult, report ProgressFunc) error {
+	if result.Quality == nil || params.Quality == nil {
+		return nil
+	}
+	result.Quality.Violations = quality.Check(result.Quality, *params.Quality)
+	if len(result.Quality.Violations) == 0 {
+		return nil
+	}
+	msg := qualityMessage(result.Quality.Violations)
+	if params.Quality.Action == quality.Fail {
+		report(&model.BatchProgress{
+			Status:  "failed",
+			Message: fmt.Sprintf("Batch processing failed: %s", msg),
+			Files:   result.Files,
+		})
+		result.Errors = append(result.Errors, msg)
+		return errors.New(msg)
+	}
+	result.Degraded = true
+	result.Log = append(result.Log, model.LogEntry{Level: "warning", Message: msg})
+	report(&model.BatchProgress{
+		Status:     "degraded",
+		TotalFiles: result.TotalFiles,
+		Processed:  result.Succeeded,
+		Failed:     result.Failed,
+		Progress:   1.0,
+		Message:    "Batch processing completed, but " + msg,
+		Files:      result.Files,
+	})
+	return nil
+}
+
+// qualityMessage describes the quality thresholds a run's rows fall short
+// of.
+func qualityMessage(violations []string) string {
+	return "data quality below the thresholds: " + strings.Join(violations, "; ")
+}
+
+// dispatch runs the script on a single worker over the input directory as
+// is, or over a staged selection of it on one or several workers. Streamed
+// rows go to out.
+func (be *BatchExecutor) dispatch(ctx context.Context, code string, params model.BatchParams, out *rowOutput, report ProgressFunc) (*model.BatchResult, error) {
+	files, err := listInputFiles(params.InputDir, params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if params.Filter != nil && len(files) == 0 {
+		return nil, fmt.Errorf("no files in %s match the file filters", params.InputDir)
+	}
+	compressed, err := hasCompressed(files)
+	if err != nil {
+		return nil, err
+	}
+	if params.Filter == nil && !compressed && (params.Workers <= 1 || len(files) <= 1) {
+		return be.executeSequential(ctx, code, params, out, report)
+	}
+
+	// Stage the selected files, with archives expanded, so the script only
+	// sees plain log files; the staging area is removed once the run ends
+	stageDir, err := os.MkdirTemp("", "batch-input-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+	if files, err = expandInputs(files, stageDir); err != nil {
+		return nil, err
+	}
+	if params.Workers > 1 && len(files) > 1 {
+		return be.executeParallel(ctx, code, params, files, out, report)
+	}
+	staged := params
+	staged.InputDir = stageDir
+	staged.Filter = nil
+	return be.executeSequential(ctx, code, staged, out, report)
+}
+
+// prepareParams validates the batch directories, makes them absolute and
+// creates the output directory.
+func prepareParams(params model.BatchParams) (model.BatchParams, error) {
+	inputDir := params.InputDir
+	outputDir := params.OutputDir
+
 	// Validate directories
 	if strings.TrimSpace(inputDir) == "" {
-		return nil, fmt.Errorf("input directory must not be empty")
+		return params, fmt.Errorf("input directory must not be empty")
 	}
 	if strings.TrimSpace(outputDir) == "" {
-		return nil, fmt.Errorf("output directory must not be empty")
+		return params, fmt.Errorf("output directory must not be empty")
 	}
 
 	// Validate inputDir exists
 	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("input directory does not exist: %s", inputDir)
+		return params, fmt.Errorf("input directory does not exist: %s", inputDir)
 	}
 
 	// Validate paths are absolute to prevent traversal issues
 	absInput, err := filepath.Abs(inputDir)
 	if err != nil {
-		return nil, fmt.Errorf("invalid input directory path: %w", err)
+		return params, fmt.Errorf("invalid input directory path: %w", err)
 	}
 	absOutput, err := filepath.Abs(outputDir)
 	if err != nil {
-		return nil, fmt.Errorf("invalid output directory path: %w", err)
+		return params, fmt.Errorf("invalid output directory path: %w", err)
+	}
+	params.InputDir = absInput
+	params.OutputDir = absOutput
+
+	if err := validateFilter(params.Filter); err != nil {
+		return params, err
+	}
+	if err := ValidateFormats(params); err != nil {
+		return params, err
 	}
-	inputDir = absInput
-	outputDir = absOutput
 
 	// Create outputDir if it doesn't exist
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
+		return params, fmt.Errorf("failed to create output directory: %w", err)
 	}
+	return params, nil
+}
+
+// OutputWorkbookPath returns the workbook a run with params writes to.
+func OutputWorkbookPath(params model.BatchParams) string {
+	return filepath.Join(params.OutputDir, outputName(params)+".xlsx")
+}
 
+// executeSequential runs the script once over the whole input directory,
+// repairing and retrying it on runtime errors.
+func (be *BatchExecutor) executeSequential(ctx context.Context, code string, params model.BatchParams, out *rowOutput, report ProgressFunc) (*model.BatchResult, error) {
 	currentCode := code
-	var lastErr string
+	var lastErr, lastStderr string
+	var lastFiles []model.FileResult
+	repairs := 0
 
 	for attempt := 0; attempt <= be.maxRetries; attempt++ {
-		result, stderrOutput, err := be.runScript(ctx, currentCode, inputDir, outputDir, outputFileName)
+		result, stderrOutput, err := be.runScript(ctx, currentCode, params.InputDir, params.OutputDir, params.OutputFileName, out, report)
+		lastStderr = stderrOutput
+		if result != nil {
+			lastFiles = result.Files
+		}
 		if err == nil {
 			// Process exited successfully (exit code 0).
 			// stderr may contain informational messages — that's fine.
-			be.setProgress(&model.BatchProgress{
-				Status:     "completed",
+			if currentCode != code {
+				result.RepairedCode = currentCode
+			}
+			result.Stderr = stderrExcerpt(stderrOutput)
+			result.RepairAttempts = repairs
+			report(&model.BatchProgress{
+				Status:     finishStatus(result),
 				TotalFiles: result.TotalFiles,
 				Processed:  result.Succeeded,
 				Failed:     result.Failed,
 				Progress:   1.0,
-				Message:    "Batch processing completed",
+				Message:    finishMessage(result),
+				Files:      result.Files,
 			})
 			return result, nil
 		}
 
+		// A cancelled run is not a code bug — don't send it for repair
+		if ctx.Err() != nil {
+			report(&model.BatchProgress{
+				Status:  "cancelled",
+				Message: "Batch processing cancelled",
+			})
+			return &model.BatchResult{}, ctx.Err()
+		}
+
+		// Neither is a run stopped by a resource limit
+		if le := pyenv.AsLimitError(err); le != nil {
+			result, err := be.limitFailure(le, lastFiles, report)
+			result.Stderr = stderrExcerpt(stderrOutput)
+			result.RepairAttempts = repairs
+			return result, err
+		}
+
 		// Determine error message
 		if err != nil {
 			if stderrOutput != "" {
@@ -112,40 +353,98 @@ func (be *BatchExecutor) Execute(ctx context.Context, code string, inputDir stri
 			break
 		}
 
-		// Try LLM repair
-		be.setProgress(&model.BatchProgress{
-			Status:  "fixing",
-			Message: fmt.Sprintf("Runtime error detected, attempting repair (attempt %d/%d)", attempt+1, be.maxRetries),
-		})
-
-		if be.llmClient == nil {
-			break
-		}
-
-		repairCtx, repairCancel := context.WithTimeout(ctx, 2*time.Minute)
-		fixedCode, repairErr := be.llmClient.RepairCode(repairCtx, currentCode, lastErr)
-		repairCancel()
-		if repairErr != nil {
-			// Can't repair, return the original error
+		fixedCode, ok := be.repair(ctx, currentCode, lastErr, attempt, report)
+		repairs++
+		if !ok {
 			break
 		}
 		currentCode = fixedCode
 	}
 
 	// Failed after all retries
-	be.setProgress(&model.BatchProgress{
+	report(&model.BatchProgress{
 		Status:  "failed",
 		Message: fmt.Sprintf("Batch processing failed: %s", lastErr),
+		Files:   lastFiles,
 	})
 
+	// Keep the files of the last attempt to show how far it got
 	return &model.BatchResult{
-		Errors: []string{lastErr},
+		Errors:         []string{lastErr},
+		Files:          lastFiles,
+		Stderr:         stderrExcerpt(lastStderr),
+		RepairAttempts: repairs,
 	}, fmt.Errorf("batch execution failed after %d retries: %s", be.maxRetries, lastErr)
 }
 
+// maxStderrExcerpt is how much of a script's stderr output a result keeps.
+const maxStderrExcerpt = 4096
+
+// stderrExcerpt returns the end of a script's stderr output, where a
+// traceback ends with the error.
+func stderrExcerpt(stderr string) string {
+	if len(stderr) <= maxStderrExcerpt {
+		return stderr
+	}
+	tail := stderr[len(stderr)-maxStderrExcerpt:]
+	// Start at a full line
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return "...\n" + tail
+}
+
+// limitFailure ends a run that a resource limit stopped. Repairing the code
+// wouldn't help, so it fails right away with the limit as failure type.
+func (be *BatchExecutor) limitFailure(le *pyenv.LimitError, files []model.FileResult, report ProgressFunc) (*model.BatchResult, error) {
+	report(&model.BatchProgress{
+		Status:  "failed",
+		Message: fmt.Sprintf("Batch processing stopped: %s", le.Message),
+		Files:   files,
+		Failure: le.Limit,
+	})
+	return &model.BatchResult{
+		Errors:  []string{le.Message},
+		Files:   files,
+		Failure: le.Limit,
+	}, fmt.Errorf("batch execution stopped: %w", le)
+}
+
+// repair asks the LLM to fix a runtime error. It returns false if no repairer
+// is configured or the repair request fails.
+func (be *BatchExecutor) repair(ctx context.Context, code string, errMsg string, attempt int, report ProgressFunc) (string, bool) {
+	fix := func(ctx context.Context, r LLMRepairer) (string, error) {
+		return r.RepairCode(ctx, code, errMsg)
+	}
+	return be.requestRepair(ctx, fmt.Sprintf("Runtime error detected, attempting repair (attempt %d/%d)", attempt+1, be.maxRetries), report, fix)
+}
+
+// requestRepair asks the LLM for fixed code through fix, reporting message
+// as progress.
+func (be *BatchExecutor) requestRepair(ctx context.Context, message string, report ProgressFunc, fix func(context.Context, LLMRepairer) (string, error)) (string, bool) {
+	report(&model.BatchProgress{
+		Status:  "fixing",
+		Message: message,
+	})
+
+	if be.llmClient == nil {
+		return "", false
+	}
+
+	repairCtx, repairCancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer repairCancel()
+	fixedCode, err := fix(repairCtx, be.llmClient)
+	if err != nil {
+		// Can't repair, return the original error
+		return "", false
+	}
+	return fixedCode, true
+}
+
 // runScript writes the code to a temp file, executes it via PythonEnvManager,
-// and reads stdout/stderr concurrently. Returns the batch result and any stderr output.
-func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir string, outputDir string, outputFileName string) (*model.BatchResult, string, error) {
+// and reads stdout/stderr concurrently, passing streamed rows to out. Returns
+// the batch result and any stderr output.
+func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir string, outputDir string, outputFileName string, out *rowOutput, report ProgressFunc) (*model.BatchResult, string, error) {
 	// Write code to temp file
 	tmpDir, err := os.MkdirTemp("", "batch-executor-*")
 	if err != nil {
@@ -157,8 +456,11 @@ func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir st
 	if err := os.WriteFile(scriptPath, []byte(code), 0644); err != nil {
 		return nil, "", fmt.Errorf("failed to write temp script: %w", err)
 	}
+	if err := writeHelperModule(tmpDir, helperConfig{Rows: true, Zones: out.timeZones()}); err != nil {
+		return nil, "", fmt.Errorf("failed to write progress helper: %w", err)
+	}
 
-	be.setProgress(&model.BatchProgress{
+	report(&model.BatchProgress{
 		Status:  "running",
 		Message: "Starting batch processing",
 	})
@@ -168,10 +470,12 @@ func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir st
 	if outputFileName != "" {
 		args = append(args, "--output-name", outputFileName)
 	}
-	cmd, stdout, stderr, err := be.envManager.RunScript(ctx, scriptPath, args)
+	access := pyenv.Access{ReadOnly: []string{inputDir}, Writable: []string{outputDir}}
+	run, err := be.envManager.RunScript(ctx, scriptPath, args, access)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to start script: %w", err)
 	}
+	stdout, stderr := run.Stdout, run.Stderr
 
 	// Read stdout and stderr concurrently
 	var stderrBuf strings.Builder
@@ -182,10 +486,17 @@ func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir st
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	// Read stdout — parse JSON progress lines
+	// Read stdout — parse JSON progress lines, quarantining rejected lines
+	rejects := newRejectWriter(rejectsPath(outputDir, outputFileName))
+	stream := newProgressStream(newFileTracker(inputDir, time.Now()), rejects)
+	stream.rows = out
+	if w := run.Sandbox.Warning; w != "" {
+		stream.addLog(model.LogEntry{Level: "warning", Message: w})
+		report(&model.BatchProgress{Status: "running", Message: "Warning: " + w})
+	}
 	go func() {
 		defer wg.Done()
-		be.readStdout(stdout, result)
+		be.readStdout(stdout, result, stream, report)
 	}()
 
 	// Read stderr
@@ -198,7 +509,13 @@ func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir st
 	wg.Wait()
 
 	// Wait for the process to finish
-	waitErr := cmd.Wait()
+	waitErr := run.Wait()
+
+	if err := rejects.close(); err != nil {
+		result.Log = append(result.Log, model.LogEntry{Level: "warning", Message: fmt.Sprintf("failed to write rejected lines: %v", err)})
+	} else if rejects.count > 0 {
+		result.RejectedPath = rejects.path
+	}
 
 	stderrOutput := strings.TrimSpace(stderrBuf.String())
 
@@ -212,37 +529,31 @@ func (be *BatchExecutor) runScript(ctx context.Context, code string, inputDir st
 	return result, stderrOutput, nil
 }
 
-// readStdout reads stdout line by line, parsing JSON progress lines and updating
-// the batch progress and result accordingly.
-func (be *BatchExecutor) readStdout(stdout io.ReadCloser, result *model.BatchResult) {
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+// readStdout reads stdout line by line, interpreting progress events and
+// legacy progress lines and keeping any other output as log entries. Lines
+// are read whole, however long, so a long line can't stall the script on a
+// full pipe.
+func (be *BatchExecutor) readStdout(stdout io.Reader, result *model.BatchResult, stream *progressStream, report ProgressFunc) {
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			if p := stream.handle(line, time.Now()); p != nil {
+				report(p)
+
+				// Update the result from the stream (protected by mutex)
+				be.mu.Lock()
+				stream.fill(result)
+				be.mu.Unlock()
+			}
 		}
-
-		var info model.ProgressInfo
-		if err := json.Unmarshal([]byte(line), &info); err != nil {
-			// Not a JSON progress line, skip
-			continue
+		if err != nil {
+			// Streamed rows don't update the result as they arrive
+			be.mu.Lock()
+			stream.fill(result)
+			be.mu.Unlock()
+			return
 		}
-
-		// Update progress
-		be.setProgress(&model.BatchProgress{
-			Status:      "running",
-			CurrentFile: info.File,
-			Progress:    info.Progress,
-			TotalFiles:  info.Total,
-			Processed:   info.Current,
-			Message:     fmt.Sprintf("Processing: %s", info.File),
-		})
-
-		// Update result counts from the latest progress info (protected by mutex)
-		be.mu.Lock()
-		result.TotalFiles = info.Total
-		result.Succeeded = info.Current
-		be.mu.Unlock()
 	}
 }
 
@@ -3,7 +3,14 @@ package executor
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/pyenv"
 
 	"pgregory.net/rapid"
 )
@@ -96,3 +103,110 @@ func TestExecute_NonExistentInputDir(t *testing.T) {
 		t.Fatal("expected error when inputDir does not exist")
 	}
 }
+
+// countingRepairer records how often a repair was requested.
+type countingRepairer struct {
+	calls int
+}
+
+func (r *countingRepairer) RepairCode(ctx context.Context, code string, errorMsg string) (string, error) {
+	r.calls++
+	return code, nil
+}
+
+func (r *countingRepairer) RepairQuality(ctx context.Context, code string, problem string) (string, error) {
+	r.calls++
+	return code, nil
+}
+
+// Unit test: a run stopped by a resource limit fails with the limit as
+// failure type and is not sent for repair
+func TestExecuteJob_LimitIsNotRepaired(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake python needs a POSIX shell")
+	}
+	envPath := t.TempDir()
+	os.MkdirAll(filepath.Join(envPath, "bin"), 0755)
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte("#!/bin/sh\nsleep 10\n"), 0755)
+	envManager := pyenv.NewPythonEnvManager("uv", envPath)
+	envManager.SetLimits(pyenv.Limits{IdleTimeout: 200 * time.Millisecond})
+
+	inputDir := t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("line\n"), 0644)
+
+	repairer := &countingRepairer{}
+	be := NewBatchExecutor(envManager, repairer, 3)
+	var last model.BatchProgress
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir:  inputDir,
+		OutputDir: t.TempDir(),
+	}, func(p *model.BatchProgress) { last = *p })
+
+	if pyenv.AsLimitError(err) == nil {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	if repairer.calls != 0 {
+		t.Fatalf("expected no repair attempts, got %d", repairer.calls)
+	}
+	if last.Status != "failed" || last.Failure != pyenv.LimitIdle || result.Failure != pyenv.LimitIdle {
+		t.Fatalf("unexpected final progress %+v / result %+v", last, result)
+	}
+}
+
+// fixingRepairer repairs any code into fixed, remembering the quality
+// problems it was asked to fix.
+type fixingRepairer struct {
+	fixed    string
+	problems []string
+}
+
+func (r *fixingRepairer) RepairCode(ctx context.Context, code string, errorMsg string) (string, error) {
+	return r.fixed, nil
+}
+
+func (r *fixingRepairer) RepairQuality(ctx context.Context, code string, problem string) (string, error) {
+	r.problems = append(r.problems, problem)
+	return r.fixed, nil
+}
+
+// Unit test: code that only worked after a runtime repair is returned in the
+// result, and code that worked as given is not
+func TestExecuteJob_ReturnsRepairedCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake python needs a POSIX shell")
+	}
+	envPath := t.TempDir()
+	os.MkdirAll(filepath.Join(envPath, "bin"), 0755)
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte("#!/bin/sh\ngrep -q FIXED \"$1\"\n"), 0755)
+	envManager := pyenv.NewPythonEnvManager("uv", envPath)
+
+	inputDir := t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("line\n"), 0644)
+	be := NewBatchExecutor(envManager, &fixingRepairer{fixed: "# FIXED"}, 3)
+	params := model.BatchParams{InputDir: inputDir, OutputDir: t.TempDir()}
+
+	result, err := be.ExecuteJob(context.Background(), "broken", params, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("expected the repaired run to succeed, got %v", err)
+	}
+	if result.RepairedCode != "# FIXED" || result.RepairAttempts != 1 {
+		t.Fatalf("expected the repaired code after 1 repair, got %q after %d", result.RepairedCode, result.RepairAttempts)
+	}
+
+	result, err = be.ExecuteJob(context.Background(), "# FIXED", params, func(p *model.BatchProgress) {})
+	if err != nil || result.RepairedCode != "" {
+		t.Fatalf("expected no repaired code for working code, got %q, %v", result.RepairedCode, err)
+	}
+}
+
+// Unit test: long stderr output is cut to its last full lines
+func TestStderrExcerpt(t *testing.T) {
+	if got := stderrExcerpt("Traceback\nValueError: x"); got != "Traceback\nValueError: x" {
+		t.Fatalf("expected short output unchanged, got %q", got)
+	}
+	long := strings.Repeat("noise line\n", 1000) + "ValueError: bad line"
+	got := stderrExcerpt(long)
+	if len(got) > maxStderrExcerpt+4 || !strings.HasPrefix(got, "...\nnoise line\n") || !strings.HasSuffix(got, "ValueError: bad line") {
+		t.Fatalf("unexpected excerpt of %d bytes: %q...", len(got), got[:40])
+	}
+}
@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"network-log-formatter/internal/model"
+)
+
+// inputFile is a file selected for processing. Name is the file name the
+// script sees: the base name for top-level files and the slash-free relative
+// path ("web1_access.log") for files in subdirectories, so names stay unique
+// and stable across runs. Size is used to balance shards across workers.
+type inputFile struct {
+	Path string
+	Name string
+	Size int64
+}
+
+// listInputFiles returns the regular, non-hidden files of dir selected by
+// filter, sorted by path. A nil filter selects every file directly inside dir.
+func listInputFiles(dir string, filter *model.FileFilter) ([]inputFile, error) {
+	if filter == nil {
+		filter = &model.FileFilter{}
+	}
+
+	var files []inputFile
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			return nil // skip unreadable entries
+		}
+		if p == dir {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		hidden := strings.HasPrefix(d.Name(), ".")
+		if d.IsDir() {
+			if hidden || !filter.Recursive || matchAny(filter.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden {
+			return nil
+		}
+
+		info, err := os.Stat(p) // follow symlinks
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		if !selected(filter, rel, info) {
+			return nil
+		}
+		files = append(files, inputFile{
+			Path: p,
+			Name: strings.ReplaceAll(rel, "/", "_"),
+			Size: info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// selected reports whether a file passes the filter's patterns, size limits
+// and modification-time window.
+func selected(filter *model.FileFilter, rel string, info os.FileInfo) bool {
+	if len(filter.Include) > 0 && !matchAny(filter.Include, rel) {
+		return false
+	}
+	if matchAny(filter.Exclude, rel) {
+		return false
+	}
+	if filter.MinSize > 0 && info.Size() < filter.MinSize {
+		return false
+	}
+	if filter.MaxSize > 0 && info.Size() > filter.MaxSize {
+		return false
+	}
+	if filter.ModifiedAfter != nil && info.ModTime().Before(*filter.ModifiedAfter) {
+		return false
+	}
+	if filter.ModifiedBefore != nil && !info.ModTime().Before(*filter.ModifiedBefore) {
+		return false
+	}
+	return true
+}
+
+// validateFilter rejects malformed patterns and contradictory limits.
+func validateFilter(filter *model.FileFilter) error {
+	if filter == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, filter.Include...), filter.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+	}
+	if filter.MinSize < 0 || filter.MaxSize < 0 {
+		return fmt.Errorf("file size limits must not be negative")
+	}
+	if filter.MaxSize > 0 && filter.MinSize > filter.MaxSize {
+		return fmt.Errorf("minimum file size %d exceeds maximum %d", filter.MinSize, filter.MaxSize)
+	}
+	if filter.ModifiedAfter != nil && filter.ModifiedBefore != nil && !filter.ModifiedAfter.Before(*filter.ModifiedBefore) {
+		return fmt.Errorf("modification time window is empty")
+	}
+	return nil
+}
+
+// matchAny reports whether rel matches any of the patterns.
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated relative path against a glob pattern.
+// Patterns without a slash match the base name at any depth ("*.log");
+// patterns with a slash match the whole path, where "**" matches any number
+// of directories ("web/**/access*.log").
+func matchGlob(pattern string, rel string) bool {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments.
+func matchSegments(pattern []string, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
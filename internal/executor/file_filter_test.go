@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 17: 文件筛选大小范围
+// For any set of files and size limits, exactly the files whose size lies
+// within the limits are selected.
+func TestProperty17_FilterSizeLimits(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		dir, err := os.MkdirTemp("", "file-filter-test-*")
+		if err != nil {
+			rt.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		sizes := rapid.SliceOfN(rapid.IntRange(0, 200), 1, 10).Draw(rt, "sizes")
+		minSize := rapid.Int64Range(0, 100).Draw(rt, "min")
+		maxSize := rapid.Int64Range(minSize, 200).Draw(rt, "max")
+
+		want := 0
+		for i, size := range sizes {
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.log", i)), make([]byte, size), 0644)
+			if int64(size) >= minSize && (maxSize == 0 || int64(size) <= maxSize) {
+				want++
+			}
+		}
+
+		files, err := listInputFiles(dir, &model.FileFilter{MinSize: minSize, MaxSize: maxSize})
+		if err != nil {
+			rt.Fatalf("unexpected error: %v", err)
+		}
+		if len(files) != want {
+			rt.Fatalf("expected %d files within [%d, %d], got %d", want, minSize, maxSize, len(files))
+		}
+		for _, f := range files {
+			if f.Size < minSize || (maxSize > 0 && f.Size > maxSize) {
+				rt.Fatalf("file %s of size %d is outside [%d, %d]", f.Name, f.Size, minSize, maxSize)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// writeTree creates the given relative files under dir.
+func writeTree(t *testing.T, dir string, rels ...string) {
+	t.Helper()
+	for _, rel := range rels {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(rel), 0644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+}
+
+// names returns the staged names of files.
+func names(files []inputFile) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Name
+	}
+	return out
+}
+
+// Unit test: recursion, include and exclude patterns
+func TestListInputFiles_RecursiveWithPatterns(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir,
+		"top.log",
+		"notes.txt",
+		"web1/access.log",
+		"web1/error.log",
+		"web2/deep/access.log",
+		"archive/old.log",
+		".git/config.log",
+	)
+
+	files, err := listInputFiles(dir, &model.FileFilter{
+		Recursive: true,
+		Include:   []string{"*.log"},
+		Exclude:   []string{"archive", "error*"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := fmt.Sprint(names(files))
+	want := "[top.log web1_access.log web2_deep_access.log]"
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	// Without recursion only the top level is read
+	files, _ = listInputFiles(dir, &model.FileFilter{Include: []string{"*.log"}})
+	if got := fmt.Sprint(names(files)); got != "[top.log]" {
+		t.Fatalf("expected only top.log, got %s", got)
+	}
+}
+
+// Unit test: patterns with a slash match the whole relative path
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.log", "a/b/c.log", true},
+		{"*.log", "c.txt", false},
+		{"web*/*.log", "web1/access.log", true},
+		{"web*/*.log", "web1/x/access.log", false},
+		{"web1/**/access*.log", "web1/access.log", true},
+		{"web1/**/access*.log", "web1/a/b/access-1.log", true},
+		{"**/error.log", "error.log", true},
+		{"", "a.log", false},
+	}
+	for _, c := range cases {
+		if got := matchGlob(c.pattern, c.rel); got != c.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", c.pattern, c.rel, got, c.want)
+		}
+	}
+}
+
+// Unit test: modification-time window
+func TestListInputFiles_ModifiedWindow(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "old.log", "new.log")
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "old.log"), old, old)
+
+	after := time.Now().Add(-24 * time.Hour)
+	files, _ := listInputFiles(dir, &model.FileFilter{ModifiedAfter: &after})
+	if got := fmt.Sprint(names(files)); got != "[new.log]" {
+		t.Fatalf("expected only new.log, got %s", got)
+	}
+	files, _ = listInputFiles(dir, &model.FileFilter{ModifiedBefore: &after})
+	if got := fmt.Sprint(names(files)); got != "[old.log]" {
+		t.Fatalf("expected only old.log, got %s", got)
+	}
+}
+
+// Unit test: malformed filters are rejected
+func TestValidateFilter(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	bad := []*model.FileFilter{
+		{Include: []string{"[a-"}},
+		{MinSize: 10, MaxSize: 5},
+		{MinSize: -1},
+		{ModifiedAfter: &now, ModifiedBefore: &earlier},
+	}
+	for i, f := range bad {
+		if err := validateFilter(f); err == nil {
+			t.Errorf("case %d: expected error for %+v", i, f)
+		}
+	}
+	if err := validateFilter(&model.FileFilter{Include: []string{"web/**/*.log"}, MinSize: 1, MaxSize: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateFilter(nil); err != nil {
+		t.Fatalf("nil filter should be valid: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// fileTracker turns the progress lines of one script run into per-file
+// results. A file's duration runs from its file_start event or, for scripts
+// that only report finished files, from the previous progress line.
+type fileTracker struct {
+	inputDir string
+	last     time.Time
+	files    []model.FileResult
+	index    map[string]int
+	started  map[string]time.Time
+	warnings map[string]int
+	rejects  map[string]int
+}
+
+// newFileTracker creates a tracker for a script reading inputDir that
+// started at start.
+func newFileTracker(inputDir string, start time.Time) *fileTracker {
+	return &fileTracker{
+		inputDir: inputDir,
+		last:     start,
+		index:    make(map[string]int),
+		started:  make(map[string]time.Time),
+		warnings: make(map[string]int),
+		rejects:  make(map[string]int),
+	}
+}
+
+// begin marks the start of processing file.
+func (ft *fileTracker) begin(file string, now time.Time) {
+	ft.started[file] = now
+}
+
+// warn counts a warning against file. Warnings reported before the file is
+// finished are added to its result when it is recorded.
+func (ft *fileTracker) warn(file string) {
+	if i, ok := ft.index[file]; ok {
+		ft.files[i].Warnings++
+		return
+	}
+	ft.warnings[file]++
+}
+
+// reject counts a line of file written to the quarantine file. A file's
+// skipped lines are at least its rejected lines, so scripts needn't count
+// them twice.
+func (ft *fileTracker) reject(file string) {
+	if i, ok := ft.index[file]; ok {
+		r := &ft.files[i]
+		r.Rejected++
+		if r.SkippedLines < r.Rejected {
+			r.SkippedLines = r.Rejected
+			r.Coverage = coverage(r.Rows, r.SkippedLines)
+		}
+		return
+	}
+	ft.rejects[file]++
+}
+
+// record adds the result of the file a progress line reports. A file that is
+// reported again replaces its earlier result.
+func (ft *fileTracker) record(info model.ProgressInfo, now time.Time) {
+	if info.File == "" {
+		return
+	}
+	from := ft.last
+	if t, ok := ft.started[info.File]; ok {
+		from = t
+		delete(ft.started, info.File)
+	}
+	rejected := ft.rejects[info.File]
+	if i, ok := ft.index[info.File]; ok {
+		rejected += ft.files[i].Rejected
+	}
+	r := model.FileResult{
+		File:         info.File,
+		Rows:         info.Rows,
+		SkippedLines: info.Skipped,
+		Rejected:     rejected,
+		Warnings:     ft.warnings[info.File],
+		Error:        info.Error,
+		DurationMs:   now.Sub(from).Milliseconds(),
+	}
+	if r.SkippedLines < r.Rejected {
+		r.SkippedLines = r.Rejected
+	}
+	r.Coverage = coverage(r.Rows, r.SkippedLines)
+	delete(ft.warnings, info.File)
+	delete(ft.rejects, info.File)
+	ft.last = now
+
+	path := info.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ft.inputDir, path)
+	}
+	if fi, err := os.Stat(path); err == nil {
+		r.Bytes = fi.Size()
+	}
+	r.Status = fileStatus(info, r.Bytes)
+
+	if i, ok := ft.index[r.File]; ok {
+		ft.files[i] = r
+		return
+	}
+	ft.index[r.File] = len(ft.files)
+	ft.files = append(ft.files, r)
+}
+
+// results returns a copy of the results recorded so far.
+func (ft *fileTracker) results() []model.FileResult {
+	return append([]model.FileResult(nil), ft.files...)
+}
+
+// fileStatus classifies a reported file. A non-empty file the script wrote no
+// rows for is "empty" rather than a success, since nothing was parsed from
+// it. Scripts that don't report rows or errors can only succeed.
+func fileStatus(info model.ProgressInfo, size int64) string {
+	switch {
+	case info.Status == "error" || info.Error != "":
+		return "failed"
+	case info.Rows != nil && *info.Rows == 0 && size > 0:
+		return "empty"
+	}
+	return "succeeded"
+}
+
+// countFiles returns how many files succeeded and how many didn't.
+func countFiles(files []model.FileResult) (succeeded int, failed int) {
+	for _, f := range files {
+		if f.Status == "succeeded" {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	return succeeded, failed
+}
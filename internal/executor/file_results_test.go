@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 19: 逐文件结果计数
+// For any sequence of per-file progress lines, every reported file has
+// exactly one result, and the succeeded and failed counts add up to the
+// number of distinct files.
+func TestProperty19_FileResultsCountEachFileOnce(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		tracker := newFileTracker(t.TempDir(), time.Now())
+		lines := rapid.IntRange(0, 30).Draw(rt, "lines")
+
+		distinct := make(map[string]bool)
+		for i := 0; i < lines; i++ {
+			info := model.ProgressInfo{
+				File: fmt.Sprintf("%02d.log", rapid.IntRange(0, 9).Draw(rt, "file")),
+			}
+			if rapid.Bool().Draw(rt, "reportRows") {
+				rows := rapid.IntRange(0, 5).Draw(rt, "rows")
+				info.Rows = &rows
+			}
+			if rapid.Bool().Draw(rt, "error") {
+				info.Status = "error"
+				info.Error = "boom"
+			}
+			distinct[info.File] = true
+			tracker.record(info, time.Now())
+		}
+
+		files := tracker.results()
+		if len(files) != len(distinct) {
+			rt.Fatalf("expected %d results, got %d", len(distinct), len(files))
+		}
+		succeeded, failed := countFiles(files)
+		if succeeded+failed != len(distinct) {
+			rt.Fatalf("%d succeeded + %d failed != %d files", succeeded, failed, len(distinct))
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: per-file fields are read from progress lines and legacy lines
+// still count as successes
+func TestReadStdout_RecordsFileResults(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.log"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.log"), []byte("line"), 0644)
+	os.WriteFile(filepath.Join(dir, "c.log"), []byte("line"), 0644)
+
+	stdout := io.NopCloser(strings.NewReader(strings.Join([]string{
+		`{"file": "a.log", "progress": 0.25, "total": 4, "current": 1, "status": "ok", "rows": 10, "skipped": 2}`,
+		`not json`,
+		`{"file": "b.log", "progress": 0.5, "total": 4, "current": 2, "status": "ok", "rows": 0}`,
+		`{"file": "c.log", "progress": 0.75, "total": 4, "current": 3, "status": "error", "error": "bad header"}`,
+		`{"file": "d.log", "progress": 1.0, "total": 4, "current": 4}`,
+	}, "\n")))
+
+	be := NewBatchExecutor(nil, nil, 0)
+	result := &model.BatchResult{}
+	var last model.BatchProgress
+	be.readStdout(stdout, result, newProgressStream(newFileTracker(dir, time.Now()), nil), func(p *model.BatchProgress) { last = *p })
+
+	if result.TotalFiles != 4 || result.Succeeded != 2 || result.Failed != 2 {
+		t.Fatalf("unexpected totals: %+v", result)
+	}
+	if len(result.Files) != 4 || len(last.Files) != 4 || last.Failed != 2 {
+		t.Fatalf("expected 4 file results in result and progress, got %d and %d", len(result.Files), len(last.Files))
+	}
+
+	a := result.Files[0]
+	if a.Status != "succeeded" || a.Rows == nil || *a.Rows != 10 || a.SkippedLines != 2 || a.Bytes != 5 {
+		t.Fatalf("unexpected result for a.log: %+v", a)
+	}
+	if b := result.Files[1]; b.Status != "empty" {
+		t.Fatalf("expected b.log to be empty, got %+v", b)
+	}
+	if c := result.Files[2]; c.Status != "failed" || c.Error != "bad header" {
+		t.Fatalf("unexpected result for c.log: %+v", c)
+	}
+	if d := result.Files[3]; d.Status != "succeeded" || d.Rows != nil {
+		t.Fatalf("expected legacy line to succeed without rows, got %+v", d)
+	}
+}
+
+// Unit test: durations are measured between progress lines
+func TestFileTracker_Duration(t *testing.T) {
+	start := time.Now()
+	tracker := newFileTracker(t.TempDir(), start)
+	tracker.record(model.ProgressInfo{File: "a.log"}, start.Add(1500*time.Millisecond))
+	tracker.record(model.ProgressInfo{File: "b.log"}, start.Add(2000*time.Millisecond))
+
+	files := tracker.results()
+	if files[0].DurationMs != 1500 || files[1].DurationMs != 500 {
+		t.Fatalf("unexpected durations: %d, %d", files[0].DurationMs, files[1].DurationMs)
+	}
+}
+
+// Unit test: an empty input file with no rows is not flagged
+func TestFileStatus_EmptyInput(t *testing.T) {
+	zero := 0
+	if got := fileStatus(model.ProgressInfo{Rows: &zero}, 0); got != "succeeded" {
+		t.Fatalf("expected zero-byte file to succeed, got %s", got)
+	}
+	if got := fileStatus(model.ProgressInfo{Rows: &zero}, 10); got != "empty" {
+		t.Fatalf("expected empty status, got %s", got)
+	}
+}
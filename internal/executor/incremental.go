@@ -0,0 +1,413 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// Reasons reported for files an incremental run skips.
+const (
+	skipUnchanged        = "unchanged since last run"
+	skipOnlyModTimeMoved = "content unchanged (only modification time differs)"
+)
+
+// ExecuteIncremental runs a batch and returns the manifest of the files the
+// output workbook now covers, for the caller to persist.
+//
+// With params.Incremental set and prev describing an earlier run from the
+// same input directory into the same, still existing workbook, only new or
+// changed files are processed: their sheets replace or are appended to the
+// existing workbook and every other file is reported in BatchResult.Skipped.
+// Otherwise the whole directory is processed as by ExecuteJob, and the
+// manifest records its files without content hashes.
+func (be *BatchExecutor) ExecuteIncremental(ctx context.Context, code string, params model.BatchParams, prev *model.FileManifest, report ProgressFunc) (*model.BatchResult, *model.FileManifest, error) {
+	if report == nil {
+		report = be.setProgress
+	}
+	params, err := prepareParams(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := listInputFiles(params.InputDir, params.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputPath := OutputWorkbookPath(params)
+
+	if !params.Incremental || !manifestApplies(prev, params.InputDir, outputPath) {
+		// Hashing would read every input once more than the run itself, so
+		// the manifest of a full run records sizes and mtimes only
+		records, err := statFiles(files, prev)
+		if err != nil {
+			return nil, nil, err
+		}
+		result, err := be.execute(ctx, code, params, report)
+		if err != nil {
+			return result, nil, err
+		}
+		now := time.Now()
+		manifest := &model.FileManifest{
+			InputDir:   params.InputDir,
+			OutputPath: outputPath,
+			Files:      make(map[string]model.FileRecord, len(records)),
+			UpdatedAt:  now,
+		}
+		if prev != nil {
+			manifest.ProjectID = prev.ProjectID
+		}
+		for _, r := range records {
+			r.ProcessedAt = now
+			manifest.Files[r.Path] = r
+		}
+		return result, manifest, nil
+	}
+
+	records, err := scanFiles(files, prev)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	plan := planIncremental(prev, records, now)
+	manifest := &model.FileManifest{
+		ProjectID:  prev.ProjectID,
+		InputDir:   params.InputDir,
+		OutputPath: outputPath,
+		Files:      make(map[string]model.FileRecord, len(prev.Files)+len(plan.Changed)),
+	}
+	current := make(map[string]model.FileRecord, len(records))
+	for _, r := range records {
+		current[r.Path] = r
+	}
+	for path, r := range prev.Files {
+		manifest.Files[path] = r
+	}
+	for _, r := range records {
+		if old, ok := prev.Files[r.Path]; ok {
+			// Remember the new mtime so the next run needn't rehash
+			r.ProcessedAt = old.ProcessedAt
+			manifest.Files[r.Path] = r
+		}
+	}
+	for oldPath, newPath := range plan.Moved {
+		r := current[newPath]
+		r.ProcessedAt = prev.Files[oldPath].ProcessedAt
+		manifest.Files[newPath] = r
+		if _, exists := current[oldPath]; !exists {
+			delete(manifest.Files, oldPath)
+		}
+	}
+
+	if len(plan.Changed) == 0 && len(plan.Renames) == 0 {
+		manifest.UpdatedAt = now
+		result := &model.BatchResult{
+			TotalFiles: len(records),
+			OutputPath: params.OutputDir,
+			Skipped:    plan.Skipped,
+			Outputs:    []string{outputPath},
+		}
+		report(&model.BatchProgress{
+			Status:     "completed",
+			TotalFiles: len(records),
+			Progress:   1.0,
+			Message:    fmt.Sprintf("No new or changed files; skipped %d file(s)", len(plan.Skipped)),
+		})
+		return result, manifest, nil
+	}
+
+	result, err := be.executeChanged(ctx, code, params, plan, report)
+	if err != nil {
+		return result, nil, err
+	}
+
+	now = time.Now()
+	for _, r := range plan.Changed {
+		r.ProcessedAt = now
+		manifest.Files[r.Path] = r
+	}
+	manifest.UpdatedAt = now
+	result.TotalFiles = len(records)
+	result.Skipped = plan.Skipped
+	result.Outputs = []string{outputPath}
+	report(&model.BatchProgress{
+		Status:     finishStatus(result),
+		TotalFiles: result.TotalFiles,
+		Processed:  result.Succeeded,
+		Failed:     result.Failed,
+		Progress:   1.0,
+		Message:    incrementalMessage(result, len(plan.Changed), len(plan.Skipped)),
+		Files:      result.Files,
+	})
+	return result, manifest, nil
+}
+
+// incrementalMessage describes a finished incremental run that processed
+// changed files and skipped the others.
+func incrementalMessage(result *model.BatchResult, changed int, skipped int) string {
+	msg := fmt.Sprintf("Processed %d new or changed file(s), skipped %d", changed, skipped)
+	if result.Quality != nil && len(result.Quality.Violations) > 0 {
+		msg += "; " + qualityMessage(result.Quality.Violations)
+	}
+	return msg
+}
+
+// executeChanged processes only the changed files in a staging directory and
+// merges their sheets into the existing output workbook, applying the sheet
+// renames of detected log rotations.
+func (be *BatchExecutor) executeChanged(ctx context.Context, code string, params model.BatchParams, plan incrementalPlan, report ProgressFunc) (*model.BatchResult, error) {
+	workDir, err := os.MkdirTemp("", "batch-incremental-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	result := &model.BatchResult{}
+	var order []string
+	var partials []string
+	if len(plan.Changed) > 0 {
+		inDir := filepath.Join(workDir, "in")
+		if err := os.MkdirAll(inDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		changed := make([]inputFile, len(plan.Changed))
+		for i, r := range plan.Changed {
+			changed[i] = inputFile{Path: r.Path, Name: recordName(r), Size: r.Size}
+		}
+		inputs, err := expandInputs(changed, inDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range inputs {
+			order = append(order, sheetName(f.Name))
+		}
+
+		report(&model.BatchProgress{
+			Status:     "running",
+			TotalFiles: len(inputs),
+			Message:    fmt.Sprintf("Processing %d new or changed file(s), skipping %d unchanged", len(plan.Changed), len(plan.Skipped)),
+		})
+
+		staged := params
+		staged.Sinks = resolveSinks(params) // dead letters stay beside the real output
+		staged.InputDir = inDir
+		staged.OutputDir = filepath.Join(workDir, "out")
+		staged.OutputFileName = "part"
+		staged.Filter = nil
+		if err := os.MkdirAll(staged.OutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+
+		// The workbook isn't final until merged, so hold back the final status
+		stagedReport := func(p *model.BatchProgress) {
+			if p.Status == "completed" || p.Status == "degraded" {
+				q := *p
+				q.Status = "running"
+				q.Message = "Merging new sheets into the existing workbook"
+				p = &q
+			}
+			report(p)
+		}
+		result, err = be.execute(ctx, code, staged, stagedReport)
+		if err != nil {
+			return result, err
+		}
+
+		partials, _ = filepath.Glob(filepath.Join(staged.OutputDir, "*.xlsx"))
+		if len(partials) == 0 {
+			err := fmt.Errorf("script did not produce any .xlsx output")
+			report(&model.BatchProgress{Status: "failed", Message: fmt.Sprintf("Batch processing failed: %v", err)})
+			return &model.BatchResult{Errors: []string{err.Error()}}, err
+		}
+	}
+
+	// Merge next to the workbook, then swap it in so a failed merge leaves
+	// the previous output intact
+	outputPath := OutputWorkbookPath(params)
+	mergedPath := filepath.Join(params.OutputDir, "."+filepath.Base(outputPath)+".merging.xlsx")
+	err = be.runMerge(ctx, workDir, params.OutputDir, map[string]interface{}{
+		"output":   mergedPath,
+		"base":     outputPath,
+		"partials": partials,
+		"order":    order,
+		"renames":  plan.Renames,
+	})
+	if err == nil {
+		err = os.Rename(mergedPath, outputPath)
+	}
+	if err != nil {
+		os.Remove(mergedPath)
+		report(&model.BatchProgress{Status: "failed", Message: fmt.Sprintf("Batch processing failed: %v", err)})
+		return &model.BatchResult{Errors: []string{err.Error()}}, err
+	}
+
+	merged := &model.BatchResult{
+		Succeeded:      result.Succeeded,
+		Failed:         result.Failed,
+		OutputPath:     params.OutputDir,
+		Files:          result.Files,
+		Log:            result.Log,
+		RepairedCode:   result.RepairedCode,
+		RepairAttempts: result.RepairAttempts,
+		Stderr:         result.Stderr,
+		Degraded:       result.Degraded,
+		Quality:        result.Quality,
+		TimeZones:      result.TimeZones,
+		Sinks:          result.Sinks,
+	}
+	// The staged sheets now sit in the existing workbook under their names
+	for _, sh := range result.Sheets {
+		sh.Workbook = outputPath
+		merged.Sheets = append(merged.Sheets, sh)
+	}
+
+	// Rejected lines of reprocessed files replace their earlier ones
+	replaced := make(map[string]bool, len(result.Files))
+	for _, f := range result.Files {
+		replaced[f.File] = true
+	}
+	dst := rejectsPath(params.OutputDir, params.OutputFileName)
+	parts := []string{rejectsPath(filepath.Join(workDir, "out"), "part")}
+	if n, err := mergeRejects(dst, dst, parts, replaced, plan.Renames); err != nil {
+		merged.Log = append(merged.Log, model.LogEntry{Level: "warning", Message: fmt.Sprintf("failed to write rejected lines: %v", err)})
+	} else if n > 0 {
+		merged.RejectedPath = dst
+	}
+	return merged, nil
+}
+
+// manifestApplies reports whether prev records a run from inputDir into the
+// workbook at outputPath and that workbook still exists.
+func manifestApplies(prev *model.FileManifest, inputDir string, outputPath string) bool {
+	if prev == nil || prev.InputDir != inputDir || prev.OutputPath != outputPath {
+		return false
+	}
+	_, err := os.Stat(outputPath)
+	return err == nil
+}
+
+// scanFiles builds a record with size, mtime and content hash for every
+// file. Hashes recorded in prev are reused when size and mtime still match.
+func scanFiles(files []inputFile, prev *model.FileManifest) ([]model.FileRecord, error) {
+	records, err := statFiles(files, prev)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range records {
+		if r.Hash == "" {
+			if records[i].Hash, err = hashFile(r.Path); err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", filepath.Base(r.Path), err)
+			}
+		}
+	}
+	return records, nil
+}
+
+// statFiles builds a record with size and mtime for every file, with the
+// hash recorded in prev when size and mtime still match and none otherwise.
+func statFiles(files []inputFile, prev *model.FileManifest) ([]model.FileRecord, error) {
+	records := make([]model.FileRecord, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", filepath.Base(f.Path), err)
+		}
+		r := model.FileRecord{
+			Path:    f.Path,
+			Name:    f.Name,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+		if prev != nil {
+			if old, ok := prev.Files[f.Path]; ok && old.Size == r.Size && old.ModTime.Equal(r.ModTime) {
+				r.Hash = old.Hash
+			}
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// incrementalPlan describes the work of an incremental run.
+type incrementalPlan struct {
+	Changed []model.FileRecord  // files to process
+	Skipped []model.SkippedFile // files left alone, with the reason
+	Renames map[string]string   // existing sheet name -> new sheet name
+	Moved   map[string]string   // recorded path -> path its record moves to
+}
+
+// planIncremental compares the current files against the manifest of the
+// previous run, detecting log rotations on the way.
+func planIncremental(prev *model.FileManifest, records []model.FileRecord, now time.Time) incrementalPlan {
+	plan := incrementalPlan{
+		Renames: make(map[string]string),
+		Moved:   make(map[string]string),
+	}
+	plan.Changed, plan.Skipped = diffManifest(prev, records)
+	detectRotations(prev, records, &plan, now)
+	return plan
+}
+
+// diffManifest splits records into files that need processing and files
+// that are skipped, with the reason for each skip.
+func diffManifest(prev *model.FileManifest, records []model.FileRecord) ([]model.FileRecord, []model.SkippedFile) {
+	var changed []model.FileRecord
+	var skipped []model.SkippedFile
+	for _, r := range records {
+		old, ok := prev.Files[r.Path]
+		if !ok || !sameContent(old, r) {
+			changed = append(changed, r)
+			continue
+		}
+		reason := skipUnchanged
+		if !old.ModTime.Equal(r.ModTime) {
+			reason = skipOnlyModTimeMoved
+		}
+		skipped = append(skipped, model.SkippedFile{File: recordName(r), Reason: reason})
+	}
+	return changed, skipped
+}
+
+// sameContent reports whether a file still has the content it was recorded
+// with. A record of a full run has no hash, so there an unchanged size and
+// mtime have to do.
+func sameContent(old model.FileRecord, r model.FileRecord) bool {
+	if old.Size != r.Size {
+		return false
+	}
+	if old.Hash == "" {
+		return old.ModTime.Equal(r.ModTime)
+	}
+	return old.Hash == r.Hash
+}
+
+// recordName returns the name a recorded file is staged and its sheet named
+// under. Manifests written before names were recorded fall back to the base
+// name.
+func recordName(r model.FileRecord) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return filepath.Base(r.Path)
+}
+
+// hashFile returns the hex SHA-256 of the file's content.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,306 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 16: 增量处理变更检测
+// For any manifest and set of current files, a file is processed exactly when
+// it is new or its size or hash differs from the recorded one; every other
+// file is skipped with a reason.
+func TestProperty16_DiffManifestDetectsChanges(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		count := rapid.IntRange(0, 20).Draw(rt, "count")
+		prev := &model.FileManifest{Files: make(map[string]model.FileRecord)}
+		base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		var records []model.FileRecord
+		want := make(map[string]bool) // path -> should be processed
+		for i := 0; i < count; i++ {
+			r := model.FileRecord{
+				Path:    fmt.Sprintf("/in/%02d.log", i),
+				Size:    rapid.Int64Range(0, 1000).Draw(rt, "size"),
+				ModTime: base,
+				Hash:    fmt.Sprintf("h%d", i),
+			}
+			old := r
+			switch rapid.IntRange(0, 4).Draw(rt, "change") {
+			case 0: // new file
+				want[r.Path] = true
+				records = append(records, r)
+				continue
+			case 1:
+				old.Size++
+				want[r.Path] = true
+			case 2:
+				old.Hash = "other"
+				want[r.Path] = true
+			case 3:
+				old.ModTime = base.Add(-time.Hour)
+			}
+			prev.Files[r.Path] = old
+			records = append(records, r)
+		}
+
+		changed, skipped := diffManifest(prev, records)
+		if len(changed)+len(skipped) != len(records) {
+			rt.Fatalf("%d changed + %d skipped != %d files", len(changed), len(skipped), len(records))
+		}
+		for _, r := range changed {
+			if !want[r.Path] {
+				rt.Fatalf("unchanged file %s was selected for processing", r.Path)
+			}
+		}
+		for _, s := range skipped {
+			if want["/in/"+s.File] {
+				rt.Fatalf("changed file %s was skipped", s.File)
+			}
+			if s.Reason == "" {
+				rt.Fatalf("file %s skipped without a reason", s.File)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: an incremental run with nothing new skips every file without
+// running the script
+func TestExecuteIncremental_NothingChanged(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("line a"), 0644)
+	os.WriteFile(filepath.Join(inputDir, "b.log"), []byte("line b"), 0644)
+	outputPath := filepath.Join(outputDir, "out.xlsx")
+	os.WriteFile(outputPath, []byte("existing"), 0644)
+
+	files, _ := listInputFiles(inputDir, nil)
+	records, err := scanFiles(files, nil)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	prev := &model.FileManifest{
+		ProjectID:  "p",
+		InputDir:   inputDir,
+		OutputPath: outputPath,
+		Files:      make(map[string]model.FileRecord),
+	}
+	for _, r := range records {
+		prev.Files[r.Path] = r
+	}
+
+	// nil envManager: running the script would panic
+	be := NewBatchExecutor(nil, nil, 3)
+	params := model.BatchParams{InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Incremental: true}
+	result, manifest, err := be.ExecuteIncremental(context.Background(), "", params, prev, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalFiles != 2 || result.Succeeded != 0 || len(result.Skipped) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Skipped[0].Reason != skipUnchanged {
+		t.Fatalf("unexpected skip reason %q", result.Skipped[0].Reason)
+	}
+	if manifest.ProjectID != "p" || len(manifest.Files) != 2 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if p := be.GetProgress(); p.Status != "completed" {
+		t.Fatalf("expected completed progress, got %+v", p)
+	}
+}
+
+// incrementalScript streams the rows of changed.log and, run as the merge
+// of an incremental run, writes an empty merged workbook.
+const incrementalScript = `
+case "$2" in *merge.json)
+  : > "$(sed 's/.*"output":"\([^"]*\)".*/\1/' "$2")"
+  exit 0;;
+esac
+echo '{"v":1,"event":"file_start","file":"changed.log"}'
+echo '{"v":1,"event":"columns","file":"changed.log","columns":["time","status"]}'
+echo '{"v":1,"event":"row","file":"changed.log","values":["2024-05-01 10:00:00",200]}'
+echo '{"v":1,"event":"row","file":"changed.log","values":["2024-05-01 10:00:01","OK"]}'
+echo '{"v":1,"event":"file_done","file":"changed.log","status":"ok"}'
+`
+
+// incrementalRun runs params incrementally over an input directory with an
+// unchanged file and a changed one, against an existing workbook.
+func incrementalRun(t *testing.T, params model.BatchParams) (*model.BatchResult, model.BatchProgress) {
+	t.Helper()
+	be, _ := fakePythonExecutor(t, incrementalScript)
+	params.InputDir, params.OutputDir, params.OutputFileName, params.Incremental = t.TempDir(), t.TempDir(), "out", true
+	os.WriteFile(filepath.Join(params.InputDir, "same.log"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(params.InputDir, "changed.log"), []byte("changed"), 0644)
+	outputPath := filepath.Join(params.OutputDir, "out.xlsx")
+	os.WriteFile(outputPath, []byte("existing"), 0644)
+
+	files, _ := listInputFiles(params.InputDir, nil)
+	records, err := scanFiles(files, nil)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	prev := &model.FileManifest{InputDir: params.InputDir, OutputPath: outputPath, Files: make(map[string]model.FileRecord)}
+	for _, r := range records {
+		if r.Name == "changed.log" {
+			r.Size++
+		}
+		prev.Files[r.Path] = r
+	}
+
+	var last model.BatchProgress
+	result, _, err := be.ExecuteIncremental(context.Background(), "pass", params, prev, func(p *model.BatchProgress) { last = *p })
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.Succeeded != 1 || len(result.Skipped) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	return result, last
+}
+
+// Unit test: rows of an incremental run falling short of the quality
+// thresholds mark it degraded, and the report reaches its result
+func TestExecuteIncremental_Quality(t *testing.T) {
+	max := 10.0
+	result, last := incrementalRun(t, model.BatchParams{
+		Schema:  []model.ColumnSchema{{Name: "status", Type: "integer"}},
+		Quality: &model.QualityThresholds{MaxMismatched: &max, Action: "degrade"},
+	})
+	if result.Quality == nil || len(result.Quality.Violations) != 1 || !result.Degraded {
+		t.Errorf("degraded %v, quality %+v", result.Degraded, result.Quality)
+	}
+	if last.Status != "degraded" || !strings.Contains(last.Message, "data quality") {
+		t.Errorf("final progress %s: %s", last.Status, last.Message)
+	}
+}
+
+// Unit test: an incremental run records the zones it normalized the
+// times of the changed files with
+func TestExecuteIncremental_TimeZones(t *testing.T) {
+	result, _ := incrementalRun(t, model.BatchParams{
+		TimeZones: &model.TimeZones{Source: "UTC", Target: "+08:00"},
+	})
+	z := result.TimeZones
+	if z == nil || z.Source != "UTC" || z.Target != "+08:00" || z.Converted != 2 {
+		t.Errorf("applied zones = %+v", z)
+	}
+}
+
+// Unit test: an incremental run reports what reached its sinks, with the
+// dead-letter file beside the real output, and where the rows of the
+// changed files now are in the existing workbook
+func TestExecuteIncremental_SinksAndSheets(t *testing.T) {
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+	}))
+	defer refusing.Close()
+
+	result, _ := incrementalRun(t, model.BatchParams{
+		Sinks: []model.SinkConfig{{Type: "webhook", URL: refusing.URL}},
+	})
+	deadLetter := filepath.Join(result.OutputPath, "out.deadletter.jsonl")
+	if len(result.Sinks) != 1 || result.Sinks[0].Failed != 2 || result.Sinks[0].DeadLetter != deadLetter {
+		t.Errorf("sinks = %+v", result.Sinks)
+	}
+	want := []model.SheetPlacement{{File: "changed.log", Workbook: filepath.Join(result.OutputPath, "out.xlsx"), Sheet: "changed.log", Rows: 2}}
+	if !reflect.DeepEqual(result.Sheets, want) {
+		t.Errorf("sheets = %+v, want %+v", result.Sheets, want)
+	}
+}
+
+// Unit test: a full run records its files without reading them for a
+// hash, and an incremental run from that manifest skips the files whose
+// size and mtime haven't moved, hashing them for the runs after it
+func TestExecuteIncremental_FullRunRecordsNoHashes(t *testing.T) {
+	be, _ := fakePythonExecutor(t, incrementalScript)
+	params := model.BatchParams{InputDir: t.TempDir(), OutputDir: t.TempDir(), OutputFileName: "out"}
+	os.WriteFile(filepath.Join(params.InputDir, "same.log"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(params.InputDir, "changed.log"), []byte("changed"), 0644)
+
+	_, manifest, err := be.ExecuteIncremental(context.Background(), "pass", params, nil, nil)
+	if err != nil {
+		t.Fatalf("full run failed: %v", err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("manifest files = %+v", manifest.Files)
+	}
+	for _, r := range manifest.Files {
+		if r.Hash != "" || r.Size == 0 {
+			t.Errorf("full run recorded %+v", r)
+		}
+	}
+
+	os.WriteFile(filepath.Join(params.InputDir, "changed.log"), []byte("changed again"), 0644)
+	params.Incremental = true
+	result, next, err := be.ExecuteIncremental(context.Background(), "pass", params, manifest, nil)
+	if err != nil {
+		t.Fatalf("incremental run failed: %v", err)
+	}
+	if result.Succeeded != 1 || len(result.Skipped) != 1 || result.Skipped[0].File != "same.log" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for _, r := range next.Files {
+		if r.Hash == "" {
+			t.Errorf("incremental run left %s unhashed", r.Name)
+		}
+	}
+}
+
+// Unit test: a manifest only applies to the same input and an existing workbook
+func TestManifestApplies(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "out.xlsx")
+	prev := &model.FileManifest{InputDir: "/in", OutputPath: outputPath}
+
+	if manifestApplies(nil, "/in", outputPath) {
+		t.Fatal("nil manifest should not apply")
+	}
+	if manifestApplies(prev, "/in", outputPath) {
+		t.Fatal("manifest should not apply while the workbook is missing")
+	}
+	os.WriteFile(outputPath, []byte("x"), 0644)
+	if !manifestApplies(prev, "/in", outputPath) {
+		t.Fatal("expected manifest to apply")
+	}
+	if manifestApplies(prev, "/other", outputPath) {
+		t.Fatal("manifest should not apply to another input directory")
+	}
+}
+
+// Unit test: recorded hashes are reused when size and mtime match
+func TestScanFiles_ReusesHash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.log")
+	os.WriteFile(path, []byte("hello"), 0644)
+	files, _ := listInputFiles(dir, nil)
+
+	records, err := scanFiles(files, nil)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if records[0].Hash != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected hash %s", records[0].Hash)
+	}
+
+	prev := &model.FileManifest{Files: map[string]model.FileRecord{
+		path: {Path: path, Size: records[0].Size, ModTime: records[0].ModTime, Hash: "recorded"},
+	}}
+	records, _ = scanFiles(files, prev)
+	if records[0].Hash != "recorded" {
+		t.Fatalf("expected recorded hash to be reused, got %s", records[0].Hash)
+	}
+}
@@ -0,0 +1,190 @@
+"""Progress reporting for LogForge batch scripts.
+
+LogForge places this module next to the script it runs, so generated scripts
+can simply ``import logforge``. Every call prints one JSON event line to
+stdout (protocol version 1), which LogForge reads to show live progress and
+per-file results:
+
+    logforge.start(total=len(files))
+    for i, name in enumerate(files, 1):
+        logforge.file_start(name, index=i, total=len(files))
+        ...
+        logforge.rows(name, written)              # optional, while writing
+        logforge.warning("bad timestamp", file=name, line=lineno)
+        logforge.reject(name, line=lineno, reason="no timestamp", text=raw)
+        logforge.file_done(name, rows=written, skipped=bad_lines)
+    logforge.done(files=len(files), rows=total_rows)
+
+A file that failed is reported with ``file_done(name, error=str(exc))``.
+Rejected lines are written to a CSV next to the workbook and count as skipped.
+Any other output on stdout is kept as log text.
+
+Parsed rows are streamed to LogForge, which writes them in the output formats
+chosen for the run (the .xlsx workbook, CSV, JSON Lines, Parquet, SQLite):
+
+    logforge.columns(name, ["time", "level", "message"])
+    for record in records:
+        logforge.row(name, record)                # dict or list of values
+
+Scripts don't write the workbook themselves; ``excel_enabled()`` is kept
+for scripts written when they did and returns False under LogForge.
+
+Times are written as the logs give them. When the run normalizes times,
+LogForge converts them to the run's target zone, taking times without an
+offset to be in the zone of their file; ``source_zone(name)`` and
+``target_zone()`` name those zones for scripts that need them.
+"""
+
+import fnmatch
+import json
+import math
+import os
+import sys
+
+VERSION = 1
+
+
+def _load_config():
+    try:
+        with open(os.path.join(os.path.dirname(os.path.abspath(__file__)), "logforge.json"), encoding="utf-8") as f:
+            return json.load(f)
+    except (OSError, ValueError):
+        return {}
+
+
+_config = _load_config()
+_columns = {}
+_warned = set()
+
+
+def _emit(event, **fields):
+    data = {"v": VERSION, "event": event}
+    for key, value in fields.items():
+        if value is not None:
+            data[key] = value
+    sys.stdout.write(json.dumps(data, ensure_ascii=False, default=str) + "\n")
+    sys.stdout.flush()
+
+
+def start(total):
+    """Announce the number of files the script is going to process."""
+    _emit("start", total=int(total))
+
+
+def file_start(file, index=None, total=None):
+    """Announce that processing of a file begins."""
+    _emit("file_start", file=str(file), index=index, total=total)
+
+
+def excel_enabled():
+    """Whether the script should write the .xlsx workbook itself."""
+    return bool(_config.get("excel", True))
+
+
+def rows_enabled():
+    """Whether rows passed to row() are written anywhere."""
+    return bool(_config.get("rows", False))
+
+
+def _zones():
+    return _config.get("zones") or {}
+
+
+def source_zone(file):
+    """The zone of the times of a file written without an offset: an IANA
+    name such as "Asia/Shanghai", "UTC", "Local" or an offset such as
+    "+08:00"; None when the run leaves times as written."""
+    zones = _zones()
+    if not zones.get("target"):
+        return None
+    name = str(file).replace("\\", "/")
+    for f in zones.get("files") or []:
+        pattern = f.get("pattern", "")
+        if fnmatch.fnmatchcase(name, pattern) or ("/" not in pattern and fnmatch.fnmatchcase(os.path.basename(name), pattern)):
+            return f.get("zone")
+    return zones.get("source") or zones.get("target")
+
+
+def target_zone():
+    """The zone LogForge converts times to; None when it leaves them as
+    written."""
+    return _zones().get("target") or None
+
+
+def columns(file, names):
+    """Declare the columns of a file's rows; call it before the first row."""
+    names = [str(n) for n in names]
+    _columns[str(file)] = names
+    if rows_enabled():
+        _emit("columns", file=str(file), columns=names)
+
+
+def _value(value):
+    if isinstance(value, float) and not math.isfinite(value):
+        return None
+    if value is None or isinstance(value, (bool, int, float, str)):
+        return value
+    return str(value)
+
+
+def row(file, values):
+    """Stream one parsed row of a file. values is a dict keyed by column name,
+    whose keys declare the columns if columns() wasn't called, or a list in
+    column order. Dates and other values are written as text."""
+    if not rows_enabled():
+        return
+    file = str(file)
+    if isinstance(values, dict):
+        names = _columns.get(file)
+        if names is None:
+            columns(file, list(values.keys()))
+            names = _columns[file]
+        if file not in _warned and any(str(k) not in names for k in values):
+            _warned.add(file)
+            warning("row has keys that are not columns; they are dropped", file=file)
+        values = [values.get(n) for n in names]
+    data = {"v": VERSION, "event": "row", "file": file, "values": [_value(v) for v in values]}
+    sys.stdout.write(json.dumps(data, ensure_ascii=False) + "\n")
+
+
+def rows(file, count):
+    """Report the rows written so far for a file; call it every few thousand rows."""
+    _emit("rows", file=str(file), rows=int(count))
+
+
+def warning(message, file=None, line=None):
+    """Report a problem that doesn't stop processing, e.g. an unparseable line."""
+    _emit("warning", message=str(message), file=None if file is None else str(file), line=line)
+
+
+def reject(file, line=None, reason=None, text=None):
+    """Quarantine a line that couldn't be parsed; it counts as skipped."""
+    _emit(
+        "reject",
+        file=str(file),
+        line=line,
+        reason=None if reason is None else str(reason),
+        text=None if text is None else str(text).rstrip("\r\n"),
+    )
+
+
+def log(message, level="info"):
+    """Report a free-form message."""
+    _emit("log", message=str(message), level=level)
+
+
+def file_done(file, rows=0, skipped=0, error=None):
+    """Report that a file is finished, or failed when error is given."""
+    _emit(
+        "file_done",
+        file=str(file),
+        status="error" if error else "ok",
+        rows=int(rows),
+        skipped=int(skipped),
+        error=None if error is None else str(error),
+    )
+
+
+def done(files=None, rows=None):
+    """Report that the script finished."""
+    _emit("done", files=files, rows=rows)
@@ -0,0 +1,84 @@
+"""Merge partial workbooks written by parallel workers into one workbook.
+
+Invoked by BatchExecutor with a single argument: the path to a JSON manifest
+of the form {"output": "<final.xlsx>", "partials": ["<a.xlsx>", ...],
+"order": ["<sheet name>", ...]}. Sheets listed in "order" are written first in
+that order, remaining sheets follow in partial order. Duplicate sheet names
+get a " (2)", " (3)" suffix within Excel's 31 character limit.
+
+Incremental runs add "base": "<existing.xlsx>" and optionally "renames":
+{"<old sheet>": "<new sheet>"}. The base workbook's sheets keep their
+position, renamed ones under their new name (replacing any unrenamed sheet of
+that name). A partial sheet with the same name as a resulting sheet replaces
+it in place and other partial sheets are appended.
+"""
+import json
+import sys
+
+from openpyxl import Workbook, load_workbook
+
+
+def unique_name(name, used):
+    if name not in used:
+        return name
+    i = 2
+    while True:
+        suffix = " (%d)" % i
+        candidate = name[:31 - len(suffix)] + suffix
+        if candidate not in used:
+            return candidate
+        i += 1
+
+
+def copy_sheet(src, dst):
+    for row in src.iter_rows(values_only=True):
+        dst.append(row)
+
+
+def main():
+    with open(sys.argv[1], "r", encoding="utf-8") as f:
+        manifest = json.load(f)
+
+    # One workbook per worker, so keeping them all open is cheap
+    books = [load_workbook(path, read_only=True) for path in manifest["partials"]]
+    sources = []  # (sheet name, workbook)
+    for wb in books:
+        for name in wb.sheetnames:
+            sources.append((name, wb))
+
+    rank = {name: i for i, name in enumerate(manifest.get("order", []))}
+    sources.sort(key=lambda s: rank.get(s[0], len(rank)))
+
+    out = Workbook(write_only=True)
+    used = set()
+    if manifest.get("base"):
+        base = load_workbook(manifest["base"], read_only=True)
+        books.append(base)
+        renames = manifest.get("renames") or {}
+        targets = set(renames.values())
+        # Dicts keep insertion order, so renamed and replaced sheets stay in place
+        merged = {}
+        for name in base.sheetnames:
+            if name in targets and name not in renames:
+                continue  # superseded by a renamed sheet
+            merged[renames.get(name, name)] = (base, name)
+        for name, wb in sources:
+            merged[name] = (wb, name)
+        for title, (wb, name) in merged.items():
+            used.add(title)
+            copy_sheet(wb[name], out.create_sheet(title=title))
+    else:
+        for name, wb in sources:
+            title = unique_name(name, used)
+            used.add(title)
+            copy_sheet(wb[name], out.create_sheet(title=title))
+
+    if not used:
+        out.create_sheet(title="Sheet1")
+    out.save(manifest["output"])
+    for wb in books:
+        wb.close()
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,463 @@
+package executor
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/pyenv"
+)
+
+// mergeWorkbooksScript merges the partial workbooks written by parallel workers.
+//
+//go:embed merge_workbooks.py
+var mergeWorkbooksScript string
+
+// maxSheetNameLen is Excel's limit on worksheet name length.
+const maxSheetNameLen = output.MaxSheetNameLen
+
+// executeParallel shards the selected files across a pool of Python processes.
+// Each worker runs the unmodified script on a staging directory holding only
+// its shard and streams its rows into the shared outputs; scripts that write
+// the workbook themselves write partial workbooks, which are then merged into
+// the single {output-name}.xlsx with one sheet per input file. Workers that
+// hit a runtime error are retried with LLM-repaired code while successful
+// shards keep their output.
+func (be *BatchExecutor) executeParallel(ctx context.Context, code string, params model.BatchParams, files []inputFile, out *rowOutput, report ProgressFunc) (*model.BatchResult, error) {
+	workDir, err := os.MkdirTemp("", "batch-parallel-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	shards := shardFiles(files, params.Workers)
+	for i, shard := range shards {
+		shardDir := filepath.Join(workDir, fmt.Sprintf("in-%d", i))
+		if err := os.MkdirAll(shardDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create shard directory: %w", err)
+		}
+		for _, f := range shard {
+			if err := stageFile(f.Path, filepath.Join(shardDir, f.Name)); err != nil {
+				return nil, fmt.Errorf("failed to stage %s: %w", f.Name, err)
+			}
+		}
+	}
+
+	tracker := newShardTracker(len(files), len(shards), report)
+	pending := make([]int, len(shards))
+	for i := range shards {
+		pending[i] = i
+	}
+
+	currentCode := code
+	var lastErr string
+	repairs := 0
+	for attempt := 0; attempt <= be.maxRetries; attempt++ {
+		failures, limitErr := be.runShards(ctx, currentCode, workDir, pending, out, tracker)
+		if len(failures) == 0 {
+			lastErr = ""
+			break
+		}
+
+		if ctx.Err() != nil {
+			report(&model.BatchProgress{
+				Status:  "cancelled",
+				Message: "Batch processing cancelled",
+			})
+			return &model.BatchResult{}, ctx.Err()
+		}
+		if limitErr != nil {
+			result, err := be.limitFailure(limitErr, tracker.fileResults(), report)
+			result.RepairAttempts = repairs
+			return result, err
+		}
+
+		pending = pending[:0]
+		for i := range failures {
+			pending = append(pending, i)
+		}
+		sort.Ints(pending)
+		lastErr = failures[pending[0]]
+
+		if attempt >= be.maxRetries {
+			break
+		}
+		fixedCode, ok := be.repair(ctx, currentCode, lastErr, attempt, report)
+		repairs++
+		if !ok {
+			break
+		}
+		currentCode = fixedCode
+
+		// Rerun failed shards from a clean output directory
+		for _, i := range pending {
+			tracker.reset(i)
+			os.RemoveAll(shardOutputDir(workDir, i))
+		}
+	}
+
+	if lastErr != "" {
+		report(&model.BatchProgress{
+			Status:  "failed",
+			Message: fmt.Sprintf("Batch processing failed: %s", lastErr),
+		})
+		return &model.BatchResult{
+			Errors:         []string{lastErr},
+			Stderr:         stderrExcerpt(lastErr),
+			RepairAttempts: repairs,
+		}, fmt.Errorf("batch execution failed after %d retries: %s", be.maxRetries, lastErr)
+	}
+
+	// Streamed rows are already in the outputs; partial workbooks are only
+	// merged for scripts that wrote the workbook themselves
+	if !out.streamed() {
+		report(&model.BatchProgress{
+			Status:     "running",
+			TotalFiles: len(files),
+			Processed:  len(files),
+			Progress:   1.0,
+			Message:    "Merging partial workbooks",
+		})
+		outputPath := OutputWorkbookPath(params)
+		if err := be.mergeWorkbooks(ctx, workDir, len(shards), files, outputPath); err != nil {
+			report(&model.BatchProgress{
+				Status:  "failed",
+				Message: fmt.Sprintf("Batch processing failed: %v", err),
+			})
+			return &model.BatchResult{Errors: []string{err.Error()}}, err
+		}
+	}
+
+	fileResults := tracker.fileResults()
+	succeeded, failed := countFiles(fileResults)
+	result := &model.BatchResult{
+		TotalFiles: len(files),
+		Succeeded:  succeeded,
+		Failed:     failed,
+		OutputPath: params.OutputDir,
+		Files:      fileResults,
+	}
+	if currentCode != code {
+		result.RepairedCode = currentCode
+	}
+	result.RepairAttempts = repairs
+	if path, err := mergeShardRejects(workDir, len(shards), params); err != nil {
+		result.Log = append(result.Log, model.LogEntry{Level: "warning", Message: fmt.Sprintf("failed to write rejected lines: %v", err)})
+	} else {
+		result.RejectedPath = path
+	}
+	report(&model.BatchProgress{
+		Status:     finishStatus(result),
+		TotalFiles: result.TotalFiles,
+		Processed:  result.Succeeded,
+		Failed:     result.Failed,
+		Progress:   1.0,
+		Message:    finishMessage(result),
+		Files:      result.Files,
+	})
+	return result, nil
+}
+
+// runShards runs the given shards concurrently and returns the error output
+// of every shard that failed, keyed by shard index, and the first resource
+// limit a shard was stopped by.
+func (be *BatchExecutor) runShards(ctx context.Context, code string, workDir string, shards []int, out *rowOutput, tracker *shardTracker) (map[int]string, *pyenv.LimitError) {
+	var mu sync.Mutex
+	failures := make(map[int]string)
+	var limitErr *pyenv.LimitError
+
+	var wg sync.WaitGroup
+	for _, i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			inDir := filepath.Join(workDir, fmt.Sprintf("in-%d", i))
+			outDir := shardOutputDir(workDir, i)
+			if err := os.MkdirAll(outDir, 0755); err != nil {
+				mu.Lock()
+				failures[i] = err.Error()
+				mu.Unlock()
+				return
+			}
+
+			_, stderrOutput, err := be.runScript(ctx, code, inDir, outDir, "part", out, tracker.reporter(i))
+			if err != nil {
+				msg := stderrOutput
+				if msg == "" {
+					msg = err.Error()
+				}
+				mu.Lock()
+				failures[i] = fmt.Sprintf("worker %d: %s", i+1, msg)
+				if le := pyenv.AsLimitError(err); le != nil && limitErr == nil {
+					limitErr = le
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return failures, limitErr
+}
+
+// mergeWorkbooks combines every workbook produced by the shards into
+// outputPath, ordering sheets by input file order.
+func (be *BatchExecutor) mergeWorkbooks(ctx context.Context, workDir string, shardCount int, files []inputFile, outputPath string) error {
+	var partials []string
+	for i := 0; i < shardCount; i++ {
+		matches, _ := filepath.Glob(filepath.Join(shardOutputDir(workDir, i), "*.xlsx"))
+		sort.Strings(matches)
+		partials = append(partials, matches...)
+	}
+	if len(partials) == 0 {
+		return fmt.Errorf("workers did not produce any .xlsx output")
+	}
+
+	order := make([]string, len(files))
+	for i, f := range files {
+		order[i] = sheetName(f.Name)
+	}
+
+	return be.runMerge(ctx, workDir, filepath.Dir(outputPath), map[string]interface{}{
+		"output":   outputPath,
+		"partials": partials,
+		"order":    order,
+	})
+}
+
+// mergeShardRejects concatenates the quarantine files of the shards into the
+// one next to the output workbook and returns its path, or "" when no line
+// was rejected.
+func mergeShardRejects(workDir string, shardCount int, params model.BatchParams) (string, error) {
+	parts := make([]string, shardCount)
+	for i := range parts {
+		parts[i] = rejectsPath(shardOutputDir(workDir, i), "part")
+	}
+	dst := rejectsPath(params.OutputDir, params.OutputFileName)
+	n, err := mergeRejects(dst, "", parts, nil, nil)
+	if err != nil || n == 0 {
+		return "", err
+	}
+	return dst, nil
+}
+
+// runMerge writes manifest into workDir and runs merge_workbooks.py on it,
+// letting it write only to outputDir.
+func (be *BatchExecutor) runMerge(ctx context.Context, workDir string, outputDir string, manifest map[string]interface{}) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal merge manifest: %w", err)
+	}
+	manifestPath := filepath.Join(workDir, "merge.json")
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write merge manifest: %w", err)
+	}
+	scriptPath := filepath.Join(workDir, "merge_workbooks.py")
+	if err := os.WriteFile(scriptPath, []byte(mergeWorkbooksScript), 0644); err != nil {
+		return fmt.Errorf("failed to write merge script: %w", err)
+	}
+
+	// The merge prints nothing while it works, so only the other limits apply
+	limits := be.envManager.Limits()
+	limits.IdleTimeout = 0
+	access := pyenv.Access{ReadOnly: []string{workDir}, Writable: []string{outputDir}}
+	run, err := be.envManager.RunScriptWithLimits(ctx, scriptPath, []string{manifestPath}, limits, access)
+	if err != nil {
+		return fmt.Errorf("failed to start merge script: %w", err)
+	}
+	go io.Copy(io.Discard, run.Stdout)
+	errOutput, _ := io.ReadAll(run.Stderr)
+	if err := run.Wait(); err != nil {
+		msg := strings.TrimSpace(string(errOutput))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("failed to merge partial workbooks: %s", msg)
+	}
+	return nil
+}
+
+// shardOutputDir returns the partial output directory of shard i.
+func shardOutputDir(workDir string, i int) string {
+	return filepath.Join(workDir, fmt.Sprintf("out-%d", i))
+}
+
+// shardFiles distributes files over at most workers shards, balancing the
+// total bytes per shard by assigning the largest remaining file to the
+// lightest shard. Files keep their name order within a shard and empty
+// shards are dropped.
+func shardFiles(files []inputFile, workers int) [][]inputFile {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	bySize := make([]int, len(files))
+	for i := range bySize {
+		bySize[i] = i
+	}
+	sort.SliceStable(bySize, func(a, b int) bool {
+		return files[bySize[a]].Size > files[bySize[b]].Size
+	})
+
+	assigned := make([][]int, workers)
+	load := make([]int64, workers)
+	for _, idx := range bySize {
+		lightest := 0
+		for w := 1; w < workers; w++ {
+			if load[w] < load[lightest] {
+				lightest = w
+			}
+		}
+		assigned[lightest] = append(assigned[lightest], idx)
+		load[lightest] += files[idx].Size
+	}
+
+	shards := make([][]inputFile, 0, workers)
+	for _, idxs := range assigned {
+		if len(idxs) == 0 {
+			continue
+		}
+		sort.Ints(idxs)
+		shard := make([]inputFile, len(idxs))
+		for i, idx := range idxs {
+			shard[i] = files[idx]
+		}
+		shards = append(shards, shard)
+	}
+	return shards
+}
+
+// stageFile makes src available at dst without copying when possible:
+// it tries a hard link, then a symbolic link, and falls back to a copy.
+func stageFile(src string, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	if err := os.Symlink(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// sheetName returns the worksheet name of a file's rows.
+func sheetName(fileName string) string {
+	return output.SheetName(fileName)
+}
+
+// shardTracker aggregates the progress of parallel workers into a single
+// progress report covering all input files.
+type shardTracker struct {
+	mu     sync.Mutex
+	total  int
+	done   []int
+	files  [][]model.FileResult
+	report ProgressFunc
+}
+
+// newShardTracker creates a tracker for total files split over shards.
+func newShardTracker(total int, shards int, report ProgressFunc) *shardTracker {
+	return &shardTracker{
+		total:  total,
+		done:   make([]int, shards),
+		files:  make([][]model.FileResult, shards),
+		report: report,
+	}
+}
+
+// reporter returns the ProgressFunc for shard i.
+func (st *shardTracker) reporter(i int) ProgressFunc {
+	return func(p *model.BatchProgress) {
+		st.mu.Lock()
+		if p.Processed > st.done[i] {
+			st.done[i] = p.Processed
+		}
+		if p.Files != nil {
+			st.files[i] = p.Files
+		}
+		processed := st.sumLocked()
+		files := st.filesLocked()
+		st.mu.Unlock()
+		_, failed := countFiles(files)
+
+		progress := 0.0
+		if st.total > 0 {
+			progress = float64(processed) / float64(st.total)
+		}
+		st.report(&model.BatchProgress{
+			Status:      "running",
+			CurrentFile: p.CurrentFile,
+			Progress:    progress,
+			TotalFiles:  st.total,
+			Processed:   processed,
+			Failed:      failed,
+			Message:     p.Message,
+			Files:       files,
+		})
+	}
+}
+
+// reset clears the progress of shard i before it is rerun.
+func (st *shardTracker) reset(i int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.done[i] = 0
+	st.files[i] = nil
+}
+
+// fileResults returns the file results of all shards, shard by shard.
+func (st *shardTracker) fileResults() []model.FileResult {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.filesLocked()
+}
+
+// processed returns the number of files processed across all shards.
+func (st *shardTracker) processed() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.sumLocked()
+}
+
+// sumLocked adds up per-shard counts. The caller must hold st.mu.
+func (st *shardTracker) sumLocked() int {
+	sum := 0
+	for _, n := range st.done {
+		sum += n
+	}
+	return sum
+}
+
+// filesLocked concatenates per-shard file results. The caller must hold st.mu.
+func (st *shardTracker) filesLocked() []model.FileResult {
+	var files []model.FileResult
+	for _, shard := range st.files {
+		files = append(files, shard...)
+	}
+	return files
+}
@@ -0,0 +1,161 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 15: 并行分片完整性
+// For any set of input files and worker count, sharding assigns every file to
+// exactly one shard, produces no empty shards and never more shards than
+// workers.
+func TestProperty15_ShardFilesCoversEveryFileOnce(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		count := rapid.IntRange(1, 40).Draw(rt, "count")
+		workers := rapid.IntRange(0, 10).Draw(rt, "workers")
+
+		files := make([]inputFile, count)
+		for i := range files {
+			files[i] = inputFile{
+				Path: fmt.Sprintf("/in/file-%03d.log", i),
+				Size: rapid.Int64Range(0, 1<<20).Draw(rt, "size"),
+			}
+		}
+
+		shards := shardFiles(files, workers)
+
+		limit := workers
+		if limit < 1 {
+			limit = 1
+		}
+		if len(shards) > limit {
+			rt.Fatalf("got %d shards for %d workers", len(shards), workers)
+		}
+
+		seen := make(map[string]int)
+		for _, shard := range shards {
+			if len(shard) == 0 {
+				rt.Fatal("empty shard")
+			}
+			for i, f := range shard {
+				seen[f.Path]++
+				if i > 0 && shard[i-1].Path >= f.Path {
+					rt.Fatalf("shard not in name order: %q before %q", shard[i-1].Path, f.Path)
+				}
+			}
+		}
+		for _, f := range files {
+			if seen[f.Path] != 1 {
+				rt.Fatalf("file %s assigned %d times", f.Path, seen[f.Path])
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: shards are balanced by size
+func TestShardFiles_BalancesBySize(t *testing.T) {
+	files := []inputFile{
+		{Path: "a", Size: 100},
+		{Path: "b", Size: 10},
+		{Path: "c", Size: 10},
+		{Path: "d", Size: 80},
+	}
+	shards := shardFiles(files, 2)
+	if len(shards) != 2 {
+		t.Fatalf("expected 2 shards, got %d", len(shards))
+	}
+	for _, shard := range shards {
+		var total int64
+		for _, f := range shard {
+			total += f.Size
+		}
+		if total != 100 {
+			t.Fatalf("expected balanced shards of 100 bytes, got %d (%+v)", total, shard)
+		}
+	}
+}
+
+// Unit test: hidden files and directories are not listed
+func TestListInputFiles_SkipsHiddenAndDirs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.log"), []byte("bb"), 0644)
+	os.WriteFile(filepath.Join(dir, "a.log"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+
+	files, err := listInputFiles(dir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0].Path) != "a.log" || filepath.Base(files[1].Path) != "b.log" {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	if files[1].Size != 2 {
+		t.Fatalf("expected size 2, got %d", files[1].Size)
+	}
+}
+
+// Unit test: staged files have the source content
+func TestStageFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	dst := filepath.Join(dir, "dst.log")
+	os.WriteFile(src, []byte("hello"), 0644)
+
+	if err := stageFile(src, dst); err != nil {
+		t.Fatalf("stage failed: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("unexpected staged content %q (%v)", data, err)
+	}
+}
+
+// Unit test: sheet names are truncated to 31 characters
+func TestSheetName(t *testing.T) {
+	if got := sheetName("short.log"); got != "short.log" {
+		t.Fatalf("expected unchanged name, got %q", got)
+	}
+	long := "a-very-long-log-file-name-exceeding-excel-limit.log"
+	if got := sheetName(long); len([]rune(got)) != 31 {
+		t.Fatalf("expected 31 characters, got %q", got)
+	}
+}
+
+// Unit test: shard progress is aggregated over all files
+func TestShardTracker_AggregatesProgress(t *testing.T) {
+	var last model.BatchProgress
+	tracker := newShardTracker(5, 2, func(p *model.BatchProgress) { last = *p })
+
+	tracker.reporter(0)(&model.BatchProgress{Processed: 2, CurrentFile: "a.log", Files: []model.FileResult{
+		{File: "a.log", Status: "succeeded"}, {File: "b.log", Status: "failed"},
+	}})
+	tracker.reporter(1)(&model.BatchProgress{Processed: 1, CurrentFile: "d.log", Files: []model.FileResult{
+		{File: "d.log", Status: "succeeded"},
+	}})
+	if last.Processed != 3 || last.TotalFiles != 5 || last.CurrentFile != "d.log" {
+		t.Fatalf("unexpected progress: %+v", last)
+	}
+	if len(last.Files) != 3 || last.Failed != 1 {
+		t.Fatalf("expected file results of both shards, got %+v", last.Files)
+	}
+	if last.Progress != 0.6 {
+		t.Fatalf("expected progress 0.6, got %v", last.Progress)
+	}
+
+	tracker.reset(0)
+	if got := tracker.processed(); got != 1 {
+		t.Fatalf("expected 1 processed after reset, got %d", got)
+	}
+	if got := tracker.fileResults(); len(got) != 1 || got[0].File != "d.log" {
+		t.Fatalf("expected only d.log after reset, got %+v", got)
+	}
+}
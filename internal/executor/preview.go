@@ -0,0 +1,282 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"network-log-formatter/internal/archive"
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/xlsx"
+)
+
+const (
+	// defaultPreviewLines is the number of lines a preview reads per file.
+	defaultPreviewLines = 100
+	// maxPreviewLines caps the lines a preview reads per file.
+	maxPreviewLines = 10000
+	// defaultPreviewPageSize is the number of rows per preview table page.
+	defaultPreviewPageSize = 50
+	// previewOutputName is the workbook name preview runs write to.
+	previewOutputName = "preview"
+)
+
+// Preview runs code once on the first lines of the selected input files and
+// reads the rows it wrote back as one table per file. The script runs in a
+// temporary directory, so nothing is written next to the real output, and
+// runtime errors are returned rather than repaired. The rows are written with
+// the schema, time zones, quality thresholds and enrichment of run, the
+// parameters a batch run of the project would execute with; its outputs and
+// sinks are ignored. The tables hold every row; use PageTable to cut them
+// into pages.
+func (be *BatchExecutor) Preview(ctx context.Context, code string, params model.PreviewParams, run model.BatchParams, report ProgressFunc) (*model.Preview, error) {
+	if report == nil {
+		report = be.setProgress
+	}
+	files, err := previewFiles(params)
+	if err != nil {
+		return nil, err
+	}
+	lines := params.Lines
+	if lines <= 0 {
+		lines = defaultPreviewLines
+	}
+	lines = min(lines, maxPreviewLines)
+
+	workDir, err := os.MkdirTemp("", "batch-preview-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	inputDir, outputDir := filepath.Join(workDir, "input"), filepath.Join(workDir, "output")
+	for _, dir := range []string{inputDir, outputDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	used := make(map[string]bool, len(files))
+	for _, f := range files {
+		name, err := headFile(f, inputDir, lines, used)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		names = append(names, name)
+	}
+
+	target := model.BatchParams{
+		OutputDir:      outputDir,
+		OutputFileName: previewOutputName,
+		Schema:         run.Schema,
+		Quality:        run.Quality,
+		TimeZones:      run.TimeZones,
+		Enrich:         run.Enrich,
+	}
+	out, err := newRowOutput(target)
+	if err != nil {
+		return nil, err
+	}
+	result, stderr, err := be.runScript(ctx, code, inputDir, outputDir, previewOutputName, out, report)
+	if err == nil && out.streamed() {
+		_, err = out.close()
+	} else {
+		out.abort()
+	}
+	if err != nil {
+		if stderr != "" {
+			return nil, fmt.Errorf("preview failed: %w\n%s", err, stderrExcerpt(stderr))
+		}
+		return nil, fmt.Errorf("preview failed: %w", err)
+	}
+
+	workbook := OutputWorkbookPath(target)
+	if _, err := os.Stat(workbook); err != nil {
+		return nil, fmt.Errorf("the script streamed no rows and wrote no workbook")
+	}
+	sheets, err := xlsx.ReadFile(workbook, 0)
+	if err != nil {
+		return nil, err
+	}
+	rejects, err := readRejects(rejectsPath(outputDir, previewOutputName))
+	if err != nil {
+		return nil, err
+	}
+	return &model.Preview{
+		Tables: previewTables(names, sheets, rejects, result.Files),
+		Log:    result.Log,
+	}, nil
+}
+
+// previewFiles returns the input files a preview reads: those selected by
+// the filter, narrowed to the named files if any are given.
+func previewFiles(params model.PreviewParams) ([]inputFile, error) {
+	if strings.TrimSpace(params.InputDir) == "" {
+		return nil, fmt.Errorf("input directory must not be empty")
+	}
+	if _, err := os.Stat(params.InputDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("input directory does not exist: %s", params.InputDir)
+	}
+	if err := validateFilter(params.Filter); err != nil {
+		return nil, err
+	}
+	files, err := listInputFiles(params.InputDir, params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Files) > 0 {
+		wanted := make(map[string]bool, len(params.Files))
+		for _, name := range params.Files {
+			wanted[name] = true
+		}
+		selected := files[:0]
+		for _, f := range files {
+			if wanted[f.Name] {
+				selected = append(selected, f)
+			}
+		}
+		files = selected
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files in %s to preview", params.InputDir)
+	}
+	return files, nil
+}
+
+// headFile writes the first lines of f, decompressed, into dir and returns
+// the name it was written under: the name a full run would stage it under,
+// with a " (n)" suffix if that is already used.
+func headFile(f inputFile, dir string, lines int, used map[string]bool) (string, error) {
+	format, err := archive.Detect(f.Path)
+	if err != nil {
+		return "", err
+	}
+	rc, inner, err := archive.Open(f.Path)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	name := f.Name
+	if format.IsArchive() {
+		name = inner
+	} else if format != archive.Plain {
+		name = archive.TrimExt(f.Name)
+	}
+	base, ext := strings.TrimSuffix(name, filepath.Ext(name)), filepath.Ext(name)
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	used[name] = true
+
+	out, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	r := bufio.NewReader(rc)
+	for i := 0; i < lines; i++ {
+		line, err := r.ReadString('\n')
+		if _, werr := out.WriteString(line); werr != nil {
+			out.Close()
+			return "", werr
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			out.Close()
+			return "", err
+		}
+	}
+	return name, out.Close()
+}
+
+// previewTables builds a table per input file from the sheet named after
+// it, followed by tables for sheets that match no file. The first row of a
+// sheet is its header.
+func previewTables(names []string, sheets []xlsx.Sheet, rejects [][]string, files []model.FileResult) []model.PreviewTable {
+	bySheet := make(map[string]int, len(sheets))
+	for i, s := range sheets {
+		bySheet[s.Name] = i
+	}
+	skipped := make(map[string]int, len(files))
+	for _, f := range files {
+		skipped[f.File] += f.SkippedLines
+	}
+	rejected := make(map[string][]model.SkippedLine)
+	for _, r := range rejects {
+		line, _ := strconv.Atoi(r[1])
+		rejected[r[0]] = append(rejected[r[0]], model.SkippedLine{Line: line, Reason: r[2], Text: r[3]})
+	}
+
+	matched := make(map[int]bool, len(names))
+	tables := make([]model.PreviewTable, 0, len(names))
+	for _, name := range names {
+		t := model.PreviewTable{File: name, Skipped: rejected[name], SkippedLines: skipped[name]}
+		if i, ok := bySheet[sheetName(name)]; ok && !matched[i] {
+			matched[i] = true
+			fillTable(&t, sheets[i])
+		}
+		tables = append(tables, t)
+	}
+	for i, s := range sheets {
+		if !matched[i] {
+			t := model.PreviewTable{}
+			fillTable(&t, s)
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// fillTable sets a table's columns and rows from a sheet and marks the
+// columns that have no value in any row.
+func fillTable(t *model.PreviewTable, s xlsx.Sheet) {
+	t.Sheet = s.Name
+	t.Columns = []string{}
+	t.Rows = [][]string{}
+	if len(s.Rows) == 0 {
+		return
+	}
+	t.Columns = s.Rows[0]
+	t.Rows = s.Rows[1:]
+	t.TotalRows = len(t.Rows)
+	if len(t.Rows) == 0 {
+		return
+	}
+	for col := range t.Columns {
+		empty := true
+		for _, row := range t.Rows {
+			if strings.TrimSpace(row[col]) != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			t.EmptyColumns = append(t.EmptyColumns, col)
+		}
+	}
+}
+
+// PageTable returns the given zero-based page of a table holding all its
+// rows, clamping the page to the available ones. A page size of 0 uses the
+// default.
+func PageTable(t model.PreviewTable, page int, pageSize int) model.PreviewTable {
+	if pageSize <= 0 {
+		pageSize = defaultPreviewPageSize
+	}
+	all := t.Rows
+	pages := max(1, (len(all)+pageSize-1)/pageSize)
+	page = min(max(page, 0), pages-1)
+	start := page * pageSize
+	t.Rows = all[start:min(start+pageSize, len(all))]
+	t.TotalRows = len(all)
+	t.Page = page
+	t.PageSize = pageSize
+	return t
+}
@@ -0,0 +1,206 @@
+package executor
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/pyenv"
+	"network-log-formatter/internal/xlsx"
+)
+
+// writeTestWorkbook writes a workbook whose sheets hold the given inline
+// string rows, with empty cells left out.
+func writeTestWorkbook(t *testing.T, file string, names []string, sheets [][][]string) {
+	t.Helper()
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	add := func(name string, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+
+	var list, rels strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&list, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		var data strings.Builder
+		for r, row := range sheets[i] {
+			fmt.Fprintf(&data, `<row r="%d">`, r+1)
+			for c, v := range row {
+				if v != "" {
+					fmt.Fprintf(&data, `<c r="%c%d" t="inlineStr"><is><t>%s</t></is></c>`, 'A'+c, r+1, v)
+				}
+			}
+			data.WriteString(`</row>`)
+		}
+		add(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+data.String()+`</sheetData></worksheet>`)
+	}
+	add("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+list.String()+`</sheets></workbook>`)
+	add("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Unit test: a preview runs on the first lines of each selected file,
+// decompressed, and returns the rows per file with empty columns and rejected
+// lines, while the input stays untouched
+func TestPreview_ReadsRowsPerFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake python needs a POSIX shell")
+	}
+	// The workbook lives in the environment so a sandboxed script can read it
+	envPath := t.TempDir()
+	os.MkdirAll(filepath.Join(envPath, "bin"), 0755)
+	workbook := filepath.Join(envPath, "rows.xlsx")
+	writeTestWorkbook(t, workbook, []string{"a.log", "b.log", "Summary"}, [][][]string{
+		{{"ip", "note", "status"}, {"1.1.1.1", "", "200"}, {"2.2.2.2", "", "404"}},
+		{{"ip", "note", "status"}, {"3.3.3.3", "x", "500"}},
+		{{"total"}, {"3"}},
+	})
+
+	// The script fails unless it sees exactly three lines per file
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte(fmt.Sprintf(`#!/bin/sh
+for f in "$3"/*; do [ "$(wc -l < "$f")" -eq 3 ] || exit 1; done
+echo "saw" $(ls "$3")
+cp %q "$5/$7.xlsx"
+echo '{"v":1,"event":"reject","file":"a.log","line":2,"reason":"no ip","text":"garbage"}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok","rows":2,"skipped":1}'
+`, workbook)), 0755)
+	be := NewBatchExecutor(pyenv.NewPythonEnvManager("uv", envPath), nil, 0)
+
+	inputDir := t.TempDir()
+	content := strings.Repeat("line\n", 200)
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte(content), 0644)
+	gz, _ := os.Create(filepath.Join(inputDir, "b.log.gz"))
+	zw := gzip.NewWriter(gz)
+	zw.Write([]byte(content))
+	zw.Close()
+	gz.Close()
+
+	preview, err := be.Preview(context.Background(), "pass", model.PreviewParams{InputDir: inputDir, Lines: 3}, model.BatchParams{}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if len(preview.Tables) != 3 {
+		t.Fatalf("expected tables for 2 files and 1 extra sheet, got %+v", preview.Tables)
+	}
+	a, b, extra := preview.Tables[0], preview.Tables[1], preview.Tables[2]
+	if a.File != "a.log" || a.TotalRows != 2 || !reflect.DeepEqual(a.EmptyColumns, []int{1}) {
+		t.Errorf("unexpected table for a.log: %+v", a)
+	}
+	if len(a.Skipped) != 1 || a.Skipped[0] != (model.SkippedLine{Line: 2, Reason: "no ip", Text: "garbage"}) || a.SkippedLines != 1 {
+		t.Errorf("expected the rejected line of a.log, got %+v", a)
+	}
+	if b.File != "b.log" || b.TotalRows != 1 || len(b.EmptyColumns) != 0 {
+		t.Errorf("unexpected table for b.log.gz: %+v", b)
+	}
+	if extra.File != "" || extra.Sheet != "Summary" {
+		t.Errorf("expected a table for the unmatched sheet, got %+v", extra)
+	}
+	if data, _ := os.ReadFile(filepath.Join(inputDir, "a.log")); string(data) != content {
+		t.Errorf("expected the input to be unchanged")
+	}
+
+	// Selecting a file previews only that file
+	preview, err = be.Preview(context.Background(), "pass", model.PreviewParams{InputDir: inputDir, Files: []string{"b.log.gz"}, Lines: 3}, model.BatchParams{}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	saw := ""
+	for _, e := range preview.Log {
+		if strings.HasPrefix(e.Message, "saw ") {
+			saw = e.Message
+		}
+	}
+	if saw != "saw b.log" {
+		t.Errorf("expected the script to see only b.log, got %+v", preview.Log)
+	}
+}
+
+// Unit test: a preview writes its rows with the time zones of the run it
+// previews, and a preview without them keeps the times as streamed
+func TestPreview_TimeZones(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["time","msg"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["2024-05-01 10:00:00","up"]}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+`)
+	inputDir := t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	params := model.PreviewParams{InputDir: inputDir}
+
+	run := model.BatchParams{TimeZones: &model.TimeZones{Source: "UTC", Target: "+08:00", Format: "iso"}}
+	preview, err := be.Preview(context.Background(), "pass", params, run, nil)
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if len(preview.Tables) != 1 || !reflect.DeepEqual(preview.Tables[0].Rows, [][]string{{"2024-05-01T18:00:00+08:00", "up"}}) {
+		t.Fatalf("expected the time converted to +08:00, got %+v", preview.Tables)
+	}
+
+	preview, err = be.Preview(context.Background(), "pass", params, model.BatchParams{}, nil)
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if len(preview.Tables) != 1 || preview.Tables[0].Rows[0][0] != "2024-05-01 10:00:00" {
+		t.Errorf("expected the time as streamed, got %+v", preview.Tables)
+	}
+}
+
+// Unit test: a preview of an unknown file or a missing directory is an error
+func TestPreview_Errors(t *testing.T) {
+	be := NewBatchExecutor(nil, nil, 0)
+	inputDir := t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	if _, err := be.Preview(context.Background(), "pass", model.PreviewParams{InputDir: inputDir, Files: []string{"missing.log"}}, model.BatchParams{}, nil); err == nil {
+		t.Fatal("expected an error for a file that doesn't exist")
+	}
+	if _, err := be.Preview(context.Background(), "pass", model.PreviewParams{InputDir: filepath.Join(inputDir, "nope")}, model.BatchParams{}, nil); err == nil {
+		t.Fatal("expected an error for a missing input directory")
+	}
+}
+
+// Unit test: pages cut a table's rows and clamp out-of-range pages
+func TestPageTable(t *testing.T) {
+	table := model.PreviewTable{Columns: []string{"n"}}
+	for i := 0; i < 7; i++ {
+		table.Rows = append(table.Rows, []string{fmt.Sprint(i)})
+	}
+	page := PageTable(table, 1, 3)
+	if page.Page != 1 || page.TotalRows != 7 || !reflect.DeepEqual(page.Rows, [][]string{{"3"}, {"4"}, {"5"}}) {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	if page = PageTable(table, 9, 3); page.Page != 2 || len(page.Rows) != 1 {
+		t.Fatalf("expected the last page, got %+v", page)
+	}
+	if page = PageTable(model.PreviewTable{}, 0, 0); page.PageSize != defaultPreviewPageSize || len(page.Rows) != 0 {
+		t.Fatalf("unexpected page of an empty table: %+v", page)
+	}
+}
+
+// Unit test: tables mark empty columns only when there are rows to judge by
+func TestFillTable_EmptyColumns(t *testing.T) {
+	var table model.PreviewTable
+	fillTable(&table, xlsx.Sheet{Name: "a", Rows: [][]string{{"x", "y"}}})
+	if len(table.EmptyColumns) != 0 || table.TotalRows != 0 {
+		t.Fatalf("expected no empty columns without rows, got %+v", table)
+	}
+}
@@ -0,0 +1,271 @@
+package executor
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"network-log-formatter/internal/model"
+)
+
+// logforgeModule is the Python helper scripts use to speak the progress
+// protocol. It is written next to every script run as logforge.py.
+//
+//go:embed logforge.py
+var logforgeModule string
+
+// protocolVersion is the newest progress protocol version understood.
+const protocolVersion = 1
+
+// maxLogEntries bounds how many log entries a run keeps; older ones are
+// dropped first.
+const maxLogEntries = 500
+
+// maxLogLine bounds the length of a single log message.
+const maxLogLine = 2000
+
+// rowReportInterval is how many streamed rows pass between progress reports.
+const rowReportInterval = 1000
+
+// helperConfig tells the logforge module which outputs a run wants. It is
+// written next to the module as logforge.json.
+type helperConfig struct {
+	Excel bool             `json:"excel"`           // the script writes the workbook itself; runs leave it to Go
+	Rows  bool             `json:"rows"`            // logforge.row streams rows to Go
+	Zones *model.TimeZones `json:"zones,omitempty"` // zones Go normalizes the times of the rows with
+}
+
+// writeHelperModule places the logforge module and its configuration in dir,
+// next to the script.
+func writeHelperModule(dir string, cfg helperConfig) error {
+	if err := os.WriteFile(filepath.Join(dir, "logforge.py"), []byte(logforgeModule), 0644); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "logforge.json"), data, 0644)
+}
+
+// progressStream interprets the stdout of one script run. Lines carrying an
+// "event" follow the versioned protocol; other JSON objects with a "file" are
+// legacy progress lines; everything else is kept as log output.
+type progressStream struct {
+	tracker   *fileTracker
+	total     int
+	processed int
+	progress  float64
+	current   string
+	message   string
+	doneRows  int  // rows of finished files
+	fileRows  int  // rows reported so far for the current file
+	streamed  bool // the current file's rows were streamed
+	log       []model.LogEntry
+	rejects   *rejectWriter // quarantine file; nil only counts rejected lines
+	rows      *rowOutput    // destination of streamed rows; nil ignores them
+}
+
+// newProgressStream creates a stream recording file results in tracker and
+// rejected lines in rejects, which may be nil.
+func newProgressStream(tracker *fileTracker, rejects *rejectWriter) *progressStream {
+	return &progressStream{tracker: tracker, rejects: rejects}
+}
+
+// handle processes one non-empty stdout line and returns the progress to
+// report, or nil when there is nothing new to report.
+func (ps *progressStream) handle(line string, now time.Time) *model.BatchProgress {
+	var ev model.ProgressEvent
+	if err := json.Unmarshal([]byte(line), &ev); err != nil {
+		ps.addLog(model.LogEntry{Level: "info", Message: line})
+		return ps.snapshot(truncateLog(line))
+	}
+	if ev.Event == "" {
+		return ps.handleLegacy(line, now)
+	}
+	if ev.V > protocolVersion {
+		ps.addLog(model.LogEntry{Level: "warning", Message: fmt.Sprintf("script uses progress protocol v%d, newer than v%d", ev.V, protocolVersion)})
+	}
+
+	switch ev.Event {
+	case "start":
+		ps.total = ev.Total
+		return ps.snapshot(fmt.Sprintf("Processing %d file(s)", ev.Total))
+	case "file_start":
+		if ev.Total > 0 {
+			ps.total = ev.Total
+		}
+		ps.current = ev.File
+		ps.fileRows = 0
+		ps.streamed = false
+		ps.tracker.begin(ev.File, now)
+		if ps.rows != nil {
+			ps.rows.begin(ev.File)
+		}
+		return ps.snapshot(fmt.Sprintf("Processing: %s", ev.File))
+	case "columns":
+		if ps.rows != nil {
+			if err := ps.rows.start(ev.File, ev.Columns); err != nil {
+				ps.addLog(model.LogEntry{Level: "warning", File: ev.File, Message: err.Error()})
+			}
+		}
+		return nil
+	case "row":
+		if ps.rows != nil {
+			if err := ps.rows.row(ev.File, ev.Values); err != nil {
+				ps.addLog(model.LogEntry{Level: "warning", File: ev.File, Message: err.Error()})
+			}
+		}
+		ps.fileRows++
+		ps.streamed = true
+		if ps.fileRows%rowReportInterval != 0 {
+			return nil
+		}
+		return ps.snapshot(ps.message)
+	case "rows":
+		if ev.Rows != nil {
+			ps.fileRows = *ev.Rows
+		}
+		return ps.snapshot(ps.message)
+	case "warning":
+		ps.tracker.warn(ev.File)
+		ps.addLog(model.LogEntry{Level: "warning", File: ev.File, Line: ev.Line, Message: ev.Message})
+		return ps.snapshot(fmt.Sprintf("Warning: %s", formatLogEntry(ps.log[len(ps.log)-1])))
+	case "reject":
+		ps.tracker.reject(ev.File)
+		if ps.rejects != nil {
+			ps.rejects.write(ev.File, ev.Line, ev.Reason, ev.Text)
+		}
+		return ps.snapshot(ps.message)
+	case "log":
+		level := ev.Level
+		if level == "" {
+			level = "info"
+		}
+		ps.addLog(model.LogEntry{Level: level, Message: ev.Message})
+		return ps.snapshot(truncateLog(ev.Message))
+	case "file_done":
+		if ps.rows != nil {
+			ps.rows.end(ev.File)
+		}
+		if ev.Rows == nil && ps.streamed {
+			// Streamed rows count when the script doesn't give a total
+			rows := ps.fileRows
+			ev.Rows = &rows
+		}
+		ps.tracker.record(model.ProgressInfo{
+			File:    ev.File,
+			Status:  ev.Status,
+			Rows:    ev.Rows,
+			Skipped: ev.Skipped,
+			Error:   ev.Error,
+		}, now)
+		if ev.Rows != nil {
+			ps.doneRows += *ev.Rows
+		} else {
+			ps.doneRows += ps.fileRows
+		}
+		ps.fileRows = 0
+		ps.streamed = false
+		ps.processed = len(ps.tracker.files)
+		if ps.total > 0 {
+			ps.progress = float64(ps.processed) / float64(ps.total)
+		}
+		if ev.Error != "" {
+			ps.addLog(model.LogEntry{Level: "error", File: ev.File, Message: ev.Error})
+			return ps.snapshot(fmt.Sprintf("Failed: %s: %s", ev.File, truncateLog(ev.Error)))
+		}
+		return ps.snapshot(fmt.Sprintf("Finished: %s", ev.File))
+	case "done":
+		return ps.snapshot(fmt.Sprintf("Script finished: %d file(s), %d row(s)", ps.processed, ps.doneRows))
+	}
+
+	ps.addLog(model.LogEntry{Level: "warning", Message: fmt.Sprintf("unknown progress event %q", ev.Event)})
+	return ps.snapshot(ps.message)
+}
+
+// handleLegacy processes a progress line of scripts generated before the
+// event protocol: one line per finished file.
+func (ps *progressStream) handleLegacy(line string, now time.Time) *model.BatchProgress {
+	var info model.ProgressInfo
+	if err := json.Unmarshal([]byte(line), &info); err != nil || info.File == "" {
+		ps.addLog(model.LogEntry{Level: "info", Message: line})
+		return ps.snapshot(truncateLog(line))
+	}
+	ps.tracker.record(info, now)
+	ps.total = info.Total
+	ps.processed = info.Current
+	ps.progress = info.Progress
+	ps.current = info.File
+	if info.Rows != nil {
+		ps.doneRows += *info.Rows
+	}
+	return ps.snapshot(fmt.Sprintf("Processing: %s", info.File))
+}
+
+// snapshot returns the current progress with the given message.
+func (ps *progressStream) snapshot(message string) *model.BatchProgress {
+	ps.message = message
+	files := ps.tracker.results()
+	_, failed := countFiles(files)
+	return &model.BatchProgress{
+		Status:      "running",
+		CurrentFile: ps.current,
+		Progress:    ps.progress,
+		TotalFiles:  ps.total,
+		Processed:   ps.processed,
+		Failed:      failed,
+		Message:     message,
+		Rows:        ps.doneRows + ps.fileRows,
+		Files:       files,
+	}
+}
+
+// fill copies the stream's totals, file results and log into result.
+func (ps *progressStream) fill(result *model.BatchResult) {
+	files := ps.tracker.results()
+	result.TotalFiles = ps.total
+	if result.TotalFiles < len(files) {
+		result.TotalFiles = len(files)
+	}
+	result.Succeeded, result.Failed = countFiles(files)
+	result.Files = files
+	result.Log = append([]model.LogEntry(nil), ps.log...)
+}
+
+// addLog appends an entry, dropping the oldest once maxLogEntries is reached.
+func (ps *progressStream) addLog(e model.LogEntry) {
+	e.Message = truncateLog(e.Message)
+	if len(ps.log) >= maxLogEntries {
+		ps.log = append(ps.log[:0], ps.log[1:]...)
+	}
+	ps.log = append(ps.log, e)
+}
+
+// formatLogEntry renders an entry as "file:line: message".
+func formatLogEntry(e model.LogEntry) string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return e.Message
+}
+
+// truncateLog shortens s to at most maxLogLine bytes without splitting a
+// UTF-8 character.
+func truncateLog(s string) string {
+	if len(s) <= maxLogLine {
+		return s
+	}
+	cut := maxLogLine
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,191 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 20: 非 JSON 输出不丢失
+// For any script output mixing progress events and plain text, every plain
+// line is kept in the run log in order, and every finished file has a result.
+func TestProperty20_PlainOutputIsKept(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		stream := newProgressStream(newFileTracker("", time.Now()), nil)
+		count := rapid.IntRange(0, 40).Draw(rt, "lines")
+
+		var plain []string
+		done := make(map[string]bool)
+		for i := 0; i < count; i++ {
+			if rapid.Bool().Draw(rt, "event") {
+				file := fmt.Sprintf("%02d.log", rapid.IntRange(0, 5).Draw(rt, "file"))
+				stream.handle(fmt.Sprintf(`{"v": 1, "event": "file_done", "file": %q, "status": "ok", "rows": 1}`, file), time.Now())
+				done[file] = true
+				continue
+			}
+			line := rapid.StringMatching(`[a-zA-Z][a-zA-Z0-9 :=]{0,40}`).Draw(rt, "plain")
+			stream.handle(line, time.Now())
+			plain = append(plain, line)
+		}
+
+		result := &model.BatchResult{}
+		stream.fill(result)
+		if len(result.Log) != len(plain) {
+			rt.Fatalf("expected %d log entries, got %d", len(plain), len(result.Log))
+		}
+		for i, e := range result.Log {
+			if e.Message != plain[i] {
+				rt.Fatalf("log entry %d is %q, want %q", i, e.Message, plain[i])
+			}
+		}
+		if len(result.Files) != len(done) || result.Succeeded != len(done) {
+			rt.Fatalf("expected %d file results, got %d (%d succeeded)", len(done), len(result.Files), result.Succeeded)
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: a full event sequence produces progress, file results and log
+func TestProgressStream_Events(t *testing.T) {
+	start := time.Now()
+	stream := newProgressStream(newFileTracker(t.TempDir(), start), nil)
+
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	stream.handle(`{"v": 1, "event": "start", "total": 2}`, at(0))
+	stream.handle(`{"v": 1, "event": "file_start", "file": "a.log", "index": 1, "total": 2}`, at(100))
+	p := stream.handle(`{"v": 1, "event": "rows", "file": "a.log", "rows": 500}`, at(200))
+	if p.Rows != 500 || p.CurrentFile != "a.log" || p.Message != "Processing: a.log" {
+		t.Fatalf("unexpected progress after rows event: %+v", p)
+	}
+	p = stream.handle(`{"v": 1, "event": "warning", "file": "a.log", "line": 7, "message": "bad timestamp"}`, at(250))
+	if p.Message != "Warning: a.log:7: bad timestamp" {
+		t.Fatalf("unexpected warning message %q", p.Message)
+	}
+	p = stream.handle(`{"v": 1, "event": "file_done", "file": "a.log", "status": "ok", "rows": 800, "skipped": 1}`, at(400))
+	if p.Processed != 1 || p.Progress != 0.5 || p.Rows != 800 {
+		t.Fatalf("unexpected progress after file_done: %+v", p)
+	}
+	stream.handle(`{"v": 1, "event": "file_start", "file": "b.log"}`, at(500))
+	stream.handle(`{"v": 1, "event": "file_done", "file": "b.log", "status": "error", "rows": 0, "error": "bad header"}`, at(600))
+	stream.handle(`{"v": 1, "event": "log", "level": "info", "message": "writing workbook"}`, at(650))
+	p = stream.handle(`{"v": 1, "event": "done", "files": 2, "rows": 800}`, at(700))
+	if p.Progress != 1 || p.Failed != 1 {
+		t.Fatalf("unexpected final progress: %+v", p)
+	}
+
+	result := &model.BatchResult{}
+	stream.fill(result)
+	if result.TotalFiles != 2 || result.Succeeded != 1 || result.Failed != 1 {
+		t.Fatalf("unexpected totals: %+v", result)
+	}
+	a := result.Files[0]
+	if a.DurationMs != 300 || a.Warnings != 1 || a.SkippedLines != 1 || *a.Rows != 800 {
+		t.Fatalf("unexpected result for a.log: %+v", a)
+	}
+	if b := result.Files[1]; b.Status != "failed" || b.Error != "bad header" {
+		t.Fatalf("unexpected result for b.log: %+v", b)
+	}
+	levels := ""
+	for _, e := range result.Log {
+		levels += e.Level[:1]
+	}
+	if levels != "wei" {
+		t.Fatalf("expected warning, error and info entries, got %q (%+v)", levels, result.Log)
+	}
+}
+
+// Unit test: unknown events and newer protocol versions are logged, not fatal
+func TestProgressStream_UnknownEvent(t *testing.T) {
+	stream := newProgressStream(newFileTracker("", time.Now()), nil)
+	stream.handle(`{"v": 2, "event": "sheet_done", "sheet": "a"}`, time.Now())
+
+	result := &model.BatchResult{}
+	stream.fill(result)
+	if len(result.Log) != 2 || result.Log[0].Level != "warning" || result.Log[1].Level != "warning" {
+		t.Fatalf("expected version and unknown event warnings, got %+v", result.Log)
+	}
+}
+
+// Unit test: the log keeps only the most recent entries
+func TestProgressStream_LogIsBounded(t *testing.T) {
+	stream := newProgressStream(newFileTracker("", time.Now()), nil)
+	for i := 0; i < maxLogEntries+10; i++ {
+		stream.handle(fmt.Sprintf("line %d", i), time.Now())
+	}
+	result := &model.BatchResult{}
+	stream.fill(result)
+	if len(result.Log) != maxLogEntries || result.Log[0].Message != "line 10" {
+		t.Fatalf("expected the last %d entries, got %d starting with %q", maxLogEntries, len(result.Log), result.Log[0].Message)
+	}
+}
+
+// Unit test: lines longer than bufio.Scanner's default limit don't stop
+// reading
+func TestReadStdout_LongLines(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	stdout := strings.NewReader(long + "\n" + `{"file": "a.log", "progress": 1.0, "total": 1, "current": 1}` + "\n")
+
+	be := NewBatchExecutor(nil, nil, 0)
+	result := &model.BatchResult{}
+	be.readStdout(stdout, result, newProgressStream(newFileTracker("", time.Now()), nil), func(p *model.BatchProgress) {})
+
+	if result.Succeeded != 1 {
+		t.Fatalf("expected the progress line after the long line to count, got %+v", result)
+	}
+	if len(result.Log) != 1 || len(result.Log[0].Message) > maxLogLine+3 {
+		t.Fatalf("expected one truncated log entry, got %d", len(result.Log))
+	}
+}
+
+// Unit test: the embedded logforge module speaks the protocol the stream reads
+func TestLogforgeModule_RoundTrip(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available, skipping helper module test")
+	}
+	dir := t.TempDir()
+	if err := writeHelperModule(dir, helperConfig{Excel: true, Rows: true}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	script := filepath.Join(dir, "script.py")
+	os.WriteFile(script, []byte(strings.Join([]string{
+		"import logforge",
+		"logforge.start(2)",
+		"logforge.file_start('a.log', index=1, total=2)",
+		"logforge.warning('bad line', file='a.log', line=3)",
+		"logforge.reject('a.log', line=4, reason='no timestamp', text='garbage\\n')",
+		"logforge.file_done('a.log', rows=10, skipped=1)",
+		"print('plain output')",
+		"logforge.file_start('b.log', index=2, total=2)",
+		"logforge.file_done('b.log', error=ValueError('boom'))",
+		"logforge.done(files=2, rows=10)",
+	}, "\n")), 0644)
+
+	out, err := exec.Command(python, script).Output()
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+
+	be := NewBatchExecutor(nil, nil, 0)
+	result := &model.BatchResult{}
+	be.readStdout(bytes.NewReader(out), result, newProgressStream(newFileTracker(dir, time.Now()), nil), func(p *model.BatchProgress) {})
+	if result.TotalFiles != 2 || result.Succeeded != 1 || result.Failed != 1 {
+		t.Fatalf("unexpected totals: %+v", result)
+	}
+	if result.Files[0].Warnings != 1 || result.Files[0].Rejected != 1 || result.Files[1].Error != "boom" {
+		t.Fatalf("unexpected file results: %+v", result.Files)
+	}
+	if len(result.Log) != 3 || result.Log[1].Message != "plain output" {
+		t.Fatalf("unexpected log: %+v", result.Log)
+	}
+}
@@ -0,0 +1,247 @@
+package executor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+)
+
+// minHealthyCoverage is the share of lines, in percent, a run must parse to
+// count as completed. Runs below it finish as "degraded".
+const minHealthyCoverage = 90.0
+
+// utf8BOM makes Excel open the quarantine CSV as UTF-8.
+const utf8BOM = "\xef\xbb\xbf"
+
+// rejectsHeader is the header row of a quarantine file.
+var rejectsHeader = []string{"file", "line", "reason", "text"}
+
+// rejectsPath returns the quarantine file kept next to the workbook a run
+// with the given output directory and name writes.
+func rejectsPath(outputDir string, outputFileName string) string {
+	if outputFileName == "" {
+		outputFileName = "result"
+	}
+	return filepath.Join(outputDir, outputFileName+".rejected.csv")
+}
+
+// rejectWriter appends rejected lines to a quarantine file, creating it on
+// the first rejection so runs without rejections leave no file behind.
+type rejectWriter struct {
+	path  string
+	file  *os.File
+	csv   *csv.Writer
+	count int
+	err   error
+}
+
+// newRejectWriter creates a writer for the quarantine file at path.
+func newRejectWriter(path string) *rejectWriter {
+	return &rejectWriter{path: path}
+}
+
+// write records one rejected line. After the first error it does nothing and
+// the error is kept for Close.
+func (rw *rejectWriter) write(file string, line int, reason string, text string) {
+	if rw.err != nil {
+		return
+	}
+	if rw.file == nil {
+		rw.file, rw.err = os.Create(rw.path)
+		if rw.err != nil {
+			return
+		}
+		rw.file.WriteString(utf8BOM)
+		rw.csv = csv.NewWriter(rw.file)
+		rw.csv.Write(rejectsHeader)
+	}
+	lineField := ""
+	if line > 0 {
+		lineField = strconv.Itoa(line)
+	}
+	rw.err = rw.csv.Write([]string{file, lineField, reason, text})
+	rw.count++
+}
+
+// close flushes the quarantine file. Without rejections it removes any file
+// a previous run left at the path, so the sidecar always matches the
+// workbook.
+func (rw *rejectWriter) close() error {
+	if rw.file == nil {
+		if rw.err != nil {
+			return rw.err
+		}
+		if err := os.Remove(rw.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	rw.csv.Flush()
+	if err := rw.csv.Error(); err != nil && rw.err == nil {
+		rw.err = err
+	}
+	if err := rw.file.Close(); err != nil && rw.err == nil {
+		rw.err = err
+	}
+	return rw.err
+}
+
+// mergeRejects rebuilds the quarantine file dst from the quarantine files of
+// partial runs. With base set, rows of base are kept unless their file was
+// reprocessed (replaced), following the sheet renames of rotated logs. dst is
+// removed when no rows remain. It returns the number of rows written.
+func mergeRejects(dst string, base string, parts []string, replaced map[string]bool, renames map[string]string) (int, error) {
+	var rows [][]string
+	if base != "" {
+		existing, err := readRejects(base)
+		if err != nil {
+			return 0, err
+		}
+		for _, row := range existing {
+			// A rotated file's rows follow its sheet; the file now at the
+			// old name is new and brings its own rows
+			if to, ok := renames[sheetName(row[0])]; ok {
+				row[0] = to
+			} else if replaced[row[0]] {
+				continue
+			}
+			rows = append(rows, row)
+		}
+	}
+	for _, part := range parts {
+		partRows, err := readRejects(part)
+		if err != nil {
+			return 0, err
+		}
+		rows = append(rows, partRows...)
+	}
+
+	if len(rows) == 0 {
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	f, err := output.CreateTemp(dst)
+	if err != nil {
+		return 0, err
+	}
+	tmp := f.Name()
+	f.WriteString(utf8BOM)
+	w := csv.NewWriter(f)
+	w.Write(rejectsHeader)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return len(rows), os.Rename(tmp, dst)
+}
+
+// readRejects returns the data rows of a quarantine file; a missing file has
+// none.
+func readRejects(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	bom := make([]byte, len(utf8BOM))
+	if n, _ := io.ReadFull(f, bom); n < len(bom) || string(bom) != utf8BOM {
+		f.Seek(0, io.SeekStart)
+	}
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(rejectsHeader)
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if len(records) > 0 {
+		records = records[1:]
+	}
+	return records, nil
+}
+
+// coverage returns the percentage of a file's lines that were parsed into
+// rows, or nil when the script doesn't report rows or the file had no lines.
+func coverage(rows *int, skipped int) *float64 {
+	if rows == nil || *rows+skipped == 0 {
+		return nil
+	}
+	c := roundCoverage(float64(*rows) / float64(*rows+skipped) * 100)
+	return &c
+}
+
+// overallCoverage returns the coverage over all files that report rows.
+func overallCoverage(files []model.FileResult) *float64 {
+	var rows, skipped int
+	reported := false
+	for _, f := range files {
+		if f.Rows == nil {
+			continue
+		}
+		reported = true
+		rows += *f.Rows
+		skipped += f.SkippedLines
+	}
+	if !reported {
+		return nil
+	}
+	return coverage(&rows, skipped)
+}
+
+// roundCoverage rounds a percentage to one decimal.
+func roundCoverage(c float64) float64 {
+	return math.Round(c*10) / 10
+}
+
+// finishStatus sets the run's overall coverage on result and returns the
+// status a successful run ends with: "degraded" when it parsed less than
+// minHealthyCoverage of the lines or its rows fall short of the quality
+// thresholds, "completed" otherwise.
+func finishStatus(result *model.BatchResult) string {
+	result.Coverage = overallCoverage(result.Files)
+	result.Degraded = lowCoverage(result) || (result.Quality != nil && len(result.Quality.Violations) > 0)
+	if result.Degraded {
+		return "degraded"
+	}
+	return "completed"
+}
+
+// lowCoverage reports whether the run parsed less than minHealthyCoverage
+// of the lines.
+func lowCoverage(result *model.BatchResult) bool {
+	return result.Coverage != nil && *result.Coverage < minHealthyCoverage
+}
+
+// finishMessage describes a successful run for its final progress update.
+func finishMessage(result *model.BatchResult) string {
+	if !result.Degraded {
+		return "Batch processing completed"
+	}
+	if !lowCoverage(result) {
+		return "Batch processing completed, but " + qualityMessage(result.Quality.Violations)
+	}
+	msg := fmt.Sprintf("Batch processing completed, but only %.1f%% of lines were parsed", *result.Coverage)
+	if result.RejectedPath != "" {
+		msg += fmt.Sprintf("; rejected lines are in %s", filepath.Base(result.RejectedPath))
+	}
+	return msg
+}
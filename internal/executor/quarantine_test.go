@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 21: 被拒绝的行全部隔离
+// For any sequence of reject events, the quarantine file holds every rejected
+// line in order, and each file counts at least its rejected lines as skipped.
+func TestProperty21_RejectedLinesAreQuarantined(t *testing.T) {
+	dir := t.TempDir()
+	rapid.Check(t, func(rt *rapid.T) {
+		path := filepath.Join(dir, "result.rejected.csv")
+		rejects := newRejectWriter(path)
+		stream := newProgressStream(newFileTracker("", time.Now()), rejects)
+
+		var want [][]string
+		perFile := make(map[string]int)
+		count := rapid.IntRange(0, 30).Draw(rt, "rejects")
+		for i := 0; i < count; i++ {
+			file := fmt.Sprintf("%02d.log", rapid.IntRange(0, 3).Draw(rt, "file"))
+			line := rapid.IntRange(1, 10000).Draw(rt, "line")
+			text := rapid.StringMatching(`[a-z0-9 ,"]{0,30}`).Draw(rt, "text")
+			stream.handle(fmt.Sprintf(`{"v": 1, "event": "reject", "file": %q, "line": %d, "reason": "bad", "text": %q}`, file, line, text), time.Now())
+			want = append(want, []string{file, fmt.Sprint(line), "bad", text})
+			perFile[file]++
+		}
+		skipped := rapid.IntRange(0, 5).Draw(rt, "skipped")
+		for file := range perFile {
+			stream.handle(fmt.Sprintf(`{"v": 1, "event": "file_done", "file": %q, "rows": 10, "skipped": %d}`, file, skipped), time.Now())
+		}
+		if err := rejects.close(); err != nil {
+			rt.Fatalf("close failed: %v", err)
+		}
+
+		got, err := readRejects(path)
+		if err != nil {
+			rt.Fatalf("read failed: %v", err)
+		}
+		if len(got) != len(want) {
+			rt.Fatalf("expected %d rejected lines, got %d", len(want), len(got))
+		}
+		for i := range want {
+			if strings.Join(got[i], "|") != strings.Join(want[i], "|") {
+				rt.Fatalf("row %d is %v, want %v", i, got[i], want[i])
+			}
+		}
+		if count == 0 {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				rt.Fatalf("expected no quarantine file without rejections")
+			}
+		}
+
+		result := &model.BatchResult{}
+		stream.fill(result)
+		for _, f := range result.Files {
+			if f.Rejected != perFile[f.File] || f.SkippedLines < f.Rejected {
+				rt.Fatalf("unexpected counts for %s: %+v", f.File, f)
+			}
+			if f.Coverage == nil || *f.Coverage < 0 || *f.Coverage > 100 {
+				rt.Fatalf("coverage out of range for %s: %v", f.File, f.Coverage)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: a run that parses too few lines finishes degraded
+func TestFinishStatus(t *testing.T) {
+	rows := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		files    []model.FileResult
+		status   string
+		coverage string
+	}{
+		{"no files", nil, "completed", "<nil>"},
+		{"rows not reported", []model.FileResult{{File: "a.log", SkippedLines: 50}}, "completed", "<nil>"},
+		{"healthy", []model.FileResult{{File: "a.log", Rows: rows(95), SkippedLines: 5}}, "completed", "95.0"},
+		{"degraded", []model.FileResult{
+			{File: "a.log", Rows: rows(100)},
+			{File: "b.log", Rows: rows(0), SkippedLines: 20},
+		}, "degraded", "83.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.BatchResult{Files: tt.files}
+			if got := finishStatus(result); got != tt.status {
+				t.Errorf("expected %q, got %q", tt.status, got)
+			}
+			coverage := "<nil>"
+			if result.Coverage != nil {
+				coverage = fmt.Sprintf("%.1f", *result.Coverage)
+			}
+			if coverage != tt.coverage || result.Degraded != (tt.status == "degraded") {
+				t.Errorf("unexpected coverage %s (degraded %v)", coverage, result.Degraded)
+			}
+		})
+	}
+}
+
+// Unit test: a run without rejections removes the quarantine file of the
+// previous run
+func TestRejectWriter_RemovesStaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.rejected.csv")
+	os.WriteFile(path, []byte("stale"), 0644)
+
+	if err := newRejectWriter(path).close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale quarantine file to be removed")
+	}
+}
+
+// Unit test: rejections reported after file_done still count for the file
+func TestFileTracker_LateReject(t *testing.T) {
+	ft := newFileTracker("", time.Now())
+	rows := 3
+	ft.record(model.ProgressInfo{File: "a.log", Rows: &rows}, time.Now())
+	ft.reject("a.log")
+
+	r := ft.results()[0]
+	if r.Rejected != 1 || r.SkippedLines != 1 || *r.Coverage != 75 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
+
+// Unit test: merging an incremental run replaces the rows of reprocessed
+// files and moves rows of rotated files to their new name
+func TestMergeRejects_Incremental(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "result.rejected.csv")
+	part := filepath.Join(dir, "part.rejected.csv")
+
+	w := newRejectWriter(dst)
+	w.write("app.log", 1, "bad", "old app")
+	w.write("db.log", 2, "bad", "old db")
+	w.write("web.log", 3, "bad", "old web")
+	if err := w.close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	w = newRejectWriter(part)
+	w.write("app.log", 9, "bad", "new app")
+	w.write("db.log", 8, "bad", "new db")
+	if err := w.close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	replaced := map[string]bool{"app.log": true, "db.log": true}
+	renames := map[string]string{"app.log": "app.log.1"}
+	n, err := mergeRejects(dst, dst, []string{part}, replaced, renames)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	rows, _ := readRejects(dst)
+	var got []string
+	for _, r := range rows {
+		got = append(got, r[0]+"="+r[3])
+	}
+	want := "app.log.1=old app,web.log=old web,app.log=new app,db.log=new db"
+	if n != 4 || strings.Join(got, ",") != want {
+		t.Fatalf("expected %s, got %d rows: %s", want, n, strings.Join(got, ","))
+	}
+}
+
+// Unit test: merging without any rejected rows removes the quarantine file
+func TestMergeRejects_Empty(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "result.rejected.csv")
+	w := newRejectWriter(dst)
+	w.write("app.log", 1, "bad", "old")
+	w.close()
+
+	n, err := mergeRejects(dst, dst, []string{filepath.Join(dir, "missing.csv")}, map[string]bool{"app.log": true}, nil)
+	if err != nil || n != 0 {
+		t.Fatalf("expected an empty merge, got %d, %v", n, err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("expected the quarantine file to be removed")
+	}
+}
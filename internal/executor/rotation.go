@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"network-log-formatter/internal/archive"
+	"network-log-formatter/internal/model"
+)
+
+// rotatedNamePattern matches names logrotate gives to rotated files:
+// numbered ("app.log.1") or dated ("app.log-20250101"), optionally compressed.
+var rotatedNamePattern = regexp.MustCompile(`^.+(\.\d+|-\d{8}(\d{2})?)(\.gz|\.bz2|\.xz)?$`)
+
+// detectRotations recognises logrotate activity between two runs so the
+// output workbook follows it instead of duplicating or losing data:
+//
+//   - A rotated file ("app.log.1", "app.log.2.gz") whose content matches a
+//     recorded file that has since changed or disappeared was produced by a
+//     rename, copytruncate or compression. The recorded file's sheet is
+//     renamed after the rotated file and the rotated file is not reprocessed.
+//   - A recorded file that shrank without a rotated copy was truncated in
+//     place. Its old sheet is kept under a time-stamped name and the file is
+//     reprocessed into a fresh sheet.
+func detectRotations(prev *model.FileManifest, records []model.FileRecord, plan *incrementalPlan, now time.Time) {
+	current := make(map[string]model.FileRecord, len(records))
+	for _, r := range records {
+		current[r.Path] = r
+	}
+
+	recorded := make([]model.FileRecord, 0, len(prev.Files))
+	for _, r := range prev.Files {
+		recorded = append(recorded, r)
+	}
+	sort.Slice(recorded, func(i, j int) bool {
+		return recorded[i].Path < recorded[j].Path
+	})
+
+	claimed := make(map[string]bool)
+	var remaining []model.FileRecord
+	for _, r := range plan.Changed {
+		source, ok := rotationSource(r, recorded, current, claimed)
+		if !ok {
+			remaining = append(remaining, r)
+			continue
+		}
+		claimed[source.Path] = true
+		from := recordName(source)
+		plan.Renames[rotatedSheetName(from)] = rotatedSheetName(recordName(r))
+		plan.Moved[source.Path] = r.Path
+		plan.Skipped = append(plan.Skipped, model.SkippedFile{
+			File:   recordName(r),
+			Reason: fmt.Sprintf("rotated from %s (sheet renamed)", from),
+		})
+	}
+	plan.Changed = remaining
+
+	for _, r := range plan.Changed {
+		old, ok := prev.Files[r.Path]
+		if ok && r.Size < old.Size && !claimed[r.Path] {
+			name := sheetName(recordName(r))
+			plan.Renames[name] = preservedSheetName(name, now)
+		}
+	}
+}
+
+// rotationSource finds the recorded file a rotated file was produced from:
+// one with the same content, raw or compressed, that is no longer in the
+// input directory in that form.
+func rotationSource(r model.FileRecord, recorded []model.FileRecord, current map[string]model.FileRecord, claimed map[string]bool) (model.FileRecord, bool) {
+	if !rotatedNamePattern.MatchString(filepath.Base(r.Path)) {
+		return model.FileRecord{}, false
+	}
+	if _, known := current[r.Path]; !known {
+		return model.FileRecord{}, false
+	}
+
+	plainHash := ""
+	if isCompressed(r.Path) {
+		plainHash, _ = hashDecompressed(r.Path)
+	}
+
+	for _, old := range recorded {
+		if old.Path == r.Path || claimed[old.Path] || filepath.Dir(old.Path) != filepath.Dir(r.Path) {
+			continue
+		}
+		if old.Hash != r.Hash && (plainHash == "" || old.Hash != plainHash) {
+			continue
+		}
+		if cur, exists := current[old.Path]; exists && cur.Hash == old.Hash {
+			// The original is still there unchanged, so this is a copy
+			continue
+		}
+		return old, true
+	}
+	return model.FileRecord{}, false
+}
+
+// preservedSheetName returns the name under which the sheet of a file
+// truncated in place is kept, e.g. "app.log 1018-140502".
+func preservedSheetName(name string, t time.Time) string {
+	suffix := " " + t.Format("0102-150405")
+	r := []rune(name)
+	if limit := maxSheetNameLen - len(suffix); len(r) > limit {
+		r = r[:limit]
+	}
+	return string(r) + suffix
+}
+
+// rotatedSheetName returns the sheet name of a possibly compressed log file:
+// compressed files are expanded before processing, so their sheet is named
+// after the decompressed file.
+func rotatedSheetName(name string) string {
+	return sheetName(archive.TrimExt(name))
+}
+
+// isCompressed reports whether the file name has a compression extension
+// logrotate uses.
+func isCompressed(path string) bool {
+	return archive.TrimExt(filepath.Base(path)) != filepath.Base(path)
+}
+
+// hashDecompressed returns the hex SHA-256 of a compressed file's content.
+func hashDecompressed(path string) (string, error) {
+	rc, _, err := archive.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// --- Unit Tests ---
+
+// rotationFixture records the files of dir as the manifest of a previous run.
+func rotationFixture(t *testing.T, dir string) *model.FileManifest {
+	t.Helper()
+	files, _ := listInputFiles(dir, nil)
+	records, err := scanFiles(files, nil)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	prev := &model.FileManifest{Files: make(map[string]model.FileRecord)}
+	for _, r := range records {
+		prev.Files[r.Path] = r
+	}
+	return prev
+}
+
+// planDir scans dir and plans an incremental run against prev.
+func planDir(t *testing.T, dir string, prev *model.FileManifest, now time.Time) incrementalPlan {
+	t.Helper()
+	files, _ := listInputFiles(dir, nil)
+	records, err := scanFiles(files, prev)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	return planIncremental(prev, records, now)
+}
+
+// Unit test: rename rotation moves the sheet and only the new file is processed
+func TestDetectRotations_Rename(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "app.log")
+	os.WriteFile(log, []byte("old line\n"), 0644)
+	prev := rotationFixture(t, dir)
+
+	os.Rename(log, filepath.Join(dir, "app.log.1"))
+	os.WriteFile(log, []byte("new\n"), 0644)
+
+	plan := planDir(t, dir, prev, time.Now())
+	if plan.Renames["app.log"] != "app.log.1" {
+		t.Fatalf("expected app.log sheet renamed to app.log.1, got %v", plan.Renames)
+	}
+	if len(plan.Changed) != 1 || filepath.Base(plan.Changed[0].Path) != "app.log" {
+		t.Fatalf("expected only app.log to be processed, got %+v", plan.Changed)
+	}
+	if plan.Moved[log] != filepath.Join(dir, "app.log.1") {
+		t.Fatalf("expected record moved to app.log.1, got %v", plan.Moved)
+	}
+}
+
+// Unit test: compressed rotations are matched on their decompressed content
+func TestDetectRotations_Compressed(t *testing.T) {
+	dir := t.TempDir()
+	rotated := filepath.Join(dir, "app.log.1")
+	os.WriteFile(rotated, []byte("rotated content\n"), 0644)
+	prev := rotationFixture(t, dir)
+
+	f, _ := os.Create(filepath.Join(dir, "app.log.2.gz"))
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte("rotated content\n"))
+	gz.Close()
+	f.Close()
+	os.Remove(rotated)
+
+	plan := planDir(t, dir, prev, time.Now())
+	if plan.Renames["app.log.1"] != "app.log.2" {
+		t.Fatalf("expected app.log.1 sheet renamed to app.log.2, got %v", plan.Renames)
+	}
+	if len(plan.Changed) != 0 {
+		t.Fatalf("expected nothing to process, got %+v", plan.Changed)
+	}
+}
+
+// Unit test: copytruncate keeps the old content under the copy's name
+func TestDetectRotations_CopyTruncate(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "app.log")
+	os.WriteFile(log, []byte("a long line of old content\n"), 0644)
+	prev := rotationFixture(t, dir)
+
+	os.WriteFile(filepath.Join(dir, "app.log.1"), []byte("a long line of old content\n"), 0644)
+	os.WriteFile(log, []byte("x\n"), 0644)
+
+	plan := planDir(t, dir, prev, time.Now())
+	if len(plan.Renames) != 1 || plan.Renames["app.log"] != "app.log.1" {
+		t.Fatalf("expected only the copy rename, got %v", plan.Renames)
+	}
+	if len(plan.Changed) != 1 || plan.Changed[0].Path != log {
+		t.Fatalf("expected truncated app.log to be processed, got %+v", plan.Changed)
+	}
+}
+
+// Unit test: truncation in place preserves the old sheet under a dated name
+func TestDetectRotations_TruncateInPlace(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "app.log")
+	os.WriteFile(log, []byte("a long line of old content\n"), 0644)
+	prev := rotationFixture(t, dir)
+
+	os.WriteFile(log, []byte("x\n"), 0644)
+	now := time.Date(2025, 10, 18, 14, 5, 2, 0, time.UTC)
+
+	plan := planDir(t, dir, prev, now)
+	if plan.Renames["app.log"] != "app.log 1018-140502" {
+		t.Fatalf("expected old sheet preserved, got %v", plan.Renames)
+	}
+	if len(plan.Changed) != 1 {
+		t.Fatalf("expected app.log to be processed, got %+v", plan.Changed)
+	}
+}
+
+// Unit test: a rotated-looking name whose original is unchanged is a new file
+func TestDetectRotations_CopyOfUnchangedFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.log"), []byte("same\n"), 0644)
+	prev := rotationFixture(t, dir)
+	os.WriteFile(filepath.Join(dir, "app.log.1"), []byte("same\n"), 0644)
+
+	plan := planDir(t, dir, prev, time.Now())
+	if len(plan.Renames) != 0 || len(plan.Changed) != 1 {
+		t.Fatalf("expected app.log.1 processed as a new file, got renames=%v changed=%+v", plan.Renames, plan.Changed)
+	}
+}
+
+// Unit test: preserved sheet names stay within Excel's limit
+func TestPreservedSheetName(t *testing.T) {
+	name := preservedSheetName("a-very-long-log-file-name-exceeding.log", time.Now())
+	if n := len([]rune(name)); n != maxSheetNameLen {
+		t.Fatalf("expected %d characters, got %d (%q)", maxSheetNameLen, n, name)
+	}
+}
@@ -0,0 +1,670 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"network-log-formatter/internal/enrich"
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/quality"
+	"network-log-formatter/internal/schema"
+	"network-log-formatter/internal/sink"
+	"network-log-formatter/internal/summary"
+	"network-log-formatter/internal/timezone"
+	"network-log-formatter/internal/xlsx"
+
+	"github.com/google/uuid"
+)
+
+// outputFormats returns the output formats of a run; no formats means the
+// workbook alone, unless the run only ships rows to its sinks.
+func outputFormats(params model.BatchParams) []string {
+	if params.SinksOnly {
+		return nil
+	}
+	if len(params.Formats) == 0 {
+		return []string{output.XLSX}
+	}
+	return params.Formats
+}
+
+// wantsWorkbook reports whether the workbook is one of the run's outputs.
+func wantsWorkbook(params model.BatchParams) bool {
+	for _, f := range outputFormats(params) {
+		if f == output.XLSX {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFormats checks the output formats of a run. Incremental and watch
+// runs merge new sheets into the existing workbook and have no such merge
+// for the other formats, so they write the workbook only, and in one piece.
+func ValidateFormats(params model.BatchParams) error {
+	if err := output.Validate(params.Formats); err != nil {
+		return err
+	}
+	if params.MaxWorkbookRows < 0 {
+		return fmt.Errorf("rows per workbook must not be negative")
+	}
+	if params.SinksOnly {
+		if len(params.Formats) > 0 {
+			return fmt.Errorf("a run that only ships rows to sinks writes no output formats")
+		}
+		if len(params.Sinks) == 0 {
+			return fmt.Errorf("a run that only ships rows to sinks needs a sink")
+		}
+		if params.Incremental || params.Watch {
+			return fmt.Errorf("incremental and watch runs write the xlsx workbook")
+		}
+	}
+	if params.Incremental || params.Watch {
+		formats := outputFormats(params)
+		if len(formats) != 1 || formats[0] != output.XLSX {
+			return fmt.Errorf("incremental and watch runs only write the xlsx workbook")
+		}
+		if params.MaxWorkbookRows > 0 {
+			return fmt.Errorf("incremental and watch runs write a single workbook")
+		}
+	}
+	if err := schema.Validate(params.Schema); err != nil {
+		return err
+	}
+	if err := enrich.Validate(params.Enrich); err != nil {
+		return err
+	}
+	if params.TimeZones != nil {
+		if err := timezone.Validate(*params.TimeZones); err != nil {
+			return err
+		}
+	}
+	if params.Summary && (params.Incremental || params.Watch) {
+		return fmt.Errorf("incremental and watch runs process only new files, so they can't sum up the rows")
+	}
+	return sink.Validate(params.Sinks)
+}
+
+// rowOutput takes the rows a script streams with logforge.row, shared by
+// the workers of a parallel run. Each row has its times converted to the
+// run's target zone and the columns of its IP columns added, when the run
+// asks for them, before it goes to the run's output formats and sinks;
+// the same row is then counted for the summary, if any, and checked
+// against the quality thresholds. A file begun again by a retried script
+// starts over, so only the rows of its last attempt remain. The summary
+// goes on a sheet of the workbook, or into a report of its own when the
+// run writes no workbook.
+type rowOutput struct {
+	mu        sync.Mutex
+	formats   []string // the format of each writer; the type of a sink
+	writers   []output.Writer
+	enricher  *enrich.Enricher   // nil when the run doesn't enrich its rows
+	collector *summary.Collector // nil when the run doesn't sum up its rows
+	report    string             // path of the summary report; "" for none
+	summary   *model.RunSummary  // the summary, once closed
+	checker   *quality.Checker
+	zones     *timezone.Normalizer // nil when the run leaves times as written
+	files     map[string]*rowFile
+	err       error // first write error; nothing more is written after it
+}
+
+// rowFile tracks the rows of one input file.
+type rowFile struct {
+	fresh   bool         // the file began again; its next rows replace earlier ones
+	started bool         // the writers began the file
+	enrich  *enrich.File // holds rows back until the enriched columns are known
+	zones   *timezone.File
+	ended   bool
+	warned  bool // a dropped row was reported
+}
+
+// resolveSinks returns the sinks of a run with their dead-letter files made
+// absolute: relative to the output directory, by default named after the
+// output.
+func resolveSinks(params model.BatchParams) []model.SinkConfig {
+	if len(params.Sinks) == 0 {
+		return nil
+	}
+	sinks := make([]model.SinkConfig, len(params.Sinks))
+	for i, cfg := range params.Sinks {
+		if cfg.DeadLetter == "" {
+			cfg.DeadLetter = outputName(params) + ".deadletter.jsonl"
+		}
+		if !filepath.IsAbs(cfg.DeadLetter) {
+			cfg.DeadLetter = filepath.Join(params.OutputDir, cfg.DeadLetter)
+		}
+		sinks[i] = cfg
+	}
+	return sinks
+}
+
+// outputName returns the name the output files of a run start with.
+// Scripts default it to "result".
+func outputName(params model.BatchParams) string {
+	if params.OutputFileName == "" {
+		return "result"
+	}
+	return params.OutputFileName
+}
+
+// newRowOutput returns the output for the formats of a run.
+func newRowOutput(params model.BatchParams) (*rowOutput, error) {
+	name := outputName(params)
+	o := &rowOutput{checker: quality.New(params.Schema), files: make(map[string]*rowFile)}
+	if params.Enrich != nil {
+		e, err := enrich.New(*params.Enrich, params.Schema)
+		if err != nil {
+			return nil, err
+		}
+		o.enricher = e
+	}
+	opts := output.Options{MaxWorkbookRows: params.MaxWorkbookRows}
+	var loc *time.Location
+	if timezone.Enabled(params.TimeZones) {
+		n, err := timezone.New(*params.TimeZones, params.Schema)
+		if err != nil {
+			return nil, err
+		}
+		o.zones = n
+		opts.TextTimes = params.TimeZones.Format == timezone.ISO
+		loc, _ = timezone.Load(params.TimeZones.Target)
+	}
+	if params.Summary {
+		o.collector = summary.New(params.Schema)
+		if !params.SinksOnly {
+			o.report = filepath.Join(params.OutputDir, name+".summary.xlsx")
+		}
+	}
+	for _, format := range outputFormats(params) {
+		w, err := output.New(format, params.OutputDir, name, opts)
+		if err != nil {
+			o.abort()
+			return nil, err
+		}
+		o.formats = append(o.formats, format)
+		o.writers = append(o.writers, w)
+	}
+	runID := uuid.NewString()
+	deadLetters := make(map[string]*sink.DeadLetter)
+	for _, cfg := range resolveSinks(params) {
+		dl := deadLetters[cfg.DeadLetter]
+		if dl == nil {
+			dl = sink.NewDeadLetter(cfg.DeadLetter)
+			deadLetters[cfg.DeadLetter] = dl
+		}
+		s, err := sink.New(cfg, sink.Options{RunID: runID, DeadLetter: dl, Location: loc})
+		if err != nil {
+			o.abort()
+			return nil, err
+		}
+		o.formats = append(o.formats, cfg.Type)
+		o.writers = append(o.writers, s)
+	}
+	return o, nil
+}
+
+// begin notes that the script started processing file.
+func (o *rowOutput) begin(file string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if f := o.files[file]; f != nil {
+		f.fresh = true
+	}
+}
+
+// start begins the rows of file with the given columns.
+func (o *rowOutput) start(file string, columns []string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.startLocked(file, columns)
+}
+
+func (o *rowOutput) startLocked(file string, columns []string) error {
+	if o.err != nil {
+		return nil
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("%s: no columns given", file)
+	}
+	f := &rowFile{}
+	if o.zones != nil {
+		f.zones = o.zones.File(file, columns)
+	}
+	if o.enricher != nil {
+		f.enrich = o.enricher.File(columns)
+		columns = f.enrich.Columns()
+	}
+	o.files[file] = f
+	if columns == nil {
+		// Begun once the enriched columns are known; until then the
+		// writers keep the rows of an earlier attempt
+		return nil
+	}
+	return o.startWriters(f, file, columns)
+}
+
+// startWriters begins file in every writer.
+func (o *rowOutput) startWriters(f *rowFile, file string, columns []string) error {
+	for _, w := range o.writers {
+		if err := w.StartFile(file, columns); err != nil {
+			o.err = err
+			return err
+		}
+	}
+	if o.collector != nil {
+		o.collector.StartFile(file, columns)
+	}
+	o.checker.StartFile(file, columns)
+	f.started = true
+	return nil
+}
+
+// writeLocked writes a row of a file, with its times normalized and
+// through the enrichment when the run asks for them.
+func (o *rowOutput) writeLocked(f *rowFile, file string, values []any) error {
+	if f.zones != nil {
+		f.zones.Normalize(values)
+	}
+	if f.enrich == nil {
+		return o.writeRowsLocked(f, file, [][]any{values})
+	}
+	return o.writeRowsLocked(f, file, f.enrich.Add(values))
+}
+
+// flushLocked writes the rows the enrichment of a file holds back.
+func (o *rowOutput) flushLocked(f *rowFile, file string) error {
+	if f.enrich == nil {
+		return nil
+	}
+	rows := f.enrich.Flush()
+	if !f.started {
+		if err := o.startWriters(f, file, f.enrich.Columns()); err != nil {
+			return err
+		}
+	}
+	return o.writeRowsLocked(f, file, rows)
+}
+
+// writeRowsLocked writes rows of a file to every writer, beginning an
+// enriched file once its rows are released.
+func (o *rowOutput) writeRowsLocked(f *rowFile, file string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if !f.started {
+		if err := o.startWriters(f, file, f.enrich.Columns()); err != nil {
+			return err
+		}
+	}
+	for _, values := range rows {
+		for _, w := range o.writers {
+			if err := w.WriteRow(file, values); err != nil {
+				o.err = err
+				return err
+			}
+		}
+		if o.collector != nil {
+			o.collector.WriteRow(file, values)
+		}
+		o.checker.WriteRow(file, values)
+	}
+	return nil
+}
+
+// row writes a row of file. Rows of a file without columns get columns
+// named column_1, column_2 and so on; rows after the file is done are
+// dropped. The returned error is a warning for the run log.
+func (o *rowOutput) row(file string, raw []json.RawMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return nil
+	}
+	f := o.files[file]
+	if f == nil || f.fresh {
+		if err := o.startLocked(file, make([]string, len(raw))); err != nil {
+			return err
+		}
+		f = o.files[file]
+	}
+	if f.ended {
+		if f.warned {
+			return nil
+		}
+		f.warned = true
+		return fmt.Errorf("%s: rows after the file was done are dropped", file)
+	}
+	values := make([]any, len(raw))
+	for i, r := range raw {
+		values[i] = decodeValue(r)
+	}
+	return o.writeLocked(f, file, values)
+}
+
+// write writes a row of a started file.
+func (o *rowOutput) write(file string, values []any) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return o.err
+	}
+	return o.writeLocked(o.files[file], file, values)
+}
+
+// end completes the rows of file.
+func (o *rowOutput) end(file string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f := o.files[file]
+	if f == nil || o.err != nil {
+		return
+	}
+	f.ended = true
+	if err := o.flushLocked(f, file); err != nil {
+		return
+	}
+	for _, w := range o.writers {
+		if err := w.EndFile(file); err != nil {
+			o.err = err
+			return
+		}
+	}
+}
+
+// streamed reports whether the script streamed any rows.
+func (o *rowOutput) streamed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.files) > 0
+}
+
+// close finishes every format and returns the files written.
+func (o *rowOutput) close() ([]string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for file, f := range o.files {
+		if !f.ended && o.err == nil {
+			o.flushLocked(f, file)
+		}
+	}
+	if o.err != nil {
+		o.abortLocked()
+		return nil, fmt.Errorf("failed to write output: %w", o.err)
+	}
+	var sheet [][]any
+	placed := false
+	if o.collector != nil {
+		o.summary = o.collector.Summary()
+		sheet = summary.Sheet(o.summary)
+		for _, w := range o.writers {
+			if sw, ok := w.(output.SummaryWriter); ok {
+				if err := sw.SetSummary(sheet); err != nil {
+					o.abortLocked()
+					return nil, fmt.Errorf("failed to write output: %w", err)
+				}
+				placed = true
+			}
+		}
+	}
+	var paths []string
+	for i, w := range o.writers {
+		written, err := w.Close()
+		if err != nil {
+			for _, rest := range o.writers[i+1:] {
+				rest.Abort()
+			}
+			return paths, fmt.Errorf("failed to write output: %w", err)
+		}
+		paths = append(paths, written...)
+	}
+	if sheet != nil && !placed && o.report != "" {
+		if err := output.WriteSummary(o.report, sheet); err != nil {
+			return paths, fmt.Errorf("failed to write %s: %w", filepath.Base(o.report), err)
+		}
+		paths = append(paths, o.report)
+	}
+	return paths, nil
+}
+
+// runSummary returns the summary of the rows once the output is closed;
+// nil when the run doesn't sum up its rows.
+func (o *rowOutput) runSummary() *model.RunSummary {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.summary
+}
+
+// qualityReport returns the quality of the rows written so far; nil when
+// there are none.
+func (o *rowOutput) qualityReport() *model.QualityReport {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.files) == 0 {
+		return nil
+	}
+	return o.checker.Report()
+}
+
+// timeZones returns the zones the run normalizes its times with; nil when
+// it leaves them as written.
+func (o *rowOutput) timeZones() *model.TimeZones {
+	if o == nil || o.zones == nil {
+		return nil
+	}
+	z := o.zones.Zones()
+	return &z
+}
+
+// appliedZones returns the zones the times of the rows were normalized
+// with; nil when they were left as written.
+func (o *rowOutput) appliedZones() *model.AppliedZones {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.zones == nil {
+		return nil
+	}
+	return o.zones.Applied()
+}
+
+// sheets returns where the rows of each file went in the workbooks written,
+// once the output is closed.
+func (o *rowOutput) sheets() []model.SheetPlacement {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var placed []model.SheetPlacement
+	for _, w := range o.writers {
+		sw, ok := w.(output.SheetWriter)
+		if !ok {
+			continue
+		}
+		for _, s := range sw.Sheets() {
+			placed = append(placed, model.SheetPlacement{File: s.File, Workbook: s.Workbook, Sheet: s.Sheet, Rows: s.Rows})
+		}
+	}
+	return placed
+}
+
+// sinkResults returns the rows delivered to each sink, once the output is
+// closed.
+func (o *rowOutput) sinkResults() []model.SinkResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var results []model.SinkResult
+	for _, w := range o.writers {
+		if s, ok := w.(sink.Sink); ok {
+			results = append(results, s.Result())
+		}
+	}
+	return results
+}
+
+// abort discards everything written but the rows already shipped, which the
+// sinks can't take back.
+func (o *rowOutput) abort() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.abortLocked()
+}
+
+func (o *rowOutput) abortLocked() {
+	for _, w := range o.writers {
+		w.Abort()
+	}
+}
+
+// dropWorkbook discards the workbook writer, keeping a workbook the script
+// wrote itself. It reports whether other formats remain.
+func (o *rowOutput) dropWorkbook() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, f := range o.formats {
+		if f == output.XLSX {
+			o.writers[i].Abort()
+			o.formats = append(o.formats[:i], o.formats[i+1:]...)
+			o.writers = append(o.writers[:i], o.writers[i+1:]...)
+			break
+		}
+	}
+	return len(o.writers) > 0
+}
+
+// reports reports whether the rows are summed up in a report of their own.
+func (o *rowOutput) reports() bool {
+	return o.report != ""
+}
+
+// copyWorkbook fills the output from the sheets of a workbook, for scripts
+// that write the workbook but don't stream rows. Each sheet becomes a file
+// whose first row holds the columns.
+func (o *rowOutput) copyWorkbook(path string) error {
+	sheets, err := xlsx.ReadFile(path, 0)
+	if err != nil {
+		return err
+	}
+	for _, sheet := range sheets {
+		if len(sheet.Rows) == 0 {
+			continue
+		}
+		if err := o.start(sheet.Name, sheet.Rows[0]); err != nil {
+			return err
+		}
+		for _, r := range sheet.Rows[1:] {
+			values := make([]any, len(r))
+			for i, v := range r {
+				if v != "" {
+					values[i] = v
+				}
+			}
+			if err := o.write(sheet.Name, values); err != nil {
+				return err
+			}
+		}
+		o.end(sheet.Name)
+	}
+	return nil
+}
+
+// finishOutputs closes the formats of a finished run and lists every file
+// the run wrote in result.Outputs. Scripts that don't stream rows write the
+// workbook themselves, as scripts generated before LogForge wrote it did;
+// their workbook is kept and the other formats and the summary are filled
+// from it.
+func finishOutputs(out *rowOutput, params model.BatchParams, result *model.BatchResult) error {
+	defer reportSinks(out, result)
+	if out.streamed() {
+		paths, err := out.close()
+		result.Outputs = append(result.Outputs, paths...)
+		result.Sheets = out.sheets()
+		result.Summary = out.runSummary()
+		result.Quality = out.qualityReport()
+		result.TimeZones = out.appliedZones()
+		return err
+	}
+
+	workbook := OutputWorkbookPath(params)
+	_, statErr := os.Stat(workbook)
+	hasWorkbook := statErr == nil
+	if hasWorkbook && wantsWorkbook(params) {
+		result.Outputs = append(result.Outputs, workbook)
+	}
+	others := out.dropWorkbook()
+	if !others && (!hasWorkbook || !out.reports()) {
+		return nil
+	}
+	if !hasWorkbook {
+		out.abort()
+		return fmt.Errorf("script neither streamed rows nor wrote %s", filepath.Base(workbook))
+	}
+	if others {
+		result.Log = append(result.Log, model.LogEntry{Level: "warning", Message: "script streamed no rows; other formats are converted from the workbook"})
+	} else {
+		result.Log = append(result.Log, model.LogEntry{Level: "warning", Message: "script streamed no rows; the summary is taken from the workbook"})
+	}
+	if err := out.copyWorkbook(workbook); err != nil {
+		out.abort()
+		return fmt.Errorf("failed to convert %s: %w", filepath.Base(workbook), err)
+	}
+	paths, err := out.close()
+	result.Outputs = append(result.Outputs, paths...)
+	result.Summary = out.runSummary()
+	result.Quality = out.qualityReport()
+	result.TimeZones = out.appliedZones()
+	return err
+}
+
+// reportSinks lists what reached each sink in result.Sinks and warns of
+// rows that didn't. Undelivered rows don't fail the run: its files are
+// written all the same.
+func reportSinks(out *rowOutput, result *model.BatchResult) {
+	result.Sinks = out.sinkResults()
+	for _, s := range result.Sinks {
+		if s.Failed > 0 {
+			result.Log = append(result.Log, model.LogEntry{
+				Level:   "warning",
+				Message: fmt.Sprintf("%s: %d of %d rows not delivered: %s", s.Target, s.Failed, s.Sent+s.Failed, s.Error),
+			})
+			if s.DeadLetter != "" {
+				result.Log = append(result.Log, model.LogEntry{Level: "info", Message: fmt.Sprintf("%s: undelivered rows kept in %s", s.Target, s.DeadLetter)})
+			}
+		} else if s.Error != "" {
+			result.Log = append(result.Log, model.LogEntry{Level: "warning", Message: fmt.Sprintf("%s: %s", s.Target, s.Error)})
+		}
+	}
+}
+
+// decodeValue converts a JSON value of a streamed row into a value for the
+// writers: strings, numbers as written, booleans and null as nil. Objects
+// and arrays are kept as their JSON text.
+func decodeValue(raw json.RawMessage) any {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil
+	}
+	switch raw[0] {
+	case 'n':
+		return nil
+	case 't':
+		return true
+	case 'f':
+		return false
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+	case '{', '[':
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err == nil {
+			return buf.String()
+		}
+	default:
+		return json.Number(raw)
+	}
+	return string(raw)
+}
@@ -0,0 +1,704 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/pyenv"
+	"network-log-formatter/internal/xlsx"
+)
+
+// fakePythonExecutor returns an executor whose python runs script as a
+// shell script.
+func fakePythonExecutor(t *testing.T, script string) (*BatchExecutor, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake python needs a POSIX shell")
+	}
+	envPath := t.TempDir()
+	os.MkdirAll(filepath.Join(envPath, "bin"), 0755)
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte("#!/bin/sh\n"+script), 0755)
+	return NewBatchExecutor(pyenv.NewPythonEnvManager("uv", envPath), nil, 0), envPath
+}
+
+// readLines returns the lines of a file without the UTF-8 BOM.
+func readLines(t *testing.T, file string) []string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", file, err)
+	}
+	return strings.Split(strings.TrimSuffix(strings.TrimPrefix(string(data), "\xef\xbb\xbf"), "\n"), "\n")
+}
+
+// --- Unit Tests ---
+
+// Unit test: rows the script streams are written in every selected format
+// and the workbook is neither asked for nor listed
+func TestExecuteJob_WritesStreamedRows(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo "config $(cat "$(dirname "$1")/logforge.json")"
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["ip","status","extra"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["1.1.1.1",200,{"k":[1, 2]}]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["2.2.2.2",null]}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+echo '{"v":1,"event":"row","file":"a.log","values":["late",1]}'
+echo '{"v":1,"event":"row","file":"b.log","values":["x","y"]}'
+`)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Formats: []string{"csv", "jsonl"},
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(outputDir, "out", "a.log.csv"),
+		filepath.Join(outputDir, "out", "b.log.csv"),
+		filepath.Join(outputDir, "out", "a.log.jsonl"),
+		filepath.Join(outputDir, "out", "b.log.jsonl"),
+	}
+	if !reflect.DeepEqual(result.Outputs, want) {
+		t.Fatalf("outputs = %v, want %v", result.Outputs, want)
+	}
+	if got := readLines(t, want[0]); !reflect.DeepEqual(got, []string{"ip,status,extra", `1.1.1.1,200,"{""k"":[1,2]}"`, "2.2.2.2,,"}) {
+		t.Errorf("a.log.csv = %q", got)
+	}
+	if got := readLines(t, want[1]); !reflect.DeepEqual(got, []string{"column_1,column_2", "x,y"}) {
+		t.Errorf("b.log.csv = %q", got)
+	}
+	if got := readLines(t, want[2]); got[1] != `{"ip":"2.2.2.2","status":null,"extra":null}` {
+		t.Errorf("a.log.jsonl = %q", got)
+	}
+	if result.Files[0].Rows == nil || *result.Files[0].Rows != 2 {
+		t.Errorf("expected the streamed rows to be counted, got %+v", result.Files)
+	}
+
+	var config, dropped bool
+	for _, e := range result.Log {
+		config = config || e.Message == `config {"excel":false,"rows":true}`
+		dropped = dropped || (e.Level == "warning" && strings.Contains(e.Message, "after the file was done"))
+	}
+	if !config || !dropped {
+		t.Errorf("expected the helper config and a dropped row warning in the log, got %+v", result.Log)
+	}
+}
+
+// Unit test: enriched runs add the range of the IP columns found by value
+// or declared in the schema, also for files the script didn't finish and
+// files begun again
+func TestExecuteJob_EnrichesIPColumns(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["src","msg"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["10.0.0.1","old"]}'
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["src","msg"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["10.0.0.1","up"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["8.8.8.8","down"]}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+echo '{"v":1,"event":"columns","file":"b.log","columns":["peer","host"]}'
+echo '{"v":1,"event":"row","file":"b.log","values":["-","127.0.0.1"]}'
+`)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Formats: []string{"jsonl"},
+		Enrich: &model.EnrichParams{Classify: true},
+		Schema: []model.ColumnSchema{{Name: "peer", Type: "ip"}, {Name: "host", Type: "string"}},
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	want := map[string][]string{
+		"a.log.jsonl": {`{"src":"10.0.0.1","msg":"up","src_range":"private"}`, `{"src":"8.8.8.8","msg":"down","src_range":"public"}`},
+		"b.log.jsonl": {`{"peer":"-","host":"127.0.0.1","peer_range":null}`},
+	}
+	for name, lines := range want {
+		if got := readLines(t, filepath.Join(outputDir, "out", name)); !reflect.DeepEqual(got, lines) {
+			t.Errorf("%s = %q, want %q", name, got, lines)
+		}
+	}
+	if len(result.Outputs) != 2 {
+		t.Errorf("outputs = %v", result.Outputs)
+	}
+}
+
+// Unit test: without streamed rows the formats are converted from the workbook
+func TestExecuteJob_ConvertsWorkbook(t *testing.T) {
+	be, envPath := fakePythonExecutor(t, "")
+	workbook := filepath.Join(envPath, "rows.xlsx")
+	writeTestWorkbook(t, workbook, []string{"a.log"}, [][][]string{{{"ip", "status"}, {"1.1.1.1", "200"}}})
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte("#!/bin/sh\ncp "+workbook+` "$5/$7.xlsx"`+"\n"), 0755)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Formats: []string{"xlsx", "csv"},
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	want := []string{filepath.Join(outputDir, "out.xlsx"), filepath.Join(outputDir, "out", "a.log.csv")}
+	if !reflect.DeepEqual(result.Outputs, want) {
+		t.Fatalf("outputs = %v, want %v", result.Outputs, want)
+	}
+	if got := readLines(t, want[1]); !reflect.DeepEqual(got, []string{"ip,status", "1.1.1.1,200"}) {
+		t.Errorf("a.log.csv = %q", got)
+	}
+}
+
+// Unit test: the summary of the streamed rows goes ahead of the files'
+// sheets in the workbook and into the result
+func TestExecuteJob_SummarySheet(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo '{"v":1,"event":"columns","file":"fw.log","columns":["time","src_ip","action"]}'
+echo '{"v":1,"event":"row","file":"fw.log","values":["2024-05-01 10:15:00","10.0.0.1","allow"]}'
+echo '{"v":1,"event":"row","file":"fw.log","values":["2024-05-01 11:20:00","10.0.0.1","deny"]}'
+echo '{"v":1,"event":"file_done","file":"fw.log","status":"ok"}'
+`)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "fw.log"), []byte("x\n"), 0644)
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Summary: true,
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if want := []string{filepath.Join(outputDir, "out.xlsx")}; !reflect.DeepEqual(result.Outputs, want) {
+		t.Errorf("outputs = %v, want %v", result.Outputs, want)
+	}
+	s := result.Summary
+	if s == nil || s.Rows != 2 || s.From != "2024-05-01 10:15:00" || len(s.TopIPs) != 1 || len(s.Statuses) != 1 || len(s.Hourly) != 2 {
+		t.Fatalf("summary = %+v", s)
+	}
+	sheets, err := xlsx.ReadFile(result.Outputs[0], 0)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(sheets) != 2 || sheets[0].Name != "Summary" || sheets[1].Name != "fw.log" {
+		t.Fatalf("sheets = %+v", sheets)
+	}
+	if got := sheets[0].Rows[1][:2]; !reflect.DeepEqual(got, []string{"Rows", "2"}) {
+		t.Errorf("summary row = %q", got)
+	}
+	if len(result.Sheets) != 1 || result.Sheets[0].Sheet != "fw.log" {
+		t.Errorf("sheets placed = %+v", result.Sheets)
+	}
+}
+
+// Unit test: a run without a workbook of its own sums up the rows in a
+// report, here from the workbook the script wrote
+func TestExecuteJob_SummaryReport(t *testing.T) {
+	be, envPath := fakePythonExecutor(t, "")
+	workbook := filepath.Join(envPath, "rows.xlsx")
+	writeTestWorkbook(t, workbook, []string{"a.log"}, [][][]string{{{"ip", "status"}, {"1.1.1.1", "200"}, {"1.1.1.1", "404"}}})
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte("#!/bin/sh\ncp "+workbook+` "$5/$7.xlsx"`+"\n"), 0755)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Summary: true,
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	report := filepath.Join(outputDir, "out.summary.xlsx")
+	if want := []string{filepath.Join(outputDir, "out.xlsx"), report}; !reflect.DeepEqual(result.Outputs, want) {
+		t.Fatalf("outputs = %v, want %v", result.Outputs, want)
+	}
+	if result.Summary == nil || result.Summary.Rows != 2 || len(result.Summary.TopIPs) != 1 || result.Summary.TopIPs[0].Values[0].Count != 2 {
+		t.Errorf("summary = %+v", result.Summary)
+	}
+	if sheets, err := xlsx.ReadFile(report, 0); err != nil || len(sheets) != 1 || sheets[0].Name != "Summary" {
+		t.Errorf("report sheets = %+v, %v", sheets, err)
+	}
+}
+
+// Unit test: a run that neither streams rows nor writes a workbook fails
+func TestExecuteJob_NoOutput(t *testing.T) {
+	be, _ := fakePythonExecutor(t, "echo nothing\n")
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+
+	_, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, Formats: []string{"sqlite"},
+	}, func(p *model.BatchProgress) {})
+	if err == nil || !strings.Contains(err.Error(), "neither streamed rows") {
+		t.Fatalf("expected a missing output error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Errorf("expected no output, got %v", entries)
+	}
+}
+
+// Unit test: a file begun again by a retried script keeps only its new rows
+func TestRowOutput_RestartsFile(t *testing.T) {
+	outputDir := t.TempDir()
+	out, err := newRowOutput(model.BatchParams{OutputDir: outputDir, Formats: []string{"csv"}})
+	if err != nil || out == nil {
+		t.Fatalf("newRowOutput: %v %v", out, err)
+	}
+	stream := newProgressStream(newFileTracker("", time.Now()), nil)
+	stream.rows = out
+	for _, line := range []string{
+		`{"v":1,"event":"file_start","file":"a.log"}`,
+		`{"v":1,"event":"row","file":"a.log","values":["first"]}`,
+		`{"v":1,"event":"file_start","file":"a.log"}`,
+		`{"v":1,"event":"row","file":"a.log","values":["second"]}`,
+	} {
+		stream.handle(line, time.Now())
+	}
+	paths, err := out.close()
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := readLines(t, paths[0]); !reflect.DeepEqual(got, []string{"column_1", "second"}) {
+		t.Errorf("a.log.csv = %q", got)
+	}
+}
+
+// Unit test: the workbook is written from streamed rows by default, with
+// the sheet of each file recorded, while a workbook written by a script that
+// streams nothing is kept
+func TestExecuteJob_WritesWorkbook(t *testing.T) {
+	be, envPath := fakePythonExecutor(t, `
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["time","ip","bytes"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["2024-01-02 03:04:05","10.0.0.1",42]}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+`)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	params := model.BatchParams{InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out"}
+
+	result, err := be.ExecuteJob(context.Background(), "pass", params, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	workbook := filepath.Join(outputDir, "out.xlsx")
+	if !reflect.DeepEqual(result.Outputs, []string{workbook}) {
+		t.Fatalf("outputs = %v", result.Outputs)
+	}
+	sheets, err := xlsx.ReadFile(workbook, 0)
+	if err != nil {
+		t.Fatalf("failed to read workbook: %v", err)
+	}
+	want := [][]string{{"time", "ip", "bytes"}, {"2024-01-02 03:04:05", "10.0.0.1", "42"}}
+	if len(sheets) != 1 || sheets[0].Name != "a.log" || !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Errorf("sheets = %+v", sheets)
+	}
+	placed := []model.SheetPlacement{{File: "a.log", Workbook: workbook, Sheet: "a.log", Rows: 1}}
+	if !reflect.DeepEqual(result.Sheets, placed) {
+		t.Errorf("placements = %+v, want %+v", result.Sheets, placed)
+	}
+
+	script := filepath.Join(envPath, "rows.xlsx")
+	writeTestWorkbook(t, script, []string{"own"}, [][][]string{{{"x"}, {"1"}}})
+	os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte("#!/bin/sh\ncp "+script+` "$5/$7.xlsx"`+"\n"), 0755)
+	if _, err := be.ExecuteJob(context.Background(), "pass", params, func(p *model.BatchProgress) {}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if sheets, _ := xlsx.ReadFile(workbook, 0); len(sheets) != 1 || sheets[0].Name != "own" {
+		t.Errorf("expected the script's workbook to be kept, got %+v", sheets)
+	}
+}
+
+// Unit test: streamed rows are shipped to the run's sinks alongside the
+// workbook, and rows a sink refuses are reported without failing the run
+func TestExecuteJob_ShipsToSinks(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["time","msg"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["2024-01-02T03:04:05Z","up"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["2024-01-02T03:04:06Z","down"]}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+`)
+	var mu sync.Mutex
+	var lines []string
+	loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var push struct {
+			Streams []struct {
+				Values [][2]string `json:"values"`
+			} `json:"streams"`
+		}
+		json.NewDecoder(r.Body).Decode(&push)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range push.Streams {
+			for _, v := range s.Values {
+				lines = append(lines, v[1])
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer loki.Close()
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer refusing.Close()
+
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	params := model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out",
+		Sinks: []model.SinkConfig{{Type: "loki", URL: loki.URL}, {Type: "loki", URL: refusing.URL}},
+	}
+	result, err := be.ExecuteJob(context.Background(), "pass", params, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "out.xlsx")); err != nil {
+		t.Errorf("workbook not written: %v", err)
+	}
+	// The keys of the rows derive from the run's random ID
+	key := regexp.MustCompile(`^\{"log_id":"[0-9a-f]{40}",`)
+	for i, line := range lines {
+		lines[i] = key.ReplaceAllString(line, "{")
+	}
+	want := []string{`{"time":"2024-01-02T03:04:05Z","msg":"up"}`, `{"time":"2024-01-02T03:04:06Z","msg":"down"}`}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+	if len(result.Sinks) != 2 || result.Sinks[0].Sent != 2 || result.Sinks[1].Failed != 2 {
+		t.Fatalf("sinks = %+v", result.Sinks)
+	}
+	warned := false
+	for _, e := range result.Log {
+		warned = warned || (e.Level == "warning" && strings.Contains(e.Message, "2 of 2 rows not delivered"))
+	}
+	if !warned {
+		t.Errorf("no warning for the refused rows in %+v", result.Log)
+	}
+}
+
+// Unit test: a sinks-only run writes no files but the dead-letter file,
+// which defaults to the output name and may be set per sink
+func TestExecuteJob_SinksOnly(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["msg"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":["up"]}'
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+`)
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+	}))
+	defer refusing.Close()
+
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	params := model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", SinksOnly: true,
+		Sinks: []model.SinkConfig{
+			{Type: "webhook", URL: refusing.URL},
+			{Type: "webhook", URL: refusing.URL + "/other", DeadLetter: "dead/other.jsonl"},
+		},
+	}
+	os.MkdirAll(filepath.Join(outputDir, "dead"), 0755)
+	result, err := be.ExecuteJob(context.Background(), "pass", params, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(result.Outputs) != 0 {
+		t.Errorf("outputs = %v, want none", result.Outputs)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "out.xlsx")); !os.IsNotExist(err) {
+		t.Errorf("workbook written: %v", err)
+	}
+	want := []string{filepath.Join(outputDir, "out.deadletter.jsonl"), filepath.Join(outputDir, "dead", "other.jsonl")}
+	if len(result.Sinks) != 2 {
+		t.Fatalf("sinks = %+v", result.Sinks)
+	}
+	for i, s := range result.Sinks {
+		if s.Failed != 1 || s.DeadLetter != want[i] {
+			t.Errorf("sink %d = %+v", i, s)
+		}
+		if lines := readLines(t, want[i]); len(lines) != 1 || !strings.Contains(lines[0], `"row":{"msg":"up"}`) {
+			t.Errorf("dead letter %s = %v", want[i], lines)
+		}
+	}
+}
+
+// Unit test: format validation, with incremental and watch runs limited to the workbook
+func TestValidateFormats(t *testing.T) {
+	cases := []struct {
+		params model.BatchParams
+		ok     bool
+	}{
+		{model.BatchParams{}, true},
+		{model.BatchParams{Formats: []string{"xlsx", "parquet"}}, true},
+		{model.BatchParams{Formats: []string{"xml"}}, false},
+		{model.BatchParams{Incremental: true}, true},
+		{model.BatchParams{Incremental: true, Formats: []string{"xlsx"}}, true},
+		{model.BatchParams{Incremental: true, Formats: []string{"xlsx", "csv"}}, false},
+		{model.BatchParams{Watch: true, Formats: []string{"jsonl"}}, false},
+		{model.BatchParams{MaxWorkbookRows: 1000}, true},
+		{model.BatchParams{MaxWorkbookRows: -1}, false},
+		{model.BatchParams{Watch: true, MaxWorkbookRows: 1000}, false},
+		{model.BatchParams{Watch: true, Sinks: []model.SinkConfig{{Type: "loki", URL: "http://loki:3100"}}}, true},
+		{model.BatchParams{Sinks: []model.SinkConfig{{Type: "loki", URL: "loki:3100"}}}, false},
+		{model.BatchParams{SinksOnly: true, Sinks: []model.SinkConfig{{Type: "webhook", URL: "http://hooks"}}}, true},
+		{model.BatchParams{SinksOnly: true}, false},
+		{model.BatchParams{SinksOnly: true, Formats: []string{"csv"}, Sinks: []model.SinkConfig{{Type: "webhook", URL: "http://hooks"}}}, false},
+		{model.BatchParams{Enrich: &model.EnrichParams{Classify: true}}, true},
+		{model.BatchParams{Enrich: &model.EnrichParams{GeoIP: true}}, false},
+		{model.BatchParams{Schema: []model.ColumnSchema{{Name: "src", Type: "inet"}}}, false},
+		{model.BatchParams{SinksOnly: true, Incremental: true, Sinks: []model.SinkConfig{{Type: "webhook", URL: "http://hooks"}}}, false},
+		{model.BatchParams{Summary: true, Formats: []string{"csv"}}, true},
+		{model.BatchParams{Summary: true, Incremental: true}, false},
+	}
+	for _, c := range cases {
+		if err := ValidateFormats(c.params); (err == nil) != c.ok {
+			t.Errorf("%+v: err = %v", c.params, err)
+		}
+	}
+}
+
+// Unit test: streamed JSON values become writer values
+func TestDecodeValue(t *testing.T) {
+	cases := map[string]any{
+		`null`:          nil,
+		`true`:          true,
+		`false`:         false,
+		`"a\"b"`:        `a"b`,
+		`-1.5e3`:        json.Number("-1.5e3"),
+		`{"a": [1, 2]}`: `{"a":[1,2]}`,
+	}
+	for raw, want := range cases {
+		if got := decodeValue(json.RawMessage(raw)); got != want {
+			t.Errorf("decodeValue(%s) = %#v, want %#v", raw, got, want)
+		}
+	}
+}
+
+// Unit test: logforge.row streams dicts and lists only when rows are enabled
+func TestLogforgeModule_Rows(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available, skipping helper module test")
+	}
+	script := strings.Join([]string{
+		"import datetime, logforge",
+		"print('excel', logforge.excel_enabled())",
+		"logforge.row('a.log', {'time': datetime.date(2024, 1, 2), 'n': float('nan')})",
+		"logforge.row('a.log', {'n': 3, 'time': None, 'other': 1})",
+		"logforge.row('a.log', ['t', 4])",
+	}, "\n")
+
+	for _, rows := range []bool{true, false} {
+		dir := t.TempDir()
+		if err := writeHelperModule(dir, helperConfig{Excel: false, Rows: rows}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		os.WriteFile(filepath.Join(dir, "script.py"), []byte(script), 0644)
+		out, err := exec.Command(python, filepath.Join(dir, "script.py")).Output()
+		if err != nil {
+			t.Fatalf("script failed: %v", err)
+		}
+
+		outputDir := t.TempDir()
+		rowOut, _ := newRowOutput(model.BatchParams{OutputDir: outputDir, Formats: []string{"jsonl"}})
+		stream := newProgressStream(newFileTracker(dir, time.Now()), nil)
+		stream.rows = rowOut
+		result := &model.BatchResult{}
+		NewBatchExecutor(nil, nil, 0).readStdout(bytes.NewReader(out), result, stream, func(p *model.BatchProgress) {})
+		paths, err := rowOut.close()
+		if err != nil {
+			t.Fatalf("close: %v", err)
+		}
+
+		if result.Log[0].Message != "excel False" {
+			t.Errorf("unexpected log: %+v", result.Log)
+		}
+		if !rows {
+			if len(paths) != 0 {
+				t.Errorf("expected no rows when disabled, got %v", paths)
+			}
+			continue
+		}
+		want := []string{`{"time":"2024-01-02","n":null}`, `{"time":null,"n":3}`, `{"time":"t","n":4}`}
+		if got := readLines(t, paths[0]); !reflect.DeepEqual(got, want) {
+			t.Errorf("rows = %q, want %q", got, want)
+		}
+		if len(result.Log) != 2 || !strings.Contains(result.Log[1].Message, "not columns") {
+			t.Errorf("expected one warning about unknown keys, got %+v", result.Log)
+		}
+	}
+}
+
+// Unit test: parallel workers stream into one output and skip the workbook merge
+func TestExecuteJob_ParallelStreamedRows(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+for f in "$3"/*; do
+  n=$(basename "$f")
+  echo '{"v":1,"event":"file_start","file":"'$n'"}'
+  echo '{"v":1,"event":"columns","file":"'$n'","columns":["name"]}'
+  echo '{"v":1,"event":"row","file":"'$n'","values":["'$n'"]}'
+  echo '{"v":1,"event":"file_done","file":"'$n'","status":"ok"}'
+done
+`)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		os.WriteFile(filepath.Join(inputDir, name), []byte("x\n"), 0644)
+	}
+
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, Workers: 2, Formats: []string{"sqlite", "csv"},
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(result.Outputs) != 4 || result.Outputs[0] != filepath.Join(outputDir, "result.sqlite") {
+		t.Fatalf("unexpected outputs: %v", result.Outputs)
+	}
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		if got := readLines(t, filepath.Join(outputDir, "result", name+".csv")); !reflect.DeepEqual(got, []string{"name", name}) {
+			t.Errorf("%s.csv = %q", name, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "result.xlsx")); !os.IsNotExist(err) {
+		t.Errorf("expected no workbook, got %v", err)
+	}
+}
+
+// qualityScript streams a status column with a text value unless the code
+// is the fixed one.
+const qualityScript = `
+echo '{"v":1,"event":"file_start","file":"a.log"}'
+echo '{"v":1,"event":"columns","file":"a.log","columns":["status"]}'
+echo '{"v":1,"event":"row","file":"a.log","values":[200]}'
+if grep -q FIXED "$1"; then
+  echo '{"v":1,"event":"row","file":"a.log","values":[404]}'
+else
+  echo '{"v":1,"event":"row","file":"a.log","values":["OK"]}'
+fi
+echo '{"v":1,"event":"file_done","file":"a.log","status":"ok"}'
+`
+
+// Unit test: rows falling short of the quality thresholds mark the run
+// degraded or fail it, and the report is returned either way
+func TestExecuteJob_QualityThresholds(t *testing.T) {
+	be, _ := fakePythonExecutor(t, qualityScript)
+	inputDir := t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	max := 10.0
+	for _, action := range []string{"degrade", "fail"} {
+		var last model.BatchProgress
+		result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+			InputDir: inputDir, OutputDir: t.TempDir(), Formats: []string{"csv"},
+			Schema:  []model.ColumnSchema{{Name: "status", Type: "integer"}},
+			Quality: &model.QualityThresholds{MaxMismatched: &max, Action: action},
+		}, func(p *model.BatchProgress) { last = *p })
+		if result == nil || result.Quality == nil || len(result.Quality.Violations) != 1 || result.Quality.Columns[0].Mismatched != 1 {
+			t.Fatalf("%s: result %+v", action, result)
+		}
+		switch action {
+		case "degrade":
+			if err != nil || !result.Degraded || last.Status != "degraded" {
+				t.Errorf("degrade: err %v, degraded %v, status %s", err, result.Degraded, last.Status)
+			}
+		case "fail":
+			if err == nil || last.Status != "failed" {
+				t.Errorf("fail: err %v, status %s", err, last.Status)
+			}
+		}
+	}
+}
+
+// Unit test: the repair action has the code repaired and run again until
+// the rows pass
+func TestExecuteJob_QualityRepair(t *testing.T) {
+	be, _ := fakePythonExecutor(t, qualityScript)
+	repairer := &fixingRepairer{fixed: "# FIXED"}
+	be.llmClient = repairer
+	be.maxRetries = 2
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	max := 0.0
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, Formats: []string{"csv"},
+		Schema:  []model.ColumnSchema{{Name: "status", Type: "integer"}},
+		Quality: &model.QualityThresholds{MaxMismatched: &max, Action: "repair"},
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.RepairedCode != "# FIXED" || result.RepairAttempts != 1 || result.Degraded || len(result.Quality.Violations) != 0 {
+		t.Errorf("result: code %q after %d repairs, degraded %v, quality %+v", result.RepairedCode, result.RepairAttempts, result.Degraded, result.Quality)
+	}
+	// The repair is asked about the rows, not about a runtime error
+	if len(repairer.problems) != 1 || !strings.Contains(repairer.problems[0], `column "status"`) {
+		t.Errorf("quality problems = %q", repairer.problems)
+	}
+	if got := readLines(t, filepath.Join(outputDir, "result", "a.log.csv")); !reflect.DeepEqual(got, []string{"status", "200", "404"}) {
+		t.Errorf("a.log.csv = %q", got)
+	}
+}
+
+// Unit test: runs with a target zone hand the zones to the script,
+// normalize the times of the rows and record the zones applied
+func TestExecuteJob_NormalizesTimes(t *testing.T) {
+	be, _ := fakePythonExecutor(t, `
+echo "config $(cat "$(dirname "$1")/logforge.json")"
+for f in fw-1.log a.log; do
+  echo '{"v":1,"event":"file_start","file":"'$f'"}'
+  echo '{"v":1,"event":"columns","file":"'$f'","columns":["time","msg"]}'
+  echo '{"v":1,"event":"row","file":"'$f'","values":["2024-05-01 10:00:00","2024-05-01 10:00:00"]}'
+  echo '{"v":1,"event":"row","file":"'$f'","values":["2024-05-01T10:00:00Z","up"]}'
+  echo '{"v":1,"event":"file_done","file":"'$f'","status":"ok"}'
+done
+`)
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(inputDir, "a.log"), []byte("x\n"), 0644)
+	zones := &model.TimeZones{Source: "UTC", Files: []model.FileZone{{Pattern: "fw-*", Zone: "+02:00"}}, Target: "+08:00", Format: "iso"}
+	result, err := be.ExecuteJob(context.Background(), "pass", model.BatchParams{
+		InputDir: inputDir, OutputDir: outputDir, OutputFileName: "out", Formats: []string{"csv"},
+		Schema:    []model.ColumnSchema{{Name: "msg", Type: "string"}},
+		TimeZones: zones,
+	}, func(p *model.BatchProgress) {})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	want := map[string][]string{
+		"fw-1.log.csv": {"time,msg", "2024-05-01T16:00:00+08:00,2024-05-01 10:00:00", "2024-05-01T18:00:00+08:00,up"},
+		"a.log.csv":    {"time,msg", "2024-05-01T18:00:00+08:00,2024-05-01 10:00:00", "2024-05-01T18:00:00+08:00,up"},
+	}
+	for name, lines := range want {
+		if got := readLines(t, filepath.Join(outputDir, "out", name)); !reflect.DeepEqual(got, lines) {
+			t.Errorf("%s = %q, want %q", name, got, lines)
+		}
+	}
+	applied := result.TimeZones
+	if applied == nil || applied.Source != "UTC" || applied.Target != "+08:00" || applied.Format != "iso" || applied.Converted != 4 || applied.Files["fw-1.log"] != "+02:00" {
+		t.Errorf("applied zones = %+v", applied)
+	}
+	config := ""
+	for _, e := range result.Log {
+		if strings.HasPrefix(e.Message, "config ") {
+			config = e.Message
+		}
+	}
+	if !strings.Contains(config, `"zones":{"source":"UTC","files":[{"pattern":"fw-*","zone":"+02:00"}],"target":"+08:00","format":"iso"}`) {
+		t.Errorf("helper config = %q", config)
+	}
+}
@@ -0,0 +1,403 @@
+// Package job schedules batch runs as first-class jobs with their own IDs,
+// progress and results, bounded by a configurable concurrency limit. Jobs can
+// be persisted with a JobStore so the queue survives application restarts.
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"network-log-formatter/internal/model"
+)
+
+// maxFinishedJobs is the number of finished jobs kept, most recent first.
+// Older ones are dropped as jobs finish, so scheduled runs don't pile up;
+// their runs stay in the projects' run history.
+const maxFinishedJobs = 100
+
+// Runner executes a single batch job and reports progress through report.
+// The context is cancelled when the job is cancelled.
+type Runner func(ctx context.Context, job model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error)
+
+// JobManager queues batch jobs and runs up to maxConcurrent of them at a time.
+// If a store is configured, every status change is written to disk.
+type JobManager struct {
+	runner        Runner
+	store         *JobStore
+	maxConcurrent int
+	paused        bool
+	jobs          map[string]*jobEntry
+	queue         []string // IDs of queued jobs in submission order
+	running       int
+	mu            sync.Mutex
+}
+
+// jobEntry pairs a job record with the cancel function of its run.
+type jobEntry struct {
+	job    model.BatchJob
+	cancel context.CancelFunc
+	slot   bool // whether the run occupies one of the maxConcurrent slots
+}
+
+// NewJobManager creates a JobManager that executes jobs with runner and
+// persists them to store. store may be nil to keep jobs in memory only.
+// A maxConcurrent below 1 is treated as 1.
+func NewJobManager(runner Runner, store *JobStore, maxConcurrent int) *JobManager {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &JobManager{
+		runner:        runner,
+		store:         store,
+		maxConcurrent: maxConcurrent,
+		jobs:          make(map[string]*jobEntry),
+	}
+}
+
+// Restore loads persisted jobs from the store. Queued jobs are re-enqueued in
+// their original order. Jobs that were running when the application exited
+// are re-enqueued if resumeInterrupted is true, otherwise they are marked
+// "interrupted". The most recent maxFinishedJobs finished jobs are kept for
+// reference.
+func (jm *JobManager) Restore(resumeInterrupted bool) error {
+	if jm.store == nil {
+		return nil
+	}
+	jobs, err := jm.store.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	for _, j := range jobs {
+		if _, exists := jm.jobs[j.ID]; exists {
+			continue
+		}
+		e := &jobEntry{job: j}
+		jm.jobs[j.ID] = e
+
+		switch j.Status {
+		case "queued":
+			jm.queue = append(jm.queue, j.ID)
+		case "running":
+			if resumeInterrupted {
+				e.job.Status = "queued"
+				e.job.Progress = model.BatchProgress{Status: "queued", Message: "Resuming after application restart"}
+				jm.queue = append(jm.queue, j.ID)
+			} else {
+				now := time.Now()
+				e.job.Status = "interrupted"
+				e.job.Error = "interrupted by application exit"
+				e.job.Progress.Status = "interrupted"
+				e.job.Progress.Message = "Batch processing was interrupted by application exit"
+				e.job.FinishedAt = &now
+			}
+			jm.persistLocked(e)
+		}
+	}
+
+	jm.pruneLocked()
+	jm.dispatchLocked()
+	return nil
+}
+
+// Submit queues a new job for the given project and parameters and starts it
+// as soon as a slot is free. It returns a snapshot of the queued job.
+func (jm *JobManager) Submit(projectID string, projectName string, params model.BatchParams) (*model.BatchJob, error) {
+	return jm.submit(model.BatchJob{ProjectID: projectID, ProjectName: projectName, Params: params})
+}
+
+// SubmitScheduled queues a job like Submit for the run of a schedule that
+// came due at the given time.
+func (jm *JobManager) SubmitScheduled(projectID string, projectName string, params model.BatchParams, scheduleID string, at time.Time) (*model.BatchJob, error) {
+	return jm.submit(model.BatchJob{ProjectID: projectID, ProjectName: projectName, Params: params, ScheduleID: scheduleID, ScheduledFor: &at})
+}
+
+// submit queues j as a new job.
+func (jm *JobManager) submit(j model.BatchJob) (*model.BatchJob, error) {
+	if j.ProjectID == "" {
+		return nil, errors.New("project ID must not be empty")
+	}
+
+	j.ID = uuid.New().String()
+	j.Status = "queued"
+	j.Progress = model.BatchProgress{Status: "queued", Message: "Waiting for a free slot"}
+	j.CreatedAt = time.Now()
+
+	jm.mu.Lock()
+	e := &jobEntry{job: j}
+	jm.jobs[j.ID] = e
+	jm.queue = append(jm.queue, j.ID)
+	jm.persistLocked(e)
+	jm.dispatchLocked()
+	snapshot := jm.jobs[j.ID].job
+	jm.mu.Unlock()
+
+	return &snapshot, nil
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (jm *JobManager) Get(id string) (*model.BatchJob, error) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	e, ok := jm.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	j := e.job
+	return &j, nil
+}
+
+// List returns snapshots of all jobs sorted by creation time descending.
+func (jm *JobManager) List() []model.BatchJob {
+	jm.mu.Lock()
+	jobs := make([]model.BatchJob, 0, len(jm.jobs))
+	for _, e := range jm.jobs {
+		jobs = append(jobs, e.job)
+	}
+	jm.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Latest returns the most recently created job, or nil if there are none.
+func (jm *JobManager) Latest() *model.BatchJob {
+	jobs := jm.List()
+	if len(jobs) == 0 {
+		return nil
+	}
+	return &jobs[0]
+}
+
+// Cancel stops a queued or running job. Queued jobs are removed from the
+// queue immediately; running jobs are cancelled through their context and
+// marked cancelled once the runner returns.
+func (jm *JobManager) Cancel(id string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	e, ok := jm.jobs[id]
+	if !ok {
+		return fmt.Errorf("job not found: %s", id)
+	}
+
+	switch e.job.Status {
+	case "queued":
+		jm.removeFromQueueLocked(id)
+		now := time.Now()
+		e.job.Status = "cancelled"
+		e.job.Progress = model.BatchProgress{Status: "cancelled", Message: "Batch processing cancelled"}
+		e.job.FinishedAt = &now
+		jm.persistLocked(e)
+		return nil
+	case "running":
+		if e.cancel != nil {
+			e.cancel()
+		}
+		return nil
+	default:
+		return fmt.Errorf("job %s is already %s", id, e.job.Status)
+	}
+}
+
+// Delete removes a finished job from the manager and the store.
+func (jm *JobManager) Delete(id string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	e, ok := jm.jobs[id]
+	if !ok {
+		return fmt.Errorf("job not found: %s", id)
+	}
+	if e.job.Status == "queued" || e.job.Status == "running" {
+		return fmt.Errorf("job %s is still %s; cancel it first", id, e.job.Status)
+	}
+	delete(jm.jobs, id)
+	if jm.store != nil {
+		return jm.store.Delete(id)
+	}
+	return nil
+}
+
+// MarkCodeApplied records that the code repaired during a finished job was
+// saved to its project.
+func (jm *JobManager) MarkCodeApplied(id string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	e, ok := jm.jobs[id]
+	if !ok {
+		return fmt.Errorf("job not found: %s", id)
+	}
+	if e.job.Result == nil || e.job.Result.RepairedCode == "" {
+		return fmt.Errorf("job %s has no repaired code", id)
+	}
+	result := *e.job.Result
+	result.CodeApplied = true
+	e.job.Result = &result
+	jm.persistLocked(e)
+	return nil
+}
+
+// SetPaused stops or restarts dispatching of queued jobs. Running jobs are
+// not affected. It is used to hold the queue until the Python environment is
+// ready.
+func (jm *JobManager) SetPaused(paused bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.paused = paused
+	jm.dispatchLocked()
+}
+
+// SetMaxConcurrent changes the concurrency limit. Raising the limit starts
+// queued jobs immediately; lowering it lets running jobs finish.
+func (jm *JobManager) SetMaxConcurrent(n int) {
+	if n < 1 {
+		n = 1
+	}
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.maxConcurrent = n
+	jm.dispatchLocked()
+}
+
+// dispatchLocked starts queued jobs while there are free slots. Watch jobs
+// spend most of their life idle, so they start without taking a slot.
+// The caller must hold jm.mu.
+func (jm *JobManager) dispatchLocked() {
+	if jm.paused {
+		return
+	}
+	var waiting []string
+	for _, id := range jm.queue {
+		e := jm.jobs[id]
+		watch := e.job.Params.Watch
+		if !watch && jm.running >= jm.maxConcurrent {
+			waiting = append(waiting, id)
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		now := time.Now()
+		e.cancel = cancel
+		e.slot = !watch
+		e.job.Status = "running"
+		e.job.StartedAt = &now
+		e.job.FinishedAt = nil
+		e.job.Attempts++
+		e.job.Progress = model.BatchProgress{Status: "running", Message: "Starting batch processing"}
+		if e.slot {
+			jm.running++
+		}
+		jm.persistLocked(e)
+
+		go jm.run(ctx, id, e.job)
+	}
+	jm.queue = waiting
+}
+
+// run executes a single job and records its outcome.
+func (jm *JobManager) run(ctx context.Context, id string, j model.BatchJob) {
+	report := func(p *model.BatchProgress) {
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		if e, ok := jm.jobs[id]; ok {
+			e.job.Progress = *p
+		}
+	}
+
+	result, err := jm.runner(ctx, j, report)
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	e := jm.jobs[id]
+	now := time.Now()
+	e.job.Result = result
+	e.job.FinishedAt = &now
+	switch {
+	case ctx.Err() != nil:
+		e.job.Status = "cancelled"
+		e.job.Progress.Status = "cancelled"
+		e.job.Progress.Message = "Batch processing cancelled"
+	case err != nil:
+		e.job.Status = "failed"
+		e.job.Error = err.Error()
+		e.job.Progress.Status = "failed"
+		if e.job.Progress.Message == "" {
+			e.job.Progress.Message = err.Error()
+		}
+	case result != nil && result.Degraded:
+		e.job.Status = "degraded"
+	default:
+		e.job.Status = "completed"
+	}
+	e.cancel()
+	e.cancel = nil
+	jm.persistLocked(e)
+
+	if e.slot {
+		jm.running--
+	}
+	jm.pruneLocked()
+	jm.dispatchLocked()
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs from the
+// manager and the store. The caller must hold jm.mu.
+func (jm *JobManager) pruneLocked() {
+	var finished []*jobEntry
+	for _, e := range jm.jobs {
+		if e.job.Status != "queued" && e.job.Status != "running" {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool {
+		return finished[i].job.CreatedAt.After(finished[k].job.CreatedAt)
+	})
+	for _, e := range finished[maxFinishedJobs:] {
+		delete(jm.jobs, e.job.ID)
+		if jm.store != nil {
+			if err := jm.store.Delete(e.job.ID); err != nil {
+				fmt.Printf("warning: failed to prune job %s: %v\n", e.job.ID, err)
+			}
+		}
+	}
+}
+
+// persistLocked writes the job to the store, if one is configured.
+// The caller must hold jm.mu.
+func (jm *JobManager) persistLocked(e *jobEntry) {
+	if jm.store == nil {
+		return
+	}
+	if err := jm.store.Save(e.job); err != nil {
+		fmt.Printf("warning: failed to persist job %s: %v\n", e.job.ID, err)
+	}
+}
+
+// removeFromQueueLocked drops id from the pending queue.
+// The caller must hold jm.mu.
+func (jm *JobManager) removeFromQueueLocked(id string) {
+	for i, qid := range jm.queue {
+		if qid == id {
+			jm.queue = append(jm.queue[:i], jm.queue[i+1:]...)
+			return
+		}
+	}
+}
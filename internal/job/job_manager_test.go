@@ -0,0 +1,323 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// waitFor polls cond until it returns true or the timeout expires.
+func waitFor(t testing.TB, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+// allDone reports whether every job has reached a terminal status.
+func allDone(jm *JobManager) bool {
+	for _, j := range jm.List() {
+		if j.Status == "queued" || j.Status == "running" {
+			return false
+		}
+	}
+	return true
+}
+
+// Feature: network-log-formatter, Property 13: 并发任务数上限
+// For any concurrency limit and number of submitted jobs, the number of jobs
+// running at the same time never exceeds the limit and every job completes.
+func TestProperty13_JobConcurrencyLimit(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		limit := rapid.IntRange(1, 4).Draw(rt, "limit")
+		count := rapid.IntRange(1, 12).Draw(rt, "count")
+
+		var current, peak int32
+		runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+			n := atomic.AddInt32(&current, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&current, -1)
+			return &model.BatchResult{}, nil
+		}
+
+		jm := NewJobManager(runner, nil, limit)
+		for i := 0; i < count; i++ {
+			if _, err := jm.Submit("project", "name", model.BatchParams{}); err != nil {
+				rt.Fatalf("submit failed: %v", err)
+			}
+		}
+		waitFor(t, 5*time.Second, func() bool { return allDone(jm) })
+
+		if int(peak) > limit {
+			rt.Fatalf("peak concurrency %d exceeds limit %d", peak, limit)
+		}
+		for _, j := range jm.List() {
+			if j.Status != "completed" {
+				rt.Fatalf("job %s ended with status %q", j.ID, j.Status)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: each job keeps its own progress
+func TestJobManager_IndependentProgress(t *testing.T) {
+	release := make(chan struct{})
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		report(&model.BatchProgress{Status: "running", CurrentFile: j.Params.InputDir})
+		<-release
+		return &model.BatchResult{OutputPath: j.Params.OutputDir}, nil
+	}
+
+	jm := NewJobManager(runner, nil, 2)
+	a, _ := jm.Submit("p1", "", model.BatchParams{InputDir: "/a", OutputDir: "/out-a"})
+	b, _ := jm.Submit("p2", "", model.BatchParams{InputDir: "/b", OutputDir: "/out-b"})
+
+	waitFor(t, time.Second, func() bool {
+		ja, _ := jm.Get(a.ID)
+		jb, _ := jm.Get(b.ID)
+		return ja.Progress.CurrentFile == "/a" && jb.Progress.CurrentFile == "/b"
+	})
+	close(release)
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+
+	ja, _ := jm.Get(a.ID)
+	if ja.Result == nil || ja.Result.OutputPath != "/out-a" {
+		t.Fatalf("unexpected result for job a: %+v", ja.Result)
+	}
+}
+
+// Unit test: cancelling a queued job removes it from the queue
+func TestJobManager_CancelQueued(t *testing.T) {
+	release := make(chan struct{})
+	var ran sync.Map
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		ran.Store(j.ID, true)
+		<-release
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, nil, 1)
+	first, _ := jm.Submit("p", "", model.BatchParams{})
+	second, _ := jm.Submit("p", "", model.BatchParams{})
+
+	if err := jm.Cancel(second.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	close(release)
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+
+	if _, ok := ran.Load(second.ID); ok {
+		t.Fatal("cancelled queued job should never run")
+	}
+	got, _ := jm.Get(second.ID)
+	if got.Status != "cancelled" {
+		t.Fatalf("expected cancelled, got %q", got.Status)
+	}
+	got, _ = jm.Get(first.ID)
+	if got.Status != "completed" {
+		t.Fatalf("expected first job completed, got %q", got.Status)
+	}
+}
+
+// Unit test: cancelling a running job cancels its context
+func TestJobManager_CancelRunning(t *testing.T) {
+	started := make(chan struct{})
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	jm := NewJobManager(runner, nil, 1)
+	j, _ := jm.Submit("p", "", model.BatchParams{})
+	<-started
+
+	if err := jm.Cancel(j.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+
+	got, _ := jm.Get(j.ID)
+	if got.Status != "cancelled" {
+		t.Fatalf("expected cancelled, got %q", got.Status)
+	}
+	if err := jm.Cancel(j.ID); err == nil {
+		t.Fatal("expected error cancelling a finished job")
+	}
+}
+
+// Unit test: runner errors mark the job failed
+func TestJobManager_FailedJob(t *testing.T) {
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		return nil, errors.New("boom")
+	}
+
+	jm := NewJobManager(runner, nil, 1)
+	j, _ := jm.Submit("p", "", model.BatchParams{})
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+
+	got, _ := jm.Get(j.ID)
+	if got.Status != "failed" || got.Error != "boom" {
+		t.Fatalf("expected failed job with error, got status=%q error=%q", got.Status, got.Error)
+	}
+}
+
+// Unit test: only a job whose result carries repaired code can be marked as
+// having had it applied
+func TestJobManager_MarkCodeApplied(t *testing.T) {
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		if j.ProjectID == "repaired" {
+			return &model.BatchResult{RepairedCode: "fixed"}, nil
+		}
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, nil, 2)
+	repaired, _ := jm.Submit("repaired", "", model.BatchParams{})
+	plain, _ := jm.Submit("plain", "", model.BatchParams{})
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+
+	if err := jm.MarkCodeApplied(plain.ID); err == nil {
+		t.Fatal("expected error for a job without repaired code")
+	}
+	if err := jm.MarkCodeApplied(repaired.ID); err != nil {
+		t.Fatalf("failed to mark code applied: %v", err)
+	}
+	got, _ := jm.Get(repaired.ID)
+	if !got.Result.CodeApplied {
+		t.Fatal("expected the result to record the applied code")
+	}
+}
+
+// Unit test: a scheduled job reaches the runner with its schedule and due
+// time
+func TestJobManager_SubmitScheduled(t *testing.T) {
+	seen := make(chan model.BatchJob, 1)
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		seen <- j
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, nil, 1)
+	at := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+	j, err := jm.SubmitScheduled("p", "name", model.BatchParams{InputDir: "/in"}, "nightly", at)
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	got := <-seen
+	if got.ID != j.ID || got.ScheduleID != "nightly" || got.ScheduledFor == nil || !got.ScheduledFor.Equal(at) || got.Params.InputDir != "/in" {
+		t.Fatalf("runner got %+v", got)
+	}
+}
+
+// Unit test: unknown job IDs and empty project IDs are rejected
+func TestJobManager_InvalidInput(t *testing.T) {
+	jm := NewJobManager(nil, nil, 1)
+	if _, err := jm.Submit("", "", model.BatchParams{}); err == nil {
+		t.Fatal("expected error for empty project ID")
+	}
+	if _, err := jm.Get("missing"); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+	if err := jm.Cancel("missing"); err == nil {
+		t.Fatal("expected error cancelling unknown job")
+	}
+	if jm.Latest() != nil {
+		t.Fatal("expected no latest job")
+	}
+}
+
+// Unit test: watch jobs run without taking a concurrency slot
+func TestJobManager_WatchJobsBypassLimit(t *testing.T) {
+	release := make(chan struct{})
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		if j.Params.Watch {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		<-release
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, nil, 1)
+	w, _ := jm.Submit("p", "", model.BatchParams{Watch: true})
+	b, _ := jm.Submit("p", "", model.BatchParams{})
+
+	waitFor(t, time.Second, func() bool {
+		jw, _ := jm.Get(w.ID)
+		jb, _ := jm.Get(b.ID)
+		return jw.Status == "running" && jb.Status == "running"
+	})
+
+	close(release)
+	if err := jm.Cancel(w.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+	if got, _ := jm.Get(w.ID); got.Status != "cancelled" {
+		t.Fatalf("expected stopped watch to be cancelled, got %q", got.Status)
+	}
+}
+
+// Unit test: only the most recent finished jobs are kept, in the manager and
+// the store, as jobs finish and when restoring
+func TestJobManager_PrunesFinishedJobs(t *testing.T) {
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		return &model.BatchResult{}, nil
+	}
+	store, err := NewJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create JobStore: %v", err)
+	}
+	jm := NewJobManager(runner, store, 1)
+
+	var first *model.BatchJob
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		j, _ := jm.Submit("p", "", model.BatchParams{})
+		if first == nil {
+			first = j
+		}
+		waitFor(t, time.Second, func() bool { return allDone(jm) })
+	}
+
+	if n := len(jm.List()); n != maxFinishedJobs {
+		t.Errorf("expected %d jobs kept, got %d", maxFinishedJobs, n)
+	}
+	if _, err := jm.Get(first.ID); err == nil {
+		t.Error("expected the oldest job to be pruned")
+	}
+	stored, _ := store.LoadAll()
+	if len(stored) != maxFinishedJobs {
+		t.Errorf("expected %d stored jobs, got %d", maxFinishedJobs, len(stored))
+	}
+
+	old := time.Now().Add(-time.Hour)
+	store.Save(model.BatchJob{ID: "old", ProjectID: "p", Status: "completed", CreatedAt: old})
+	restored := NewJobManager(runner, store, 1)
+	if err := restored.Restore(false); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if _, err := restored.Get("old"); err == nil || len(restored.List()) != maxFinishedJobs {
+		t.Errorf("expected the restored jobs to be pruned, got %d jobs", len(restored.List()))
+	}
+}
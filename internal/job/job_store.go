@@ -0,0 +1,98 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/sink"
+)
+
+// JobStore persists batch jobs so that queued and interrupted jobs survive
+// application restarts. Each job is stored as an individual JSON file named
+// {id}.json.
+type JobStore struct {
+	storagePath string
+}
+
+// NewJobStore creates a JobStore that keeps job files in the given directory.
+// It creates the storage directory if it does not exist.
+func NewJobStore(storagePath string) (*JobStore, error) {
+	if err := os.MkdirAll(storagePath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create job storage directory: %w", err)
+	}
+	return &JobStore{storagePath: storagePath}, nil
+}
+
+// Save writes the job to disk, without the credentials of the run's sinks.
+// The file is written to a temporary name and renamed into place so a crash
+// mid-write never leaves a truncated record.
+func (js *JobStore) Save(j model.BatchJob) error {
+	j.Params.Sinks = sink.Redact(j.Params.Sinks)
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	path := js.filePath(j.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
+
+// LoadAll reads every stored job sorted by CreatedAt ascending, so queued
+// jobs can be re-enqueued in their original order.
+func (js *JobStore) LoadAll() ([]model.BatchJob, error) {
+	entries, err := os.ReadDir(js.storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job storage directory: %w", err)
+	}
+
+	var jobs []model.BatchJob
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(js.storagePath, entry.Name()))
+		if err != nil {
+			continue // skip unreadable files
+		}
+		var j model.BatchJob
+		if err := json.Unmarshal(data, &j); err != nil || j.ID == "" {
+			continue // skip corrupted files
+		}
+		jobs = append(jobs, j)
+	}
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].CreatedAt.Before(jobs[k].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// Delete removes a stored job by ID. Missing files are not an error.
+func (js *JobStore) Delete(id string) error {
+	if err := os.Remove(js.filePath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
+
+// filePath returns the full file path for a job by ID.
+// It validates the ID to prevent path traversal attacks.
+func (js *JobStore) filePath(id string) string {
+	clean := filepath.Base(id)
+	if clean == "." || clean == ".." || clean == "" {
+		clean = "_invalid_"
+	}
+	return filepath.Join(js.storagePath, clean+".json")
+}
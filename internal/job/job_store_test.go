@@ -0,0 +1,189 @@
+package job
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 14: 任务持久化往返
+// For any batch job, saving it to the store then loading all jobs should
+// return an equivalent job.
+func TestProperty14_JobStoreRoundTrip(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		tmpDir, err := os.MkdirTemp("", "job-store-test-*")
+		if err != nil {
+			rt.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		store, err := NewJobStore(tmpDir)
+		if err != nil {
+			rt.Fatalf("failed to create JobStore: %v", err)
+		}
+
+		original := model.BatchJob{
+			ID:        rapid.StringMatching(`[a-zA-Z0-9-]{1,36}`).Draw(rt, "id"),
+			ProjectID: rapid.StringMatching(`[a-zA-Z0-9-]{1,36}`).Draw(rt, "projectID"),
+			Params: model.BatchParams{
+				InputDir:       rapid.String().Draw(rt, "inputDir"),
+				OutputDir:      rapid.String().Draw(rt, "outputDir"),
+				OutputFileName: rapid.String().Draw(rt, "outputName"),
+			},
+			Status:    rapid.SampledFrom([]string{"queued", "running", "completed", "failed", "cancelled"}).Draw(rt, "status"),
+			Attempts:  rapid.IntRange(0, 5).Draw(rt, "attempts"),
+			CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		if err := store.Save(original); err != nil {
+			rt.Fatalf("failed to save job: %v", err)
+		}
+		jobs, err := store.LoadAll()
+		if err != nil {
+			rt.Fatalf("failed to load jobs: %v", err)
+		}
+		if len(jobs) != 1 {
+			rt.Fatalf("expected 1 job, got %d", len(jobs))
+		}
+		got := jobs[0]
+		if got.ID != original.ID || got.ProjectID != original.ProjectID || got.Status != original.Status {
+			rt.Fatalf("job mismatch: got %+v, want %+v", got, original)
+		}
+		if !reflect.DeepEqual(got.Params, original.Params) {
+			rt.Fatalf("params mismatch: got %+v, want %+v", got.Params, original.Params)
+		}
+		if got.Attempts != original.Attempts {
+			rt.Fatalf("attempts mismatch: got %d, want %d", got.Attempts, original.Attempts)
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// newTestStore creates a JobStore in a temp directory removed after the test.
+func newTestStore(t *testing.T) *JobStore {
+	t.Helper()
+	store, err := NewJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create JobStore: %v", err)
+	}
+	return store
+}
+
+// Unit test: stored jobs keep the run's sinks without their credentials,
+// which the job being run still has
+func TestJobStore_RedactsSinkCredentials(t *testing.T) {
+	store := newTestStore(t)
+	j := model.BatchJob{ID: "j1", Params: model.BatchParams{Sinks: []model.SinkConfig{
+		{Type: "webhook", URL: "https://hook", Token: "secret"},
+	}}}
+	if err := store.Save(j); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	jobs, _ := store.LoadAll()
+	if len(jobs) != 1 || len(jobs[0].Params.Sinks) != 1 {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if s := jobs[0].Params.Sinks[0]; s.Token != "" || !s.Redacted || s.URL != "https://hook" {
+		t.Errorf("stored sink = %+v", s)
+	}
+	if j.Params.Sinks[0].Token != "secret" {
+		t.Error("saving redacted the job being run")
+	}
+}
+
+// Unit test: restored queued and running jobs are re-enqueued in order
+func TestRestore_ResumesQueuedAndRunningJobs(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = store.Save(model.BatchJob{ID: "a", ProjectID: "p", Status: "running", Attempts: 1, CreatedAt: base})
+	_ = store.Save(model.BatchJob{ID: "b", ProjectID: "p", Status: "queued", CreatedAt: base.Add(time.Minute)})
+	_ = store.Save(model.BatchJob{ID: "c", ProjectID: "p", Status: "completed", CreatedAt: base.Add(2 * time.Minute)})
+
+	var order []string
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		order = append(order, j.ID)
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, store, 1)
+	if err := jm.Restore(true); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+
+	if len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Fatalf("expected jobs a then b to run, got %v", order)
+	}
+	got, _ := jm.Get("a")
+	if got.Attempts != 2 {
+		t.Fatalf("expected resumed job to have 2 attempts, got %d", got.Attempts)
+	}
+
+	// The finished state must be persisted
+	jobs, _ := store.LoadAll()
+	for _, j := range jobs {
+		if j.Status != "completed" {
+			t.Fatalf("expected persisted job %s completed, got %q", j.ID, j.Status)
+		}
+	}
+}
+
+// Unit test: running jobs are marked interrupted when resuming is disabled
+func TestRestore_MarksInterruptedJobs(t *testing.T) {
+	store := newTestStore(t)
+	_ = store.Save(model.BatchJob{ID: "a", ProjectID: "p", Status: "running", CreatedAt: time.Now()})
+
+	ran := false
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		ran = true
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, store, 1)
+	if err := jm.Restore(false); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	got, _ := jm.Get("a")
+	if got.Status != "interrupted" || got.FinishedAt == nil {
+		t.Fatalf("expected interrupted job with finish time, got %+v", got)
+	}
+	if ran {
+		t.Fatal("interrupted job should not run")
+	}
+	jobs, _ := store.LoadAll()
+	if len(jobs) != 1 || jobs[0].Status != "interrupted" {
+		t.Fatalf("expected persisted interrupted job, got %+v", jobs)
+	}
+}
+
+// Unit test: paused managers hold queued jobs until unpaused
+func TestJobManager_Paused(t *testing.T) {
+	runner := func(ctx context.Context, j model.BatchJob, report func(p *model.BatchProgress)) (*model.BatchResult, error) {
+		return &model.BatchResult{}, nil
+	}
+
+	jm := NewJobManager(runner, newTestStore(t), 1)
+	jm.SetPaused(true)
+	j, _ := jm.Submit("p", "", model.BatchParams{})
+	time.Sleep(20 * time.Millisecond)
+	if got, _ := jm.Get(j.ID); got.Status != "queued" {
+		t.Fatalf("expected job to stay queued while paused, got %q", got.Status)
+	}
+
+	jm.SetPaused(false)
+	waitFor(t, time.Second, func() bool { return allDone(jm) })
+	if err := jm.Delete(j.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := jm.Get(j.ID); err == nil {
+		t.Fatal("expected deleted job to be gone")
+	}
+}
@@ -1,7 +1,10 @@
 // Package model defines shared data types used across the application.
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // LLMConfig holds configuration for the LLM API connection.
 type LLMConfig struct {
@@ -12,32 +15,173 @@ type LLMConfig struct {
 
 // Settings holds global application settings.
 type Settings struct {
-	LLM              LLMConfig `json:"llm"`
-	UvPath           string    `json:"uv_path"`
-	DefaultInputDir  string    `json:"default_input_dir"`
-	DefaultOutputDir string    `json:"default_output_dir"`
-	SampleLines      int       `json:"sample_lines,omitempty"`
-	ShowWizard       *bool     `json:"show_wizard,omitempty"`
-	Language         string    `json:"language,omitempty"` // "zh-CN" or "en"
+	LLM                   LLMConfig `json:"llm"`
+	UvPath                string    `json:"uv_path"`
+	DefaultInputDir       string    `json:"default_input_dir"`
+	DefaultOutputDir      string    `json:"default_output_dir"`
+	SampleLines           int       `json:"sample_lines,omitempty"`
+	ShowWizard            *bool     `json:"show_wizard,omitempty"`
+	Language              string    `json:"language,omitempty"` // "zh-CN" or "en"
+	MaxConcurrentJobs     int       `json:"max_concurrent_jobs,omitempty"`
+	ResumeInterruptedJobs *bool     `json:"resume_interrupted_jobs,omitempty"` // re-queue jobs cut off by an exit (default true)
+	ScriptTimeoutMinutes  int       `json:"script_timeout_minutes,omitempty"`  // wall-clock limit of a script run; 0 for none
+	ScriptIdleMinutes     int       `json:"script_idle_minutes,omitempty"`     // stop a script that prints no progress for this long; 0 for none
+	ScriptMemoryMB        int       `json:"script_memory_mb,omitempty"`        // memory limit of a script process (Linux); 0 for none
+	ScriptNice            int       `json:"script_nice,omitempty"`             // CPU priority of scripts from 0 (normal) to 19 (lowest) (Linux)
+	AutoApplyRepairs      bool      `json:"auto_apply_repairs,omitempty"`      // save code fixed during a run to the project without asking
+	GeoIPCityDB           string    `json:"geoip_city_db,omitempty"`           // MaxMind City or Country database (.mmdb) for IP enrichment
+	GeoIPASNDB            string    `json:"geoip_asn_db,omitempty"`            // MaxMind ASN database (.mmdb) for IP enrichment
 }
 
-
 // Project represents a single code generation project record.
 type Project struct {
-	ID         string    `json:"id"`
-	Name       string    `json:"name"`
-	SampleData string    `json:"sample_data"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	SampleData string            `json:"sample_data"`
+	Code       string            `json:"code"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Status     string            `json:"status"`              // "draft", "validated", "executed", "failed"
+	Revisions  []CodeRevision    `json:"revisions,omitempty"` // earlier versions of Code, oldest first
+	Sinks      []SinkConfig      `json:"sinks,omitempty"`     // services every run ships its rows to
+	Schema     []ColumnSchema    `json:"schema,omitempty"`    // declared types of output columns; others are inferred from their values
+	Schedules  []Schedule        `json:"schedules,omitempty"` // runs started on a timetable while the application is open
+	Quality    QualityThresholds `json:"quality"`             // data quality every run must reach
+	TimeZones  TimeZones         `json:"time_zones"`          // zones the times of every run are normalized with
+}
+
+// ColumnSchema declares the type of an output column.
+type ColumnSchema struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // "string", "integer", "number", "boolean", "datetime" or "ip"
+}
+
+// QualityThresholds are the data quality a project's runs must reach, as
+// percentages; nil ones aren't checked. The column thresholds hold for
+// every column, or only the listed ones.
+type QualityThresholds struct {
+	MaxEmpty        *float64 `json:"max_empty,omitempty"`         // rows of a column without a value
+	MaxMismatched   *float64 `json:"max_mismatched,omitempty"`    // values of a column not of its type
+	MaxTimeFailures *float64 `json:"max_time_failures,omitempty"` // values of the time columns that aren't times
+	MaxOutOfOrder   *float64 `json:"max_out_of_order,omitempty"`  // rows whose time is earlier than the row before
+	MaxFuture       *float64 `json:"max_future,omitempty"`        // rows whose time is in the future
+	MaxDuplicates   *float64 `json:"max_duplicates,omitempty"`    // rows repeating an earlier row of their file
+	Columns         []string `json:"columns,omitempty"`           // columns MaxEmpty and MaxMismatched hold for; empty for all
+	Action          string   `json:"action,omitempty"`            // when rows fall short: "degrade" (default) marks the run degraded, "fail" fails it, "repair" has the code repaired and run again
+}
+
+// TimeZones normalize the times of a run's rows: times written without an
+// offset are taken to be in the source zone of their file, and every time
+// is converted to the target zone. Zones are IANA names such as
+// "Asia/Shanghai", "UTC", "Local" for this computer's zone, or offsets such
+// as "+08:00". Without a target zone times are left as written.
+type TimeZones struct {
+	Source string     `json:"source,omitempty"` // zone of times without an offset; empty for the target zone
+	Files  []FileZone `json:"files,omitempty"`  // source zones of the input files matching a pattern, before Source
+	Target string     `json:"target,omitempty"` // zone times are converted to
+	Format string     `json:"format,omitempty"` // "datetime" (default): the time in the target zone without an offset, a date cell in workbooks; "iso": ISO 8601 with the offset, text in workbooks
+}
+
+// FileZone is the source zone of the input files whose name matches a
+// glob pattern, such as "fw-berlin-*.log" or "branch-a/*".
+type FileZone struct {
+	Pattern string `json:"pattern"`
+	Zone    string `json:"zone"`
+}
+
+// Schedule runs a project on a timetable. Its runs are queued as batch jobs
+// while the application is open.
+type Schedule struct {
+	ID          string `json:"id"`
+	Cron        string `json:"cron"` // five-field cron expression in local time, or a macro such as @daily
+	InputDir    string `json:"input_dir"`
+	OutputDir   string `json:"output_dir"`
+	OutputName  string `json:"output_name,omitempty"` // output file name template; empty uses "{project}_{date}_{time}"
+	Incremental bool   `json:"incremental,omitempty"` // process only new or changed files
+	Summary     bool   `json:"summary,omitempty"`     // sum up the rows on a Summary sheet; not for incremental runs
+	Enabled     bool   `json:"enabled"`
+	MissedRuns  string `json:"missed_runs,omitempty"` // runs due while the application was closed: "skip" (default) or "catch_up" to run the latest one on start
+}
+
+// ScheduleState is what a schedule has done so far.
+type ScheduleState struct {
+	LastSlot  time.Time  `json:"last_slot"`          // latest time the schedule came due, run or not
+	LastRun   *time.Time `json:"last_run,omitempty"` // when it last queued a run
+	LastJobID string     `json:"last_job_id,omitempty"`
+	LastError string     `json:"last_error,omitempty"` // why the last run due couldn't be queued
+	Missed    int        `json:"missed,omitempty"`     // times due without a run
+}
+
+// ScheduleStatus is a schedule with its state and the time it next comes
+// due.
+type ScheduleStatus struct {
+	Schedule Schedule      `json:"schedule"`
+	State    ScheduleState `json:"state"`
+	Next     *time.Time    `json:"next,omitempty"` // nil when disabled or invalid
+}
+
+// SinkConfig configures a service a run ships its parsed rows to, besides
+// or instead of the output files.
+type SinkConfig struct {
+	Type         string            `json:"type"`                    // "elasticsearch", "opensearch", "loki", "webhook", "splunk" or "clickhouse"
+	URL          string            `json:"url"`                     // base URL of the service
+	Index        string            `json:"index,omitempty"`         // elasticsearch/opensearch: target index, empty uses the project name; splunk: index, empty uses the token's default
+	Table        string            `json:"table,omitempty"`         // clickhouse: target table, optionally as database.table
+	SourceType   string            `json:"source_type,omitempty"`   // splunk: sourcetype; empty uses the project name
+	Labels       map[string]string `json:"labels,omitempty"`        // loki: stream labels added to job, project and file; webhook: labels of every request; splunk: indexed fields
+	LabelColumns []string          `json:"label_columns,omitempty"` // loki: columns whose values become stream labels
+	TimeColumn   string            `json:"time_column,omitempty"`   // column with each row's time; empty uses the first date/time column
+	Username     string            `json:"username,omitempty"`      // basic authentication
+	Password     string            `json:"password,omitempty"`
+	APIKey       string            `json:"api_key,omitempty"`      // elasticsearch/opensearch: API key, instead of a password
+	TenantID     string            `json:"tenant_id,omitempty"`    // loki: X-Scope-OrgID of a multi-tenant Loki
+	Token        string            `json:"token,omitempty"`        // splunk: HEC token; webhook: bearer token
+	Headers      map[string]string `json:"headers,omitempty"`      // headers added to every request, such as custom authentication
+	TLSInsecure  bool              `json:"tls_insecure,omitempty"` // skip verifying the server certificate
+	CAFile       string            `json:"ca_file,omitempty"`      // PEM file of the CAs to trust besides the system's
+	CertFile     string            `json:"cert_file,omitempty"`    // PEM client certificate, with KeyFile
+	KeyFile      string            `json:"key_file,omitempty"`
+	DeadLetter   string            `json:"dead_letter,omitempty"` // file collecting undelivered rows; relative to the output directory, empty uses {output name}.deadletter.jsonl
+	BatchSize    int               `json:"batch_size,omitempty"`  // rows per request; 0 uses 500
+	MaxRetries   int               `json:"max_retries,omitempty"` // attempts after a failed request; 0 uses 3, negative never retries
+	MaxPending   int               `json:"max_pending,omitempty"` // batches queued before the run waits for the service; 0 uses 4
+	Redacted     bool              `json:"redacted,omitempty"`    // credentials were removed before the config was stored; it no longer validates
+}
+
+// SinkResult is how many rows of a run reached a sink.
+type SinkResult struct {
+	Type       string `json:"type"`
+	Target     string `json:"target"`                // URL, with the index or table where there is one
+	Sent       int    `json:"sent"`                  // rows the service accepted
+	Failed     int    `json:"failed"`                // rows it refused or that couldn't be delivered
+	Error      string `json:"error,omitempty"`       // first failure
+	DeadLetter string `json:"dead_letter,omitempty"` // file the failed rows were written to
+}
+
+// CodeRevision is an earlier version of a project's code.
+type CodeRevision struct {
 	Code       string    `json:"code"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	Status     string    `json:"status"` // "draft", "validated", "executed", "failed"
+	Reason     string    `json:"reason"` // what replaced it: "edit" or "repair"
+	ReplacedAt time.Time `json:"replaced_at"`
 }
 
 // ProjectUpdate holds optional fields for partial project updates.
 type ProjectUpdate struct {
-	Name   *string `json:"name,omitempty"`
-	Code   *string `json:"code,omitempty"`
-	Status *string `json:"status,omitempty"`
+	Name       *string            `json:"name,omitempty"`
+	Code       *string            `json:"code,omitempty"`
+	CodeReason string             `json:"code_reason,omitempty"` // revision reason of a code change; "edit" when empty
+	Status     *string            `json:"status,omitempty"`
+	Sinks      *[]SinkConfig      `json:"sinks,omitempty"`
+	Schema     *[]ColumnSchema    `json:"schema,omitempty"`
+	Schedules  *[]Schedule        `json:"schedules,omitempty"`
+	Quality    *QualityThresholds `json:"quality,omitempty"`
+	TimeZones  *TimeZones         `json:"time_zones,omitempty"`
+}
+
+// DiffLine is one line of a line-by-line diff between two versions of code.
+type DiffLine struct {
+	Op   string `json:"op"` // "equal", "add" or "remove"
+	Text string `json:"text"`
 }
 
 // GenerateResult holds the result of a code generation operation.
@@ -50,22 +194,333 @@ type GenerateResult struct {
 
 // BatchResult holds the summary of a batch processing run.
 type BatchResult struct {
-	TotalFiles int      `json:"total_files"`
-	Succeeded  int      `json:"succeeded"`
-	Failed     int      `json:"failed"`
-	OutputPath string   `json:"output_path"`
-	Errors     []string `json:"errors,omitempty"`
+	TotalFiles     int              `json:"total_files"`
+	Succeeded      int              `json:"succeeded"`
+	Failed         int              `json:"failed"`
+	OutputPath     string           `json:"output_path"`
+	Errors         []string         `json:"errors,omitempty"`
+	Skipped        []SkippedFile    `json:"skipped,omitempty"`         // files left out of an incremental run
+	Files          []FileResult     `json:"files,omitempty"`           // per-file outcome in processing order
+	Log            []LogEntry       `json:"log,omitempty"`             // warnings, log events and other script output, most recent last
+	Coverage       *float64         `json:"coverage,omitempty"`        // percentage of lines parsed into rows, over files that report rows
+	Degraded       bool             `json:"degraded,omitempty"`        // the run finished but parsed too few lines
+	RejectedPath   string           `json:"rejected_path,omitempty"`   // CSV of the lines the script rejected, next to the workbook
+	Failure        string           `json:"failure,omitempty"`         // resource limit that stopped the run, as in BatchProgress
+	RepairedCode   string           `json:"repaired_code,omitempty"`   // code a runtime repair produced, when the run succeeded with it
+	CodeApplied    bool             `json:"code_applied,omitempty"`    // RepairedCode was saved to the project
+	RepairAttempts int              `json:"repair_attempts,omitempty"` // repairs requested from the LLM during the run
+	Stderr         string           `json:"stderr,omitempty"`          // end of the last attempt's stderr output
+	Outputs        []string         `json:"outputs,omitempty"`         // files the run wrote, in every selected format
+	Sheets         []SheetPlacement `json:"sheets,omitempty"`          // where each file's rows went in the workbooks LogForge wrote
+	Sinks          []SinkResult     `json:"sinks,omitempty"`           // rows shipped to each sink
+	Summary        *RunSummary      `json:"summary,omitempty"`         // statistics of the rows, for runs that ask for them
+	Quality        *QualityReport   `json:"quality,omitempty"`         // data quality of the rows the script streamed
+	TimeZones      *AppliedZones    `json:"time_zones,omitempty"`      // zones the times were normalized with
+}
+
+// AppliedZones records how the times of a run were normalized. Zones are
+// named as configured, "Local" with the offset it had.
+type AppliedZones struct {
+	Source    string            `json:"source"`          // zone of times without an offset
+	Files     map[string]string `json:"files,omitempty"` // input files whose times were in another zone, with that zone
+	Target    string            `json:"target"`
+	Format    string            `json:"format"`
+	Converted int               `json:"converted"` // time values normalized
+}
+
+// SheetPlacement records a worksheet holding rows of an input file. A file
+// past Excel's row limit, or past the run's rows per workbook, spans several
+// sheets, possibly in several workbooks.
+type SheetPlacement struct {
+	File     string `json:"file"`
+	Workbook string `json:"workbook"` // path of the workbook
+	Sheet    string `json:"sheet"`
+	Rows     int    `json:"rows"` // data rows on the sheet
+}
+
+// RunSummary sums up the rows of a run: what the Summary sheet shows.
+// Times are as the script wrote them.
+type RunSummary struct {
+	Rows        int             `json:"rows"`
+	From        string          `json:"from,omitempty"` // earliest time of the rows
+	To          string          `json:"to,omitempty"`   // latest time of the rows
+	Files       []FileSummary   `json:"files,omitempty"`
+	Columns     []ColumnSummary `json:"columns,omitempty"`
+	TopIPs      []TopValues     `json:"top_ips,omitempty"`     // most frequent addresses of each IP column
+	Statuses    []TopValues     `json:"statuses,omitempty"`    // distribution of each status or action column
+	Hourly      []HourCount     `json:"hourly,omitempty"`      // rows per hour of their time
+	Errors      []ValueCount    `json:"errors,omitempty"`      // most frequent error messages
+	Approximate bool            `json:"approximate,omitempty"` // some columns had too many distinct values to count exactly; their counts are lower bounds
+}
+
+// FileSummary is the rows of an input file and the time they cover.
+type FileSummary struct {
+	File       string `json:"file"`
+	Rows       int    `json:"rows"`
+	TimeColumn string `json:"time_column,omitempty"` // column the time range and hours are taken from
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+}
+
+// ColumnSummary is the type of a column and how many rows have a value
+// in it.
+type ColumnSummary struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Declared bool   `json:"declared,omitempty"` // the type comes from the schema rather than the values
+	Values   int    `json:"values"`
+}
+
+// TopValues is the most frequent values of a column.
+type TopValues struct {
+	Column string       `json:"column"`
+	Total  int          `json:"total"` // values in the column
+	Values []ValueCount `json:"values"`
+}
+
+// ValueCount is how often a value occurs.
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// HourCount is the rows of an hour, given as "2006-01-02 15:00:00".
+type HourCount struct {
+	Hour  string `json:"hour"`
+	Count int    `json:"count"`
+}
+
+// QualityReport is the data quality of the rows of a run.
+type QualityReport struct {
+	Rows         int             `json:"rows"`
+	Columns      []ColumnQuality `json:"columns,omitempty"`
+	Files        []FileQuality   `json:"files,omitempty"`
+	TimeFailures int             `json:"time_failures"`         // values of the time columns that aren't times
+	OutOfOrder   int             `json:"out_of_order"`          // rows whose time is earlier than the row before
+	Future       int             `json:"future"`                // rows whose time is in the future
+	Duplicates   int             `json:"duplicates"`            // rows repeating an earlier row of their file
+	Approximate  bool            `json:"approximate,omitempty"` // too many rows to compare every one; Duplicates is a lower bound
+	Violations   []string        `json:"violations,omitempty"`  // thresholds of the project the rows fell short of
+}
+
+// ColumnQuality is the data quality of a column.
+type ColumnQuality struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"` // declared, or of most values; datetime for columns named as times
+	Declared     bool     `json:"declared,omitempty"`
+	Rows         int      `json:"rows"`          // rows of the files with the column
+	Empty        int      `json:"empty"`         // rows without a value or with blank text
+	Mismatched   int      `json:"mismatched"`    // values not of Type
+	TimeFailures int      `json:"time_failures"` // of a time column, values that aren't times
+	Examples     []string `json:"examples,omitempty"`
+}
+
+// FileQuality is the data quality of the rows of a file. Times are those
+// of its first time column.
+type FileQuality struct {
+	File       string `json:"file"`
+	Rows       int    `json:"rows"`
+	TimeColumn string `json:"time_column,omitempty"`
+	OutOfOrder int    `json:"out_of_order"`
+	Future     int    `json:"future"`
+	Duplicates int    `json:"duplicates"`
+}
+
+// RunRecord is the history entry of one batch run of a project.
+type RunRecord struct {
+	ID             string           `json:"id"`
+	ProjectID      string           `json:"project_id"`
+	JobID          string           `json:"job_id,omitempty"`
+	Params         BatchParams      `json:"params"`
+	OutputFile     string           `json:"output_file"` // workbook the run wrote to
+	Status         string           `json:"status"`      // "completed", "degraded", "failed", "cancelled"
+	StartedAt      time.Time        `json:"started_at"`
+	FinishedAt     time.Time        `json:"finished_at"`
+	TotalFiles     int              `json:"total_files"`
+	Succeeded      int              `json:"succeeded"`
+	Failed         int              `json:"failed"`
+	Files          []FileResult     `json:"files,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	Stderr         string           `json:"stderr,omitempty"` // end of the script's stderr output
+	RepairAttempts int              `json:"repair_attempts,omitempty"`
+	CodeHash       string           `json:"code_hash"`               // SHA-256 of the code the run started with
+	RepairedCode   bool             `json:"repaired_code,omitempty"` // the run ended with repaired code
+	Outputs        []string         `json:"outputs,omitempty"`       // files the run wrote, in every selected format
+	Sheets         []SheetPlacement `json:"sheets,omitempty"`        // where each file's rows went in the workbooks
+	Sinks          []SinkResult     `json:"sinks,omitempty"`         // rows shipped to each sink
+	Summary        *RunSummary      `json:"summary,omitempty"`       // statistics of the rows
+	Quality        *QualityReport   `json:"quality,omitempty"`       // data quality of the rows
+	TimeZones      *AppliedZones    `json:"time_zones,omitempty"`    // zones the times were normalized with
+	ScheduleID     string           `json:"schedule_id,omitempty"`   // schedule that started the run
+	ScheduledFor   *time.Time       `json:"scheduled_for,omitempty"` // time the schedule came due
+}
+
+// LogEntry is a message a script reported or printed during a run.
+type LogEntry struct {
+	Level   string `json:"level"` // "info", "warning" or "error"
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// FileResult records how the script handled a single input file.
+type FileResult struct {
+	File         string   `json:"file"`
+	Status       string   `json:"status"`                  // "succeeded", "empty" (no rows from a non-empty file), "failed"
+	Rows         *int     `json:"rows,omitempty"`          // rows written; nil when the script doesn't report them
+	SkippedLines int      `json:"skipped_lines,omitempty"` // lines the script couldn't parse
+	Rejected     int      `json:"rejected,omitempty"`      // skipped lines written to the quarantine file
+	Coverage     *float64 `json:"coverage,omitempty"`      // percentage of lines parsed into rows; nil without reported rows
+	Warnings     int      `json:"warnings,omitempty"`      // warning events reported for the file
+	Error        string   `json:"error,omitempty"`
+	DurationMs   int64    `json:"duration_ms"`
+	Bytes        int64    `json:"bytes"` // input file size
+}
+
+// SkippedFile records an input file that a run did not process and why.
+type SkippedFile struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
 }
 
 // BatchProgress holds the current state of a batch processing operation.
 type BatchProgress struct {
-	Status      string  `json:"status"` // "running", "completed", "failed", "fixing"
-	CurrentFile string  `json:"current_file"`
-	Progress    float64 `json:"progress"`
-	TotalFiles  int     `json:"total_files"`
-	Processed   int     `json:"processed"`
-	Failed      int     `json:"failed"`
-	Message     string  `json:"message"`
+	Status      string       `json:"status"` // "queued", "running", "watching", "completed", "degraded", "failed", "fixing", "cancelled"
+	CurrentFile string       `json:"current_file"`
+	Progress    float64      `json:"progress"`
+	TotalFiles  int          `json:"total_files"`
+	Processed   int          `json:"processed"`
+	Failed      int          `json:"failed"`
+	Message     string       `json:"message"`
+	Rows        int          `json:"rows,omitempty"`    // rows written so far, when the script reports them
+	Files       []FileResult `json:"files,omitempty"`   // results of the files finished so far
+	Failure     string       `json:"failure,omitempty"` // why a failed run was stopped: "timeout", "idle" or "memory"; empty for script errors
+}
+
+// BatchParams holds the parameters of a single batch run.
+type BatchParams struct {
+	InputDir        string             `json:"input_dir"`
+	OutputDir       string             `json:"output_dir"`
+	OutputFileName  string             `json:"output_file_name"`
+	Workers         int                `json:"workers,omitempty"`           // parallel Python processes; 0 or 1 runs a single process
+	Incremental     bool               `json:"incremental,omitempty"`       // only process files that are new or changed since the last run
+	Watch           bool               `json:"watch,omitempty"`             // keep watching the input directory until stopped
+	WatchDebounce   int                `json:"watch_debounce,omitempty"`    // seconds of quiet before a watch run starts; 0 uses the default
+	Filter          *FileFilter        `json:"filter,omitempty"`            // file selection; nil reads the top level of InputDir
+	Formats         []string           `json:"formats,omitempty"`           // output formats: "xlsx", "csv", "jsonl", "parquet", "sqlite"; empty writes xlsx
+	MaxWorkbookRows int                `json:"max_workbook_rows,omitempty"` // data rows per workbook before rows continue in another; 0 means no limit
+	Sinks           []SinkConfig       `json:"sinks,omitempty"`             // services the run ships its rows to, besides the project's
+	NoProjectSinks  bool               `json:"no_project_sinks,omitempty"`  // leave out the project's sinks
+	SinksOnly       bool               `json:"sinks_only,omitempty"`        // ship rows to the sinks without writing output files
+	Enrich          *EnrichParams      `json:"enrich,omitempty"`            // columns added for the IP addresses in the rows; nil adds none
+	Schema          []ColumnSchema     `json:"schema,omitempty"`            // declared column types; the app adds the project's for the run
+	Summary         bool               `json:"summary,omitempty"`           // sum up the rows on a Summary sheet, or in a report beside the output
+	Quality         *QualityThresholds `json:"quality,omitempty"`           // data quality the rows must reach; the app adds the project's for the run
+	TimeZones       *TimeZones         `json:"time_zones,omitempty"`        // zones of the times; nil uses the project's
+}
+
+// EnrichParams selects the columns a run adds for each IP column of its
+// rows: the columns declared as IPs in the schema and the columns whose
+// values are IP addresses. The app fills in the databases and language from
+// the settings.
+type EnrichParams struct {
+	Classify bool   `json:"classify,omitempty"` // add the address range: public, private, loopback and so on
+	GeoIP    bool   `json:"geoip,omitempty"`    // add country, city, ASN and organization from the databases
+	CityDB   string `json:"city_db,omitempty"`
+	ASNDB    string `json:"asn_db,omitempty"`
+	Language string `json:"language,omitempty"` // language of country and city names; English when missing
+}
+
+// FileFilter selects the input files of a batch run. Go resolves the
+// selection so it doesn't depend on how a generated script lists files.
+type FileFilter struct {
+	Recursive      bool       `json:"recursive,omitempty"`       // descend into subdirectories
+	Include        []string   `json:"include,omitempty"`         // glob patterns; a file must match one if any are given
+	Exclude        []string   `json:"exclude,omitempty"`         // glob patterns; matching files and directories are skipped
+	MinSize        int64      `json:"min_size,omitempty"`        // bytes; 0 means no minimum
+	MaxSize        int64      `json:"max_size,omitempty"`        // bytes; 0 means no maximum
+	ModifiedAfter  *time.Time `json:"modified_after,omitempty"`  // keep files modified at or after this time
+	ModifiedBefore *time.Time `json:"modified_before,omitempty"` // keep files modified before this time
+}
+
+// PreviewParams selects the input a preview run reads. A preview runs the
+// project's code on the first lines of each file and writes nothing to the
+// output directory.
+type PreviewParams struct {
+	InputDir string        `json:"input_dir"`
+	Files    []string      `json:"files,omitempty"`     // input file names to preview; empty previews every selected file
+	Filter   *FileFilter   `json:"filter,omitempty"`    // file selection, as in BatchParams
+	Lines    int           `json:"lines,omitempty"`     // lines read from the start of each file; 0 uses 100
+	Enrich   *EnrichParams `json:"enrich,omitempty"`    // columns added for the IP addresses, as in BatchParams
+	PageSize int           `json:"page_size,omitempty"` // rows per table page; 0 uses 50
+}
+
+// Preview holds the rows a preview run produced, one table per input file.
+type Preview struct {
+	ID     string         `json:"id"`
+	Tables []PreviewTable `json:"tables"`
+	Log    []LogEntry     `json:"log,omitempty"`
+}
+
+// PreviewTable is one page of the rows the script wrote for an input file.
+type PreviewTable struct {
+	File         string        `json:"file"`                    // input file; empty for sheets not named after one
+	Sheet        string        `json:"sheet,omitempty"`         // worksheet the rows were read from
+	Columns      []string      `json:"columns"`                 // header row
+	EmptyColumns []int         `json:"empty_columns,omitempty"` // indexes of columns without a value in any row
+	Rows         [][]string    `json:"rows"`                    // rows of the current page
+	TotalRows    int           `json:"total_rows"`
+	Page         int           `json:"page"` // zero-based
+	PageSize     int           `json:"page_size"`
+	Skipped      []SkippedLine `json:"skipped,omitempty"`       // lines the script rejected
+	SkippedLines int           `json:"skipped_lines,omitempty"` // lines the script reported as unparsed
+}
+
+// SkippedLine is an input line a script rejected.
+type SkippedLine struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason,omitempty"`
+	Text   string `json:"text"`
+}
+
+// FileRecord describes an input file as it was when last processed.
+type FileRecord struct {
+	Path        string    `json:"path"`
+	Name        string    `json:"name,omitempty"` // name the file is staged and its sheet named under
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	Hash        string    `json:"hash"` // hex SHA-256 of the content; "" when recorded by a full run
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// FileManifest lists the files a project's last runs have written into an
+// output workbook. It is what incremental runs compare the input directory
+// against.
+type FileManifest struct {
+	ProjectID  string                `json:"project_id"`
+	InputDir   string                `json:"input_dir"`
+	OutputPath string                `json:"output_path"` // workbook the recorded files were written to
+	Files      map[string]FileRecord `json:"files"`       // keyed by absolute path
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// BatchJob represents a batch run tracked by the job manager. Each job has its
+// own progress and result so that several batches can run side by side.
+type BatchJob struct {
+	ID           string        `json:"id"`
+	ProjectID    string        `json:"project_id"`
+	ProjectName  string        `json:"project_name"`
+	Params       BatchParams   `json:"params"`
+	Status       string        `json:"status"`             // "queued", "running", "completed", "degraded", "failed", "cancelled", "interrupted"
+	Attempts     int           `json:"attempts,omitempty"` // number of times the job was started
+	Progress     BatchProgress `json:"progress"`
+	Result       *BatchResult  `json:"result,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	StartedAt    *time.Time    `json:"started_at,omitempty"`
+	FinishedAt   *time.Time    `json:"finished_at,omitempty"`
+	ScheduleID   string        `json:"schedule_id,omitempty"`   // schedule that queued the job
+	ScheduledFor *time.Time    `json:"scheduled_for,omitempty"` // time the schedule came due
 }
 
 // LogFileSample holds the result of browsing a log file for sample lines.
@@ -82,9 +537,38 @@ type Message struct {
 }
 
 // ProgressInfo represents progress output from the Python processing script (stdout JSON).
+// Scripts print one line per finished file; the per-file fields are optional
+// so scripts generated before they existed keep working.
 type ProgressInfo struct {
 	File     string  `json:"file"`
 	Progress float64 `json:"progress"`
 	Total    int     `json:"total"`
 	Current  int     `json:"current"`
+	Status   string  `json:"status,omitempty"`  // "ok" or "error"
+	Rows     *int    `json:"rows,omitempty"`    // rows written for the file
+	Skipped  int     `json:"skipped,omitempty"` // lines that couldn't be parsed
+	Error    string  `json:"error,omitempty"`   // why the file failed
+}
+
+// ProgressEvent is a line of the versioned stdout protocol scripts speak
+// through the injected logforge module. Lines without an event are read as
+// ProgressInfo, so scripts generated before the protocol keep working.
+type ProgressEvent struct {
+	V       int               `json:"v"`
+	Event   string            `json:"event"` // "start", "file_start", "columns", "row", "rows", "warning", "reject", "log", "file_done", "done"
+	File    string            `json:"file,omitempty"`
+	Index   int               `json:"index,omitempty"`
+	Total   int               `json:"total,omitempty"`
+	Status  string            `json:"status,omitempty"` // file_done: "ok" or "error"
+	Rows    *int              `json:"rows,omitempty"`
+	Skipped int               `json:"skipped,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Level   string            `json:"level,omitempty"`   // log: "info", "warning" or "error"
+	Line    int               `json:"line,omitempty"`    // warning, reject: line number in File
+	Reason  string            `json:"reason,omitempty"`  // reject: why the line was rejected
+	Text    string            `json:"text,omitempty"`    // reject: the rejected line
+	Files   int               `json:"files,omitempty"`   // done: number of files processed
+	Columns []string          `json:"columns,omitempty"` // columns: the column names of File's rows
+	Values  []json.RawMessage `json:"values,omitempty"`  // row: one value per column
 }
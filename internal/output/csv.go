@@ -0,0 +1,45 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// utf8BOM starts CSV files so Excel opens them as UTF-8.
+const utf8BOM = "\xef\xbb\xbf"
+
+// csvFile writes a CSV file with a header row.
+type csvFile struct {
+	raw    io.Writer
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVFile(w io.Writer, columns []string) (tableWriter, error) {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvFile{raw: w, w: cw, record: make([]string, len(columns))}, nil
+}
+
+func (c *csvFile) write(values []any) error {
+	for i, v := range values {
+		c.record[i] = Text(v)
+	}
+	if len(c.record) == 1 && c.record[0] == "" {
+		// A blank line would be read as no row at all
+		c.w.Flush()
+		_, err := io.WriteString(c.raw, "\"\"\n")
+		return err
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvFile) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
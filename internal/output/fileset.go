@@ -0,0 +1,165 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// tableWriter writes the rows of one input file in a per-file format.
+type tableWriter interface {
+	write(values []any) error
+	// close writes whatever the format needs after the last row.
+	close() error
+}
+
+// tableDiscarder is implemented by table writers that hold files of their
+// own until close.
+type tableDiscarder interface {
+	// discard removes those files when the table is abandoned.
+	discard()
+}
+
+// openTable starts a table with the given columns on w.
+type openTable func(w io.Writer, columns []string) (tableWriter, error)
+
+// fileSet writes one file per input file into a directory. Each file is
+// written under a hidden temporary name and renamed once the set is closed.
+type fileSet struct {
+	dir   string
+	ext   string
+	open  openTable
+	names *uniqueNames
+	files map[string]*tableFile
+	order []*tableFile
+}
+
+// tableFile is the output file of one input file.
+type tableFile struct {
+	path    string
+	tmp     string
+	f       *os.File
+	buf     *bufio.Writer
+	table   tableWriter
+	columns int
+}
+
+func newFileSet(dir string, ext string, open openTable) *fileSet {
+	return &fileSet{dir: dir, ext: ext, open: open, names: newUniqueNames(), files: make(map[string]*tableFile)}
+}
+
+func (fs *fileSet) StartFile(file string, columns []string) error {
+	tf := fs.files[file]
+	if tf == nil {
+		if err := os.MkdirAll(fs.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		name := fs.names.name(file, fileStem(file))
+		tf = &tableFile{path: filepath.Join(fs.dir, name+fs.ext)}
+		fs.files[file] = tf
+		fs.order = append(fs.order, tf)
+	} else {
+		tf.discard()
+	}
+
+	f, err := CreateTemp(tf.path)
+	if err != nil {
+		return fmt.Errorf("failed to create output for %s: %w", file, err)
+	}
+	tf.f, tf.tmp, tf.buf = f, f.Name(), bufio.NewWriterSize(f, 64*1024)
+	columns = Columns(columns)
+	tf.columns = len(columns)
+	if tf.table, err = fs.open(tf.buf, columns); err != nil {
+		tf.discard()
+		return fmt.Errorf("failed to write output for %s: %w", file, err)
+	}
+	return nil
+}
+
+func (fs *fileSet) WriteRow(file string, values []any) error {
+	tf := fs.files[file]
+	if tf == nil {
+		return fmt.Errorf("rows for %s before its columns", file)
+	}
+	if tf.f == nil {
+		return fmt.Errorf("rows for %s after it ended", file)
+	}
+	if err := tf.table.write(fitRow(values, tf.columns)); err != nil {
+		return fmt.Errorf("failed to write output for %s: %w", file, err)
+	}
+	return nil
+}
+
+func (fs *fileSet) EndFile(file string) error {
+	tf := fs.files[file]
+	if tf == nil {
+		return nil
+	}
+	return tf.finish()
+}
+
+func (fs *fileSet) Close() ([]string, error) {
+	var firstErr error
+	for _, tf := range fs.order {
+		if err := tf.finish(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		fs.Abort()
+		return nil, firstErr
+	}
+
+	paths := make([]string, 0, len(fs.order))
+	for _, tf := range fs.order {
+		if err := os.Rename(tf.tmp, tf.path); err != nil {
+			fs.Abort()
+			return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(tf.path), err)
+		}
+		paths = append(paths, tf.path)
+	}
+	return paths, nil
+}
+
+func (fs *fileSet) Abort() {
+	for _, tf := range fs.order {
+		tf.discard()
+	}
+}
+
+// finish completes the table and closes its file, keeping the temporary
+// file for Close to rename.
+func (tf *tableFile) finish() error {
+	if tf.f == nil {
+		return nil
+	}
+	err := tf.table.close()
+	if ferr := tf.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := tf.f.Close(); err == nil {
+		err = cerr
+	}
+	tf.f = nil
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(tf.path), err)
+	}
+	return nil
+}
+
+// discard closes and removes the temporary file.
+func (tf *tableFile) discard() {
+	if tf.f != nil {
+		if d, ok := tf.table.(tableDiscarder); ok {
+			d.discard()
+		}
+		tf.f.Close()
+		tf.f = nil
+	}
+	if tf.tmp != "" {
+		os.Remove(tf.tmp)
+		tf.tmp = ""
+	}
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// jsonlFile writes one JSON object per row, with the keys in column order.
+type jsonlFile struct {
+	w    io.Writer
+	keys [][]byte // columns encoded as JSON strings
+	line bytes.Buffer
+}
+
+func newJSONLFile(w io.Writer, columns []string) (tableWriter, error) {
+	keys := make([][]byte, len(columns))
+	for i, c := range columns {
+		key, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return &jsonlFile{w: w, keys: keys}, nil
+}
+
+func (j *jsonlFile) write(values []any) error {
+	j.line.Reset()
+	j.line.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			j.line.WriteByte(',')
+		}
+		j.line.Write(j.keys[i])
+		j.line.WriteByte(':')
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		j.line.Write(value)
+	}
+	j.line.WriteString("}\n")
+	_, err := j.w.Write(j.line.Bytes())
+	return err
+}
+
+func (j *jsonlFile) close() error {
+	return nil
+}
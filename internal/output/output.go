@@ -0,0 +1,247 @@
+// Package output writes the rows scripts stream over the progress protocol
+// in the formats of a batch, the workbook among them, so a batch's output
+// doesn't depend on the generated code.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats a batch can write.
+const (
+	XLSX    = "xlsx"
+	CSV     = "csv"
+	JSONL   = "jsonl"
+	Parquet = "parquet"
+	SQLite  = "sqlite"
+)
+
+// Formats lists every supported output format.
+var Formats = []string{XLSX, CSV, JSONL, Parquet, SQLite}
+
+// Writer writes the rows of a run in one format. Rows are grouped by the
+// input file they were parsed from and the rows of different files may
+// interleave. Starting a file again discards the rows written for it so far,
+// so a retried script can emit a file anew. Output is written under
+// temporary names and only moved into place by Close.
+type Writer interface {
+	// StartFile begins the rows of an input file with the given columns.
+	StartFile(file string, columns []string) error
+	// WriteRow writes a row of a started file, one value per column. Values
+	// are nil, bool, string or json.Number.
+	WriteRow(file string, values []any) error
+	// EndFile flushes a file whose rows are complete.
+	EndFile(file string) error
+	// Close finishes every file and returns the paths written.
+	Close() ([]string, error)
+	// Abort removes everything written so far.
+	Abort()
+}
+
+// Options tunes the writers of a run.
+type Options struct {
+	// MaxWorkbookRows is how many data rows a workbook holds before the
+	// rows continue in another workbook; 0 keeps every row in one.
+	MaxWorkbookRows int
+	// TextTimes writes dates and times as text in workbooks rather than as
+	// date cells, which keeps their offset.
+	TextTimes bool
+}
+
+// New returns a writer for format that writes into outputDir: per-file
+// formats into a directory named outputName, one file per input file,
+// XLSX into the workbook outputName.xlsx with a sheet per input file and
+// SQLite into the database outputName.sqlite with a table per input file.
+func New(format string, outputDir string, outputName string, opts Options) (Writer, error) {
+	dir := filepath.Join(outputDir, outputName)
+	switch format {
+	case XLSX:
+		w, err := newXLSXWriter(filepath.Join(outputDir, outputName+".xlsx"), opts.MaxWorkbookRows)
+		if err != nil {
+			return nil, err
+		}
+		w.textTimes = opts.TextTimes
+		return w, nil
+	case CSV:
+		return newFileSet(dir, ".csv", newCSVFile), nil
+	case JSONL:
+		return newFileSet(dir, ".jsonl", newJSONLFile), nil
+	case Parquet:
+		return newFileSet(dir, ".parquet", newParquetFile), nil
+	case SQLite:
+		w, err := newSQLiteWriter(filepath.Join(outputDir, outputName+".sqlite"))
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+	return nil, fmt.Errorf("unsupported output format %q", format)
+}
+
+// timeLayouts are the ISO 8601 forms ParseTime accepts. Parsing accepts
+// fractional seconds after the seconds of a layout.
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+}
+
+// ParseTime parses a value streamed as an ISO 8601 date, or date and time
+// with optional fraction and offset, as the generation prompt asks scripts
+// to write them. Times without an offset are taken to be in loc. dateOnly
+// reports a date without a time.
+func ParseTime(s string, loc *time.Location) (t time.Time, dateOnly bool, ok bool) {
+	if len(s) < 10 || len(s) > 35 || s[4] != '-' || s[7] != '-' {
+		return time.Time{}, false, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, len(layout) == len("2006-01-02"), true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// PerFile reports whether format writes one file per input file into the
+// directory named after the output.
+func PerFile(format string) bool {
+	return format == CSV || format == JSONL || format == Parquet
+}
+
+// Extension returns the file extension of a per-file format.
+func Extension(format string) string {
+	return "." + format
+}
+
+// CreateTemp creates the temporary file an output is written to before it is
+// renamed to path: hidden, next to path and named after it, but unique, so
+// runs writing the same output never share one.
+func CreateTemp(path string) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// Outputs get the permissions of a created file, not those of a temp file
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// Validate checks that formats are known and listed once.
+func Validate(formats []string) error {
+	seen := make(map[string]bool, len(formats))
+	for _, f := range formats {
+		known := false
+		for _, k := range Formats {
+			known = known || f == k
+		}
+		if !known {
+			return fmt.Errorf("unsupported output format %q", f)
+		}
+		if seen[f] {
+			return fmt.Errorf("output format %q is listed twice", f)
+		}
+		seen[f] = true
+	}
+	return nil
+}
+
+// Text returns a value as text: numbers as written by the script, booleans
+// as true or false and nil as the empty string.
+func Text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// Columns returns the columns names with empty names replaced by
+// "column_N" and repeated names made unique with a numeric suffix, as
+// tables and schemas require. Names are compared case-insensitively.
+func Columns(columns []string) []string {
+	out := make([]string, len(columns))
+	used := make(map[string]bool, len(columns))
+	for i, c := range columns {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			c = fmt.Sprintf("column_%d", i+1)
+		}
+		name := c
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", c, n)
+		}
+		used[strings.ToLower(name)] = true
+		out[i] = name
+	}
+	return out
+}
+
+// fitRow returns values padded with nil or cut to n values.
+func fitRow(values []any, n int) []any {
+	if len(values) == n {
+		return values
+	}
+	row := make([]any, n)
+	copy(row, values)
+	return row
+}
+
+// uniqueNames hands out names derived from input file names that are unique
+// case-insensitively, as on Windows and macOS file systems.
+type uniqueNames struct {
+	byFile map[string]string
+	used   map[string]bool
+}
+
+func newUniqueNames() *uniqueNames {
+	return &uniqueNames{byFile: make(map[string]string), used: make(map[string]bool)}
+}
+
+// name returns the name assigned to file, assigning base with a " (n)"
+// suffix if that is already taken by another file.
+func (u *uniqueNames) name(file string, base string) string {
+	if name, ok := u.byFile[file]; ok {
+		return name
+	}
+	name := base
+	for n := 2; u.used[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s (%d)", base, n)
+	}
+	u.used[strings.ToLower(name)] = true
+	u.byFile[file] = name
+	return name
+}
+
+// fileStem returns a file name safe on every platform for an input file
+// name: path separators and characters Windows rejects become "_".
+func fileStem(file string) string {
+	stem := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, file)
+	stem = strings.TrimRight(stem, ". ")
+	if stem == "" {
+		stem = "_"
+	}
+	return stem
+}
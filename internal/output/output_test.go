@@ -0,0 +1,299 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// readCSV reads a CSV file written by csvFile.
+func readCSV(t interface{ Fatalf(string, ...interface{}) }, file string) [][]string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", file, err)
+	}
+	if !strings.HasPrefix(string(data), utf8BOM) {
+		t.Fatalf("%s has no BOM", file)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(data[len(utf8BOM):]))).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", file, err)
+	}
+	return records
+}
+
+// readJSONL reads a JSON Lines file, returning each line's keys in order
+// and its values.
+func readJSONL(t interface{ Fatalf(string, ...interface{}) }, file string) ([][]string, [][]any) {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", file, err)
+	}
+	defer f.Close()
+	var keys [][]string
+	var values [][]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		dec.UseNumber()
+		dec.Token()
+		var k []string
+		var v []any
+		for dec.More() {
+			key, _ := dec.Token()
+			var value any
+			if err := dec.Decode(&value); err != nil {
+				t.Fatalf("bad line %q: %v", scanner.Text(), err)
+			}
+			k = append(k, key.(string))
+			v = append(v, value)
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return keys, values
+}
+
+// Feature: network-log-formatter, Property 24: 流式行按格式完整写出
+// For any files with interleaved rows, the CSV and JSON Lines outputs hold
+// one file per input file with its header and every row in order, values
+// intact and short rows padded.
+func TestProperty24_StreamedRowsAreWritten(t *testing.T) {
+	base := t.TempDir()
+	iteration := 0
+	rapid.Check(t, func(rt *rapid.T) {
+		iteration++
+		dir := filepath.Join(base, fmt.Sprint(iteration))
+		csvOut, _ := New(CSV, dir, "out", Options{})
+		jsonlOut, _ := New(JSONL, dir, "out", Options{})
+		writers := []Writer{csvOut, jsonlOut}
+
+		files := rapid.SliceOfNDistinct(rapid.StringMatching(`[a-z]{1,6}\.log`), 1, 4, rapid.ID[string]).Draw(rt, "files")
+		columns := make(map[string][]string)
+		for _, file := range files {
+			n := rapid.IntRange(1, 4).Draw(rt, "columns")
+			cols := make([]string, n)
+			for i := range cols {
+				cols[i] = fmt.Sprintf("c%d", i)
+			}
+			columns[file] = cols
+			for _, w := range writers {
+				if err := w.StartFile(file, cols); err != nil {
+					rt.Fatalf("StartFile: %v", err)
+				}
+			}
+		}
+
+		want := make(map[string][][]any)
+		count := rapid.IntRange(0, 40).Draw(rt, "rows")
+		for i := 0; i < count; i++ {
+			file := rapid.SampledFrom(files).Draw(rt, "file")
+			n := rapid.IntRange(0, len(columns[file])).Draw(rt, "values")
+			row := make([]any, len(columns[file]))
+			for j := 0; j < n; j++ {
+				switch rapid.IntRange(0, 3).Draw(rt, "kind") {
+				case 0:
+					row[j] = rapid.StringMatching(`[a-z0-9 ,"\n中]{0,10}`).Draw(rt, "text")
+				case 1:
+					row[j] = json.Number(fmt.Sprint(rapid.Int64().Draw(rt, "number")))
+				case 2:
+					row[j] = rapid.Bool().Draw(rt, "bool")
+				}
+			}
+			for _, w := range writers {
+				if err := w.WriteRow(file, row[:n]); err != nil {
+					rt.Fatalf("WriteRow: %v", err)
+				}
+			}
+			want[file] = append(want[file], row)
+		}
+		for _, w := range writers {
+			if _, err := w.Close(); err != nil {
+				rt.Fatalf("Close: %v", err)
+			}
+		}
+
+		for _, file := range files {
+			records := readCSV(rt, filepath.Join(dir, "out", file+".csv"))
+			if !reflect.DeepEqual(records[0], columns[file]) {
+				rt.Fatalf("%s: header %v, want %v", file, records[0], columns[file])
+			}
+			if len(records)-1 != len(want[file]) {
+				rt.Fatalf("%s: %d CSV rows, want %d", file, len(records)-1, len(want[file]))
+			}
+			keys, values := readJSONL(rt, filepath.Join(dir, "out", file+".jsonl"))
+			if len(values) != len(want[file]) {
+				rt.Fatalf("%s: %d JSON lines, want %d", file, len(values), len(want[file]))
+			}
+			for i, row := range want[file] {
+				for j, v := range row {
+					if records[i+1][j] != Text(v) {
+						rt.Fatalf("%s row %d: CSV %q, want %q", file, i, records[i+1][j], Text(v))
+					}
+				}
+				if !reflect.DeepEqual(keys[i], columns[file]) || !reflect.DeepEqual(values[i], row) {
+					rt.Fatalf("%s row %d: JSON %v %v, want %v", file, i, keys[i], values[i], row)
+				}
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: restarting a file discards its rows and nothing is visible before Close
+func TestFileSet_RestartAndClose(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(CSV, dir, "out", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.StartFile("a.log", []string{"x"})
+	w.WriteRow("a.log", []any{"old"})
+	w.StartFile("a.log", []string{"y"})
+	w.WriteRow("a.log", []any{"new"})
+	w.EndFile("a.log")
+	if err := w.WriteRow("a.log", []any{"late"}); err == nil {
+		t.Error("expected an error for a row after the file ended")
+	}
+	if err := w.WriteRow("b.log", []any{"x"}); err == nil {
+		t.Error("expected an error for a row before the columns")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "a.log.csv")); !os.IsNotExist(err) {
+		t.Errorf("output visible before Close: %v", err)
+	}
+
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("paths = %v", paths)
+	}
+	if got := readCSV(t, paths[0]); !reflect.DeepEqual(got, [][]string{{"y"}, {"new"}}) {
+		t.Errorf("records = %v", got)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "out"))
+	if len(entries) != 1 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+// Unit test: Abort leaves nothing behind
+func TestFileSet_Abort(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(JSONL, dir, "out", Options{})
+	w.StartFile("a.log", []string{"x"})
+	w.WriteRow("a.log", []any{"v"})
+	w.Abort()
+	entries, _ := os.ReadDir(filepath.Join(dir, "out"))
+	if len(entries) != 0 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+// Unit test: writers of the same output use their own temporary files, so
+// both finish and the output is complete
+func TestFileSet_SameOutput(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := New(CSV, dir, "out", Options{})
+	second, _ := New(CSV, dir, "out", Options{})
+	first.StartFile("a.log", []string{"x"})
+	second.StartFile("a.log", []string{"x"})
+	first.WriteRow("a.log", []any{"first"})
+	second.WriteRow("a.log", []any{"second"})
+
+	if _, err := first.Close(); err != nil {
+		t.Fatalf("Close first: %v", err)
+	}
+	paths, err := second.Close()
+	if err != nil {
+		t.Fatalf("Close second: %v", err)
+	}
+	if got := readCSV(t, paths[0]); !reflect.DeepEqual(got, [][]string{{"x"}, {"second"}}) {
+		t.Errorf("records = %v", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "out")); len(entries) != 1 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+// Unit test: file names are made safe and unique case-insensitively
+func TestFileSet_Names(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(CSV, dir, "out", Options{})
+	for _, file := range []string{"a.log", "A.log", `x/y:z?.log`, "trail. "} {
+		w.StartFile(file, []string{"x"})
+	}
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	var names []string
+	for _, p := range paths {
+		names = append(names, filepath.Base(p))
+	}
+	want := []string{"a.log.csv", "A.log (2).csv", "x_y_z_.log.csv", "trail.csv"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+}
+
+// Unit test: empty and repeated column names
+func TestColumns(t *testing.T) {
+	got := Columns([]string{"a", "", "A", " b ", "a"})
+	want := []string{"a", "column_2", "A_2", "b", "a_3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns = %v, want %v", got, want)
+	}
+}
+
+// Unit test: format validation
+func TestValidate(t *testing.T) {
+	if err := Validate([]string{XLSX, CSV, SQLite}); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if err := Validate([]string{"xml"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if err := Validate([]string{CSV, CSV}); err == nil {
+		t.Error("expected an error for a repeated format")
+	}
+	if _, err := New("xml", t.TempDir(), "out", Options{}); err == nil {
+		t.Error("expected New to reject an unknown format")
+	}
+}
+
+// Unit test: ISO 8601 dates and times, with and without offset
+func TestParseTime(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	cases := []struct {
+		in       string
+		want     time.Time
+		dateOnly bool
+		ok       bool
+	}{
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, loc), true, true},
+		{"2024-03-01 08:30:00", time.Date(2024, 3, 1, 8, 30, 0, 0, loc), false, true},
+		{"2024-03-01T08:30:00.250", time.Date(2024, 3, 1, 8, 30, 0, 250e6, loc), false, true},
+		{"2024-03-01T08:30:00Z", time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC), false, true},
+		{"2024-03-01T08:30:00+02:00", time.Date(2024, 3, 1, 6, 30, 0, 0, time.UTC), false, true},
+		{"2024/03/01", time.Time{}, false, false},
+		{"10.0.0.1", time.Time{}, false, false},
+	}
+	for _, c := range cases {
+		got, dateOnly, ok := ParseTime(c.in, loc)
+		if ok != c.ok || dateOnly != c.dateOnly || (ok && !got.Equal(c.want)) {
+			t.Errorf("ParseTime(%q) = %v, %v, %v; want %v, %v, %v", c.in, got, dateOnly, ok, c.want, c.dateOnly, c.ok)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	parquetMagic = "PAR1"
+	// maxRowGroupRows and maxRowGroupBytes bound the rows buffered before a
+	// row group is written.
+	maxRowGroupRows  = 64 * 1024
+	maxRowGroupBytes = 64 << 20
+)
+
+// Parquet enum values written by parquetFile.
+const (
+	parquetBoolean   = 0  // Type BOOLEAN
+	parquetInt64     = 2  // Type INT64
+	parquetDouble    = 5  // Type DOUBLE
+	parquetByteArray = 6  // Type BYTE_ARRAY
+	parquetOptional  = 1  // FieldRepetitionType OPTIONAL
+	parquetUTF8      = 0  // ConvertedType UTF8
+	parquetMicros    = 10 // ConvertedType TIMESTAMP_MICROS
+	parquetDataPage  = 0  // PageType DATA_PAGE
+	parquetPlain     = 0  // Encoding PLAIN
+	parquetRLE       = 3  // Encoding RLE
+)
+
+// Column kinds of a Parquet file, from the values of the column.
+const (
+	kindNone      = iota // only nulls so far
+	kindBoolean          // BOOLEAN
+	kindInteger          // INT64
+	kindNumber           // DOUBLE
+	kindTime             // INT64 TIMESTAMP(MICROS), adjusted to UTC
+	kindLocalTime        // INT64 TIMESTAMP(MICROS) of times without an offset
+	kindString           // UTF-8 BYTE_ARRAY
+)
+
+// parquetFile writes a Parquet file whose columns have the narrowest type
+// all their values fit: integers, other numbers, booleans, date-times as
+// timestamps, and UTF-8 strings for everything else, nil values staying
+// null. A column's type depends on every value, so the rows go to a spool
+// file first and are encoded by close, into row groups of one uncompressed,
+// plainly encoded data page per column. Date-times with an offset are
+// stored adjusted to UTC; a column of date-times without one keeps their
+// wall-clock times.
+type parquetFile struct {
+	w       *countingWriter
+	columns []string
+	kinds   []int
+	spool   *os.File
+	enc     *json.Encoder
+	buf     *bufio.Writer
+	groups  []parquetRowGroup
+	total   int64
+}
+
+type parquetRowGroup struct {
+	rows   int
+	bytes  int64
+	chunks []parquetChunk
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+func newParquetFile(w io.Writer, columns []string) (tableWriter, error) {
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, parquetMagic); err != nil {
+		return nil, err
+	}
+	spool, err := os.CreateTemp("", "logforge-parquet-*")
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriterSize(spool, 64*1024)
+	return &parquetFile{
+		w:       cw,
+		columns: columns,
+		kinds:   make([]int, len(columns)),
+		spool:   spool,
+		enc:     json.NewEncoder(buf),
+		buf:     buf,
+	}, nil
+}
+
+func (p *parquetFile) write(values []any) error {
+	for i, v := range values {
+		p.kinds[i] = mergeKinds(p.kinds[i], valueKind(v))
+	}
+	return p.enc.Encode(values)
+}
+
+// valueKind returns the kind of column a value fits.
+func valueKind(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return kindNone
+	case bool:
+		return kindBoolean
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return kindInteger
+		}
+		if _, err := v.Float64(); err == nil {
+			return kindNumber
+		}
+	case string:
+		t, dateOnly, ok := ParseTime(v, time.UTC)
+		if !ok || dateOnly {
+			break
+		}
+		// A time with an offset is the same instant in any zone
+		if other, _, _ := ParseTime(v, time.FixedZone("", 3600)); other.Equal(t) {
+			return kindTime
+		}
+		return kindLocalTime
+	}
+	return kindString
+}
+
+// mergeKinds returns the kind of a column holding values of kinds a and b.
+func mergeKinds(a, b int) int {
+	switch {
+	case a == kindNone || a == b:
+		return b
+	case b == kindNone:
+		return a
+	case (a == kindInteger && b == kindNumber) || (a == kindNumber && b == kindInteger):
+		return kindNumber
+	}
+	return kindString
+}
+
+// close encodes the spooled rows and writes the file metadata.
+func (p *parquetFile) close() error {
+	defer p.discard()
+	if err := p.buf.Flush(); err != nil {
+		return err
+	}
+	if _, err := p.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bufio.NewReaderSize(p.spool, 64*1024))
+	dec.UseNumber()
+	g := newParquetGroup(len(p.columns))
+	for {
+		var values []any
+		if err := dec.Decode(&values); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read spooled rows: %w", err)
+		}
+		for i, v := range values {
+			g.add(i, p.kinds[i], v)
+		}
+		g.rows++
+		if g.rows >= maxRowGroupRows || g.size >= maxRowGroupBytes {
+			if err := p.flush(g); err != nil {
+				return err
+			}
+			g = newParquetGroup(len(p.columns))
+		}
+	}
+	if err := p.flush(g); err != nil {
+		return err
+	}
+	return p.writeMetadata()
+}
+
+// discard closes and removes the spool file.
+func (p *parquetFile) discard() {
+	if p.spool != nil {
+		p.spool.Close()
+		os.Remove(p.spool.Name())
+		p.spool = nil
+	}
+}
+
+// parquetGroup buffers the encoded values of a row group.
+type parquetGroup struct {
+	data [][]byte // plain encoded non-null values per column
+	defs [][]byte // definition level of every row per column
+	bits []int    // non-null values of each boolean column
+	rows int
+	size int // bytes buffered
+}
+
+func newParquetGroup(columns int) *parquetGroup {
+	return &parquetGroup{
+		data: make([][]byte, columns),
+		defs: make([][]byte, columns),
+		bits: make([]int, columns),
+	}
+}
+
+// add appends a value of column i, of the given kind, to the group.
+func (g *parquetGroup) add(i int, kind int, v any) {
+	if v == nil {
+		g.defs[i] = append(g.defs[i], 0)
+		return
+	}
+	g.defs[i] = append(g.defs[i], 1)
+	before := len(g.data[i])
+	switch kind {
+	case kindBoolean:
+		// Booleans are bit-packed, least significant bit first
+		if g.bits[i]%8 == 0 {
+			g.data[i] = append(g.data[i], 0)
+		}
+		if v.(bool) {
+			g.data[i][len(g.data[i])-1] |= 1 << (g.bits[i] % 8)
+		}
+		g.bits[i]++
+	case kindInteger:
+		n, _ := v.(json.Number).Int64()
+		g.data[i] = binary.LittleEndian.AppendUint64(g.data[i], uint64(n))
+	case kindNumber:
+		f, _ := v.(json.Number).Float64()
+		g.data[i] = binary.LittleEndian.AppendUint64(g.data[i], math.Float64bits(f))
+	case kindTime, kindLocalTime:
+		// Wall-clock times are stored as if they were UTC
+		t, _, _ := ParseTime(v.(string), time.UTC)
+		g.data[i] = binary.LittleEndian.AppendUint64(g.data[i], uint64(t.UnixMicro()))
+	default:
+		text := Text(v)
+		g.data[i] = binary.LittleEndian.AppendUint32(g.data[i], uint32(len(text)))
+		g.data[i] = append(g.data[i], text...)
+	}
+	g.size += len(g.data[i]) - before
+}
+
+// flush writes the buffered rows as a row group.
+func (p *parquetFile) flush(g *parquetGroup) error {
+	if g.rows == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: g.rows}
+	for i := range p.columns {
+		levels := rleLevels(g.defs[i])
+		page := make([]byte, 4, 4+len(levels)+len(g.data[i]))
+		binary.LittleEndian.PutUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+		page = append(page, g.data[i]...)
+
+		t := &thriftWriter{}
+		t.begin()
+		t.i32(1, parquetDataPage)
+		t.i32(2, int32(len(page)))
+		t.i32(3, int32(len(page)))
+		t.structField(5)
+		t.i32(1, int32(g.rows))
+		t.i32(2, parquetPlain)
+		t.i32(3, parquetRLE)
+		t.i32(4, parquetRLE)
+		t.end()
+		t.end()
+
+		chunk := parquetChunk{offset: p.w.n, size: int64(len(t.buf) + len(page))}
+		if _, err := p.w.Write(t.buf); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.bytes += chunk.size
+	}
+	p.groups = append(p.groups, group)
+	p.total += int64(g.rows)
+	return nil
+}
+
+// writeMetadata writes the file metadata and the footer.
+func (p *parquetFile) writeMetadata() error {
+	t := &thriftWriter{}
+	t.begin()
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.end()
+	for i, c := range p.columns {
+		t.begin()
+		t.i32(1, physicalType(p.kinds[i]))
+		t.i32(3, parquetOptional)
+		t.binary(4, c)
+		switch p.kinds[i] {
+		case kindTime:
+			t.i32(6, parquetMicros)
+			timestampType(t, true)
+		case kindLocalTime:
+			timestampType(t, false)
+		case kindString, kindNone:
+			t.i32(6, parquetUTF8)
+			t.structField(10) // LogicalType
+			t.structField(1)  // STRING
+			t.end()
+			t.end()
+		}
+		t.end()
+	}
+	t.i64(3, p.total)
+	t.list(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.begin()
+		t.list(1, thriftStruct, len(g.chunks))
+		for i, c := range g.chunks {
+			t.begin()
+			t.i64(2, c.offset)
+			t.structField(3) // ColumnMetaData
+			t.i32(1, physicalType(p.kinds[i]))
+			t.list(2, thriftI32, 2)
+			t.i32Elem(parquetPlain)
+			t.i32Elem(parquetRLE)
+			t.list(3, thriftBinary, 1)
+			t.binaryElem(p.columns[i])
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, int64(g.rows))
+			t.i64(6, c.size)
+			t.i64(7, c.size)
+			t.i64(9, c.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.bytes)
+		t.i64(3, int64(g.rows))
+		t.end()
+	}
+	t.binary(6, "LogForge")
+	t.end()
+
+	if _, err := p.w.Write(t.buf); err != nil {
+		return err
+	}
+	footer := binary.LittleEndian.AppendUint32(nil, uint32(len(t.buf)))
+	if _, err := p.w.Write(append(footer, parquetMagic...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// physicalType returns the Parquet type of a column kind.
+func physicalType(kind int) int32 {
+	switch kind {
+	case kindBoolean:
+		return parquetBoolean
+	case kindInteger, kindTime, kindLocalTime:
+		return parquetInt64
+	case kindNumber:
+		return parquetDouble
+	}
+	return parquetByteArray
+}
+
+// timestampType writes the logical type of a timestamp column in
+// microseconds.
+func timestampType(t *thriftWriter, adjustedToUTC bool) {
+	t.structField(10) // LogicalType
+	t.structField(8)  // TIMESTAMP
+	t.boolean(1, adjustedToUTC)
+	t.structField(2) // TimeUnit
+	t.structField(2) // MICROS
+	t.end()
+	t.end()
+	t.end()
+	t.end()
+}
+
+// rleLevels encodes definition levels of bit width 1 as runs of the
+// RLE/bit-packing hybrid encoding.
+func rleLevels(levels []byte) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		out = append(out, levels[i])
+		i = j
+	}
+	return out
+}
+
+// countingWriter counts the bytes written, for the offsets in the metadata.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,276 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// thriftReader decodes the Thrift compact protocol into maps of field id to
+// value, lists into slices, integers into int64 and binaries into strings.
+type thriftReader struct {
+	b []byte
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case thriftI32, thriftI64:
+		v := r.uvarint()
+		return int64(v>>1) ^ -int64(v&1)
+	case thriftBinary:
+		n := r.uvarint()
+		s := string(r.b[:n])
+		r.b = r.b[n:]
+		return s
+	case thriftList:
+		h := r.b[0]
+		r.b = r.b[1:]
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unexpected thrift type %d", typ))
+}
+
+func (r *thriftReader) structure() map[int16]any {
+	fields := map[int16]any{}
+	var id int16
+	for {
+		h := r.b[0]
+		r.b = r.b[1:]
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			v := r.uvarint()
+			id = int16(int64(v>>1) ^ -int64(v&1))
+		}
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+// readParquet reads the columns, their types and the rows of a file written
+// by parquetFile, with nil for null values, int64 for INT64 values,
+// float64 for DOUBLE, bool for BOOLEAN and string for BYTE_ARRAY.
+func readParquet(t *testing.T, file string) ([]string, []int64, [][]any) {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", file, err)
+	}
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("missing magic")
+	}
+	size := binary.LittleEndian.Uint32(data[len(data)-8:])
+	meta := (&thriftReader{b: data[len(data)-8-int(size) : len(data)-8]}).structure()
+
+	var columns []string
+	var types []int64
+	for _, e := range meta[2].([]any)[1:] {
+		columns = append(columns, e.(map[int16]any)[4].(string))
+		types = append(types, e.(map[int16]any)[1].(int64))
+	}
+	var rows [][]any
+	for _, g := range meta[4].([]any) {
+		group := g.(map[int16]any)
+		n := int(group[3].(int64))
+		start := len(rows)
+		for i := 0; i < n; i++ {
+			rows = append(rows, make([]any, len(columns)))
+		}
+		for c, ch := range group[1].([]any) {
+			offset := ch.(map[int16]any)[3].(map[int16]any)[9].(int64)
+			r := &thriftReader{b: data[offset:]}
+			header := r.structure()
+			page := r.b[:header[2].(int64)]
+
+			levelsLen := binary.LittleEndian.Uint32(page)
+			levels := &thriftReader{b: page[4 : 4+levelsLen]}
+			values := page[4+levelsLen:]
+			row, bit := start, 0
+			for len(levels.b) > 0 {
+				run := int(levels.uvarint() >> 1)
+				level := levels.b[0]
+				levels.b = levels.b[1:]
+				for i := 0; i < run; i++ {
+					if level == 1 {
+						switch types[c] {
+						case parquetBoolean:
+							rows[row][c] = values[bit/8]&(1<<(bit%8)) != 0
+							bit++
+						case parquetInt64:
+							rows[row][c] = int64(binary.LittleEndian.Uint64(values))
+							values = values[8:]
+						case parquetDouble:
+							rows[row][c] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+							values = values[8:]
+						default:
+							l := binary.LittleEndian.Uint32(values)
+							rows[row][c] = string(values[4 : 4+l])
+							values = values[4+l:]
+						}
+					}
+					row++
+				}
+			}
+			if row != start+n {
+				t.Fatalf("column %s has %d levels, want %d", columns[c], row-start, n)
+			}
+		}
+	}
+	if total := int(meta[3].(int64)); total != len(rows) {
+		t.Fatalf("num_rows = %d, read %d", total, len(rows))
+	}
+	return columns, types, rows
+}
+
+// pyarrowRead reads a Parquet file with pyarrow, an independent reader,
+// and returns the schema and the rows as pyarrow prints them.
+func pyarrowRead(t *testing.T, file string) string {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	if exec.Command(python, "-c", "import pyarrow.parquet").Run() != nil {
+		t.Skip("pyarrow not installed")
+	}
+	script := `import sys, pyarrow.parquet as pq
+table = pq.read_table(sys.argv[1])
+for field in table.schema:
+    print(field.name, field.type)
+for row in table.to_pylist():
+    print({k: v.isoformat() if hasattr(v, "isoformat") else v for k, v in row.items()})
+`
+	out, err := exec.Command(python, "-c", script, file).CombinedOutput()
+	if err != nil {
+		t.Fatalf("pyarrow: %v\n%s", err, out)
+	}
+	return strings.TrimRight(string(out), "\n")
+}
+
+// --- Unit Tests ---
+
+// Unit test: values read back with their types and nulls kept, across row
+// groups
+func TestParquetFile_ReadsBack(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", t.TempDir()) // for the spool file
+	w, err := New(Parquet, dir, "out", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.StartFile("a.log", []string{"time", "count", ""})
+	var want [][]any
+	for i := 0; i < maxRowGroupRows+10; i++ {
+		row := []any{fmt.Sprintf("t%d", i), json.Number(fmt.Sprint(i)), nil}
+		read := []any{row[0], int64(i), nil}
+		if i%3 == 0 {
+			row[0], read[0] = nil, nil
+		}
+		if i%5 == 0 {
+			row[2], read[2] = i%2 == 0, i%2 == 0
+		}
+		if err := w.WriteRow("a.log", row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+		want = append(want, read)
+	}
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != filepath.Join(dir, "out", "a.log.parquet") {
+		t.Fatalf("paths = %v", paths)
+	}
+
+	columns, types, rows := readParquet(t, paths[0])
+	if !reflect.DeepEqual(columns, []string{"time", "count", "column_3"}) {
+		t.Errorf("columns = %v", columns)
+	}
+	if !reflect.DeepEqual(types, []int64{parquetByteArray, parquetInt64, parquetBoolean}) {
+		t.Errorf("types = %v", types)
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows differ: got %d rows, want %d", len(rows), len(want))
+	}
+	if spools, _ := filepath.Glob(filepath.Join(os.TempDir(), "logforge-parquet-*")); len(spools) != 0 {
+		t.Errorf("spool files left: %v", spools)
+	}
+}
+
+// Unit test: each column gets the narrowest type all its values fit, and
+// an independent reader reads the types and values back
+func TestParquetFile_Types(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(Parquet, dir, "out", Options{})
+	w.StartFile("a.log", []string{"n", "ratio", "ok", "at", "local", "day", "mixed"})
+	w.WriteRow("a.log", []any{json.Number("42"), json.Number("1"), true, "2024-03-01T08:00:00+08:00", "2024-03-01 08:00:00.5", "2024-03-01", json.Number("7")})
+	w.WriteRow("a.log", []any{nil, json.Number("0.25"), false, "2024-03-01T00:00:01Z", nil, "2024-03-02", "n/a"})
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	_, types, rows := readParquet(t, paths[0])
+	wantTypes := []int64{parquetInt64, parquetDouble, parquetBoolean, parquetInt64, parquetInt64, parquetByteArray, parquetByteArray}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("types = %v, want %v", types, wantTypes)
+	}
+	want := [][]any{
+		{int64(42), 1.0, true, int64(1709251200000000), int64(1709280000500000), "2024-03-01", "7"},
+		{nil, 0.25, false, int64(1709251201000000), nil, "2024-03-02", "n/a"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	got := pyarrowRead(t, paths[0])
+	wantRead := `n int64
+ratio double
+ok bool
+at timestamp[us, tz=UTC]
+local timestamp[us]
+day string
+mixed string
+{'n': 42, 'ratio': 1.0, 'ok': True, 'at': '2024-03-01T00:00:00+00:00', 'local': '2024-03-01T08:00:00.500000', 'day': '2024-03-01', 'mixed': '7'}
+{'n': None, 'ratio': 0.25, 'ok': False, 'at': '2024-03-01T00:00:01+00:00', 'local': None, 'day': '2024-03-02', 'mixed': 'n/a'}`
+	if got != wantRead {
+		t.Errorf("pyarrow read:\n%s\nwant:\n%s", got, wantRead)
+	}
+}
+
+// Unit test: definition levels are encoded as RLE runs
+func TestRLELevels(t *testing.T) {
+	got := rleLevels([]byte{1, 1, 1, 0, 1})
+	want := []byte{6, 1, 2, 0, 2, 1}
+	if !bytes.Equal(got, want) {
+		t.Errorf("rleLevels = %v, want %v", got, want)
+	}
+}
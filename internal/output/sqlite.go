@@ -0,0 +1,514 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sqlitePageSize is the page size of databases written by sqliteWriter.
+const sqlitePageSize = 4096
+
+// sqliteWriter writes a SQLite database with one table per input file. The
+// records of each table are spooled to a temporary file as they arrive and
+// the database file is built from them on Close, table after table, as
+// B-trees of fully packed pages. Columns are declared without a type, so
+// values keep the type the script gave them: integers, reals, text or null.
+type sqliteWriter struct {
+	path   string
+	spool  string // directory of the spool files
+	names  *uniqueNames
+	tables map[string]*sqliteTable
+	order  []*sqliteTable
+}
+
+// sqliteTable is a table being spooled.
+type sqliteTable struct {
+	name    string
+	columns []string
+	f       *os.File
+	buf     *bufio.Writer
+	rows    int64
+}
+
+func newSQLiteWriter(path string) (*sqliteWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	spool, err := os.MkdirTemp("", "sqlite-spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	return &sqliteWriter{path: path, spool: spool, names: newUniqueNames(), tables: make(map[string]*sqliteTable)}, nil
+}
+
+func (sw *sqliteWriter) StartFile(file string, columns []string) error {
+	t := sw.tables[file]
+	if t == nil {
+		f, err := os.CreateTemp(sw.spool, "table-*")
+		if err != nil {
+			return fmt.Errorf("failed to spool rows of %s: %w", file, err)
+		}
+		t = &sqliteTable{name: sw.names.name(file, tableName(file)), f: f, buf: bufio.NewWriterSize(f, 64*1024)}
+		sw.tables[file] = t
+		sw.order = append(sw.order, t)
+	} else {
+		// Start over
+		t.buf.Reset(t.f)
+		if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := t.f.Truncate(0); err != nil {
+			return err
+		}
+		t.rows = 0
+	}
+	t.columns = Columns(columns)
+	return nil
+}
+
+func (sw *sqliteWriter) WriteRow(file string, values []any) error {
+	t := sw.tables[file]
+	if t == nil {
+		return fmt.Errorf("rows for %s before its columns", file)
+	}
+	record := sqliteRecord(fitRow(values, len(t.columns)))
+	var size [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(size[:], uint64(len(record)))
+	t.buf.Write(size[:n])
+	if _, err := t.buf.Write(record); err != nil {
+		return fmt.Errorf("failed to spool rows of %s: %w", file, err)
+	}
+	t.rows++
+	return nil
+}
+
+func (sw *sqliteWriter) EndFile(file string) error {
+	if t := sw.tables[file]; t != nil {
+		return t.buf.Flush()
+	}
+	return nil
+}
+
+func (sw *sqliteWriter) Close() ([]string, error) {
+	defer sw.Abort()
+	f, err := CreateTemp(sw.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(sw.path), err)
+	}
+	f.Close()
+	tmp := f.Name()
+	if err := sw.build(tmp); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(sw.path), err)
+	}
+	if err := os.Rename(tmp, sw.path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(sw.path), err)
+	}
+	return []string{sw.path}, nil
+}
+
+func (sw *sqliteWriter) Abort() {
+	for _, t := range sw.order {
+		t.f.Close()
+	}
+	os.RemoveAll(sw.spool)
+}
+
+// build writes the database to path: the tables first, from page 2 on, then
+// the schema table rooted at page 1 together with the database header.
+func (sw *sqliteWriter) build(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p := &pager{f: f, next: 2}
+
+	schema := newBTree(p)
+	for i, t := range sw.order {
+		if err := t.buf.Flush(); err != nil {
+			return err
+		}
+		if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		tree := newBTree(p)
+		r := bufio.NewReaderSize(t.f, 64*1024)
+		for rowid := int64(1); rowid <= t.rows; rowid++ {
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			record := make([]byte, n)
+			if _, err := io.ReadFull(r, record); err != nil {
+				return err
+			}
+			if err := tree.add(rowid, record); err != nil {
+				return err
+			}
+		}
+		root := p.alloc()
+		if err := tree.finish(root, 0); err != nil {
+			return err
+		}
+		sql := createTableSQL(t.name, t.columns)
+		entry := sqliteRecord([]any{"table", t.name, t.name, json.Number(fmt.Sprint(root)), sql})
+		if err := schema.add(int64(i+1), entry); err != nil {
+			return err
+		}
+	}
+	if err := schema.finish(1, 100); err != nil {
+		return err
+	}
+
+	if _, err := f.WriteAt(sqliteHeader(p.next-1), 0); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// tableName returns the table name for an input file. Names starting with
+// "sqlite_" are reserved for SQLite's own tables.
+func tableName(file string) string {
+	if strings.HasPrefix(strings.ToLower(file), "sqlite_") {
+		return "_" + file
+	}
+	return file
+}
+
+// createTableSQL returns the statement creating a table with untyped
+// columns.
+func createTableSQL(name string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	return "CREATE TABLE " + quoteIdent(name) + " (" + strings.Join(quoted, ", ") + ")"
+}
+
+// quoteIdent quotes an SQL identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// sqliteHeader returns the 100 byte database header of a database of the
+// given number of pages.
+func sqliteHeader(pages uint32) []byte {
+	h := make([]byte, 100)
+	copy(h, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(h[16:], sqlitePageSize)
+	h[18], h[19] = 1, 1 // legacy journal mode
+	h[21], h[22], h[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(h[24:], 1) // file change counter
+	binary.BigEndian.PutUint32(h[28:], pages)
+	binary.BigEndian.PutUint32(h[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(h[44:], 4) // schema format
+	binary.BigEndian.PutUint32(h[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(h[92:], 1) // version-valid-for, matching the change counter
+	binary.BigEndian.PutUint32(h[96:], 3045000)
+	return h
+}
+
+// sqliteRecord encodes values in the SQLite record format.
+func sqliteRecord(values []any) []byte {
+	types := make([]byte, 0, len(values)*2)
+	var body []byte
+	for _, v := range values {
+		var typ uint64
+		switch v := v.(type) {
+		case nil:
+			typ = 0
+		case bool:
+			typ = 8
+			if v {
+				typ = 9
+			}
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				typ, body = appendInt(body, i)
+			} else if f, err := v.Float64(); err == nil {
+				typ = 7
+				body = binary.BigEndian.AppendUint64(body, math.Float64bits(f))
+			} else {
+				typ = uint64(len(v))*2 + 13
+				body = append(body, v...)
+			}
+		default:
+			s := Text(v)
+			typ = uint64(len(s))*2 + 13
+			body = append(body, s...)
+		}
+		types = appendSQLiteVarint(types, typ)
+	}
+
+	// The header size counts itself
+	size := len(types) + 1
+	for sqliteVarintLen(uint64(size)) != size-len(types) {
+		size = len(types) + sqliteVarintLen(uint64(size))
+	}
+	record := appendSQLiteVarint(make([]byte, 0, size+len(body)), uint64(size))
+	record = append(record, types...)
+	return append(record, body...)
+}
+
+// appendInt appends an integer in the smallest serial type holding it.
+func appendInt(b []byte, i int64) (uint64, []byte) {
+	switch {
+	case i == 0:
+		return 8, b
+	case i == 1:
+		return 9, b
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return 1, append(b, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return 2, binary.BigEndian.AppendUint16(b, uint16(i))
+	case i >= -1<<23 && i < 1<<23:
+		return 3, append(b, byte(i>>16), byte(i>>8), byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return 4, binary.BigEndian.AppendUint32(b, uint32(i))
+	case i >= -1<<47 && i < 1<<47:
+		return 5, append(b, byte(i>>40), byte(i>>32), byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+	}
+	return 6, binary.BigEndian.AppendUint64(b, uint64(i))
+}
+
+// appendSQLiteVarint appends v as a SQLite varint: big-endian groups of
+// seven bits, with a ninth byte of eight bits.
+func appendSQLiteVarint(b []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
+
+// sqliteVarintLen returns the encoded length of v.
+func sqliteVarintLen(v uint64) int {
+	if v > 1<<56-1 {
+		return 9
+	}
+	n := 1
+	for v >>= 7; v > 0; v >>= 7 {
+		n++
+	}
+	return n
+}
+
+// pager allocates and writes the pages of a database file.
+type pager struct {
+	f    *os.File
+	next uint32 // next page number to allocate
+}
+
+// lockBytePage is the page holding the bytes SQLite uses for file locking,
+// which must not hold data.
+const lockBytePage = 1<<30/sqlitePageSize + 1
+
+func (p *pager) alloc() uint32 {
+	if p.next == lockBytePage {
+		p.next++
+	}
+	n := p.next
+	p.next++
+	return n
+}
+
+func (p *pager) write(pgno uint32, page []byte) error {
+	_, err := p.f.WriteAt(page, int64(pgno-1)*sqlitePageSize)
+	return err
+}
+
+// Page types of table B-trees.
+const (
+	interiorPage = 0x05
+	leafPage     = 0x0d
+)
+
+// btree builds a table B-tree from rows added in rowid order. Leaves are
+// written as soon as they are full; the interior pages are built by finish.
+type btree struct {
+	p        *pager
+	cells    [][]byte // cells of the leaf being filled
+	used     int      // bytes those cells take, with their pointers
+	lastKey  int64
+	children []btreeChild // written leaves
+}
+
+type btreeChild struct {
+	page uint32
+	key  int64 // largest rowid in the subtree
+}
+
+func newBTree(p *pager) *btree {
+	return &btree{p: p}
+}
+
+// add appends a row, spilling a payload too large for a leaf cell into a
+// chain of overflow pages.
+func (t *btree) add(rowid int64, payload []byte) error {
+	const usable = sqlitePageSize
+	const maxLocal = usable - 35
+	cell := appendSQLiteVarint(nil, uint64(len(payload)))
+	cell = appendSQLiteVarint(cell, uint64(rowid))
+	if len(payload) <= maxLocal {
+		cell = append(cell, payload...)
+	} else {
+		minLocal := (usable-12)*32/255 - 23
+		local := minLocal + (len(payload)-minLocal)%(usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+		first, err := t.overflow(payload[local:])
+		if err != nil {
+			return err
+		}
+		cell = append(cell, payload[:local]...)
+		cell = binary.BigEndian.AppendUint32(cell, first)
+	}
+
+	if t.used+len(cell)+2 > sqlitePageSize-8 {
+		if err := t.flushLeaf(); err != nil {
+			return err
+		}
+	}
+	t.cells = append(t.cells, cell)
+	t.used += len(cell) + 2
+	t.lastKey = rowid
+	return nil
+}
+
+// overflow writes data to a chain of overflow pages and returns the first.
+func (t *btree) overflow(data []byte) (uint32, error) {
+	const chunk = sqlitePageSize - 4
+	pages := make([]uint32, (len(data)+chunk-1)/chunk)
+	for i := range pages {
+		pages[i] = t.p.alloc()
+	}
+	for i, pgno := range pages {
+		page := make([]byte, sqlitePageSize)
+		if i+1 < len(pages) {
+			binary.BigEndian.PutUint32(page, pages[i+1])
+		}
+		copy(page[4:], data[i*chunk:])
+		if err := t.p.write(pgno, page); err != nil {
+			return 0, err
+		}
+	}
+	return pages[0], nil
+}
+
+// flushLeaf writes the leaf being filled to a new page.
+func (t *btree) flushLeaf() error {
+	if len(t.cells) == 0 {
+		return nil
+	}
+	pgno := t.p.alloc()
+	if err := t.p.write(pgno, buildPage(leafPage, 0, t.cells, 0)); err != nil {
+		return err
+	}
+	t.children = append(t.children, btreeChild{page: pgno, key: t.lastKey})
+	t.cells, t.used = nil, 0
+	return nil
+}
+
+// finish writes the remaining rows and the interior pages, with the root at
+// page root. The root page's content starts at offset, where page 1 leaves
+// room for the database header.
+func (t *btree) finish(root uint32, offset int) error {
+	if len(t.children) == 0 && t.used <= sqlitePageSize-offset-8 {
+		return t.p.write(root, buildPage(leafPage, offset, t.cells, 0))
+	}
+	if err := t.flushLeaf(); err != nil {
+		return err
+	}
+
+	level := t.children
+	for {
+		// Interior cells: a child page number and the largest key under it;
+		// the last child becomes the right-most pointer
+		if fitsInterior(level, sqlitePageSize-offset-12) {
+			return t.p.write(root, buildInterior(level, offset))
+		}
+		var next []btreeChild
+		for len(level) > 0 {
+			n := 1
+			for n < len(level) && fitsInterior(level[:n+1], sqlitePageSize-12) {
+				n++
+			}
+			pgno := t.p.alloc()
+			if err := t.p.write(pgno, buildInterior(level[:n], 0)); err != nil {
+				return err
+			}
+			next = append(next, btreeChild{page: pgno, key: level[n-1].key})
+			level = level[n:]
+		}
+		level = next
+	}
+}
+
+// interiorCell returns the cell pointing to a child.
+func interiorCell(c btreeChild) []byte {
+	cell := binary.BigEndian.AppendUint32(nil, c.page)
+	return appendSQLiteVarint(cell, uint64(c.key))
+}
+
+// fitsInterior reports whether an interior page of children fits in space.
+func fitsInterior(children []btreeChild, space int) bool {
+	used := 0
+	for _, c := range children[:len(children)-1] {
+		used += len(interiorCell(c)) + 2
+	}
+	return used <= space
+}
+
+// buildInterior builds an interior page over children.
+func buildInterior(children []btreeChild, offset int) []byte {
+	cells := make([][]byte, len(children)-1)
+	for i, c := range children[:len(children)-1] {
+		cells[i] = interiorCell(c)
+	}
+	return buildPage(interiorPage, offset, cells, children[len(children)-1].page)
+}
+
+// buildPage lays out a B-tree page: the header at offset, the cell pointer
+// array after it and the cells packed at the end of the page.
+func buildPage(kind byte, offset int, cells [][]byte, rightmost uint32) []byte {
+	page := make([]byte, sqlitePageSize)
+	header := 8
+	if kind == interiorPage {
+		header = 12
+		binary.BigEndian.PutUint32(page[offset+8:], rightmost)
+	}
+	page[offset] = kind
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+
+	content := sqlitePageSize
+	for i, cell := range cells {
+		content -= len(cell)
+		copy(page[content:], cell)
+		binary.BigEndian.PutUint16(page[offset+header+2*i:], uint16(content))
+	}
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(content))
+	return page
+}
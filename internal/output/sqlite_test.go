@@ -0,0 +1,161 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sqliteQuery runs a query with the sqlite3 command line tool, skipping the
+// test where it isn't installed.
+func sqliteQuery(t *testing.T, db string, query string) string {
+	t.Helper()
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	out, err := exec.Command(bin, "-bail", "-separator", "|", db, query).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3 %q: %v\n%s", query, err, out)
+	}
+	return strings.TrimRight(string(out), "\n")
+}
+
+// --- Unit Tests ---
+
+// Unit test: a table per input file with values keeping their types
+func TestSQLiteWriter_TablesAndTypes(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(SQLite, dir, "out", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.StartFile("a.log", []string{"time", "count", "ok", "ratio", ""})
+	w.StartFile(`b "x".log`, []string{"msg"})
+	w.WriteRow("a.log", []any{"2024-01-01", json.Number("42"), true, json.Number("0.5"), nil})
+	w.WriteRow(`b "x".log`, []any{"hello"})
+	w.WriteRow("a.log", []any{"2024-01-02", json.Number("-70000"), false})
+	w.EndFile("a.log")
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	db := filepath.Join(dir, "out.sqlite")
+	if len(paths) != 1 || paths[0] != db {
+		t.Fatalf("paths = %v", paths)
+	}
+
+	if got := sqliteQuery(t, db, "PRAGMA integrity_check"); got != "ok" {
+		t.Fatalf("integrity_check: %s", got)
+	}
+	if got := sqliteQuery(t, db, "SELECT name FROM sqlite_schema ORDER BY name"); got != "a.log\nb \"x\".log" {
+		t.Errorf("tables = %q", got)
+	}
+	got := sqliteQuery(t, db, `SELECT time, count, typeof(count), ok, ratio, typeof(ratio), typeof(column_5) FROM "a.log" ORDER BY rowid`)
+	want := "2024-01-01|42|integer|1|0.5|real|null\n2024-01-02|-70000|integer|0||null|null"
+	if got != want {
+		t.Errorf("rows:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// Unit test: large tables, long values and many tables span several levels of pages
+func TestSQLiteWriter_LargeTables(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(SQLite, dir, "out", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	const tables = 300
+	for i := 0; i < tables; i++ {
+		w.StartFile(fmt.Sprintf("file%03d.log", i), []string{"n"})
+		w.WriteRow(fmt.Sprintf("file%03d.log", i), []any{json.Number(fmt.Sprint(i))})
+	}
+	w.StartFile("big.log", []string{"n", "text"})
+	const rows = 50000
+	for i := 0; i < rows; i++ {
+		text := fmt.Sprintf("row %d", i)
+		if i%1000 == 0 {
+			text = strings.Repeat("x", 20000+i)
+		}
+		w.WriteRow("big.log", []any{json.Number(fmt.Sprint(i)), text})
+	}
+	if _, err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	db := filepath.Join(dir, "out.sqlite")
+
+	if got := sqliteQuery(t, db, "PRAGMA integrity_check"); got != "ok" {
+		t.Fatalf("integrity_check: %s", got)
+	}
+	if got := sqliteQuery(t, db, "SELECT count(*) FROM sqlite_schema"); got != fmt.Sprint(tables+1) {
+		t.Errorf("tables = %s", got)
+	}
+	if got := sqliteQuery(t, db, `SELECT n FROM "file299.log"`); got != "299" {
+		t.Errorf("file299.log = %s", got)
+	}
+	got := sqliteQuery(t, db, `SELECT count(*), sum(n), max(length(text)) FROM "big.log"`)
+	if want := fmt.Sprintf("%d|%d|%d", rows, rows*(rows-1)/2, 20000+49000); got != want {
+		t.Errorf("big.log = %s, want %s", got, want)
+	}
+}
+
+// Unit test: starting a file again replaces its rows and reserved names are avoided
+func TestSQLiteWriter_RestartAndNames(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(SQLite, dir, "out", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.StartFile("sqlite_master", []string{"a"})
+	w.WriteRow("sqlite_master", []any{"old"})
+	w.StartFile("sqlite_master", []string{"b"})
+	w.WriteRow("sqlite_master", []any{"new"})
+	w.StartFile("A.log", []string{"a"})
+	w.StartFile("a.log", []string{"a"})
+	if _, err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	db := filepath.Join(dir, "out.sqlite")
+	if got := sqliteQuery(t, db, `SELECT b FROM "_sqlite_master"`); got != "new" {
+		t.Errorf("rows = %q", got)
+	}
+	if got := sqliteQuery(t, db, "SELECT name FROM sqlite_schema ORDER BY rowid"); got != "_sqlite_master\nA.log\na.log (2)" {
+		t.Errorf("tables = %q", got)
+	}
+}
+
+// Unit test: record encoding picks the smallest integer serial types
+func TestSQLiteRecord(t *testing.T) {
+	got := sqliteRecord([]any{nil, json.Number("0"), json.Number("1"), json.Number("-2"), json.Number("300"), "ab"})
+	want := []byte{7, 0, 8, 9, 1, 2, 17, 0xfe, 0x01, 0x2c, 'a', 'b'}
+	if string(got) != string(want) {
+		t.Errorf("record = %v, want %v", got, want)
+	}
+	for _, v := range []uint64{0, 127, 128, 16383, 16384, 1<<56 - 1, 1 << 56, 1<<64 - 1} {
+		if n := len(appendSQLiteVarint(nil, v)); n != sqliteVarintLen(v) {
+			t.Errorf("varint %d: %d bytes, want %d", v, n, sqliteVarintLen(v))
+		}
+	}
+}
+
+// Unit test: a database without tables is valid
+func TestSQLiteWriter_Empty(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(SQLite, dir, "out", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	db := filepath.Join(dir, "out.sqlite")
+	if got := sqliteQuery(t, db, "PRAGMA integrity_check"); got != "ok" {
+		t.Fatalf("integrity_check: %s", got)
+	}
+	if got := sqliteQuery(t, db, "SELECT count(*) FROM sqlite_schema"); got != "0" {
+		t.Errorf("tables = %s", got)
+	}
+}
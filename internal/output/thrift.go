@@ -0,0 +1,105 @@
+package output
+
+import "encoding/binary"
+
+// Thrift compact protocol types used by the Parquet metadata.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol, the encoding
+// of Parquet's page headers and file metadata. Fields must be written in
+// increasing id order within a struct, and every struct, including the
+// outermost, between begin and end.
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // id of the last field written, per open struct
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	top := len(t.last) - 1
+	if delta := id - t.last[top]; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(int64(id))
+	}
+	t.last[top] = id
+}
+
+// varint appends a zigzag encoded integer.
+func (t *thriftWriter) varint(v int64) {
+	t.buf = binary.AppendUvarint(t.buf, uint64((v<<1)^(v>>63)))
+}
+
+// boolean writes a bool field, whose value the compact protocol keeps in
+// the field type.
+func (t *thriftWriter) boolean(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// structField opens a struct valued field; close it with end.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// list opens a list field of n elements of type typ. Struct elements are
+// each written between begin and end.
+func (t *thriftWriter) list(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|typ)
+	} else {
+		t.buf = append(t.buf, 0xf0|typ)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+// i32Elem appends an element of an i32 list.
+func (t *thriftWriter) i32Elem(v int32) {
+	t.varint(int64(v))
+}
+
+// binaryElem appends an element of a binary list.
+func (t *thriftWriter) binaryElem(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// begin opens a struct.
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+// end closes the innermost struct.
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
@@ -0,0 +1,639 @@
+package output
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxSheetRows is Excel's limit on the rows of a worksheet.
+	MaxSheetRows = 1 << 20
+	// MaxSheetNameLen is Excel's limit on the length of a worksheet name.
+	MaxSheetNameLen = 31
+	// maxCellText is Excel's limit on the characters in a cell.
+	maxCellText = 32767
+	// maxExactDigits is how many significant digits Excel keeps of a number;
+	// longer integers are written as text so they aren't rounded.
+	maxExactDigits = 15
+)
+
+// Cell styles of workbooks written by xlsxWriter, as indexes into the
+// cellXfs of xlsxStyles.
+const (
+	styleDefault = iota
+	styleHeader
+	styleDate
+	styleDateTime
+	styleDateTimeMillis
+)
+
+// xlsxWriter writes a workbook with a sheet per input file. Rows are
+// streamed to temporary files as they arrive, one per sheet, and zipped into
+// the workbook on Close, so memory use doesn't grow with the rows. Every
+// sheet starts with a header row of the file's columns; a file with more
+// rows than a sheet holds continues on sheets named "name (2)", "name (3)"
+// and so on, each with the header again. Sheets are ordered by input file
+// name. With a limit on the rows of a workbook, sheets that would pass it
+// roll over to further workbooks named "name (2).xlsx" and so on. A summary
+// of the run goes on a sheet ahead of them in the first workbook.
+type xlsxWriter struct {
+	path        string
+	spool       string
+	maxRows     int  // rows per sheet, header included
+	maxBookRows int  // data rows per workbook; 0 means no limit
+	textTimes   bool // dates and times stay text
+	sheets      map[string]*xlsxSheet
+	summary     *sheetPart // nil without a summary
+	placed      []Sheet
+}
+
+// Sheet records where rows of an input file were written.
+type Sheet struct {
+	File     string // input file
+	Workbook string // path of the workbook
+	Sheet    string // worksheet name
+	Rows     int    // data rows on the sheet
+}
+
+// SheetWriter is implemented by writers that spread the rows of a file over
+// worksheets.
+type SheetWriter interface {
+	// Sheets returns where the rows of every file went, valid after Close.
+	Sheets() []Sheet
+}
+
+// SummarySheet is the name of the sheet holding the summary of a run.
+const SummarySheet = "Summary"
+
+// SummaryWriter is implemented by writers that put a summary of the run
+// ahead of the rows of the files.
+type SummaryWriter interface {
+	// SetSummary sets the rows of the Summary sheet before Close. Values
+	// are as for WriteRow, and Heading.
+	SetSummary(rows [][]any) error
+}
+
+// Heading is a value written as a heading cell, as the headers of the
+// columns are.
+type Heading string
+
+// plainText is a string written as text even when it holds a date.
+type plainText string
+
+// textRow returns values with their strings made plain text.
+func textRow(values []any) []any {
+	row := make([]any, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			row[i] = plainText(s)
+		} else {
+			row[i] = v
+		}
+	}
+	return row
+}
+
+// xlsxSheet is the rows of one input file.
+type xlsxSheet struct {
+	file    string
+	columns []string
+	parts   []*sheetPart
+	ended   bool
+}
+
+// sheetPart is one worksheet of a file's rows.
+type sheetPart struct {
+	path string
+	f    *os.File
+	buf  *bufio.Writer
+	rows int
+}
+
+// sheetEntry is a worksheet of a workbook being built.
+type sheetEntry struct {
+	file string
+	name string
+	part *sheetPart // nil for an empty sheet
+}
+
+func newXLSXWriter(path string, maxBookRows int) (*xlsxWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	spool, err := os.MkdirTemp("", "xlsx-spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	return &xlsxWriter{
+		path:        path,
+		spool:       spool,
+		maxRows:     MaxSheetRows,
+		maxBookRows: maxBookRows,
+		sheets:      make(map[string]*xlsxSheet),
+	}, nil
+}
+
+func (xw *xlsxWriter) StartFile(file string, columns []string) error {
+	if s := xw.sheets[file]; s != nil {
+		s.discard()
+	}
+	s := &xlsxSheet{file: file, columns: Columns(columns)}
+	xw.sheets[file] = s
+	return xw.addPart(s)
+}
+
+// addPart starts a new worksheet for s with the header row.
+func (xw *xlsxWriter) addPart(s *xlsxSheet) error {
+	if n := len(s.parts); n > 0 {
+		if err := s.parts[n-1].close(); err != nil {
+			return err
+		}
+	}
+	f, err := os.CreateTemp(xw.spool, "sheet-*")
+	if err != nil {
+		return fmt.Errorf("failed to spool rows of %s: %w", s.file, err)
+	}
+	part := &sheetPart{path: f.Name(), f: f, buf: bufio.NewWriterSize(f, 64*1024)}
+	s.parts = append(s.parts, part)
+
+	header := make([]any, len(s.columns))
+	for i, c := range s.columns {
+		header[i] = c
+	}
+	return part.writeRow(header, styleHeader)
+}
+
+// full reports whether a sheet can take no more rows, either because Excel
+// allows no more or because it holds as many as a workbook may.
+func (xw *xlsxWriter) full(p *sheetPart) bool {
+	return p.rows >= xw.maxRows || (xw.maxBookRows > 0 && p.rows-1 >= xw.maxBookRows)
+}
+
+func (xw *xlsxWriter) WriteRow(file string, values []any) error {
+	s := xw.sheets[file]
+	if s == nil {
+		return fmt.Errorf("rows for %s before its columns", file)
+	}
+	if s.ended {
+		return fmt.Errorf("rows for %s after it ended", file)
+	}
+	if xw.full(s.parts[len(s.parts)-1]) {
+		if err := xw.addPart(s); err != nil {
+			return err
+		}
+	}
+	row := fitRow(values, len(s.columns))
+	if xw.textTimes {
+		row = textRow(row)
+	}
+	if err := s.parts[len(s.parts)-1].writeRow(row, styleDefault); err != nil {
+		return fmt.Errorf("failed to spool rows of %s: %w", file, err)
+	}
+	return nil
+}
+
+func (xw *xlsxWriter) EndFile(file string) error {
+	s := xw.sheets[file]
+	if s == nil || s.ended {
+		return nil
+	}
+	s.ended = true
+	return s.parts[len(s.parts)-1].close()
+}
+
+func (xw *xlsxWriter) SetSummary(rows [][]any) error {
+	if xw.summary != nil {
+		os.Remove(xw.summary.path)
+	}
+	f, err := os.CreateTemp(xw.spool, "summary-*")
+	if err != nil {
+		return fmt.Errorf("failed to spool summary: %w", err)
+	}
+	xw.summary = &sheetPart{path: f.Name(), f: f, buf: bufio.NewWriter(f)}
+	for _, row := range rows {
+		if err := xw.summary.writeRow(row, styleDefault); err != nil {
+			xw.summary.close()
+			return fmt.Errorf("failed to spool summary: %w", err)
+		}
+	}
+	return xw.summary.close()
+}
+
+// WriteSummary writes a workbook at path holding only the Summary sheet,
+// for runs that write no workbook of their rows.
+func WriteSummary(path string, rows [][]any) error {
+	xw, err := newXLSXWriter(path, 0)
+	if err != nil {
+		return err
+	}
+	if err := xw.SetSummary(rows); err != nil {
+		xw.Abort()
+		return err
+	}
+	_, err = xw.Close()
+	return err
+}
+
+func (xw *xlsxWriter) Close() ([]string, error) {
+	defer xw.Abort()
+	books, err := xw.layout()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(xw.path), err)
+	}
+
+	paths := make([]string, len(books))
+	tmps := make([]string, len(books))
+	removeTmps := func() {
+		for _, tmp := range tmps {
+			if tmp != "" {
+				os.Remove(tmp)
+			}
+		}
+	}
+	for i, book := range books {
+		paths[i] = xw.bookPath(i)
+		f, err := CreateTemp(paths[i])
+		if err != nil {
+			removeTmps()
+			return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(paths[i]), err)
+		}
+		f.Close()
+		tmps[i] = f.Name()
+		if err := writeWorkbook(tmps[i], book); err != nil {
+			removeTmps()
+			return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(paths[i]), err)
+		}
+	}
+	for i := range books {
+		if err := os.Rename(tmps[i], paths[i]); err != nil {
+			removeTmps()
+			return paths[:i], fmt.Errorf("failed to write %s: %w", filepath.Base(paths[i]), err)
+		}
+		tmps[i] = ""
+	}
+
+	xw.placed = nil
+	for i, book := range books {
+		for _, e := range book {
+			if e.file != "" {
+				xw.placed = append(xw.placed, Sheet{File: e.file, Workbook: paths[i], Sheet: e.name, Rows: e.part.rows - 1})
+			}
+		}
+	}
+	return paths, nil
+}
+
+func (xw *xlsxWriter) Sheets() []Sheet {
+	return xw.placed
+}
+
+func (xw *xlsxWriter) Abort() {
+	for _, s := range xw.sheets {
+		s.discard()
+	}
+	os.RemoveAll(xw.spool)
+}
+
+// bookPath returns the path of the i-th workbook, counting from zero.
+func (xw *xlsxWriter) bookPath(i int) string {
+	if i == 0 {
+		return xw.path
+	}
+	ext := filepath.Ext(xw.path)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(xw.path, ext), i+1, ext)
+}
+
+// layout names the sheets and spreads them over workbooks, filling each
+// workbook in file order until the next sheet would pass the row limit.
+func (xw *xlsxWriter) layout() ([][]sheetEntry, error) {
+	files := make([]*xlsxSheet, 0, len(xw.sheets))
+	for _, s := range xw.sheets {
+		files = append(files, s)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].file < files[j].file })
+
+	var books [][]sheetEntry
+	var book []sheetEntry
+	rows := 0
+	names := newSheetNames()
+	if xw.summary != nil {
+		book = append(book, sheetEntry{name: names.add(SummarySheet, 1), part: xw.summary})
+	}
+	for _, s := range files {
+		base := SheetName(s.file)
+		for i, part := range s.parts {
+			if err := part.close(); err != nil {
+				return nil, err
+			}
+			if xw.maxBookRows > 0 && rows > 0 && rows+part.rows-1 > xw.maxBookRows {
+				books = append(books, book)
+				book, rows = nil, 0
+			}
+			book = append(book, sheetEntry{file: s.file, name: names.add(base, i+1), part: part})
+			rows += part.rows - 1
+		}
+	}
+	if len(book) == 0 {
+		// A workbook needs a sheet
+		book = append(book, sheetEntry{name: "Sheet1"})
+	}
+	return append(books, book), nil
+}
+
+// writeWorkbook zips the sheets into a workbook at path.
+func writeWorkbook(path string, entries []sheetEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	add := func(name string, content string) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, content)
+		return err
+	}
+
+	var sheetList, rels, overrides strings.Builder
+	for i, e := range entries {
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(e.name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(entries)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, overrides.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheetList.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxWorkbookRels, rels.String())},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		if err := add(p.name, p.content); err != nil {
+			return err
+		}
+	}
+	for i, e := range entries {
+		w, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xlsxSheetStart); err != nil {
+			return err
+		}
+		if e.part != nil {
+			if err := copyFile(w, e.part.path); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, xlsxSheetEnd); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// copyFile copies the content of the file at path to w.
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// writeRow appends a row of cells in the given style, typed by value.
+func (p *sheetPart) writeRow(values []any, style int) error {
+	p.rows++
+	fmt.Fprintf(p.buf, `<row r="%d">`, p.rows)
+	for i, v := range values {
+		writeCell(p.buf, columnName(i)+strconv.Itoa(p.rows), v, style)
+	}
+	_, err := p.buf.WriteString("</row>")
+	return err
+}
+
+// close flushes and closes the spool file, which stays for the workbook.
+func (p *sheetPart) close() error {
+	if p.f == nil {
+		return nil
+	}
+	err := p.buf.Flush()
+	if cerr := p.f.Close(); err == nil {
+		err = cerr
+	}
+	p.f = nil
+	return err
+}
+
+// discard removes the spooled rows of every part.
+func (s *xlsxSheet) discard() {
+	for _, p := range s.parts {
+		p.close()
+		os.Remove(p.path)
+	}
+	s.parts = nil
+}
+
+// writeCell writes a cell: numbers and booleans keep their type, strings
+// holding an ISO 8601 date or date and time become dates, and all other
+// strings, IP addresses among them, stay text. Empty cells are left out.
+func writeCell(w *bufio.Writer, ref string, v any, style int) {
+	switch v := v.(type) {
+	case nil:
+		return
+	case Heading:
+		style = styleHeader
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(w, `<c r="%s" t="b"%s><v>%d</v></c>`, ref, styleAttr(style), b)
+		return
+	case json.Number:
+		if n, ok := cellNumber(v); ok {
+			fmt.Fprintf(w, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr(style), n)
+			return
+		}
+	case string:
+		if style == styleDefault {
+			if serial, dateStyle, ok := dateSerial(v); ok {
+				fmt.Fprintf(w, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr(dateStyle), strconv.FormatFloat(serial, 'f', -1, 64))
+				return
+			}
+		}
+	}
+	text := cellText(Text(v))
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(w, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, styleAttr(style))
+	xml.EscapeText(w, []byte(text))
+	w.WriteString("</t></is></c>")
+}
+
+func styleAttr(style int) string {
+	if style == styleDefault {
+		return ""
+	}
+	return fmt.Sprintf(` s="%d"`, style)
+}
+
+// cellNumber returns a number as a cell value, or false when Excel would
+// lose digits of it or can't hold it.
+func cellNumber(n json.Number) (string, bool) {
+	f, err := n.Float64()
+	if err != nil || math.IsInf(f, 0) {
+		return "", false
+	}
+	if _, err := n.Int64(); err == nil || !strings.ContainsAny(string(n), ".eE") {
+		digits := strings.TrimLeft(strings.TrimPrefix(string(n), "-"), "0")
+		if len(digits) > maxExactDigits {
+			return "", false
+		}
+	}
+	return string(n), true
+}
+
+// cellText removes characters XML can't hold and cuts text to Excel's limit.
+func cellText(s string) string {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "�")
+	}
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0xFFFE || r == 0xFFFF {
+			return -1
+		}
+		return r
+	}, s)
+	if utf8.RuneCountInString(s) > maxCellText {
+		s = string([]rune(s)[:maxCellText])
+	}
+	return s
+}
+
+// dateSerial returns the Excel serial number and style of a string holding
+// an ISO 8601 date or date and time. The time is taken as written, ignoring
+// any offset, as Excel dates have no zone. Dates before 1900-03-01, where
+// Excel's calendar is off by a day, stay text.
+func dateSerial(s string) (float64, int, bool) {
+	t, dateOnly, ok := ParseTime(s, time.UTC)
+	if !ok || t.Year() > 9999 || t.Before(time.Date(1900, 3, 1, 0, 0, 0, 0, t.Location())) {
+		return 0, 0, false
+	}
+	style := styleDateTime
+	switch {
+	case dateOnly:
+		style = styleDate
+	case t.Nanosecond() != 0:
+		style = styleDateTimeMillis
+	}
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return wall.Sub(epoch).Seconds() / 86400, style, true
+}
+
+// SheetName returns the worksheet name for an input file: the file name
+// with the characters Excel doesn't allow in sheet names replaced by "_",
+// cut to Excel's 31 character limit.
+func SheetName(file string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, file)
+	name = strings.Trim(name, "'")
+	if r := []rune(name); len(r) > MaxSheetNameLen {
+		name = strings.TrimRight(string(r[:MaxSheetNameLen]), "'")
+	}
+	if name == "" || strings.EqualFold(name, "History") {
+		name += "_"
+	}
+	return name
+}
+
+// sheetNames hands out worksheet names unique case-insensitively, as Excel
+// requires.
+type sheetNames struct {
+	used map[string]bool
+}
+
+func newSheetNames() *sheetNames {
+	return &sheetNames{used: make(map[string]bool)}
+}
+
+// add returns base for the first sheet of a file and "base (n)" for its
+// n-th, cutting base so the name stays within 31 characters. A name that is
+// taken gets the next free number.
+func (sn *sheetNames) add(base string, n int) string {
+	for ; ; n++ {
+		name := base
+		if n > 1 {
+			suffix := fmt.Sprintf(" (%d)", n)
+			r := []rune(base)
+			if max := MaxSheetNameLen - len(suffix); len(r) > max {
+				r = r[:max]
+			}
+			name = string(r) + suffix
+		}
+		if !sn.used[strings.ToLower(name)] {
+			sn.used[strings.ToLower(name)] = true
+			return name
+		}
+	}
+}
+
+// columnName returns the letters of a zero-based column.
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// xmlEscape escapes s for an XML attribute.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>%s</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>%s</sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s</Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="3"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="166" formatCode="yyyy-mm-dd hh:mm:ss.000"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="5"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
@@ -0,0 +1,387 @@
+package output
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"network-log-formatter/internal/xlsx"
+
+	"pgregory.net/rapid"
+)
+
+// sheetXML returns the XML of the n-th worksheet of a workbook.
+func sheetXML(t *testing.T, path string, n int) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer zr.Close()
+	f, err := zr.Open(fmt.Sprintf("xl/worksheets/sheet%d.xml", n))
+	if err != nil {
+		t.Fatalf("no sheet %d: %v", n, err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	return string(data)
+}
+
+// Feature: network-log-formatter, Property 25: 流式行写入工作簿后可完整读回
+// For any files with interleaved rows of text and integers, the workbook
+// holds a sheet per file in name order with its header and every row, and
+// reads back the text and numbers as written.
+func TestProperty25_WorkbookRoundTrip(t *testing.T) {
+	base := t.TempDir()
+	iteration := 0
+	rapid.Check(t, func(rt *rapid.T) {
+		iteration++
+		dir := filepath.Join(base, fmt.Sprint(iteration))
+		w, err := New(XLSX, dir, "out", Options{})
+		if err != nil {
+			rt.Fatalf("New: %v", err)
+		}
+
+		files := rapid.SliceOfNDistinct(rapid.StringMatching(`[a-z]{1,6}\.log`), 1, 4, rapid.ID[string]).Draw(rt, "files")
+		columns := make(map[string][]string)
+		for _, file := range files {
+			cols := make([]string, rapid.IntRange(1, 4).Draw(rt, "columns"))
+			for i := range cols {
+				cols[i] = fmt.Sprintf("c%d", i)
+			}
+			columns[file] = cols
+			if err := w.StartFile(file, cols); err != nil {
+				rt.Fatalf("StartFile: %v", err)
+			}
+		}
+
+		want := make(map[string][][]string)
+		count := rapid.IntRange(0, 40).Draw(rt, "rows")
+		for i := 0; i < count; i++ {
+			file := rapid.SampledFrom(files).Draw(rt, "file")
+			row := make([]any, len(columns[file]))
+			text := make([]string, len(row))
+			for j := range row {
+				switch rapid.IntRange(0, 2).Draw(rt, "kind") {
+				case 0:
+					text[j] = rapid.StringMatching(`[a-z0-9 <&"中]{0,10}`).Draw(rt, "text")
+					row[j] = text[j]
+				case 1:
+					n := fmt.Sprint(rapid.Int64Range(-1e12, 1e12).Draw(rt, "number"))
+					text[j] = n
+					row[j] = json.Number(n)
+				}
+			}
+			if err := w.WriteRow(file, row); err != nil {
+				rt.Fatalf("WriteRow: %v", err)
+			}
+			want[file] = append(want[file], text)
+		}
+		paths, err := w.Close()
+		if err != nil {
+			rt.Fatalf("Close: %v", err)
+		}
+
+		sheets, err := xlsx.ReadFile(paths[0], 0)
+		if err != nil {
+			rt.Fatalf("ReadFile: %v", err)
+		}
+		if len(sheets) != len(files) {
+			rt.Fatalf("%d sheets, want %d", len(sheets), len(files))
+		}
+		for i, sheet := range sheets {
+			if i > 0 && sheets[i-1].Name >= sheet.Name {
+				rt.Fatalf("sheets out of order: %s, %s", sheets[i-1].Name, sheet.Name)
+			}
+			file := sheet.Name
+			if !reflect.DeepEqual(sheet.Rows[0], columns[file]) {
+				rt.Fatalf("%s: header %v, want %v", file, sheet.Rows[0], columns[file])
+			}
+			if len(sheet.Rows)-1 != len(want[file]) {
+				rt.Fatalf("%s: %d rows, want %d", file, len(sheet.Rows)-1, len(want[file]))
+			}
+			for r, row := range want[file] {
+				got := sheet.Rows[r+1]
+				for c, v := range row {
+					cell := ""
+					if c < len(got) {
+						cell = got[c]
+					}
+					if cell != v {
+						rt.Fatalf("%s row %d column %d: %q, want %q", file, r, c, cell, v)
+					}
+				}
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: cells keep the type of their value and dates become Excel dates
+func TestXLSXWriter_CellTypes(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(XLSX, dir, "out", Options{})
+	w.StartFile("a.log", []string{"text", "ip", "int", "long", "float", "bool", "date", "time", "zoned", "old", "ctl"})
+	w.WriteRow("a.log", []any{"x<y", "10.0.0.1", json.Number("42"), json.Number("1234567890123456789"), json.Number("1.5"), true,
+		"2024-01-02", "2024-01-02T03:04:05.250", "2024-01-02 03:04:05+08:00", "1899-12-31", "a\x01b"})
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	sheets, err := xlsx.ReadFile(paths[0], 0)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := []string{"x<y", "10.0.0.1", "42", "1234567890123456789", "1.5", "TRUE",
+		"2024-01-02", "2024-01-02 03:04:05.250", "2024-01-02 03:04:05", "1899-12-31", "ab"}
+	if !reflect.DeepEqual(sheets[0].Rows[1], want) {
+		t.Errorf("row = %q, want %q", sheets[0].Rows[1], want)
+	}
+
+	data := sheetXML(t, paths[0], 1)
+	for _, cell := range []string{
+		`<c r="A1" t="inlineStr" s="1">`,
+		`<c r="B2" t="inlineStr">`,
+		`<c r="C2"><v>42</v></c>`,
+		`<c r="D2" t="inlineStr">`,
+		`<c r="F2" t="b"><v>1</v></c>`,
+		`<c r="G2" s="2"><v>45293</v></c>`,
+		`<c r="H2" s="4">`,
+		`<c r="I2" s="3">`,
+		`<c r="J2" t="inlineStr">`,
+		`state="frozen"`,
+	} {
+		if !strings.Contains(data, cell) {
+			t.Errorf("sheet lacks %s:\n%s", cell, data)
+		}
+	}
+}
+
+// Unit test: with text times dates and times stay text, keeping their
+// offset, and other values keep their type
+func TestXLSXWriter_TextTimes(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(XLSX, dir, "out", Options{TextTimes: true})
+	w.StartFile("a.log", []string{"time", "int"})
+	w.WriteRow("a.log", []any{"2024-01-02T03:04:05+08:00", json.Number("42")})
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data := sheetXML(t, paths[0], 1)
+	for _, cell := range []string{`<c r="A2" t="inlineStr"><is><t xml:space="preserve">2024-01-02T03:04:05+08:00</t>`, `<c r="B2"><v>42</v></c>`} {
+		if !strings.Contains(data, cell) {
+			t.Errorf("sheet lacks %s:\n%s", cell, data)
+		}
+	}
+}
+
+// Unit test: a file with more rows than a sheet holds continues on numbered
+// sheets, each with the header
+func TestXLSXWriter_SplitsSheets(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := newXLSXWriter(filepath.Join(dir, "out.xlsx"), 0)
+	w.maxRows = 3
+	w.StartFile("b.log", []string{"n"})
+	w.StartFile("a.log", []string{"n"})
+	for i := 1; i <= 5; i++ {
+		w.WriteRow("b.log", []any{json.Number(fmt.Sprint(i))})
+	}
+	w.WriteRow("a.log", []any{"x"})
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	sheets, err := xlsx.ReadFile(paths[0], 0)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	got := make(map[string][][]string)
+	var names []string
+	for _, s := range sheets {
+		names = append(names, s.Name)
+		got[s.Name] = s.Rows
+	}
+	if want := []string{"a.log", "b.log", "b.log (2)", "b.log (3)"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("sheets = %q, want %q", names, want)
+	}
+	if want := [][]string{{"n"}, {"5"}}; !reflect.DeepEqual(got["b.log (3)"], want) {
+		t.Errorf("b.log (3) = %q, want %q", got["b.log (3)"], want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+// Unit test: sheets past the row limit of a workbook roll over to further
+// workbooks and every sheet's place is recorded
+func TestXLSXWriter_RollsOverWorkbooks(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(XLSX, dir, "out", Options{MaxWorkbookRows: 3})
+	for file, n := range map[string]int{"a.log": 2, "b.log": 5, "c.log": 0} {
+		w.StartFile(file, []string{"n"})
+		for i := 0; i < n; i++ {
+			w.WriteRow(file, []any{json.Number(fmt.Sprint(i))})
+		}
+	}
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	want := []string{filepath.Join(dir, "out.xlsx"), filepath.Join(dir, "out (2).xlsx"), filepath.Join(dir, "out (3).xlsx")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %q, want %q", paths, want)
+	}
+
+	placed := w.(SheetWriter).Sheets()
+	wantPlaced := []Sheet{
+		{File: "a.log", Workbook: want[0], Sheet: "a.log", Rows: 2},
+		{File: "b.log", Workbook: want[1], Sheet: "b.log", Rows: 3},
+		{File: "b.log", Workbook: want[2], Sheet: "b.log (2)", Rows: 2},
+		{File: "c.log", Workbook: want[2], Sheet: "c.log", Rows: 0},
+	}
+	if !reflect.DeepEqual(placed, wantPlaced) {
+		t.Errorf("sheets = %+v, want %+v", placed, wantPlaced)
+	}
+	for _, p := range placed {
+		sheets, err := xlsx.ReadFile(p.Workbook, 0)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		found := false
+		for _, s := range sheets {
+			found = found || (s.Name == p.Sheet && len(s.Rows)-1 == p.Rows)
+		}
+		if !found {
+			t.Errorf("%s has no sheet %s with %d rows: %+v", p.Workbook, p.Sheet, p.Rows, sheets)
+		}
+	}
+}
+
+// Unit test: restarting a file discards its rows; with no files the workbook
+// has an empty sheet
+func TestXLSXWriter_RestartAndEmpty(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(XLSX, dir, "out", Options{})
+	w.StartFile("a.log", []string{"x"})
+	w.WriteRow("a.log", []any{"old"})
+	w.StartFile("a.log", []string{"y"})
+	w.WriteRow("a.log", []any{"new"})
+	w.EndFile("a.log")
+	if err := w.WriteRow("a.log", []any{"late"}); err == nil {
+		t.Error("expected an error for a row after the file ended")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.xlsx")); !os.IsNotExist(err) {
+		t.Errorf("workbook visible before Close: %v", err)
+	}
+	paths, _ := w.Close()
+	sheets, _ := xlsx.ReadFile(paths[0], 0)
+	if len(sheets) != 1 || !reflect.DeepEqual(sheets[0].Rows, [][]string{{"y"}, {"new"}}) {
+		t.Errorf("sheets = %+v", sheets)
+	}
+
+	w, _ = New(XLSX, dir, "empty", Options{})
+	paths, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if sheets, err := xlsx.ReadFile(paths[0], 0); err != nil || len(sheets) != 1 || sheets[0].Name != "Sheet1" {
+		t.Errorf("sheets = %+v, %v", sheets, err)
+	}
+}
+
+// Unit test: the summary goes ahead of the files' sheets in the first
+// workbook only, with headings styled as headers, and a summary can be a
+// workbook of its own
+func TestXLSXWriter_Summary(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := New(XLSX, dir, "out", Options{MaxWorkbookRows: 2})
+	for _, file := range []string{"Summary", "b.log"} {
+		w.StartFile(file, []string{"n"})
+		for i := 0; i < 2; i++ {
+			w.WriteRow(file, []any{json.Number(fmt.Sprint(i))})
+		}
+	}
+	rows := [][]any{{Heading("Rows"), json.Number("4")}, {"From", "2024-05-01 10:00:00"}}
+	if err := w.(SummaryWriter).SetSummary(rows); err != nil {
+		t.Fatalf("SetSummary: %v", err)
+	}
+	paths, err := w.Close()
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("Close: %v, %v", paths, err)
+	}
+	sheets, _ := xlsx.ReadFile(paths[0], 0)
+	if len(sheets) != 2 || sheets[0].Name != SummarySheet || sheets[1].Name != "Summary (2)" {
+		t.Fatalf("sheets = %+v", sheets)
+	}
+	if want := [][]string{{"Rows", "4"}, {"From", "2024-05-01 10:00:00"}}; !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Errorf("summary rows = %q, want %q", sheets[0].Rows, want)
+	}
+	if xml := sheetXML(t, paths[0], 1); !strings.Contains(xml, `<c r="A1" t="inlineStr" s="1">`) {
+		t.Errorf("heading not styled: %s", xml)
+	}
+	if sheets, _ := xlsx.ReadFile(paths[1], 0); len(sheets) != 1 || sheets[0].Name != "b.log" {
+		t.Errorf("second workbook sheets = %+v", sheets)
+	}
+	for _, p := range w.(SheetWriter).Sheets() {
+		if p.File == "" {
+			t.Errorf("summary recorded as a file's sheet: %+v", p)
+		}
+	}
+
+	path := filepath.Join(dir, "out.summary.xlsx")
+	if err := WriteSummary(path, rows); err != nil {
+		t.Fatalf("WriteSummary: %v", err)
+	}
+	if sheets, _ := xlsx.ReadFile(path, 0); len(sheets) != 1 || sheets[0].Name != SummarySheet {
+		t.Errorf("report sheets = %+v", sheets)
+	}
+}
+
+// Unit test: sheet names follow Excel's rules and stay unique
+func TestSheetNames(t *testing.T) {
+	long := strings.Repeat("x", 40) + ".log"
+	for file, want := range map[string]string{
+		"a.log":      "a.log",
+		`a/b:c?[d]*`: "a_b_c__d__",
+		"'quoted'":   "quoted",
+		"":           "_",
+		"history":    "history_",
+		long:         strings.Repeat("x", 31),
+	} {
+		if got := SheetName(file); got != want {
+			t.Errorf("SheetName(%q) = %q, want %q", file, got, want)
+		}
+	}
+
+	names := newSheetNames()
+	var got []string
+	for _, n := range []struct {
+		base string
+		n    int
+	}{{"a", 1}, {"A", 1}, {"a", 2}, {strings.Repeat("x", 31), 1}, {strings.Repeat("x", 31), 2}} {
+		got = append(got, names.add(n.base, n.n))
+	}
+	want := []string{"a", "A (2)", "a (3)", strings.Repeat("x", 31), strings.Repeat("x", 27) + " (2)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+}
+
+// Unit test: column letters
+func TestColumnName(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(col); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", col, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package project
+
+import (
+	"strings"
+
+	"network-log-formatter/internal/model"
+)
+
+// DiffLines compares two versions of code line by line and returns the
+// lines of the new version interleaved with the removed lines of the old
+// one, as a unified diff shows them. It uses the longest common subsequence
+// of lines, which is fast enough for scripts of a few thousand lines.
+func DiffLines(oldCode string, newCode string) []model.DiffLine {
+	a, b := splitLines(oldCode), splitLines(newCode)
+
+	// Trim the common prefix and suffix to keep the table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]model.DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		diff = append(diff, model.DiffLine{Op: "equal", Text: line})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			diff = append(diff, model.DiffLine{Op: "equal", Text: midA[i]})
+			i++
+			j++
+		case j < len(midB) && (i == len(midA) || lcs[i][j+1] > lcs[i+1][j]):
+			diff = append(diff, model.DiffLine{Op: "add", Text: midB[j]})
+			j++
+		default:
+			diff = append(diff, model.DiffLine{Op: "remove", Text: midA[i]})
+			i++
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, model.DiffLine{Op: "equal", Text: line})
+	}
+	return diff
+}
+
+// splitLines splits code into lines, ignoring a final newline.
+func splitLines(code string) []string {
+	code = strings.TrimSuffix(strings.ReplaceAll(code, "\r\n", "\n"), "\n")
+	if code == "" {
+		return nil
+	}
+	return strings.Split(code, "\n")
+}
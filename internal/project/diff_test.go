@@ -0,0 +1,100 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+// rebuild returns the old and new code a diff was computed from.
+func rebuild(diff []model.DiffLine) (string, string) {
+	var oldLines, newLines []string
+	for _, d := range diff {
+		if d.Op != "add" {
+			oldLines = append(oldLines, d.Text)
+		}
+		if d.Op != "remove" {
+			newLines = append(newLines, d.Text)
+		}
+	}
+	return strings.Join(oldLines, "\n"), strings.Join(newLines, "\n")
+}
+
+// Feature: network-log-formatter, Property 22: 代码差异可还原
+// For any two versions of code, the unchanged and removed lines of their diff
+// spell out the old version and the unchanged and added lines the new one,
+// and the number of unchanged lines is that of their longest common
+// subsequence, so the diff is minimal.
+func TestProperty22_DiffRebuildsBothVersions(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		line := rapid.SampledFrom([]string{"a", "b", "c", "d", ""})
+		oldLines := rapid.SliceOf(line).Draw(t, "old")
+		newLines := rapid.SliceOf(line).Draw(t, "new")
+		oldCode, newCode := strings.Join(oldLines, "\n"), strings.Join(newLines, "\n")
+
+		diff := DiffLines(oldCode, newCode)
+		gotOld, gotNew := rebuild(diff)
+		if want := strings.Join(splitLines(oldCode), "\n"); gotOld != want {
+			t.Fatalf("old version mismatch: got %q, want %q", gotOld, want)
+		}
+		if want := strings.Join(splitLines(newCode), "\n"); gotNew != want {
+			t.Fatalf("new version mismatch: got %q, want %q", gotNew, want)
+		}
+
+		equal := 0
+		for _, d := range diff {
+			if d.Op == "equal" {
+				equal++
+			}
+		}
+		if want := lcsLength(splitLines(oldCode), splitLines(newCode)); equal != want {
+			t.Fatalf("expected %d unchanged lines, got %d", want, equal)
+		}
+	})
+}
+
+// lcsLength is a plain recursive longest common subsequence for checking.
+func lcsLength(a, b []string) int {
+	memo := make(map[[2]int]int)
+	var f func(i, j int) int
+	f = func(i, j int) int {
+		if i == len(a) || j == len(b) {
+			return 0
+		}
+		key := [2]int{i, j}
+		if v, ok := memo[key]; ok {
+			return v
+		}
+		v := max(f(i+1, j), f(i, j+1))
+		if a[i] == b[j] {
+			v = max(v, f(i+1, j+1)+1)
+		}
+		memo[key] = v
+		return v
+	}
+	return f(0, 0)
+}
+
+// --- Unit Tests ---
+
+// Unit test: a changed line shows as its removal followed by its addition
+func TestDiffLines_ChangedLine(t *testing.T) {
+	diff := DiffLines("import re\nx = 1\nprint(x)\n", "import re\nx = 2\nprint(x)\n")
+	want := []model.DiffLine{
+		{Op: "equal", Text: "import re"},
+		{Op: "remove", Text: "x = 1"},
+		{Op: "add", Text: "x = 2"},
+		{Op: "equal", Text: "print(x)"},
+	}
+	if len(diff) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), diff)
+	}
+	for i := range want {
+		if diff[i] != want[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, want[i], diff[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"network-log-formatter/internal/model"
+)
+
+// ManifestStore persists the per-project manifest of processed input files.
+// Each manifest is stored as an individual JSON file named {projectID}.json.
+type ManifestStore struct {
+	storagePath string
+	mu          sync.Mutex
+	locks       map[string]chan struct{} // per project, held by the run updating its manifest
+}
+
+// NewManifestStore creates a ManifestStore that keeps manifests in the given
+// directory. It creates the storage directory if it does not exist.
+func NewManifestStore(storagePath string) (*ManifestStore, error) {
+	if err := os.MkdirAll(storagePath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create manifest storage directory: %w", err)
+	}
+	return &ManifestStore{storagePath: storagePath, locks: make(map[string]chan struct{})}, nil
+}
+
+// Lock waits until no other run holds the manifest of the given project and
+// takes it, returning the function that releases it. A run holds the lock
+// from loading the manifest until it saved the new one, so runs of a project
+// never process the same changes twice or overwrite each other's manifest.
+// It fails with the context's error if ctx is done first.
+func (ms *ManifestStore) Lock(ctx context.Context, projectID string) (func(), error) {
+	ms.mu.Lock()
+	lock := ms.locks[projectID]
+	if lock == nil {
+		lock = make(chan struct{}, 1)
+		ms.locks[projectID] = lock
+	}
+	ms.mu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Load returns the manifest of the given project, or nil if none has been
+// recorded yet.
+func (ms *ManifestStore) Load(projectID string) (*model.FileManifest, error) {
+	data, err := os.ReadFile(ms.filePath(projectID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m model.FileManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]model.FileRecord)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to disk, replacing any previous manifest of the
+// same project. The file is written to a temporary name and renamed into
+// place so a crash mid-write never leaves a truncated manifest.
+func (ms *ManifestStore) Save(m model.FileManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	path := ms.filePath(m.ProjectID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the manifest of the given project. A missing manifest is
+// not an error.
+func (ms *ManifestStore) Delete(projectID string) error {
+	if err := os.Remove(ms.filePath(projectID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete manifest: %w", err)
+	}
+	return nil
+}
+
+// filePath returns the manifest file path for a project ID.
+// It validates the ID to prevent path traversal attacks.
+func (ms *ManifestStore) filePath(projectID string) string {
+	clean := filepath.Base(projectID)
+	if clean == "." || clean == ".." || clean == "" {
+		clean = "_invalid_"
+	}
+	return filepath.Join(ms.storagePath, clean+".json")
+}
@@ -0,0 +1,101 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// --- Unit Tests ---
+
+// Unit test: manifests round-trip and a missing manifest loads as nil
+func TestManifestStore_SaveLoadDelete(t *testing.T) {
+	store, err := NewManifestStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ManifestStore: %v", err)
+	}
+
+	m, err := store.Load("p1")
+	if err != nil || m != nil {
+		t.Fatalf("expected no manifest, got %+v (%v)", m, err)
+	}
+
+	mod := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	original := model.FileManifest{
+		ProjectID:  "p1",
+		InputDir:   "/in",
+		OutputPath: "/out/result.xlsx",
+		Files: map[string]model.FileRecord{
+			"/in/a.log": {Path: "/in/a.log", Size: 10, ModTime: mod, Hash: "abc"},
+		},
+	}
+	if err := store.Save(original); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	m, err = store.Load("p1")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	r := m.Files["/in/a.log"]
+	if m.OutputPath != original.OutputPath || r.Hash != "abc" || r.Size != 10 || !r.ModTime.Equal(mod) {
+		t.Fatalf("manifest mismatch: %+v", m)
+	}
+
+	if err := store.Delete("p1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := store.Delete("p1"); err != nil {
+		t.Fatalf("deleting a missing manifest should not fail: %v", err)
+	}
+	if m, _ := store.Load("p1"); m != nil {
+		t.Fatal("expected manifest to be gone")
+	}
+}
+
+// Unit test: the manifest lock of a project is held by one run at a time,
+// doesn't block other projects and gives up when the context is done
+func TestManifestStore_Lock(t *testing.T) {
+	store, err := NewManifestStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ManifestStore: %v", err)
+	}
+
+	unlock, err := store.Lock(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	other, err := store.Lock(context.Background(), "p2")
+	if err != nil {
+		t.Fatalf("expected another project to be free: %v", err)
+	}
+	other()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := store.Lock(ctx, "p1"); err != context.DeadlineExceeded {
+		t.Fatalf("expected to wait for the held lock until the deadline, got %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		next, err := store.Lock(context.Background(), "p1")
+		if err != nil {
+			t.Errorf("lock failed: %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the second run to wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	if next := <-acquired; next != nil {
+		next()
+	}
+}
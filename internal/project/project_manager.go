@@ -8,15 +8,23 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"network-log-formatter/internal/model"
 )
 
+// maxRevisions is the number of earlier code versions kept per project.
+const maxRevisions = 20
+
 // ProjectManager handles CRUD operations for project records.
 // Each project is persisted as an individual JSON file named {id}.json.
+// Changes are serialized, so concurrent updates of a project, such as a run
+// recording its status while the user edits the code, don't overwrite each
+// other.
 type ProjectManager struct {
 	storagePath string
+	mu          sync.Mutex
 }
 
 // NewProjectManager creates a new ProjectManager that stores projects in the given directory.
@@ -32,17 +40,13 @@ func NewProjectManager(storagePath string) (*ProjectManager, error) {
 // If a project with the same Name already exists, a numeric suffix is appended
 // (e.g. "name_2", "name_3") to ensure uniqueness.
 func (pm *ProjectManager) Create(project model.Project) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	if project.Name != "" {
 		existing, _ := pm.List()
 		project.Name = pm.uniqueName(project.Name, project.ID, existing)
 	}
-
-	data, err := json.MarshalIndent(project, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal project: %w", err)
-	}
-	path := pm.filePath(project.ID)
-	return os.WriteFile(path, data, 0o644)
+	return pm.write(project)
 }
 
 // List reads all project files and returns them sorted by CreatedAt descending.
@@ -93,7 +97,10 @@ func (pm *ProjectManager) Get(id string) (*model.Project, error) {
 }
 
 // Update applies partial updates to an existing project using pointer fields.
+// A code change keeps the replaced code in the project's revision history.
 func (pm *ProjectManager) Update(id string, updates model.ProjectUpdate) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	p, err := pm.Get(id)
 	if err != nil {
 		return err
@@ -103,24 +110,65 @@ func (pm *ProjectManager) Update(id string, updates model.ProjectUpdate) error {
 		existing, _ := pm.List()
 		p.Name = pm.uniqueName(*updates.Name, id, existing)
 	}
-	if updates.Code != nil {
+	if updates.Code != nil && *updates.Code != p.Code {
+		reason := updates.CodeReason
+		if reason == "" {
+			reason = "edit"
+		}
+		p.Revisions = append(p.Revisions, model.CodeRevision{Code: p.Code, Reason: reason, ReplacedAt: time.Now()})
+		if len(p.Revisions) > maxRevisions {
+			p.Revisions = p.Revisions[len(p.Revisions)-maxRevisions:]
+		}
 		p.Code = *updates.Code
 	}
 	if updates.Status != nil {
 		p.Status = *updates.Status
 	}
+	if updates.Sinks != nil {
+		p.Sinks = *updates.Sinks
+	}
+	if updates.Schema != nil {
+		p.Schema = *updates.Schema
+	}
+	if updates.Schedules != nil {
+		p.Schedules = *updates.Schedules
+	}
+	if updates.Quality != nil {
+		p.Quality = *updates.Quality
+	}
+	if updates.TimeZones != nil {
+		p.TimeZones = *updates.TimeZones
+	}
 	p.UpdatedAt = time.Now()
 
 	// Write directly to avoid re-checking uniqueness against self
-	data, jsonErr := json.MarshalIndent(*p, "", "  ")
-	if jsonErr != nil {
-		return fmt.Errorf("failed to marshal project: %w", jsonErr)
+	return pm.write(*p)
+}
+
+// write saves a project, renaming it into place so readers never see a
+// partly written file.
+func (pm *ProjectManager) write(p model.Project) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal project: %w", err)
+	}
+
+	path := pm.filePath(p.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write project: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write project: %w", err)
 	}
-	return os.WriteFile(pm.filePath(id), data, 0o644)
+	return nil
 }
 
 // Delete removes a project file by ID.
 func (pm *ProjectManager) Delete(id string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	path := pm.filePath(id)
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return fmt.Errorf("project not found: %s", id)
@@ -1,7 +1,9 @@
 package project
 
 import (
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -228,3 +230,145 @@ func TestProperty11_ProjectDeletionValidity(t *testing.T) {
 		}
 	})
 }
+
+// --- Unit Tests ---
+
+// Unit test: code changes keep the replaced code with its reason, unchanged
+// code adds no revision, and only the latest revisions are kept
+func TestUpdate_CodeRevisions(t *testing.T) {
+	pm, err := NewProjectManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ProjectManager: %v", err)
+	}
+	if err := pm.Create(model.Project{ID: "p1", Code: "v0", Status: "draft"}); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	v1, v2 := "v1", "v2"
+	pm.Update("p1", model.ProjectUpdate{Code: &v1})
+	pm.Update("p1", model.ProjectUpdate{Code: &v1})
+	pm.Update("p1", model.ProjectUpdate{Code: &v2, CodeReason: "repair"})
+
+	got, _ := pm.Get("p1")
+	if got.Code != "v2" || len(got.Revisions) != 2 {
+		t.Fatalf("expected code v2 with 2 revisions, got %q with %+v", got.Code, got.Revisions)
+	}
+	if r := got.Revisions[0]; r.Code != "v0" || r.Reason != "edit" {
+		t.Errorf("expected v0 replaced by an edit, got %+v", r)
+	}
+	if r := got.Revisions[1]; r.Code != "v1" || r.Reason != "repair" {
+		t.Errorf("expected v1 replaced by a repair, got %+v", r)
+	}
+
+	for i := 0; i < maxRevisions+5; i++ {
+		code := fmt.Sprintf("code %d", i)
+		pm.Update("p1", model.ProjectUpdate{Code: &code})
+	}
+	got, _ = pm.Get("p1")
+	if len(got.Revisions) != maxRevisions {
+		t.Fatalf("expected %d revisions, got %d", maxRevisions, len(got.Revisions))
+	}
+	if last := got.Revisions[maxRevisions-1].Code; last != fmt.Sprintf("code %d", maxRevisions+3) {
+		t.Errorf("expected the latest revisions to be kept, last is %q", last)
+	}
+}
+
+// Unit test: sinks are replaced as a whole and left alone by other updates
+func TestUpdate_Sinks(t *testing.T) {
+	pm, err := NewProjectManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ProjectManager: %v", err)
+	}
+	if err := pm.Create(model.Project{ID: "p1", Code: "v0", Status: "draft"}); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	sinks := []model.SinkConfig{{Type: "loki", URL: "http://loki:3100", Labels: map[string]string{"env": "prod"}}}
+	pm.Update("p1", model.ProjectUpdate{Sinks: &sinks})
+	status := "executed"
+	pm.Update("p1", model.ProjectUpdate{Status: &status})
+	got, _ := pm.Get("p1")
+	if len(got.Sinks) != 1 || got.Sinks[0].Labels["env"] != "prod" {
+		t.Fatalf("expected the sink to be kept, got %+v", got.Sinks)
+	}
+
+	none := []model.SinkConfig{}
+	pm.Update("p1", model.ProjectUpdate{Sinks: &none})
+	if got, _ := pm.Get("p1"); len(got.Sinks) != 0 {
+		t.Errorf("expected no sinks, got %+v", got.Sinks)
+	}
+}
+
+// Unit test: the schema is replaced as a whole and left alone by other updates
+func TestUpdate_Schema(t *testing.T) {
+	pm, err := NewProjectManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ProjectManager: %v", err)
+	}
+	if err := pm.Create(model.Project{ID: "p1", Code: "v0", Status: "draft"}); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	columns := []model.ColumnSchema{{Name: "src", Type: "ip"}, {Name: "time", Type: "datetime"}}
+	pm.Update("p1", model.ProjectUpdate{Schema: &columns})
+	name := "firewall"
+	pm.Update("p1", model.ProjectUpdate{Name: &name})
+	got, _ := pm.Get("p1")
+	if len(got.Schema) != 2 || got.Schema[0].Type != "ip" {
+		t.Fatalf("expected the schema to be kept, got %+v", got.Schema)
+	}
+
+	none := []model.ColumnSchema{}
+	pm.Update("p1", model.ProjectUpdate{Schema: &none})
+	if got, _ := pm.Get("p1"); len(got.Schema) != 0 {
+		t.Errorf("expected no schema, got %+v", got.Schema)
+	}
+}
+
+// Unit test: concurrent updates of a project all take effect, and readers
+// never see a partly written project file
+func TestUpdate_Concurrent(t *testing.T) {
+	pm, err := NewProjectManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ProjectManager: %v", err)
+	}
+	if err := pm.Create(model.Project{ID: "p1", Code: "v0", Status: "draft"}); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	const updates = 10
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := fmt.Sprintf("code %d", i)
+			if err := pm.Update("p1", model.ProjectUpdate{Code: &code}); err != nil {
+				t.Errorf("failed to update: %v", err)
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		columns := []model.ColumnSchema{{Name: "src", Type: "ip"}}
+		pm.Update("p1", model.ProjectUpdate{Schema: &columns})
+	}()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if _, err := pm.Get("p1"); err != nil {
+				t.Errorf("failed to read during updates: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	got, _ := pm.Get("p1")
+	if len(got.Revisions) != updates || len(got.Schema) != 1 {
+		t.Errorf("expected %d revisions and the schema, got %d revisions and %+v", updates, len(got.Revisions), got.Schema)
+	}
+}
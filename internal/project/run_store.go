@@ -0,0 +1,141 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/sink"
+)
+
+// maxRuns is the number of run records kept per project.
+const maxRuns = 100
+
+// RunStore persists the history of batch runs per project. The records of a
+// project are stored as individual JSON files named {runID}.json in a
+// directory named after the project ID.
+type RunStore struct {
+	storagePath string
+}
+
+// NewRunStore creates a RunStore that keeps run records in the given
+// directory. It creates the storage directory if it does not exist.
+func NewRunStore(storagePath string) (*RunStore, error) {
+	if err := os.MkdirAll(storagePath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create run storage directory: %w", err)
+	}
+	return &RunStore{storagePath: storagePath}, nil
+}
+
+// Save writes a run record to disk, without the credentials of the run's
+// sinks, replacing an earlier record with the same ID, and drops the oldest
+// records of the project beyond the most recent maxRuns. The file is
+// written to a temporary name and renamed into place so a crash mid-write
+// never leaves a truncated record.
+func (rs *RunStore) Save(r model.RunRecord) error {
+	r.Params.Sinks = sink.Redact(r.Params.Sinks)
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	dir := rs.projectDir(r.ProjectID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	path := filepath.Join(dir, safeName(r.ID)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+
+	runs, err := rs.List(r.ProjectID)
+	if err != nil {
+		return nil
+	}
+	for _, old := range runs[min(len(runs), maxRuns):] {
+		os.Remove(filepath.Join(dir, safeName(old.ID)+".json"))
+	}
+	return nil
+}
+
+// List returns the run records of a project sorted by StartedAt descending.
+// A project without runs has an empty history.
+func (rs *RunStore) List(projectID string) ([]model.RunRecord, error) {
+	entries, err := os.ReadDir(rs.projectDir(projectID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read run history: %w", err)
+	}
+
+	var runs []model.RunRecord
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(rs.projectDir(projectID), entry.Name()))
+		if err != nil {
+			continue // skip unreadable files
+		}
+		var r model.RunRecord
+		if err := json.Unmarshal(data, &r); err != nil || r.ID == "" {
+			continue // skip corrupted files
+		}
+		runs = append(runs, r)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	return runs, nil
+}
+
+// Get reads a single run record of a project.
+func (rs *RunStore) Get(projectID string, runID string) (*model.RunRecord, error) {
+	data, err := os.ReadFile(filepath.Join(rs.projectDir(projectID), safeName(runID)+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run not found: %s", runID)
+		}
+		return nil, fmt.Errorf("failed to read run: %w", err)
+	}
+
+	var r model.RunRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run: %w", err)
+	}
+	return &r, nil
+}
+
+// DeleteProject removes the run history of a project. A project without runs
+// is not an error.
+func (rs *RunStore) DeleteProject(projectID string) error {
+	if err := os.RemoveAll(rs.projectDir(projectID)); err != nil {
+		return fmt.Errorf("failed to delete run history: %w", err)
+	}
+	return nil
+}
+
+// projectDir returns the directory holding the runs of a project.
+func (rs *RunStore) projectDir(projectID string) string {
+	return filepath.Join(rs.storagePath, safeName(projectID))
+}
+
+// safeName strips directory components from an ID to prevent path
+// traversal attacks.
+func safeName(id string) string {
+	clean := filepath.Base(id)
+	if clean == "." || clean == ".." || clean == "" || clean == string(filepath.Separator) {
+		clean = "_invalid_"
+	}
+	return clean
+}
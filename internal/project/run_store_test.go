@@ -0,0 +1,89 @@
+package project
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// --- Unit Tests ---
+
+// Unit test: runs are listed newest first per project, can be read back and
+// are deleted with their project
+func TestRunStore_SaveListGet(t *testing.T) {
+	store, err := NewRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create RunStore: %v", err)
+	}
+
+	if runs, err := store.List("p1"); err != nil || len(runs) != 0 {
+		t.Fatalf("expected no runs, got %+v (%v)", runs, err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"r1", "r2"} {
+		r := model.RunRecord{
+			ID:        id,
+			ProjectID: "p1",
+			Params:    model.BatchParams{InputDir: "/in", OutputDir: "/out"},
+			Status:    "completed",
+			StartedAt: start.Add(time.Duration(i) * time.Hour),
+			CodeHash:  "abc",
+		}
+		if err := store.Save(r); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+	store.Save(model.RunRecord{ID: "r3", ProjectID: "p2", StartedAt: start})
+
+	runs, err := store.List("p1")
+	if err != nil || len(runs) != 2 || runs[0].ID != "r2" || runs[1].ID != "r1" {
+		t.Fatalf("expected r2, r1, got %+v (%v)", runs, err)
+	}
+
+	r, err := store.Get("p1", "r1")
+	if err != nil || r.Params.InputDir != "/in" || r.CodeHash != "abc" {
+		t.Fatalf("run mismatch: %+v (%v)", r, err)
+	}
+	store.Save(model.RunRecord{ID: "r4", ProjectID: "p1", StartedAt: start, Params: model.BatchParams{
+		Sinks: []model.SinkConfig{{Type: "elasticsearch", URL: "https://es", Username: "u", Password: "pw"}},
+	}})
+	if r, _ := store.Get("p1", "r4"); r == nil || r.Params.Sinks[0].Password != "" || r.Params.Sinks[0].Username != "u" {
+		t.Errorf("stored sinks = %+v", r)
+	}
+	if _, err := store.Get("p1", "r3"); err == nil {
+		t.Fatal("expected runs of other projects not to be found")
+	}
+
+	if err := store.DeleteProject("p1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if runs, _ := store.List("p1"); len(runs) != 0 {
+		t.Fatalf("expected no runs after delete, got %d", len(runs))
+	}
+	if runs, _ := store.List("p2"); len(runs) != 1 {
+		t.Fatalf("expected the other project's runs to stay, got %d", len(runs))
+	}
+}
+
+// Unit test: only the most recent runs of a project are kept
+func TestRunStore_KeepsRecentRuns(t *testing.T) {
+	store, err := NewRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create RunStore: %v", err)
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxRuns+3; i++ {
+		store.Save(model.RunRecord{ID: fmt.Sprintf("r%d", i), ProjectID: "p1", StartedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	runs, _ := store.List("p1")
+	if len(runs) != maxRuns {
+		t.Fatalf("expected %d runs, got %d", maxRuns, len(runs))
+	}
+	if runs[len(runs)-1].ID != "r3" {
+		t.Fatalf("expected the oldest runs to be dropped, oldest kept is %s", runs[len(runs)-1].ID)
+	}
+}
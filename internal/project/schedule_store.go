@@ -0,0 +1,82 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"network-log-formatter/internal/model"
+)
+
+// ScheduleStore persists the state of each project's schedules, keyed by
+// schedule ID. The states of a project are stored as an individual JSON
+// file named {projectID}.json.
+type ScheduleStore struct {
+	storagePath string
+}
+
+// NewScheduleStore creates a ScheduleStore that keeps schedule states in the
+// given directory. It creates the storage directory if it does not exist.
+func NewScheduleStore(storagePath string) (*ScheduleStore, error) {
+	if err := os.MkdirAll(storagePath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create schedule storage directory: %w", err)
+	}
+	return &ScheduleStore{storagePath: storagePath}, nil
+}
+
+// Load returns the schedule states of the given project; an empty map if
+// none has been recorded yet.
+func (ss *ScheduleStore) Load(projectID string) (map[string]model.ScheduleState, error) {
+	states := make(map[string]model.ScheduleState)
+	data, err := os.ReadFile(ss.filePath(projectID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, fmt.Errorf("failed to read schedule states: %w", err)
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule states: %w", err)
+	}
+	return states, nil
+}
+
+// Save writes the schedule states of a project, replacing the earlier
+// ones. Like manifests they are renamed into place.
+func (ss *ScheduleStore) Save(projectID string, states map[string]model.ScheduleState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule states: %w", err)
+	}
+
+	path := ss.filePath(projectID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write schedule states: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write schedule states: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the schedule states of the given project. Missing states
+// are not an error.
+func (ss *ScheduleStore) Delete(projectID string) error {
+	if err := os.Remove(ss.filePath(projectID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete schedule states: %w", err)
+	}
+	return nil
+}
+
+// filePath returns the state file path for a project ID, guarding against
+// path traversal like the other stores.
+func (ss *ScheduleStore) filePath(projectID string) string {
+	clean := filepath.Base(projectID)
+	if clean == "." || clean == ".." || clean == "" {
+		clean = "_invalid_"
+	}
+	return filepath.Join(ss.storagePath, clean+".json")
+}
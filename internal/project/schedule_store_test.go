@@ -0,0 +1,47 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// --- Unit Tests ---
+
+// Unit test: schedule states round-trip and missing states load empty
+func TestScheduleStore_SaveLoadDelete(t *testing.T) {
+	store, err := NewScheduleStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create ScheduleStore: %v", err)
+	}
+
+	states, err := store.Load("p1")
+	if err != nil || len(states) != 0 {
+		t.Fatalf("expected no states, got %+v (%v)", states, err)
+	}
+
+	slot := time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC)
+	states["s1"] = model.ScheduleState{LastSlot: slot, LastJobID: "j1", Missed: 2}
+	if err := store.Save("p1", states); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	got, err := store.Load("p1")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if s := got["s1"]; !s.LastSlot.Equal(slot) || s.LastJobID != "j1" || s.Missed != 2 {
+		t.Fatalf("state mismatch: %+v", s)
+	}
+
+	if err := store.Delete("p1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := store.Delete("p1"); err != nil {
+		t.Fatalf("deleting missing states should not fail: %v", err)
+	}
+	if got, _ := store.Load("p1"); len(got) != 0 {
+		t.Fatal("expected states to be gone")
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 )
 
 // EnvStatus holds the current state of the Python environment.
 type EnvStatus struct {
-	UvAvailable bool   `json:"uv_available"`
-	EnvExists   bool   `json:"env_exists"`
-	EnvPath     string `json:"env_path"`
+	UvAvailable bool        `json:"uv_available"`
+	EnvExists   bool        `json:"env_exists"`
+	EnvPath     string      `json:"env_path"`
+	Sandbox     SandboxInfo `json:"sandbox"`
 }
 
 // PythonEnvManager manages isolated Python environments using uv.
@@ -22,6 +25,9 @@ type EnvStatus struct {
 type PythonEnvManager struct {
 	uvPath  string
 	envPath string
+
+	mu     sync.Mutex
+	limits Limits
 }
 
 // NewPythonEnvManager creates a new PythonEnvManager with the given uv binary path
@@ -67,35 +73,113 @@ func (pem *PythonEnvManager) EnsureEnv(ctx context.Context) error {
 	return nil
 }
 
-// RunScript starts a Python script in the managed virtual environment and returns
-// the command, stdout pipe, and stderr pipe for the caller to read from.
-func (pem *PythonEnvManager) RunScript(ctx context.Context, scriptPath string, args []string) (*exec.Cmd, io.ReadCloser, io.ReadCloser, error) {
-	pythonBin := pem.pythonPath()
+// SetLimits sets the resource limits of scripts started from now on.
+func (pem *PythonEnvManager) SetLimits(limits Limits) {
+	pem.mu.Lock()
+	defer pem.mu.Unlock()
+	pem.limits = limits
+}
+
+// Limits returns the resource limits scripts run with.
+func (pem *PythonEnvManager) Limits() Limits {
+	pem.mu.Lock()
+	defer pem.mu.Unlock()
+	return pem.limits
+}
+
+// RunScript starts a Python script in the managed virtual environment under
+// the manager's resource limits, sandboxed to access and the script's own
+// directory where the system allows. The caller reads the returned run's
+// stdout and stderr pipes to the end and then calls Wait, which reports a
+// script stopped by a limit as a *LimitError.
+func (pem *PythonEnvManager) RunScript(ctx context.Context, scriptPath string, args []string, access Access) (*Run, error) {
+	return pem.RunScriptWithLimits(ctx, scriptPath, args, pem.Limits(), access)
+}
+
+// RunScriptWithLimits is RunScript with explicit limits, for helper scripts
+// that don't report progress and so can't be held to the idle timeout.
+func (pem *PythonEnvManager) RunScriptWithLimits(ctx context.Context, scriptPath string, args []string, limits Limits, access Access) (*Run, error) {
+	access.ReadOnly = append([]string{filepath.Dir(scriptPath)}, access.ReadOnly...)
+	return pem.run(ctx, append([]string{scriptPath}, args...), limits, access)
+}
+
+// RunCode runs a Python code string like RunScriptWithLimits runs a script.
+func (pem *PythonEnvManager) RunCode(ctx context.Context, code string, limits Limits, access Access) (*Run, error) {
+	return pem.run(ctx, []string{"-c", code}, limits, access)
+}
+
+// run starts python with pyArgs under limits in the sandbox.
+func (pem *PythonEnvManager) run(ctx context.Context, pyArgs []string, limits Limits, access Access) (*Run, error) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	sandbox := DetectSandbox()
+	spec := pem.sandboxSpec(access)
 
-	cmdArgs := make([]string, 0, 1+len(args))
-	cmdArgs = append(cmdArgs, scriptPath)
-	cmdArgs = append(cmdArgs, args...)
-	cmd := exec.CommandContext(ctx, pythonBin, cmdArgs...)
+	cmd, procs, stdout, stderr, err := pem.startScript(runCtx, pyArgs, limits, sandbox, spec, true)
+	if err != nil && limits.MemoryBytes > 0 {
+		// Kernels without clone into a cgroup fall back to rlimits
+		cmd, procs, stdout, stderr, err = pem.startScript(runCtx, pyArgs, limits, sandbox, spec, false)
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+
+	run := &Run{
+		Cmd:      cmd,
+		Sandbox:  sandbox,
+		limits:   limits,
+		cancel:   cancel,
+		ctx:      runCtx,
+		done:     make(chan struct{}),
+		procs:    procs,
+		activity: &activityReader{ReadCloser: stdout, at: time.Now()},
+		tail:     &tailReader{ReadCloser: stderr},
+	}
+	run.Stdout = run.activity
+	run.Stderr = run.tail
+	if limits.Timeout > 0 || limits.IdleTimeout > 0 {
+		go run.watch(time.Now())
+	}
+	return run, nil
+}
+
+// startScript starts python with its sandbox and its memory and CPU limits
+// applied.
+func (pem *PythonEnvManager) startScript(ctx context.Context, pyArgs []string, limits Limits, sandbox SandboxInfo, spec sandboxSpec, useCgroup bool) (*exec.Cmd, *procLimits, io.ReadCloser, io.ReadCloser, error) {
+	procs := prepareLimits(limits, useCgroup)
+	if sandbox.Mode != SandboxNone {
+		spec.AddressLimit = procs.addressLimit()
+	}
+	cmd, err := sandboxCommand(ctx, sandbox, spec, pem.pythonPath(), pyArgs)
+	if err != nil {
+		releaseLimits(procs)
+		return nil, nil, nil, nil, fmt.Errorf("failed to prepare sandbox: %w", err)
+	}
 	hideWindow(cmd)
+	attachLimits(cmd, procs)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		releaseLimits(procs)
+		return nil, nil, nil, nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		stdout.Close()
-		return nil, nil, nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+		releaseLimits(procs)
+		return nil, nil, nil, nil, fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
 		stdout.Close()
 		stderr.Close()
-		return nil, nil, nil, fmt.Errorf("failed to start python script: %w", err)
+		releaseLimits(procs)
+		return nil, nil, nil, nil, fmt.Errorf("failed to start python script: %w", err)
 	}
+	applyLimits(cmd.Process.Pid, procs)
 
-	return cmd, stdout, stderr, nil
+	return cmd, procs, stdout, stderr, nil
 }
 
 // GetStatus returns the current state of the Python environment, including
@@ -109,6 +193,7 @@ func (pem *PythonEnvManager) GetStatus() *EnvStatus {
 		UvAvailable: uvAvailable,
 		EnvExists:   envExists,
 		EnvPath:     pem.envPath,
+		Sandbox:     DetectSandbox(),
 	}
 }
 
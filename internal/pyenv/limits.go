@@ -0,0 +1,175 @@
+package pyenv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits bounds the resources of a script run. Zero values mean no limit.
+type Limits struct {
+	Timeout     time.Duration // wall-clock time of a whole run
+	IdleTimeout time.Duration // longest time the script may go without writing to stdout
+	MemoryBytes uint64        // memory of the script process (cgroup v2 memory.max, else RLIMIT_AS)
+	Nice        int           // CPU priority from 0 (normal) to 19 (lowest)
+}
+
+// Limit kinds reported in LimitError.
+const (
+	LimitTimeout = "timeout"
+	LimitIdle    = "idle"
+	LimitMemory  = "memory"
+)
+
+// LimitError reports that a script was stopped for exceeding a resource
+// limit rather than failing on its own.
+type LimitError struct {
+	Limit   string // LimitTimeout, LimitIdle or LimitMemory
+	Message string
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+// AsLimitError returns the LimitError in err's chain, or nil.
+func AsLimitError(err error) *LimitError {
+	var le *LimitError
+	if errors.As(err, &le) {
+		return le
+	}
+	return nil
+}
+
+// stderrTailSize is how much of the end of stderr a run keeps to recognise
+// a MemoryError raised under RLIMIT_AS.
+const stderrTailSize = 4096
+
+// Run is a started script. Read Stdout and Stderr to the end, then call Wait.
+type Run struct {
+	Cmd     *exec.Cmd
+	Stdout  io.ReadCloser
+	Stderr  io.ReadCloser
+	Sandbox SandboxInfo // how the script is isolated
+
+	limits   Limits
+	cancel   context.CancelCauseFunc
+	ctx      context.Context
+	done     chan struct{}
+	procs    *procLimits
+	activity *activityReader
+	tail     *tailReader
+}
+
+// Wait waits for the script to exit and releases its limits. A script
+// stopped by a limit returns a *LimitError.
+func (r *Run) Wait() error {
+	err := r.Cmd.Wait()
+	close(r.done)
+	oom := releaseLimits(r.procs)
+	defer r.cancel(nil)
+
+	if le := AsLimitError(context.Cause(r.ctx)); le != nil {
+		return le
+	}
+	if err == nil {
+		return nil
+	}
+	if r.limits.MemoryBytes > 0 && (oom || strings.Contains(r.tail.String(), "MemoryError")) {
+		return &LimitError{
+			Limit:   LimitMemory,
+			Message: fmt.Sprintf("script exceeded the memory limit of %d MB", r.limits.MemoryBytes>>20),
+		}
+	}
+	return err
+}
+
+// watch stops the script once it runs longer than the timeout or goes
+// without stdout output for longer than the idle timeout.
+func (r *Run) watch(start time.Time) {
+	interval := time.Second
+	for _, d := range []time.Duration{r.limits.Timeout, r.limits.IdleTimeout} {
+		if d > 0 && d/4 < interval {
+			interval = d / 4
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if r.limits.Timeout > 0 && now.Sub(start) > r.limits.Timeout {
+				r.cancel(&LimitError{
+					Limit:   LimitTimeout,
+					Message: fmt.Sprintf("script exceeded the time limit of %s", r.limits.Timeout),
+				})
+				return
+			}
+			if r.limits.IdleTimeout > 0 && now.Sub(r.activity.last()) > r.limits.IdleTimeout {
+				r.cancel(&LimitError{
+					Limit:   LimitIdle,
+					Message: fmt.Sprintf("script made no progress for %s", r.limits.IdleTimeout),
+				})
+				return
+			}
+		}
+	}
+}
+
+// activityReader records when data was last read through it.
+type activityReader struct {
+	io.ReadCloser
+	mu sync.Mutex
+	at time.Time
+}
+
+func (a *activityReader) Read(p []byte) (int, error) {
+	n, err := a.ReadCloser.Read(p)
+	if n > 0 {
+		a.mu.Lock()
+		a.at = time.Now()
+		a.mu.Unlock()
+	}
+	return n, err
+}
+
+func (a *activityReader) last() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.at
+}
+
+// tailReader keeps the last stderrTailSize bytes read through it.
+type tailReader struct {
+	io.ReadCloser
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.mu.Lock()
+		t.buf = append(t.buf, p[:n]...)
+		if len(t.buf) > stderrTailSize {
+			t.buf = append(t.buf[:0], t.buf[len(t.buf)-stderrTailSize:]...)
+		}
+		t.mu.Unlock()
+	}
+	return n, err
+}
+
+func (t *tailReader) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,169 @@
+package pyenv
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupSeq numbers the cgroups created by this process.
+var cgroupSeq atomic.Int64
+
+// procLimits holds what is needed to apply and release the limits of one
+// script process.
+type procLimits struct {
+	limits Limits
+	cgroup string   // cgroup v2 directory the script is started in, if any
+	fd     *os.File // open cgroup directory passed to clone
+	inner  bool     // the sandbox sets RLIMIT_AS itself before starting python
+}
+
+// prepareLimits creates, with a memory limit, a fresh cgroup v2 with
+// memory.max set when the cgroup hierarchy is writable. Otherwise the memory
+// limit falls back to RLIMIT_AS.
+func prepareLimits(limits Limits, useCgroup bool) *procLimits {
+	pl := &procLimits{limits: limits}
+	if limits.MemoryBytes == 0 || !useCgroup {
+		return pl
+	}
+	dir, err := createCgroup(limits.MemoryBytes)
+	if err != nil {
+		return pl
+	}
+	fd, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return pl
+	}
+	pl.cgroup, pl.fd = dir, fd
+	return pl
+}
+
+// addressLimit hands the RLIMIT_AS fallback over to a sandbox, which must
+// set it on python itself: set from outside it would also cover the sandbox
+// setup, or miss python when that was already forked. It returns the limit
+// in bytes, or 0 when none applies.
+func (pl *procLimits) addressLimit() uint64 {
+	if pl.cgroup != "" {
+		return 0
+	}
+	pl.inner = true
+	return pl.limits.MemoryBytes
+}
+
+// attachLimits arranges for cmd to start in its own process group and in the
+// cgroup of pl, if any.
+func attachLimits(cmd *exec.Cmd, pl *procLimits) {
+	// Run the script in its own process group so stopping it also stops any
+	// processes it spawned, which would otherwise keep its pipes open
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if pl.cgroup != "" {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(pl.fd.Fd())
+	}
+}
+
+// applyLimits sets the limits that apply to a started process: RLIMIT_AS
+// when neither a cgroup nor the sandbox enforces memory, and the CPU
+// priority.
+func applyLimits(pid int, pl *procLimits) {
+	if pl.limits.MemoryBytes > 0 && pl.cgroup == "" && !pl.inner {
+		rlim := &unix.Rlimit{Cur: pl.limits.MemoryBytes, Max: pl.limits.MemoryBytes}
+		unix.Prlimit(pid, unix.RLIMIT_AS, rlim, nil)
+	}
+	if pl.limits.Nice > 0 {
+		syscall.Setpriority(syscall.PRIO_PROCESS, pid, pl.limits.Nice)
+	}
+}
+
+// releaseLimits removes the script's cgroup and reports whether the kernel
+// killed a process in it for running out of memory.
+func releaseLimits(pl *procLimits) (oom bool) {
+	if pl == nil || pl.cgroup == "" {
+		return false
+	}
+	oom = cgroupOOMKills(pl.cgroup) > 0
+	pl.fd.Close()
+	os.Remove(pl.cgroup)
+	pl.cgroup = ""
+	return oom
+}
+
+// createCgroup creates a cgroup next to the one this process runs in, with
+// the given memory limit and no swap. The parent must delegate the memory
+// controller, as systemd does for user sessions.
+func createCgroup(memoryBytes uint64) (string, error) {
+	self, err := selfCgroup()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(filepath.Join(cgroupRoot, self))
+	controllers, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(" "+strings.TrimSpace(string(controllers))+" ", " memory ") {
+		return "", fmt.Errorf("memory controller not enabled in %s", parent)
+	}
+
+	dir := filepath.Join(parent, fmt.Sprintf("logforge-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	limit := strconv.FormatUint(memoryBytes, 10)
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(limit), 0644); err != nil {
+		os.Remove(dir)
+		return "", err
+	}
+	// Without swap accounting the limit still holds for resident memory
+	os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+	return dir, nil
+}
+
+// selfCgroup returns the cgroup v2 path of this process.
+func selfCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 hierarchy")
+}
+
+// cgroupOOMKills returns the oom_kill count of the cgroup at dir.
+func cgroupOOMKills(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			n, _ := strconv.Atoi(strings.TrimSpace(v))
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,30 @@
+//go:build !linux
+
+package pyenv
+
+import "os/exec"
+
+// procLimits is empty on platforms without rlimits or cgroups; only the
+// timeouts apply there.
+type procLimits struct{}
+
+// prepareLimits is a no-op on non-Linux platforms.
+func prepareLimits(_ Limits, _ bool) *procLimits {
+	return &procLimits{}
+}
+
+// addressLimit returns 0 as there is no memory limit to hand over.
+func (pl *procLimits) addressLimit() uint64 {
+	return 0
+}
+
+// attachLimits is a no-op on non-Linux platforms.
+func attachLimits(_ *exec.Cmd, _ *procLimits) {}
+
+// applyLimits is a no-op on non-Linux platforms.
+func applyLimits(_ int, _ *procLimits) {}
+
+// releaseLimits is a no-op on non-Linux platforms.
+func releaseLimits(_ *procLimits) bool {
+	return false
+}
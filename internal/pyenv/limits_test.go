@@ -0,0 +1,109 @@
+package pyenv
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnv creates an environment whose python is a shell script running body.
+func fakeEnv(t *testing.T, body string) *PythonEnvManager {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake python needs a POSIX shell")
+	}
+	envPath := t.TempDir()
+	os.MkdirAll(filepath.Join(envPath, "bin"), 0755)
+	script := "#!/bin/sh\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(envPath, "bin", "python"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake python: %v", err)
+	}
+	return NewPythonEnvManager("uv", envPath)
+}
+
+// runToEnd starts a script and waits for it after draining its output.
+func runToEnd(t *testing.T, pem *PythonEnvManager) error {
+	t.Helper()
+	run, err := pem.RunScript(context.Background(), "script.py", nil, Access{})
+	if err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	go io.Copy(io.Discard, run.Stderr)
+	io.Copy(io.Discard, run.Stdout)
+	return run.Wait()
+}
+
+// Unit test: a run longer than the timeout is stopped with a timeout error
+func TestRunScript_Timeout(t *testing.T) {
+	pem := fakeEnv(t, "while true; do echo tick; sleep 0.05; done")
+	pem.SetLimits(Limits{Timeout: 300 * time.Millisecond})
+
+	err := runToEnd(t, pem)
+	if le := AsLimitError(err); le == nil || le.Limit != LimitTimeout {
+		t.Fatalf("expected a timeout limit error, got %v", err)
+	}
+}
+
+// Unit test: a script that stops printing is stopped by the idle watchdog,
+// while one that keeps printing is not
+func TestRunScript_IdleTimeout(t *testing.T) {
+	pem := fakeEnv(t, "echo started; sleep 10")
+	pem.SetLimits(Limits{IdleTimeout: 300 * time.Millisecond})
+
+	start := time.Now()
+	err := runToEnd(t, pem)
+	if le := AsLimitError(err); le == nil || le.Limit != LimitIdle {
+		t.Fatalf("expected an idle limit error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("idle script was not stopped promptly")
+	}
+
+	pem = fakeEnv(t, "for i in 1 2 3 4 5 6 7 8; do echo tick; sleep 0.1; done")
+	pem.SetLimits(Limits{IdleTimeout: 400 * time.Millisecond})
+	if err := runToEnd(t, pem); err != nil {
+		t.Fatalf("expected a script printing progress to finish, got %v", err)
+	}
+}
+
+// Unit test: script failures are not reported as limit errors
+func TestRunScript_ScriptErrorIsNotALimit(t *testing.T) {
+	pem := fakeEnv(t, "echo 'MemoryError' >&2; exit 1")
+	pem.SetLimits(Limits{Timeout: time.Minute})
+
+	err := runToEnd(t, pem)
+	if err == nil || AsLimitError(err) != nil {
+		t.Fatalf("expected a plain exit error, got %v", err)
+	}
+}
+
+// Unit test: a script running out of memory under the limit is reported as
+// a memory limit error
+func TestRunScript_Memory(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory limits are only enforced on Linux")
+	}
+	// Resolve shims to the interpreter itself, which the environment's
+	// pyvenv.cfg points at as a real virtual environment does
+	out, err := exec.Command("python3", "-c", "import sys, platform; print(sys.executable); print(platform.python_version())").Output()
+	if err != nil {
+		t.Skip("python3 not available, skipping memory limit test")
+	}
+	python, version, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	python, _ = filepath.EvalSymlinks(python)
+	pem := fakeEnv(t, "exec "+python+" -c 'x = bytearray(1 << 30)'")
+	cfg := "home = " + filepath.Dir(python) + "\nversion = " + version + "\n"
+	os.WriteFile(filepath.Join(pem.envPath, "pyvenv.cfg"), []byte(cfg), 0644)
+	pem.SetLimits(Limits{MemoryBytes: 256 << 20})
+
+	err = runToEnd(t, pem)
+	if le := AsLimitError(err); le == nil || le.Limit != LimitMemory {
+		t.Fatalf("expected a memory limit error, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package pyenv
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Access lists the directories a sandboxed script may use besides the Python
+// installation and the system directories it needs, which are read-only.
+// Nothing else of the file system is visible: the home directory and the
+// temp directories are empty private ones, and there is no network.
+type Access struct {
+	ReadOnly []string
+	Writable []string
+}
+
+// Sandbox modes reported in SandboxInfo.
+const (
+	SandboxBubblewrap = "bubblewrap"
+	SandboxNamespaces = "namespaces"
+	SandboxNone       = "none"
+)
+
+// SandboxInfo describes how scripts are isolated on this system.
+type SandboxInfo struct {
+	Mode    string `json:"mode"`
+	Warning string `json:"warning,omitempty"` // why scripts run without a sandbox
+}
+
+// sandboxBind makes Src visible at Dst inside the sandbox.
+type sandboxBind struct {
+	Src      string `json:"src"`
+	Dst      string `json:"dst"`
+	Writable bool   `json:"writable,omitempty"`
+}
+
+// sandboxSpec is the file system layout of a sandbox: an empty read-only
+// root with the binds applied in order. Hidden directories are empty tmpfs
+// mounts made before the binds.
+type sandboxSpec struct {
+	Hidden []string      `json:"hidden"`
+	Binds  []sandboxBind `json:"binds"`
+	Probe  bool          `json:"probe,omitempty"` // only check the sandbox can be set up
+
+	// AddressLimit is the RLIMIT_AS in bytes set right before python starts
+	AddressLimit uint64 `json:"address_limit,omitempty"`
+}
+
+// systemPaths are the parts of the root file system a sandboxed Python needs
+// besides its installation: programs and shared libraries, and the
+// configuration they read, such as the dynamic linker cache, certificates
+// and the local time zone. Paths missing on a system are left out, and
+// symlinked ones are bound from their targets.
+var systemPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32",
+	"/etc/ld.so.cache", "/etc/ld.so.conf", "/etc/ld.so.conf.d",
+	"/etc/ssl", "/etc/pki", "/etc/ca-certificates", "/etc/alternatives",
+	"/etc/localtime", "/etc/nsswitch.conf", "/etc/mime.types", "/etc/fonts",
+}
+
+// sandboxSpec builds the layout a script with the given access runs in: the
+// system paths, the virtual environment and its base Python read-only, plus
+// the directories of access and the targets of symlinks inside the
+// read-only ones, which is how staged input files point at the originals.
+func (pem *PythonEnvManager) sandboxSpec(access Access) sandboxSpec {
+	spec := sandboxSpec{Hidden: hiddenDirs()}
+	seen := make(map[string]bool)
+	add := func(path string, writable bool) {
+		dst, err := filepath.Abs(path)
+		if err != nil {
+			return
+		}
+		src, err := filepath.EvalSymlinks(dst)
+		if err != nil || seen[dst] {
+			return
+		}
+		seen[dst] = true
+		spec.Binds = append(spec.Binds, sandboxBind{Src: src, Dst: dst, Writable: writable})
+	}
+
+	for _, path := range systemPaths {
+		add(path, false)
+	}
+	add(pem.envPath, false)
+	for _, path := range basePython(pem.envPath) {
+		add(path, false)
+	}
+	for _, dir := range access.ReadOnly {
+		add(dir, false)
+		for _, target := range linkTargets(dir) {
+			add(target, false)
+		}
+	}
+	for _, dir := range access.Writable {
+		add(dir, true)
+	}
+
+	// Parents go first so binds inside them stay visible
+	sort.SliceStable(spec.Binds, func(i, j int) bool {
+		return len(spec.Binds[i].Dst) < len(spec.Binds[j].Dst)
+	})
+	return spec
+}
+
+// hiddenDirs returns the existing directories a sandbox replaces with empty
+// ones: the home directory and the temp directories.
+func hiddenDirs() []string {
+	candidates := []string{os.TempDir(), "/tmp", "/var/tmp"}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, home)
+	}
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range candidates {
+		dir = filepath.Clean(dir)
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() || seen[dir] || dir == "/" {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// basePython returns what a virtual environment needs of the Python it was
+// created from, read from the "home" and version keys of pyvenv.cfg: the
+// interpreter pythonX.Y in home, which the environment's python links to,
+// the standard library in <prefix>/lib/pythonX.Y and the shared libpython
+// of that version. Nothing is returned when the prefix is the root or lies in the
+// system paths, which are bound anyway.
+func basePython(envPath string) []string {
+	f, err := os.Open(filepath.Join(envPath, "pyvenv.cfg"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var home, version string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "home":
+			home = strings.TrimSpace(value)
+		case "version", "version_info":
+			version = strings.TrimSpace(value)
+		}
+	}
+	if home == "" {
+		return nil
+	}
+
+	// home is the bin directory; the standard library is next to it
+	prefix := filepath.Dir(filepath.Clean(home))
+	if !filepath.IsAbs(prefix) || prefix == "/" || inSystemPaths(prefix) {
+		return nil
+	}
+	series := "3.*"
+	if parts := strings.Split(version, "."); len(parts) >= 2 {
+		series = parts[0] + "." + parts[1]
+	}
+	var paths []string
+	for _, pattern := range []string{
+		filepath.Join(home, "python"+series),
+		filepath.Join(prefix, "lib", "python"+series),
+		filepath.Join(prefix, "lib", "libpython"+series+"*"),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		paths = append(paths, matches...)
+	}
+	return paths
+}
+
+// inSystemPaths reports whether path is one of the system paths or lies in
+// one of them.
+func inSystemPaths(path string) bool {
+	for _, sys := range systemPaths {
+		if path == sys || strings.HasPrefix(path, sys+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTargets returns what symlinks under dir point to: the files
+// themselves, bound at their own paths so the rest of their directories
+// stays hidden, and the directories linked as a whole.
+func linkTargets(dir string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil
+		}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+		return nil
+	})
+	return targets
+}
@@ -0,0 +1,290 @@
+package pyenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv carries the sandbox layout to the helper process.
+const sandboxEnv = "LOGFORGE_SANDBOX"
+
+// helperInstalled records that SandboxMain runs at startup, so the
+// executable can act as the namespace helper.
+var helperInstalled bool
+
+var (
+	sandboxOnce sync.Once
+	sandboxInfo SandboxInfo
+	bwrapPath   string
+)
+
+// SandboxMain must be called first thing in main. When the process was
+// started as the sandbox helper it sets up the sandbox and replaces itself
+// with the script; otherwise it returns and enables the namespace sandbox.
+func SandboxMain() {
+	data, ok := os.LookupEnv(sandboxEnv)
+	if !ok {
+		helperInstalled = true
+		return
+	}
+	runHelper(data)
+}
+
+// DetectSandbox returns how scripts are isolated on this system: with
+// bubblewrap if it is installed and works, else with namespaces set up by
+// the helper, else not at all. The result is probed once per process.
+func DetectSandbox() SandboxInfo {
+	sandboxOnce.Do(func() {
+		sandboxInfo = probeSandbox()
+	})
+	return sandboxInfo
+}
+
+func probeSandbox() SandboxInfo {
+	var reasons []string
+	if path, err := exec.LookPath("bwrap"); err == nil {
+		out, err := exec.Command(path, "--ro-bind", "/", "/", "--unshare-all", "--die-with-parent", "--", "/bin/sh", "-c", ":").CombinedOutput()
+		if err == nil {
+			bwrapPath = path
+			return SandboxInfo{Mode: SandboxBubblewrap}
+		}
+		reasons = append(reasons, fmt.Sprintf("bubblewrap failed: %s", firstLine(out, err)))
+	}
+	if helperInstalled {
+		dir, err := os.MkdirTemp("", "sandbox-probe-*")
+		if err == nil {
+			defer os.RemoveAll(dir)
+			spec := sandboxSpec{
+				Hidden: hiddenDirs(),
+				Binds:  []sandboxBind{{Src: dir, Dst: dir, Writable: true}},
+				Probe:  true,
+			}
+			cmd, cerr := helperCommand(context.Background(), spec, nil)
+			if cerr == nil {
+				var out []byte
+				out, err = cmd.CombinedOutput()
+				if err == nil {
+					return SandboxInfo{Mode: SandboxNamespaces}
+				}
+				err = fmt.Errorf("%s", firstLine(out, err))
+			} else {
+				err = cerr
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("user namespaces unavailable: %v", err))
+	} else {
+		reasons = append(reasons, "bubblewrap is not installed")
+	}
+	return SandboxInfo{
+		Mode:    SandboxNone,
+		Warning: "scripts run without a sandbox (" + strings.Join(reasons, "; ") + ")",
+	}
+}
+
+// sandboxCommand returns the command running python with pyArgs in the
+// sandbox described by spec.
+func sandboxCommand(ctx context.Context, info SandboxInfo, spec sandboxSpec, python string, pyArgs []string) (*exec.Cmd, error) {
+	argv := append([]string{python}, pyArgs...)
+	switch info.Mode {
+	case SandboxBubblewrap:
+		if spec.AddressLimit > 0 {
+			// bubblewrap can't set rlimits, so a shell does it inside
+			ulimit := fmt.Sprintf(`ulimit -v %d && exec "$@"`, spec.AddressLimit/1024)
+			argv = append([]string{"/bin/sh", "-c", ulimit, "sh"}, argv...)
+		}
+		return exec.CommandContext(ctx, bwrapPath, append(bwrapArgs(spec), argv...)...), nil
+	case SandboxNamespaces:
+		return helperCommand(ctx, spec, argv)
+	}
+	return exec.CommandContext(ctx, python, pyArgs...), nil
+}
+
+// bwrapArgs translates spec into bubblewrap options, up to the "--" that
+// precedes the command. bubblewrap starts from an empty root.
+func bwrapArgs(spec sandboxSpec) []string {
+	args := []string{"--die-with-parent", "--unshare-all", "--dev", "/dev", "--proc", "/proc"}
+	for _, dir := range spec.Hidden {
+		args = append(args, "--tmpfs", dir)
+	}
+	for _, b := range spec.Binds {
+		if b.Writable {
+			args = append(args, "--bind", b.Src, b.Dst)
+		} else {
+			args = append(args, "--ro-bind", b.Src, b.Dst)
+		}
+	}
+	return append(args, "--remount-ro", "/", "--")
+}
+
+// helperCommand starts this executable as the sandbox helper in new user,
+// mount, network and IPC namespaces, mapped to root inside so it may mount.
+func helperCommand(ctx context.Context, spec sandboxSpec, argv []string) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, exe, argv...)
+	cmd.Env = append(os.Environ(), sandboxEnv+"="+string(data))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:                 syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	return cmd, nil
+}
+
+// helperFailed is the exit code of a helper that couldn't set up the sandbox.
+const helperFailed = 125
+
+// runHelper sets up the sandbox described by data in the namespaces the
+// helper was started in and then executes its arguments. It never returns.
+func runHelper(data string) {
+	runtime.LockOSThread()
+	os.Unsetenv(sandboxEnv)
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: invalid layout: %v\n", err)
+		os.Exit(helperFailed)
+	}
+	if err := enterSandbox(spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(helperFailed)
+	}
+	if spec.Probe || len(os.Args) < 2 {
+		os.Exit(0)
+	}
+	if spec.AddressLimit > 0 {
+		rlim := &unix.Rlimit{Cur: spec.AddressLimit, Max: spec.AddressLimit}
+		if err := unix.Setrlimit(unix.RLIMIT_AS, rlim); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: failed to limit memory: %v\n", err)
+			os.Exit(helperFailed)
+		}
+	}
+	err := syscall.Exec(os.Args[1], os.Args[1:], os.Environ())
+	fmt.Fprintf(os.Stderr, "sandbox: failed to start %s: %v\n", os.Args[1], err)
+	os.Exit(helperFailed)
+}
+
+// enterSandbox switches to a new root holding only the binds of spec, /dev
+// and /proc, with the hidden directories as empty tmpfs mounts. The binds
+// are cloned first, while they are still visible; the new root is built on
+// a tmpfs over the temp directory, which the sandbox hides anyway, and is
+// made read-only once everything is in place.
+func enterSandbox(spec sandboxSpec) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	wd, _ := os.Getwd()
+
+	binds := append([]sandboxBind{{Src: "/dev", Dst: "/dev", Writable: true}, {Src: "/proc", Dst: "/proc", Writable: true}}, spec.Binds...)
+	trees := make([]int, len(binds))
+	for i, b := range binds {
+		fd, err := unix.OpenTree(unix.AT_FDCWD, b.Src, unix.OPEN_TREE_CLONE|unix.O_CLOEXEC|unix.AT_RECURSIVE)
+		if err != nil {
+			return fmt.Errorf("failed to clone %s: %w", b.Src, err)
+		}
+		trees[i] = fd
+	}
+
+	root := os.TempDir()
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to create the root: %w", err)
+	}
+	for _, dir := range spec.Hidden {
+		if err := os.MkdirAll(root+dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := unix.Mount("tmpfs", root+dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+			return fmt.Errorf("failed to hide %s: %w", dir, err)
+		}
+	}
+
+	for i, b := range binds {
+		if !b.Writable {
+			if err := readOnly(trees[i], "", unix.AT_EMPTY_PATH); err != nil {
+				return fmt.Errorf("failed to make %s read-only: %w", b.Dst, err)
+			}
+		}
+		if err := mountPoint(b.Src, root+b.Dst); err != nil {
+			return fmt.Errorf("failed to create %s: %w", b.Dst, err)
+		}
+		if err := unix.MoveMount(trees[i], "", unix.AT_FDCWD, root+b.Dst, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+			return fmt.Errorf("failed to mount %s: %w", b.Dst, err)
+		}
+		unix.Close(trees[i])
+	}
+
+	// Swap the roots and drop the old one
+	old := root + "/.old-root"
+	if err := os.Mkdir(old, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", old, err)
+	}
+	if err := unix.PivotRoot(root, old); err != nil {
+		return fmt.Errorf("failed to switch to the new root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.old-root", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach the old root: %w", err)
+	}
+	os.Remove("/.old-root")
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+	if err := unix.MountSetattr(unix.AT_FDCWD, "/", 0, attr); err != nil {
+		return fmt.Errorf("failed to make / read-only: %w", err)
+	}
+
+	// Keep the working directory when it is inside a bind
+	if wd == "" || os.Chdir(wd) != nil {
+		os.Chdir("/")
+	}
+	return nil
+}
+
+// mountPoint creates what src is bound onto at dst: a directory, or an
+// empty file for a file.
+func mountPoint(src string, dst string) error {
+	if fi, err := os.Stat(src); err != nil || fi.IsDir() {
+		return os.MkdirAll(dst, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readOnly makes a mount tree read-only, including the mounts below it.
+func readOnly(dirfd int, path string, flags uint) error {
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+	return unix.MountSetattr(dirfd, path, flags|unix.AT_RECURSIVE, attr)
+}
+
+// firstLine returns the first line of a failed command's output, or its
+// error when it printed nothing.
+func firstLine(out []byte, err error) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if line == "" {
+		return err.Error()
+	}
+	return line
+}
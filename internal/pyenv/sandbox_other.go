@@ -0,0 +1,24 @@
+//go:build !linux
+
+package pyenv
+
+import (
+	"context"
+	"os/exec"
+)
+
+// SandboxMain is a no-op on non-Linux platforms.
+func SandboxMain() {}
+
+// DetectSandbox reports that scripts can't be sandboxed on this platform.
+func DetectSandbox() SandboxInfo {
+	return SandboxInfo{
+		Mode:    SandboxNone,
+		Warning: "scripts run without a sandbox (sandboxing is only supported on Linux)",
+	}
+}
+
+// sandboxCommand returns the command running python with pyArgs directly.
+func sandboxCommand(ctx context.Context, _ SandboxInfo, _ sandboxSpec, python string, pyArgs []string) (*exec.Cmd, error) {
+	return exec.CommandContext(ctx, python, pyArgs...), nil
+}
@@ -0,0 +1,159 @@
+package pyenv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary act as the sandbox helper, as main does.
+func TestMain(m *testing.M) {
+	SandboxMain()
+	os.Exit(m.Run())
+}
+
+// Unit test: binds are ordered parents first, keep their access and include
+// the files symlinked input files point to, but not their directories
+func TestSandboxSpec_Binds(t *testing.T) {
+	pem := NewPythonEnvManager("uv", t.TempDir())
+	input := t.TempDir()
+	output := filepath.Join(input, "nested", "out")
+	os.MkdirAll(output, 0755)
+	originals := t.TempDir()
+	os.WriteFile(filepath.Join(originals, "a.log"), []byte("x\n"), 0644)
+	if err := os.Symlink(filepath.Join(originals, "a.log"), filepath.Join(input, "a.log")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	spec := pem.sandboxSpec(Access{ReadOnly: []string{input}, Writable: []string{output}})
+
+	binds := make(map[string]sandboxBind)
+	for i, b := range spec.Binds {
+		binds[b.Dst] = b
+		if i > 0 && len(b.Dst) < len(spec.Binds[i-1].Dst) {
+			t.Errorf("bind %s comes after longer %s", b.Dst, spec.Binds[i-1].Dst)
+		}
+	}
+	for _, dir := range []string{pem.envPath, input, filepath.Join(originals, "a.log")} {
+		if b, ok := binds[dir]; !ok || b.Writable {
+			t.Errorf("expected a read-only bind of %s, got %+v", dir, b)
+		}
+	}
+	if b, ok := binds[originals]; ok {
+		t.Errorf("expected the directory of a linked file to stay hidden, got %+v", b)
+	}
+	if b, ok := binds[output]; !ok || !b.Writable {
+		t.Errorf("expected a writable bind of %s, got %+v", output, b)
+	}
+}
+
+// Unit test: only the interpreter, standard library and libpython of the
+// base Python are bound, and nothing when its prefix is the root or a
+// system path
+func TestBasePython(t *testing.T) {
+	prefix := t.TempDir()
+	for _, dir := range []string{"bin", "lib/python3.11", "lib/python3.12", "share"} {
+		os.MkdirAll(filepath.Join(prefix, dir), 0755)
+	}
+	for _, file := range []string{"bin/python3.11", "lib/libpython3.11.so.1.0", "lib/libpython3.12.so.1.0"} {
+		os.WriteFile(filepath.Join(prefix, file), nil, 0755)
+	}
+	env := t.TempDir()
+
+	for home, want := range map[string][]string{
+		filepath.Join(prefix, "bin"): {
+			filepath.Join(prefix, "bin", "python3.11"),
+			filepath.Join(prefix, "lib", "python3.11"),
+			filepath.Join(prefix, "lib", "libpython3.11.so.1.0"),
+		},
+		"/usr/bin":       nil,
+		"/bin":           nil,
+		"/opt":           nil,
+		"/usr/local/bin": nil,
+	} {
+		cfg := fmt.Sprintf("home = %s\nversion_info = 3.11.7\n", home)
+		os.WriteFile(filepath.Join(env, "pyvenv.cfg"), []byte(cfg), 0644)
+		if got := basePython(env); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("home %s: expected %v, got %v", home, want, got)
+		}
+	}
+}
+
+// Unit test: bubblewrap hides directories before applying the binds, makes
+// the root read-only and ends its options with "--"
+func TestBwrapArgs(t *testing.T) {
+	spec := sandboxSpec{
+		Hidden: []string{"/tmp"},
+		Binds: []sandboxBind{
+			{Src: "/data/in", Dst: "/tmp/in"},
+			{Src: "/data/out", Dst: "/tmp/in/out", Writable: true},
+		},
+	}
+	got := strings.Join(bwrapArgs(spec), " ")
+	want := "--tmpfs /tmp --ro-bind /data/in /tmp/in --bind /data/out /tmp/in/out --remount-ro / --"
+	if !strings.HasSuffix(got, want) {
+		t.Fatalf("expected args ending with %q, got %q", want, got)
+	}
+	if strings.Contains(got, "--ro-bind / /") {
+		t.Errorf("expected an empty root, got %q", got)
+	}
+}
+
+// Unit test: a sandboxed script reads its input and writes its output, but
+// neither sees nor changes anything else, in the temp directories or
+// elsewhere on the file system
+func TestRunScript_Sandbox(t *testing.T) {
+	if info := DetectSandbox(); info.Mode == SandboxNone {
+		t.Skipf("no sandbox available: %s", info.Warning)
+	}
+	input, output, outside := t.TempDir(), t.TempDir(), t.TempDir()
+	// The script's directory is bound, but not the rest of the repository
+	elsewhere, err := filepath.Abs(filepath.Join("..", "model", "model.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(input, "a.log"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("s\n"), 0644)
+
+	pem := fakeEnv(t, fmt.Sprintf(`
+cat %[1]q/a.log || exit 10
+echo ok > %[2]q/result || exit 11
+echo bad > %[1]q/a.log 2>/dev/null && exit 12
+[ -e %[3]q/secret ] && exit 13
+echo leaked > %[3]q/leak 2>/dev/null
+[ -e %[4]q ] && exit 14
+[ -e /etc/hostname ] && exit 15
+exit 0`, input, output, outside, elsewhere))
+
+	run, err := pem.RunScript(context.Background(), "script.py", nil, Access{ReadOnly: []string{input}, Writable: []string{output}})
+	if err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	stderr := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(run.Stderr)
+		stderr <- data
+	}()
+	out, _ := io.ReadAll(run.Stdout)
+	errOut := <-stderr
+	if err := run.Wait(); err != nil {
+		t.Fatalf("sandboxed script failed: %v: %s", err, errOut)
+	}
+
+	if strings.TrimSpace(string(out)) != "hello" {
+		t.Errorf("expected the input to be readable, got %q", out)
+	}
+	if data, err := os.ReadFile(filepath.Join(output, "result")); err != nil || string(data) != "ok\n" {
+		t.Errorf("expected the output to be written, got %q, %v", data, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(input, "a.log")); string(data) != "hello\n" {
+		t.Errorf("expected the input to be unchanged, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "leak")); err == nil {
+		t.Errorf("expected writes outside the output directory not to reach the host")
+	}
+}
@@ -0,0 +1,119 @@
+package quality
+
+import (
+	"fmt"
+	"strings"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/schema"
+)
+
+// What a run does when its rows fall short of the thresholds.
+const (
+	Degrade = "degrade"
+	Fail    = "fail"
+	Repair  = "repair"
+)
+
+// Validate checks that the thresholds are percentages and the action is
+// known.
+func Validate(t model.QualityThresholds) error {
+	for _, th := range []struct {
+		name  string
+		value *float64
+	}{
+		{"empty values", t.MaxEmpty},
+		{"mismatched values", t.MaxMismatched},
+		{"time failures", t.MaxTimeFailures},
+		{"out-of-order times", t.MaxOutOfOrder},
+		{"future times", t.MaxFuture},
+		{"duplicate rows", t.MaxDuplicates},
+	} {
+		if th.value != nil && (*th.value < 0 || *th.value > 100) {
+			return fmt.Errorf("threshold of %s must be a percentage between 0 and 100", th.name)
+		}
+	}
+	switch t.Action {
+	case "", Degrade, Fail, Repair:
+		return nil
+	}
+	return fmt.Errorf("unknown quality action %q", t.Action)
+}
+
+// Check returns the thresholds r falls short of, one sentence each.
+func Check(r *model.QualityReport, t model.QualityThresholds) []string {
+	var violations []string
+	over := func(max *float64, n int, total int) (float64, bool) {
+		if max == nil || total == 0 {
+			return 0, false
+		}
+		p := 100 * float64(n) / float64(total)
+		return p, p > *max
+	}
+
+	for _, c := range r.Columns {
+		if len(t.Columns) > 0 && !contains(t.Columns, c.Name) {
+			continue
+		}
+		if p, bad := over(t.MaxEmpty, c.Empty, c.Rows); bad {
+			violations = append(violations, fmt.Sprintf("column %q is empty in %.1f%% of rows (at most %g%%)", c.Name, p, *t.MaxEmpty))
+		}
+		if p, bad := over(t.MaxMismatched, c.Mismatched, c.Rows-c.Empty); bad {
+			violations = append(violations, fmt.Sprintf("%.1f%% of the values of column %q are not of type %s (at most %g%%)%s", p, c.Name, c.Type, *t.MaxMismatched, examples(c.Examples)))
+		}
+	}
+
+	times := 0
+	for _, c := range r.Columns {
+		if c.Type == schema.DateTime {
+			times += c.Rows - c.Empty
+		}
+	}
+	if p, bad := over(t.MaxTimeFailures, r.TimeFailures, times); bad {
+		violations = append(violations, fmt.Sprintf("%.1f%% of the values of time columns are not ISO 8601 times (at most %g%%)", p, *t.MaxTimeFailures))
+	}
+	if p, bad := over(t.MaxOutOfOrder, r.OutOfOrder, r.Rows); bad {
+		violations = append(violations, fmt.Sprintf("%.1f%% of rows have a time earlier than the row before (at most %g%%)", p, *t.MaxOutOfOrder))
+	}
+	if p, bad := over(t.MaxFuture, r.Future, r.Rows); bad {
+		violations = append(violations, fmt.Sprintf("%.1f%% of rows have a time in the future (at most %g%%)", p, *t.MaxFuture))
+	}
+	if p, bad := over(t.MaxDuplicates, r.Duplicates, r.Rows); bad {
+		violations = append(violations, fmt.Sprintf("%.1f%% of rows repeat an earlier row of their file (at most %g%%)", p, *t.MaxDuplicates))
+	}
+	return violations
+}
+
+// Problem describes violations as the error a repair is asked to fix.
+func Problem(violations []string) string {
+	var b strings.Builder
+	b.WriteString("The script ran without errors, but the rows it wrote fail data quality checks:\n")
+	for _, v := range violations {
+		b.WriteString("- ")
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+	b.WriteString("Fix the parsing so the rows pass these checks.")
+	return b.String()
+}
+
+// examples returns the examples of a violation to append to it.
+func examples(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return ", such as " + strings.Join(quoted, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package quality
+
+import (
+	"strings"
+	"testing"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/schema"
+)
+
+func pct(v float64) *float64 { return &v }
+
+// --- Unit Tests ---
+
+// Unit test: thresholds are percentages and actions are known
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		t  model.QualityThresholds
+		ok bool
+	}{
+		{model.QualityThresholds{}, true},
+		{model.QualityThresholds{MaxEmpty: pct(0), MaxDuplicates: pct(100), Action: Repair}, true},
+		{model.QualityThresholds{MaxMismatched: pct(-1)}, false},
+		{model.QualityThresholds{MaxFuture: pct(101)}, false},
+		{model.QualityThresholds{Action: "retry"}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(c.t); (err == nil) != c.ok {
+			t.Errorf("%+v: err = %v", c.t, err)
+		}
+	}
+}
+
+// Unit test: only the thresholds set and the columns listed are checked,
+// and a share equal to a threshold passes
+func TestCheck(t *testing.T) {
+	r := &model.QualityReport{
+		Rows: 100,
+		Columns: []model.ColumnQuality{
+			{Name: "status", Type: schema.Integer, Rows: 100, Mismatched: 3, Examples: []string{"OK"}},
+			{Name: "msg", Type: schema.String, Rows: 100, Empty: 40},
+			{Name: "time", Type: schema.DateTime, Rows: 100, Empty: 20, TimeFailures: 8},
+		},
+		TimeFailures: 8,
+		OutOfOrder:   5,
+		Duplicates:   2,
+	}
+	if v := Check(r, model.QualityThresholds{}); len(v) != 0 {
+		t.Errorf("no thresholds: %q", v)
+	}
+
+	v := Check(r, model.QualityThresholds{MaxEmpty: pct(30), MaxMismatched: pct(1), MaxTimeFailures: pct(5), MaxOutOfOrder: pct(5), MaxDuplicates: pct(1)})
+	if len(v) != 4 {
+		t.Fatalf("violations = %q", v)
+	}
+	if !strings.Contains(v[0], `"status"`) || !strings.Contains(v[0], `"OK"`) || !strings.Contains(v[1], `"msg"`) {
+		t.Errorf("violations = %q", v)
+	}
+	if !strings.Contains(v[2], "10.0%") || !strings.Contains(v[3], "repeat") {
+		t.Errorf("violations = %q", v)
+	}
+
+	v = Check(r, model.QualityThresholds{MaxEmpty: pct(10), MaxMismatched: pct(1), Columns: []string{"time"}})
+	if len(v) != 1 || !strings.Contains(v[0], `"time"`) {
+		t.Errorf("time column only: %q", v)
+	}
+}
+
+// Unit test: the repair problem lists each violation
+func TestProblem(t *testing.T) {
+	p := Problem([]string{"first", "second"})
+	if !strings.Contains(p, "- first\n- second\n") {
+		t.Errorf("problem = %q", p)
+	}
+}
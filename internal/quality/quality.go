@@ -0,0 +1,295 @@
+// Package quality checks the rows a run writes: how many rows of each
+// column lack a value, how many values aren't of the column's type, times
+// that don't parse, run backwards or lie in the future, and rows repeating
+// an earlier row of their file. Runs hold the report to the thresholds of
+// their project.
+package quality
+
+import (
+	"hash/maphash"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/schema"
+)
+
+// maxExamples is how many values not of a column's type a report lists.
+const maxExamples = 3
+
+// maxExample is the length in characters examples are cut to.
+const maxExample = 80
+
+// maxRows bounds the rows remembered to find duplicates; rows past it are
+// only compared with the ones remembered.
+const maxRows = 1 << 21
+
+// Slack allowed to times in the future: clocks drift, and a time without
+// an offset may be in a zone ahead of this computer's.
+const (
+	futureSlack      = 5 * time.Minute
+	naiveFutureSlack = 24 * time.Hour
+)
+
+// timeWords are words of a column name that make it a time column: its
+// values that don't parse as times are failures.
+var timeWords = []string{"time", "timestamp", "datetime", "date", "ts", "when"}
+
+// Checker checks the rows of a run as they are written. A file started
+// again drops the rows of its earlier start, as the writers do. It isn't
+// safe for concurrent use.
+type Checker struct {
+	schema []model.ColumnSchema
+	now    func() time.Time
+	seed   maphash.Seed
+	files  map[string]*fileCheck
+	seen   int // rows remembered to find duplicates, over all files
+}
+
+// fileCheck is what the rows of a file come to.
+type fileCheck struct {
+	columns    []*column
+	rows       int
+	seen       map[uint64]struct{}
+	duplicates int
+}
+
+// column is what the values of a file's column come to.
+type column struct {
+	name     string
+	declared string // type in the schema; "" when undeclared
+	timeName bool   // the name makes it a time column
+	values   int    // rows with a non-empty value
+	types    map[string]int
+	examples map[string][]string // values of each type, the first few
+	times    timeline
+}
+
+// timeline follows the times of a column in row order.
+type timeline struct {
+	last       time.Time
+	started    bool
+	outOfOrder int // times earlier than the one before
+	future     int // times later than now
+}
+
+// New returns a checker typing the columns declared in columns by the
+// schema.
+func New(columns []model.ColumnSchema) *Checker {
+	return &Checker{schema: columns, now: time.Now, seed: maphash.MakeSeed(), files: make(map[string]*fileCheck)}
+}
+
+// StartFile begins the rows of file with the given columns.
+func (c *Checker) StartFile(file string, columns []string) {
+	if old := c.files[file]; old != nil {
+		c.seen -= len(old.seen)
+	}
+	f := &fileCheck{seen: make(map[uint64]struct{})}
+	for _, name := range output.Columns(columns) {
+		f.columns = append(f.columns, &column{
+			name:     name,
+			declared: schema.Declared(c.schema, name),
+			timeName: hasTimeWord(name),
+			types:    make(map[string]int),
+			examples: make(map[string][]string),
+		})
+	}
+	c.files[file] = f
+}
+
+// WriteRow checks a row of a started file.
+func (c *Checker) WriteRow(file string, values []any) {
+	f := c.files[file]
+	if f == nil {
+		return
+	}
+	f.rows++
+	now := c.now()
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	for i, col := range f.columns {
+		var v any
+		if i < len(values) {
+			v = values[i]
+		}
+		if !empty(v) {
+			col.add(v, now)
+		}
+		// Type and text, so 1 and "1" differ
+		h.WriteString(schema.Infer(v))
+		h.WriteByte(0)
+		h.WriteString(output.Text(v))
+		h.WriteByte(0)
+	}
+	sum := h.Sum64()
+	if _, ok := f.seen[sum]; ok {
+		f.duplicates++
+	} else if c.seen < maxRows {
+		f.seen[sum] = struct{}{}
+		c.seen++
+	}
+}
+
+// empty reports whether v is no value: null or blank text.
+func empty(v any) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+// add checks a non-empty value of the column.
+func (col *column) add(v any, now time.Time) {
+	col.values++
+	typ := schema.Infer(v)
+	col.types[typ]++
+	if len(col.examples[typ]) < maxExamples {
+		text := output.Text(v)
+		if r := []rune(text); len(r) > maxExample {
+			text = string(r[:maxExample]) + "…"
+		}
+		col.examples[typ] = append(col.examples[typ], text)
+	}
+	if typ == schema.DateTime {
+		s, _ := v.(string)
+		col.times.add(s, now)
+	}
+}
+
+// add follows a time written as s.
+func (tl *timeline) add(s string, now time.Time) {
+	// Ordered on the clock as written, like the rows of the file
+	wall, _, _ := output.ParseTime(s, time.UTC)
+	if tl.started && wall.Before(tl.last) {
+		tl.outOfOrder++
+	}
+	tl.last, tl.started = wall, true
+
+	t, _, _ := output.ParseTime(s, time.Local)
+	slack := naiveFutureSlack
+	if hasOffset(s) {
+		slack = futureSlack
+	}
+	if t.After(now.Add(slack)) {
+		tl.future++
+	}
+}
+
+// hasOffset reports whether a time written as s names its offset.
+func hasOffset(s string) bool {
+	if len(s) <= len("2006-01-02") {
+		return false
+	}
+	rest := s[len("2006-01-02"):]
+	return strings.HasSuffix(rest, "Z") || strings.ContainsAny(rest, "+-")
+}
+
+// typ returns the type of the column: the declared one, or the one most
+// of its values have.
+func (col *column) typ() string {
+	if col.declared != "" {
+		return col.declared
+	}
+	return schema.Majority(col.types)
+}
+
+// timeColumn reports whether the column holds times.
+func (col *column) timeColumn() bool {
+	return col.typ() == schema.DateTime || (col.declared == "" && col.timeName)
+}
+
+// fits reports whether a value of type typ fits a column of type want.
+func fits(typ string, want string) bool {
+	switch want {
+	case "", schema.String:
+		return true
+	case schema.Number:
+		return typ == schema.Number || typ == schema.Integer
+	}
+	return typ == want
+}
+
+// Report returns the report of the rows checked so far. Columns of the
+// same name in several files are reported as one.
+func (c *Checker) Report() *model.QualityReport {
+	files := make([]string, 0, len(c.files))
+	for file := range c.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	r := &model.QualityReport{}
+	var order []string
+	merged := make(map[string]*model.ColumnQuality)
+	for _, file := range files {
+		f := c.files[file]
+		fq := model.FileQuality{File: file, Rows: f.rows, Duplicates: f.duplicates}
+		for _, col := range f.columns {
+			m := merged[col.name]
+			if m == nil {
+				m = &model.ColumnQuality{Name: col.name, Declared: col.declared != ""}
+				merged[col.name] = m
+				order = append(order, col.name)
+			}
+			m.Rows += f.rows
+			m.Empty += f.rows - col.values
+
+			typ := col.typ()
+			if col.timeColumn() {
+				typ = schema.DateTime
+				if fq.TimeColumn == "" && col.times.started {
+					fq.TimeColumn = col.name
+					fq.OutOfOrder, fq.Future = col.times.outOfOrder, col.times.future
+				}
+			}
+			m.Type = schema.Merge(m.Type, typ)
+			for _, t := range schema.Types {
+				n := col.types[t]
+				if n == 0 || fits(t, typ) {
+					continue
+				}
+				m.Mismatched += n
+				if typ == schema.DateTime {
+					m.TimeFailures += n
+				}
+				for _, ex := range col.examples[t] {
+					if len(m.Examples) < maxExamples {
+						m.Examples = append(m.Examples, ex)
+					}
+				}
+			}
+		}
+		r.Files = append(r.Files, fq)
+		r.Rows += f.rows
+		r.OutOfOrder += fq.OutOfOrder
+		r.Future += fq.Future
+		r.Duplicates += f.duplicates
+		r.Approximate = r.Approximate || len(f.seen)+f.duplicates < f.rows
+	}
+	for _, name := range order {
+		m := merged[name]
+		r.TimeFailures += m.TimeFailures
+		r.Columns = append(r.Columns, *m)
+	}
+	return r
+}
+
+// hasTimeWord reports whether one of the words of a column name makes it a
+// time column.
+func hasTimeWord(name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		for _, t := range timeWords {
+			if w == t {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package quality
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/schema"
+
+	"pgregory.net/rapid"
+)
+
+// valueGen draws a streamed value from a small set, so rows repeat.
+var valueGen = rapid.SampledFrom([]any{
+	nil, "", " ", "a", "b", json.Number("1"), json.Number("2.5"), true,
+	"2024-05-01T10:00:00", "10.0.0.1",
+})
+
+// Feature: network-log-formatter, Property 29: 质量报告的行数、空值与重复行计数一致
+// For any rows written to any files, each column's empty and non-empty
+// values add up to the rows, and each file's duplicates are its rows less
+// its distinct rows.
+func TestProperty29_ReportCounts(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		c := New(nil)
+		files := rapid.IntRange(1, 3).Draw(rt, "files")
+		wantRows, wantDuplicates := 0, 0
+		nonEmpty := make(map[string]int)
+		for i := 0; i < files; i++ {
+			file := fmt.Sprintf("f%d.log", i)
+			c.StartFile(file, []string{"x", "y"})
+			distinct := make(map[string]bool)
+			rows := rapid.IntRange(0, 20).Draw(rt, "rows")
+			for r := 0; r < rows; r++ {
+				row := []any{valueGen.Draw(rt, "x"), valueGen.Draw(rt, "y")}
+				c.WriteRow(file, row)
+				key := fmt.Sprintf("%s:%v|%s:%v", schema.Infer(row[0]), row[0], schema.Infer(row[1]), row[1])
+				if distinct[key] {
+					wantDuplicates++
+				}
+				distinct[key] = true
+				for j, name := range []string{"x", "y"} {
+					if !empty(row[j]) {
+						nonEmpty[name]++
+					}
+				}
+			}
+			wantRows += rows
+		}
+
+		r := c.Report()
+		if r.Rows != wantRows || r.Duplicates != wantDuplicates || len(r.Files) != files {
+			rt.Fatalf("rows %d, duplicates %d, files %d; want %d, %d, %d", r.Rows, r.Duplicates, len(r.Files), wantRows, wantDuplicates, files)
+		}
+		for _, col := range r.Columns {
+			if col.Rows != wantRows || col.Rows-col.Empty != nonEmpty[col.Name] {
+				rt.Fatalf("column %s: %d rows, %d empty; want %d rows, %d values", col.Name, col.Rows, col.Empty, wantRows, nonEmpty[col.Name])
+			}
+			if col.Mismatched > col.Rows-col.Empty {
+				rt.Fatalf("column %s: %d mismatched of %d values", col.Name, col.Mismatched, col.Rows-col.Empty)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: values not of the declared or inferred type are counted with
+// examples, and integers fit number columns
+func TestReport_Types(t *testing.T) {
+	c := New([]model.ColumnSchema{{Name: "bytes", Type: schema.Number}})
+	c.StartFile("a.log", []string{"status", "bytes", "msg"})
+	for i := 0; i < 97; i++ {
+		c.WriteRow("a.log", []any{json.Number("200"), json.Number(fmt.Sprint(i)), fmt.Sprint("m", i)})
+	}
+	c.WriteRow("a.log", []any{"OK", "n/a", nil})
+	c.WriteRow("a.log", []any{"-", json.Number("1.5"), ""})
+	c.WriteRow("a.log", []any{"OK", json.Number("2"), "x"})
+
+	r := c.Report()
+	cols := make(map[string]model.ColumnQuality)
+	for _, col := range r.Columns {
+		cols[col.Name] = col
+	}
+	status := cols["status"]
+	if status.Type != schema.Integer || status.Declared || status.Mismatched != 3 || len(status.Examples) != 3 || status.Examples[0] != "OK" {
+		t.Errorf("status = %+v", status)
+	}
+	bytes := cols["bytes"]
+	if bytes.Type != schema.Number || !bytes.Declared || bytes.Mismatched != 1 || bytes.Examples[0] != "n/a" {
+		t.Errorf("bytes = %+v", bytes)
+	}
+	msg := cols["msg"]
+	if msg.Empty != 2 || msg.Mismatched != 0 {
+		t.Errorf("msg = %+v", msg)
+	}
+}
+
+// Unit test: values of time columns that don't parse are time failures,
+// and times running backwards or lying in the future are counted
+func TestReport_Times(t *testing.T) {
+	c := New(nil)
+	c.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	c.StartFile("a.log", []string{"timestamp", "event_time"})
+	rows := [][]any{
+		{"2024-05-01T10:00:00Z", "2024-05-01"},
+		{"2024-05-01T09:00:00Z", "yesterday"},
+		{"01/05/2024 10:00", "2024-05-01"},
+		{"2024-05-01T11:00:00Z", nil},
+		{"2024-05-01T13:00:00Z", "2024-05-01"},
+		{"2024-05-03T13:00:00", "2024-05-01"},
+	}
+	for _, row := range rows {
+		c.WriteRow("a.log", row)
+	}
+
+	r := c.Report()
+	if r.TimeFailures != 2 {
+		t.Errorf("time failures = %d, want 2", r.TimeFailures)
+	}
+	// Event times are mostly strings, but the name makes it a time column
+	if r.Columns[1].Type != schema.DateTime || r.Columns[1].TimeFailures != 1 {
+		t.Errorf("event_time = %+v", r.Columns[1])
+	}
+	f := r.Files[0]
+	if f.TimeColumn != "timestamp" || f.OutOfOrder != 1 || f.Future != 2 {
+		t.Errorf("file = %+v", f)
+	}
+	if r.OutOfOrder != 1 || r.Future != 2 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+// Unit test: duplicates are rows repeating an earlier row of the same file,
+// compared by type and text, and starting a file again drops its rows
+func TestReport_Duplicates(t *testing.T) {
+	c := New(nil)
+	c.StartFile("a.log", []string{"v"})
+	c.WriteRow("a.log", []any{"1"})
+	c.WriteRow("a.log", []any{json.Number("1")})
+	c.WriteRow("a.log", []any{"1"})
+	c.StartFile("b.log", []string{"v"})
+	c.WriteRow("b.log", []any{"1"})
+	if r := c.Report(); r.Duplicates != 1 || r.Rows != 4 || r.Approximate {
+		t.Errorf("report = %+v", r)
+	}
+
+	c.StartFile("a.log", []string{"v"})
+	c.WriteRow("a.log", []any{"2"})
+	if r := c.Report(); r.Duplicates != 0 || r.Rows != 2 || c.seen != 2 {
+		t.Errorf("report after restart = %+v (%d remembered)", r, c.seen)
+	}
+	// Rows of files not started are ignored
+	c.WriteRow("c.log", []any{"1"})
+	if r := c.Report(); r.Rows != 2 {
+		t.Errorf("rows = %d", r.Rows)
+	}
+}
+
+// Unit test: past the rows remembered duplicates are only approximate
+func TestReport_Approximate(t *testing.T) {
+	c := New(nil)
+	c.seen = maxRows - 1
+	c.StartFile("a.log", []string{"v"})
+	c.WriteRow("a.log", []any{"1"})
+	c.WriteRow("a.log", []any{"2"})
+	c.WriteRow("a.log", []any{"1"})
+	c.WriteRow("a.log", []any{"2"})
+	r := c.Report()
+	if r.Duplicates != 1 || !r.Approximate {
+		t.Errorf("report = %+v", r)
+	}
+}
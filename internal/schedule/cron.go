@@ -0,0 +1,191 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a parsed cron expression of five fields: minute, hour, day of
+// month, month and day of week. Fields take *, values, ranges (a-b), steps
+// (*/n, a-b/n) and lists of those; months and days of week also take their
+// three-letter English names, and Sunday is 0 or 7. The macros @hourly,
+// @daily, @midnight, @weekly, @monthly, @yearly and @annually stand for
+// their usual expressions.
+type Expr struct {
+	minute, hour, dom, month, dow field
+	// anyDay is set when the day of month or the day of week is *: a day
+	// then has to match both, otherwise either of them
+	anyDay bool
+}
+
+// field is a set of the values of a field, one bit each.
+type field uint64
+
+func (f field) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+// macros are the expressions the @ names stand for.
+var macros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// horizon is how far ahead Next looks for a matching minute; expressions
+// matching none within it, like 30 February, never fire.
+const horizon = 5 * 366 * 24 * time.Hour
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Expr, error) {
+	s := strings.TrimSpace(strings.ToLower(expr))
+	if m, ok := macros[s]; ok {
+		s = m
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q: want 5 fields, got %d", expr, len(fields))
+	}
+
+	e := &Expr{}
+	var err error
+	if e.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron expression %q: minute: %w", expr, err)
+	}
+	if e.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron expression %q: hour: %w", expr, err)
+	}
+	if e.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron expression %q: day of month: %w", expr, err)
+	}
+	if e.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron expression %q: month: %w", expr, err)
+	}
+	if e.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("cron expression %q: day of week: %w", expr, err)
+	}
+	if e.dow.has(7) {
+		e.dow |= 1
+	}
+	e.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+
+	ref := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if e.Next(ref).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	return e, nil
+}
+
+// parseField parses a comma separated list of a field's values, ranges and
+// steps between min and max. names, when given, name the values from min
+// on.
+func parseField(s string, min, max int, names []string) (field, error) {
+	var f field
+	for _, part := range strings.Split(s, ",") {
+		expr, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			expr, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case expr == "*":
+		case strings.Contains(expr, "-"):
+			i := strings.IndexByte(expr, '-')
+			var err error
+			if lo, err = value(expr[:i], min, max, names); err != nil {
+				return 0, err
+			}
+			if hi, err = value(expr[i+1:], min, max, names); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("range %q runs backwards", expr)
+			}
+		default:
+			v, err := value(expr, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			f |= 1 << uint(v)
+		}
+	}
+	return f, nil
+}
+
+// value parses a single value of a field, by number or by name.
+func value(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if s == name {
+			return min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
+
+// Next returns the first minute after t the expression matches, in t's
+// location; the zero time when there is none within five years.
+func (e *Expr) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(horizon)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !e.month.has(int(m)):
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !e.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case !e.hour.has(t.Hour()):
+			next := time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+			if !next.After(t) {
+				// The hour repeats as clocks go back
+				next = t.Truncate(time.Hour).Add(time.Hour)
+			}
+			t = next
+		case !e.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day of month and day
+// of week fields.
+func (e *Expr) dayMatches(t time.Time) bool {
+	dom := e.dom.has(t.Day())
+	dow := e.dow.has(int(t.Weekday()))
+	if e.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
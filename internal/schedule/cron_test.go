@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// matches reports whether e matches the minute of t.
+func matches(e *Expr, t time.Time) bool {
+	return e.minute.has(t.Minute()) && e.hour.has(t.Hour()) && e.month.has(int(t.Month())) && e.dayMatches(t)
+}
+
+// fieldGen draws a cron field of values between min and max.
+func fieldGen(min, max int) *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		a := rapid.IntRange(min, max).Draw(t, "a")
+		b := rapid.IntRange(a, max).Draw(t, "b")
+		switch rapid.IntRange(0, 4).Draw(t, "kind") {
+		case 0:
+			return "*"
+		case 1:
+			return fmt.Sprintf("*/%d", rapid.IntRange(1, max-min+1).Draw(t, "step"))
+		case 2:
+			return fmt.Sprint(a)
+		case 3:
+			return fmt.Sprintf("%d-%d", a, b)
+		default:
+			return fmt.Sprintf("%d,%d", a, b)
+		}
+	})
+}
+
+// Feature: network-log-formatter, Property 28: 下次触发时间是之后第一个匹配的分钟
+// For any cron expression and time, Next returns a later minute the
+// expression matches, and no minute in between matches it.
+func TestProperty28_NextIsFirstMatch(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		expr := fmt.Sprintf("%s %s %s * %s",
+			fieldGen(0, 59).Draw(rt, "minute"), fieldGen(0, 23).Draw(rt, "hour"),
+			fieldGen(1, 28).Draw(rt, "dom"), fieldGen(0, 6).Draw(rt, "dow"))
+		e, err := Parse(expr)
+		if err != nil {
+			rt.Fatalf("Parse(%q): %v", expr, err)
+		}
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(rapid.IntRange(0, 366*24*60).Draw(rt, "offset")) * time.Minute)
+		from = from.Add(time.Duration(rapid.IntRange(0, 59).Draw(rt, "seconds")) * time.Second)
+
+		next := e.Next(from)
+		if !next.After(from) || !matches(e, next) || next.Second() != 0 {
+			rt.Fatalf("%q: Next(%v) = %v", expr, from, next)
+		}
+		// Every month has days 1 to 28, so a match is never more than a
+		// month away
+		for m := from.Truncate(time.Minute).Add(time.Minute); m.Before(next); m = m.Add(time.Minute) {
+			if matches(e, m) {
+				rt.Fatalf("%q: Next(%v) = %v skips %v", expr, from, next, m)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: fields take names, lists, ranges, steps and macros, and bad
+// or never firing expressions are rejected
+func TestParse(t *testing.T) {
+	from := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC) // a Friday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 3, 16, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// With both days restricted either of them matches
+		{"0 0 20 * 1", time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"5-10/5 22 * * *", time.Date(2024, 3, 15, 22, 5, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		e, err := Parse(c.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.expr, err)
+			continue
+		}
+		if got := e.Next(from); !got.Equal(c.want) {
+			t.Errorf("%q: Next = %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "x * * * *", "0 0 30 2 *"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+// Unit test: the hour skipped as clocks go forward is left out and the
+// times keep moving forward as they go back
+func TestNext_DaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	e, _ := Parse("30 2 * * *")
+	// 2:30 doesn't exist on 31 March 2024
+	got := e.Next(time.Date(2024, 3, 30, 12, 0, 0, 0, loc))
+	if want := time.Date(2024, 4, 1, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+
+	e, _ = Parse("*/30 * * * *")
+	last := time.Date(2024, 10, 27, 1, 0, 0, 0, loc)
+	for i := 0; i < 8; i++ {
+		next := e.Next(last)
+		if d := next.Sub(last); d != 30*time.Minute {
+			t.Fatalf("Next(%v) = %v, %v later", last, next, d)
+		}
+		last = next
+	}
+}
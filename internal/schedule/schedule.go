@@ -0,0 +1,263 @@
+// Package schedule runs projects on a timetable: each schedule of a project
+// names a cron expression and the input and output of its runs, and the
+// scheduler queues a run whenever one comes due while the application is
+// open. Runs due while it was closed are skipped or, if the schedule asks
+// for it, caught up with a single run on start.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// Missed-run policies of a schedule.
+const (
+	Skip    = "skip"
+	CatchUp = "catch_up"
+)
+
+// DefaultOutputName is the output name template of schedules without one.
+const DefaultOutputName = "{project}_{date}_{time}"
+
+// Interval is how often the scheduler looks for schedules that came due.
+const Interval = 30 * time.Second
+
+// Grace is how late a run may be noticed and still count as on time; a
+// later one was missed, say while the application was closed or the
+// computer asleep.
+const Grace = 2 * time.Minute
+
+// lookback bounds how far back missed runs are counted.
+const lookback = 366 * 24 * time.Hour
+
+// Validate checks the schedules of a project: unique IDs, a cron expression
+// that fires, both directories, a known missed-run policy and no summary
+// of incremental runs, which only see the new files.
+func Validate(schedules []model.Schedule) error {
+	seen := make(map[string]bool)
+	for i, s := range schedules {
+		if s.ID == "" {
+			return fmt.Errorf("schedule %d has no ID", i+1)
+		}
+		if seen[s.ID] {
+			return fmt.Errorf("schedule %d: ID %q is used twice", i+1, s.ID)
+		}
+		seen[s.ID] = true
+		if _, err := Parse(s.Cron); err != nil {
+			return fmt.Errorf("schedule %d: %w", i+1, err)
+		}
+		if strings.TrimSpace(s.InputDir) == "" || strings.TrimSpace(s.OutputDir) == "" {
+			return fmt.Errorf("schedule %d needs an input and an output directory", i+1)
+		}
+		if s.MissedRuns != "" && s.MissedRuns != Skip && s.MissedRuns != CatchUp {
+			return fmt.Errorf("schedule %d: unknown missed-run policy %q", i+1, s.MissedRuns)
+		}
+		if s.Summary && s.Incremental {
+			return fmt.Errorf("schedule %d: incremental runs can't sum up their rows", i+1)
+		}
+	}
+	return nil
+}
+
+// OutputName expands the output name template of a run due at the given
+// time: {project} is the project name, {date} is 20060102 and {time} 1504,
+// and {year}, {month}, {day}, {hour} and {minute} are the parts of the
+// time. Characters not allowed in file names become underscores.
+func OutputName(template string, project string, at time.Time) string {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultOutputName
+	}
+	name := strings.NewReplacer(
+		"{project}", project,
+		"{date}", at.Format("20060102"),
+		"{time}", at.Format("1504"),
+		"{year}", at.Format("2006"),
+		"{month}", at.Format("01"),
+		"{day}", at.Format("02"),
+		"{hour}", at.Format("15"),
+		"{minute}", at.Format("04"),
+	).Replace(template)
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimSpace(name)
+}
+
+// Fire queues the run of schedule s of project p due at the given time and
+// returns the ID of its job.
+type Fire func(p model.Project, s model.Schedule, at time.Time) (string, error)
+
+// States keeps the state of each project's schedules;
+// project.ScheduleStore is one.
+type States interface {
+	Load(projectID string) (map[string]model.ScheduleState, error)
+	Save(projectID string, states map[string]model.ScheduleState) error
+	Delete(projectID string) error
+}
+
+// Scheduler queues the runs of the projects' schedules as they come due.
+// A new or re-enabled schedule starts counting from the time it is first
+// seen, so it doesn't catch up on runs from before.
+type Scheduler struct {
+	mu       sync.Mutex // serializes ticks with Forget
+	projects func() ([]model.Project, error)
+	states   States
+	fire     Fire
+}
+
+// New returns a scheduler going through the projects the given function
+// lists, keeping the schedules' states in states and queueing runs with
+// fire.
+func New(projects func() ([]model.Project, error), states States, fire Fire) *Scheduler {
+	return &Scheduler{projects: projects, states: states, fire: fire}
+}
+
+// Run looks for due schedules right away, which catches up on runs missed
+// while the application was closed, and then every Interval until ctx is
+// done.
+func (sc *Scheduler) Run(ctx context.Context) {
+	sc.Tick(time.Now())
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sc.Tick(now)
+		}
+	}
+}
+
+// Tick queues the runs of the schedules that came due by now.
+func (sc *Scheduler) Tick(now time.Time) {
+	projects, err := sc.projects()
+	if err != nil {
+		fmt.Printf("warning: schedules not checked: %v\n", err)
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, p := range projects {
+		if err := sc.tickProject(p, now); err != nil {
+			fmt.Printf("warning: schedules of project %s: %v\n", p.ID, err)
+		}
+	}
+}
+
+// tickProject queues the due runs of p's schedules and drops the states of
+// schedules that are gone.
+func (sc *Scheduler) tickProject(p model.Project, now time.Time) error {
+	states, err := sc.states.Load(p.ID)
+	if err != nil {
+		return err
+	}
+	changed := false
+	current := make(map[string]bool)
+	for _, s := range p.Schedules {
+		current[s.ID] = true
+		st, had := states[s.ID]
+		expr, err := Parse(s.Cron)
+		if !s.Enabled || err != nil {
+			// Forget where it was, so enabling it again doesn't catch up
+			if had && !st.LastSlot.IsZero() {
+				st.LastSlot = time.Time{}
+				states[s.ID] = st
+				changed = true
+			}
+			continue
+		}
+		if st.LastSlot.IsZero() {
+			st.LastSlot = now
+			states[s.ID] = st
+			changed = true
+			continue
+		}
+
+		due, n := slots(expr, st.LastSlot.In(now.Location()), now)
+		if n == 0 {
+			continue
+		}
+		changed = true
+		st.LastSlot = due
+		st.Missed += n - 1
+		if now.Sub(due) > Grace && s.MissedRuns != CatchUp {
+			st.Missed++
+			states[s.ID] = st
+			continue
+		}
+		jobID, err := sc.fire(p, s, due)
+		if err != nil {
+			st.LastError = err.Error()
+			st.Missed++
+		} else {
+			ran := now
+			st.LastRun, st.LastJobID, st.LastError = &ran, jobID, ""
+		}
+		states[s.ID] = st
+	}
+	for id := range states {
+		if !current[id] {
+			delete(states, id)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if len(states) == 0 {
+		return sc.states.Delete(p.ID)
+	}
+	return sc.states.Save(p.ID, states)
+}
+
+// slots returns the latest time expr matches after last and no later than
+// now, and how many times it matches in between.
+func slots(expr *Expr, last time.Time, now time.Time) (time.Time, int) {
+	if now.Sub(last) > lookback {
+		last = now.Add(-lookback)
+	}
+	var due time.Time
+	n := 0
+	for t := expr.Next(last); !t.IsZero() && !t.After(now); t = expr.Next(t) {
+		due = t
+		n++
+	}
+	return due, n
+}
+
+// Forget drops the schedule states of a deleted project.
+func (sc *Scheduler) Forget(projectID string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.states.Delete(projectID)
+}
+
+// Status returns the schedules of p with their state and the time each
+// next comes due after now.
+func (sc *Scheduler) Status(p model.Project, now time.Time) ([]model.ScheduleStatus, error) {
+	sc.mu.Lock()
+	states, err := sc.states.Load(p.ID)
+	sc.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]model.ScheduleStatus, 0, len(p.Schedules))
+	for _, s := range p.Schedules {
+		status := model.ScheduleStatus{Schedule: s, State: states[s.ID]}
+		if expr, err := Parse(s.Cron); err == nil && s.Enabled {
+			next := expr.Next(now)
+			status.Next = &next
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
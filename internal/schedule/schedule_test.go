@@ -0,0 +1,204 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+)
+
+// memStates keeps schedule states in memory.
+type memStates map[string]map[string]model.ScheduleState
+
+func (m memStates) Load(projectID string) (map[string]model.ScheduleState, error) {
+	states := make(map[string]model.ScheduleState)
+	for id, st := range m[projectID] {
+		states[id] = st
+	}
+	return states, nil
+}
+
+func (m memStates) Save(projectID string, states map[string]model.ScheduleState) error {
+	m[projectID] = states
+	return nil
+}
+
+func (m memStates) Delete(projectID string) error {
+	delete(m, projectID)
+	return nil
+}
+
+// firing is a run a scheduler queued.
+type firing struct {
+	schedule string
+	at       time.Time
+}
+
+// newTestScheduler returns a scheduler of the given project recording the
+// runs it queues; fire fails while failing is set.
+func newTestScheduler(p *model.Project, failing *error) (*Scheduler, memStates, *[]firing) {
+	states := memStates{}
+	var fired []firing
+	sc := New(func() ([]model.Project, error) {
+		return []model.Project{*p}, nil
+	}, states, func(p model.Project, s model.Schedule, at time.Time) (string, error) {
+		if *failing != nil {
+			return "", *failing
+		}
+		fired = append(fired, firing{s.ID, at})
+		return "job-" + s.ID, nil
+	})
+	return sc, states, &fired
+}
+
+// --- Unit Tests ---
+
+// Unit test: a schedule starts counting when first seen and runs each time
+// it comes due
+func TestScheduler_RunsWhenDue(t *testing.T) {
+	p := &model.Project{ID: "p", Schedules: []model.Schedule{{ID: "s", Cron: "0 * * * *", Enabled: true}}}
+	var failing error
+	sc, states, fired := newTestScheduler(p, &failing)
+
+	start := time.Date(2024, 5, 1, 9, 50, 0, 0, time.UTC)
+	sc.Tick(start)
+	sc.Tick(start.Add(5 * time.Minute))
+	if len(*fired) != 0 {
+		t.Fatalf("fired before due: %+v", *fired)
+	}
+	sc.Tick(time.Date(2024, 5, 1, 10, 0, 20, 0, time.UTC))
+	sc.Tick(time.Date(2024, 5, 1, 10, 0, 50, 0, time.UTC))
+	want := []firing{{"s", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}}
+	if len(*fired) != 1 || (*fired)[0] != want[0] {
+		t.Fatalf("fired %+v, want %+v", *fired, want)
+	}
+	st := states["p"]["s"]
+	if st.LastJobID != "job-s" || st.LastRun == nil || st.Missed != 0 {
+		t.Errorf("state = %+v", st)
+	}
+
+	failing = errors.New("no code")
+	sc.Tick(time.Date(2024, 5, 1, 11, 0, 10, 0, time.UTC))
+	if st := states["p"]["s"]; st.LastError != "no code" || st.Missed != 1 || st.LastJobID != "job-s" {
+		t.Errorf("state after a failed run = %+v", st)
+	}
+}
+
+// Unit test: runs due while closed are skipped, or caught up with one run
+// for the latest of them
+func TestScheduler_MissedRuns(t *testing.T) {
+	for _, policy := range []string{Skip, CatchUp} {
+		p := &model.Project{ID: "p", Schedules: []model.Schedule{{ID: "s", Cron: "0 * * * *", Enabled: true, MissedRuns: policy}}}
+		var failing error
+		sc, states, fired := newTestScheduler(p, &failing)
+		sc.Tick(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC))
+		// Closed from 9:30 to 13:20: due at 10, 11, 12 and 13
+		sc.Tick(time.Date(2024, 5, 1, 13, 20, 0, 0, time.UTC))
+
+		st := states["p"]["s"]
+		if !st.LastSlot.Equal(time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: last slot %v", policy, st.LastSlot)
+		}
+		switch policy {
+		case Skip:
+			if len(*fired) != 0 || st.Missed != 4 {
+				t.Errorf("skip: fired %+v, missed %d", *fired, st.Missed)
+			}
+		case CatchUp:
+			want := firing{"s", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)}
+			if len(*fired) != 1 || (*fired)[0] != want || st.Missed != 3 {
+				t.Errorf("catch up: fired %+v, missed %d", *fired, st.Missed)
+			}
+		}
+	}
+}
+
+// Unit test: disabled schedules don't run nor catch up when enabled again,
+// and the states of removed schedules are dropped
+func TestScheduler_DisabledAndRemoved(t *testing.T) {
+	p := &model.Project{ID: "p", Schedules: []model.Schedule{{ID: "s", Cron: "*/10 * * * *", Enabled: true, MissedRuns: CatchUp}}}
+	var failing error
+	sc, states, fired := newTestScheduler(p, &failing)
+	sc.Tick(time.Date(2024, 5, 1, 9, 0, 30, 0, time.UTC))
+
+	p.Schedules[0].Enabled = false
+	sc.Tick(time.Date(2024, 5, 1, 9, 10, 30, 0, time.UTC))
+	p.Schedules[0].Enabled = true
+	sc.Tick(time.Date(2024, 5, 1, 11, 5, 0, 0, time.UTC))
+	sc.Tick(time.Date(2024, 5, 1, 11, 6, 0, 0, time.UTC))
+	if len(*fired) != 0 {
+		t.Fatalf("fired %+v", *fired)
+	}
+	sc.Tick(time.Date(2024, 5, 1, 11, 10, 5, 0, time.UTC))
+	if len(*fired) != 1 {
+		t.Fatalf("fired %+v", *fired)
+	}
+
+	p.Schedules = nil
+	sc.Tick(time.Date(2024, 5, 1, 11, 11, 0, 0, time.UTC))
+	if _, ok := states["p"]; ok {
+		t.Errorf("states kept: %+v", states["p"])
+	}
+}
+
+// Unit test: status gives the next run of enabled schedules only
+func TestScheduler_Status(t *testing.T) {
+	p := &model.Project{ID: "p", Schedules: []model.Schedule{
+		{ID: "on", Cron: "@daily", Enabled: true},
+		{ID: "off", Cron: "@daily"},
+	}}
+	var failing error
+	sc, _, _ := newTestScheduler(p, &failing)
+	statuses, err := sc.Status(*p, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("statuses %+v (%v)", statuses, err)
+	}
+	if next := statuses[0].Next; next == nil || !next.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("next = %v", next)
+	}
+	if statuses[1].Next != nil {
+		t.Errorf("disabled schedule runs at %v", statuses[1].Next)
+	}
+}
+
+// Unit test: output names fill in the project and the due time
+func TestOutputName(t *testing.T) {
+	at := time.Date(2024, 5, 1, 2, 5, 0, 0, time.UTC)
+	cases := []struct{ template, want string }{
+		{"", "fw_20240501_0205"},
+		{"{project}-{year}-{month}-{day}T{hour}{minute}", "fw-2024-05-01T0205"},
+		{"daily/{date}", "daily_20240501"},
+		{"fixed", "fixed"},
+	}
+	for _, c := range cases {
+		if got := OutputName(c.template, "fw", at); got != c.want {
+			t.Errorf("OutputName(%q) = %q, want %q", c.template, got, c.want)
+		}
+	}
+}
+
+// Unit test: schedules need an ID, a valid expression, directories and a
+// known policy
+func TestValidate(t *testing.T) {
+	ok := model.Schedule{ID: "a", Cron: "@daily", InputDir: "/in", OutputDir: "/out"}
+	cases := []struct {
+		schedules []model.Schedule
+		ok        bool
+	}{
+		{nil, true},
+		{[]model.Schedule{ok}, true},
+		{[]model.Schedule{ok, ok}, false},
+		{[]model.Schedule{{ID: "a", Cron: "bad", InputDir: "/in", OutputDir: "/out"}}, false},
+		{[]model.Schedule{{ID: "a", Cron: "@daily", OutputDir: "/out"}}, false},
+		{[]model.Schedule{{ID: "a", Cron: "@daily", InputDir: "/in", OutputDir: "/out", MissedRuns: "later"}}, false},
+		{[]model.Schedule{{Cron: "@daily", InputDir: "/in", OutputDir: "/out"}}, false},
+		{[]model.Schedule{{ID: "a", Cron: "@daily", InputDir: "/in", OutputDir: "/out", Summary: true}}, true},
+		{[]model.Schedule{{ID: "a", Cron: "@daily", InputDir: "/in", OutputDir: "/out", Summary: true, Incremental: true}}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(c.schedules); (err == nil) != c.ok {
+			t.Errorf("%+v: err = %v", c.schedules, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package schema declares and infers the types of the columns scripts
+// stream, for the Go stages that work on the rows after the script.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+)
+
+// Column types.
+const (
+	String   = "string"
+	Integer  = "integer"
+	Number   = "number"
+	Boolean  = "boolean"
+	DateTime = "datetime"
+	IP       = "ip"
+)
+
+// Types lists every column type.
+var Types = []string{String, Integer, Number, Boolean, DateTime, IP}
+
+// Validate checks that each column is named once with a known type.
+func Validate(columns []model.ColumnSchema) error {
+	seen := make(map[string]bool)
+	for _, c := range columns {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			return fmt.Errorf("a column of the schema has no name")
+		}
+		if seen[name] {
+			return fmt.Errorf("column %q is declared twice", name)
+		}
+		seen[name] = true
+		known := false
+		for _, t := range Types {
+			known = known || c.Type == t
+		}
+		if !known {
+			return fmt.Errorf("column %q: unknown type %q", name, c.Type)
+		}
+	}
+	return nil
+}
+
+// Declared returns the declared type of column; "" when undeclared.
+func Declared(columns []model.ColumnSchema, column string) string {
+	for _, c := range columns {
+		if strings.TrimSpace(c.Name) == column {
+			return c.Type
+		}
+	}
+	return ""
+}
+
+// Infer returns the type of a streamed value; "" for null. Strings are
+// dates and times when they parse as output.ParseTime does, IPs when they
+// are IPv4 or IPv6 addresses and strings otherwise; numbers written as
+// strings stay strings.
+func Infer(v any) string {
+	switch v := v.(type) {
+	case bool:
+		return Boolean
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return Integer
+		}
+		return Number
+	case string:
+		if _, _, ok := output.ParseTime(v, time.Local); ok {
+			return DateTime
+		}
+		if IsIP(v) {
+			return IP
+		}
+		return String
+	}
+	return ""
+}
+
+// Merge returns the type of a column holding values of types a and b:
+// either when the other is "", number for integers and numbers, and
+// string when they disagree otherwise.
+func Merge(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case (a == Integer && b == Number) || (a == Number && b == Integer):
+		return Number
+	}
+	return String
+}
+
+// IsIP reports whether s is an IPv4 or IPv6 address without a zone.
+func IsIP(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Zone() == ""
+}
+
+// MinShare is the share of a column's values that must be of a type for
+// the column to be inferred to have it.
+const MinShare = 0.8
+
+// Majority returns the type of a column from how many of its values are of
+// each type: the type of most of them when at least MinShare are, number
+// when that many are integers or numbers, and string otherwise; "" for a
+// column without values.
+func Majority(counts map[string]int) string {
+	total, best := 0, ""
+	for _, t := range Types {
+		total += counts[t]
+		if counts[t] > counts[best] {
+			best = t
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	min := MinShare * float64(total)
+	if counts[Number] > 0 && float64(counts[Integer]+counts[Number]) >= min {
+		return Number
+	}
+	if float64(counts[best]) >= min {
+		return best
+	}
+	return String
+}
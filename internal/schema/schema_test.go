@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// --- Unit Tests ---
+
+// Unit test: values are typed as the output stages read them
+func TestInfer(t *testing.T) {
+	cases := []struct {
+		value any
+		want  string
+	}{
+		{nil, ""},
+		{true, Boolean},
+		{json.Number("443"), Integer},
+		{json.Number("0.5"), Number},
+		{"2024-03-01 08:00:00", DateTime},
+		{"2024-03-01", DateTime},
+		{"10.0.0.1", IP},
+		{"2001:db8::1", IP},
+		{"fe80::1%eth0", String},
+		{"10.0.0.1:443", String},
+		{"443", String},
+		{"denied", String},
+	}
+	for _, c := range cases {
+		if got := Infer(c.value); got != c.want {
+			t.Errorf("Infer(%#v) = %q, want %q", c.value, got, c.want)
+		}
+	}
+}
+
+// Unit test: integers and numbers make numbers, other disagreements strings
+func TestMerge(t *testing.T) {
+	cases := []struct{ a, b, want string }{
+		{"", IP, IP},
+		{IP, "", IP},
+		{IP, IP, IP},
+		{Integer, Number, Number},
+		{Number, Integer, Number},
+		{IP, String, String},
+		{DateTime, Integer, String},
+	}
+	for _, c := range cases {
+		if got := Merge(c.a, c.b); got != c.want {
+			t.Errorf("Merge(%q, %q) = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+// Unit test: a column takes the type of most of its values, allowing a few
+// placeholders
+func TestMajority(t *testing.T) {
+	cases := []struct {
+		counts map[string]int
+		want   string
+	}{
+		{nil, ""},
+		{map[string]int{IP: 9, String: 1}, IP},
+		{map[string]int{IP: 7, String: 3}, String},
+		{map[string]int{Integer: 6, Number: 3, String: 1}, Number},
+		{map[string]int{Integer: 10}, Integer},
+		{map[string]int{DateTime: 4, Boolean: 4}, String},
+	}
+	for _, c := range cases {
+		if got := Majority(c.counts); got != c.want {
+			t.Errorf("Majority(%v) = %q, want %q", c.counts, got, c.want)
+		}
+	}
+}
+
+// Unit test: schemas name each column once with a known type
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		columns []model.ColumnSchema
+		ok      bool
+	}{
+		{nil, true},
+		{[]model.ColumnSchema{{Name: "src", Type: IP}, {Name: "time", Type: DateTime}}, true},
+		{[]model.ColumnSchema{{Name: "src", Type: "inet"}}, false},
+		{[]model.ColumnSchema{{Name: " ", Type: IP}}, false},
+		{[]model.ColumnSchema{{Name: "src", Type: IP}, {Name: "src", Type: String}}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(c.columns); (err == nil) != c.ok {
+			t.Errorf("%+v: err = %v", c.columns, err)
+		}
+	}
+	if got := Declared([]model.ColumnSchema{{Name: "src", Type: IP}}, "src"); got != IP {
+		t.Errorf("Declared = %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+)
+
+// clickHouseTarget inserts rows into a ClickHouse table over the HTTP
+// interface with INSERT ... FORMAT JSONEachRow, one object per row with
+// the row's file as log_file and its key as log_id. The table must exist;
+// fields it has no column for are skipped and dates are parsed the way
+// best_effort does, so the ISO 8601 times scripts write fit DateTime
+// columns. A ReplacingMergeTree table ordered by log_id keeps one copy of
+// rows a retry sent again.
+type clickHouseTarget struct {
+	c *client
+}
+
+func newClickHouseTarget(c *client) *clickHouseTarget {
+	return &clickHouseTarget{c: c}
+}
+
+func (t *clickHouseTarget) describe() string {
+	return strings.TrimRight(t.c.cfg.URL, "/") + " / " + t.c.cfg.Table
+}
+
+func (t *clickHouseTarget) prepare(ctx context.Context, batch []record) error {
+	return nil
+}
+
+func (t *clickHouseTarget) send(ctx context.Context, batch []record) outcome {
+	var buf bytes.Buffer
+	for _, r := range batch {
+		buf.Write(rowJSON(r, field{fileField, r.file}, field{idField, r.id}))
+		buf.WriteByte('\n')
+	}
+	endpoint, err := t.endpoint()
+	if err != nil {
+		return outcome{rejected: batch, err: err}
+	}
+	if _, failed := t.c.post(ctx, endpoint, "application/x-ndjson", buf.Bytes(), nil, batch); failed != nil {
+		return *failed
+	}
+	return outcome{}
+}
+
+// endpoint returns the URL of the insert, keeping settings such as the
+// database given in the configured URL.
+func (t *clickHouseTarget) endpoint() (string, error) {
+	u, err := url.Parse(t.c.cfg.URL)
+	if err != nil {
+		return "", err
+	}
+	var table []string
+	for _, part := range strings.Split(t.c.cfg.Table, ".") {
+		table = append(table, "`"+part+"`")
+	}
+	q := u.Query()
+	q.Set("query", "INSERT INTO "+strings.Join(table, ".")+" FORMAT JSONEachRow")
+	q.Set("input_format_skip_unknown_fields", "1")
+	q.Set("date_time_input_format", "best_effort")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
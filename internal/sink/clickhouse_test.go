@@ -0,0 +1,71 @@
+package sink
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// Unit test: rows are inserted as JSONEachRow into the quoted table, with
+// the settings of the configured URL kept
+func TestClickHouse_Insert(t *testing.T) {
+	var mu sync.Mutex
+	var query, database, skip, user string
+	var lines []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		q := r.URL.Query()
+		query, database, skip = q.Get("query"), q.Get("database"), q.Get("input_format_skip_unknown_fields")
+		user, _, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		lines = append(lines, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+	}))
+	defer srv.Close()
+
+	cfg := model.SinkConfig{Type: ClickHouse, URL: srv.URL + "/?database=net", Table: "logs.firewall", Username: "writer", Password: "pw"}
+	s, err := New(cfg, Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.StartFile("fw.log", []string{"time", "port"})
+	s.WriteRow("fw.log", []any{"2024-03-01 08:00:00", "443"})
+	s.StartFile("own.log", []string{"log_file", "port"})
+	s.WriteRow("own.log", []any{"custom", nil})
+	s.Close()
+
+	if res := s.Result(); res.Sent != 2 || !strings.HasSuffix(res.Target, " / logs.firewall") {
+		t.Fatalf("result = %+v", res)
+	}
+	if query != "INSERT INTO `logs`.`firewall` FORMAT JSONEachRow" || database != "net" || skip != "1" || user != "writer" {
+		t.Errorf("query %q, database %q, skip %q, user %q", query, database, skip, user)
+	}
+	want := []string{
+		`{"log_file":"fw.log","log_id":"` + rowID("", "fw.log", 1) + `","time":"2024-03-01 08:00:00","port":"443"}`,
+		`{"log_id":"` + rowID("", "own.log", 1) + `","log_file":"custom","port":null}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+// Unit test: a refused insert fails with the server's message
+func TestClickHouse_Rejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Code: 60. DB::Exception: Table logs.missing does not exist", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	s, _ := New(model.SinkConfig{Type: ClickHouse, URL: srv.URL, Table: "missing"}, Options{})
+	s.StartFile("fw.log", []string{"msg"})
+	s.WriteRow("fw.log", []any{"x"})
+	s.Close()
+	if res := s.Result(); res.Failed != 1 || !strings.Contains(res.Error, "does not exist") {
+		t.Errorf("result = %+v", res)
+	}
+}
@@ -0,0 +1,71 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter collects the rows sinks fail to deliver in a JSON Lines file,
+// so they can be looked into and sent again. Each line holds the sink, the
+// error and the row as an object with its columns in order. Runs append to
+// the file, since rows earlier runs failed to deliver still haven't been; it
+// is created with the first row. The sinks of a run may share one.
+type DeadLetter struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewDeadLetter returns a dead-letter file at path.
+func NewDeadLetter(path string) *DeadLetter {
+	return &DeadLetter{path: path}
+}
+
+// Path returns the path of the file.
+func (d *DeadLetter) Path() string {
+	return d.path
+}
+
+// deadRow is a line of the dead-letter file.
+type deadRow struct {
+	Time   string          `json:"time"`
+	RunID  string          `json:"run_id,omitempty"`
+	Sink   string          `json:"sink"`
+	Target string          `json:"target"`
+	File   string          `json:"file"`
+	Line   int             `json:"line"`
+	Error  string          `json:"error,omitempty"`
+	Row    json.RawMessage `json:"row"`
+}
+
+// write appends rows a sink failed to deliver for cause.
+func (d *DeadLetter) write(sinkType string, target string, runID string, rows []record, cause error) error {
+	var buf bytes.Buffer
+	now := time.Now().Format(time.RFC3339)
+	for _, r := range rows {
+		line := deadRow{Time: now, RunID: runID, Sink: sinkType, Target: target, File: r.file, Line: r.seq, Row: rowJSON(r)}
+		if cause != nil {
+			line.Error = cause.Error()
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,107 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// readDeadLetter reads the lines of a dead-letter file.
+func readDeadLetter(t *testing.T, path string) []deadRow {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open dead-letter file: %v", err)
+	}
+	defer f.Close()
+	var rows []deadRow
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r deadRow
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("bad line %q: %v", scanner.Text(), err)
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+// Unit test: refused and undeliverable rows of every sink go to the shared
+// dead-letter file, and later runs append to it
+func TestDeadLetter_CollectsFailedRows(t *testing.T) {
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+	}))
+	defer refusing.Close()
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer busy.Close()
+
+	path := filepath.Join(t.TempDir(), "out.deadletter.jsonl")
+	dl := NewDeadLetter(path)
+	run := func(runID string) []model.SinkResult {
+		var results []model.SinkResult
+		for _, url := range []string{refusing.URL, busy.URL} {
+			s, err := New(model.SinkConfig{Type: Webhook, URL: url, MaxRetries: 1}, Options{RunID: runID, DeadLetter: dl})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			s.StartFile("fw.log", []string{"msg", "port"})
+			s.WriteRow("fw.log", []any{"up", json.Number("443")})
+			s.Close()
+			results = append(results, s.Result())
+		}
+		return results
+	}
+
+	for _, res := range run("run-1") {
+		if res.Failed != 1 || res.DeadLetter != path {
+			t.Errorf("result = %+v", res)
+		}
+	}
+	rows := readDeadLetter(t, path)
+	if len(rows) != 2 {
+		t.Fatalf("%d dead rows, want 2", len(rows))
+	}
+	r := rows[0]
+	if r.RunID != "run-1" || r.Sink != Webhook || r.Target != refusing.URL || r.File != "fw.log" || r.Line != 1 {
+		t.Errorf("dead row = %+v", r)
+	}
+	if r.Error != "HTTP 400: bad payload" || string(r.Row) != `{"msg":"up","port":443}` {
+		t.Errorf("dead row error %q, row %s", r.Error, r.Row)
+	}
+	if rows[1].Target != busy.URL || rows[1].Error != "HTTP 503: busy" {
+		t.Errorf("dead row = %+v", rows[1])
+	}
+
+	run("run-2")
+	if rows := readDeadLetter(t, path); len(rows) != 4 || rows[3].RunID != "run-2" {
+		t.Errorf("expected the second run to append, got %d rows", len(rows))
+	}
+}
+
+// Unit test: a dead-letter file that can't be written is reported with the
+// sink's error
+func TestDeadLetter_WriteError(t *testing.T) {
+	fail := func(b []record) outcome { return outcome{rejected: b, err: errors.New("HTTP 400")} }
+	dl := NewDeadLetter(filepath.Join(t.TempDir(), "missing", "dead.jsonl"))
+	s := newShipper(model.SinkConfig{Type: Webhook}, Options{DeadLetter: dl}, &fakeTarget{replies: []func([]record) outcome{fail}})
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"x"})
+	s.Close()
+	res := s.Result()
+	if res.Failed != 1 || res.DeadLetter != "" {
+		t.Errorf("result = %+v", res)
+	}
+	if want := "HTTP 400; dead-letter file: "; len(res.Error) < len(want) || res.Error[:len(want)] != want {
+		t.Errorf("error = %q", res.Error)
+	}
+}
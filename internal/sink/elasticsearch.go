@@ -0,0 +1,197 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/schema"
+)
+
+// Fields every document gets besides the row's columns.
+const (
+	fileField = "log_file"
+	timeField = "@timestamp"
+	idField   = "log_id" // key of the row, where the service has no ID of its own
+)
+
+// elasticTarget indexes rows into Elasticsearch or OpenSearch with the
+// _bulk API, one document per row. Before the first batch it installs an
+// index template mapping the columns to the types their values have, so
+// numbers, dates and IP addresses are searchable as such from the first
+// document on. The ID of a document is the key of its row.
+type elasticTarget struct {
+	c     *client
+	index string
+	types map[string]string // field types of the template
+	loc   *time.Location    // zone of times without an offset
+}
+
+func newElasticTarget(c *client, index string, loc *time.Location) *elasticTarget {
+	return &elasticTarget{c: c, index: index, loc: loc}
+}
+
+func (t *elasticTarget) describe() string {
+	return strings.TrimRight(t.c.cfg.URL, "/") + " / " + t.index
+}
+
+// prepare installs the index template derived from the first batch. The
+// template only applies to an index created after it, so an existing index
+// keeps its mappings.
+func (t *elasticTarget) prepare(ctx context.Context, batch []record) error {
+	t.types = fieldTypes(batch)
+	properties := map[string]any{
+		fileField: map[string]any{"type": "keyword"},
+		timeField: map[string]any{"type": "date"},
+	}
+	for field, typ := range t.types {
+		properties[field] = mapping(typ)
+	}
+	body, err := json.Marshal(map[string]any{
+		"index_patterns": []string{t.index},
+		"priority":       200,
+		"template":       map[string]any{"mappings": map[string]any{"properties": properties}},
+		"_meta":          map[string]any{"created_by": "LogForge"},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := t.c.do(ctx, http.MethodPut, t.c.url("/_index_template/logforge-"+t.index), "application/json", body, nil)
+	if err != nil {
+		return fmt.Errorf("index template: %w", err)
+	}
+	if resp.status < 200 || resp.status >= 300 {
+		return fmt.Errorf("index template: %w", statusError(resp))
+	}
+	return nil
+}
+
+func (t *elasticTarget) send(ctx context.Context, batch []record) outcome {
+	var buf bytes.Buffer
+	for _, r := range batch {
+		action, _ := json.Marshal(map[string]any{"index": map[string]string{"_index": t.index, "_id": r.id}})
+		doc, err := json.Marshal(t.document(r))
+		if err != nil {
+			doc, _ = json.Marshal(map[string]string{fileField: r.file})
+		}
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(doc)
+		buf.WriteByte('\n')
+	}
+	resp, failed := t.c.post(ctx, t.c.url("/_bulk"), "application/x-ndjson", buf.Bytes(), nil, batch)
+	if failed != nil {
+		return *failed
+	}
+
+	// The request succeeds as a whole even when documents fail
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return outcome{rejected: batch, err: fmt.Errorf("unreadable _bulk response: %w", err)}
+	}
+	if !result.Errors {
+		return outcome{}
+	}
+	var out outcome
+	for i, item := range result.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, res := range item {
+			if res.Status < 300 {
+				continue
+			}
+			var err error = fmt.Errorf("HTTP %d", res.Status)
+			if res.Error != nil {
+				err = fmt.Errorf("%s: %s", res.Error.Type, res.Error.Reason)
+			}
+			if out.err == nil {
+				out.err = err
+			}
+			if res.Status == http.StatusTooManyRequests {
+				out.retry = append(out.retry, batch[i])
+			} else {
+				out.rejected = append(out.rejected, batch[i])
+			}
+		}
+	}
+	return out
+}
+
+// document returns the fields of a row's document. Values of date columns
+// are written as RFC 3339 times.
+func (t *elasticTarget) document(r record) map[string]any {
+	doc := map[string]any{fileField: r.file}
+	if !r.time.IsZero() {
+		doc[timeField] = r.time.Format(time.RFC3339Nano)
+	}
+	for i, c := range r.columns {
+		v := r.values[i]
+		if v == nil {
+			continue
+		}
+		if s, ok := v.(string); ok && t.types[c] == "date" {
+			if tm, dateOnly, ok := output.ParseTime(s, t.loc); ok && !dateOnly {
+				v = tm.Format(time.RFC3339Nano)
+			}
+		}
+		doc[c] = v
+	}
+	return doc
+}
+
+// fieldTypes derives the type of each column from the values in batch:
+// long, double, boolean, date, ip or text. Columns whose values disagree
+// are text, except that long and double make double; columns without
+// values are left to dynamic mapping.
+func fieldTypes(batch []record) map[string]string {
+	merged := make(map[string]string)
+	for _, r := range batch {
+		for i, c := range r.columns {
+			merged[c] = schema.Merge(merged[c], schema.Infer(r.values[i]))
+		}
+	}
+	types := make(map[string]string)
+	for c, typ := range merged {
+		if typ != "" {
+			types[c] = fieldType[typ]
+		}
+	}
+	return types
+}
+
+// fieldType maps column types to field types.
+var fieldType = map[string]string{
+	schema.Integer:  "long",
+	schema.Number:   "double",
+	schema.Boolean:  "boolean",
+	schema.DateTime: "date",
+	schema.IP:       "ip",
+	schema.String:   "text",
+}
+
+// mapping returns the mapping of a field type. Text keeps a keyword
+// sub-field for sorting and aggregations, as dynamic mapping would.
+func mapping(typ string) map[string]any {
+	if typ == "text" {
+		return map[string]any{
+			"type":   "text",
+			"fields": map[string]any{"keyword": map[string]any{"type": "keyword", "ignore_above": 256}},
+		}
+	}
+	return map[string]any{"type": typ}
+}
@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// Unit test: the index template maps columns by their values, and _bulk
+// documents carry the file, the row time and normalized dates
+func TestElasticsearch_TemplateAndBulk(t *testing.T) {
+	var mu sync.Mutex
+	var template map[string]any
+	var docs []map[string]any
+	var auth, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/_index_template/logforge-fw":
+			json.Unmarshal(body, &template)
+		case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+			auth = r.Header.Get("Authorization")
+			contentType = r.Header.Get("Content-Type")
+			lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+			for i := 1; i < len(lines); i += 2 {
+				var doc map[string]any
+				json.Unmarshal([]byte(lines[i]), &doc)
+				docs = append(docs, doc)
+			}
+			w.Write([]byte(`{"errors":false,"items":[]}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := New(model.SinkConfig{Type: Elasticsearch, URL: srv.URL + "/", Index: "fw", APIKey: "secret"}, Options{RunID: "run"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.StartFile("fw.log", []string{"time", "src", "port", "bytes", "allowed", "msg", "note"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:00+08:00", "10.0.0.1", json.Number("443"), json.Number("1.5"), true, "ok", nil})
+	s.WriteRow("fw.log", []any{"2024-03-01T09:00:00+08:00", "::1", json.Number("80"), json.Number("2"), false, "2024-03-01", nil})
+	s.Close()
+
+	if res := s.Result(); res.Sent != 2 || res.Error != "" {
+		t.Fatalf("result = %+v", res)
+	}
+	props := template["template"].(map[string]any)["mappings"].(map[string]any)["properties"].(map[string]any)
+	want := map[string]string{
+		"time": "date", "src": "ip", "port": "long", "bytes": "double",
+		"allowed": "boolean", "msg": "text", fileField: "keyword", timeField: "date",
+	}
+	for field, typ := range want {
+		if got := props[field].(map[string]any)["type"]; got != typ {
+			t.Errorf("%s mapped as %v, want %s", field, got, typ)
+		}
+	}
+	if _, ok := props["note"]; ok {
+		t.Error("a column without values was mapped")
+	}
+	if auth != "ApiKey secret" || contentType != "application/x-ndjson" {
+		t.Errorf("auth %q, content type %q", auth, contentType)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("%d documents, want 2", len(docs))
+	}
+	doc := docs[0]
+	if doc[fileField] != "fw.log" || doc[timeField] != "2024-03-01T08:00:00+08:00" || doc["port"] != float64(443) {
+		t.Errorf("document = %v", doc)
+	}
+	if _, ok := doc["note"]; ok {
+		t.Error("null values should be left out")
+	}
+}
+
+// Unit test: refused documents are retried or rejected by status, and a
+// failed template doesn't stop the rows
+func TestElasticsearch_PartialFailures(t *testing.T) {
+	var mu sync.Mutex
+	bulks := 0
+	var ids [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"no privileges"}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		var batch []string
+		for i := 0; i < len(lines); i += 2 {
+			batch = append(batch, lines[i])
+		}
+		ids = append(ids, batch)
+		bulks++
+		if bulks == 1 {
+			json.NewEncoder(w).Encode(map[string]any{"errors": true, "items": []any{
+				bulkItem(http.StatusCreated), bulkItem(http.StatusTooManyRequests), bulkItem(http.StatusBadRequest),
+			}})
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	s, _ := New(model.SinkConfig{Type: OpenSearch, URL: srv.URL, Index: "fw"}, Options{RunID: "run"})
+	s.StartFile("fw.log", []string{"msg"})
+	for _, m := range []string{"a", "b", "c"} {
+		s.WriteRow("fw.log", []any{m})
+	}
+	s.Close()
+
+	res := s.Result()
+	if res.Sent != 2 || res.Failed != 1 || !strings.Contains(res.Error, "index template") {
+		t.Errorf("result = %+v", res)
+	}
+	if len(ids) != 2 || len(ids[1]) != 1 || ids[1][0] != ids[0][1] {
+		t.Errorf("retried actions = %v, want the second row again", ids)
+	}
+}
+
+// Unit test: field types of mixed columns
+func TestFieldTypes(t *testing.T) {
+	cols := []string{"n", "mixed", "ipish"}
+	batch := []record{
+		{columns: cols, values: []any{json.Number("1"), json.Number("3"), "10.0.0.1"}},
+		{columns: cols, values: []any{json.Number("1.5"), "x", "host"}},
+	}
+	got := fieldTypes(batch)
+	if got["n"] != "double" || got["mixed"] != "text" || got["ipish"] != "text" {
+		t.Errorf("types = %v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"network-log-formatter/internal/output"
+)
+
+// lokiTarget pushes rows to Grafana Loki as log lines holding each row as a
+// JSON object with the key of the row as log_id, timed by the row's time or,
+// without one, by when its file was first started. A row sent again is the
+// same line at the same time, which Loki drops. Rows are grouped into streams by their labels: job="logforge",
+// file, the configured labels (the project among them) and the values of
+// the label columns.
+type lokiTarget struct {
+	c       *client
+	labels  map[string]string
+	columns []string // columns whose values become labels
+}
+
+func newLokiTarget(c *client, labels map[string]string, columns []string) *lokiTarget {
+	return &lokiTarget{c: c, labels: labels, columns: columns}
+}
+
+func (t *lokiTarget) describe() string {
+	return t.c.url("/loki/api/v1/push")
+}
+
+func (t *lokiTarget) prepare(ctx context.Context, batch []record) error {
+	return nil
+}
+
+// lokiEntry is a line of a stream and its time in Unix nanoseconds.
+type lokiEntry struct {
+	ns   int64
+	line string
+}
+
+func (t *lokiTarget) send(ctx context.Context, batch []record) outcome {
+	streams := make(map[string]map[string]string)
+	entries := make(map[string][]lokiEntry)
+	var keys []string
+	for _, r := range batch {
+		labels := t.rowLabels(r)
+		key := labelKey(labels)
+		if _, ok := streams[key]; !ok {
+			streams[key] = labels
+			keys = append(keys, key)
+		}
+		entries[key] = append(entries[key], lokiEntry{ns: rowTimeOrReceived(r).UnixNano(), line: string(rowJSON(r, field{idField, r.id}))})
+	}
+
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	push := struct {
+		Streams []stream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		// Older Lokis want the entries of a stream in time order
+		es := entries[key]
+		sort.SliceStable(es, func(i, j int) bool { return es[i].ns < es[j].ns })
+		s := stream{Stream: streams[key]}
+		for _, e := range es {
+			s.Values = append(s.Values, [2]string{strconv.FormatInt(e.ns, 10), e.line})
+		}
+		push.Streams = append(push.Streams, s)
+	}
+	body, err := json.Marshal(push)
+	if err != nil {
+		return outcome{rejected: batch, err: err}
+	}
+
+	var header map[string]string
+	if t.c.cfg.TenantID != "" {
+		header = map[string]string{"X-Scope-OrgID": t.c.cfg.TenantID}
+	}
+	if _, failed := t.c.post(ctx, t.c.url("/loki/api/v1/push"), "application/json", body, header, batch); failed != nil {
+		return *failed
+	}
+	return outcome{}
+}
+
+// rowLabels returns the stream labels of a row.
+func (t *lokiTarget) rowLabels(r record) map[string]string {
+	labels := map[string]string{"job": "logforge"}
+	for k, v := range t.labels {
+		labels[k] = v
+	}
+	labels["file"] = r.file
+	for _, name := range t.columns {
+		for i, c := range r.columns {
+			if strings.EqualFold(c, name) && r.values[i] != nil {
+				labels[labelFor(c)] = output.Text(r.values[i])
+			}
+		}
+	}
+	return labels
+}
+
+// labelKey identifies a set of labels.
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// labelFor turns a column name into a valid label name.
+func labelFor(column string) string {
+	name := []byte(column)
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,110 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// lokiPush is the body of a push request.
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// Unit test: rows are pushed as JSON lines in streams by label, in time order
+func TestLoki_Push(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []lokiPush
+	var tenant, user string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		tenant = r.Header.Get("X-Scope-OrgID")
+		user, _, _ = r.BasicAuth()
+		var p lokiPush
+		json.NewDecoder(r.Body).Decode(&p)
+		pushes = append(pushes, p)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := model.SinkConfig{
+		Type: Loki, URL: srv.URL + "/loki/api/v1/push",
+		Labels: map[string]string{"project": "fw"}, LabelColumns: []string{"level"},
+		TenantID: "team-a", Username: "ops", Password: "pw",
+	}
+	s, err := New(cfg, Options{RunID: "run"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.StartFile("fw.log", []string{"time", "level", "msg"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:02Z", "error", "late"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:01Z", "error", "early"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:03Z", "info", json.Number("7")})
+	s.Close()
+
+	if res := s.Result(); res.Sent != 3 || res.Failed != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if tenant != "team-a" || user != "ops" {
+		t.Errorf("tenant %q, user %q", tenant, user)
+	}
+	if len(pushes) != 1 || len(pushes[0].Streams) != 2 {
+		t.Fatalf("pushes = %+v", pushes)
+	}
+	errorStream := pushes[0].Streams[0]
+	want := map[string]string{"job": "logforge", "project": "fw", "file": "fw.log", "level": "error"}
+	for k, v := range want {
+		if errorStream.Stream[k] != v {
+			t.Errorf("label %s = %q, want %q", k, errorStream.Stream[k], v)
+		}
+	}
+	if len(errorStream.Values) != 2 || errorStream.Values[0][0] != "1709280001000000000" {
+		t.Fatalf("values = %v", errorStream.Values)
+	}
+	if line := errorStream.Values[0][1]; line != `{"log_id":"`+rowID("run", "fw.log", 2)+`","time":"2024-03-01T08:00:01Z","level":"error","msg":"early"}` {
+		t.Errorf("line = %s", line)
+	}
+	if line := pushes[0].Streams[1].Values[0][1]; line != `{"log_id":"`+rowID("run", "fw.log", 3)+`","time":"2024-03-01T08:00:03Z","level":"info","msg":7}` {
+		t.Errorf("line = %s", line)
+	}
+}
+
+// Unit test: a push Loki refuses fails without retries
+func TestLoki_Rejected(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s, _ := New(model.SinkConfig{Type: Loki, URL: srv.URL}, Options{RunID: "run"})
+	s.StartFile("fw.log", []string{"msg"})
+	s.WriteRow("fw.log", []any{"x"})
+	s.Close()
+	res := s.Result()
+	if calls != 1 || res.Failed != 1 || res.Error != "HTTP 400: entry too far behind" {
+		t.Errorf("%d calls, result = %+v", calls, res)
+	}
+}
+
+// Unit test: column names as label names
+func TestLabelFor(t *testing.T) {
+	cases := map[string]string{"level": "level", "src-ip": "src_ip", "9x": "_x", "源": "___"}
+	for in, want := range cases {
+		if got := labelFor(in); got != want {
+			t.Errorf("labelFor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,747 @@
+// Package sink ships the rows scripts stream over the progress protocol to
+// log services such as Elasticsearch, OpenSearch, Grafana Loki and Splunk,
+// to ClickHouse or to any HTTP endpoint, so parsed logs land where they are
+// searched without an export and re-import by hand.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+)
+
+// Sink types.
+const (
+	Elasticsearch = "elasticsearch"
+	OpenSearch    = "opensearch"
+	Loki          = "loki"
+	Webhook       = "webhook"
+	Splunk        = "splunk"
+	ClickHouse    = "clickhouse"
+)
+
+// Types lists every supported sink type.
+var Types = []string{Elasticsearch, OpenSearch, Loki, Webhook, Splunk, ClickHouse}
+
+const (
+	defaultBatchSize  = 500
+	defaultMaxRetries = 3
+	defaultMaxPending = 4
+	// requestTimeout bounds a single request to a service.
+	requestTimeout = 30 * time.Second
+	// maxRetryDelay caps the delay between attempts.
+	maxRetryDelay = 30 * time.Second
+	// maxErrorBody is how much of an error response is kept.
+	maxErrorBody = 512
+)
+
+// retryDelay is the delay before the first retry of a batch; it doubles
+// with every further attempt.
+var retryDelay = time.Second
+
+// Sink ships the rows of a run to a service. It takes rows as an output
+// writer does, collects them into batches and sends the batches from a
+// goroutine of its own. While the queue of unsent batches is full, WriteRow
+// waits, which slows the script down to the pace of the service. Rows
+// already sent can't be taken back, so a file started again by a retried or
+// repaired script is sent again. Every row therefore carries a key derived
+// from the run, its file and its line, the same in every attempt, and a row
+// sent again has the same time: Elasticsearch and OpenSearch replace the
+// first document, Loki drops a line it already has, and the other services
+// get the key for the table or receiver to deduplicate on.
+type Sink interface {
+	output.Writer
+	// Result reports the rows delivered; it is complete after Close.
+	Result() model.SinkResult
+}
+
+// record is a row queued for a service.
+type record struct {
+	file     string
+	seq      int    // row number within the file, from 1
+	id       string // deduplication key; see rowID
+	columns  []string
+	values   []any
+	time     time.Time // time of the row; zero when it has none
+	received time.Time // when the run first started the file, plus seq nanoseconds
+}
+
+// outcome is what became of a batch sent to a service.
+type outcome struct {
+	retry    []record      // rows to send again: refused for load, or lost with the request
+	rejected []record      // rows refused for good
+	err      error         // why rows were retried or refused
+	wait     time.Duration // delay the service asked for before a retry
+}
+
+// target delivers batches to one kind of service.
+type target interface {
+	// prepare runs once, before the first batch is sent.
+	prepare(ctx context.Context, batch []record) error
+	send(ctx context.Context, batch []record) outcome
+	// describe names the target in results.
+	describe() string
+}
+
+// shipper implements Sink for any target.
+type shipper struct {
+	cfg       model.SinkConfig
+	opts      Options
+	target    target
+	batchSize int
+	retries   int
+	files     map[string]*fileState
+	started   map[string]time.Time // when each file was first started
+	pending   []record
+	queue     chan []record
+	done      chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	prepared  bool
+	closed    bool
+
+	mu     sync.Mutex // guards result and dlErr
+	result model.SinkResult
+	dlErr  error // first failure to write the dead-letter file
+}
+
+// fileState tracks the rows of one input file.
+type fileState struct {
+	columns []string
+	timeCol int // column with the row time; -1 until one is found
+	rows    int
+	ended   bool
+}
+
+// Options are the settings a sink takes from its run.
+type Options struct {
+	// RunID identifies the run; with the file and line it makes up the
+	// key of each row.
+	RunID string
+	// DeadLetter collects the rows the sink fails to deliver; nil drops
+	// them.
+	DeadLetter *DeadLetter
+	// Location is the zone of times written without an offset; nil is
+	// the local zone.
+	Location *time.Location
+}
+
+// New returns a sink for cfg.
+func New(cfg model.SinkConfig, opts Options) (Sink, error) {
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var t target
+	switch cfg.Type {
+	case Elasticsearch, OpenSearch:
+		t = newElasticTarget(c, cfg.Index, opts.location())
+	case Loki:
+		t = newLokiTarget(c, cfg.Labels, cfg.LabelColumns)
+	case Webhook:
+		t = newWebhookTarget(c, opts.RunID)
+	case Splunk:
+		t = newSplunkTarget(c)
+	case ClickHouse:
+		t = newClickHouseTarget(c)
+	}
+	return newShipper(cfg, opts, t), nil
+}
+
+// location returns the zone of times without an offset.
+func (opts Options) location() *time.Location {
+	if opts.Location == nil {
+		return time.Local
+	}
+	return opts.Location
+}
+
+func newShipper(cfg model.SinkConfig, opts Options, t target) *shipper {
+	s := &shipper{
+		cfg:       cfg,
+		opts:      opts,
+		target:    t,
+		batchSize: orDefault(cfg.BatchSize, defaultBatchSize),
+		retries:   cfg.MaxRetries,
+		files:     make(map[string]*fileState),
+		started:   make(map[string]time.Time),
+		queue:     make(chan []record, orDefault(cfg.MaxPending, defaultMaxPending)),
+		done:      make(chan struct{}),
+		result:    model.SinkResult{Type: cfg.Type, Target: t.describe()},
+	}
+	if s.retries == 0 {
+		s.retries = defaultMaxRetries
+	} else if s.retries < 0 {
+		s.retries = 0
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s
+}
+
+func orDefault(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
+
+// StartFile begins a file, or begins it again for a retried script: the
+// rows of the file not sent yet are dropped, and the rows written now get
+// the keys and times of the ones they replace.
+func (s *shipper) StartFile(file string, columns []string) error {
+	kept := s.pending[:0]
+	for _, r := range s.pending {
+		if r.file != file {
+			kept = append(kept, r)
+		}
+	}
+	s.pending = kept
+	if _, ok := s.started[file]; !ok {
+		s.started[file] = time.Now()
+	}
+	s.files[file] = &fileState{columns: output.Columns(columns), timeCol: -1}
+	return nil
+}
+
+func (s *shipper) WriteRow(file string, values []any) error {
+	f := s.files[file]
+	if f == nil {
+		return fmt.Errorf("rows for %s before its columns", file)
+	}
+	if f.ended {
+		return fmt.Errorf("rows for %s after it ended", file)
+	}
+	f.rows++
+	r := record{
+		file:     file,
+		seq:      f.rows,
+		id:       rowID(s.opts.RunID, file, f.rows),
+		columns:  f.columns,
+		values:   padRow(values, len(f.columns)),
+		received: s.started[file].Add(time.Duration(f.rows)),
+	}
+	r.time = s.rowTime(f, r.values)
+	s.pending = append(s.pending, r)
+	if len(s.pending) >= s.batchSize {
+		s.flush()
+	}
+	return nil
+}
+
+func (s *shipper) EndFile(file string) error {
+	if f := s.files[file]; f != nil {
+		f.ended = true
+	}
+	return nil
+}
+
+func (s *shipper) Close() ([]string, error) {
+	if s.closed {
+		return nil, nil
+	}
+	s.flush()
+	s.closed = true
+	close(s.queue)
+	<-s.done
+	s.cancel()
+	return nil, nil
+}
+
+func (s *shipper) Abort() {
+	s.cancel()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	<-s.done
+}
+
+func (s *shipper) Result() model.SinkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.result
+	if s.dlErr != nil {
+		res.Error = strings.TrimPrefix(res.Error+"; ", "; ") + "dead-letter file: " + s.dlErr.Error()
+	}
+	return res
+}
+
+// flush queues the pending rows as a batch, waiting while the queue is full.
+func (s *shipper) flush() {
+	if len(s.pending) == 0 {
+		return
+	}
+	batch := s.pending
+	s.pending = nil
+	select {
+	case s.queue <- batch:
+	case <-s.ctx.Done():
+	}
+}
+
+// run sends the queued batches one after another.
+func (s *shipper) run() {
+	defer close(s.done)
+	for batch := range s.queue {
+		if s.ctx.Err() != nil {
+			continue
+		}
+		if !s.prepared {
+			s.prepared = true
+			if err := s.target.prepare(s.ctx, batch); err != nil {
+				s.fail(nil, err)
+			}
+		}
+		s.deliver(batch)
+	}
+}
+
+// deliver sends a batch, retrying the rows the service didn't take with a
+// growing delay.
+func (s *shipper) deliver(batch []record) {
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		out := s.target.send(s.ctx, batch)
+		sent := len(batch) - len(out.retry) - len(out.rejected)
+		s.mu.Lock()
+		s.result.Sent += sent
+		s.mu.Unlock()
+		if len(out.rejected) > 0 {
+			s.fail(out.rejected, out.err)
+		}
+		if len(out.retry) == 0 {
+			return
+		}
+		if attempt >= s.retries || s.ctx.Err() != nil {
+			s.fail(out.retry, out.err)
+			return
+		}
+		wait := delay
+		if out.wait > wait {
+			wait = min(out.wait, maxRetryDelay)
+		}
+		select {
+		case <-time.After(wait):
+		case <-s.ctx.Done():
+			s.fail(out.retry, s.ctx.Err())
+			return
+		}
+		delay = min(delay*2, maxRetryDelay)
+		batch = out.retry
+	}
+}
+
+// fail counts rows that weren't delivered, keeps the first error and puts
+// the rows in the dead-letter file.
+func (s *shipper) fail(rows []record, err error) {
+	var dlErr error
+	if len(rows) > 0 && s.opts.DeadLetter != nil {
+		dlErr = s.opts.DeadLetter.write(s.result.Type, s.result.Target, s.opts.RunID, rows, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.result.Failed += len(rows)
+	if s.result.Error == "" && err != nil {
+		s.result.Error = err.Error()
+	}
+	if dlErr != nil && s.dlErr == nil {
+		s.dlErr = dlErr
+	} else if dlErr == nil && len(rows) > 0 && s.opts.DeadLetter != nil {
+		s.result.DeadLetter = s.opts.DeadLetter.Path()
+	}
+}
+
+// rowTime returns the time of a row from the configured time column, or
+// from the first column holding a date and time. Times without an offset
+// are taken to be in the zone of the options.
+func (s *shipper) rowTime(f *fileState, values []any) time.Time {
+	if f.timeCol < 0 {
+		for i, c := range f.columns {
+			if s.cfg.TimeColumn != "" {
+				if strings.EqualFold(c, s.cfg.TimeColumn) {
+					f.timeCol = i
+					break
+				}
+				continue
+			}
+			if v, ok := values[i].(string); ok {
+				if _, dateOnly, ok := output.ParseTime(v, s.opts.location()); ok && !dateOnly {
+					f.timeCol = i
+					break
+				}
+			}
+		}
+		if f.timeCol < 0 {
+			return time.Time{}
+		}
+	}
+	if v, ok := values[f.timeCol].(string); ok {
+		if t, _, ok := output.ParseTime(v, s.opts.location()); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// padRow fits a row to n columns.
+func padRow(values []any, n int) []any {
+	if len(values) == n {
+		return values
+	}
+	row := make([]any, n)
+	copy(row, values)
+	return row
+}
+
+// Validate checks the sink configurations of a project or run.
+func Validate(cfgs []model.SinkConfig) error {
+	for i, cfg := range cfgs {
+		if err := validate(cfg); err != nil {
+			return fmt.Errorf("sink %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+var (
+	// labelName matches the stream label names Loki accepts.
+	labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// headerName matches HTTP header names.
+	headerName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+	// tableName matches a ClickHouse table, optionally with its database.
+	tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+func validate(cfg model.SinkConfig) error {
+	if cfg.Redacted {
+		return fmt.Errorf("the credentials of the %s sink %s aren't saved with jobs and runs; add the sink to the run again", cfg.Type, cfg.URL)
+	}
+	switch cfg.Type {
+	case Elasticsearch, OpenSearch, Loki, Webhook:
+	case Splunk:
+		if cfg.Token == "" {
+			return fmt.Errorf("splunk needs an HTTP Event Collector token")
+		}
+	case ClickHouse:
+		if !tableName.MatchString(cfg.Table) {
+			return fmt.Errorf("clickhouse needs a table, as table or database.table: %q", cfg.Table)
+		}
+	default:
+		return fmt.Errorf("unsupported sink type %q", cfg.Type)
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s URL must be an http or https URL: %q", cfg.Type, cfg.URL)
+	}
+	if cfg.BatchSize < 0 || cfg.MaxPending < 0 {
+		return fmt.Errorf("batch size and pending batches must not be negative")
+	}
+	if cfg.Index != "" && indexName(cfg.Index) != cfg.Index {
+		return fmt.Errorf("invalid index name %q: use lowercase letters, digits, '-' and '_'", cfg.Index)
+	}
+	for name := range cfg.Labels {
+		if !labelName.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	for name, value := range cfg.Headers {
+		if !headerName.MatchString(name) || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid header %q", name)
+		}
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return fmt.Errorf("a client certificate needs both its certificate and key file")
+	}
+	return nil
+}
+
+// ForRun returns the sinks a run of project p ships to: the project's,
+// unless the run leaves them out, followed by the run's own. Settings that
+// default to the project, such as the index name, are filled in.
+func ForRun(p *model.Project, params model.BatchParams) []model.SinkConfig {
+	var cfgs []model.SinkConfig
+	if !params.NoProjectSinks {
+		cfgs = append(cfgs, p.Sinks...)
+	}
+	cfgs = append(cfgs, params.Sinks...)
+	for i, cfg := range cfgs {
+		switch cfg.Type {
+		case Elasticsearch, OpenSearch:
+			if cfg.Index == "" {
+				cfg.Index = indexName(p.Name)
+			}
+		case Loki, Webhook:
+			labels := map[string]string{"project": p.Name}
+			for k, v := range cfg.Labels {
+				labels[k] = v
+			}
+			cfg.Labels = labels
+		case Splunk:
+			if cfg.SourceType == "" {
+				cfg.SourceType = p.Name
+			}
+		}
+		cfgs[i] = cfg
+	}
+	return cfgs
+}
+
+// Redact returns copies of cfgs without their credentials, for storing the
+// parameters of jobs and runs. Copies that lost any are marked Redacted, so
+// they fail validation instead of reaching the service unauthenticated.
+// The project's sinks aren't part of the parameters; runs take them from
+// the project.
+func Redact(cfgs []model.SinkConfig) []model.SinkConfig {
+	if len(cfgs) == 0 {
+		return cfgs
+	}
+	redacted := make([]model.SinkConfig, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Password != "" || cfg.APIKey != "" || cfg.Token != "" || len(cfg.Headers) > 0 {
+			cfg.Redacted = true
+		}
+		cfg.Password, cfg.APIKey, cfg.Token = "", "", ""
+		if len(cfg.Headers) > 0 {
+			// Headers carry custom authentication, so only their names stay
+			headers := make(map[string]string, len(cfg.Headers))
+			for k := range cfg.Headers {
+				headers[k] = ""
+			}
+			cfg.Headers = headers
+		}
+		redacted[i] = cfg
+	}
+	return redacted
+}
+
+// indexName turns a name into a valid Elasticsearch index name: lowercase,
+// without the characters indexes can't hold and not starting with one of
+// "-_+.".
+func indexName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/*?"<>| ,#:`, r) {
+			return '-'
+		}
+		return r
+	}, strings.ToLower(name))
+	name = strings.TrimLeft(name, "-_+.")
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	if name == "" {
+		return "logforge"
+	}
+	return name
+}
+
+// client makes requests with the authentication and TLS settings of a sink.
+type client struct {
+	cfg  model.SinkConfig
+	http *http.Client
+}
+
+func newClient(cfg model.SinkConfig) (*client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLSInsecure || cfg.CAFile != "" || cfg.CertFile != "" {
+		tc := &tls.Config{InsecureSkipVerify: cfg.TLSInsecure}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+			}
+			tc.RootCAs = pool
+		}
+		if cfg.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tc.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tc
+	}
+	return &client{cfg: cfg, http: &http.Client{Timeout: requestTimeout, Transport: transport}}, nil
+}
+
+// response is the status, headers and body of a response.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (c *client) do(ctx context.Context, method string, url string, contentType string, body []byte, header map[string]string) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case c.cfg.Token != "" && c.cfg.Type == Splunk:
+		req.Header.Set("Authorization", "Splunk "+c.cfg.Token)
+	case c.cfg.Token != "" && c.cfg.Type == Webhook:
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	case c.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.cfg.APIKey)
+	case c.cfg.Username != "":
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: data}, nil
+}
+
+// url joins path to the base URL of the sink, unless the URL already ends
+// with it.
+func (c *client) url(path string) string {
+	base := strings.TrimRight(c.cfg.URL, "/")
+	if strings.HasSuffix(base, path) {
+		return base
+	}
+	return base + path
+}
+
+// post sends a batch and sorts out failures every service shares: requests
+// that didn't get through and load or server errors are retried, other
+// errors refuse the batch. A nil outcome means the service took the request.
+func (c *client) post(ctx context.Context, url string, contentType string, body []byte, header map[string]string, batch []record) (*response, *outcome) {
+	resp, err := c.do(ctx, http.MethodPost, url, contentType, body, header)
+	if err != nil {
+		return nil, &outcome{retry: batch, err: err}
+	}
+	if resp.status >= 200 && resp.status < 300 {
+		return resp, nil
+	}
+	err = statusError(resp)
+	if retryable(resp.status) {
+		return resp, &outcome{retry: batch, err: err, wait: retryAfter(resp.header)}
+	}
+	return resp, &outcome{rejected: batch, err: err}
+}
+
+// retryable reports whether a request that got status may succeed later.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= 500
+}
+
+// retryAfter returns the delay a Retry-After header asks for.
+func retryAfter(h http.Header) time.Duration {
+	if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// statusError describes an unsuccessful response.
+func statusError(resp *response) error {
+	body := strings.TrimSpace(string(resp.body))
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody] + "..."
+	}
+	return fmt.Errorf("HTTP %d: %s", resp.status, body)
+}
+
+// rowJSON returns a row as a JSON object with its columns in order, after
+// the given fields.
+func rowJSON(r record, fields ...field) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	n := 0
+	add := func(k string, v any) {
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		n++
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(v)
+		if err != nil {
+			value, _ = json.Marshal(output.Text(v))
+		}
+		b.Write(value)
+	}
+	for _, f := range fields {
+		if !hasColumn(r.columns, f.key) {
+			add(f.key, f.value)
+		}
+	}
+	for i, c := range r.columns {
+		add(c, r.values[i])
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// field is a key and value added to a row object.
+type field struct {
+	key   string
+	value any
+}
+
+func hasColumn(columns []string, name string) bool {
+	for _, c := range columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rowID derives the deduplication key of a row from the run, its file and
+// its line, so a row sent again within the run has the key it was first
+// sent with.
+func rowID(runID string, file string, seq int) string {
+	sum := sha1.Sum([]byte(runID + "\x00" + file + "\x00" + strconv.Itoa(seq)))
+	return hex.EncodeToString(sum[:])
+}
+
+// rowTimeOrReceived returns the time of a row or, without one, when its
+// file was first started, in row order.
+func rowTimeOrReceived(r record) time.Time {
+	if r.time.IsZero() {
+		return r.received
+	}
+	return r.time
+}
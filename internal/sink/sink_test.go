@@ -0,0 +1,492 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+
+	"pgregory.net/rapid"
+)
+
+func init() {
+	retryDelay = time.Millisecond
+}
+
+// fakeTarget records the batches it is sent; send answers with the
+// outcomes queued in replies, then takes every row.
+type fakeTarget struct {
+	mu       sync.Mutex
+	batches  [][]record
+	replies  []func([]record) outcome
+	block    chan struct{} // when set, send waits for it
+	prepared int
+}
+
+func (t *fakeTarget) prepare(ctx context.Context, batch []record) error {
+	t.prepared++
+	return nil
+}
+
+func (t *fakeTarget) send(ctx context.Context, batch []record) outcome {
+	if t.block != nil {
+		<-t.block
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batches = append(t.batches, batch)
+	if len(t.replies) == 0 {
+		return outcome{}
+	}
+	reply := t.replies[0]
+	t.replies = t.replies[1:]
+	return reply(batch)
+}
+
+func (t *fakeTarget) describe() string { return "fake" }
+
+// bulkItem is an item of a _bulk response.
+func bulkItem(status int) map[string]any {
+	item := map[string]any{"status": status}
+	if status >= 300 {
+		item["error"] = map[string]string{"type": "es_rejected_execution_exception", "reason": "queue full"}
+	}
+	return map[string]any{"index": item}
+}
+
+// Feature: network-log-formatter, Property 26: 输出目标重试后每行恰好送达一次
+// For any rows and any pattern of failed requests and documents refused for
+// load, every row is indexed under its own ID exactly once when retries
+// suffice, and the result counts every row as sent.
+func TestProperty26_RowsArriveOnceDespiteRetries(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		var mu sync.Mutex
+		indexed := make(map[string]int)
+		failRequests := rapid.IntRange(0, 2).Draw(rt, "failedRequests")
+		rejectRate := rapid.IntRange(0, 50).Draw(rt, "rejectPercent")
+		attempt := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			attempt++
+			if failRequests > 0 {
+				failRequests--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+			var items []map[string]any
+			for i := 0; i < len(lines); i += 2 {
+				var action struct {
+					Index struct {
+						ID string `json:"_id"`
+					} `json:"index"`
+				}
+				json.Unmarshal([]byte(lines[i]), &action)
+				// Refuse a share of the documents, but never on the last
+				// attempts, so retries always suffice
+				if attempt < 4 && (attempt*31+i*17)%100 < rejectRate {
+					items = append(items, bulkItem(http.StatusTooManyRequests))
+					continue
+				}
+				indexed[action.Index.ID]++
+				items = append(items, bulkItem(http.StatusCreated))
+			}
+			json.NewEncoder(w).Encode(map[string]any{"errors": true, "items": items})
+		}))
+		defer srv.Close()
+
+		cfg := model.SinkConfig{
+			Type:       Elasticsearch,
+			URL:        srv.URL,
+			Index:      "logs",
+			BatchSize:  rapid.IntRange(1, 10).Draw(rt, "batchSize"),
+			MaxRetries: 10,
+		}
+		s, err := New(cfg, Options{RunID: "run"})
+		if err != nil {
+			rt.Fatalf("New: %v", err)
+		}
+		files := []string{"a.log", "b.log"}
+		for _, f := range files {
+			s.StartFile(f, []string{"msg"})
+		}
+		rows := rapid.IntRange(0, 30).Draw(rt, "rows")
+		for i := 0; i < rows; i++ {
+			f := rapid.SampledFrom(files).Draw(rt, "file")
+			if err := s.WriteRow(f, []any{fmt.Sprint(i)}); err != nil {
+				rt.Fatalf("WriteRow: %v", err)
+			}
+		}
+		if _, err := s.Close(); err != nil {
+			rt.Fatalf("Close: %v", err)
+		}
+
+		res := s.Result()
+		if res.Sent != rows || res.Failed != 0 {
+			rt.Fatalf("sent %d, failed %d (%s); want %d sent", res.Sent, res.Failed, res.Error, rows)
+		}
+		if len(indexed) != rows {
+			rt.Fatalf("%d distinct documents, want %d", len(indexed), rows)
+		}
+		for id, n := range indexed {
+			if n != 1 {
+				rt.Fatalf("document %s indexed %d times", id, n)
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: rows are sent in batches, with the rest on Close
+func TestShipper_Batches(t *testing.T) {
+	target := &fakeTarget{}
+	s := newShipper(model.SinkConfig{Type: Loki, BatchSize: 2}, Options{}, target)
+	s.StartFile("a.log", []string{"msg"})
+	for i := 0; i < 5; i++ {
+		s.WriteRow("a.log", []any{fmt.Sprint(i)})
+	}
+	s.Close()
+	if len(target.batches) != 3 || len(target.batches[2]) != 1 {
+		t.Fatalf("batches = %d, want 2+2+1", len(target.batches))
+	}
+	if target.prepared != 1 {
+		t.Errorf("prepared %d times, want 1", target.prepared)
+	}
+	if target.batches[2][0].seq != 5 {
+		t.Errorf("last row seq = %d, want 5", target.batches[2][0].seq)
+	}
+	if res := s.Result(); res.Sent != 5 || res.Target != "fake" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+// Unit test: retries stop after MaxRetries and the rows count as failed
+func TestShipper_RetriesExhausted(t *testing.T) {
+	fail := func(b []record) outcome { return outcome{retry: b, err: errors.New("HTTP 503")} }
+	target := &fakeTarget{replies: []func([]record) outcome{fail, fail, fail}}
+	s := newShipper(model.SinkConfig{Type: Loki, MaxRetries: 2}, Options{}, target)
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"x"})
+	s.WriteRow("a.log", []any{"y"})
+	s.Close()
+	res := s.Result()
+	if res.Sent != 0 || res.Failed != 2 || res.Error != "HTTP 503" {
+		t.Errorf("result = %+v", res)
+	}
+	if len(target.batches) != 3 {
+		t.Errorf("%d attempts, want 3", len(target.batches))
+	}
+
+	// Rejected rows are not retried
+	target = &fakeTarget{replies: []func([]record) outcome{func(b []record) outcome {
+		return outcome{rejected: b[:1], err: errors.New("mapper_parsing_exception")}
+	}}}
+	s = newShipper(model.SinkConfig{Type: Loki, MaxRetries: -1}, Options{}, target)
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"x"})
+	s.WriteRow("a.log", []any{"y"})
+	s.Close()
+	if res := s.Result(); res.Sent != 1 || res.Failed != 1 || len(target.batches) != 1 {
+		t.Errorf("result = %+v after %d attempts", res, len(target.batches))
+	}
+}
+
+// Unit test: WriteRow waits while the queue of batches is full
+func TestShipper_Backpressure(t *testing.T) {
+	target := &fakeTarget{block: make(chan struct{})}
+	s := newShipper(model.SinkConfig{Type: Loki, BatchSize: 1, MaxPending: 1}, Options{}, target)
+	s.StartFile("a.log", []string{"msg"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// One batch being sent, one queued, the third waits
+		for i := 0; i < 3; i++ {
+			s.WriteRow("a.log", []any{fmt.Sprint(i)})
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatal("WriteRow did not wait for the service")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(target.block)
+	<-done
+	s.Close()
+	if res := s.Result(); res.Sent != 3 {
+		t.Errorf("sent %d, want 3", res.Sent)
+	}
+}
+
+// Unit test: a restarted file drops its unsent rows and numbers from 1 again
+func TestShipper_RestartAndAbort(t *testing.T) {
+	target := &fakeTarget{}
+	s := newShipper(model.SinkConfig{Type: Loki, BatchSize: 10}, Options{}, target)
+	s.StartFile("a.log", []string{"msg"})
+	s.StartFile("b.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"old"})
+	s.WriteRow("b.log", []any{"keep"})
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"new"})
+	s.Close()
+	batch := target.batches[0]
+	if len(batch) != 2 || batch[0].values[0] != "keep" || batch[1].values[0] != "new" || batch[1].seq != 1 {
+		t.Errorf("batch = %+v", batch)
+	}
+	if err := s.WriteRow("c.log", []any{"x"}); err == nil {
+		t.Error("expected an error for rows without columns")
+	}
+
+	target = &fakeTarget{block: make(chan struct{})}
+	s = newShipper(model.SinkConfig{Type: Loki, BatchSize: 1}, Options{}, target)
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"x"})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(target.block)
+	}()
+	s.Abort()
+	s.Abort()
+}
+
+// Unit test: rows a retried script sends again carry the keys and times
+// of the rows they repeat, and rows of other files or runs don't
+func TestShipper_ResentRowsKeepKeys(t *testing.T) {
+	target := &fakeTarget{}
+	s := newShipper(model.SinkConfig{Type: Loki, BatchSize: 2}, Options{RunID: "run"}, target)
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"one"})
+	s.WriteRow("a.log", []any{"two"})
+	time.Sleep(time.Millisecond)
+	s.StartFile("a.log", []string{"msg"})
+	s.WriteRow("a.log", []any{"one"})
+	s.WriteRow("a.log", []any{"two, repaired"})
+	s.StartFile("b.log", []string{"msg"})
+	s.WriteRow("b.log", []any{"one"})
+	s.Close()
+
+	if len(target.batches) != 3 {
+		t.Fatalf("batches = %+v", target.batches)
+	}
+	first, again := target.batches[0], target.batches[1]
+	for i := range first {
+		if again[i].id != first[i].id || !again[i].received.Equal(first[i].received) {
+			t.Errorf("row %d sent again as %s at %v, first as %s at %v", i+1, again[i].id, again[i].received, first[i].id, first[i].received)
+		}
+	}
+	if first[0].id == first[1].id || !first[0].received.Before(first[1].received) {
+		t.Errorf("rows of a file share key or time: %+v", first)
+	}
+	if other := target.batches[2][0]; other.id == first[0].id || other.id != rowID("run", "b.log", 1) {
+		t.Errorf("row of another file has key %s", other.id)
+	}
+	if rowID("run", "a.log", 1) == rowID("other run", "a.log", 1) {
+		t.Error("runs share row keys")
+	}
+}
+
+// Unit test: the row time comes from the configured or first datetime column,
+// read in the zone of the options
+func TestShipper_RowTime(t *testing.T) {
+	target := &fakeTarget{}
+	s := newShipper(model.SinkConfig{Type: Loki}, Options{}, target)
+	s.StartFile("a.log", []string{"day", "at", "msg"})
+	s.WriteRow("a.log", []any{"2024-03-01", "2024-03-01T08:00:00Z", "x"})
+	s.WriteRow("a.log", []any{"2024-03-02", "bad", "y"})
+	s.Close()
+	got := target.batches[0]
+	if !got[0].time.Equal(time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)) || !got[1].time.IsZero() {
+		t.Errorf("times = %v, %v", got[0].time, got[1].time)
+	}
+
+	target = &fakeTarget{}
+	s = newShipper(model.SinkConfig{Type: Loki, TimeColumn: "When"}, Options{}, target)
+	s.StartFile("a.log", []string{"at", "when"})
+	s.WriteRow("a.log", []any{"2024-03-01T08:00:00Z", "2024-03-05T00:00:00Z"})
+	s.Close()
+	if got := target.batches[0][0].time; got.Day() != 5 {
+		t.Errorf("time = %v, want the when column", got)
+	}
+
+	// Times without an offset are in the zone of the options
+	target = &fakeTarget{}
+	s = newShipper(model.SinkConfig{Type: Loki}, Options{Location: time.FixedZone("", 8*3600)}, target)
+	s.StartFile("a.log", []string{"at"})
+	s.WriteRow("a.log", []any{"2024-03-01 08:00:00"})
+	s.Close()
+	if got := target.batches[0][0].time; !got.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("time = %v, want 08:00 at +08:00", got)
+	}
+}
+
+// Unit test: sink configuration validation
+func TestValidate(t *testing.T) {
+	good := []model.SinkConfig{
+		{Type: Elasticsearch, URL: "https://es:9200", Index: "fw-logs"},
+		{Type: OpenSearch, URL: "http://os:9200"},
+		{Type: Loki, URL: "http://loki:3100", Labels: map[string]string{"env": "prod"}},
+		{Type: Webhook, URL: "https://hooks.example.com/logs", Headers: map[string]string{"X-Api-Key": "k"}},
+		{Type: Splunk, URL: "https://splunk:8088", Token: "t"},
+		{Type: ClickHouse, URL: "http://ch:8123", Table: "logs.firewall", CertFile: "c.pem", KeyFile: "k.pem"},
+	}
+	if err := Validate(good); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	bad := []model.SinkConfig{
+		{Type: "kafka", URL: "http://k:9092"},
+		{Type: Loki, URL: "loki:3100"},
+		{Type: Loki, URL: "ftp://loki"},
+		{Type: Elasticsearch, URL: "http://es", Index: "Logs"},
+		{Type: Loki, URL: "http://loki", Labels: map[string]string{"bad-name": "x"}},
+		{Type: Loki, URL: "http://loki", BatchSize: -1},
+		{Type: Splunk, URL: "https://splunk:8088"},
+		{Type: ClickHouse, URL: "http://ch:8123"},
+		{Type: ClickHouse, URL: "http://ch:8123", Table: "logs; DROP TABLE x"},
+		{Type: Webhook, URL: "http://hook", Headers: map[string]string{"Bad Header": "x"}},
+		{Type: Webhook, URL: "http://hook", Headers: map[string]string{"X-Key": "a\r\nInjected: 1"}},
+		{Type: Webhook, URL: "http://hook", CertFile: "c.pem"},
+	}
+	for _, cfg := range bad {
+		if err := Validate([]model.SinkConfig{cfg}); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+	if _, err := New(bad[0], Options{RunID: "run"}); err == nil {
+		t.Error("expected New to reject an unknown type")
+	}
+}
+
+// Unit test: redacted sinks lose their credentials and header values, keep
+// everything else and no longer validate
+func TestRedact(t *testing.T) {
+	cfgs := []model.SinkConfig{
+		{Type: Elasticsearch, URL: "https://es", Username: "u", Password: "pw", APIKey: "k"},
+		{Type: Webhook, URL: "https://hook", Headers: map[string]string{"X-Auth": "secret"}},
+		{Type: Loki, URL: "http://loki"},
+	}
+	redacted := Redact(cfgs)
+	if r := redacted[0]; r.Password != "" || r.APIKey != "" || r.Username != "u" || !r.Redacted {
+		t.Errorf("redacted = %+v", r)
+	}
+	if r := redacted[1]; r.Headers["X-Auth"] != "" || len(r.Headers) != 1 || !r.Redacted {
+		t.Errorf("redacted = %+v", r)
+	}
+	if redacted[2].Redacted || Validate(redacted[2:]) != nil {
+		t.Errorf("a sink without credentials should stay usable: %+v", redacted[2])
+	}
+	if err := Validate(redacted[:1]); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Validate = %v", err)
+	}
+	if cfgs[0].Password != "pw" || cfgs[1].Headers["X-Auth"] != "secret" {
+		t.Error("Redact changed its argument")
+	}
+}
+
+// Unit test: project sinks with project defaults, then the run's
+func TestForRun(t *testing.T) {
+	p := &model.Project{Name: "Core Firewall", Sinks: []model.SinkConfig{
+		{Type: Elasticsearch, URL: "http://es"},
+		{Type: Loki, URL: "http://loki", Labels: map[string]string{"env": "prod"}},
+	}}
+	params := model.BatchParams{Sinks: []model.SinkConfig{
+		{Type: OpenSearch, URL: "http://os", Index: "adhoc"},
+		{Type: Splunk, URL: "https://splunk:8088", Token: "t"},
+		{Type: Webhook, URL: "http://hook"},
+	}}
+	got := ForRun(p, params)
+	if len(got) != 5 || got[0].Index != "core-firewall" || got[2].Index != "adhoc" {
+		t.Fatalf("sinks = %+v", got)
+	}
+	if got[3].SourceType != "Core Firewall" || got[4].Labels["project"] != "Core Firewall" {
+		t.Errorf("splunk and webhook defaults = %+v, %+v", got[3], got[4])
+	}
+	if got[1].Labels["project"] != "Core Firewall" || got[1].Labels["env"] != "prod" {
+		t.Errorf("labels = %v", got[1].Labels)
+	}
+	if _, ok := p.Sinks[1].Labels["project"]; ok {
+		t.Error("ForRun changed the project's labels")
+	}
+	params.NoProjectSinks = true
+	if got := ForRun(p, params); len(got) != 3 || got[0].Type != OpenSearch {
+		t.Errorf("sinks without the project's = %+v", got)
+	}
+}
+
+// Unit test: index names derived from project names
+func TestIndexName(t *testing.T) {
+	cases := map[string]string{
+		"Core Firewall": "core-firewall",
+		"_fw:logs":      "fw-logs",
+		"防火墙":           "防火墙",
+		"...":           "logforge",
+	}
+	for in, want := range cases {
+		if got := indexName(in); got != want {
+			t.Errorf("indexName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// Unit test: requests carry the configured headers and trust the given CA
+func TestClient_HeadersAndTLS(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o644)
+
+	send := func(cfg model.SinkConfig) model.SinkResult {
+		t.Helper()
+		s, err := New(cfg, Options{})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		s.StartFile("a.log", []string{"msg"})
+		s.WriteRow("a.log", []any{"x"})
+		s.Close()
+		return s.Result()
+	}
+
+	base := model.SinkConfig{Type: Webhook, URL: srv.URL, MaxRetries: -1, Token: "secret", Headers: map[string]string{"X-Team": "ops"}}
+	if res := send(base); res.Failed != 1 {
+		t.Errorf("expected an untrusted certificate to fail, got %+v", res)
+	}
+	withCA := base
+	withCA.CAFile = caFile
+	if res := send(withCA); res.Sent != 1 {
+		t.Fatalf("result with the CA = %+v", res)
+	}
+	if header.Get("Authorization") != "Bearer secret" || header.Get("X-Team") != "ops" {
+		t.Errorf("headers = %v", header)
+	}
+	insecure := base
+	insecure.TLSInsecure = true
+	if res := send(insecure); res.Sent != 1 {
+		t.Errorf("result without verification = %+v", res)
+	}
+
+	bad := base
+	bad.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := New(bad, Options{}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
@@ -0,0 +1,90 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// splunkTarget sends rows to a Splunk HTTP Event Collector as events, one
+// per row, with the row as a JSON object. The source of an event is the
+// row's file and its time the row's time, or when it was streamed; the
+// sourcetype defaults to the project name and the labels, with the key of
+// the row as log_id, become indexed fields. HEC doesn't deduplicate, so
+// searches drop rows a retry sent again with "dedup log_id".
+type splunkTarget struct {
+	c *client
+}
+
+func newSplunkTarget(c *client) *splunkTarget {
+	return &splunkTarget{c: c}
+}
+
+func (t *splunkTarget) describe() string {
+	d := t.endpoint() + " / " + t.c.cfg.SourceType
+	if t.c.cfg.Index != "" {
+		d += " / " + t.c.cfg.Index
+	}
+	return d
+}
+
+func (t *splunkTarget) prepare(ctx context.Context, batch []record) error {
+	return nil
+}
+
+// splunkEvent is an event of an HEC request.
+type splunkEvent struct {
+	Time       json.Number       `json:"time"`
+	Source     string            `json:"source"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Event      json.RawMessage   `json:"event"`
+}
+
+// send posts the events of a batch, one JSON object after another, as HEC
+// takes them.
+func (t *splunkTarget) send(ctx context.Context, batch []record) outcome {
+	var buf bytes.Buffer
+	for _, r := range batch {
+		ts := rowTimeOrReceived(r)
+		secs := strconv.FormatFloat(float64(ts.UnixMilli())/1000, 'f', 3, 64)
+		data, err := json.Marshal(splunkEvent{
+			Time:       json.Number(secs),
+			Source:     r.file,
+			SourceType: t.c.cfg.SourceType,
+			Index:      t.c.cfg.Index,
+			Fields:     t.fields(r),
+			Event:      rowJSON(r),
+		})
+		if err != nil {
+			return outcome{rejected: batch, err: err}
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if _, failed := t.c.post(ctx, t.endpoint(), "application/json", buf.Bytes(), nil, batch); failed != nil {
+		return *failed
+	}
+	return outcome{}
+}
+
+// fields returns the indexed fields of a row's event.
+func (t *splunkTarget) fields(r record) map[string]string {
+	fields := map[string]string{idField: r.id}
+	for k, v := range t.c.cfg.Labels {
+		fields[k] = v
+	}
+	return fields
+}
+
+// endpoint returns the event endpoint of the collector, or the configured
+// URL when it names a collector endpoint already.
+func (t *splunkTarget) endpoint() string {
+	if strings.Contains(t.c.cfg.URL, "/services/collector") {
+		return t.c.cfg.URL
+	}
+	return t.c.url("/services/collector/event")
+}
@@ -0,0 +1,111 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// Unit test: rows are sent to the collector as events with the token,
+// sourcetype, index, source file and row time
+func TestSplunk_Events(t *testing.T) {
+	var mu sync.Mutex
+	var auth, path string
+	var events []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("Authorization")
+		path = r.URL.Path
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var e map[string]json.RawMessage
+			if err := dec.Decode(&e); err != nil {
+				t.Errorf("bad event: %v", err)
+				break
+			}
+			events = append(events, e)
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	cfg := model.SinkConfig{Type: Splunk, URL: srv.URL, Token: "hec-token", SourceType: "Core Firewall", Index: "net", Labels: map[string]string{"site": "bj"}}
+	s, err := New(cfg, Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.StartFile("fw.log", []string{"time", "msg"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:00.250Z", "up"})
+	s.WriteRow("fw.log", []any{"bad", "down"})
+	s.Close()
+
+	res := s.Result()
+	if res.Sent != 2 || res.Target != srv.URL+"/services/collector/event / Core Firewall / net" {
+		t.Fatalf("result = %+v", res)
+	}
+	if auth != "Splunk hec-token" || path != "/services/collector/event" {
+		t.Errorf("auth %q, path %q", auth, path)
+	}
+	if len(events) != 2 {
+		t.Fatalf("%d events, want 2", len(events))
+	}
+	e := events[0]
+	want := map[string]string{
+		"time": "1709280000.250", "source": `"fw.log"`, "sourcetype": `"Core Firewall"`, "index": `"net"`,
+		"fields": `{"log_id":"` + rowID("", "fw.log", 1) + `","site":"bj"}`, "event": `{"time":"2024-03-01T08:00:00.250Z","msg":"up"}`,
+	}
+	for k, v := range want {
+		if string(e[k]) != v {
+			t.Errorf("%s = %s, want %s", k, e[k], v)
+		}
+	}
+	if !strings.Contains(string(events[1]["time"]), ".") {
+		t.Errorf("a row without a time should get when its file was started, got %s", events[1]["time"])
+	}
+}
+
+// Unit test: a busy collector is retried, an invalid token is not
+func TestSplunk_Errors(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		io.Copy(io.Discard, r.Body)
+		calls++
+		if r.Header.Get("Authorization") != "Splunk good" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"text":"Invalid token","code":4}`))
+			return
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	send := func(token string) model.SinkResult {
+		s, _ := New(model.SinkConfig{Type: Splunk, URL: srv.URL + "/services/collector", Token: token}, Options{})
+		s.StartFile("fw.log", []string{"msg"})
+		s.WriteRow("fw.log", []any{"x"})
+		s.Close()
+		return s.Result()
+	}
+	if res := send("good"); res.Sent != 1 || calls != 2 {
+		t.Errorf("result = %+v after %d calls", res, calls)
+	}
+	calls = 0
+	if res := send("bad"); res.Failed != 1 || calls != 1 || !strings.Contains(res.Error, "Invalid token") {
+		t.Errorf("result = %+v after %d calls", res, calls)
+	}
+}
@@ -0,0 +1,60 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// webhookTarget posts each batch to a URL as a JSON object holding the run,
+// the labels and the rows. Each row carries its file, line number and key,
+// with which the receiver can drop rows a retry sends again.
+type webhookTarget struct {
+	c     *client
+	runID string
+}
+
+func newWebhookTarget(c *client, runID string) *webhookTarget {
+	return &webhookTarget{c: c, runID: runID}
+}
+
+func (t *webhookTarget) describe() string {
+	return t.c.cfg.URL
+}
+
+func (t *webhookTarget) prepare(ctx context.Context, batch []record) error {
+	return nil
+}
+
+// webhookRow is a row of a webhook request.
+type webhookRow struct {
+	ID     string          `json:"id"`
+	File   string          `json:"file"`
+	Line   int             `json:"line"`
+	Time   string          `json:"time,omitempty"`
+	Values json.RawMessage `json:"values"`
+}
+
+func (t *webhookTarget) send(ctx context.Context, batch []record) outcome {
+	body := struct {
+		Source string            `json:"source"`
+		RunID  string            `json:"run_id,omitempty"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Rows   []webhookRow      `json:"rows"`
+	}{Source: "logforge", RunID: t.runID, Labels: t.c.cfg.Labels}
+	for _, r := range batch {
+		row := webhookRow{ID: r.id, File: r.file, Line: r.seq, Values: rowJSON(r)}
+		if !r.time.IsZero() {
+			row.Time = r.time.Format(time.RFC3339Nano)
+		}
+		body.Rows = append(body.Rows, row)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return outcome{rejected: batch, err: err}
+	}
+	if _, failed := t.c.post(ctx, t.c.cfg.URL, "application/json", data, nil, batch); failed != nil {
+		return *failed
+	}
+	return outcome{}
+}
@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"network-log-formatter/internal/model"
+)
+
+// Unit test: each batch is posted as one JSON object with the run, labels
+// and rows, each row with its key, file, line, time and values in column
+// order
+func TestWebhook_Post(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/hooks/logs" || r.Method != http.MethodPost {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	cfg := model.SinkConfig{Type: Webhook, URL: srv.URL + "/hooks/logs", BatchSize: 2, Labels: map[string]string{"project": "fw"}}
+	s, err := New(cfg, Options{RunID: "run-1"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.StartFile("fw.log", []string{"time", "port", "msg"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:00Z", json.Number("443"), "up"})
+	s.WriteRow("fw.log", []any{"bad", nil, "down"})
+	s.WriteRow("fw.log", []any{"2024-03-01T08:00:02Z", json.Number("80")})
+	s.Close()
+
+	if res := s.Result(); res.Sent != 3 || res.Target != cfg.URL {
+		t.Fatalf("result = %+v", res)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("%d requests, want 2", len(bodies))
+	}
+	if string(bodies[0]["source"]) != `"logforge"` || string(bodies[0]["run_id"]) != `"run-1"` || string(bodies[0]["labels"]) != `{"project":"fw"}` {
+		t.Errorf("body = %v", bodies[0])
+	}
+	var rows []map[string]json.RawMessage
+	json.Unmarshal(bodies[0]["rows"], &rows)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %s", bodies[0]["rows"])
+	}
+	if string(rows[0]["id"]) != `"`+rowID("run-1", "fw.log", 1)+`"` || string(rows[0]["file"]) != `"fw.log"` || string(rows[0]["line"]) != "1" || string(rows[0]["time"]) != `"2024-03-01T08:00:00Z"` {
+		t.Errorf("row = %v", rows[0])
+	}
+	if got := string(rows[0]["values"]); got != `{"time":"2024-03-01T08:00:00Z","port":443,"msg":"up"}` {
+		t.Errorf("values = %s", got)
+	}
+	if _, ok := rows[1]["time"]; ok {
+		t.Errorf("a row without a time should have none, got %s", rows[1]["time"])
+	}
+	json.Unmarshal(bodies[1]["rows"], &rows)
+	if got := string(rows[0]["values"]); got != `{"time":"2024-03-01T08:00:02Z","port":80,"msg":null}` {
+		t.Errorf("short row values = %s", got)
+	}
+}
@@ -0,0 +1,99 @@
+package summary
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+)
+
+// Sheet returns the rows of the Summary sheet of s: a section per table,
+// each under a heading and separated by an empty row.
+func Sheet(s *model.RunSummary) [][]any {
+	var rows [][]any
+	section := func(title string, header ...string) {
+		if len(rows) > 0 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, []any{output.Heading(title)})
+		if len(header) > 0 {
+			h := make([]any, len(header))
+			for i, c := range header {
+				h[i] = output.Heading(c)
+			}
+			rows = append(rows, h)
+		}
+	}
+
+	section("Summary")
+	rows = append(rows, []any{"Rows", number(s.Rows)}, []any{"From", text(s.From)}, []any{"To", text(s.To)})
+	if s.Approximate {
+		rows = append(rows, []any{"Note", "Some columns had too many distinct values to count exactly; their counts are lower bounds"})
+	}
+
+	section("Files", "File", "Rows", "Time column", "From", "To")
+	for _, f := range s.Files {
+		rows = append(rows, []any{f.File, number(f.Rows), text(f.TimeColumn), text(f.From), text(f.To)})
+	}
+
+	if len(s.Columns) > 0 {
+		section("Columns", "Column", "Type", "Type from", "Values")
+		for _, c := range s.Columns {
+			from := "values"
+			if c.Declared {
+				from = "schema"
+			}
+			rows = append(rows, []any{c.Name, text(c.Type), from, number(c.Values)})
+		}
+	}
+
+	for _, top := range s.TopIPs {
+		section("Top addresses: "+top.Column, "Address", "Rows", "Share (%)")
+		rows = appendCounts(rows, top.Values, top.Total)
+	}
+	for _, st := range s.Statuses {
+		section("Values: "+st.Column, "Value", "Rows", "Share (%)")
+		rows = appendCounts(rows, st.Values, st.Total)
+	}
+
+	if len(s.Hourly) > 0 {
+		section("Rows per hour", "Hour", "Rows")
+		for _, h := range s.Hourly {
+			rows = append(rows, []any{h.Hour, number(h.Count)})
+		}
+	}
+
+	if len(s.Errors) > 0 {
+		section("Top error messages", "Message", "Rows")
+		for _, e := range s.Errors {
+			rows = append(rows, []any{e.Value, number(e.Count)})
+		}
+	}
+	return rows
+}
+
+// appendCounts appends a row per value with its count and its share of
+// total.
+func appendCounts(rows [][]any, values []model.ValueCount, total int) [][]any {
+	for _, v := range values {
+		var share any
+		if total > 0 {
+			share = json.Number(strconv.FormatFloat(100*float64(v.Count)/float64(total), 'f', 1, 64))
+		}
+		rows = append(rows, []any{v.Value, number(v.Count), share})
+	}
+	return rows
+}
+
+func number(n int) any {
+	return json.Number(strconv.Itoa(n))
+}
+
+// text returns s as a cell value; nil leaves the cell empty.
+func text(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
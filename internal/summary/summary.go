@@ -0,0 +1,403 @@
+// Package summary sums up the rows a run writes: the rows and time range
+// of each file, the most frequent addresses of the IP columns, the values
+// of the status and action columns, the rows per hour and the most
+// frequent error messages. Columns declared in the schema keep their type;
+// the others are typed by their values.
+package summary
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/schema"
+)
+
+// TopN is how many addresses of an IP column and how many error messages
+// a summary lists.
+const TopN = 10
+
+// maxStatuses is how many values of a status or action column a summary
+// lists.
+const maxStatuses = 20
+
+// maxTracked bounds the distinct values counted per column; past it the
+// less frequent half is dropped.
+const maxTracked = 4096
+
+// maxGapHours is the longest span listed hour by hour, hours without rows
+// included; longer spans list the hours with rows only.
+const maxGapHours = 31 * 24
+
+// maxMessage is the length in characters error messages are cut to.
+const maxMessage = 200
+
+// hourLayout names the hours of the histogram.
+const hourLayout = "2006-01-02 15:00:00"
+
+// Words of a column name that give the column a role.
+var (
+	statusWords  = []string{"status", "action", "result", "verdict", "disposition", "decision", "outcome"}
+	levelWords   = []string{"level", "severity", "loglevel", "lvl", "priority"}
+	messageWords = []string{"message", "msg", "reason", "description", "detail", "details"}
+	errorWords   = []string{"error", "err", "errmsg", "errormessage"}
+)
+
+// errorLevels are the values of a level column that mark an error.
+var errorLevels = map[string]bool{
+	"error": true, "err": true, "fatal": true, "critical": true, "crit": true,
+	"alert": true, "emerg": true, "emergency": true, "panic": true, "severe": true,
+}
+
+// Collector sums up the rows of a run as they are written. A file started
+// again drops the rows of its earlier start, as the writers do. It isn't
+// safe for concurrent use.
+type Collector struct {
+	schema []model.ColumnSchema
+	files  map[string]*fileStats
+}
+
+// fileStats is what a file's rows add up to.
+type fileStats struct {
+	columns []*column
+	rows    int
+	level   int // level or severity column; -1 for none
+	message int // column of the error messages; -1 for none
+	errors  counter
+}
+
+// column is what the values of a file's column add up to.
+type column struct {
+	name     string
+	declared string // type in the schema; "" when undeclared
+	status   bool   // a status or action column
+	values   int    // rows with a value
+	types    map[string]int
+	ips      counter
+	statuses counter
+	times    times
+}
+
+// times is the range and hours of the values of a column that are times.
+type times struct {
+	first, last time.Time
+	from, to    string // first and last as written
+	hours       map[string]int
+}
+
+// New returns a collector typing the columns declared in columns by the
+// schema.
+func New(columns []model.ColumnSchema) *Collector {
+	return &Collector{schema: columns, files: make(map[string]*fileStats)}
+}
+
+// StartFile begins the rows of file with the given columns.
+func (c *Collector) StartFile(file string, columns []string) {
+	f := &fileStats{level: -1, message: -1}
+	errorColumn := -1
+	for i, name := range output.Columns(columns) {
+		words := nameWords(name)
+		col := &column{
+			name:     name,
+			declared: schema.Declared(c.schema, name),
+			status:   hasWord(words, statusWords),
+			types:    make(map[string]int),
+		}
+		f.columns = append(f.columns, col)
+		switch {
+		case f.level < 0 && hasWord(words, levelWords):
+			f.level = i
+		case f.message < 0 && hasWord(words, messageWords):
+			f.message = i
+		case errorColumn < 0 && hasWord(words, errorWords):
+			errorColumn = i
+		}
+	}
+	if f.level < 0 || f.message < 0 {
+		// Without a level every value of an error column is an error; with
+		// one, the error column holds the message if nothing else does
+		f.message = errorColumn
+	}
+	c.files[file] = f
+}
+
+// WriteRow counts a row of a started file.
+func (c *Collector) WriteRow(file string, values []any) {
+	f := c.files[file]
+	if f == nil {
+		return
+	}
+	f.rows++
+	for i, col := range f.columns {
+		if i < len(values) && values[i] != nil {
+			col.add(values[i])
+		}
+	}
+	if msg := f.errorMessage(values); msg != "" {
+		f.errors.add(msg, 1)
+	}
+}
+
+// add counts a value of the column.
+func (col *column) add(v any) {
+	col.values++
+	typ := schema.Infer(v)
+	col.types[typ]++
+	s, _ := v.(string)
+	switch {
+	case typ == schema.IP && (col.declared == "" || col.declared == schema.IP):
+		col.ips.add(s, 1)
+	case typ == schema.DateTime && (col.declared == "" || col.declared == schema.DateTime):
+		col.times.add(s)
+	}
+	if col.status {
+		col.statuses.add(output.Text(v), 1)
+	}
+}
+
+// typ returns the type of the column: the declared one, or the one most
+// of its values have.
+func (col *column) typ() string {
+	if col.declared != "" {
+		return col.declared
+	}
+	return schema.Majority(col.types)
+}
+
+// add counts a time written as s.
+func (ts *times) add(s string) {
+	t, _, ok := output.ParseTime(s, time.UTC)
+	if !ok {
+		return
+	}
+	if ts.hours == nil {
+		ts.hours = make(map[string]int)
+		ts.first, ts.from, ts.last, ts.to = t, s, t, s
+	} else if t.Before(ts.first) {
+		ts.first, ts.from = t, s
+	} else if t.After(ts.last) {
+		ts.last, ts.to = t, s
+	}
+	// Hours as written: a time with an offset counts in its own hour
+	ts.hours[t.Format(hourLayout)]++
+}
+
+// errorMessage returns the error message of a row; "" when the row isn't
+// an error.
+func (f *fileStats) errorMessage(values []any) string {
+	text := func(i int) string {
+		if i < 0 || i >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(output.Text(values[i]))
+	}
+	if f.level >= 0 && !errorLevels[strings.ToLower(text(f.level))] {
+		return ""
+	}
+	msg := text(f.message)
+	if r := []rune(msg); len(r) > maxMessage {
+		msg = string(r[:maxMessage]) + "…"
+	}
+	return msg
+}
+
+// timeColumn returns the first column of the file holding times; nil when
+// there is none.
+func (f *fileStats) timeColumn() *column {
+	for _, col := range f.columns {
+		if col.times.hours != nil && col.typ() == schema.DateTime {
+			return col
+		}
+	}
+	return nil
+}
+
+// Summary returns the summary of the rows counted so far. Columns of the
+// same name in several files are summed up as one.
+func (c *Collector) Summary() *model.RunSummary {
+	files := make([]string, 0, len(c.files))
+	for file := range c.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	s := &model.RunSummary{}
+	var span times
+	var errors counter
+	var order []string
+	merged := make(map[string]*column)
+	for _, file := range files {
+		f := c.files[file]
+		fs := model.FileSummary{File: file, Rows: f.rows}
+		if tc := f.timeColumn(); tc != nil {
+			fs.TimeColumn, fs.From, fs.To = tc.name, tc.times.from, tc.times.to
+			span.merge(tc.times)
+		}
+		s.Files = append(s.Files, fs)
+		s.Rows += f.rows
+		errors.merge(f.errors)
+		for _, col := range f.columns {
+			m := merged[col.name]
+			if m == nil {
+				m = &column{name: col.name, declared: col.declared, types: make(map[string]int)}
+				merged[col.name] = m
+				order = append(order, col.name)
+			}
+			m.status = m.status || col.status
+			m.values += col.values
+			for t, n := range col.types {
+				m.types[t] += n
+			}
+			m.ips.merge(col.ips)
+			m.statuses.merge(col.statuses)
+		}
+	}
+	s.From, s.To = span.from, span.to
+	s.Hourly = span.histogram()
+	s.Errors = errors.top(TopN)
+	s.Approximate = errors.dropped
+
+	for _, name := range order {
+		m := merged[name]
+		s.Columns = append(s.Columns, model.ColumnSummary{Name: name, Type: m.typ(), Declared: m.declared != "", Values: m.values})
+		if m.typ() == schema.IP && len(m.ips.counts) > 0 {
+			s.TopIPs = append(s.TopIPs, model.TopValues{Column: name, Total: m.values, Values: m.ips.top(TopN)})
+			s.Approximate = s.Approximate || m.ips.dropped
+		}
+		if m.status && len(m.statuses.counts) > 0 {
+			s.Statuses = append(s.Statuses, model.TopValues{Column: name, Total: m.values, Values: m.statuses.top(maxStatuses)})
+			s.Approximate = s.Approximate || m.statuses.dropped
+		}
+	}
+	return s
+}
+
+// merge adds the times of o.
+func (ts *times) merge(o times) {
+	if o.hours == nil {
+		return
+	}
+	if ts.hours == nil {
+		ts.hours = make(map[string]int)
+		ts.first, ts.from, ts.last, ts.to = o.first, o.from, o.last, o.to
+	}
+	if o.first.Before(ts.first) {
+		ts.first, ts.from = o.first, o.from
+	}
+	if o.last.After(ts.last) {
+		ts.last, ts.to = o.last, o.to
+	}
+	for h, n := range o.hours {
+		ts.hours[h] += n
+	}
+}
+
+// histogram returns the rows per hour in order, with the hours without
+// rows in between when the span is short enough.
+func (ts *times) histogram() []model.HourCount {
+	hours := make([]string, 0, len(ts.hours))
+	for h := range ts.hours {
+		hours = append(hours, h)
+	}
+	if len(hours) == 0 {
+		return nil
+	}
+	sort.Strings(hours)
+	first, _ := time.Parse(hourLayout, hours[0])
+	last, _ := time.Parse(hourLayout, hours[len(hours)-1])
+	if last.Sub(first) <= maxGapHours*time.Hour {
+		hours = hours[:0]
+		for t := first; !t.After(last); t = t.Add(time.Hour) {
+			hours = append(hours, t.Format(hourLayout))
+		}
+	}
+	counts := make([]model.HourCount, len(hours))
+	for i, h := range hours {
+		counts[i] = model.HourCount{Hour: h, Count: ts.hours[h]}
+	}
+	return counts
+}
+
+// counter counts how often values occur, keeping at most maxTracked of
+// them. Past that it drops the less frequent half, so the counts of the
+// values kept may miss rows from before they were dropped.
+type counter struct {
+	counts  map[string]int
+	dropped bool
+}
+
+// add counts n more of v.
+func (c *counter) add(v string, n int) {
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	if _, ok := c.counts[v]; !ok && len(c.counts) >= maxTracked {
+		c.prune()
+	}
+	c.counts[v] += n
+}
+
+// prune drops the values counted no more often than the median.
+func (c *counter) prune() {
+	counts := make([]int, 0, len(c.counts))
+	for _, n := range c.counts {
+		counts = append(counts, n)
+	}
+	sort.Ints(counts)
+	median := counts[len(counts)/2]
+	for v, n := range c.counts {
+		if n <= median {
+			delete(c.counts, v)
+		}
+	}
+	c.dropped = true
+}
+
+// merge adds the counts of o.
+func (c *counter) merge(o counter) {
+	for v, n := range o.counts {
+		c.add(v, n)
+	}
+	c.dropped = c.dropped || o.dropped
+}
+
+// top returns the n most frequent values, the most frequent first and
+// ties in value order.
+func (c *counter) top(n int) []model.ValueCount {
+	values := make([]model.ValueCount, 0, len(c.counts))
+	for v, count := range c.counts {
+		values = append(values, model.ValueCount{Value: v, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
+
+// nameWords splits a column name into its lower case words.
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// hasWord reports whether one of words is among want.
+func hasWord(words []string, want []string) bool {
+	for _, w := range words {
+		for _, x := range want {
+			if w == x {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+
+	"pgregory.net/rapid"
+)
+
+// Feature: network-log-formatter, Property 27: 汇总的行数与每小时行数与写入的行一致
+// For any files of rows with a time in their first column, the summary
+// counts every row once per file and once in the hour of its time, and an
+// address is never counted in more rows than hold it.
+func TestProperty27_SummaryCountsEveryRow(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		c := New(nil)
+		files := rapid.SliceOfNDistinct(rapid.StringMatching(`[a-z]{1,6}\.log`), 1, 4, rapid.ID[string]).Draw(rt, "files")
+		total := 0
+		addrs := make(map[string]int)
+		for _, file := range files {
+			c.StartFile(file, []string{"time", "src"})
+			n := rapid.IntRange(0, 50).Draw(rt, "rows")
+			for i := 0; i < n; i++ {
+				ts := fmt.Sprintf("2024-05-%02d %02d:%02d:00", rapid.IntRange(1, 3).Draw(rt, "day"), rapid.IntRange(0, 23).Draw(rt, "hour"), rapid.IntRange(0, 59).Draw(rt, "minute"))
+				addr := fmt.Sprintf("10.0.0.%d", rapid.IntRange(1, 5).Draw(rt, "addr"))
+				c.WriteRow(file, []any{ts, addr})
+				addrs[addr]++
+			}
+			total += n
+		}
+
+		s := c.Summary()
+		if s.Rows != total || len(s.Files) != len(files) {
+			rt.Fatalf("summary of %d rows in %d files, want %d in %d", s.Rows, len(s.Files), total, len(files))
+		}
+		hourly := 0
+		for _, h := range s.Hourly {
+			hourly += h.Count
+		}
+		if hourly != total {
+			rt.Fatalf("hours hold %d rows, want %d", hourly, total)
+		}
+		for _, top := range s.TopIPs {
+			for _, v := range top.Values {
+				if v.Count != addrs[v.Value] {
+					rt.Fatalf("%s counted %d times, want %d", v.Value, v.Count, addrs[v.Value])
+				}
+			}
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: files, times, addresses, statuses, hours and error messages
+// add up across files, with column types from the values
+func TestCollector_Summary(t *testing.T) {
+	c := New(nil)
+	c.StartFile("fw.log", []string{"ts", "src_ip", "dst_ip", "action", "level", "msg"})
+	rows := [][]any{
+		{"2024-05-01T10:15:00", "10.0.0.1", "8.8.8.8", "allow", "info", "ok"},
+		{"2024-05-01T10:45:00", "10.0.0.1", "1.1.1.1", "deny", "ERROR", "blocked by rule 7"},
+		{"2024-05-01T13:05:00", "10.0.0.2", "8.8.8.8", "allow", "error", "blocked by rule 7"},
+		{"2024-05-01T09:59:59", nil, nil, "allow", "warn", "late"},
+	}
+	for _, r := range rows {
+		c.WriteRow("fw.log", r)
+	}
+	c.StartFile("app.log", []string{"time", "status", "error", "bytes"})
+	c.WriteRow("app.log", []any{"2024-05-01 08:30:00", json.Number("500"), "timeout", json.Number("12")})
+	c.WriteRow("app.log", []any{"2024-05-01 08:31:00", json.Number("200"), nil, json.Number("1.5")})
+	c.WriteRow("app.log", []any{nil, json.Number("200"), "", json.Number("3")})
+
+	s := c.Summary()
+	wantFiles := []model.FileSummary{
+		{File: "app.log", Rows: 3, TimeColumn: "time", From: "2024-05-01 08:30:00", To: "2024-05-01 08:31:00"},
+		{File: "fw.log", Rows: 4, TimeColumn: "ts", From: "2024-05-01T09:59:59", To: "2024-05-01T13:05:00"},
+	}
+	if !reflect.DeepEqual(s.Files, wantFiles) {
+		t.Errorf("files = %+v, want %+v", s.Files, wantFiles)
+	}
+	if s.Rows != 7 || s.From != "2024-05-01 08:30:00" || s.To != "2024-05-01T13:05:00" {
+		t.Errorf("rows %d from %q to %q", s.Rows, s.From, s.To)
+	}
+
+	types := make(map[string]string)
+	for _, col := range s.Columns {
+		types[col.Name] = col.Type
+	}
+	wantTypes := map[string]string{
+		"time": "datetime", "status": "integer", "error": "string", "bytes": "number",
+		"ts": "datetime", "src_ip": "ip", "dst_ip": "ip", "action": "string", "level": "string", "msg": "string",
+	}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("types = %v, want %v", types, wantTypes)
+	}
+
+	wantIPs := []model.TopValues{
+		{Column: "src_ip", Total: 3, Values: []model.ValueCount{{Value: "10.0.0.1", Count: 2}, {Value: "10.0.0.2", Count: 1}}},
+		{Column: "dst_ip", Total: 3, Values: []model.ValueCount{{Value: "8.8.8.8", Count: 2}, {Value: "1.1.1.1", Count: 1}}},
+	}
+	if !reflect.DeepEqual(s.TopIPs, wantIPs) {
+		t.Errorf("top IPs = %+v, want %+v", s.TopIPs, wantIPs)
+	}
+	wantStatuses := []model.TopValues{
+		{Column: "status", Total: 3, Values: []model.ValueCount{{Value: "200", Count: 2}, {Value: "500", Count: 1}}},
+		{Column: "action", Total: 4, Values: []model.ValueCount{{Value: "allow", Count: 3}, {Value: "deny", Count: 1}}},
+	}
+	if !reflect.DeepEqual(s.Statuses, wantStatuses) {
+		t.Errorf("statuses = %+v, want %+v", s.Statuses, wantStatuses)
+	}
+
+	wantHours := []model.HourCount{
+		{Hour: "2024-05-01 08:00:00", Count: 2}, {Hour: "2024-05-01 09:00:00", Count: 1}, {Hour: "2024-05-01 10:00:00", Count: 2},
+		{Hour: "2024-05-01 11:00:00", Count: 0}, {Hour: "2024-05-01 12:00:00", Count: 0}, {Hour: "2024-05-01 13:00:00", Count: 1},
+	}
+	if !reflect.DeepEqual(s.Hourly, wantHours) {
+		t.Errorf("hours = %+v, want %+v", s.Hourly, wantHours)
+	}
+	wantErrors := []model.ValueCount{{Value: "blocked by rule 7", Count: 2}, {Value: "timeout", Count: 1}}
+	if !reflect.DeepEqual(s.Errors, wantErrors) || s.Approximate {
+		t.Errorf("errors = %+v, approximate %v", s.Errors, s.Approximate)
+	}
+}
+
+// Unit test: declared types win over the values, a restarted file counts
+// its last rows only and hours far apart aren't filled in
+func TestCollector_DeclaredAndRestart(t *testing.T) {
+	c := New([]model.ColumnSchema{{Name: "peer", Type: "string"}, {Name: "when", Type: "datetime"}})
+	c.StartFile("a.log", []string{"peer", "when"})
+	c.WriteRow("a.log", []any{"10.0.0.1", "2024-01-01 00:00:00"})
+	c.StartFile("a.log", []string{"peer", "when"})
+	c.WriteRow("a.log", []any{"10.0.0.9", "2024-01-01 00:10:00"})
+	c.WriteRow("a.log", []any{"10.0.0.9", "2024-06-01 00:10:00"})
+
+	s := c.Summary()
+	if s.Rows != 2 || len(s.TopIPs) != 0 {
+		t.Errorf("rows %d, top IPs %+v", s.Rows, s.TopIPs)
+	}
+	want := []model.ColumnSummary{{Name: "peer", Type: "string", Declared: true, Values: 2}, {Name: "when", Type: "datetime", Declared: true, Values: 2}}
+	if !reflect.DeepEqual(s.Columns, want) {
+		t.Errorf("columns = %+v, want %+v", s.Columns, want)
+	}
+	if len(s.Hourly) != 2 {
+		t.Errorf("hours = %+v", s.Hourly)
+	}
+}
+
+// Unit test: a column of too many distinct values keeps its frequent ones
+// and marks the summary approximate
+func TestCounter_Prune(t *testing.T) {
+	var c counter
+	for i := 0; i < maxTracked*3; i++ {
+		c.add("frequent", 1)
+		c.add(fmt.Sprint("rare-", i), 1)
+	}
+	if len(c.counts) > maxTracked || !c.dropped {
+		t.Fatalf("%d values kept, dropped %v", len(c.counts), c.dropped)
+	}
+	if top := c.top(1); top[0].Value != "frequent" || top[0].Count != maxTracked*3 {
+		t.Errorf("top = %+v", top)
+	}
+}
+
+// Unit test: the sheet has a section per table with headings and shares
+func TestSheet(t *testing.T) {
+	s := &model.RunSummary{
+		Rows:        4,
+		From:        "2024-05-01 10:00:00",
+		Files:       []model.FileSummary{{File: "a.log", Rows: 4}},
+		TopIPs:      []model.TopValues{{Column: "src", Total: 4, Values: []model.ValueCount{{Value: "10.0.0.1", Count: 3}}}},
+		Errors:      []model.ValueCount{{Value: "timeout", Count: 1}},
+		Approximate: true,
+	}
+	want := [][]any{
+		{output.Heading("Summary")},
+		{"Rows", json.Number("4")},
+		{"From", "2024-05-01 10:00:00"},
+		{"To", nil},
+		{"Note", "Some columns had too many distinct values to count exactly; their counts are lower bounds"},
+		nil,
+		{output.Heading("Files")},
+		{output.Heading("File"), output.Heading("Rows"), output.Heading("Time column"), output.Heading("From"), output.Heading("To")},
+		{"a.log", json.Number("4"), nil, nil, nil},
+		nil,
+		{output.Heading("Top addresses: src")},
+		{output.Heading("Address"), output.Heading("Rows"), output.Heading("Share (%)")},
+		{"10.0.0.1", json.Number("3"), json.Number("75.0")},
+		nil,
+		{output.Heading("Top error messages")},
+		{output.Heading("Message"), output.Heading("Rows")},
+		{"timeout", json.Number("1")},
+	}
+	if got := Sheet(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("sheet = %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+// Package timezone normalizes the times in the rows scripts stream. Logs
+// come from devices in several zones, some writing an offset and many not:
+// times without one are taken to be in the source zone of their file, and
+// every time is converted to the target zone of the run and written either
+// as that zone's wall clock, which workbooks hold as date cells, or as ISO
+// 8601 with the offset.
+package timezone
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// Zone data for computers without it, Windows among them
+	_ "time/tzdata"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+	"network-log-formatter/internal/schema"
+)
+
+// Formats of normalized times.
+const (
+	DateTime = "datetime"
+	ISO      = "iso"
+)
+
+// Local names the zone of this computer.
+const Local = "Local"
+
+// Layouts of normalized times; fractions of a second are kept when given.
+const (
+	dateTimeLayout = "2006-01-02 15:04:05.999999999"
+	isoLayout      = time.RFC3339Nano
+)
+
+// offsetPattern matches zones given as an offset from UTC, such as
+// "+08:00", "-0530", "UTC+8" or "GMT-03".
+var offsetPattern = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// Load returns the zone called name: "Local", "UTC", an offset or an IANA
+// name.
+func Load(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return nil, fmt.Errorf("no time zone given")
+	case strings.EqualFold(name, Local):
+		return time.Local, nil
+	case strings.EqualFold(name, "UTC"), strings.EqualFold(name, "GMT"), name == "Z":
+		return time.UTC, nil
+	}
+	if m := offsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("time zone offset %q is out of range", name)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(fmt.Sprintf("%s%02d:%02d", m[1], hours, minutes), offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Enabled reports whether z normalizes times: it has a target zone.
+func Enabled(z *model.TimeZones) bool {
+	return z != nil && strings.TrimSpace(z.Target) != ""
+}
+
+// Validate checks that the zones are known, the patterns valid and the
+// format known, and that zones of the logs come with a target zone.
+func Validate(z model.TimeZones) error {
+	if strings.TrimSpace(z.Target) == "" {
+		if strings.TrimSpace(z.Source) != "" || len(z.Files) > 0 {
+			return fmt.Errorf("the zones of the logs need a target zone to convert their times to")
+		}
+		return nil
+	}
+	if _, err := Load(z.Target); err != nil {
+		return fmt.Errorf("target zone: %w", err)
+	}
+	if strings.TrimSpace(z.Source) != "" {
+		if _, err := Load(z.Source); err != nil {
+			return fmt.Errorf("source zone: %w", err)
+		}
+	}
+	for i, f := range z.Files {
+		if strings.TrimSpace(f.Pattern) == "" {
+			return fmt.Errorf("file zone %d has no pattern", i+1)
+		}
+		if _, err := path.Match(f.Pattern, ""); err != nil {
+			return fmt.Errorf("file zone %d: bad pattern %q", i+1, f.Pattern)
+		}
+		if _, err := Load(f.Zone); err != nil {
+			return fmt.Errorf("file zone %d: %w", i+1, err)
+		}
+	}
+	switch z.Format {
+	case "", DateTime, ISO:
+		return nil
+	}
+	return fmt.Errorf("unknown time format %q", z.Format)
+}
+
+// Normalizer normalizes the times of a run's files. It isn't safe for
+// concurrent use.
+type Normalizer struct {
+	zones     model.TimeZones
+	schema    []model.ColumnSchema
+	source    *time.Location
+	target    *time.Location
+	files     []*time.Location // zone of each of zones.Files
+	layout    string
+	converted map[string]int    // times normalized in each file
+	other     map[string]string // zone of the files a pattern gives another zone
+}
+
+// New returns the normalizer of zones, which must normalize times. Columns
+// declared in columns as something other than a datetime are left alone.
+func New(zones model.TimeZones, columns []model.ColumnSchema) (*Normalizer, error) {
+	if !Enabled(&zones) {
+		return nil, fmt.Errorf("no target zone to convert times to")
+	}
+	if err := Validate(zones); err != nil {
+		return nil, err
+	}
+	n := &Normalizer{
+		zones:     zones,
+		schema:    columns,
+		layout:    dateTimeLayout,
+		converted: make(map[string]int),
+		other:     make(map[string]string),
+	}
+	n.target, _ = Load(zones.Target)
+	n.source = n.target
+	if strings.TrimSpace(zones.Source) != "" {
+		n.source, _ = Load(zones.Source)
+	}
+	for _, f := range zones.Files {
+		loc, _ := Load(f.Zone)
+		n.files = append(n.files, loc)
+	}
+	if zones.Format == ISO {
+		n.layout = isoLayout
+	}
+	return n, nil
+}
+
+// Zones returns the zones the normalizer applies.
+func (n *Normalizer) Zones() model.TimeZones {
+	return n.zones
+}
+
+// sourceZone returns the zone of the times of file without an offset, and
+// its name when a pattern gives it.
+func (n *Normalizer) sourceZone(file string) (*time.Location, string) {
+	name := strings.ReplaceAll(file, `\`, "/")
+	for i, f := range n.zones.Files {
+		if match(f.Pattern, name) {
+			return n.files[i], f.Zone
+		}
+	}
+	return n.source, ""
+}
+
+// match reports whether pattern matches the name of a file, or its base
+// name when the pattern has no directory.
+func match(pattern string, name string) bool {
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	if strings.Contains(pattern, "/") {
+		return false
+	}
+	ok, _ := path.Match(pattern, path.Base(name))
+	return ok
+}
+
+// File is the normalization of the rows of a file.
+type File struct {
+	n    *Normalizer
+	name string
+	zone *time.Location
+	skip []bool // columns declared as other than datetimes
+}
+
+// File begins the rows of file with the given columns, dropping the count
+// of an earlier start.
+func (n *Normalizer) File(file string, columns []string) *File {
+	f := &File{n: n, name: file, skip: make([]bool, len(columns))}
+	var zone string
+	f.zone, zone = n.sourceZone(file)
+	delete(n.other, file)
+	if zone != "" {
+		n.other[file] = zone
+	}
+	n.converted[file] = 0
+	for i, c := range output.Columns(columns) {
+		if typ := schema.Declared(n.schema, c); typ != "" && typ != schema.DateTime {
+			f.skip[i] = true
+		}
+	}
+	return f
+}
+
+// Normalize converts the times among values in place. Dates without a
+// time are left as they are.
+func (f *File) Normalize(values []any) {
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok || (i < len(f.skip) && f.skip[i]) {
+			continue
+		}
+		t, dateOnly, ok := output.ParseTime(s, f.zone)
+		if !ok || dateOnly {
+			continue
+		}
+		values[i] = t.In(f.n.target).Format(f.n.layout)
+		f.n.converted[f.name]++
+	}
+}
+
+// Applied returns the zones the times of the files begun so far were
+// normalized with.
+func (n *Normalizer) Applied() *model.AppliedZones {
+	a := &model.AppliedZones{
+		Source: zoneName(n.zones.Source),
+		Target: zoneName(n.zones.Target),
+		Format: n.zones.Format,
+	}
+	if a.Source == "" {
+		a.Source = a.Target
+	}
+	if a.Format == "" {
+		a.Format = DateTime
+	}
+	for file, zone := range n.other {
+		if a.Files == nil {
+			a.Files = make(map[string]string)
+		}
+		a.Files[file] = zoneName(zone)
+	}
+	for _, c := range n.converted {
+		a.Converted += c
+	}
+	return a
+}
+
+// zoneName returns the name a zone is recorded under: as given, "Local"
+// with its current offset.
+func zoneName(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, Local) {
+		return Local + " (" + time.Now().Format("-07:00") + ")"
+	}
+	return name
+}
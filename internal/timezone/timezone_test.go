@@ -0,0 +1,185 @@
+package timezone
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"network-log-formatter/internal/model"
+	"network-log-formatter/internal/output"
+
+	"pgregory.net/rapid"
+)
+
+// offsetName writes an offset in minutes as a zone name.
+func offsetName(minutes int) string {
+	sign := "+"
+	if minutes < 0 {
+		sign, minutes = "-", -minutes
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, minutes/60, minutes%60)
+}
+
+// Feature: network-log-formatter, Property 30: 时区规范化保持时刻不变
+// For any time written without an offset in any source zone, the
+// normalized time read in the target zone is the same instant, in either
+// format.
+func TestProperty30_NormalizeKeepsInstant(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		source := rapid.IntRange(-12*4, 14*4).Draw(rt, "source") * 15
+		target := rapid.IntRange(-12*4, 14*4).Draw(rt, "target") * 15
+		format := rapid.SampledFrom([]string{DateTime, ISO}).Draw(rt, "format")
+		n, err := New(model.TimeZones{Source: offsetName(source), Target: offsetName(target), Format: format}, nil)
+		if err != nil {
+			rt.Fatalf("New: %v", err)
+		}
+		wall := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(rapid.Int64Range(0, 40*365*24*3600).Draw(rt, "seconds")) * time.Second)
+		written := wall.Format("2006-01-02 15:04:05")
+
+		values := []any{written}
+		n.File("a.log", []string{"time"}).Normalize(values)
+		got, _, ok := output.ParseTime(values[0].(string), time.FixedZone("", target*60))
+		want := wall.Add(-time.Duration(source) * time.Minute)
+		if !ok || !got.Equal(want) {
+			rt.Fatalf("%s in %s to %s as %s: %v, want the instant %v", written, offsetName(source), offsetName(target), format, values[0], want)
+		}
+		if n.Applied().Converted != 1 {
+			rt.Fatalf("converted %d", n.Applied().Converted)
+		}
+	})
+}
+
+// --- Unit Tests ---
+
+// Unit test: zones are names, offsets, UTC or the local zone
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name   string
+		offset int // seconds east of UTC in January 2024; -1 for the local zone
+	}{
+		{"UTC", 0},
+		{"Local", -1},
+		{"+08:00", 8 * 3600},
+		{"-0530", -(5*3600 + 30*60)},
+		{"UTC+8", 8 * 3600},
+		{"gmt-03", -3 * 3600},
+		{"Asia/Shanghai", 8 * 3600},
+		{"America/New_York", -5 * 3600},
+	}
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	for _, c := range cases {
+		loc, err := Load(c.name)
+		if err != nil {
+			t.Errorf("Load(%q): %v", c.name, err)
+			continue
+		}
+		if c.offset == -1 {
+			if loc != time.Local {
+				t.Errorf("Load(%q) = %v", c.name, loc)
+			}
+			continue
+		}
+		if _, off := at.In(loc).Zone(); off != c.offset {
+			t.Errorf("Load(%q): offset %d, want %d", c.name, off, c.offset)
+		}
+	}
+	for _, bad := range []string{"", "z", "Mars/Base", "+15:00", "+08:75"} {
+		if _, err := Load(bad); err == nil {
+			t.Errorf("Load(%q) succeeded", bad)
+		}
+	}
+}
+
+// Unit test: zones need a target, known names, patterns and format
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		z  model.TimeZones
+		ok bool
+	}{
+		{model.TimeZones{}, true},
+		{model.TimeZones{Target: "UTC"}, true},
+		{model.TimeZones{Source: "+08:00", Target: "Local", Format: ISO, Files: []model.FileZone{{Pattern: "fw-*", Zone: "Europe/Berlin"}}}, true},
+		{model.TimeZones{Source: "+08:00"}, false},
+		{model.TimeZones{Target: "Nowhere"}, false},
+		{model.TimeZones{Target: "UTC", Files: []model.FileZone{{Pattern: "[", Zone: "UTC"}}}, false},
+		{model.TimeZones{Target: "UTC", Files: []model.FileZone{{Zone: "UTC"}}}, false},
+		{model.TimeZones{Target: "UTC", Format: "excel"}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(c.z); (err == nil) != c.ok {
+			t.Errorf("%+v: err = %v", c.z, err)
+		}
+	}
+}
+
+// Unit test: files matching a pattern use its zone, times with an offset
+// keep their instant, dates and columns declared as other types stay as
+// written, and the zones applied are recorded
+func TestNormalize(t *testing.T) {
+	n, err := New(model.TimeZones{
+		Source: "UTC",
+		Files:  []model.FileZone{{Pattern: "fw-*.log", Zone: "+02:00"}, {Pattern: "asia/*", Zone: "Asia/Shanghai"}},
+		Target: "+01:00",
+	}, []model.ColumnSchema{{Name: "raw", Type: "string"}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	columns := []string{"time", "raw", "day", "status"}
+	row := func(file string) []any {
+		values := []any{"2024-05-01 10:00:00", "2024-05-01 10:00:00", "2024-05-01", json.Number("200")}
+		n.File(file, columns).Normalize(values)
+		return values
+	}
+	if got := row("a.log"); got[0] != "2024-05-01 11:00:00" || got[1] != "2024-05-01 10:00:00" || got[2] != "2024-05-01" || got[3] != json.Number("200") {
+		t.Errorf("a.log = %v", got)
+	}
+	if got := row("logs/fw-1.log"); got[0] != "2024-05-01 09:00:00" {
+		t.Errorf("fw-1.log = %v", got)
+	}
+	if got := row(`asia\b.log`); got[0] != "2024-05-01 03:00:00" {
+		t.Errorf("asia/b.log = %v", got)
+	}
+	// The pattern with a directory doesn't match the base name elsewhere
+	if got := row("b.log"); got[0] != "2024-05-01 11:00:00" {
+		t.Errorf("b.log = %v", got)
+	}
+
+	values := []any{"2024-05-01T10:00:00.25-04:00"}
+	n.File("c.log", []string{"time"}).Normalize(values)
+	if values[0] != "2024-05-01 15:00:00.25" {
+		t.Errorf("time with an offset = %v", values[0])
+	}
+
+	a := n.Applied()
+	want := map[string]string{"logs/fw-1.log": "+02:00", `asia\b.log`: "Asia/Shanghai"}
+	if a.Source != "UTC" || a.Target != "+01:00" || a.Format != DateTime || a.Converted != 5 || fmt.Sprint(a.Files) != fmt.Sprint(want) {
+		t.Errorf("applied = %+v", a)
+	}
+
+	// Starting a file again drops its count
+	n.File("c.log", []string{"time"})
+	if a := n.Applied(); a.Converted != 4 {
+		t.Errorf("converted after a restart = %d", a.Converted)
+	}
+}
+
+// Unit test: the ISO format writes the offset of the target zone, and the
+// source zone defaults to the target
+func TestNormalize_ISO(t *testing.T) {
+	n, err := New(model.TimeZones{Target: "Asia/Kolkata", Format: ISO}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	values := []any{"2024-05-01 10:00:00", "2024-05-01T10:00:00Z"}
+	n.File("a.log", []string{"a", "b"}).Normalize(values)
+	if values[0] != "2024-05-01T10:00:00+05:30" || values[1] != "2024-05-01T15:30:00+05:30" {
+		t.Errorf("values = %v", values)
+	}
+	if a := n.Applied(); a.Source != "Asia/Kolkata" || a.Format != ISO || a.Files != nil {
+		t.Errorf("applied = %+v", a)
+	}
+	if _, err := New(model.TimeZones{}, nil); err == nil {
+		t.Errorf("New without a target zone succeeded")
+	}
+}
@@ -0,0 +1,170 @@
+// Package watch turns filesystem notifications on an input directory into
+// debounced processing runs, so logs arriving on a collection box are picked
+// up without re-running batches by hand.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long a directory must be quiet before a run starts.
+const DefaultDebounce = 2 * time.Second
+
+// TriggerFunc processes the watched directory. changed lists the paths that
+// produced events since the previous run; it is empty for the initial run and
+// after the event queue overflowed, when the whole directory should be
+// rescanned.
+type TriggerFunc func(ctx context.Context, changed []string)
+
+// Watcher calls a TriggerFunc whenever files in a directory are created,
+// written, renamed or removed, once no further events arrived for the
+// debounce interval. Files still being written keep resetting the interval,
+// so they are processed only after the writer pauses.
+type Watcher struct {
+	dir       string
+	recursive bool
+	debounce  time.Duration
+}
+
+// NewWatcher creates a Watcher for dir. With recursive set, subdirectories,
+// including ones created later, are watched as well. A debounce of zero or
+// less uses DefaultDebounce.
+func NewWatcher(dir string, recursive bool, debounce time.Duration) *Watcher {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	return &Watcher{dir: dir, recursive: recursive, debounce: debounce}
+}
+
+// Run triggers an initial run, then watches the directory until ctx is
+// cancelled. Runs never overlap: events arriving during a run are collected
+// and trigger the next one. Run returns nil when ctx is cancelled and an
+// error only if the directory cannot be watched.
+func (w *Watcher) Run(ctx context.Context, trigger TriggerFunc) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fw.Close()
+	if err := w.add(fw, w.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.dir, err)
+	}
+
+	timer := time.NewTimer(0) // initial run
+	defer timer.Stop()
+
+	pending := make(map[string]struct{})
+	rescan := false
+	running := false
+	done := make(chan struct{})
+
+	for {
+		select {
+		case <-ctx.Done():
+			if running {
+				<-done
+			}
+			return nil
+
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if !relevant(ev) {
+				continue
+			}
+			if w.recursive && ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					_ = w.add(fw, ev.Name)
+				}
+			}
+			pending[ev.Name] = struct{}{}
+			resetTimer(timer, w.debounce)
+
+		case _, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			// Events may have been lost (e.g. queue overflow); fall back to a rescan
+			rescan = true
+			resetTimer(timer, w.debounce)
+
+		case <-timer.C:
+			if running {
+				continue // picked up when the current run finishes
+			}
+			changed := make([]string, 0, len(pending))
+			if !rescan {
+				for path := range pending {
+					changed = append(changed, path)
+				}
+				sort.Strings(changed)
+			}
+			pending = make(map[string]struct{})
+			rescan = false
+			running = true
+			go func() {
+				trigger(ctx, changed)
+				done <- struct{}{}
+			}()
+
+		case <-done:
+			running = false
+			if len(pending) > 0 || rescan {
+				resetTimer(timer, w.debounce)
+			}
+		}
+	}
+}
+
+// add watches dir and, for recursive watchers, its non-hidden subdirectories.
+func (w *Watcher) add(fw *fsnotify.Watcher, dir string) error {
+	if !w.recursive {
+		return fw.Add(dir)
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return fw.Add(p)
+	})
+}
+
+// relevant reports whether an event may change what a run produces.
+// Permission changes and hidden files (editor swap files, the merge
+// workbook written next to outputs) are ignored.
+func relevant(ev fsnotify.Event) bool {
+	if strings.HasPrefix(filepath.Base(ev.Name), ".") {
+		return false
+	}
+	return ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename|fsnotify.Remove) != 0
+}
+
+// resetTimer restarts t to fire after d, draining a pending fire first.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
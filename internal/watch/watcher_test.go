@@ -0,0 +1,130 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// --- Unit Tests ---
+
+// Unit test: an initial run happens, then bursts of writes are debounced into
+// a single run listing the changed files
+func TestWatcher_DebouncesEvents(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var runs [][]string
+	trigger := func(ctx context.Context, changed []string) {
+		mu.Lock()
+		runs = append(runs, changed)
+		mu.Unlock()
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(runs)
+	}
+
+	w := NewWatcher(dir, false, 100*time.Millisecond)
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.Run(ctx, trigger) }()
+
+	waitFor(t, 2*time.Second, func() bool { return count() == 1 })
+
+	path := filepath.Join(dir, "app.log")
+	for i := 0; i < 5; i++ {
+		f, _ := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString("line\n")
+		f.Close()
+		time.Sleep(20 * time.Millisecond)
+	}
+	os.WriteFile(filepath.Join(dir, ".swap"), []byte("x"), 0644)
+
+	waitFor(t, 2*time.Second, func() bool { return count() == 2 })
+	time.Sleep(300 * time.Millisecond)
+	if n := count(); n != 2 {
+		t.Fatalf("expected the burst to trigger one run, got %d runs", n)
+	}
+
+	mu.Lock()
+	changed := runs[1]
+	mu.Unlock()
+	if len(changed) != 1 || changed[0] != path {
+		t.Fatalf("expected only %s to be reported, got %v", path, changed)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("expected nil error on cancel, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher did not stop after cancel")
+	}
+}
+
+// Unit test: watching a missing directory fails
+func TestWatcher_MissingDir(t *testing.T) {
+	w := NewWatcher(filepath.Join(t.TempDir(), "missing"), false, 0)
+	err := w.Run(context.Background(), func(ctx context.Context, changed []string) {})
+	if err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
+
+// waitFor polls cond until it returns true or the timeout expires.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+// Unit test: recursive watchers pick up files in new subdirectories
+func TestWatcher_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var seen []string
+	trigger := func(ctx context.Context, changed []string) {
+		mu.Lock()
+		seen = append(seen, changed...)
+		mu.Unlock()
+	}
+	saw := func(path string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range seen {
+			if p == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	w := NewWatcher(dir, true, 50*time.Millisecond)
+	go w.Run(ctx, trigger)
+	time.Sleep(100 * time.Millisecond)
+
+	sub := filepath.Join(dir, "web1")
+	os.Mkdir(sub, 0755)
+	time.Sleep(100 * time.Millisecond)
+	path := filepath.Join(sub, "access.log")
+	os.WriteFile(path, []byte("line\n"), 0644)
+
+	waitFor(t, 2*time.Second, func() bool { return saw(path) })
+}
@@ -0,0 +1,380 @@
+// Package xlsx reads the worksheets of Excel workbooks as text, so the
+// output of generated scripts can be inspected in Go without a spreadsheet
+// library. Only what scripts produce is supported: shared, inline and
+// formula strings, numbers, booleans and dates.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sheet is a worksheet with its cells as text. Rows are as wide as the
+// widest row of the sheet, so empty cells, including whole empty columns,
+// are kept as empty strings.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// ReadFile reads every worksheet of the workbook at path in workbook order.
+// At most maxRows rows are read per sheet; 0 reads them all.
+func ReadFile(file string, maxRows int) ([]Sheet, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	defer zr.Close()
+
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	wb := &workbook{parts: parts}
+	if err := wb.load(); err != nil {
+		return nil, err
+	}
+
+	sheets := make([]Sheet, 0, len(wb.sheets))
+	for _, s := range wb.sheets {
+		rows, err := wb.readSheet(s.path, maxRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %s: %w", s.name, err)
+		}
+		sheets = append(sheets, Sheet{Name: s.name, Rows: rows})
+	}
+	return sheets, nil
+}
+
+// workbook holds the parts of a workbook needed to read its sheets.
+type workbook struct {
+	parts    map[string]*zip.File
+	sheets   []sheetRef
+	strings  []string
+	dates    map[int]bool // cell style indexes with a date format
+	date1904 bool
+}
+
+// sheetRef is a worksheet name and the zip part holding it.
+type sheetRef struct {
+	name string
+	path string
+}
+
+// load reads the sheet list, shared strings and cell styles.
+func (wb *workbook) load() error {
+	var doc struct {
+		Pr struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decode("xl/workbook.xml", &doc); err != nil {
+		return err
+	}
+	wb.date1904 = doc.Pr.Date1904
+
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return err
+	}
+	targets := make(map[string]string, len(rels.Rels))
+	for _, r := range rels.Rels {
+		if strings.HasPrefix(r.Target, "/") {
+			targets[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+	for _, s := range doc.Sheets {
+		if target, ok := targets[s.RID]; ok {
+			wb.sheets = append(wb.sheets, sheetRef{name: s.Name, path: target})
+		}
+	}
+
+	if err := wb.loadStrings(); err != nil {
+		return err
+	}
+	return wb.loadStyles()
+}
+
+// loadStrings reads the shared string table, if there is one. Rich text
+// strings are flattened to their text.
+func (wb *workbook) loadStrings() error {
+	if wb.parts["xl/sharedStrings.xml"] == nil {
+		return nil
+	}
+	var doc struct {
+		Items []richText `xml:"si"`
+	}
+	if err := wb.decode("xl/sharedStrings.xml", &doc); err != nil {
+		return err
+	}
+	wb.strings = make([]string, len(doc.Items))
+	for i, si := range doc.Items {
+		wb.strings[i] = si.text()
+	}
+	return nil
+}
+
+// richText is a string item: plain text or runs of formatted text.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt richText) text() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var b strings.Builder
+	for _, r := range rt.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// loadStyles finds the cell styles that format numbers as dates, using the
+// built-in date formats and custom formats with date or time codes.
+func (wb *workbook) loadStyles() error {
+	wb.dates = make(map[int]bool)
+	if wb.parts["xl/styles.xml"] == nil {
+		return nil
+	}
+	var doc struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := wb.decode("xl/styles.xml", &doc); err != nil {
+		return err
+	}
+	custom := make(map[int]bool, len(doc.NumFmts))
+	for _, f := range doc.NumFmts {
+		custom[f.ID] = isDateFormat(f.Code)
+	}
+	for i, xf := range doc.Xfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || custom[id] {
+			wb.dates[i] = true
+		}
+	}
+	return nil
+}
+
+// isDateFormat reports whether a number format code shows a date or time,
+// ignoring quoted text, escaped characters and colour or condition brackets.
+func isDateFormat(code string) bool {
+	quoted, bracket := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case quoted:
+			quoted = c != '"'
+		case bracket:
+			bracket = c != ']'
+		case c == '"':
+			quoted = true
+		case c == '[':
+			// Elapsed time such as [h] is still a time
+			if i+1 < len(code) && strings.ContainsRune("hHmMsS", rune(code[i+1])) {
+				return true
+			}
+			bracket = true
+		case c == '\\':
+			i++
+		case strings.IndexByte("yYmMdDhHsS", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// decode unmarshals the XML part name into v.
+func (wb *workbook) decode(name string, v interface{}) error {
+	f := wb.parts[name]
+	if f == nil {
+		return fmt.Errorf("workbook has no %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// cell is a worksheet cell as stored.
+type cell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+// readSheet reads the rows of the worksheet part name, placing cells by
+// their reference and padding every row to the sheet's width.
+func (wb *workbook) readSheet(name string, maxRows int) ([][]string, error) {
+	f := wb.parts[name]
+	if f == nil {
+		return nil, fmt.Errorf("workbook has no %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows [][]string
+	width := 0
+	rowIndex := -1 // index of the current row; rows without r follow the previous one
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			rowIndex++
+			for _, a := range start.Attr {
+				if a.Name.Local == "r" {
+					if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+						rowIndex = n - 1
+					}
+				}
+			}
+			if maxRows > 0 && rowIndex >= maxRows {
+				return padRows(rows, width), nil
+			}
+			for len(rows) <= rowIndex {
+				rows = append(rows, nil)
+			}
+		case "c":
+			var c cell
+			if err := dec.DecodeElement(&c, &start); err != nil {
+				return nil, err
+			}
+			if rowIndex < 0 {
+				continue
+			}
+			col := len(rows[rowIndex])
+			if c.Ref != "" {
+				if col, err = columnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			row := rows[rowIndex]
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			row[col] = wb.cellText(c)
+			rows[rowIndex] = row
+			width = max(width, len(row))
+		}
+	}
+	return padRows(rows, width), nil
+}
+
+// cellText returns a cell's value as text.
+func (wb *workbook) cellText(c cell) string {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(wb.strings) {
+			return ""
+		}
+		return wb.strings[i]
+	case "inlineStr":
+		return c.Inline.text()
+	case "b":
+		if c.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e", "d":
+		return c.Value
+	}
+	if c.Value != "" && wb.dates[c.Style] {
+		if v, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			return formatDate(v, wb.date1904)
+		}
+	}
+	return c.Value
+}
+
+// formatDate formats an Excel date serial number, leaving out the time of
+// whole days.
+func formatDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	ms := math.Round((serial - days) * 24 * 60 * 60 * 1000)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+	switch {
+	case ms == 0:
+		return t.Format("2006-01-02")
+	case t.Nanosecond() == 0:
+		return t.Format("2006-01-02 15:04:05")
+	default:
+		return t.Format("2006-01-02 15:04:05.000")
+	}
+}
+
+// columnIndex returns the zero-based column of a cell reference like "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || col > 1<<14 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+// padRows extends every row to width columns.
+func padRows(rows [][]string, width int) [][]string {
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		rows[i] = row
+	}
+	return rows
+}
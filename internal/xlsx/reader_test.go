@@ -0,0 +1,202 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+const (
+	workbookXML = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>%s</sheets></workbook>`
+	relsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s</Relationships>`
+	sheetXML = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>%s</sheetData></worksheet>`
+)
+
+// writeWorkbook writes a workbook with the given sheets, each given as the
+// content of its sheetData element, plus any extra parts.
+func writeWorkbook(t interface{ Fatalf(string, ...interface{}) }, file string, names []string, sheets []string, extra map[string]string) {
+	parts := map[string]string{}
+	var sheetList, rels strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		parts[fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)] = fmt.Sprintf(sheetXML, sheets[i])
+	}
+	parts["xl/workbook.xml"] = fmt.Sprintf(workbookXML, sheetList.String())
+	parts["xl/_rels/workbook.xml.rels"] = fmt.Sprintf(relsXML, rels.String())
+	for name, content := range extra {
+		parts[name] = content
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("failed to create workbook: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to write workbook: %v", err)
+	}
+}
+
+// columnName returns the letters of a zero-based column.
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// Feature: network-log-formatter, Property 23: 工作表单元格按引用还原
+// For any grid of text with empty cells, a sheet storing only the non-empty
+// cells as inline strings at their references reads back as the grid, with
+// every row as wide as the widest one.
+func TestProperty23_SheetReadsBackByReference(t *testing.T) {
+	dir := t.TempDir()
+	rapid.Check(t, func(t *rapid.T) {
+		grid := rapid.SliceOfN(rapid.SliceOfN(rapid.SampledFrom([]string{"", "", "a", "b c", "<&>", "中文"}), 0, 30), 1, 10).Draw(t, "grid")
+
+		var data strings.Builder
+		width := 0
+		for r, row := range grid {
+			fmt.Fprintf(&data, `<row r="%d">`, r+1)
+			for c, v := range row {
+				if v != "" {
+					fmt.Fprintf(&data, `<c r="%s%d" t="inlineStr"><is><t>%s</t></is></c>`, columnName(c), r+1, escape(v))
+					width = max(width, c+1)
+				}
+			}
+			data.WriteString(`</row>`)
+		}
+		file := filepath.Join(dir, "grid.xlsx")
+		writeWorkbook(t, file, []string{"Sheet1"}, []string{data.String()}, nil)
+
+		sheets, err := ReadFile(file, 0)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if len(sheets) != 1 || len(sheets[0].Rows) != len(grid) {
+			t.Fatalf("expected 1 sheet of %d rows, got %+v", len(grid), sheets)
+		}
+		for r, row := range sheets[0].Rows {
+			if len(row) != width {
+				t.Fatalf("row %d: expected width %d, got %d", r, width, len(row))
+			}
+			for c, v := range row {
+				want := ""
+				if c < len(grid[r]) {
+					want = grid[r][c]
+				}
+				if v != want {
+					t.Fatalf("cell %s%d: expected %q, got %q", columnName(c), r+1, want, v)
+				}
+			}
+		}
+	})
+}
+
+// escape escapes text for an XML element.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// --- Unit Tests ---
+
+// Unit test: shared and rich text strings, numbers, booleans and dated
+// numbers are read as text, and sheets keep their workbook order
+func TestReadFile_CellTypes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "types.xlsx")
+	writeWorkbook(t, file, []string{"访问日志", "Empty"}, []string{
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>` +
+			`<row r="2"><c r="A2"><v>42.5</v></c><c r="B2" t="b"><v>1</v></c><c r="D2" s="1"><v>45292</v></c></row>` +
+			`<row r="3"><c r="A3" t="str"><v>=x</v></c><c r="D3" s="2"><v>45292.5</v></c></row>`,
+		``,
+	}, map[string]string{
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>ip</t></si><si><r><t>st</t></r><r><t>atus</t></r></si><si><t>time</t></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+			`<cellXfs count="3"><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs></styleSheet>`,
+	})
+
+	sheets, err := ReadFile(file, 0)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	want := []Sheet{
+		{Name: "访问日志", Rows: [][]string{
+			{"ip", "status", "", "time"},
+			{"42.5", "TRUE", "", "2024-01-01"},
+			{"=x", "", "", "2024-01-01 12:00:00"},
+		}},
+		{Name: "Empty"},
+	}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Fatalf("expected %+v, got %+v", want, sheets)
+	}
+}
+
+// Unit test: maxRows limits the rows read per sheet
+func TestReadFile_MaxRows(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "long.xlsx")
+	var data strings.Builder
+	for r := 1; r <= 10; r++ {
+		fmt.Fprintf(&data, `<row r="%d"><c r="A%d"><v>%d</v></c></row>`, r, r, r)
+	}
+	writeWorkbook(t, file, []string{"Sheet1"}, []string{data.String()}, nil)
+
+	sheets, err := ReadFile(file, 3)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if want := [][]string{{"1"}, {"2"}, {"3"}}; !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Fatalf("expected %v, got %v", want, sheets[0].Rows)
+	}
+}
+
+// Unit test: number formats are recognized as dates by their codes, not by
+// quoted text or colours
+func TestIsDateFormat(t *testing.T) {
+	cases := map[string]bool{
+		"yyyy-mm-dd":    true,
+		"[h]:mm":        true,
+		"0.00":          false,
+		`"day "0`:       false,
+		"[Red]0.00":     false,
+		`0\d`:           false,
+		"#,##0;[Red]-0": false,
+		"hh:mm AM/PM":   true,
+	}
+	for code, want := range cases {
+		if got := isDateFormat(code); got != want {
+			t.Errorf("isDateFormat(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+// Unit test: a file that is not a workbook is an error
+func TestReadFile_NotWorkbook(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bad.xlsx")
+	os.WriteFile(file, []byte("not a zip"), 0644)
+	if _, err := ReadFile(file, 0); err == nil {
+		t.Fatal("expected an error for a file that is not a workbook")
+	}
+}
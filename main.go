@@ -9,12 +9,17 @@ import (
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+
+	"network-log-formatter/internal/pyenv"
 )
 
 //go:embed all:frontend
 var assets embed.FS
 
 func main() {
+	// Act as the script sandbox helper when started as one
+	pyenv.SandboxMain()
+
 	configDir, err := getConfigDir()
 	if err != nil {
 		fmt.Printf("Error determining config directory: %v\n", err)